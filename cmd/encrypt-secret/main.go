@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/config"
+)
+
+// Encrypts a config value read from stdin for use as an enc:v1:... environment variable, e.g.
+//
+//	go run ./cmd/encrypt-secret -key /run/secrets/config_master_key < jwt_secret.txt
+//
+// Use -generate-key to create a new master key.
+func main() {
+	keyPath := flag.String("key", os.Getenv("CONFIG_MASTER_KEY_FILE"), "file holding the base64 master key")
+	generateKey := flag.Bool("generate-key", false, "print a new random master key and exit")
+	flag.Parse()
+
+	if *generateKey {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(base64.StdEncoding.EncodeToString(key))
+		return
+	}
+
+	if *keyPath == "" {
+		log.Fatal("-key or CONFIG_MASTER_KEY_FILE is required")
+	}
+	masterKey, err := config.LoadMasterKey(*keyPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	plaintext, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && plaintext == "" {
+		log.Fatal("no value on stdin")
+	}
+
+	encrypted, err := config.EncryptValue(masterKey, strings.TrimRight(plaintext, "\r\n"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(encrypted)
+}
@@ -3,6 +3,8 @@ package main
 import (
 	"log"
 	"net/http"
+	// Embedded zone database so user time zones load in containers without one
+	_ "time/tzdata"
 
 	"github.com/CodeWithKrushnal/ChainBank/internal/app"
 	"github.com/CodeWithKrushnal/ChainBank/internal/config"
@@ -10,12 +12,52 @@ import (
 
 func main() {
 	// Config Setup
-	postgresDB, ethClient := config.InitConfig()
-	defer config.ReleaseConfig(postgresDB)
+	dbRouter, ethClient := config.InitConfig()
+	defer config.ReleaseConfig(dbRouter)
 
-	deps := app.NewDependencies(postgresDB, ethClient)
+	deps := app.NewDependencies(dbRouter, ethClient)
+
+	// Background jobs
+	stopJobs := make(chan struct{})
+	defer close(stopJobs)
+	go deps.SettingsService.RunRefresher(stopJobs)
+	go deps.FeatureService.RunRefresher(stopJobs)
+	go deps.CalendarService.RunRefresher(stopJobs)
+	// Schedulers run on the elected leader only so that their work is done once across all instances,
+	// the workers below claim their work and run everywhere
+	go deps.LeaderService.Run(stopJobs, func(stopScheduling <-chan struct{}) {
+		go deps.ArchiveService.RunScheduler(stopScheduling)
+		go deps.NotificationService.RunDigestScheduler(stopScheduling)
+		go deps.RecoveryService.RunScheduler(stopScheduling)
+		go deps.BalanceHistoryService.RunScheduler(stopScheduling)
+		go deps.BudgetService.RunScheduler(stopScheduling)
+		go deps.MerchantService.RunSettlementScheduler(stopScheduling)
+		go deps.InvoiceService.RunScheduler(stopScheduling)
+		go deps.SweepService.RunScheduler(stopScheduling)
+	})
+	go deps.ExportService.RunWorker(stopJobs)
+	go deps.JobService.RunWorker(stopJobs)
+	go deps.OutboxService.RunRelay(stopJobs)
+	go deps.UserService.ResumeImports()
+	if deps.FaucetSigner != nil {
+		go deps.FaucetSigner.RunHealthChecks(stopJobs)
+	}
 
 	router := app.SetupRoutes(deps)
+
+	// Internal callers reach the same routes over mutual TLS
+	if addr := config.ConfigDetails.MTLSListenAddr; addr != "" {
+		tlsConfig, err := config.MutualTLSConfig()
+		if err != nil {
+			log.Fatalf("Error configuring mutual TLS: %v", err)
+		}
+		internalServer := &http.Server{Addr: addr, Handler: router, TLSConfig: tlsConfig}
+		go func() {
+			log.Printf("Mutual TLS listener started on %s", addr)
+			log.Fatal(internalServer.ListenAndServeTLS("", ""))
+		}()
+	}
+
 	log.Println("Server started on port 8080")
 	log.Fatal(http.ListenAndServe(":8080", router))
 }
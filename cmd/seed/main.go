@@ -0,0 +1,100 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/app"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/user"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/wallet"
+	"github.com/CodeWithKrushnal/ChainBank/internal/config"
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+)
+
+// Seeds a development database with users, wallets and transfer history.
+// Uses the same environment as the server, e.g.
+//
+//	go run ./cmd/seed -users 20 -transfers 200
+func main() {
+	userCount := flag.Int("users", 10, "number of users to create")
+	transferCount := flag.Int("transfers", 50, "number of transfers between seeded users")
+	password := flag.String("password", "Seed-Password-1", "password for every seeded user")
+	seed := flag.Int64("seed", time.Now().UnixNano(), "random seed, reuse it to reproduce a data set")
+	flag.Parse()
+
+	if *userCount < 2 && *transferCount > 0 {
+		log.Fatal("at least 2 users are needed to seed transfers")
+	}
+
+	dbRouter, ethClient := config.InitConfig()
+	defer config.ReleaseConfig(dbRouter)
+
+	deps := app.NewDependencies(dbRouter, ethClient)
+	userRepo := repo.NewUserRepo(dbRouter.Writer(), config.PrivateKeyRing())
+	random := rand.New(rand.NewSource(*seed))
+	runID := strconv.FormatInt(time.Now().Unix(), 36)
+
+	// Create users, alternating between the two self-service roles
+	var users []struct {
+		UserID    string
+		UserEmail string
+		UserRole  int
+	}
+	for i := 0; i < *userCount; i++ {
+		role := i%2 + 1
+		username := fmt.Sprintf("seed_%s_%d", runID, i)
+		email := username + "@example.com"
+
+		_, err := deps.UserService.CreateUserAccount(user.SignupRequest{
+			Username: username,
+			Email:    email,
+			Password: *password,
+			FullName: fmt.Sprintf("Seed User %d", i),
+			DOB:      time.Date(1960+random.Intn(40), time.Month(1+random.Intn(12)), 1+random.Intn(28), 0, 0, 0, 0, time.UTC).Format("2006-01-02"),
+			Role:     strconv.Itoa(role),
+		})
+		if err != nil {
+			log.Fatalf("Error creating seed user %s: %v", email, err)
+		}
+
+		created, err := userRepo.GetUserByEmail(email)
+		if err != nil {
+			log.Fatalf("Error loading seed user %s: %v", email, err)
+		}
+
+		users = append(users, struct {
+			UserID    string
+			UserEmail string
+			UserRole  int
+		}{UserID: created.ID, UserEmail: email, UserRole: role})
+	}
+	log.Printf("Seeded %d users", len(users))
+
+	// Transfer small random amounts between random pairs to build transaction histories
+	seededTransfers := 0
+	for i := 0; i < *transferCount; i++ {
+		sender := users[random.Intn(len(users))]
+		recipient := users[random.Intn(len(users))]
+		for recipient.UserID == sender.UserID {
+			recipient = users[random.Intn(len(users))]
+		}
+
+		amount := new(big.Int).Mul(big.NewInt(int64(1+random.Intn(1000))), big.NewInt(1e12))
+		_, err := deps.WalletService.TransferFunds(sender, wallet.TransferRequest{
+			RecipientUserID: recipient.UserID,
+			AmountETH:       amount.String(),
+			Password:        *password,
+		})
+		if err != nil {
+			log.Printf("Skipping transfer %d: %v", i+1, err)
+			continue
+		}
+		seededTransfers++
+	}
+	log.Printf("Seeded %d transfers (random seed %d)", seededTransfers, *seed)
+}
@@ -0,0 +1,75 @@
+package activity
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/respond"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// EventResponse is one entry of the activity feed. counterparty is the public handle of the other
+// user of a transfer, invoice or delegation.
+type EventResponse struct {
+	EventID      string    `json:"event_id"`
+	Type         string    `json:"type"`
+	OccurredAt   time.Time `json:"occurred_at"`
+	ReferenceID  string    `json:"reference_id"`
+	AmountWei    string    `json:"amount_wei,omitempty"`
+	Detail       string    `json:"detail,omitempty"`
+	Counterparty string    `json:"counterparty,omitempty"`
+}
+
+// FeedResponse is a page of the activity feed, sent as the data and pagination of a list envelope
+type FeedResponse struct {
+	Events     []EventResponse
+	NextCursor string
+}
+
+type Handler struct {
+	service Service
+}
+
+// Constructor function
+func NewHandler(service Service) Handler {
+	return Handler{service: service}
+}
+
+// GetFeedHandler returns the authenticated user's recent events, newest first. Pass "cursor" from a
+// previous response's next_cursor for the next page.
+func (hd Handler) GetFeedHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	limit := defaultPageSize
+	if limitParam := query.Get("limit"); limitParam != "" {
+		parsed, err := strconv.Atoi(limitParam)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(parsed, maxPageSize)
+	}
+
+	feed, err := hd.service.GetFeed(userInfo.UserID, limit, query.Get("cursor"))
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.List(w, r, feed.Events, respond.Pagination{
+		Count:      len(feed.Events),
+		Limit:      limit,
+		NextCursor: feed.NextCursor,
+	})
+}
@@ -0,0 +1,89 @@
+package activity
+
+import (
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/privacy"
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+type service struct {
+	activityRepo repo.ActivityStorer
+}
+
+type Service interface {
+	GetFeed(userID string, limit int, cursor string) (FeedResponse, error)
+}
+
+// Constructor function
+func NewService(activityRepo repo.ActivityStorer) Service {
+	return service{activityRepo: activityRepo}
+}
+
+// GetFeed returns one page of the user's logins, transfers, invoices, sweeps, fiat deposits, fiat withdrawals and security changes
+// merged into a single timeline
+func (sd service) GetFeed(userID string, limit int, cursor string) (FeedResponse, error) {
+	var position *repo.ActivityCursor
+	if cursor != "" {
+		var err error
+		if position, err = decodeCursor(cursor); err != nil {
+			return FeedResponse{}, err
+		}
+	}
+
+	events, err := sd.activityRepo.GetActivity(userID, limit, position)
+	if err != nil {
+		return FeedResponse{}, err
+	}
+
+	response := FeedResponse{Events: make([]EventResponse, len(events))}
+	for i, event := range events {
+		response.Events[i] = EventResponse{
+			EventID:     event.EventID,
+			Type:        event.Type,
+			OccurredAt:  event.OccurredAt,
+			ReferenceID: event.ReferenceID,
+			AmountWei:   event.Amount,
+			Detail:      event.Detail,
+		}
+		if event.CounterpartyUserID != "" {
+			response.Events[i].Counterparty = privacy.PublicHandle(event.CounterpartyUserID)
+		}
+	}
+
+	// A full page means there may be more events, hand back a cursor pointing at the last one
+	if len(events) == limit && limit > 0 {
+		last := events[len(events)-1]
+		response.NextCursor = encodeCursor(repo.ActivityCursor{OccurredAt: last.OccurredAt, EventID: last.EventID})
+	}
+	return response, nil
+}
+
+// encodeCursor turns a feed position into an opaque URL-safe token
+func encodeCursor(cursor repo.ActivityCursor) string {
+	raw := cursor.OccurredAt.UTC().Format(time.RFC3339Nano) + "|" + cursor.EventID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeCursor parses a token produced by encodeCursor
+func decodeCursor(token string) (*repo.ActivityCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, utils.Validation("invalid cursor")
+	}
+
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, utils.Validation("invalid cursor")
+	}
+
+	occurredAt, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return nil, utils.Validation("invalid cursor")
+	}
+
+	return &repo.ActivityCursor{OccurredAt: occurredAt, EventID: parts[1]}, nil
+}
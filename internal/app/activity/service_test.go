@@ -0,0 +1,49 @@
+package activity
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	// Event IDs are prefixed with their source, they are no UUIDs
+	cursor := repo.ActivityCursor{
+		OccurredAt: time.Date(2026, 10, 16, 10, 0, 0, 123456789, time.UTC),
+		EventID:    "login:7f1c1f1e-8f55-4c55-9b8e-6a4f2c0d9a11",
+	}
+
+	decoded, err := decodeCursor(encodeCursor(cursor))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.OccurredAt.Equal(cursor.OccurredAt) || decoded.EventID != cursor.EventID {
+		t.Errorf("decoded %+v, want %+v", decoded, cursor)
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	encode := func(raw string) string { return base64.RawURLEncoding.EncodeToString([]byte(raw)) }
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"not base64", "not a cursor!"},
+		{"no separator", encode("2026-10-16T10:00:00Z")},
+		{"empty ID", encode("2026-10-16T10:00:00Z|")},
+		{"invalid time", encode("yesterday|login:7f1c1f1e-8f55-4c55-9b8e-6a4f2c0d9a11")},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if cursor, err := decodeCursor(test.token); !errors.Is(err, utils.ErrValidation) {
+				t.Errorf("decoded %+v, %v, want a validation error", cursor, err)
+			}
+		})
+	}
+}
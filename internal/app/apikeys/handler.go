@@ -0,0 +1,113 @@
+package apikeys
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/respond"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+	"github.com/gorilla/mux"
+)
+
+// CreateKeyRequest represents a new API key
+type CreateKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+// KeyResponse represents an API key without its secret
+type KeyResponse struct {
+	KeyID      string     `json:"key_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// CreateKeyResponse carries the plain key, which cannot be retrieved again
+type CreateKeyResponse struct {
+	KeyResponse
+	Key string `json:"key"`
+}
+
+type Handler struct {
+	service Service
+}
+
+// Constructor function
+func NewHandler(service Service) Handler {
+	return Handler{service: service}
+}
+
+// CreateKeyHandler creates an API key for the authenticated user
+func (hd Handler) CreateKeyHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req CreateKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	key, err := hd.service.CreateKey(userInfo.UserID, req)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, key)
+}
+
+// ListKeysHandler lists the authenticated user's API keys
+func (hd Handler) ListKeysHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	keys, err := hd.service.ListKeys(userInfo.UserID)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.List(w, r, keys, respond.Pagination{Count: len(keys)})
+}
+
+// RevokeKeyHandler revokes one of the authenticated user's API keys
+func (hd Handler) RevokeKeyHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	if err := hd.service.RevokeKey(userInfo.UserID, mux.Vars(r)["keyID"]); err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// newKeyResponse maps a stored key to its response
+func newKeyResponse(key repo.APIKey) KeyResponse {
+	return KeyResponse{
+		KeyID:      key.KeyID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     key.Scopes,
+		CreatedAt:  key.CreatedAt,
+		LastUsedAt: key.LastUsedAt,
+		RevokedAt:  key.RevokedAt,
+	}
+}
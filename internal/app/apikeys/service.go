@@ -0,0 +1,125 @@
+package apikeys
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"slices"
+	"strings"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+// Scopes an API key can be granted
+const (
+	ScopeRead     = "read"
+	ScopeTransfer = "transfer"
+	ScopeMerchant = "merchant"
+)
+
+var knownScopes = []string{ScopeRead, ScopeTransfer, ScopeMerchant}
+
+const (
+	// Keys look like cbk_<prefix>_<secret>, the prefix is stored in clear to tell keys apart
+	keyPrefix       = "cbk_"
+	prefixLength    = 8
+	secretLength    = 32
+	maxKeysPerUser  = 10
+	maxKeyNameBytes = 100
+)
+
+type service struct {
+	apiKeyRepo repo.APIKeyStorer
+}
+
+type Service interface {
+	CreateKey(userID string, req CreateKeyRequest) (CreateKeyResponse, error)
+	ListKeys(userID string) ([]KeyResponse, error)
+	RevokeKey(userID, keyID string) error
+}
+
+// Constructor function
+func NewService(apiKeyRepo repo.APIKeyStorer) Service {
+	return service{apiKeyRepo: apiKeyRepo}
+}
+
+// CreateKey generates a new API key, the plain key is only ever returned here
+func (sd service) CreateKey(userID string, req CreateKeyRequest) (CreateKeyResponse, error) {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > maxKeyNameBytes {
+		return CreateKeyResponse{}, utils.Validation("name is required and must be at most 100 characters")
+	}
+	if len(req.Scopes) == 0 {
+		return CreateKeyResponse{}, utils.Validation("at least one scope is required")
+	}
+	for _, scope := range req.Scopes {
+		if !slices.Contains(knownScopes, scope) {
+			return CreateKeyResponse{}, utils.Validationf("unknown scope %q", scope)
+		}
+	}
+
+	active, err := sd.apiKeyRepo.CountActiveAPIKeys(userID)
+	if err != nil {
+		return CreateKeyResponse{}, err
+	}
+	if active >= maxKeysPerUser {
+		return CreateKeyResponse{}, utils.Conflictf("at most %d active API keys are allowed", maxKeysPerUser)
+	}
+
+	plainKey, prefix, err := generateKey()
+	if err != nil {
+		return CreateKeyResponse{}, err
+	}
+
+	slices.Sort(req.Scopes)
+	key, err := sd.apiKeyRepo.CreateAPIKey(repo.APIKey{
+		UserID:  userID,
+		Name:    req.Name,
+		Prefix:  prefix,
+		KeyHash: HashKey(plainKey),
+		Scopes:  slices.Compact(req.Scopes),
+	})
+	if err != nil {
+		return CreateKeyResponse{}, err
+	}
+
+	return CreateKeyResponse{KeyResponse: newKeyResponse(key), Key: plainKey}, nil
+}
+
+// ListKeys returns the user's API keys without their secrets
+func (sd service) ListKeys(userID string) ([]KeyResponse, error) {
+	keys, err := sd.apiKeyRepo.GetUserAPIKeys(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]KeyResponse, len(keys))
+	for i, key := range keys {
+		response[i] = newKeyResponse(key)
+	}
+	return response, nil
+}
+
+// RevokeKey disables one of the user's API keys
+func (sd service) RevokeKey(userID, keyID string) error {
+	return sd.apiKeyRepo.RevokeAPIKey(userID, keyID)
+}
+
+// HashKey returns the hex SHA-256 of a plain API key, as stored in the database
+func HashKey(plainKey string) string {
+	sum := sha256.Sum256([]byte(plainKey))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateKey returns a new random key and its display prefix
+func generateKey() (string, string, error) {
+	buf := make([]byte, prefixLength/2+secretLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	prefix := hex.EncodeToString(buf[:prefixLength/2])
+	secret := base64.RawURLEncoding.EncodeToString(buf[prefixLength/2:])
+	return keyPrefix + prefix + "_" + secret, prefix, nil
+}
@@ -0,0 +1,35 @@
+package archive
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/respond"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+// ArchiveResult represents the outcome of an archival run
+type ArchiveResult struct {
+	Cutoff               time.Time `json:"cutoff"`
+	TransactionsArchived int64     `json:"transactions_archived"`
+}
+
+type Handler struct {
+	service Service
+}
+
+// Constructor function
+func NewHandler(service Service) Handler {
+	return Handler{service: service}
+}
+
+// TriggerArchivalHandler runs transaction archival on demand, needs archive.manage
+func (hd Handler) TriggerArchivalHandler(w http.ResponseWriter, r *http.Request) {
+	result, err := hd.service.ArchiveTransactions()
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, result)
+}
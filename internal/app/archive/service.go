@@ -0,0 +1,96 @@
+package archive
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/jobs"
+	"github.com/CodeWithKrushnal/ChainBank/internal/config"
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+const (
+	archiveBatchSize = 1000
+
+	// Background job queue of the scheduled archival runs
+	archiveJobQueue    = "archive.transactions"
+	maxArchiveAttempts = 3
+)
+
+type service struct {
+	archiveRepo repo.ArchiveStorer
+	jobs        jobs.Service
+	// Prevents the scheduler and an on-demand request from archiving concurrently
+	running *sync.Mutex
+}
+
+type Service interface {
+	ArchiveTransactions() (ArchiveResult, error)
+	RunScheduler(stop <-chan struct{})
+}
+
+// Constructor function, registers the archival job queue
+func NewService(archiveRepo repo.ArchiveStorer, jobService jobs.Service) Service {
+	sd := service{
+		archiveRepo: archiveRepo,
+		jobs:        jobService,
+		running:     &sync.Mutex{},
+	}
+	jobService.Register(archiveJobQueue, maxArchiveAttempts, sd.processArchive)
+	return sd
+}
+
+// ArchiveTransactions moves transactions older than the retention period to the archive table
+func (sd service) ArchiveTransactions() (ArchiveResult, error) {
+	retentionDays := config.ConfigDetails.TransactionRetentionDays
+	if retentionDays <= 0 {
+		return ArchiveResult{}, utils.Conflict("transaction archival is disabled")
+	}
+
+	if !sd.running.TryLock() {
+		return ArchiveResult{}, utils.Conflict("archival already in progress")
+	}
+	defer sd.running.Unlock()
+
+	cutoff := time.Now().AddDate(0, 0, -retentionDays)
+	log.Printf("Archiving transactions created before %s", cutoff.Format(time.RFC3339))
+
+	archived, err := sd.archiveRepo.ArchiveTransactionsBefore(cutoff, archiveBatchSize)
+	if err != nil {
+		return ArchiveResult{Cutoff: cutoff, TransactionsArchived: archived}, err
+	}
+
+	log.Printf("Archived %d transactions", archived)
+	return ArchiveResult{Cutoff: cutoff, TransactionsArchived: archived}, nil
+}
+
+// RunScheduler queues an archival job on the configured interval until stop is closed
+func (sd service) RunScheduler(stop <-chan struct{}) {
+	interval := time.Duration(config.ConfigDetails.ArchivalIntervalHours) * time.Hour
+	if interval <= 0 || config.ConfigDetails.TransactionRetentionDays <= 0 {
+		log.Println("Scheduled transaction archival disabled")
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := sd.jobs.Enqueue(archiveJobQueue, struct{}{}); err != nil {
+				log.Printf("Error queueing archival: %v", err)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// processArchive runs an archival job
+func (sd service) processArchive(_ jobs.Attempt) error {
+	_, err := sd.ArchiveTransactions()
+	return err
+}
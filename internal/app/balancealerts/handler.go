@@ -0,0 +1,64 @@
+package balancealerts
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/respond"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+// AlertSettings represents a user's standing balance alerts in wei, an empty value turns an alert off
+type AlertSettings struct {
+	LowBalanceWei  string `json:"low_balance_wei"`
+	LargeCreditWei string `json:"large_credit_wei"`
+}
+
+type Handler struct {
+	service Service
+}
+
+// Constructor function
+func NewHandler(service Service) Handler {
+	return Handler{service: service}
+}
+
+// GetAlertsHandler returns the authenticated user's balance alerts
+func (hd Handler) GetAlertsHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	settings, err := hd.service.GetAlerts(userInfo.UserID)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, settings)
+}
+
+// UpdateAlertsHandler replaces the authenticated user's balance alerts
+func (hd Handler) UpdateAlertsHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req AlertSettings
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	settings, err := hd.service.UpdateAlerts(userInfo.UserID, req)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, settings)
+}
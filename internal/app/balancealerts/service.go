@@ -0,0 +1,149 @@
+package balancealerts
+
+import (
+	"log"
+	"math/big"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/ethereum"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/notification"
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+type service struct {
+	alertRepo     repo.BalanceAlertStorer
+	ethRepo       ethereum.EthRepo
+	notifications notification.Service
+}
+
+type Service interface {
+	GetAlerts(userID string) (AlertSettings, error)
+	UpdateAlerts(userID string, req AlertSettings) (AlertSettings, error)
+	CheckTransfer(senderUserID, senderWalletID, recipientUserID string, amount *big.Int)
+}
+
+// Constructor function
+func NewService(alertRepo repo.BalanceAlertStorer, ethRepo ethereum.EthRepo, notificationService notification.Service) Service {
+	return service{
+		alertRepo:     alertRepo,
+		ethRepo:       ethRepo,
+		notifications: notificationService,
+	}
+}
+
+// GetAlerts returns the user's alert thresholds
+func (sd service) GetAlerts(userID string) (AlertSettings, error) {
+	alert, err := sd.alertRepo.GetBalanceAlert(userID)
+	if err != nil {
+		return AlertSettings{}, err
+	}
+	return toSettings(alert), nil
+}
+
+// UpdateAlerts stores new thresholds, an empty value turns that alert off
+func (sd service) UpdateAlerts(userID string, req AlertSettings) (AlertSettings, error) {
+	alert := repo.BalanceAlert{UserID: userID}
+	var err error
+	if alert.LowBalanceWei, err = parseThreshold("low_balance_wei", req.LowBalanceWei); err != nil {
+		return AlertSettings{}, err
+	}
+	if alert.LargeCreditWei, err = parseThreshold("large_credit_wei", req.LargeCreditWei); err != nil {
+		return AlertSettings{}, err
+	}
+
+	if err := sd.alertRepo.UpsertBalanceAlert(alert); err != nil {
+		return AlertSettings{}, err
+	}
+	return toSettings(alert), nil
+}
+
+// CheckTransfer evaluates the alerts of both parties of a broadcast transfer in the background
+func (sd service) CheckTransfer(senderUserID, senderWalletID, recipientUserID string, amount *big.Int) {
+	go func() {
+		sd.checkLargeCredit(recipientUserID, amount)
+		sd.checkLowBalance(senderUserID, senderWalletID)
+	}()
+}
+
+// checkLargeCredit alerts the recipient when a single credit reaches their threshold
+func (sd service) checkLargeCredit(userID string, amount *big.Int) {
+	alert, err := sd.alertRepo.GetBalanceAlert(userID)
+	if err != nil {
+		log.Printf("Error loading balance alerts of %s: %v", userID, err)
+		return
+	}
+	if alert.LargeCreditWei == nil || amount.Cmp(alert.LargeCreditWei) < 0 {
+		return
+	}
+
+	sd.notifications.Notify(notification.Notification{
+		UserID:   userID,
+		Category: notification.CategoryBalance,
+		Title:    "Large credit received",
+		Body:     "You received %s ETH, above your alert threshold of %s ETH.",
+		BodyArgs: []any{weiToETH(amount), weiToETH(alert.LargeCreditWei)},
+		Data:     map[string]string{"amount": amount.String()},
+	})
+}
+
+// checkLowBalance alerts the sender once when the balance falls below their threshold and re-arms
+// the alert when the balance is seen above it again. The balance is read after the broadcast, so
+// on chains that do not mine instantly it may not include the transfer yet.
+func (sd service) checkLowBalance(userID, walletID string) {
+	alert, err := sd.alertRepo.GetBalanceAlert(userID)
+	if err != nil {
+		log.Printf("Error loading balance alerts of %s: %v", userID, err)
+		return
+	}
+	if alert.LowBalanceWei == nil {
+		return
+	}
+
+	balance, err := sd.ethRepo.GetBalance(walletID)
+	if err != nil {
+		log.Printf("Error fetching balance of %s for alerts: %v", walletID, err)
+		return
+	}
+
+	isLow := balance.Cmp(alert.LowBalanceWei) < 0
+	changed, err := sd.alertRepo.SetLowBalanceAlerted(userID, isLow)
+	if err != nil || !changed || !isLow {
+		return
+	}
+
+	sd.notifications.Notify(notification.Notification{
+		UserID:   userID,
+		Category: notification.CategoryBalance,
+		Title:    "Low balance",
+		Body:     "Your balance is %s ETH, below your alert threshold of %s ETH.",
+		BodyArgs: []any{weiToETH(balance), weiToETH(alert.LowBalanceWei)},
+		Data:     map[string]string{"balance": balance.String()},
+	})
+}
+
+// parseThreshold reads an optional positive amount in wei
+func parseThreshold(field, value string) (*big.Int, error) {
+	if value == "" {
+		return nil, nil
+	}
+	threshold, ok := new(big.Int).SetString(value, 10)
+	if !ok || threshold.Sign() <= 0 {
+		return nil, utils.Validationf("%s must be a positive integer", field)
+	}
+	return threshold, nil
+}
+
+func toSettings(alert repo.BalanceAlert) AlertSettings {
+	var settings AlertSettings
+	if alert.LowBalanceWei != nil {
+		settings.LowBalanceWei = alert.LowBalanceWei.String()
+	}
+	if alert.LargeCreditWei != nil {
+		settings.LargeCreditWei = alert.LargeCreditWei.String()
+	}
+	return settings
+}
+
+func weiToETH(wei *big.Int) string {
+	return new(big.Float).Quo(new(big.Float).SetInt(wei), big.NewFloat(1e18)).Text('f', -1)
+}
@@ -0,0 +1,68 @@
+package balancehistory
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/respond"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+// BalancePoint is one point of a wallet's balance history
+type BalancePoint struct {
+	Date       string `json:"date"`
+	Balance    string `json:"balance"`
+	BalanceWei string `json:"balance_wei"`
+}
+
+// SnapshotResult represents the outcome of a snapshot run
+type SnapshotResult struct {
+	Date            time.Time `json:"date"`
+	WalletsRecorded int       `json:"wallets_recorded"`
+	WalletsFailed   int       `json:"wallets_failed"`
+}
+
+type Handler struct {
+	service Service
+}
+
+// Constructor function
+func NewHandler(service Service) Handler {
+	return Handler{service: service}
+}
+
+// GetHistoryHandler returns the daily balances of the user's wallet over ?range=30d, oldest first.
+// Admins may pass userid to look at another user's wallet. The history changes once a day, so clients
+// revalidate it with the ETag.
+func (hd Handler) GetHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	days, err := parseRange(r.URL.Query().Get("range"))
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	points, err := hd.service.GetHistory(userInfo, r.URL.Query().Get("userid"), days)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.CachedList(w, r, points, respond.Pagination{Count: len(points)})
+}
+
+// TriggerSnapshotHandler records today's balances on demand, needs balance_snapshot.manage
+func (hd Handler) TriggerSnapshotHandler(w http.ResponseWriter, r *http.Request) {
+	result, err := hd.service.RecordSnapshots()
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, result)
+}
@@ -0,0 +1,177 @@
+package balancehistory
+
+import (
+	"fmt"
+	"log"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/ethereum"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/jobs"
+	"github.com/CodeWithKrushnal/ChainBank/internal/config"
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+const (
+	defaultRangeDays = 30
+	maxRangeDays     = 366
+
+	// Background job queue of the scheduled snapshots
+	snapshotJobQueue    = "balance.snapshot"
+	maxSnapshotAttempts = 5
+)
+
+type service struct {
+	balanceRepo repo.BalanceStorer
+	walletRepo  repo.WalletStorer
+	ethRepo     ethereum.EthRepo
+	jobs        jobs.Service
+	// Prevents the scheduler and an on-demand request from recording concurrently
+	running *sync.Mutex
+}
+
+type Service interface {
+	GetHistory(userInfo utils.User, queryUserID string, days int) ([]BalancePoint, error)
+	RecordSnapshots() (SnapshotResult, error)
+	RunScheduler(stop <-chan struct{})
+}
+
+// Constructor function, registers the snapshot job queue
+func NewService(balanceRepo repo.BalanceStorer, walletRepo repo.WalletStorer, ethRepo ethereum.EthRepo, jobService jobs.Service) Service {
+	sd := service{
+		balanceRepo: balanceRepo,
+		walletRepo:  walletRepo,
+		ethRepo:     ethRepo,
+		jobs:        jobService,
+		running:     &sync.Mutex{},
+	}
+	jobService.Register(snapshotJobQueue, maxSnapshotAttempts, sd.processSnapshot)
+	return sd
+}
+
+// GetHistory returns the recorded daily balances of the last days, oldest first. Days without a
+// snapshot are left out, charts carry the previous point forward.
+func (sd service) GetHistory(userInfo utils.User, queryUserID string, days int) ([]BalancePoint, error) {
+	var walletID string
+	var err error
+	if userInfo.UserRole == 3 && queryUserID != "" {
+		walletID, err = sd.walletRepo.GetWalletID("", queryUserID)
+	} else {
+		walletID, err = sd.walletRepo.GetWalletID(userInfo.UserEmail, userInfo.UserID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	today := startOfDayUTC(time.Now())
+	snapshots, err := sd.balanceRepo.GetBalanceSnapshots(walletID, today.AddDate(0, 0, 1-days))
+	if err != nil {
+		return nil, err
+	}
+
+	points := make([]BalancePoint, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		points = append(points, BalancePoint{
+			Date:       snapshot.Date.Format(time.DateOnly),
+			Balance:    new(big.Float).Quo(new(big.Float).SetInt(snapshot.Balance), big.NewFloat(1e18)).Text('f', -1),
+			BalanceWei: snapshot.Balance.String(),
+		})
+	}
+	return points, nil
+}
+
+// RecordSnapshots stores today's on-chain balance of every open wallet. A wallet whose balance
+// cannot be fetched is skipped and picked up again by the next run of the day.
+func (sd service) RecordSnapshots() (SnapshotResult, error) {
+	if !sd.running.TryLock() {
+		return SnapshotResult{}, utils.Conflict("balance snapshot already in progress")
+	}
+	defer sd.running.Unlock()
+
+	result := SnapshotResult{Date: startOfDayUTC(time.Now())}
+	walletIDs, err := sd.balanceRepo.GetActiveWalletIDs()
+	if err != nil {
+		return result, err
+	}
+
+	for _, walletID := range walletIDs {
+		balance, err := sd.ethRepo.GetBalance(walletID)
+		if err == nil {
+			err = sd.balanceRepo.SaveBalanceSnapshot(repo.BalanceSnapshot{WalletID: walletID, Date: result.Date, Balance: balance})
+		}
+		if err != nil {
+			log.Printf("Error recording balance snapshot of %s: %v", walletID, err)
+			result.WalletsFailed++
+			continue
+		}
+		result.WalletsRecorded++
+	}
+
+	log.Printf("Recorded %d balance snapshots, %d failed", result.WalletsRecorded, result.WalletsFailed)
+	return result, nil
+}
+
+// RunScheduler queues a snapshot job once a day at the configured UTC hour until stop is closed
+func (sd service) RunScheduler(stop <-chan struct{}) {
+	hour := config.ConfigDetails.BalanceSnapshotHourUTC
+	if hour < 0 {
+		log.Println("Scheduled balance snapshots disabled")
+		return
+	}
+
+	for {
+		timer := time.NewTimer(time.Until(nextSnapshotTime(time.Now(), hour)))
+		select {
+		case <-timer.C:
+			if err := sd.jobs.Enqueue(snapshotJobQueue, struct{}{}); err != nil {
+				log.Printf("Error queueing balance snapshot: %v", err)
+			}
+		case <-stop:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// processSnapshot runs a snapshot job, it fails while wallets are left without today's snapshot so
+// that the retry picks them up
+func (sd service) processSnapshot(_ jobs.Attempt) error {
+	result, err := sd.RecordSnapshots()
+	if err != nil {
+		return err
+	}
+	if result.WalletsFailed > 0 {
+		return fmt.Errorf("%d wallets failed", result.WalletsFailed)
+	}
+	return nil
+}
+
+// parseRange turns a range such as 30d into a number of days, 30 days when empty
+func parseRange(value string) (int, error) {
+	if value == "" {
+		return defaultRangeDays, nil
+	}
+	days, err := strconv.Atoi(strings.TrimSuffix(value, "d"))
+	if err != nil || !strings.HasSuffix(value, "d") || days < 1 || days > maxRangeDays {
+		return 0, utils.Validationf("range must be between 1d and %dd", maxRangeDays)
+	}
+	return days, nil
+}
+
+func startOfDayUTC(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// nextSnapshotTime returns the next occurrence of hour:00 UTC after now
+func nextSnapshotTime(now time.Time, hour int) time.Time {
+	next := startOfDayUTC(now).Add(time.Duration(hour) * time.Hour)
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
@@ -0,0 +1,163 @@
+package banklinks
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/respond"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+	"github.com/gorilla/mux"
+)
+
+// LinkTokenResponse carries the token the client opens the provider's linking flow with
+type LinkTokenResponse struct {
+	LinkToken string    `json:"link_token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// LinkAccountRequest carries the public token the provider's linking flow ended with
+type LinkAccountRequest struct {
+	PublicToken string `json:"public_token"`
+}
+
+// BankLinkResponse represents a linked bank account, mask is the last digits of its number
+type BankLinkResponse struct {
+	LinkID          string    `json:"link_id"`
+	InstitutionName string    `json:"institution_name"`
+	Mask            string    `json:"mask"`
+	AccountType     string    `json:"account_type"`
+	Currency        string    `json:"currency"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// IncomeStreamResponse is a recurring source of income, amounts are decimals in the account currency
+type IncomeStreamResponse struct {
+	Source        string `json:"source"`
+	Frequency     string `json:"frequency"`
+	MonthlyAmount string `json:"monthly_amount"`
+}
+
+// AccountSummary is the balance of a linked account and the average monthly income paid into it over
+// the last income_months months
+type AccountSummary struct {
+	BankLinkResponse
+	CurrentBalance   string                 `json:"current_balance"`
+	AvailableBalance string                 `json:"available_balance"`
+	MonthlyIncome    string                 `json:"monthly_income"`
+	IncomeMonths     int                    `json:"income_months"`
+	IncomeStreams    []IncomeStreamResponse `json:"income_streams"`
+}
+
+// CurrencyTotal adds up the linked accounts of one currency
+type CurrencyTotal struct {
+	Currency         string `json:"currency"`
+	CurrentBalance   string `json:"current_balance"`
+	AvailableBalance string `json:"available_balance"`
+	MonthlyIncome    string `json:"monthly_income"`
+}
+
+// FinancialSummary is the balance and income of all the user's linked accounts
+type FinancialSummary struct {
+	Accounts []AccountSummary `json:"accounts"`
+	Totals   []CurrencyTotal  `json:"totals"`
+}
+
+type Handler struct {
+	service Service
+}
+
+// Constructor function
+func NewHandler(service Service) Handler {
+	return Handler{service: service}
+}
+
+// CreateLinkTokenHandler starts linking a bank account of the authenticated user
+func (hd Handler) CreateLinkTokenHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	token, err := hd.service.CreateLinkToken(r.Context(), userInfo.UserID)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, token)
+}
+
+// LinkAccountHandler links the bank account the authenticated user picked in the provider's flow
+func (hd Handler) LinkAccountHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req LinkAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	link, err := hd.service.LinkAccount(r.Context(), userInfo.UserID, req)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, link)
+}
+
+// ListLinksHandler lists the bank accounts of the authenticated user
+func (hd Handler) ListLinksHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	links, err := hd.service.ListLinks(userInfo.UserID)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.List(w, r, links, respond.Pagination{Count: len(links)})
+}
+
+// UnlinkAccountHandler unlinks a bank account of the authenticated user
+func (hd Handler) UnlinkAccountHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	if err := hd.service.UnlinkAccount(r.Context(), userInfo.UserID, mux.Vars(r)["linkID"]); err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetSummaryHandler returns the balances and income of the authenticated user's linked accounts
+func (hd Handler) GetSummaryHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	summary, err := hd.service.GetSummary(r.Context(), userInfo.UserID)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, summary)
+}
@@ -0,0 +1,101 @@
+package banklinks
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Prefixes of the tokens the mock provider issues. Any public token with the prefix links an account,
+// the same token always links the same account with the same data.
+const (
+	mockLinkTokenPrefix   = "link-mock-"
+	mockPublicTokenPrefix = "public-mock-"
+	mockAccessTokenPrefix = "access-mock-"
+	mockLinkTokenTTL      = 30 * time.Minute
+	mockIncomeMonths      = 6
+)
+
+var mockInstitutions = []string{"State Bank of India", "HDFC Bank", "ICICI Bank", "Axis Bank", "Kotak Mahindra Bank"}
+
+// mockProvider generates accounts, balances and income from the tokens it is given
+type mockProvider struct{}
+
+func (mockProvider) CreateLinkToken(ctx context.Context, userID string) (LinkToken, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return LinkToken{}, fmt.Errorf("error generating link token: %w", err)
+	}
+	return LinkToken{Token: mockLinkTokenPrefix + hex.EncodeToString(random), ExpiresAt: time.Now().Add(mockLinkTokenTTL)}, nil
+}
+
+func (mockProvider) ExchangePublicToken(ctx context.Context, publicToken string) (Account, error) {
+	if !strings.HasPrefix(publicToken, mockPublicTokenPrefix) || len(publicToken) == len(mockPublicTokenPrefix) {
+		return Account{}, ErrInvalidPublicToken
+	}
+
+	seed := sha256.Sum256([]byte(publicToken))
+	return Account{
+		AccessToken:     mockAccessTokenPrefix + hex.EncodeToString(seed[:16]),
+		AccountID:       "mock-account-" + hex.EncodeToString(seed[16:24]),
+		InstitutionName: mockInstitutions[int(seed[24])%len(mockInstitutions)],
+		Mask:            fmt.Sprintf("%04d", binary.BigEndian.Uint16(seed[25:27])%10000),
+		Type:            []string{"checking", "savings"}[seed[27]%2],
+		Currency:        "INR",
+	}, nil
+}
+
+func (mockProvider) GetBalance(ctx context.Context, accessToken string) (Balance, error) {
+	seed, err := mockSeed(accessToken)
+	if err != nil {
+		return Balance{}, err
+	}
+	// Between 5,000 and 505,000 rupees, up to a tenth of it pending
+	current := 500_000 + int64(binary.BigEndian.Uint32(seed[0:4])%50_000_000)
+	pending := current * int64(seed[4]%10) / 100
+	return Balance{Current: current, Available: current - pending}, nil
+}
+
+func (mockProvider) GetIncome(ctx context.Context, accessToken string) (Income, error) {
+	seed, err := mockSeed(accessToken)
+	if err != nil {
+		return Income{}, err
+	}
+	// A salary between 20,000 and 220,000 rupees a month, every other account has freelance income on top
+	streams := []IncomeStream{{
+		Source:        "Salary",
+		Frequency:     "monthly",
+		MonthlyAmount: 2_000_000 + int64(binary.BigEndian.Uint32(seed[8:12])%20_000_000),
+	}}
+	if seed[12]%2 == 0 {
+		streams = append(streams, IncomeStream{
+			Source:        "Freelance payments",
+			Frequency:     "irregular",
+			MonthlyAmount: 100_000 + int64(binary.BigEndian.Uint32(seed[13:17])%5_000_000),
+		})
+	}
+
+	var total int64
+	for _, stream := range streams {
+		total += stream.MonthlyAmount
+	}
+	return Income{Months: mockIncomeMonths, MonthlyAverage: total, Streams: streams}, nil
+}
+
+func (mockProvider) RemoveAccess(ctx context.Context, accessToken string) error {
+	_, err := mockSeed(accessToken)
+	return err
+}
+
+// mockSeed derives the generated data of an account from its access token
+func mockSeed(accessToken string) ([32]byte, error) {
+	if !strings.HasPrefix(accessToken, mockAccessTokenPrefix) {
+		return [32]byte{}, fmt.Errorf("access token was not issued by the mock provider")
+	}
+	return sha256.Sum256([]byte(accessToken)), nil
+}
@@ -0,0 +1,76 @@
+package banklinks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Supported bank data providers
+const (
+	// ProviderMock serves generated accounts for development, no bank is contacted
+	ProviderMock = "mock"
+)
+
+// ErrInvalidPublicToken is returned by a provider for a public token it did not issue or that expired
+var ErrInvalidPublicToken = errors.New("invalid public token")
+
+// LinkToken starts the provider's hosted linking flow on the client. The flow ends with a public token
+// the client sends back to be exchanged.
+type LinkToken struct {
+	Token     string
+	ExpiresAt time.Time
+}
+
+// Account is a bank account the user granted access to, AccessToken reads its data from then on
+type Account struct {
+	AccessToken     string
+	AccountID       string
+	InstitutionName string
+	Mask            string
+	Type            string
+	Currency        string
+}
+
+// Balance is what an account holds now in the minor unit of its currency, Available leaves out
+// pending debits
+type Balance struct {
+	Current   int64
+	Available int64
+}
+
+// IncomeStream is a recurring deposit the provider recognized as income, amounts of income are in the
+// minor unit of the account currency as well
+type IncomeStream struct {
+	Source        string
+	Frequency     string
+	MonthlyAmount int64
+}
+
+// Income summarizes the income paid into an account over the last Months months
+type Income struct {
+	Months         int
+	MonthlyAverage int64
+	Streams        []IncomeStream
+}
+
+// Provider links bank accounts through its hosted flow and reads their balances and income, in the
+// style of Plaid: a link token starts the flow, the public token it returns is exchanged for an access
+// token kept on our side.
+type Provider interface {
+	CreateLinkToken(ctx context.Context, userID string) (LinkToken, error)
+	ExchangePublicToken(ctx context.Context, publicToken string) (Account, error)
+	GetBalance(ctx context.Context, accessToken string) (Balance, error)
+	GetIncome(ctx context.Context, accessToken string) (Income, error)
+	RemoveAccess(ctx context.Context, accessToken string) error
+}
+
+// newProvider returns the provider of a supported name
+func newProvider(name string) (Provider, error) {
+	switch name {
+	case ProviderMock:
+		return mockProvider{}, nil
+	}
+	return nil, fmt.Errorf("unknown bank data provider %q", name)
+}
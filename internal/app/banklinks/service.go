@@ -0,0 +1,257 @@
+package banklinks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/config"
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+// A user can link at most this many bank accounts at a time
+const maxLinks = 10
+
+type service struct {
+	linkRepo repo.BankLinkStorer
+	// nil while bank linking is disabled
+	provider     Provider
+	providerName string
+}
+
+type Service interface {
+	CreateLinkToken(ctx context.Context, userID string) (LinkTokenResponse, error)
+	LinkAccount(ctx context.Context, userID string, req LinkAccountRequest) (BankLinkResponse, error)
+	ListLinks(userID string) ([]BankLinkResponse, error)
+	UnlinkAccount(ctx context.Context, userID, linkID string) error
+	GetSummary(ctx context.Context, userID string) (FinancialSummary, error)
+}
+
+// Constructor function, bank linking stays disabled while no provider is configured
+func NewService(linkRepo repo.BankLinkStorer) Service {
+	sd := service{linkRepo: linkRepo}
+	name := config.ConfigDetails.BankLinkProvider
+	if name == "" {
+		return sd
+	}
+
+	provider, err := newProvider(name)
+	if err != nil {
+		log.Printf("Bank linking disabled: %v", err)
+		return sd
+	}
+	sd.provider, sd.providerName = provider, name
+	return sd
+}
+
+// CreateLinkToken starts the provider's linking flow for the user
+func (sd service) CreateLinkToken(ctx context.Context, userID string) (LinkTokenResponse, error) {
+	if sd.provider == nil {
+		return LinkTokenResponse{}, utils.NotFound("bank linking is not configured", nil)
+	}
+
+	token, err := sd.provider.CreateLinkToken(ctx, userID)
+	if err != nil {
+		return LinkTokenResponse{}, utils.Upstream("bank data provider unavailable", err)
+	}
+	return LinkTokenResponse{LinkToken: token.Token, ExpiresAt: token.ExpiresAt}, nil
+}
+
+// LinkAccount exchanges the public token the linking flow ended with and records the account
+func (sd service) LinkAccount(ctx context.Context, userID string, req LinkAccountRequest) (BankLinkResponse, error) {
+	if sd.provider == nil {
+		return BankLinkResponse{}, utils.NotFound("bank linking is not configured", nil)
+	}
+	publicToken := strings.TrimSpace(req.PublicToken)
+	if publicToken == "" {
+		return BankLinkResponse{}, utils.Validation("public_token is required")
+	}
+
+	links, err := sd.linkRepo.GetBankLinks(userID)
+	if err != nil {
+		return BankLinkResponse{}, err
+	}
+	if len(links) >= maxLinks {
+		return BankLinkResponse{}, utils.Conflictf("at most %d bank accounts can be linked", maxLinks)
+	}
+
+	account, err := sd.provider.ExchangePublicToken(ctx, publicToken)
+	if errors.Is(err, ErrInvalidPublicToken) {
+		return BankLinkResponse{}, utils.Validation("invalid public token")
+	}
+	if err != nil {
+		return BankLinkResponse{}, utils.Upstream("bank data provider unavailable", err)
+	}
+
+	link, err := sd.linkRepo.CreateBankLink(repo.BankLink{
+		UserID:            userID,
+		Provider:          sd.providerName,
+		ProviderAccountID: account.AccountID,
+		InstitutionName:   account.InstitutionName,
+		Mask:              account.Mask,
+		AccountType:       account.Type,
+		Currency:          account.Currency,
+	}, account.AccessToken)
+	if err != nil {
+		return BankLinkResponse{}, err
+	}
+
+	log.Printf("User %s linked bank account %s at %s", userID, link.LinkID, link.InstitutionName)
+	return newBankLinkResponse(link), nil
+}
+
+// ListLinks returns the bank accounts the user has linked
+func (sd service) ListLinks(userID string) ([]BankLinkResponse, error) {
+	links, err := sd.linkRepo.GetBankLinks(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]BankLinkResponse, len(links))
+	for i, link := range links {
+		response[i] = newBankLinkResponse(link)
+	}
+	return response, nil
+}
+
+// UnlinkAccount revokes the provider's access to an account of the user and forgets its token. The
+// account is unlinked on our side even when the provider cannot be reached.
+func (sd service) UnlinkAccount(ctx context.Context, userID, linkID string) error {
+	link, err := sd.userLink(userID, linkID)
+	if err != nil {
+		return err
+	}
+
+	if sd.provider != nil && link.Provider == sd.providerName {
+		accessToken, err := sd.linkRepo.GetAccessToken(link.LinkID)
+		if err == nil {
+			err = sd.provider.RemoveAccess(ctx, accessToken)
+		}
+		if err != nil {
+			log.Printf("Error revoking provider access of bank link %s: %v", link.LinkID, err)
+		}
+	}
+
+	if err := sd.linkRepo.UnlinkBankLink(link.LinkID); err != nil {
+		return err
+	}
+	log.Printf("User %s unlinked bank account %s", userID, link.LinkID)
+	return nil
+}
+
+// GetSummary pulls the current balance and recent income of every account the user linked, with
+// totals per currency. Income verification and credit scoring read the user's finances from here.
+func (sd service) GetSummary(ctx context.Context, userID string) (FinancialSummary, error) {
+	if sd.provider == nil {
+		return FinancialSummary{}, utils.NotFound("bank linking is not configured", nil)
+	}
+	links, err := sd.linkRepo.GetBankLinks(userID)
+	if err != nil {
+		return FinancialSummary{}, err
+	}
+
+	summary := FinancialSummary{Accounts: []AccountSummary{}, Totals: []CurrencyTotal{}}
+	totals := map[string]*currencySums{}
+	var currencies []string
+	for _, link := range links {
+		// Accounts linked through a provider no longer configured cannot be read
+		if link.Provider != sd.providerName {
+			continue
+		}
+		accessToken, err := sd.linkRepo.GetAccessToken(link.LinkID)
+		if err != nil {
+			return FinancialSummary{}, err
+		}
+		balance, err := sd.provider.GetBalance(ctx, accessToken)
+		if err != nil {
+			return FinancialSummary{}, utils.Upstream("bank data provider unavailable", fmt.Errorf("balance of %s: %w", link.LinkID, err))
+		}
+		income, err := sd.provider.GetIncome(ctx, accessToken)
+		if err != nil {
+			return FinancialSummary{}, utils.Upstream("bank data provider unavailable", fmt.Errorf("income of %s: %w", link.LinkID, err))
+		}
+
+		summary.Accounts = append(summary.Accounts, newAccountSummary(link, balance, income))
+		sums, ok := totals[link.Currency]
+		if !ok {
+			sums = &currencySums{}
+			totals[link.Currency] = sums
+			currencies = append(currencies, link.Currency)
+		}
+		sums.current += balance.Current
+		sums.available += balance.Available
+		sums.monthlyIncome += income.MonthlyAverage
+	}
+
+	for _, currency := range currencies {
+		sums := totals[currency]
+		summary.Totals = append(summary.Totals, CurrencyTotal{
+			Currency:         currency,
+			CurrentBalance:   formatMinor(sums.current),
+			AvailableBalance: formatMinor(sums.available),
+			MonthlyIncome:    formatMinor(sums.monthlyIncome),
+		})
+	}
+	return summary, nil
+}
+
+// userLink returns a linked account of the user, accounts of others are not disclosed
+func (sd service) userLink(userID, linkID string) (repo.BankLink, error) {
+	link, err := sd.linkRepo.GetBankLink(linkID)
+	if err != nil {
+		return link, err
+	}
+	if link.UserID != userID {
+		return link, utils.NotFound("bank link not found", nil)
+	}
+	return link, nil
+}
+
+// currencySums adds up the accounts of one currency in minor units
+type currencySums struct {
+	current       int64
+	available     int64
+	monthlyIncome int64
+}
+
+// formatMinor formats an amount in minor units as a decimal with two places
+func formatMinor(amount int64) string {
+	sign := ""
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, amount/100, amount%100)
+}
+
+func newBankLinkResponse(link repo.BankLink) BankLinkResponse {
+	return BankLinkResponse{
+		LinkID:          link.LinkID,
+		InstitutionName: link.InstitutionName,
+		Mask:            link.Mask,
+		AccountType:     link.AccountType,
+		Currency:        link.Currency,
+		CreatedAt:       link.CreatedAt,
+	}
+}
+
+func newAccountSummary(link repo.BankLink, balance Balance, income Income) AccountSummary {
+	streams := make([]IncomeStreamResponse, len(income.Streams))
+	for i, stream := range income.Streams {
+		streams[i] = IncomeStreamResponse{
+			Source:        stream.Source,
+			Frequency:     stream.Frequency,
+			MonthlyAmount: formatMinor(stream.MonthlyAmount),
+		}
+	}
+	return AccountSummary{
+		BankLinkResponse: newBankLinkResponse(link),
+		CurrentBalance:   formatMinor(balance.Current),
+		AvailableBalance: formatMinor(balance.Available),
+		MonthlyIncome:    formatMinor(income.MonthlyAverage),
+		IncomeMonths:     income.Months,
+		IncomeStreams:    streams,
+	}
+}
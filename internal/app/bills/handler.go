@@ -0,0 +1,133 @@
+package bills
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/respond"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+	"github.com/gorilla/mux"
+)
+
+// CreateBillRequest splits amount wei among the participants, either evenly or by the amount
+// set for every participant. Shares expire after a week unless expires_at is set.
+type CreateBillRequest struct {
+	Title        string             `json:"title"`
+	Amount       string             `json:"amount"`
+	Participants []ParticipantShare `json:"participants"`
+	ExpiresAt    *time.Time         `json:"expires_at"`
+}
+
+// ParticipantShare is a participant of a bill and the wei they owe
+type ParticipantShare struct {
+	UserID string `json:"user_id"`
+	Amount string `json:"amount,omitempty"`
+}
+
+// BillResponse represents a bill and the progress of its shares, amounts in wei
+type BillResponse struct {
+	BillID            string      `json:"bill_id"`
+	Title             string      `json:"title"`
+	TotalAmount       string      `json:"total_amount"`
+	Status            string      `json:"status"`
+	ParticipantCount  int         `json:"participant_count"`
+	PaidCount         int         `json:"paid_count"`
+	AmountPaid        string      `json:"amount_paid"`
+	AmountOutstanding string      `json:"amount_outstanding"`
+	Shares            []BillShare `json:"shares"`
+	CreatedAt         time.Time   `json:"created_at"`
+}
+
+// BillShare is the payment request of one participant
+type BillShare struct {
+	RequestID string     `json:"request_id"`
+	UserID    string     `json:"user_id"`
+	Amount    string     `json:"amount"`
+	Status    string     `json:"status"`
+	TxHash    string     `json:"transaction_hash,omitempty"`
+	PaidAt    *time.Time `json:"paid_at,omitempty"`
+}
+
+type Handler struct {
+	service Service
+}
+
+// Constructor function
+func NewHandler(service Service) Handler {
+	return Handler{service: service}
+}
+
+// CreateBillHandler splits a bill among participants and requests their shares
+func (hd Handler) CreateBillHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req CreateBillRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	bill, err := hd.service.CreateBill(userInfo.UserID, req)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, bill)
+}
+
+// ListBillsHandler lists the bills the authenticated user organized
+func (hd Handler) ListBillsHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	bills, err := hd.service.ListBills(userInfo.UserID)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.List(w, r, bills, respond.Pagination{Count: len(bills)})
+}
+
+// GetBillHandler returns the live status of a bill of the authenticated user
+func (hd Handler) GetBillHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	bill, err := hd.service.GetBill(userInfo.UserID, mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, bill)
+}
+
+// CancelBillHandler cancels the unpaid shares of a bill of the authenticated user
+func (hd Handler) CancelBillHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	bill, err := hd.service.CancelBill(userInfo.UserID, mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, bill)
+}
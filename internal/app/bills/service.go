@@ -0,0 +1,293 @@
+package bills
+
+import (
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/notification"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/paymentrequests"
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+// Bill statuses, derived from the statuses of its shares
+const (
+	billOpen          = "open"
+	billPartiallyPaid = "partially_paid"
+	billPaid          = "paid"
+	billClosed        = "closed"
+)
+
+const (
+	defaultExpiry   = 7 * 24 * time.Hour
+	maxExpiry       = 30 * 24 * time.Hour
+	maxParticipants = 50
+	maxTitleLength  = 100
+	billListLimit   = 100
+)
+
+type service struct {
+	billRepo           repo.BillStorer
+	paymentRequestRepo repo.PaymentRequestStorer
+	walletRepo         repo.WalletStorer
+	notifications      notification.Service
+}
+
+type Service interface {
+	CreateBill(userID string, req CreateBillRequest) (BillResponse, error)
+	GetBill(userID, billID string) (BillResponse, error)
+	ListBills(userID string) ([]BillResponse, error)
+	CancelBill(userID, billID string) (BillResponse, error)
+}
+
+// Constructor function
+func NewService(billRepo repo.BillStorer, paymentRequestRepo repo.PaymentRequestStorer, walletRepo repo.WalletStorer, notificationService notification.Service) Service {
+	return service{
+		billRepo:           billRepo,
+		paymentRequestRepo: paymentRequestRepo,
+		walletRepo:         walletRepo,
+		notifications:      notificationService,
+	}
+}
+
+// CreateBill splits a bill among the participants and sends each of them a payment request for
+// their share. Without amounts per participant the total is split evenly, the remainder in wei
+// going to the first participants.
+func (sd service) CreateBill(userID string, req CreateBillRequest) (BillResponse, error) {
+	title := strings.TrimSpace(req.Title)
+	if title == "" || len(title) > maxTitleLength {
+		return BillResponse{}, utils.Validationf("title is required and must be at most %d characters", maxTitleLength)
+	}
+
+	shares, total, err := splitShares(req)
+	if err != nil {
+		return BillResponse{}, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(defaultExpiry)
+	if req.ExpiresAt != nil {
+		expiresAt = *req.ExpiresAt
+		if !expiresAt.After(now) || expiresAt.After(now.Add(maxExpiry)) {
+			return BillResponse{}, utils.Validationf("expires_at must be in the future and within %d days", int(maxExpiry.Hours()/24))
+		}
+	}
+
+	if _, err := sd.walletRepo.GetWalletID("", userID); err != nil {
+		return BillResponse{}, utils.NotFound("wallet not found", err)
+	}
+	requests := make([]repo.PaymentRequest, len(shares))
+	for i, share := range shares {
+		if share.UserID == userID {
+			return BillResponse{}, utils.Validation("the organizer cannot be a participant")
+		}
+		if _, err := sd.walletRepo.GetWalletID("", share.UserID); err != nil {
+			return BillResponse{}, utils.NotFound("participant wallet not found", err)
+		}
+		requests[i] = repo.PaymentRequest{
+			PayerUserID: share.UserID,
+			Amount:      share.Amount,
+			Memo:        title,
+			ExpiresAt:   expiresAt.UTC().Truncate(time.Second),
+		}
+	}
+
+	bill, requests, err := sd.billRepo.CreateBill(repo.Bill{
+		OrganizerUserID: userID,
+		Title:           title,
+		TotalAmount:     total.String(),
+	}, requests)
+	if err != nil {
+		return BillResponse{}, err
+	}
+
+	for _, request := range requests {
+		sd.notifyParticipant(bill, request)
+	}
+	return newBillResponse(bill, requests), nil
+}
+
+// GetBill returns the current status of a bill the user organized
+func (sd service) GetBill(userID, billID string) (BillResponse, error) {
+	bill, err := sd.organizedBill(userID, billID)
+	if err != nil {
+		return BillResponse{}, err
+	}
+	requests, err := sd.paymentRequestRepo.GetBillPaymentRequests(bill.BillID)
+	if err != nil {
+		return BillResponse{}, err
+	}
+	return newBillResponse(bill, requests), nil
+}
+
+// ListBills returns the latest bills the user organized with their current status
+func (sd service) ListBills(userID string) ([]BillResponse, error) {
+	bills, err := sd.billRepo.GetBills(userID, billListLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]BillResponse, len(bills))
+	for i, bill := range bills {
+		requests, err := sd.paymentRequestRepo.GetBillPaymentRequests(bill.BillID)
+		if err != nil {
+			return nil, err
+		}
+		response[i] = newBillResponse(bill, requests)
+	}
+	return response, nil
+}
+
+// CancelBill cancels the shares that are still open, paid shares stay paid
+func (sd service) CancelBill(userID, billID string) (BillResponse, error) {
+	bill, err := sd.organizedBill(userID, billID)
+	if err != nil {
+		return BillResponse{}, err
+	}
+	if _, err := sd.paymentRequestRepo.CancelBillPaymentRequests(bill.BillID); err != nil {
+		return BillResponse{}, err
+	}
+
+	requests, err := sd.paymentRequestRepo.GetBillPaymentRequests(bill.BillID)
+	if err != nil {
+		return BillResponse{}, err
+	}
+	return newBillResponse(bill, requests), nil
+}
+
+// organizedBill returns the bill if the user organized it, bills of others are not disclosed
+func (sd service) organizedBill(userID, billID string) (repo.Bill, error) {
+	bill, err := sd.billRepo.GetBill(billID)
+	if err != nil {
+		return bill, err
+	}
+	if bill.OrganizerUserID != userID {
+		return bill, utils.NotFound("bill not found", nil)
+	}
+	return bill, nil
+}
+
+// notifyParticipant asks a participant to pay their share
+func (sd service) notifyParticipant(bill repo.Bill, request repo.PaymentRequest) {
+	amount, _ := new(big.Int).SetString(request.Amount, 10)
+	ethAmount := new(big.Float).Quo(new(big.Float).SetInt(amount), big.NewFloat(1e18)).Text('f', -1)
+
+	sd.notifications.Notify(notification.Notification{
+		UserID:   request.PayerUserID,
+		Category: notification.CategoryPaymentRequest,
+		Title:    "Payment requested",
+		Body:     "You were asked to pay %s ETH for %s.",
+		BodyArgs: []any{ethAmount, bill.Title},
+		Data:     map[string]string{"payment_request_id": request.RequestID, "bill_id": bill.BillID, "amount": request.Amount},
+	})
+}
+
+// splitShares validates the participants and returns the share of each along with the total
+func splitShares(req CreateBillRequest) ([]ParticipantShare, *big.Int, error) {
+	if len(req.Participants) == 0 || len(req.Participants) > maxParticipants {
+		return nil, nil, utils.Validationf("a bill needs between 1 and %d participants", maxParticipants)
+	}
+
+	seen := map[string]bool{}
+	withAmount := 0
+	for _, participant := range req.Participants {
+		if participant.UserID == "" {
+			return nil, nil, utils.Validation("user_id is required for every participant")
+		}
+		if seen[participant.UserID] {
+			return nil, nil, utils.Validation("participants must be distinct")
+		}
+		seen[participant.UserID] = true
+		if participant.Amount != "" {
+			withAmount++
+		}
+	}
+
+	shares := make([]ParticipantShare, len(req.Participants))
+	total := new(big.Int)
+
+	switch withAmount {
+	case len(req.Participants):
+		for i, participant := range req.Participants {
+			amount, ok := new(big.Int).SetString(participant.Amount, 10)
+			if !ok || amount.Sign() <= 0 {
+				return nil, nil, utils.Validation("amount must be a positive integer in wei")
+			}
+			total.Add(total, amount)
+			shares[i] = ParticipantShare{UserID: participant.UserID, Amount: amount.String()}
+		}
+		if req.Amount != "" && req.Amount != total.String() {
+			return nil, nil, utils.Validation("amount must equal the sum of the participant amounts")
+		}
+
+	case 0:
+		if _, ok := total.SetString(req.Amount, 10); !ok || total.Sign() <= 0 {
+			return nil, nil, utils.Validation("amount must be a positive integer in wei")
+		}
+		count := big.NewInt(int64(len(req.Participants)))
+		each, remainder := new(big.Int).QuoRem(total, count, new(big.Int))
+		if each.Sign() == 0 {
+			return nil, nil, utils.Validation("amount is too small to split among the participants")
+		}
+		for i, participant := range req.Participants {
+			amount := new(big.Int).Set(each)
+			if int64(i) < remainder.Int64() {
+				amount.Add(amount, big.NewInt(1))
+			}
+			shares[i] = ParticipantShare{UserID: participant.UserID, Amount: amount.String()}
+		}
+
+	default:
+		return nil, nil, utils.Validation("set the amount of every participant or of none")
+	}
+	return shares, total, nil
+}
+
+// newBillResponse summarizes the shares of a bill, shares that are being paid count as outstanding
+func newBillResponse(bill repo.Bill, requests []repo.PaymentRequest) BillResponse {
+	response := BillResponse{
+		BillID:           bill.BillID,
+		Title:            bill.Title,
+		TotalAmount:      bill.TotalAmount,
+		ParticipantCount: len(requests),
+		Shares:           make([]BillShare, len(requests)),
+		CreatedAt:        bill.CreatedAt,
+	}
+
+	paid, outstanding := new(big.Int), new(big.Int)
+	for i, request := range requests {
+		status := paymentrequests.EffectiveStatus(request)
+		amount, _ := new(big.Int).SetString(request.Amount, 10)
+		switch status {
+		case paymentrequests.StatusPaid:
+			response.PaidCount++
+			paid.Add(paid, amount)
+		case paymentrequests.StatusOpen, paymentrequests.StatusPaying:
+			outstanding.Add(outstanding, amount)
+		}
+
+		response.Shares[i] = BillShare{
+			RequestID: request.RequestID,
+			UserID:    request.PayerUserID,
+			Amount:    request.Amount,
+			Status:    status,
+			TxHash:    request.TxHash,
+			PaidAt:    request.PaidAt,
+		}
+	}
+	response.AmountPaid = paid.String()
+	response.AmountOutstanding = outstanding.String()
+
+	switch {
+	case response.PaidCount == len(requests):
+		response.Status = billPaid
+	case outstanding.Sign() > 0 && response.PaidCount > 0:
+		response.Status = billPartiallyPaid
+	case outstanding.Sign() > 0:
+		response.Status = billOpen
+	default:
+		response.Status = billClosed
+	}
+	return response
+}
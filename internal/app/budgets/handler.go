@@ -0,0 +1,89 @@
+package budgets
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/respond"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+// SetBudgetRequest sets the monthly spending limit in wei
+type SetBudgetRequest struct {
+	MonthlyLimitWei string `json:"monthly_limit_wei"`
+}
+
+// BudgetResponse represents a budget along with what was spent against it this month, months
+// start at midnight in time_zone
+type BudgetResponse struct {
+	Month           string `json:"month"`
+	MonthlyLimitWei string `json:"monthly_limit_wei"`
+	SpentWei        string `json:"spent_wei"`
+	PercentUsed     int    `json:"percent_used"`
+	TimeZone        string `json:"time_zone"`
+}
+
+type Handler struct {
+	service Service
+}
+
+// Constructor function
+func NewHandler(service Service) Handler {
+	return Handler{service: service}
+}
+
+// GetBudgetHandler returns the authenticated user's budget and this month's spending
+func (hd Handler) GetBudgetHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	budget, err := hd.service.GetBudget(userInfo.UserID)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, budget)
+}
+
+// SetBudgetHandler creates or replaces the authenticated user's monthly budget
+func (hd Handler) SetBudgetHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req SetBudgetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	budget, err := hd.service.SetBudget(userInfo.UserID, req)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, budget)
+}
+
+// DeleteBudgetHandler removes the authenticated user's budget
+func (hd Handler) DeleteBudgetHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	if err := hd.service.DeleteBudget(userInfo.UserID); err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
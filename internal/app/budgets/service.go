@@ -0,0 +1,179 @@
+package budgets
+
+import (
+	"log"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/notification"
+	"github.com/CodeWithKrushnal/ChainBank/internal/config"
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+// Percentages of the monthly budget that trigger an alert, highest last
+var alertThresholds = []int{80, 100}
+
+const maxPercentUsed = 1000
+
+type service struct {
+	budgetRepo      repo.BudgetStorer
+	transactionRepo repo.TransactionStorer
+	userRepo        repo.UserStorer
+	notifications   notification.Service
+}
+
+type Service interface {
+	GetBudget(userID string) (BudgetResponse, error)
+	SetBudget(userID string, req SetBudgetRequest) (BudgetResponse, error)
+	DeleteBudget(userID string) error
+	RunScheduler(stop <-chan struct{})
+}
+
+// Constructor function
+func NewService(budgetRepo repo.BudgetStorer, transactionRepo repo.TransactionStorer, userRepo repo.UserStorer, notificationService notification.Service) Service {
+	return service{
+		budgetRepo:      budgetRepo,
+		transactionRepo: transactionRepo,
+		userRepo:        userRepo,
+		notifications:   notificationService,
+	}
+}
+
+// GetBudget returns the user's budget with the amount spent so far this month
+func (sd service) GetBudget(userID string) (BudgetResponse, error) {
+	budget, err := sd.budgetRepo.GetBudget(userID)
+	if err != nil {
+		return BudgetResponse{}, err
+	}
+	return sd.withSpending(budget)
+}
+
+// SetBudget stores a new monthly limit, alerts already sent this month are not repeated
+func (sd service) SetBudget(userID string, req SetBudgetRequest) (BudgetResponse, error) {
+	monthlyLimit, ok := new(big.Int).SetString(req.MonthlyLimitWei, 10)
+	if !ok || monthlyLimit.Sign() <= 0 {
+		return BudgetResponse{}, utils.Validation("monthly_limit_wei must be a positive integer")
+	}
+
+	budget, err := sd.budgetRepo.UpsertBudget(userID, monthlyLimit)
+	if err != nil {
+		return BudgetResponse{}, err
+	}
+	return sd.withSpending(budget)
+}
+
+// DeleteBudget removes the user's budget
+func (sd service) DeleteBudget(userID string) error {
+	return sd.budgetRepo.DeleteBudget(userID)
+}
+
+// RunScheduler checks every budget on the configured interval until stop is closed
+func (sd service) RunScheduler(stop <-chan struct{}) {
+	interval := time.Duration(config.ConfigDetails.BudgetCheckIntervalMinutes) * time.Minute
+	if interval <= 0 {
+		log.Println("Scheduled budget checks disabled")
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			sd.checkBudgets()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// checkBudgets alerts users whose spending this month crossed a threshold for the first time
+func (sd service) checkBudgets() {
+	budgets, err := sd.budgetRepo.GetBudgets()
+	if err != nil {
+		log.Printf("Error loading budgets: %v", err)
+		return
+	}
+
+	for _, budget := range budgets {
+		status, err := sd.withSpending(budget)
+		if err != nil {
+			log.Printf("Error checking budget of %s: %v", budget.UserID, err)
+			continue
+		}
+
+		// Every reached threshold is recorded against the user's local month, only the highest new one is sent
+		month, _ := time.Parse("2006-01", status.Month)
+		alertThreshold := 0
+		for _, threshold := range alertThresholds {
+			if status.PercentUsed < threshold {
+				break
+			}
+			recorded, err := sd.budgetRepo.RecordBudgetAlert(budget.UserID, month, threshold)
+			if err != nil {
+				break
+			}
+			if recorded {
+				alertThreshold = threshold
+			}
+		}
+
+		if alertThreshold > 0 {
+			sd.notifyBudget(budget.UserID, alertThreshold, status)
+		}
+	}
+}
+
+// notifyBudget tells the user how much of the budget has been used
+func (sd service) notifyBudget(userID string, threshold int, status BudgetResponse) {
+	spent, _ := new(big.Int).SetString(status.SpentWei, 10)
+	limit, _ := new(big.Int).SetString(status.MonthlyLimitWei, 10)
+
+	sd.notifications.Notify(notification.Notification{
+		UserID:   userID,
+		Category: notification.CategoryBudget,
+		Title:    "Budget alert",
+		Body:     "You have used %d%% of your monthly budget: %s of %s ETH spent.",
+		BodyArgs: []any{status.PercentUsed, weiToETH(spent), weiToETH(limit)},
+		Data:     map[string]string{"threshold": strconv.Itoa(threshold), "month": status.Month},
+	})
+}
+
+// withSpending adds the sent total of the current month in the user's time zone to a budget
+func (sd service) withSpending(budget repo.Budget) (BudgetResponse, error) {
+	location, err := sd.userRepo.GetUserLocation(budget.UserID)
+	if err != nil {
+		return BudgetResponse{}, err
+	}
+	month := startOfMonth(time.Now(), location)
+	spent, err := sd.transactionRepo.SumSentSince(budget.UserID, month)
+	if err != nil {
+		return BudgetResponse{}, err
+	}
+
+	// Capped so that spending far above a tiny budget still fits an int
+	percentUsed := maxPercentUsed
+	if percent := new(big.Int).Div(new(big.Int).Mul(spent, big.NewInt(100)), budget.MonthlyLimit); percent.Cmp(big.NewInt(maxPercentUsed)) < 0 {
+		percentUsed = int(percent.Int64())
+	}
+
+	return BudgetResponse{
+		Month:           month.Format("2006-01"),
+		MonthlyLimitWei: budget.MonthlyLimit.String(),
+		SpentWei:        spent.String(),
+		PercentUsed:     percentUsed,
+		TimeZone:        location.String(),
+	}, nil
+}
+
+func startOfMonth(t time.Time, location *time.Location) time.Time {
+	t = t.In(location)
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, location)
+}
+
+func weiToETH(wei *big.Int) string {
+	return new(big.Float).Quo(new(big.Float).SetInt(wei), big.NewFloat(1e18)).Text('f', -1)
+}
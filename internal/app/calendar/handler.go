@@ -0,0 +1,81 @@
+package calendar
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/respond"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+	"github.com/gorilla/mux"
+)
+
+// AddHolidayRequest adds a holiday on date (YYYY-MM-DD), region defaults to the configured one
+type AddHolidayRequest struct {
+	Region string `json:"region"`
+	Date   string `json:"date"`
+	Name   string `json:"name"`
+}
+
+// HolidayResponse represents a holiday of a calendar region
+type HolidayResponse struct {
+	Region    string    `json:"region"`
+	Date      string    `json:"date"`
+	Name      string    `json:"name"`
+	CreatedBy string    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Handler struct {
+	service Service
+}
+
+// Constructor function
+func NewHandler(service Service) Handler {
+	return Handler{service: service}
+}
+
+// ListHolidaysHandler lists the holidays of the region query parameter or the configured region, needs holiday.manage
+func (hd Handler) ListHolidaysHandler(w http.ResponseWriter, r *http.Request) {
+	holidays, err := hd.service.ListHolidays(r.URL.Query().Get("region"))
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.List(w, r, holidays, respond.Pagination{Count: len(holidays)})
+}
+
+// AddHolidayHandler adds a holiday to a region, needs holiday.manage
+func (hd Handler) AddHolidayHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req AddHolidayRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	holiday, err := hd.service.AddHoliday(userInfo.UserID, req)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, holiday)
+}
+
+// DeleteHolidayHandler removes a holiday of a region, needs holiday.manage
+func (hd Handler) DeleteHolidayHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if err := hd.service.DeleteHoliday(vars["region"], vars["date"]); err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
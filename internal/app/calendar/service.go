@@ -0,0 +1,201 @@
+package calendar
+
+import (
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/config"
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+const (
+	// How often holidays are reloaded so changes made on another instance are picked up
+	refreshInterval      = 5 * time.Minute
+	maxHolidayNameLength = 100
+	// Bounds the roll forward should a region be marked as holidays for a whole year
+	maxRollDays = 366
+)
+
+var regionPattern = regexp.MustCompile(`^[A-Z]{2,8}$`)
+
+type service struct {
+	holidayRepo repo.HolidayStorer
+	region      string
+
+	mu       sync.RWMutex
+	holidays map[string]bool
+}
+
+type Service interface {
+	Load() error
+	IsBusinessDay(day time.Time) bool
+	NextBusinessDay(day time.Time) time.Time
+	ListHolidays(region string) ([]HolidayResponse, error)
+	AddHoliday(adminID string, req AddHolidayRequest) (HolidayResponse, error)
+	DeleteHoliday(region, date string) error
+	RunRefresher(stop <-chan struct{})
+}
+
+// Constructor function
+func NewService(holidayRepo repo.HolidayStorer) Service {
+	return &service{
+		holidayRepo: holidayRepo,
+		region:      config.ConfigDetails.BusinessCalendarRegion,
+		holidays:    map[string]bool{},
+	}
+}
+
+// Load caches the holidays of the configured region
+func (sd *service) Load() error {
+	stored, err := sd.holidayRepo.GetHolidays(sd.region)
+	if err != nil {
+		return err
+	}
+
+	holidays := make(map[string]bool, len(stored))
+	for _, holiday := range stored {
+		holidays[holiday.Date.Format(time.DateOnly)] = true
+	}
+
+	sd.mu.Lock()
+	sd.holidays = holidays
+	sd.mu.Unlock()
+	return nil
+}
+
+// IsBusinessDay reports whether the UTC date of day is neither a weekend nor a holiday of the
+// configured region
+func (sd *service) IsBusinessDay(day time.Time) bool {
+	day = day.UTC()
+	if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+		return false
+	}
+
+	sd.mu.RLock()
+	defer sd.mu.RUnlock()
+	return !sd.holidays[day.Format(time.DateOnly)]
+}
+
+// NextBusinessDay returns day when it is a business day, otherwise the first business day after it
+func (sd *service) NextBusinessDay(day time.Time) time.Time {
+	for i := 0; i < maxRollDays && !sd.IsBusinessDay(day); i++ {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day
+}
+
+// ListHolidays returns the holidays of a region, the configured one when region is empty
+func (sd *service) ListHolidays(region string) ([]HolidayResponse, error) {
+	region, err := sd.resolveRegion(region)
+	if err != nil {
+		return nil, err
+	}
+
+	holidays, err := sd.holidayRepo.GetHolidays(region)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]HolidayResponse, len(holidays))
+	for i, holiday := range holidays {
+		response[i] = newHolidayResponse(holiday)
+	}
+	return response, nil
+}
+
+// AddHoliday adds a holiday to a region, the configured one when the request has no region
+func (sd *service) AddHoliday(adminID string, req AddHolidayRequest) (HolidayResponse, error) {
+	region, err := sd.resolveRegion(req.Region)
+	if err != nil {
+		return HolidayResponse{}, err
+	}
+	date, err := time.Parse(time.DateOnly, req.Date)
+	if err != nil {
+		return HolidayResponse{}, utils.Validation("date must be formatted as YYYY-MM-DD")
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > maxHolidayNameLength {
+		return HolidayResponse{}, utils.Validationf("name is required and must be at most %d characters", maxHolidayNameLength)
+	}
+
+	holiday, err := sd.holidayRepo.AddHoliday(repo.Holiday{Region: region, Date: date, Name: name, CreatedBy: adminID})
+	if err != nil {
+		return HolidayResponse{}, err
+	}
+
+	log.Printf("Holiday %s added to the %s calendar by %s", req.Date, region, adminID)
+	sd.reloadRegion(region)
+	return newHolidayResponse(holiday), nil
+}
+
+// DeleteHoliday removes a holiday of a region
+func (sd *service) DeleteHoliday(region, date string) error {
+	region, err := sd.resolveRegion(region)
+	if err != nil {
+		return err
+	}
+	day, err := time.Parse(time.DateOnly, date)
+	if err != nil {
+		return utils.Validation("date must be formatted as YYYY-MM-DD")
+	}
+
+	if err := sd.holidayRepo.DeleteHoliday(region, day); err != nil {
+		return err
+	}
+
+	sd.reloadRegion(region)
+	return nil
+}
+
+// RunRefresher periodically reloads holidays until stop is closed
+func (sd *service) RunRefresher(stop <-chan struct{}) {
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := sd.Load(); err != nil {
+				log.Printf("Error refreshing holidays: %v", err)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// reloadRegion refreshes the cache right away when the configured region changed
+func (sd *service) reloadRegion(region string) {
+	if region != sd.region {
+		return
+	}
+	if err := sd.Load(); err != nil {
+		log.Printf("Error reloading holidays: %v", err)
+	}
+}
+
+// resolveRegion normalizes a region code, an empty one stands for the configured region
+func (sd *service) resolveRegion(region string) (string, error) {
+	if region == "" {
+		return sd.region, nil
+	}
+	region = strings.ToUpper(strings.TrimSpace(region))
+	if !regionPattern.MatchString(region) {
+		return "", utils.Validation("region must be 2 to 8 letters")
+	}
+	return region, nil
+}
+
+func newHolidayResponse(holiday repo.Holiday) HolidayResponse {
+	return HolidayResponse{
+		Region:    holiday.Region,
+		Date:      holiday.Date.Format(time.DateOnly),
+		Name:      holiday.Name,
+		CreatedBy: holiday.CreatedBy,
+		CreatedAt: holiday.CreatedAt,
+	}
+}
@@ -0,0 +1,79 @@
+package captcha
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/config"
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+	"github.com/CodeWithKrushnal/ChainBank/middleware"
+)
+
+// TokenHeader carries the token of a solved CAPTCHA
+const TokenHeader = "X-Captcha-Token"
+
+// Window in which the attempts of a client are counted
+const attemptWindow = 10 * time.Minute
+
+type service struct {
+	// nil while CAPTCHA is disabled
+	verifier verifier
+	// Counts attempts per client address, clients past the limit have to solve a CAPTCHA
+	attempts *middleware.RateLimiter
+	// Set when every attempt needs a CAPTCHA
+	always bool
+}
+
+type Service interface {
+	Check(r *http.Request) error
+}
+
+// Constructor function, CAPTCHA stays disabled while no provider is configured. Attempts are counted
+// in rateLimitRepo, or in memory when it is nil.
+func NewService(rateLimitRepo repo.RateLimitStorer) Service {
+	cfg := config.ConfigDetails
+	sd := service{}
+	if cfg.CaptchaProvider == "" {
+		return sd
+	}
+
+	verifier, err := newVerifier(cfg.CaptchaProvider, cfg.CaptchaSecret)
+	if err != nil {
+		log.Printf("CAPTCHA disabled: %v", err)
+		return sd
+	}
+	sd.verifier = verifier
+	sd.attempts = middleware.NewRateLimiter("captcha", attemptWindow, rateLimitRepo)
+	sd.attempts.SetLimit(cfg.CaptchaAfterAttempts)
+	sd.always = cfg.CaptchaAfterAttempts == 0
+	return sd
+}
+
+// Check records a signup or sign-in attempt of the client. Once the client made more attempts
+// within the window than configured, the attempt must carry a solved CAPTCHA in TokenHeader.
+func (sd service) Check(r *http.Request) error {
+	if sd.verifier == nil {
+		return nil
+	}
+
+	clientIP := middleware.ClientIP(r)
+	if !sd.always && sd.attempts.Allow(clientIP) {
+		return nil
+	}
+
+	token := r.Header.Get(TokenHeader)
+	if token == "" {
+		return utils.Forbidden("captcha required")
+	}
+
+	ok, err := sd.verifier.Verify(r.Context(), token, clientIP)
+	if err != nil {
+		return utils.Upstream("captcha verification unavailable", err)
+	}
+	if !ok {
+		return utils.Forbidden("captcha verification failed")
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package captcha
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Supported CAPTCHA providers
+const (
+	ProviderHCaptcha  = "hcaptcha"
+	ProviderReCAPTCHA = "recaptcha"
+)
+
+// Siteverify endpoints of the providers, both take the same form and answer in the same shape
+var siteverifyEndpoints = map[string]string{
+	ProviderHCaptcha:  "https://api.hcaptcha.com/siteverify",
+	ProviderReCAPTCHA: "https://www.google.com/recaptcha/api/siteverify",
+}
+
+// verifier checks a token solved by the client against the provider
+type verifier interface {
+	Verify(ctx context.Context, token, remoteIP string) (bool, error)
+}
+
+// siteverifyVerifier checks tokens with the siteverify API shared by hCaptcha and reCAPTCHA
+type siteverifyVerifier struct {
+	endpoint string
+	secret   string
+	client   *http.Client
+}
+
+// newVerifier returns the verifier of a supported provider
+func newVerifier(provider, secret string) (verifier, error) {
+	endpoint, ok := siteverifyEndpoints[provider]
+	if !ok {
+		return nil, fmt.Errorf("unknown CAPTCHA provider %q", provider)
+	}
+	return &siteverifyVerifier{endpoint: endpoint, secret: secret, client: &http.Client{Timeout: 10 * time.Second}}, nil
+}
+
+func (sv *siteverifyVerifier) Verify(ctx context.Context, token, remoteIP string) (bool, error) {
+	form := url.Values{}
+	form.Set("secret", sv.secret)
+	form.Set("response", token)
+	form.Set("remoteip", remoteIP)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sv.endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := sv.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("error calling CAPTCHA provider: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("CAPTCHA provider responded %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Success    bool     `json:"success"`
+		ErrorCodes []string `json:"error-codes"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return false, fmt.Errorf("error reading CAPTCHA provider response: %w", err)
+	}
+	// A rejected secret is a misconfiguration on our side, not a failed challenge
+	for _, code := range result.ErrorCodes {
+		if strings.Contains(code, "secret") {
+			return false, fmt.Errorf("CAPTCHA provider rejected the secret: %s", code)
+		}
+	}
+	return result.Success, nil
+}
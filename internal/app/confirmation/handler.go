@@ -0,0 +1,262 @@
+package confirmation
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/respond"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+	"github.com/gorilla/mux"
+)
+
+// Proof carries the credentials confirming a transfer or a settings change, each verifier only
+// reads the field of its own mode
+type Proof struct {
+	Password    string `json:"password"`
+	TOTPCode    string `json:"totp_code"`
+	DeviceToken string `json:"device_token"`
+	PIN         string `json:"pin"`
+}
+
+// SetModeRequest switches the confirmation mode, proven with the password and the current mode
+type SetModeRequest struct {
+	Mode string `json:"mode"`
+	Proof
+}
+
+// ConfirmTOTPRequest carries a code of the newly enrolled authenticator
+type ConfirmTOTPRequest struct {
+	Code string `json:"code"`
+}
+
+// AddDeviceRequest names a device to pre-authorize, proven like a mode change
+type AddDeviceRequest struct {
+	Name string `json:"name"`
+	Proof
+}
+
+// SetPINRequest sets or resets the transaction PIN, proven like a mode change
+type SetPINRequest struct {
+	NewPIN string `json:"new_pin"`
+	Proof
+}
+
+// SettingsResponse represents how the user confirms transfers. A PIN can confirm transfers up
+// to PINMaxAmountWei in place of the password.
+type SettingsResponse struct {
+	Mode            string           `json:"mode"`
+	TOTPEnabled     bool             `json:"totp_enabled"`
+	Devices         []DeviceResponse `json:"devices"`
+	PINEnabled      bool             `json:"pin_enabled"`
+	PINLockedUntil  *time.Time       `json:"pin_locked_until,omitempty"`
+	PINMaxAmountWei string           `json:"pin_max_amount_wei"`
+}
+
+// TOTPEnrollmentResponse carries the secret to add to an authenticator app, shown only once
+type TOTPEnrollmentResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// DeviceResponse represents a pre-authorized device without its token
+type DeviceResponse struct {
+	DeviceID   string     `json:"device_id"`
+	Name       string     `json:"name"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// AddDeviceResponse is returned once on creation, it is the only time the token is visible
+type AddDeviceResponse struct {
+	DeviceResponse
+	Token string `json:"device_token"`
+}
+
+func newDeviceResponse(device repo.TransferDevice) DeviceResponse {
+	return DeviceResponse{
+		DeviceID:   device.DeviceID,
+		Name:       device.Name,
+		CreatedAt:  device.CreatedAt,
+		LastUsedAt: device.LastUsedAt,
+	}
+}
+
+type Handler struct {
+	service Service
+}
+
+// Constructor function
+func NewHandler(service Service) Handler {
+	return Handler{service: service}
+}
+
+// GetSettingsHandler returns how the authenticated user confirms transfers
+func (hd Handler) GetSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	settings, err := hd.service.GetSettings(userInfo.UserID)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, settings)
+}
+
+// SetModeHandler switches the confirmation mode of the authenticated user
+func (hd Handler) SetModeHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req SetModeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	settings, err := hd.service.SetMode(userInfo.UserID, req)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, settings)
+}
+
+// EnrollTOTPHandler starts the enrollment of an authenticator app
+func (hd Handler) EnrollTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var proof Proof
+	if err := json.NewDecoder(r.Body).Decode(&proof); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	enrollment, err := hd.service.EnrollTOTP(userInfo.UserID, userInfo.UserEmail, proof)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, enrollment)
+}
+
+// ConfirmTOTPHandler completes the enrollment with a code from the authenticator app
+func (hd Handler) ConfirmTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req ConfirmTOTPRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := hd.service.ConfirmTOTP(userInfo.UserID, req); err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AddDeviceHandler pre-authorizes a device to confirm the authenticated user's transfers
+func (hd Handler) AddDeviceHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req AddDeviceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	device, err := hd.service.AddDevice(userInfo.UserID, req)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, device)
+}
+
+// RevokeDeviceHandler revokes one of the authenticated user's devices
+func (hd Handler) RevokeDeviceHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	if err := hd.service.RevokeDevice(userInfo.UserID, mux.Vars(r)["deviceID"]); err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SetPINHandler sets the transaction PIN of the authenticated user, also resetting a forgotten or
+// locked one
+func (hd Handler) SetPINHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req SetPINRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := hd.service.SetPIN(userInfo.UserID, req); err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeletePINHandler removes the transaction PIN of the authenticated user, proven like setting it
+func (hd Handler) DeletePINHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var proof Proof
+	if err := json.NewDecoder(r.Body).Decode(&proof); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := hd.service.DeletePIN(userInfo.UserID, proof); err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
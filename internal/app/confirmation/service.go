@@ -0,0 +1,361 @@
+package confirmation
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/settings"
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+// Modes a user can confirm transfers with, password unless they chose another
+const (
+	ModePassword = "password"
+	ModeTOTP     = "totp"
+	ModeDevice   = "device"
+)
+
+const (
+	// Device tokens look like cbd_<secret>
+	deviceTokenPrefix  = "cbd_"
+	deviceTokenBytes   = 32
+	maxDevicesPerUser  = 10
+	maxDeviceNameBytes = 100
+	pinLength          = 6
+	// After maxPINAttempts wrong PINs in a row each further attempt waits out pinLockout
+	maxPINAttempts = 5
+	pinLockout     = 15 * time.Minute
+)
+
+type service struct {
+	confirmationRepo repo.TransferConfirmationStorer
+	settings         settings.Service
+	verifiers        map[string]ConfirmationVerifier
+	// Stands in for the password on small transfers
+	pin ConfirmationVerifier
+}
+
+type Service interface {
+	VerifyTransfer(userID string, amount *big.Int, proof Proof) error
+	GetSettings(userID string) (SettingsResponse, error)
+	SetMode(userID string, req SetModeRequest) (SettingsResponse, error)
+	EnrollTOTP(userID, email string, proof Proof) (TOTPEnrollmentResponse, error)
+	ConfirmTOTP(userID string, req ConfirmTOTPRequest) error
+	AddDevice(userID string, req AddDeviceRequest) (AddDeviceResponse, error)
+	RevokeDevice(userID, deviceID string) error
+	SetPIN(userID string, req SetPINRequest) error
+	DeletePIN(userID string, proof Proof) error
+}
+
+// Constructor function
+func NewService(confirmationRepo repo.TransferConfirmationStorer, userRepo repo.UserStorer, settingsService settings.Service) Service {
+	return service{
+		confirmationRepo: confirmationRepo,
+		settings:         settingsService,
+		verifiers: map[string]ConfirmationVerifier{
+			ModePassword: passwordVerifier{userRepo: userRepo},
+			ModeTOTP:     totpVerifier{confirmationRepo: confirmationRepo},
+			ModeDevice:   deviceVerifier{confirmationRepo: confirmationRepo},
+		},
+		pin: pinVerifier{confirmationRepo: confirmationRepo},
+	}
+}
+
+// VerifyTransfer checks the proof with the verifier of the user's confirmation mode. Users
+// confirming with their password may send their PIN instead for amounts up to the PIN limit.
+func (sd service) VerifyTransfer(userID string, amount *big.Int, proof Proof) error {
+	mode, err := sd.mode(userID)
+	if err != nil {
+		return err
+	}
+
+	if mode == ModePassword && proof.Password == "" && proof.PIN != "" {
+		maxAmount := sd.settings.GetBigInt(settings.TransferPINMaxAmountWei)
+		if maxAmount.Sign() == 0 {
+			return utils.Validation("transaction PINs are disabled, confirm with your password")
+		}
+		if amount.Cmp(maxAmount) > 0 {
+			return utils.Validationf("a PIN only confirms transfers up to %s wei, confirm with your password", maxAmount)
+		}
+		return sd.pin.Verify(userID, proof)
+	}
+
+	return sd.verifiers[mode].Verify(userID, proof)
+}
+
+// GetSettings returns the user's confirmation mode, authenticator state and devices
+func (sd service) GetSettings(userID string) (SettingsResponse, error) {
+	mode, err := sd.mode(userID)
+	if err != nil {
+		return SettingsResponse{}, err
+	}
+	totpConfirmed, err := sd.totpConfirmed(userID)
+	if err != nil {
+		return SettingsResponse{}, err
+	}
+	devices, err := sd.confirmationRepo.GetTransferDevices(userID)
+	if err != nil {
+		return SettingsResponse{}, err
+	}
+
+	response := SettingsResponse{
+		Mode:            mode,
+		TOTPEnabled:     totpConfirmed,
+		Devices:         make([]DeviceResponse, len(devices)),
+		PINMaxAmountWei: sd.settings.GetBigInt(settings.TransferPINMaxAmountWei).String(),
+	}
+	for i, device := range devices {
+		response.Devices[i] = newDeviceResponse(device)
+	}
+
+	pin, err := sd.confirmationRepo.GetTransactionPIN(userID)
+	if err != nil && !errors.Is(err, utils.ErrNotFound) {
+		return SettingsResponse{}, err
+	}
+	if err == nil {
+		response.PINEnabled = true
+		if pin.LockedUntil != nil && pin.LockedUntil.After(time.Now()) {
+			response.PINLockedUntil = pin.LockedUntil
+		}
+	}
+	return response, nil
+}
+
+// SetMode switches the user's confirmation mode. It takes the password and, when leaving
+// another mode, that mode's proof as well, so a stolen password alone cannot turn 2FA off.
+func (sd service) SetMode(userID string, req SetModeRequest) (SettingsResponse, error) {
+	if _, ok := sd.verifiers[req.Mode]; !ok {
+		return SettingsResponse{}, utils.Validationf("unknown confirmation mode %q", req.Mode)
+	}
+	if err := sd.verifyOwner(userID, req.Proof); err != nil {
+		return SettingsResponse{}, err
+	}
+
+	switch req.Mode {
+	case ModeTOTP:
+		confirmed, err := sd.totpConfirmed(userID)
+		if err != nil {
+			return SettingsResponse{}, err
+		}
+		if !confirmed {
+			return SettingsResponse{}, utils.Conflict("enroll and confirm an authenticator first")
+		}
+	case ModeDevice:
+		devices, err := sd.confirmationRepo.GetTransferDevices(userID)
+		if err != nil {
+			return SettingsResponse{}, err
+		}
+		if len(devices) == 0 {
+			return SettingsResponse{}, utils.Conflict("add a device first")
+		}
+	}
+
+	if err := sd.confirmationRepo.SetConfirmationMode(userID, req.Mode); err != nil {
+		return SettingsResponse{}, err
+	}
+	return sd.GetSettings(userID)
+}
+
+// EnrollTOTP creates a new authenticator secret, it has to be confirmed with a code before use
+func (sd service) EnrollTOTP(userID, email string, proof Proof) (TOTPEnrollmentResponse, error) {
+	mode, err := sd.mode(userID)
+	if err != nil {
+		return TOTPEnrollmentResponse{}, err
+	}
+	// Replacing the secret in use would leave transfers unconfirmable until the new one is confirmed
+	if mode == ModeTOTP {
+		return TOTPEnrollmentResponse{}, utils.Conflict("switch to another confirmation mode before enrolling a new authenticator")
+	}
+	if err := sd.verifyOwner(userID, proof); err != nil {
+		return TOTPEnrollmentResponse{}, err
+	}
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		return TOTPEnrollmentResponse{}, err
+	}
+	if err := sd.confirmationRepo.SaveTOTPSecret(userID, secret); err != nil {
+		return TOTPEnrollmentResponse{}, err
+	}
+	return TOTPEnrollmentResponse{Secret: secret, URI: totpURI(secret, email)}, nil
+}
+
+// ConfirmTOTP activates the enrolled authenticator once it produces a valid code
+func (sd service) ConfirmTOTP(userID string, req ConfirmTOTPRequest) error {
+	totp, err := sd.confirmationRepo.GetTOTPSecret(userID)
+	if err != nil {
+		return err
+	}
+	if totp.Confirmed {
+		return utils.Conflict("authenticator is already confirmed")
+	}
+
+	step, ok := matchTOTP(totp.Secret, strings.TrimSpace(req.Code), time.Now())
+	if !ok {
+		return utils.Unauthorized("invalid authenticator code")
+	}
+	confirmed, err := sd.confirmationRepo.ConfirmTOTPSecret(userID, step)
+	if err != nil {
+		return err
+	}
+	if !confirmed {
+		return utils.Conflict("authenticator is already confirmed")
+	}
+	return nil
+}
+
+// AddDevice pre-authorizes a device to confirm transfers, the token is only ever returned here
+func (sd service) AddDevice(userID string, req AddDeviceRequest) (AddDeviceResponse, error) {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > maxDeviceNameBytes {
+		return AddDeviceResponse{}, utils.Validation("name is required and must be at most 100 characters")
+	}
+	if err := sd.verifyOwner(userID, req.Proof); err != nil {
+		return AddDeviceResponse{}, err
+	}
+
+	devices, err := sd.confirmationRepo.GetTransferDevices(userID)
+	if err != nil {
+		return AddDeviceResponse{}, err
+	}
+	if len(devices) >= maxDevicesPerUser {
+		return AddDeviceResponse{}, utils.Conflictf("at most %d devices are allowed", maxDevicesPerUser)
+	}
+
+	token, err := generateDeviceToken()
+	if err != nil {
+		return AddDeviceResponse{}, err
+	}
+	device, err := sd.confirmationRepo.CreateTransferDevice(repo.TransferDevice{
+		UserID:    userID,
+		Name:      req.Name,
+		TokenHash: hashDeviceToken(token),
+	})
+	if err != nil {
+		return AddDeviceResponse{}, err
+	}
+	return AddDeviceResponse{DeviceResponse: newDeviceResponse(device), Token: token}, nil
+}
+
+// RevokeDevice removes a device, the last one cannot go while transfers are confirmed by device
+func (sd service) RevokeDevice(userID, deviceID string) error {
+	mode, err := sd.mode(userID)
+	if err != nil {
+		return err
+	}
+	if mode == ModeDevice {
+		devices, err := sd.confirmationRepo.GetTransferDevices(userID)
+		if err != nil {
+			return err
+		}
+		if len(devices) == 1 && devices[0].DeviceID == deviceID {
+			return utils.Conflict("switch to another confirmation mode before revoking your last device")
+		}
+	}
+	return sd.confirmationRepo.RevokeTransferDevice(userID, deviceID)
+}
+
+// SetPIN sets the transaction PIN, which also resets a forgotten or locked one. It is proven
+// with the password and the current mode, never with the PIN itself.
+func (sd service) SetPIN(userID string, req SetPINRequest) error {
+	if err := validatePIN(req.NewPIN); err != nil {
+		return err
+	}
+	if err := sd.verifyOwner(userID, req.Proof); err != nil {
+		return err
+	}
+
+	pinHash, err := bcrypt.GenerateFromPassword([]byte(req.NewPIN), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	return sd.confirmationRepo.SetTransactionPIN(userID, string(pinHash))
+}
+
+// DeletePIN removes the transaction PIN, transfers then need the password again. It is proven like
+// SetPIN, so a stolen session cannot remove the PIN either.
+func (sd service) DeletePIN(userID string, proof Proof) error {
+	if err := sd.verifyOwner(userID, proof); err != nil {
+		return err
+	}
+	return sd.confirmationRepo.DeleteTransactionPIN(userID)
+}
+
+// validatePIN requires 6 digits that are not all the same or a plain run like 123456
+func validatePIN(pin string) error {
+	if len(pin) != pinLength {
+		return utils.Validationf("the PIN must be %d digits", pinLength)
+	}
+	for _, digit := range pin {
+		if digit < '0' || digit > '9' {
+			return utils.Validationf("the PIN must be %d digits", pinLength)
+		}
+	}
+
+	same, ascending, descending := true, true, true
+	for i := 1; i < len(pin); i++ {
+		step := int(pin[i]) - int(pin[i-1])
+		same = same && step == 0
+		ascending = ascending && step == 1
+		descending = descending && step == -1
+	}
+	if same || ascending || descending {
+		return utils.Validation("the PIN is too easy to guess")
+	}
+	return nil
+}
+
+// mode returns the user's confirmation mode, password when they never chose one
+func (sd service) mode(userID string) (string, error) {
+	mode, err := sd.confirmationRepo.GetConfirmationMode(userID)
+	if err != nil {
+		return "", err
+	}
+	if mode == "" {
+		return ModePassword, nil
+	}
+	return mode, nil
+}
+
+// verifyOwner requires the password, plus the proof of the current mode when it is not password
+func (sd service) verifyOwner(userID string, proof Proof) error {
+	if err := sd.verifiers[ModePassword].Verify(userID, proof); err != nil {
+		return err
+	}
+	mode, err := sd.mode(userID)
+	if err != nil {
+		return err
+	}
+	if mode == ModePassword {
+		return nil
+	}
+	return sd.verifiers[mode].Verify(userID, proof)
+}
+
+// totpConfirmed reports whether the user has a confirmed authenticator
+func (sd service) totpConfirmed(userID string) (bool, error) {
+	totp, err := sd.confirmationRepo.GetTOTPSecret(userID)
+	if errors.Is(err, utils.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return totp.Confirmed, nil
+}
+
+// generateDeviceToken returns a new random device token
+func generateDeviceToken() (string, error) {
+	buf := make([]byte, deviceTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return deviceTokenPrefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,185 @@
+package confirmation
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+const (
+	testUserID   = "user-1"
+	testPassword = "Test-Password-1"
+	testPIN      = "482915"
+)
+
+func TestPINLockout(t *testing.T) {
+	confirmationRepo := newMemoryConfirmationRepo(t)
+	verifier := pinVerifier{confirmationRepo: confirmationRepo}
+	wrong := Proof{PIN: "000111"}
+
+	for attempt := 1; attempt < maxPINAttempts; attempt++ {
+		if err := verifier.Verify(testUserID, wrong); !errors.Is(err, utils.ErrUnauthorized) {
+			t.Fatalf("wrong PIN %d: %v, want unauthorized", attempt, err)
+		}
+	}
+	// The attempt reaching the limit locks the PIN, from then on even the right PIN is refused
+	if err := verifier.Verify(testUserID, wrong); !errors.Is(err, utils.ErrForbidden) {
+		t.Fatalf("wrong PIN %d: %v, want forbidden", maxPINAttempts, err)
+	}
+	if err := verifier.Verify(testUserID, Proof{PIN: testPIN}); !errors.Is(err, utils.ErrForbidden) {
+		t.Fatalf("right PIN while locked: %v, want forbidden", err)
+	}
+
+	// Once the lockout passed a further failure locks the PIN again right away
+	confirmationRepo.now = confirmationRepo.now.Add(pinLockout + time.Second)
+	if err := verifier.Verify(testUserID, wrong); !errors.Is(err, utils.ErrForbidden) {
+		t.Fatalf("wrong PIN after the lockout: %v, want forbidden", err)
+	}
+
+	// The right PIN after the lockout clears the failed attempts
+	confirmationRepo.now = confirmationRepo.now.Add(pinLockout + time.Second)
+	if err := verifier.Verify(testUserID, Proof{PIN: testPIN}); err != nil {
+		t.Fatalf("right PIN after the lockout: %v", err)
+	}
+	if err := verifier.Verify(testUserID, wrong); !errors.Is(err, utils.ErrUnauthorized) {
+		t.Fatalf("wrong PIN after a reset: %v, want unauthorized", err)
+	}
+}
+
+func TestDeletePINRequiresProof(t *testing.T) {
+	totpKey := []byte("12345678901234567890")
+	currentCode := func() string { return totpCode(totpKey, time.Now().Unix()/totpPeriod) }
+
+	tests := []struct {
+		name    string
+		mode    string
+		proof   Proof
+		wantErr error
+	}{
+		{"no password", ModePassword, Proof{}, utils.ErrValidation},
+		{"wrong password", ModePassword, Proof{Password: "Wrong-Password-1"}, utils.ErrUnauthorized},
+		// The PIN cannot prove its own removal
+		{"pin only", ModePassword, Proof{PIN: testPIN}, utils.ErrValidation},
+		{"password", ModePassword, Proof{Password: testPassword}, nil},
+		{"totp mode without code", ModeTOTP, Proof{Password: testPassword}, utils.ErrValidation},
+		{"totp mode with wrong code", ModeTOTP, Proof{Password: testPassword, TOTPCode: "000000"}, utils.ErrUnauthorized},
+		{"totp mode", ModeTOTP, Proof{Password: testPassword, TOTPCode: currentCode()}, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			confirmationRepo := newMemoryConfirmationRepo(t)
+			confirmationRepo.mode = test.mode
+			confirmationRepo.totp = repo.TOTPSecret{Secret: totpEncoding.EncodeToString(totpKey), Confirmed: true}
+			service := NewService(confirmationRepo, newMemoryUserRepo(t), nil)
+
+			err := service.DeletePIN(testUserID, test.proof)
+			if test.wantErr == nil {
+				if err != nil {
+					t.Fatalf("DeletePIN: %v", err)
+				}
+				if confirmationRepo.pin != nil {
+					t.Error("PIN still set")
+				}
+				return
+			}
+			if !errors.Is(err, test.wantErr) {
+				t.Errorf("DeletePIN = %v, want %v", err, test.wantErr)
+			}
+			if confirmationRepo.pin == nil {
+				t.Error("PIN deleted without proof")
+			}
+		})
+	}
+}
+
+// memoryConfirmationRepo keeps the confirmation settings of testUserID in memory. Methods the tests
+// do not use are left to the embedded nil interface and panic.
+type memoryConfirmationRepo struct {
+	repo.TransferConfirmationStorer
+	mode string
+	totp repo.TOTPSecret
+	pin  *repo.TransactionPIN
+	now  time.Time
+}
+
+func newMemoryConfirmationRepo(t *testing.T) *memoryConfirmationRepo {
+	t.Helper()
+	pinHash, err := bcrypt.GenerateFromPassword([]byte(testPIN), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &memoryConfirmationRepo{pin: &repo.TransactionPIN{PINHash: string(pinHash)}, now: time.Now()}
+}
+
+func (m *memoryConfirmationRepo) GetConfirmationMode(userID string) (string, error) {
+	return m.mode, nil
+}
+
+func (m *memoryConfirmationRepo) GetTOTPSecret(userID string) (repo.TOTPSecret, error) {
+	return m.totp, nil
+}
+
+func (m *memoryConfirmationRepo) UseTOTPStep(userID string, step int64) (bool, error) {
+	if step <= m.totp.LastUsedStep {
+		return false, nil
+	}
+	m.totp.LastUsedStep = step
+	return true, nil
+}
+
+func (m *memoryConfirmationRepo) GetTransactionPIN(userID string) (repo.TransactionPIN, error) {
+	if m.pin == nil {
+		return repo.TransactionPIN{}, utils.NotFound("transaction PIN not found", nil)
+	}
+	return *m.pin, nil
+}
+
+// ClaimPINAttempt follows claimPINAttemptQuery
+func (m *memoryConfirmationRepo) ClaimPINAttempt(userID string, maxAttempts int, lockout time.Duration) (repo.TransactionPIN, bool, error) {
+	if m.pin == nil || (m.pin.LockedUntil != nil && m.pin.LockedUntil.After(m.now)) {
+		return repo.TransactionPIN{}, false, nil
+	}
+	m.pin.FailedAttempts++
+	m.pin.LockedUntil = nil
+	if m.pin.FailedAttempts >= maxAttempts {
+		lockedUntil := m.now.Add(lockout)
+		m.pin.LockedUntil = &lockedUntil
+	}
+	return *m.pin, true, nil
+}
+
+func (m *memoryConfirmationRepo) ResetPINAttempts(userID string) error {
+	m.pin.FailedAttempts = 0
+	m.pin.LockedUntil = nil
+	return nil
+}
+
+func (m *memoryConfirmationRepo) DeleteTransactionPIN(userID string) error {
+	m.pin = nil
+	return nil
+}
+
+// memoryUserRepo holds testUserID with testPassword
+type memoryUserRepo struct {
+	repo.UserStorer
+	user repo.User
+}
+
+func newMemoryUserRepo(t *testing.T) memoryUserRepo {
+	t.Helper()
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return memoryUserRepo{user: repo.User{ID: testUserID, Password: string(passwordHash)}}
+}
+
+func (m memoryUserRepo) GetUserByID(userID string) (repo.User, error) {
+	return m.user, nil
+}
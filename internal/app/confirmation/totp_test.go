@@ -0,0 +1,63 @@
+package confirmation
+
+import (
+	"testing"
+	"time"
+)
+
+// RFC 6238 appendix B, SHA-1 with the ASCII secret "12345678901234567890". The RFC lists 8 digit
+// codes, 6 digit codes are their last six digits.
+func TestTOTPCodeRFC6238(t *testing.T) {
+	key := []byte("12345678901234567890")
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, test := range tests {
+		if got := totpCode(key, test.unix/totpPeriod); got != test.want {
+			t.Errorf("code at %d = %s, want %s", test.unix, got, test.want)
+		}
+	}
+}
+
+func TestMatchTOTP(t *testing.T) {
+	secret := totpEncoding.EncodeToString([]byte("12345678901234567890"))
+	now := time.Unix(1111111111, 0)
+	current := now.Unix() / totpPeriod
+	key := []byte("12345678901234567890")
+
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current step", secret, totpCode(key, current), current, true},
+		// One step either side is accepted for clock drift
+		{"previous step", secret, totpCode(key, current-1), current - 1, true},
+		{"next step", secret, totpCode(key, current+1), current + 1, true},
+		{"two steps behind", secret, totpCode(key, current-2), 0, false},
+		{"two steps ahead", secret, totpCode(key, current+2), 0, false},
+		{"lowercase secret", "gezdgnbvgy3tqojqgezdgnbvgy3tqojq", totpCode(key, current), current, true},
+		{"short code", secret, totpCode(key, current)[:5], 0, false},
+		{"invalid secret", "not base32!", totpCode(key, current), 0, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			step, ok := matchTOTP(test.secret, test.code, now)
+			if ok != test.wantOK || step != test.wantStep {
+				t.Errorf("matchTOTP = %d, %v, want %d, %v", step, ok, test.wantStep, test.wantOK)
+			}
+		})
+	}
+}
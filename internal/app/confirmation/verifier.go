@@ -0,0 +1,125 @@
+package confirmation
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+// ConfirmationVerifier checks the proof a user sent with a transfer against one confirmation mode
+type ConfirmationVerifier interface {
+	Verify(userID string, proof Proof) error
+}
+
+// passwordVerifier checks the account password, the default mode
+type passwordVerifier struct {
+	userRepo repo.UserStorer
+}
+
+func (v passwordVerifier) Verify(userID string, proof Proof) error {
+	if proof.Password == "" {
+		return utils.Validation("password is required to confirm the transfer")
+	}
+
+	user, err := v.userRepo.GetUserByID(userID)
+	if err != nil {
+		return utils.NotFound("user not found", err)
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(proof.Password)); err != nil {
+		return utils.Unauthorized("invalid password")
+	}
+	return nil
+}
+
+// totpVerifier checks a code of the user's confirmed authenticator, each code works only once
+type totpVerifier struct {
+	confirmationRepo repo.TransferConfirmationStorer
+}
+
+func (v totpVerifier) Verify(userID string, proof Proof) error {
+	if proof.TOTPCode == "" {
+		return utils.Validation("totp_code is required to confirm the transfer")
+	}
+
+	totp, err := v.confirmationRepo.GetTOTPSecret(userID)
+	if err != nil {
+		return err
+	}
+	if !totp.Confirmed {
+		return utils.Unauthorized("invalid authenticator code")
+	}
+	step, ok := matchTOTP(totp.Secret, proof.TOTPCode, time.Now())
+	if !ok {
+		return utils.Unauthorized("invalid authenticator code")
+	}
+	used, err := v.confirmationRepo.UseTOTPStep(userID, step)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return utils.Unauthorized("authenticator code was already used")
+	}
+	return nil
+}
+
+// deviceVerifier checks the token of a device the user pre-authorized
+type deviceVerifier struct {
+	confirmationRepo repo.TransferConfirmationStorer
+}
+
+func (v deviceVerifier) Verify(userID string, proof Proof) error {
+	if proof.DeviceToken == "" {
+		return utils.Validation("device_token is required to confirm the transfer")
+	}
+
+	ok, err := v.confirmationRepo.UseTransferDevice(userID, hashDeviceToken(proof.DeviceToken))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return utils.Unauthorized("invalid device token")
+	}
+	return nil
+}
+
+// pinVerifier checks the transaction PIN, every attempt counts towards the lockout
+type pinVerifier struct {
+	confirmationRepo repo.TransferConfirmationStorer
+}
+
+func (v pinVerifier) Verify(userID string, proof Proof) error {
+	if proof.PIN == "" {
+		return utils.Validation("pin is required to confirm the transfer")
+	}
+
+	pin, ok, err := v.confirmationRepo.ClaimPINAttempt(userID, maxPINAttempts, pinLockout)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		// Either there is no PIN or it is locked
+		if _, err := v.confirmationRepo.GetTransactionPIN(userID); err != nil {
+			return err
+		}
+		return utils.Forbidden("too many wrong PIN attempts, try again later")
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(pin.PINHash), []byte(proof.PIN)); err != nil {
+		if pin.LockedUntil != nil {
+			return utils.Forbidden("too many wrong PIN attempts, try again later")
+		}
+		return utils.Unauthorized("invalid PIN")
+	}
+	return v.confirmationRepo.ResetPINAttempts(userID)
+}
+
+// hashDeviceToken returns the SHA-256 hex digest under which a device token is stored
+func hashDeviceToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,138 @@
+package delegations
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/respond"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+	"github.com/gorilla/mux"
+)
+
+// GrantDelegationRequest gives the user with DelegateEmail access until ExpiresAt
+type GrantDelegationRequest struct {
+	DelegateEmail string    `json:"delegate_email"`
+	Scopes        []string  `json:"scopes"`
+	ExpiresAt     time.Time `json:"expires_at"`
+}
+
+// DelegationResponse represents a delegation between two users
+type DelegationResponse struct {
+	DelegationID   string     `json:"delegation_id"`
+	GrantorUserID  string     `json:"grantor_user_id"`
+	DelegateUserID string     `json:"delegate_user_id"`
+	Scopes         []string   `json:"scopes"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	RevokedAt      *time.Time `json:"revoked_at,omitempty"`
+}
+
+// AuditEntryResponse represents one entry of the delegation audit log
+type AuditEntryResponse struct {
+	DelegationID string    `json:"delegation_id"`
+	ActorUserID  string    `json:"actor_user_id"`
+	Action       string    `json:"action"`
+	Method       string    `json:"method,omitempty"`
+	Path         string    `json:"path,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type Handler struct {
+	service Service
+}
+
+// Constructor function
+func NewHandler(service Service) Handler {
+	return Handler{service: service}
+}
+
+// GrantDelegationHandler delegates access to the authenticated user's account
+func (hd Handler) GrantDelegationHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req GrantDelegationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	delegation, err := hd.service.GrantDelegation(userInfo.UserID, req)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, delegation)
+}
+
+// ListGrantedHandler lists the delegations the authenticated user granted
+func (hd Handler) ListGrantedHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	delegations, err := hd.service.ListGranted(userInfo.UserID)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, delegations)
+}
+
+// ListReceivedHandler lists the delegations granted to the authenticated user
+func (hd Handler) ListReceivedHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	delegations, err := hd.service.ListReceived(userInfo.UserID)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, delegations)
+}
+
+// RevokeDelegationHandler ends a delegation the authenticated user granted or received
+func (hd Handler) RevokeDelegationHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	if err := hd.service.RevokeDelegation(userInfo.UserID, mux.Vars(r)["delegationID"]); err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AuditLogHandler returns the delegation audit log of the authenticated user
+func (hd Handler) AuditLogHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	entries, err := hd.service.AuditLog(userInfo.UserID)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, entries)
+}
@@ -0,0 +1,143 @@
+package delegations
+
+import (
+	"slices"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+// Scopes a delegation can grant, a view delegation only reaches read requests
+const (
+	ScopeView = "view"
+)
+
+var knownScopes = []string{ScopeView}
+
+// OnBehalfOfHeader names the user a delegate is acting for
+const OnBehalfOfHeader = "X-On-Behalf-Of"
+
+const (
+	maxDelegationDays = 90
+	auditLogLimit     = 200
+)
+
+type service struct {
+	delegationRepo repo.DelegationStorer
+	userRepo       repo.UserStorer
+}
+
+type Service interface {
+	GrantDelegation(userID string, req GrantDelegationRequest) (DelegationResponse, error)
+	ListGranted(userID string) ([]DelegationResponse, error)
+	ListReceived(userID string) ([]DelegationResponse, error)
+	RevokeDelegation(userID, delegationID string) error
+	AuditLog(userID string) ([]AuditEntryResponse, error)
+}
+
+// Constructor function
+func NewService(delegationRepo repo.DelegationStorer, userRepo repo.UserStorer) Service {
+	return service{delegationRepo: delegationRepo, userRepo: userRepo}
+}
+
+// GrantDelegation gives another user access to the caller's account until expires_at
+func (sd service) GrantDelegation(userID string, req GrantDelegationRequest) (DelegationResponse, error) {
+	if len(req.Scopes) == 0 {
+		return DelegationResponse{}, utils.Validation("at least one scope is required")
+	}
+	for _, scope := range req.Scopes {
+		if !slices.Contains(knownScopes, scope) {
+			return DelegationResponse{}, utils.Validationf("unknown scope %q", scope)
+		}
+	}
+	now := time.Now()
+	if !req.ExpiresAt.After(now) || req.ExpiresAt.After(now.AddDate(0, 0, maxDelegationDays)) {
+		return DelegationResponse{}, utils.Validationf("expires_at must be in the next %d days", maxDelegationDays)
+	}
+
+	delegate, err := sd.userRepo.GetUserByEmail(req.DelegateEmail)
+	if err != nil {
+		return DelegationResponse{}, err
+	}
+	if delegate.ID == userID {
+		return DelegationResponse{}, utils.Validation("access cannot be delegated to yourself")
+	}
+
+	scopes := slices.Clone(req.Scopes)
+	slices.Sort(scopes)
+	delegation, err := sd.delegationRepo.CreateDelegation(repo.Delegation{
+		GrantorUserID:  userID,
+		DelegateUserID: delegate.ID,
+		Scopes:         slices.Compact(scopes),
+		ExpiresAt:      req.ExpiresAt,
+	})
+	if err != nil {
+		return DelegationResponse{}, err
+	}
+	return newDelegationResponse(delegation), nil
+}
+
+// ListGranted returns the delegations the user granted, expired and revoked ones included
+func (sd service) ListGranted(userID string) ([]DelegationResponse, error) {
+	delegations, err := sd.delegationRepo.GetGrantedDelegations(userID)
+	if err != nil {
+		return nil, err
+	}
+	return newDelegationResponses(delegations), nil
+}
+
+// ListReceived returns the delegations granted to the user
+func (sd service) ListReceived(userID string) ([]DelegationResponse, error) {
+	delegations, err := sd.delegationRepo.GetReceivedDelegations(userID)
+	if err != nil {
+		return nil, err
+	}
+	return newDelegationResponses(delegations), nil
+}
+
+// RevokeDelegation ends a delegation the user granted or received, effective on the next request
+func (sd service) RevokeDelegation(userID, delegationID string) error {
+	return sd.delegationRepo.RevokeDelegation(delegationID, userID)
+}
+
+// AuditLog returns the latest grants, revocations and delegated requests involving the user
+func (sd service) AuditLog(userID string) ([]AuditEntryResponse, error) {
+	entries, err := sd.delegationRepo.GetDelegationAuditLog(userID, auditLogLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]AuditEntryResponse, len(entries))
+	for i, entry := range entries {
+		response[i] = AuditEntryResponse{
+			DelegationID: entry.DelegationID,
+			ActorUserID:  entry.ActorUserID,
+			Action:       entry.Action,
+			Method:       entry.Method,
+			Path:         entry.Path,
+			CreatedAt:    entry.CreatedAt,
+		}
+	}
+	return response, nil
+}
+
+func newDelegationResponses(delegations []repo.Delegation) []DelegationResponse {
+	response := make([]DelegationResponse, len(delegations))
+	for i, delegation := range delegations {
+		response[i] = newDelegationResponse(delegation)
+	}
+	return response
+}
+
+func newDelegationResponse(delegation repo.Delegation) DelegationResponse {
+	return DelegationResponse{
+		DelegationID:   delegation.DelegationID,
+		GrantorUserID:  delegation.GrantorUserID,
+		DelegateUserID: delegation.DelegateUserID,
+		Scopes:         delegation.Scopes,
+		ExpiresAt:      delegation.ExpiresAt,
+		CreatedAt:      delegation.CreatedAt,
+		RevokedAt:      delegation.RevokedAt,
+	}
+}
@@ -0,0 +1,72 @@
+package deltasync
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/respond"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+// Longest a client may ask a sync call to wait for changes
+const maxWait = 30 * time.Second
+
+// SyncRequest asks for the changes after Since, a cursor from a previous response. Wait is how long
+// to hold the call open when there are none yet.
+type SyncRequest struct {
+	Since string
+	Wait  time.Duration
+}
+
+// SyncResponse carries the changes after the requested cursor, oldest first, and the cursor to pass
+// on the next call. HasMore is set when the client should call again right away.
+type SyncResponse struct {
+	Transactions  []repo.TransactionChange `json:"transactions"`
+	Notifications []repo.InboxNotification `json:"notifications"`
+	Cursor        string                   `json:"cursor"`
+	HasMore       bool                     `json:"has_more"`
+}
+
+type Handler struct {
+	service Service
+}
+
+// Constructor function
+func NewHandler(service Service) Handler {
+	return Handler{service: service}
+}
+
+// SyncHandler returns everything that changed for the user since the "since" cursor, in one call so
+// that mobile clients can catch up after being offline. Leave "since" out on the first call. With
+// "wait" (in seconds, at most 30) the call is held open until a change comes in.
+func (hd Handler) SyncHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Incoming Request On Sync Handler")
+
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	req := SyncRequest{Since: query.Get("since")}
+	if waitParam := query.Get("wait"); waitParam != "" {
+		seconds, err := strconv.Atoi(waitParam)
+		if err != nil || seconds < 0 {
+			http.Error(w, "Invalid wait", http.StatusBadRequest)
+			return
+		}
+		req.Wait = min(time.Duration(seconds)*time.Second, maxWait)
+	}
+
+	response, err := hd.service.Sync(r.Context(), userInfo, req)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, response)
+}
@@ -0,0 +1,157 @@
+package deltasync
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/notification"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/wallet"
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+const (
+	// Changes of each kind returned by one call, a client with more to catch up on calls again
+	changesPerKind = 100
+	// How often a waiting call looks for new changes
+	pollInterval = 2 * time.Second
+)
+
+type service struct {
+	walletService       wallet.Service
+	notificationService notification.Service
+}
+
+type Service interface {
+	Sync(ctx context.Context, userInfo utils.User, req SyncRequest) (SyncResponse, error)
+}
+
+// Constructor function
+func NewService(walletService wallet.Service, notificationService notification.Service) Service {
+	return service{walletService: walletService, notificationService: notificationService}
+}
+
+// syncCursor holds how far a client has read each kind of change, nil for a kind it never read
+type syncCursor struct {
+	Transactions  *repo.ChangeCursor
+	Notifications *repo.ChangeCursor
+}
+
+// Sync returns the changes made after the request's cursor. Without changes it waits up to req.Wait
+// for some to come in, or until ctx is done, and then answers with none.
+func (sd service) Sync(ctx context.Context, userInfo utils.User, req SyncRequest) (SyncResponse, error) {
+	cursor, err := decodeSyncCursor(req.Since)
+	if err != nil {
+		return SyncResponse{}, err
+	}
+
+	deadline := time.Now().Add(req.Wait)
+	for {
+		response, err := sd.changes(userInfo, cursor)
+		if err != nil || len(response.Transactions) > 0 || len(response.Notifications) > 0 {
+			return response, err
+		}
+
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			return response, nil
+		}
+		timer := time.NewTimer(min(wait, pollInterval))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return response, nil
+		}
+	}
+}
+
+// changes loads one batch of each kind of change after cursor and the cursor following them
+func (sd service) changes(userInfo utils.User, cursor syncCursor) (SyncResponse, error) {
+	// One row more than returned tells whether the client has to call again
+	transactions, err := sd.walletService.GetTransactionChanges(userInfo, cursor.Transactions, changesPerKind+1)
+	if err != nil {
+		return SyncResponse{}, err
+	}
+	notifications, err := sd.notificationService.GetInboxNotifications(userInfo.UserID, cursor.Notifications, changesPerKind+1)
+	if err != nil {
+		return SyncResponse{}, err
+	}
+
+	response := SyncResponse{HasMore: len(transactions) > changesPerKind || len(notifications) > changesPerKind}
+	response.Transactions = transactions[:min(len(transactions), changesPerKind)]
+	response.Notifications = notifications[:min(len(notifications), changesPerKind)]
+
+	if n := len(response.Transactions); n > 0 {
+		last := response.Transactions[n-1]
+		cursor.Transactions = &repo.ChangeCursor{ChangedAt: last.UpdatedAt, ID: last.TransactionID}
+	}
+	if n := len(response.Notifications); n > 0 {
+		last := response.Notifications[n-1]
+		cursor.Notifications = &repo.ChangeCursor{ChangedAt: last.CreatedAt, ID: last.NotificationID}
+	}
+	response.Cursor = encodeSyncCursor(cursor)
+
+	return response, nil
+}
+
+// encodeSyncCursor turns the positions of a sync cursor into an opaque URL-safe token
+func encodeSyncCursor(cursor syncCursor) string {
+	raw := encodeChangeCursor(cursor.Transactions) + "|" + encodeChangeCursor(cursor.Notifications)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeSyncCursor parses a token produced by encodeSyncCursor, an empty token starts from the beginning
+func decodeSyncCursor(token string) (syncCursor, error) {
+	if token == "" {
+		return syncCursor{}, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return syncCursor{}, utils.Validation("invalid cursor")
+	}
+
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 4 {
+		return syncCursor{}, utils.Validation("invalid cursor")
+	}
+
+	var cursor syncCursor
+	if cursor.Transactions, err = decodeChangeCursor(parts[0], parts[1]); err != nil {
+		return syncCursor{}, err
+	}
+	if cursor.Notifications, err = decodeChangeCursor(parts[2], parts[3]); err != nil {
+		return syncCursor{}, err
+	}
+	return cursor, nil
+}
+
+// encodeChangeCursor writes a position as its time and ID, both left empty for nil
+func encodeChangeCursor(cursor *repo.ChangeCursor) string {
+	if cursor == nil {
+		return "|"
+	}
+	return cursor.ChangedAt.UTC().Format(time.RFC3339Nano) + "|" + cursor.ID
+}
+
+// decodeChangeCursor parses a position written by encodeChangeCursor, the ID has to be a UUID like
+// the transaction and notification IDs it stands for
+func decodeChangeCursor(changedAt, id string) (*repo.ChangeCursor, error) {
+	if changedAt == "" && id == "" {
+		return nil, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339Nano, changedAt)
+	if err != nil {
+		return nil, utils.Validation("invalid cursor")
+	}
+	if parsedID, err := uuid.Parse(id); err != nil || parsedID.String() != id {
+		return nil, utils.Validation("invalid cursor")
+	}
+	return &repo.ChangeCursor{ChangedAt: parsed, ID: id}, nil
+}
@@ -0,0 +1,78 @@
+package deltasync
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+func TestSyncCursorRoundTrip(t *testing.T) {
+	changedAt := time.Date(2026, 10, 16, 10, 0, 0, 123456789, time.UTC)
+	tests := []struct {
+		name   string
+		cursor syncCursor
+	}{
+		{"both positions", syncCursor{
+			Transactions:  &repo.ChangeCursor{ChangedAt: changedAt, ID: "7f1c1f1e-8f55-4c55-9b8e-6a4f2c0d9a11"},
+			Notifications: &repo.ChangeCursor{ChangedAt: changedAt.Add(time.Second), ID: "0b6c2a4e-1d2f-4b8e-9c3a-5e7f9a1b3c5d"},
+		}},
+		// A stream without changes yet keeps no position
+		{"no notifications yet", syncCursor{
+			Transactions: &repo.ChangeCursor{ChangedAt: changedAt, ID: "7f1c1f1e-8f55-4c55-9b8e-6a4f2c0d9a11"},
+		}},
+		{"empty", syncCursor{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			decoded, err := decodeSyncCursor(encodeSyncCursor(test.cursor))
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkChangeCursor(t, "transactions", decoded.Transactions, test.cursor.Transactions)
+			checkChangeCursor(t, "notifications", decoded.Notifications, test.cursor.Notifications)
+		})
+	}
+
+	if cursor, err := decodeSyncCursor(""); err != nil || cursor.Transactions != nil || cursor.Notifications != nil {
+		t.Errorf("empty token decoded to %+v, %v, want the start", cursor, err)
+	}
+}
+
+func TestDecodeSyncCursorInvalid(t *testing.T) {
+	encode := func(raw string) string { return base64.RawURLEncoding.EncodeToString([]byte(raw)) }
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"not base64", "not a cursor!"},
+		{"too few parts", encode("2026-10-16T10:00:00Z|7f1c1f1e-8f55-4c55-9b8e-6a4f2c0d9a11")},
+		{"too many parts", encode("||||")},
+		{"invalid time", encode("yesterday|7f1c1f1e-8f55-4c55-9b8e-6a4f2c0d9a11||")},
+		{"time without ID", encode("2026-10-16T10:00:00Z|||")},
+		{"ID not a UUID", encode("||2026-10-16T10:00:00Z|1 OR 1=1")},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if cursor, err := decodeSyncCursor(test.token); !errors.Is(err, utils.ErrValidation) {
+				t.Errorf("decoded %+v, %v, want a validation error", cursor, err)
+			}
+		})
+	}
+}
+
+func checkChangeCursor(t *testing.T, stream string, got, want *repo.ChangeCursor) {
+	t.Helper()
+	if (got == nil) != (want == nil) {
+		t.Fatalf("%s position %+v, want %+v", stream, got, want)
+	}
+	if want != nil && (!got.ChangedAt.Equal(want.ChangedAt) || got.ID != want.ID) {
+		t.Errorf("%s position %+v, want %+v", stream, got, want)
+	}
+}
@@ -1,11 +1,51 @@
 package app
 
 import (
-	"database/sql"
+	"log"
+	"strconv"
+	"time"
 
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/activity"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/apikeys"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/archive"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/balancealerts"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/balancehistory"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/banklinks"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/bills"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/budgets"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/calendar"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/captcha"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/confirmation"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/delegations"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/deltasync"
 	"github.com/CodeWithKrushnal/ChainBank/internal/app/ethereum"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/exports"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/externalwallets"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/features"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/hdwallets"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/insights"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/invoices"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/jobs"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/keyrotation"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/leader"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/merchants"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/notification"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/offramp"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/onramp"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/organizations"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/outbox"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/paymentrequests"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/piibackfill"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/publicstats"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/recovery"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/reversals"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/roles"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/settings"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/siwe"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/sweeps"
 	"github.com/CodeWithKrushnal/ChainBank/internal/app/user"
 	"github.com/CodeWithKrushnal/ChainBank/internal/app/wallet"
+	"github.com/CodeWithKrushnal/ChainBank/internal/config"
 	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
 	"github.com/CodeWithKrushnal/ChainBank/middleware"
 	"github.com/ethereum/go-ethereum/ethclient"
@@ -13,27 +53,227 @@ import (
 
 // Dependencies struct for dependency injection
 type Dependencies struct {
-	UserService       user.Service
-	WalletService     wallet.Service
-	MiddlewareService middleware.Service
+	UserService           user.Service
+	WalletService         wallet.Service
+	MiddlewareService     middleware.Service
+	ArchiveService        archive.Service
+	SettingsService       settings.Service
+	FeatureService        features.Service
+	CalendarService       calendar.Service
+	NotificationService   notification.Service
+	RecoveryService       recovery.Service
+	APIKeyService         apikeys.Service
+	KeyRotationService    keyrotation.Service
+	PIIBackfillService    piibackfill.Service
+	HDWalletService       hdwallets.Service
+	BalanceHistoryService balancehistory.Service
+	InsightsService       insights.Service
+	BudgetService         budgets.Service
+	BalanceAlertService   balancealerts.Service
+	OrganizationService   organizations.Service
+	DelegationService     delegations.Service
+	PublicStatsService    publicstats.Service
+	ConfirmationService   confirmation.Service
+	ExternalWalletService externalwallets.Service
+	SIWEService           siwe.Service
+	PaymentRequestService paymentrequests.Service
+	BillService           bills.Service
+	MerchantService       merchants.Service
+	InvoiceService        invoices.Service
+	SweepService          sweeps.Service
+	ReversalService       reversals.Service
+	OnrampService         onramp.Service
+	OfframpService        offramp.Service
+	BankLinkService       banklinks.Service
+	ExportService         exports.Service
+	ActivityService       activity.Service
+	CaptchaService        captcha.Service
+	RoleService           roles.Service
+	SyncService           deltasync.Service
+	JobService            jobs.Service
+	OutboxService         outbox.Service
+	LeaderService         leader.Service
+	RateLimiter           *middleware.RateLimiter
+	// Stricter limit of the unauthenticated /public endpoints, applied on top of RateLimiter
+	PublicRateLimiter *middleware.RateLimiter
+	// Nil on the simulated chain, which needs no funding account
+	FaucetSigner *ethereum.FailoverSigner
 }
 
 // NewDependencies initializes all dependencies
-func NewDependencies(db *sql.DB, ethClient *ethclient.Client) *Dependencies {
+func NewDependencies(dbRouter *repo.DBRouter, ethClient *ethclient.Client) *Dependencies {
 	// Initialize repositories
-	userRepo := repo.NewUserRepo(db)
-	walletRepo := repo.NewWalletRepo(db)
-	ethRepo := ethereum.NewEthRepo(ethClient)
+	keyRing := config.PrivateKeyRing()
+	userRepo := repo.NewUserRepo(dbRouter.Writer(), keyRing)
+	walletRepo := repo.NewWalletRepo(dbRouter.Writer(), keyRing)
+	transactionRepo := repo.NewTransactionRepo(dbRouter)
+	archiveRepo := repo.NewArchiveRepo(dbRouter)
+	settingsRepo := repo.NewSettingsRepo(dbRouter.Writer())
+	featureRepo := repo.NewFeatureRepo(dbRouter.Writer())
+	holidayRepo := repo.NewHolidayRepo(dbRouter.Writer())
+	notificationRepo := repo.NewNotificationRepo(dbRouter.Writer())
+	apiKeyRepo := repo.NewAPIKeyRepo(dbRouter.Writer())
+	balanceRepo := repo.NewBalanceRepo(dbRouter)
+	budgetRepo := repo.NewBudgetRepo(dbRouter.Writer())
+	balanceAlertRepo := repo.NewBalanceAlertRepo(dbRouter.Writer())
+	organizationRepo := repo.NewOrganizationRepo(dbRouter.Writer())
+	delegationRepo := repo.NewDelegationRepo(dbRouter.Writer())
+	userImportRepo := repo.NewUserImportRepo(dbRouter.Writer())
+	confirmationRepo := repo.NewTransferConfirmationRepo(dbRouter.Writer(), keyRing)
+	externalWalletRepo := repo.NewExternalWalletRepo(dbRouter.Writer())
+	siweNonceRepo := repo.NewSIWENonceRepo(dbRouter.Writer())
+	refreshTokenRepo := repo.NewRefreshTokenRepo(dbRouter.Writer())
+	paymentRequestRepo := repo.NewPaymentRequestRepo(dbRouter.Writer())
+	billRepo := repo.NewBillRepo(dbRouter.Writer())
+	merchantRepo := repo.NewMerchantRepo(dbRouter.Writer(), keyRing)
+	invoiceRepo := repo.NewInvoiceRepo(dbRouter.Writer())
+	sweepRepo := repo.NewSweepRepo(dbRouter.Writer())
+	reversalRepo := repo.NewReversalRepo(dbRouter.Writer())
+	fiatDepositRepo := repo.NewFiatDepositRepo(dbRouter.Writer())
+	fiatWithdrawalRepo := repo.NewFiatWithdrawalRepo(dbRouter.Writer())
+	bankLinkRepo := repo.NewBankLinkRepo(dbRouter.Writer(), keyRing)
+	exportJobRepo := repo.NewExportJobRepo(dbRouter.Writer())
+	loginEventRepo := repo.NewLoginEventRepo(dbRouter.Writer())
+	activityRepo := repo.NewActivityRepo(dbRouter.Writer())
+	outboxRepo := repo.NewOutboxRepo(dbRouter.Writer())
+	lockRepo := repo.NewLockRepo(dbRouter.Writer())
+	roleRepo := repo.NewRoleRepo(dbRouter.Writer())
+	jobRepo := repo.NewJobRepo(dbRouter.Writer())
+	// Rate limit counters stay in memory unless they are shared through the database
+	var rateLimitRepo repo.RateLimitStorer
+	if config.ConfigDetails.RateLimitStore == "database" {
+		rateLimitRepo = repo.NewRateLimitRepo(dbRouter.Writer())
+	}
+	var ethRepo ethereum.EthRepo
+	var faucetSigner *ethereum.FailoverSigner
+	if config.ConfigDetails.SimulatedChain {
+		ethRepo = ethereum.NewSimulatedEthRepo()
+	} else {
+		var err error
+		faucetSigner, err = config.FaucetSigner()
+		if err != nil {
+			log.Fatalf("Error configuring the faucet signer: %v", err)
+		}
+		if err := faucetSigner.CheckHealth(); err != nil {
+			log.Printf("Faucet signer unavailable at startup: %v", err)
+		}
+		ethRepo = ethereum.NewEthRepo(ethClient, faucetSigner)
+	}
+
+	hdWallet, err := config.HDWallet()
+	if err != nil {
+		log.Fatalf("Error configuring HD wallets: %v", err)
+	}
 
 	// Initialize services
-	userService := user.NewService(userRepo, walletRepo, ethRepo)
-	walletService := wallet.NewService(userRepo, walletRepo, ethRepo)
-	middlewareService := middleware.NewService(userRepo, walletRepo)
+	settingsService := settings.NewService(settingsRepo)
+	if err := settingsService.Load(); err != nil {
+		log.Printf("Error loading runtime settings, using defaults: %v", err)
+	}
+	featureService := features.NewService(featureRepo)
+	if err := featureService.Load(); err != nil {
+		log.Printf("Error loading feature flags, using config defaults: %v", err)
+	}
+	calendarService := calendar.NewService(holidayRepo)
+	if err := calendarService.Load(); err != nil {
+		log.Printf("Error loading holidays, only weekends are skipped: %v", err)
+	}
+
+	// Services register their job queues and outbox subscribers as they are created
+	jobService := jobs.NewService(jobRepo)
+	outboxService := outbox.NewService(outboxRepo)
+	leaderService := leader.NewService(lockRepo)
+	notificationService := notification.NewService(notificationRepo, userRepo, jobService, outboxService)
+	userService := user.NewService(userRepo, walletRepo, transactionRepo, userImportRepo, loginEventRepo, refreshTokenRepo, notificationService, ethRepo, hdWallet)
+	balanceAlertService := balancealerts.NewService(balanceAlertRepo, ethRepo, notificationService)
+	confirmationService := confirmation.NewService(confirmationRepo, userRepo, settingsService)
+	walletService := wallet.NewService(userRepo, walletRepo, transactionRepo, fiatWithdrawalRepo, lockRepo, ethRepo, settingsService, notificationService, balanceAlertService, confirmationService)
+	middlewareService := middleware.NewService(userRepo, walletRepo, apiKeyRepo, delegationRepo, roleRepo)
+	archiveService := archive.NewService(archiveRepo, jobService)
+	recoveryService := recovery.NewService(transactionRepo, walletRepo, ethRepo)
+	apiKeyService := apikeys.NewService(apiKeyRepo)
+	keyRotationService := keyrotation.NewService(walletRepo)
+	piiBackfillService := piibackfill.NewService(userRepo)
+	hdWalletService := hdwallets.NewService(walletRepo, hdWallet)
+	balanceHistoryService := balancehistory.NewService(balanceRepo, walletRepo, ethRepo, jobService)
+	insightsService := insights.NewService(transactionRepo, userRepo)
+	budgetService := budgets.NewService(budgetRepo, transactionRepo, userRepo, notificationService)
+	organizationService := organizations.NewService(organizationRepo, userRepo, walletRepo, ethRepo)
+	delegationService := delegations.NewService(delegationRepo, userRepo)
+	publicStatsService := publicstats.NewService(transactionRepo)
+	externalWalletService := externalwallets.NewService(externalWalletRepo, walletRepo, transactionRepo, ethRepo)
+	siweService := siwe.NewService(siweNonceRepo, externalWalletRepo, userRepo, userService)
+	paymentRequestService := paymentrequests.NewService(paymentRequestRepo, walletRepo, walletService, notificationService)
+	billService := bills.NewService(billRepo, paymentRequestRepo, walletRepo, notificationService)
+	merchantService := merchants.NewService(merchantRepo, walletRepo, walletService, jobService)
+	invoiceService := invoices.NewService(invoiceRepo, walletRepo, userRepo, calendarService, notificationService)
+	sweepService := sweeps.NewService(sweepRepo, walletRepo, externalWalletRepo, transactionRepo, fiatWithdrawalRepo, lockRepo, ethRepo, notificationService)
+	reversalService := reversals.NewService(reversalRepo, transactionRepo, walletRepo, ethRepo, notificationService)
+	onrampService := onramp.NewService(fiatDepositRepo, userRepo, walletRepo, ethRepo, notificationService, jobService)
+	bankLinkService := banklinks.NewService(bankLinkRepo)
+	offrampService := offramp.NewService(fiatWithdrawalRepo, userRepo, walletRepo, transactionRepo, merchantRepo, lockRepo, ethRepo, confirmationService, notificationService)
+	exportService := exports.NewService(exportJobRepo, transactionRepo)
+	activityService := activity.NewService(activityRepo)
+	captchaService := captcha.NewService(rateLimitRepo)
+	roleService := roles.NewService(roleRepo, userRepo)
+	syncService := deltasync.NewService(walletService, notificationService)
+
+	// Rate limiter follows the runtime setting without a restart
+	rateLimiter := middleware.NewRateLimiter("api", time.Minute, rateLimitRepo)
+	settingsService.Subscribe(settings.RateLimitRequestsPerMinute, func(value string) {
+		limit, _ := strconv.Atoi(value)
+		rateLimiter.SetLimit(limit)
+	})
+	publicRateLimiter := middleware.NewRateLimiter("public", time.Minute, rateLimitRepo)
+	settingsService.Subscribe(settings.PublicRequestsPerMinute, func(value string) {
+		limit, _ := strconv.Atoi(value)
+		publicRateLimiter.SetLimit(limit)
+	})
 
 	// Return initialized dependencies
 	return &Dependencies{
-		UserService:       userService,
-		WalletService:     walletService,
-		MiddlewareService: middlewareService,
+		UserService:           userService,
+		WalletService:         walletService,
+		MiddlewareService:     middlewareService,
+		ArchiveService:        archiveService,
+		SettingsService:       settingsService,
+		FeatureService:        featureService,
+		CalendarService:       calendarService,
+		NotificationService:   notificationService,
+		RecoveryService:       recoveryService,
+		APIKeyService:         apiKeyService,
+		KeyRotationService:    keyRotationService,
+		PIIBackfillService:    piiBackfillService,
+		HDWalletService:       hdWalletService,
+		BalanceHistoryService: balanceHistoryService,
+		InsightsService:       insightsService,
+		BudgetService:         budgetService,
+		BalanceAlertService:   balanceAlertService,
+		OrganizationService:   organizationService,
+		DelegationService:     delegationService,
+		PublicStatsService:    publicStatsService,
+		ConfirmationService:   confirmationService,
+		ExternalWalletService: externalWalletService,
+		SIWEService:           siweService,
+		PaymentRequestService: paymentRequestService,
+		BillService:           billService,
+		MerchantService:       merchantService,
+		InvoiceService:        invoiceService,
+		SweepService:          sweepService,
+		ReversalService:       reversalService,
+		OnrampService:         onrampService,
+		OfframpService:        offrampService,
+		BankLinkService:       bankLinkService,
+		ExportService:         exportService,
+		ActivityService:       activityService,
+		CaptchaService:        captchaService,
+		RoleService:           roleService,
+		SyncService:           syncService,
+		JobService:            jobService,
+		OutboxService:         outboxService,
+		LeaderService:         leaderService,
+		RateLimiter:           rateLimiter,
+		PublicRateLimiter:     publicRateLimiter,
+		FaucetSigner:          faucetSigner,
 	}
 }
@@ -0,0 +1,118 @@
+package ethereum
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// BIP-32 constants, accounts are derived along the BIP-44 path of Ethereum m/44'/60'/0'/0/index
+const (
+	hardenedOffset = 0x80000000
+	hdMasterKey    = "Bitcoin seed"
+	MinHDSeedBytes = 16
+	MaxHDSeedBytes = 64
+)
+
+var hdAccountPath = []uint32{hardenedOffset + 44, hardenedOffset + 60, hardenedOffset + 0, 0}
+
+// HDWallet derives user accounts from the platform master seed. Only the extended key of the
+// parent path is kept, so the seed itself does not stay in memory.
+type HDWallet struct {
+	key       []byte
+	chainCode []byte
+}
+
+// Constructor function
+func NewHDWallet(seed []byte) (*HDWallet, error) {
+	if len(seed) < MinHDSeedBytes || len(seed) > MaxHDSeedBytes {
+		return nil, fmt.Errorf("HD wallet seed must be %d to %d bytes, got %d", MinHDSeedBytes, MaxHDSeedBytes, len(seed))
+	}
+
+	key, chainCode, err := newMasterKey(seed)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, index := range hdAccountPath {
+		if key, chainCode, err = deriveChildKey(key, chainCode, index); err != nil {
+			return nil, err
+		}
+	}
+	return &HDWallet{key: key, chainCode: chainCode}, nil
+}
+
+// Derive returns the private key of the account at index
+func (hw *HDWallet) Derive(index uint32) (*ecdsa.PrivateKey, error) {
+	if index >= hardenedOffset {
+		return nil, fmt.Errorf("HD wallet index %d is out of range", index)
+	}
+	key, _, err := deriveChildKey(hw.key, hw.chainCode, index)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.ToECDSA(key)
+}
+
+// Address returns the address of the account at index, used to check a stored wallet against the seed
+func (hw *HDWallet) Address(index uint32) (string, error) {
+	privateKey, err := hw.Derive(index)
+	if err != nil {
+		return "", err
+	}
+	return crypto.PubkeyToAddress(privateKey.PublicKey).Hex(), nil
+}
+
+// HDPath returns the derivation path of the account at index
+func HDPath(index uint32) string {
+	return fmt.Sprintf("m/44'/60'/0'/0/%d", index)
+}
+
+// newMasterKey computes the master private key and chain code of a seed as specified by BIP-32
+func newMasterKey(seed []byte) ([]byte, []byte, error) {
+	mac := hmac.New(sha512.New, []byte(hdMasterKey))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+
+	if _, err := crypto.ToECDSA(sum[:32]); err != nil {
+		return nil, nil, fmt.Errorf("HD wallet seed gives an invalid master key, use another seed")
+	}
+	return sum[:32], sum[32:], nil
+}
+
+// deriveChildKey computes the private child key and chain code at index as specified by BIP-32
+func deriveChildKey(key, chainCode []byte, index uint32) ([]byte, []byte, error) {
+	var data []byte
+	if index >= hardenedOffset {
+		data = append([]byte{0}, key...)
+	} else {
+		privateKey, err := crypto.ToECDSA(key)
+		if err != nil {
+			return nil, nil, err
+		}
+		data = crypto.CompressPubkey(&privateKey.PublicKey)
+	}
+	data = binary.BigEndian.AppendUint32(data, index)
+
+	mac := hmac.New(sha512.New, chainCode)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+
+	// The child is invalid with a probability below 2^-127, BIP-32 then moves on to the next index
+	order := crypto.S256().Params().N
+	tweak := new(big.Int).SetBytes(sum[:32])
+	if tweak.Cmp(order) >= 0 {
+		return nil, nil, fmt.Errorf("HD wallet index %d gives an invalid key", index)
+	}
+	child := tweak.Add(tweak, new(big.Int).SetBytes(key))
+	child.Mod(child, order)
+	if child.Sign() == 0 {
+		return nil, nil, fmt.Errorf("HD wallet index %d gives an invalid key", index)
+	}
+	return child.FillBytes(make([]byte, 32)), sum[32:], nil
+}
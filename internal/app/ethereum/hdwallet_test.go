@@ -0,0 +1,129 @@
+package ethereum
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// BIP-32 test vectors 1 and 2, each step derives the next path element from the previous key
+var bip32Vectors = []struct {
+	seed  string
+	steps []struct {
+		index uint32
+		xprv  string
+	}
+}{
+	{
+		seed: "000102030405060708090a0b0c0d0e0f",
+		steps: []struct {
+			index uint32
+			xprv  string
+		}{
+			{0, "xprv9s21ZrQH143K3QTDL4LXw2F7HEK3wJUD2nW2nRk4stbPy6cq3jPPqjiChkVvvNKmPGJxWUtg6LnF5kejMRNNU3TGtRBeJgk33yuGBxrMPHi"},
+			{hardenedOffset + 0, "xprv9uHRZZhk6KAJC1avXpDAp4MDc3sQKNxDiPvvkX8Br5ngLNv1TxvUxt4cV1rGL5hj6KCesnDYUhd7oWgT11eZG7XnxHrnYeSvkzY7d2bhkJ7"},
+			{1, "xprv9wTYmMFdV23N2TdNG573QoEsfRrWKQgWeibmLntzniatZvR9BmLnvSxqu53Kw1UmYPxLgboyZQaXwTCg8MSY3H2EU4pWcQDnRnrVA1xe8fs"},
+			{hardenedOffset + 2, "xprv9z4pot5VBttmtdRTWfWQmoH1taj2axGVzFqSb8C9xaxKymcFzXBDptWmT7FwuEzG3ryjH4ktypQSAewRiNMjANTtpgP4mLTj34bhnZX7UiM"},
+			{2, "xprvA2JDeKCSNNZky6uBCviVfJSKyQ1mDYahRjijr5idH2WwLsEd4Hsb2Tyh8RfQMuPh7f7RtyzTtdrbdqqsunu5Mm3wDvUAKRHSC34sJ7in334"},
+			{1000000000, "xprvA41z7zogVVwxVSgdKUHDy1SKmdb533PjDz7J6N6mV6uS3ze1ai8FHa8kmHScGpWmj4WggLyQjgPie1rFSruoUihUZREPSL39UNdE3BBDu76"},
+		},
+	},
+	{
+		seed: "fffcf9f6f3f0edeae7e4e1dedbd8d5d2cfccc9c6c3c0bdbab7b4b1aeaba8a5a29f9c999693908d8a8784817e7b7875726f6c696663605d5a5754514e4b484542",
+		steps: []struct {
+			index uint32
+			xprv  string
+		}{
+			{0, "xprv9s21ZrQH143K31xYSDQpPDxsXRTUcvj2iNHm5NUtrGiGG5e2DtALGdso3pGz6ssrdK4PFmM8NSpSBHNqPqm55Qn3LqFtT2emdEXVYsCzC2U"},
+			{0, "xprv9vHkqa6EV4sPZHYqZznhT2NPtPCjKuDKGY38FBWLvgaDx45zo9WQRUT3dKYnjwih2yJD9mkrocEZXo1ex8G81dwSM1fwqWpWkeS3v86pgKt"},
+			{hardenedOffset + 2147483647, "xprv9wSp6B7kry3Vj9m1zSnLvN3xH8RdsPP1Mh7fAaR7aRLcQMKTR2vidYEeEg2mUCTAwCd6vnxVrcjfy2kRgVsFawNzmjuHc2YmYRmagcEPdU9"},
+			{1, "xprv9zFnWC6h2cLgpmSA46vutJzBcfJ8yaJGg8cX1e5StJh45BBciYTRXSd25UEPVuesF9yog62tGAQtHjXajPPdbRCHuWS6T8XA2ECKADdw4Ef"},
+			{hardenedOffset + 2147483646, "xprvA1RpRA33e1JQ7ifknakTFpgNXPmW2YvmhqLQYMmrj4xJXXWYpDPS3xz7iAxn8L39njGVyuoseXzU6rcxFLJ8HFsTjSyQbLYnMpCqE2VbFWc"},
+			{2, "xprvA2nrNbFZABcdryreWet9Ea4LvTJcGsqrMzxHx98MMrotbir7yrKCEXw7nadnHM8Dq38EGfSh6dqA9QWTyefMLEcBYJUuekgW4BYPJcr9E7j"},
+		},
+	},
+}
+
+func TestBIP32Vectors(t *testing.T) {
+	for i, vector := range bip32Vectors {
+		seed, _ := hex.DecodeString(vector.seed)
+		key, chainCode, err := newMasterKey(seed)
+		if err != nil {
+			t.Fatalf("vector %d: master key: %v", i+1, err)
+		}
+
+		for j, step := range vector.steps {
+			// The first step of a vector is the master key itself
+			if j > 0 {
+				if key, chainCode, err = deriveChildKey(key, chainCode, step.index); err != nil {
+					t.Fatalf("vector %d step %d: %v", i+1, j, err)
+				}
+			}
+
+			wantChainCode, wantKey := decodeXprv(t, step.xprv)
+			if !bytes.Equal(chainCode, wantChainCode) {
+				t.Errorf("vector %d step %d: chain code %x, want %x", i+1, j, chainCode, wantChainCode)
+			}
+			if !bytes.Equal(key, wantKey) {
+				t.Errorf("vector %d step %d: key %x, want %x", i+1, j, key, wantKey)
+			}
+		}
+	}
+}
+
+// The BIP-39 mnemonic "abandon ... about" without passphrase, as wallets like MetaMask derive it
+func TestHDWalletKnownAddress(t *testing.T) {
+	mnemonic := strings.TrimSpace(strings.Repeat("abandon ", 11) + "about")
+	seed := pbkdf2.Key([]byte(mnemonic), []byte("mnemonic"), 2048, 64, sha512.New)
+
+	wallet, err := NewHDWallet(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	address, err := wallet.Address(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "0x9858EfFD232B4033E47d90003D41EC34EcaEda94"; address != want {
+		t.Errorf("address at %s = %s, want %s", HDPath(0), address, want)
+	}
+}
+
+func TestNewHDWalletSeedLength(t *testing.T) {
+	for _, length := range []int{MinHDSeedBytes - 1, MaxHDSeedBytes + 1} {
+		if _, err := NewHDWallet(make([]byte, length)); err == nil {
+			t.Errorf("seed of %d bytes accepted", length)
+		}
+	}
+}
+
+// decodeXprv returns the chain code and private key of a Base58Check encoded extended private key
+func decodeXprv(t *testing.T, xprv string) ([]byte, []byte) {
+	t.Helper()
+	const alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+	value := new(big.Int)
+	for _, char := range xprv {
+		digit := strings.IndexRune(alphabet, char)
+		if digit < 0 {
+			t.Fatalf("invalid base58 character %q in %s", char, xprv)
+		}
+		value.Mul(value, big.NewInt(58)).Add(value, big.NewInt(int64(digit)))
+	}
+
+	// version 4, depth 1, fingerprint 4, child number 4, chain code 32, 0x00 and key 32, checksum 4
+	decoded := value.FillBytes(make([]byte, 82))
+	payload, checksum := decoded[:78], decoded[78:]
+	first := sha256.Sum256(payload)
+	second := sha256.Sum256(first[:])
+	if !bytes.Equal(second[:4], checksum) {
+		t.Fatalf("bad checksum in %s", xprv)
+	}
+	return payload[13:45], payload[46:78]
+}
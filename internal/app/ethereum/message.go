@@ -0,0 +1,32 @@
+package ethereum
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ErrMalformedSignature is returned for signatures that are not 65 bytes of hex
+var ErrMalformedSignature = errors.New("signature must be a 65 byte hex string")
+
+// RecoverTextSigner returns the address that made an EIP-191 personal_sign signature of message,
+// as produced by wallets like MetaMask
+func RecoverTextSigner(message, signature string) (string, error) {
+	sig, err := hexutil.Decode(signature)
+	if err != nil || len(sig) != crypto.SignatureLength {
+		return "", ErrMalformedSignature
+	}
+	// Wallets put 27 or 28 in the recovery byte, the curve expects 0 or 1
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+
+	publicKey, err := crypto.SigToPub(accounts.TextHash([]byte(message)), sig)
+	if err != nil {
+		return "", fmt.Errorf("invalid signature: %w", err)
+	}
+	return crypto.PubkeyToAddress(*publicKey).Hex(), nil
+}
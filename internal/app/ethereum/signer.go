@@ -0,0 +1,262 @@
+package ethereum
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"log"
+	"math/big"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Ganache development account that funded new wallets before signers were configurable
+const (
+	devFaucetPrivateKeyHex = "ea97d6b94a9086cf06acdd6504b9e78e67af38d7fefaea5d05f96e2e9532aeea"
+	signerRequestTimeout   = 10 * time.Second
+	signerHealthInterval   = 30 * time.Second
+)
+
+// TransactionSigner signs transactions for a platform account without handing out its key
+type TransactionSigner interface {
+	Name() string
+	Address() common.Address
+	SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
+	CheckHealth() error
+}
+
+// localSigner holds the private key in process memory, meant for development chains
+type localSigner struct {
+	privateKey *ecdsa.PrivateKey
+	address    common.Address
+}
+
+// Constructor function
+func NewLocalSigner(privateKeyHex string) (TransactionSigner, error) {
+	privateKey, err := crypto.HexToECDSA(privateKeyHex)
+	if err != nil {
+		return nil, fmt.Errorf("parsing signer private key: %w", err)
+	}
+	return &localSigner{privateKey: privateKey, address: crypto.PubkeyToAddress(privateKey.PublicKey)}, nil
+}
+
+// NewDevFaucetSigner signs with the Ganache development account, used when no faucet signer is configured
+func NewDevFaucetSigner() TransactionSigner {
+	signer, _ := NewLocalSigner(devFaucetPrivateKeyHex)
+	return signer
+}
+
+func (ls *localSigner) Name() string {
+	return "local key"
+}
+
+func (ls *localSigner) Address() common.Address {
+	return ls.address
+}
+
+func (ls *localSigner) SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return types.SignTx(tx, types.NewEIP155Signer(chainID), ls.privateKey)
+}
+
+// CheckHealth always succeeds, the key is in memory
+func (ls *localSigner) CheckHealth() error {
+	return nil
+}
+
+// remoteSigner asks a JSON-RPC signer such as Web3Signer or Clef, usually backed by an HSM, to
+// sign with eth_signTransaction. The key never leaves the signer.
+type remoteSigner struct {
+	url     string
+	address common.Address
+	client  *rpc.Client
+}
+
+// Constructor function
+func NewRemoteSigner(url string, address common.Address) (TransactionSigner, error) {
+	client, err := rpc.DialOptions(context.Background(), url)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to signer %s: %w", url, err)
+	}
+	return &remoteSigner{url: url, address: address, client: client}, nil
+}
+
+// signTransactionArgs is the eth_signTransaction request of a legacy transaction
+type signTransactionArgs struct {
+	From     common.Address  `json:"from"`
+	To       *common.Address `json:"to"`
+	Gas      hexutil.Uint64  `json:"gas"`
+	GasPrice *hexutil.Big    `json:"gasPrice"`
+	Value    *hexutil.Big    `json:"value"`
+	Nonce    hexutil.Uint64  `json:"nonce"`
+	Data     hexutil.Bytes   `json:"data"`
+	ChainID  *hexutil.Big    `json:"chainId"`
+}
+
+func (rs *remoteSigner) Name() string {
+	return "remote signer " + rs.url
+}
+
+func (rs *remoteSigner) Address() common.Address {
+	return rs.address
+}
+
+// SignTx sends the transaction to the signer and checks that the signature really comes from the account
+func (rs *remoteSigner) SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), signerRequestTimeout)
+	defer cancel()
+
+	var raw hexutil.Bytes
+	err := rs.client.CallContext(ctx, &raw, "eth_signTransaction", signTransactionArgs{
+		From:     rs.address,
+		To:       tx.To(),
+		Gas:      hexutil.Uint64(tx.Gas()),
+		GasPrice: (*hexutil.Big)(tx.GasPrice()),
+		Value:    (*hexutil.Big)(tx.Value()),
+		Nonce:    hexutil.Uint64(tx.Nonce()),
+		Data:     tx.Data(),
+		ChainID:  (*hexutil.Big)(chainID),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", rs.Name(), err)
+	}
+
+	signedTx := new(types.Transaction)
+	if err := signedTx.UnmarshalBinary(raw); err != nil {
+		return nil, fmt.Errorf("%s returned an invalid transaction: %w", rs.Name(), err)
+	}
+	sender, err := types.Sender(types.NewEIP155Signer(chainID), signedTx)
+	if err != nil || sender != rs.address {
+		return nil, fmt.Errorf("%s did not sign as %s", rs.Name(), rs.address.Hex())
+	}
+	return signedTx, nil
+}
+
+// CheckHealth verifies that the signer answers and still manages the account
+func (rs *remoteSigner) CheckHealth() error {
+	ctx, cancel := context.WithTimeout(context.Background(), signerRequestTimeout)
+	defer cancel()
+
+	var accounts []common.Address
+	if err := rs.client.CallContext(ctx, &accounts, "eth_accounts"); err != nil {
+		return fmt.Errorf("%s: %w", rs.Name(), err)
+	}
+	if !slices.Contains(accounts, rs.address) {
+		return fmt.Errorf("%s does not manage %s", rs.Name(), rs.address.Hex())
+	}
+	return nil
+}
+
+// FailoverSigner signs with the first healthy signer, falling back to the next one on failure.
+// Every signer must sign for the same account.
+type FailoverSigner struct {
+	signers []TransactionSigner
+	mu      *sync.Mutex
+	// Signers whose last health check or signing attempt failed, they are tried last
+	unhealthy map[int]bool
+}
+
+// Constructor function
+func NewFailoverSigner(signers ...TransactionSigner) (*FailoverSigner, error) {
+	if len(signers) == 0 {
+		return nil, fmt.Errorf("at least one signer is required")
+	}
+	for _, signer := range signers[1:] {
+		if signer.Address() != signers[0].Address() {
+			return nil, fmt.Errorf("%s signs for %s, expected %s", signer.Name(), signer.Address().Hex(), signers[0].Address().Hex())
+		}
+	}
+	return &FailoverSigner{signers: signers, mu: &sync.Mutex{}, unhealthy: map[int]bool{}}, nil
+}
+
+func (fs *FailoverSigner) Name() string {
+	return "failover signer"
+}
+
+func (fs *FailoverSigner) Address() common.Address {
+	return fs.signers[0].Address()
+}
+
+// SignTx tries healthy signers in configured order before the unhealthy ones
+func (fs *FailoverSigner) SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	var lastErr error
+	for _, i := range fs.signingOrder() {
+		signedTx, err := fs.signers[i].SignTx(tx, chainID)
+		fs.setHealthy(i, err == nil)
+		if err == nil {
+			return signedTx, nil
+		}
+		log.Printf("Signing with %s failed, trying the next signer: %v", fs.signers[i].Name(), err)
+		lastErr = err
+	}
+	return nil, fmt.Errorf("no signer available: %w", lastErr)
+}
+
+// CheckHealth checks every signer and succeeds while at least one of them is healthy
+func (fs *FailoverSigner) CheckHealth() error {
+	var lastErr error
+	healthy := 0
+	for i, signer := range fs.signers {
+		err := signer.CheckHealth()
+		fs.setHealthy(i, err == nil)
+		if err != nil {
+			log.Printf("Signer health check failed: %v", err)
+			lastErr = err
+			continue
+		}
+		healthy++
+	}
+	if healthy == 0 {
+		return fmt.Errorf("no healthy signer: %w", lastErr)
+	}
+	return nil
+}
+
+// RunHealthChecks checks the signers periodically until stop is closed
+func (fs *FailoverSigner) RunHealthChecks(stop <-chan struct{}) {
+	ticker := time.NewTicker(signerHealthInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := fs.CheckHealth(); err != nil {
+				log.Printf("Platform signer unavailable: %v", err)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// signingOrder lists healthy signers first, each group in configured order
+func (fs *FailoverSigner) signingOrder() []int {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	var healthy, unhealthy []int
+	for i := range fs.signers {
+		if fs.unhealthy[i] {
+			unhealthy = append(unhealthy, i)
+		} else {
+			healthy = append(healthy, i)
+		}
+	}
+	return append(healthy, unhealthy...)
+}
+
+func (fs *FailoverSigner) setHealthy(i int, healthy bool) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if healthy {
+		delete(fs.unhealthy, i)
+	} else {
+		fs.unhealthy[i] = true
+	}
+}
@@ -0,0 +1,179 @@
+package ethereum
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"log"
+	"math/big"
+	"sync"
+
+	goethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// simulatedEthRepo is an in-memory chain for demos and local runs without an Ethereum node.
+// Balances and nonces live in memory, every accepted transaction is mined instantly.
+type simulatedEthRepo struct {
+	mu          *sync.Mutex
+	balances    map[common.Address]*big.Int
+	nonces      map[common.Address]uint64
+	receipts    map[common.Hash]*types.Receipt
+	blockNumber *big.Int
+}
+
+// Constructor function
+func NewSimulatedEthRepo() EthRepo {
+	log.Println("Using the simulated chain, no Ethereum node will be contacted")
+	return &simulatedEthRepo{
+		mu:          &sync.Mutex{},
+		balances:    map[common.Address]*big.Int{},
+		nonces:      map[common.Address]uint64{},
+		receipts:    map[common.Hash]*types.Receipt{},
+		blockNumber: big.NewInt(0),
+	}
+}
+
+// CreateWallet generates a new key pair, nothing is written to the keystore
+func (sim *simulatedEthRepo) CreateWallet(password string) (string, *ecdsa.PrivateKey, error) {
+	privateKey, err := crypto.GenerateKey()
+	if err != nil {
+		return "", nil, err
+	}
+	return crypto.PubkeyToAddress(privateKey.PublicKey).Hex(), privateKey, nil
+}
+
+// TransferFunds signs a legacy transaction using the simulated nonce of the sender
+func (sim *simulatedEthRepo) TransferFunds(fromPrivateKeyHex string, fromAddressHex string, toAddressHex string, amount *big.Int, gasPrice *big.Int, gasLimit uint64, chainID *big.Int) (*types.Transaction, error) {
+	privateKey, err := crypto.HexToECDSA(fromPrivateKeyHex)
+	if err != nil {
+		return nil, err
+	}
+
+	fromAddress := common.HexToAddress(fromAddressHex)
+	if derived := crypto.PubkeyToAddress(privateKey.PublicKey); derived != fromAddress {
+		return nil, fmt.Errorf("derived address (%s) does not match fromAddress (%s)", derived.Hex(), fromAddress.Hex())
+	}
+
+	sim.mu.Lock()
+	nonce := sim.nonces[fromAddress]
+	sim.mu.Unlock()
+
+	return signLegacyTx(fromPrivateKeyHex, toAddressHex, amount, gasPrice, gasLimit, nonce, chainID)
+}
+
+// SignWithNonce signs a legacy transaction with an explicit nonce. Transactions are mined
+// instantly on the simulated chain, so a replacement is rejected with an invalid nonce.
+func (sim *simulatedEthRepo) SignWithNonce(fromPrivateKeyHex string, toAddressHex string, amount *big.Int, gasPrice *big.Int, gasLimit uint64, nonce uint64, chainID *big.Int) (*types.Transaction, error) {
+	return signLegacyTx(fromPrivateKeyHex, toAddressHex, amount, gasPrice, gasLimit, nonce, chainID)
+}
+
+// PreloadTokens credits the wallet directly, there is no funding account on the simulated chain and
+// so no transaction hash either
+func (sim *simulatedEthRepo) PreloadTokens(walletAddress string, amount *big.Int) (string, error) {
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+
+	address := common.HexToAddress(walletAddress)
+	sim.balances[address] = new(big.Int).Add(sim.balanceOf(address), amount)
+	log.Printf("Simulated chain credited %s wei to %s", amount, address.Hex())
+	return "", nil
+}
+
+// GetBalance returns the in-memory balance of the address
+func (sim *simulatedEthRepo) GetBalance(walletAddress string) (*big.Int, error) {
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+	return new(big.Int).Set(sim.balanceOf(common.HexToAddress(walletAddress))), nil
+}
+
+// GetTransactionCount returns the simulated nonce of the address
+func (sim *simulatedEthRepo) GetTransactionCount(walletAddress string) (uint64, error) {
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+	return sim.nonces[common.HexToAddress(walletAddress)], nil
+}
+
+// SendTransaction validates and applies a signed transaction, mining it immediately
+func (sim *simulatedEthRepo) SendTransaction(signedTx *types.Transaction) error {
+	sender, err := types.Sender(types.NewEIP155Signer(ChainID), signedTx)
+	if err != nil {
+		return fmt.Errorf("invalid transaction signature: %w", err)
+	}
+
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+
+	if _, known := sim.receipts[signedTx.Hash()]; known {
+		return fmt.Errorf("already known")
+	}
+	if expected := sim.nonces[sender]; signedTx.Nonce() != expected {
+		return fmt.Errorf("invalid nonce: have %d, want %d", signedTx.Nonce(), expected)
+	}
+
+	gasCost := new(big.Int).Mul(new(big.Int).SetUint64(signedTx.Gas()), signedTx.GasPrice())
+	totalCost := new(big.Int).Add(signedTx.Value(), gasCost)
+	if sim.balanceOf(sender).Cmp(totalCost) < 0 {
+		return fmt.Errorf("insufficient funds for gas * price + value")
+	}
+
+	sim.balances[sender] = new(big.Int).Sub(sim.balanceOf(sender), totalCost)
+	if to := signedTx.To(); to != nil {
+		sim.balances[*to] = new(big.Int).Add(sim.balanceOf(*to), signedTx.Value())
+	}
+	sim.nonces[sender]++
+
+	sim.blockNumber = new(big.Int).Add(sim.blockNumber, big.NewInt(1))
+	sim.receipts[signedTx.Hash()] = &types.Receipt{
+		Type:              signedTx.Type(),
+		Status:            types.ReceiptStatusSuccessful,
+		CumulativeGasUsed: signedTx.Gas(),
+		GasUsed:           signedTx.Gas(),
+		TxHash:            signedTx.Hash(),
+		BlockHash:         common.BigToHash(sim.blockNumber),
+		BlockNumber:       new(big.Int).Set(sim.blockNumber),
+	}
+
+	log.Printf("Simulated chain mined %s in block %s", signedTx.Hash().Hex(), sim.blockNumber)
+	return nil
+}
+
+// GetReceipt returns the receipt of a simulated transaction
+func (sim *simulatedEthRepo) GetReceipt(txHash string) (*types.Receipt, error) {
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+
+	receipt, ok := sim.receipts[common.HexToHash(txHash)]
+	if !ok {
+		return nil, goethereum.NotFound
+	}
+	return receipt, nil
+}
+
+// GetBlockNumber returns the number of the latest simulated block
+func (sim *simulatedEthRepo) GetBlockNumber() (uint64, error) {
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+
+	return sim.blockNumber.Uint64(), nil
+}
+
+// GetBlockHash returns the hash of a simulated block, the simulated chain never reorganizes
+func (sim *simulatedEthRepo) GetBlockHash(blockNumber uint64) (string, error) {
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+
+	if sim.blockNumber.Cmp(new(big.Int).SetUint64(blockNumber)) < 0 {
+		return "", goethereum.NotFound
+	}
+	return common.BigToHash(new(big.Int).SetUint64(blockNumber)).Hex(), nil
+}
+
+// balanceOf returns the stored balance or zero, callers must hold the lock
+func (sim *simulatedEthRepo) balanceOf(address common.Address) *big.Int {
+	if balance, ok := sim.balances[address]; ok {
+		return balance
+	}
+	return new(big.Int)
+}
@@ -0,0 +1,44 @@
+package ethereum
+
+import (
+	"encoding/hex"
+	"io"
+	"log"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Signs and mines one transfer per iteration, the chain side of /api/transfer on the simulated chain
+func BenchmarkSimulatedTransfer(b *testing.B) {
+	output := log.Writer()
+	log.SetOutput(io.Discard)
+	b.Cleanup(func() { log.SetOutput(output) })
+
+	sim := NewSimulatedEthRepo()
+	sender, privateKey, err := sim.CreateWallet("")
+	if err != nil {
+		b.Fatal(err)
+	}
+	receiver, _, err := sim.CreateWallet("")
+	if err != nil {
+		b.Fatal(err)
+	}
+	if _, err := sim.PreloadTokens(sender, new(big.Int).Mul(big.NewInt(1e18), big.NewInt(int64(b.N)+1))); err != nil {
+		b.Fatal(err)
+	}
+	privateKeyHex := hex.EncodeToString(crypto.FromECDSA(privateKey))
+	amount := big.NewInt(1000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		signedTx, err := sim.TransferFunds(privateKeyHex, sender, receiver, amount, DefaultGasPrice, TransferGasLimit, ChainID)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := sim.SendTransaction(signedTx); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
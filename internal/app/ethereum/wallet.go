@@ -8,26 +8,44 @@ import (
 	"math/big"
 	"os"
 
+	goethereum "github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
 )
 
+// Transaction parameters used for plain ETH transfers on the Ganache network
+var (
+	DefaultGasPrice  = big.NewInt(20000000000) // 20 Gwei
+	TransferGasLimit = uint64(21000)
+	ChainID          = big.NewInt(1337) // Ganache
+)
+
 type ethRepo struct {
 	ethereumClient *ethclient.Client
+	// Signs for the platform account funding new wallets
+	faucetSigner TransactionSigner
 }
 
 // Constructor function
-func NewEthRepo(ethereumClient *ethclient.Client) EthRepo {
-	return &ethRepo{ethereumClient: ethereumClient}
+func NewEthRepo(ethereumClient *ethclient.Client, faucetSigner TransactionSigner) EthRepo {
+	return &ethRepo{ethereumClient: ethereumClient, faucetSigner: faucetSigner}
 }
 
 type EthRepo interface {
 	CreateWallet(password string) (string, *ecdsa.PrivateKey, error)
 	TransferFunds(fromPrivateKeyHex string, fromAddressHex string, toAddressHex string, amount *big.Int, gasPrice *big.Int, gasLimit uint64, chainID *big.Int) (*types.Transaction, error)
-	PreloadTokens(walletAddress string, amount *big.Int) error
+	PreloadTokens(walletAddress string, amount *big.Int) (string, error)
+	GetBalance(walletAddress string) (*big.Int, error)
+	GetTransactionCount(walletAddress string) (uint64, error)
+	SendTransaction(signedTx *types.Transaction) error
+	GetReceipt(txHash string) (*types.Receipt, error)
+	GetBlockNumber() (uint64, error)
+	GetBlockHash(blockNumber uint64) (string, error)
+	SignWithNonce(fromPrivateKeyHex string, toAddressHex string, amount *big.Int, gasPrice *big.Int, gasLimit uint64, nonce uint64, chainID *big.Int) (*types.Transaction, error)
 }
 
 // CreateWallet generates a new Ethereum wallet
@@ -76,8 +94,6 @@ func (ethdep ethRepo) TransferFunds(fromPrivateKeyHex string, fromAddressHex str
 	fromAddress := common.HexToAddress(fromAddressHex)
 	toAddress := common.HexToAddress(toAddressHex)
 
-	log.Println("fromPrivateKeyHex", fromPrivateKeyHex)
-
 	// Parse the private key
 	privateKey, err := crypto.HexToECDSA(fromPrivateKeyHex)
 	if err != nil {
@@ -136,40 +152,108 @@ func (ethdep ethRepo) TransferFunds(fromPrivateKeyHex string, fromAddressHex str
 	return signedTx, nil
 }
 
-func (ethdep ethRepo) PreloadTokens(walletAddress string, amount *big.Int) error {
+// PreloadTokens sends amount from the funding account to the wallet and returns the transaction hash
+func (ethdep ethRepo) PreloadTokens(walletAddress string, amount *big.Int) (string, error) {
 	log.Println("Starting the token preloading process...")
 	if ethdep.ethereumClient == nil {
-		return fmt.Errorf("Ethereum client is not initialized")
+		return "", fmt.Errorf("Ethereum client is not initialized")
 	}
 
-	// Define the private key and sender address
-	fromPrivateKeyHex := "ea97d6b94a9086cf06acdd6504b9e78e67af38d7fefaea5d05f96e2e9532aeea"
-	fromAddressHex := "0x6AA382D6b0586027CF8491a81F691DC43AE281Da"
+	fromAddress := ethdep.faucetSigner.Address()
+	toAddress := common.HexToAddress(walletAddress)
+	log.Printf("From Address: %s, To Address: %s", fromAddress.Hex(), toAddress.Hex())
 
-	// Log the recipient address
-	toAddress := walletAddress
-	log.Printf("From Address: %s, To Address: %s", fromAddressHex, toAddress)
-
-	// Set gas price and gas limit
-	gasPrice := big.NewInt(20000000000) // 20 Gwei
-	gasLimit := uint64(21000)
-	chainID := big.NewInt(1337) // For Ganache
+	nonce, err := ethdep.ethereumClient.PendingNonceAt(context.Background(), fromAddress)
+	if err != nil {
+		log.Printf("Error fetching nonce: %v", err)
+		return "", err
+	}
 
-	// Call TransferFunds to handle the actual fund transfer
-	signedTx, err := ethdep.TransferFunds(fromPrivateKeyHex, fromAddressHex, toAddress, amount, gasPrice, gasLimit, chainID)
+	// Sign with the faucet signer, the key may live in an HSM behind it
+	signedTx, err := ethdep.faucetSigner.SignTx(types.NewTx(&types.LegacyTx{
+		Nonce:    nonce,
+		GasPrice: DefaultGasPrice,
+		Gas:      TransferGasLimit,
+		To:       &toAddress,
+		Value:    amount,
+	}), ChainID)
 	if err != nil {
-		log.Printf("Error during fund transfer: %v", err)
-		return err
+		log.Printf("Error signing faucet transfer: %v", err)
+		return "", err
 	}
 
 	// Send the transaction
 	err = ethdep.ethereumClient.SendTransaction(context.Background(), signedTx)
 	if err != nil {
 		log.Printf("Error sending transaction: %v", err)
-		return err
+		return "", err
 	}
 
 	log.Printf("Tokens successfully preloaded to wallet: %s. Transaction Hash: %s",
-		toAddress, signedTx.Hash().Hex())
-	return nil
+		toAddress.Hex(), signedTx.Hash().Hex())
+	return signedTx.Hash().Hex(), nil
+}
+
+// GetBalance returns the latest balance of the address in wei
+func (ethdep ethRepo) GetBalance(walletAddress string) (*big.Int, error) {
+	return ethdep.ethereumClient.BalanceAt(context.Background(), common.HexToAddress(walletAddress), nil)
+}
+
+// GetTransactionCount returns how many transactions the address has sent, its nonce in the latest block
+func (ethdep ethRepo) GetTransactionCount(walletAddress string) (uint64, error) {
+	return ethdep.ethereumClient.NonceAt(context.Background(), common.HexToAddress(walletAddress), nil)
+}
+
+// SendTransaction broadcasts a signed transaction
+func (ethdep ethRepo) SendTransaction(signedTx *types.Transaction) error {
+	return ethdep.ethereumClient.SendTransaction(context.Background(), signedTx)
+}
+
+// GetReceipt returns the receipt of a mined transaction, ethereum.NotFound while it is pending
+func (ethdep ethRepo) GetReceipt(txHash string) (*types.Receipt, error) {
+	return ethdep.ethereumClient.TransactionReceipt(context.Background(), common.HexToHash(txHash))
+}
+
+// GetBlockNumber returns the number of the latest block
+func (ethdep ethRepo) GetBlockNumber() (uint64, error) {
+	return ethdep.ethereumClient.BlockNumber(context.Background())
+}
+
+// GetBlockHash returns the hash of the canonical block at blockNumber as reported by the node,
+// which is what receipts refer to even on chains hashing headers differently
+func (ethdep ethRepo) GetBlockHash(blockNumber uint64) (string, error) {
+	var block *struct {
+		Hash common.Hash `json:"hash"`
+	}
+	err := ethdep.ethereumClient.Client().CallContext(context.Background(), &block, "eth_getBlockByNumber", hexutil.EncodeUint64(blockNumber), false)
+	if err != nil {
+		return "", err
+	}
+	if block == nil {
+		return "", goethereum.NotFound
+	}
+	return block.Hash.Hex(), nil
+}
+
+// SignWithNonce signs a legacy transaction with an explicit nonce, used to replace a transaction still in the mempool
+func (ethdep ethRepo) SignWithNonce(fromPrivateKeyHex string, toAddressHex string, amount *big.Int, gasPrice *big.Int, gasLimit uint64, nonce uint64, chainID *big.Int) (*types.Transaction, error) {
+	return signLegacyTx(fromPrivateKeyHex, toAddressHex, amount, gasPrice, gasLimit, nonce, chainID)
+}
+
+// signLegacyTx signs a plain value transfer using LegacyTxType for Ganache compatibility
+func signLegacyTx(fromPrivateKeyHex string, toAddressHex string, amount *big.Int, gasPrice *big.Int, gasLimit uint64, nonce uint64, chainID *big.Int) (*types.Transaction, error) {
+	privateKey, err := crypto.HexToECDSA(fromPrivateKeyHex)
+	if err != nil {
+		log.Printf("Error parsing private key: %v", err)
+		return nil, err
+	}
+
+	toAddress := common.HexToAddress(toAddressHex)
+	return types.SignNewTx(privateKey, types.NewEIP155Signer(chainID), &types.LegacyTx{
+		Nonce:    nonce,
+		GasPrice: gasPrice,
+		Gas:      gasLimit,
+		To:       &toAddress,
+		Value:    amount,
+	})
 }
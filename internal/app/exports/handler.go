@@ -0,0 +1,116 @@
+package exports
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/respond"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+	"github.com/gorilla/mux"
+)
+
+// CreateExportRequest asks for an export of dataset, csv unless format is json. from and to limit the
+// rows to those created in between, to exclusive.
+type CreateExportRequest struct {
+	Dataset string     `json:"dataset"`
+	Format  string     `json:"format"`
+	From    *time.Time `json:"from"`
+	To      *time.Time `json:"to"`
+}
+
+// ExportJobResponse represents an export job. Completed jobs carry a signed download_url of the
+// gzipped file that works without credentials until download_expires_at.
+type ExportJobResponse struct {
+	JobID             string     `json:"job_id"`
+	Dataset           string     `json:"dataset"`
+	Format            string     `json:"format"`
+	From              *time.Time `json:"from,omitempty"`
+	To                *time.Time `json:"to,omitempty"`
+	Status            string     `json:"status"`
+	RowCount          int        `json:"row_count"`
+	SizeBytes         int64      `json:"size_bytes"`
+	Error             string     `json:"error,omitempty"`
+	DownloadURL       string     `json:"download_url,omitempty"`
+	DownloadExpiresAt *time.Time `json:"download_expires_at,omitempty"`
+	CreatedBy         string     `json:"created_by"`
+	CreatedAt         time.Time  `json:"created_at"`
+	CompletedAt       *time.Time `json:"completed_at,omitempty"`
+}
+
+// ExportFile is the gzipped file of a completed export
+type ExportFile struct {
+	Name    string
+	Content []byte
+}
+
+type Handler struct {
+	service Service
+}
+
+// Constructor function
+func NewHandler(service Service) Handler {
+	return Handler{service: service}
+}
+
+// CreateExportHandler queues an export, needs export.manage
+func (hd Handler) CreateExportHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req CreateExportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	job, err := hd.service.CreateExport(userInfo.UserID, req)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, job)
+}
+
+// ListExportsHandler lists the latest exports, needs export.manage
+func (hd Handler) ListExportsHandler(w http.ResponseWriter, r *http.Request) {
+	jobs, err := hd.service.ListExports()
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.List(w, r, jobs, respond.Pagination{Count: len(jobs)})
+}
+
+// GetExportHandler returns the status of an export and its download link once completed, needs export.manage
+func (hd Handler) GetExportHandler(w http.ResponseWriter, r *http.Request) {
+	job, err := hd.service.GetExport(mux.Vars(r)["jobID"])
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, job)
+}
+
+// DownloadHandler serves the file of a completed export to holders of a valid signed link
+func (hd Handler) DownloadHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	file, err := hd.service.Download(mux.Vars(r)["jobID"], query.Get("expires"), query.Get("signature"))
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+file.Name+`"`)
+	w.Header().Set("Content-Length", strconv.Itoa(len(file.Content)))
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.Write(file.Content)
+}
@@ -0,0 +1,361 @@
+package exports
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/config"
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+// Datasets that can be exported
+const (
+	DatasetTransactions = "transactions"
+)
+
+// File formats
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// Status of an export job
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+const (
+	workerPollInterval = 10 * time.Second
+	purgeInterval      = time.Hour
+	// Running jobs not finished after this long are taken over by another worker
+	jobStaleAfter   = 30 * time.Minute
+	exportBatchSize = 1000
+	maxExportRows   = 1000000
+	listLimit       = 50
+)
+
+var (
+	datasets = []string{DatasetTransactions}
+	formats  = []string{FormatCSV, FormatJSON}
+
+	transactionColumns = []string{"transaction_id", "tx_hash", "sender_user_id", "receiver_user_id", "sender_wallet_id",
+		"receiver_wallet_id", "amount_wei", "status", "block_number", "created_at"}
+)
+
+type service struct {
+	exportJobRepo   repo.ExportJobStorer
+	transactionRepo repo.TransactionStorer
+}
+
+type Service interface {
+	CreateExport(adminID string, req CreateExportRequest) (ExportJobResponse, error)
+	ListExports() ([]ExportJobResponse, error)
+	GetExport(jobID string) (ExportJobResponse, error)
+	Download(jobID, expires, signature string) (ExportFile, error)
+	RunWorker(stop <-chan struct{})
+}
+
+// Constructor function
+func NewService(exportJobRepo repo.ExportJobStorer, transactionRepo repo.TransactionStorer) Service {
+	return service{
+		exportJobRepo:   exportJobRepo,
+		transactionRepo: transactionRepo,
+	}
+}
+
+// CreateExport queues an export, the worker picks it up within a few seconds
+func (sd service) CreateExport(adminID string, req CreateExportRequest) (ExportJobResponse, error) {
+	if !slices.Contains(datasets, req.Dataset) {
+		return ExportJobResponse{}, utils.Validationf("dataset must be one of %s", strings.Join(datasets, ", "))
+	}
+	if req.Format == "" {
+		req.Format = FormatCSV
+	}
+	if !slices.Contains(formats, req.Format) {
+		return ExportJobResponse{}, utils.Validationf("format must be one of %s", strings.Join(formats, ", "))
+	}
+	if req.From != nil && req.To != nil && !req.From.Before(*req.To) {
+		return ExportJobResponse{}, utils.Validation("from must be before to")
+	}
+
+	job, err := sd.exportJobRepo.CreateExportJob(repo.ExportJob{
+		CreatedBy: adminID,
+		Dataset:   req.Dataset,
+		Format:    req.Format,
+		From:      req.From,
+		To:        req.To,
+	})
+	if err != nil {
+		return ExportJobResponse{}, err
+	}
+	log.Printf("Export %s of %s requested by %s", job.JobID, job.Dataset, adminID)
+	return newExportJobResponse(job), nil
+}
+
+// ListExports returns the latest export jobs
+func (sd service) ListExports() ([]ExportJobResponse, error) {
+	jobs, err := sd.exportJobRepo.GetExportJobs(listLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]ExportJobResponse, len(jobs))
+	for i, job := range jobs {
+		responses[i] = newExportJobResponse(job)
+	}
+	return responses, nil
+}
+
+// GetExport returns the status of an export, with a fresh download link once it is completed
+func (sd service) GetExport(jobID string) (ExportJobResponse, error) {
+	job, err := sd.exportJobRepo.GetExportJob(jobID)
+	if err != nil {
+		return ExportJobResponse{}, err
+	}
+	return newExportJobResponse(job), nil
+}
+
+// Download returns the file of a completed export. The link is checked instead of the caller, so
+// it can be handed to a browser or a script without credentials until it expires.
+func (sd service) Download(jobID, expires, signature string) (ExportFile, error) {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt || !hmac.Equal([]byte(signature), []byte(signDownload(jobID, expiresAt))) {
+		return ExportFile{}, utils.Forbidden("download link is invalid or has expired")
+	}
+
+	job, err := sd.exportJobRepo.GetExportJob(jobID)
+	if err != nil {
+		return ExportFile{}, err
+	}
+	content, err := sd.exportJobRepo.GetExportContent(jobID)
+	if err != nil {
+		return ExportFile{}, err
+	}
+	return ExportFile{Name: fileName(job), Content: content}, nil
+}
+
+// RunWorker works through queued exports one at a time and deletes expired ones until stop is closed
+func (sd service) RunWorker(stop <-chan struct{}) {
+	poll := time.NewTicker(workerPollInterval)
+	defer poll.Stop()
+	purge := time.NewTicker(purgeInterval)
+	defer purge.Stop()
+
+	for {
+		select {
+		case <-poll.C:
+			sd.runQueuedJobs(stop)
+		case <-purge.C:
+			sd.purgeExpired()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// runQueuedJobs runs jobs until the queue is empty or stop is closed
+func (sd service) runQueuedJobs(stop <-chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		job, ok, err := sd.exportJobRepo.ClaimExportJob(jobStaleAfter)
+		if err != nil || !ok {
+			return
+		}
+		sd.runJob(job)
+	}
+}
+
+// runJob writes the rows of a job into a gzipped file and stores it
+func (sd service) runJob(job repo.ExportJob) {
+	log.Printf("Running export %s of %s", job.JobID, job.Dataset)
+
+	var file bytes.Buffer
+	compressed := gzip.NewWriter(&file)
+	rowCount, err := sd.writeTransactions(job, compressed)
+	if err == nil {
+		err = compressed.Close()
+	}
+	if err != nil {
+		log.Printf("Export %s failed: %v", job.JobID, err)
+		if err := sd.exportJobRepo.FailExportJob(job.JobID, err.Error()); err != nil {
+			log.Printf("Error recording failure of export %s: %v", job.JobID, err)
+		}
+		return
+	}
+
+	if err := sd.exportJobRepo.CompleteExportJob(job.JobID, rowCount, file.Bytes()); err != nil {
+		return
+	}
+	log.Printf("Export %s completed with %d rows, %d bytes", job.JobID, rowCount, file.Len())
+}
+
+// writeTransactions streams live and archived transactions of the job's period, oldest first, a
+// page at a time
+func (sd service) writeTransactions(job repo.ExportJob, w io.Writer) (int, error) {
+	writer := newRowWriter(job.Format, w)
+	if err := writer.header(transactionColumns); err != nil {
+		return 0, err
+	}
+
+	rowCount := 0
+	var cursor *repo.TransactionCursor
+	for {
+		transactions, err := sd.transactionRepo.ExportTransactions(job.From, job.To, cursor, exportBatchSize)
+		if err != nil {
+			return rowCount, err
+		}
+		if rowCount+len(transactions) > maxExportRows {
+			return rowCount, fmt.Errorf("export exceeds %d rows, narrow it down with from and to", maxExportRows)
+		}
+
+		for _, txn := range transactions {
+			blockNumber := ""
+			if txn.BlockNumber != nil {
+				blockNumber = strconv.FormatInt(*txn.BlockNumber, 10)
+			}
+			err := writer.row(txn, []string{txn.TransactionID, txn.TxHash, txn.SenderUserID, txn.ReceiverUserID, txn.SenderWalletID,
+				txn.ReceiverWalletID, txn.Amount, string(txn.Status), blockNumber, txn.CreatedAt.UTC().Format(time.RFC3339)})
+			if err != nil {
+				return rowCount, err
+			}
+		}
+		rowCount += len(transactions)
+
+		if len(transactions) < exportBatchSize {
+			return rowCount, writer.close()
+		}
+		last := transactions[len(transactions)-1]
+		cursor = &repo.TransactionCursor{CreatedAt: last.CreatedAt, TransactionID: last.TransactionID}
+	}
+}
+
+// purgeExpired deletes finished exports older than the retention period
+func (sd service) purgeExpired() {
+	cutoff := time.Now().AddDate(0, 0, -config.ConfigDetails.ExportRetentionDays)
+	deleted, err := sd.exportJobRepo.DeleteExportsBefore(cutoff)
+	if err != nil {
+		return
+	}
+	if deleted > 0 {
+		log.Printf("Deleted %d expired exports", deleted)
+	}
+}
+
+// rowWriter writes the rows of an export as CSV records or as the elements of a JSON array
+type rowWriter struct {
+	format  string
+	w       io.Writer
+	csv     *csv.Writer
+	written bool
+}
+
+func newRowWriter(format string, w io.Writer) *rowWriter {
+	writer := &rowWriter{format: format, w: w}
+	if format == FormatCSV {
+		writer.csv = csv.NewWriter(w)
+	}
+	return writer
+}
+
+// header writes the CSV header or opens the JSON array
+func (rw *rowWriter) header(columns []string) error {
+	if rw.format == FormatCSV {
+		return rw.csv.Write(columns)
+	}
+	_, err := io.WriteString(rw.w, "[")
+	return err
+}
+
+// row writes record to CSV files and value to JSON files
+func (rw *rowWriter) row(value any, record []string) error {
+	if rw.format == FormatCSV {
+		return rw.csv.Write(record)
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if rw.written {
+		if _, err := io.WriteString(rw.w, ",\n"); err != nil {
+			return err
+		}
+	}
+	rw.written = true
+	_, err = rw.w.Write(encoded)
+	return err
+}
+
+// close flushes the CSV records or closes the JSON array
+func (rw *rowWriter) close() error {
+	if rw.format == FormatCSV {
+		rw.csv.Flush()
+		return rw.csv.Error()
+	}
+	_, err := io.WriteString(rw.w, "]\n")
+	return err
+}
+
+// downloadURL returns a link to the export's file that is valid for the configured lifetime
+func downloadURL(jobID string, expiresAt time.Time) string {
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expiresAt.Unix(), 10))
+	query.Set("signature", signDownload(jobID, expiresAt.Unix()))
+	return "/exports/" + url.PathEscape(jobID) + "?" + query.Encode()
+}
+
+// signDownload authenticates a download link of jobID expiring at the given Unix time
+func signDownload(jobID string, expiresAt int64) string {
+	mac := hmac.New(sha256.New, []byte(config.ConfigDetails.JWTSecretKey))
+	fmt.Fprintf(mac, "export-download:%s:%d", jobID, expiresAt)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func fileName(job repo.ExportJob) string {
+	return fmt.Sprintf("%s-%s.%s.gz", job.Dataset, job.JobID, job.Format)
+}
+
+func newExportJobResponse(job repo.ExportJob) ExportJobResponse {
+	response := ExportJobResponse{
+		JobID:       job.JobID,
+		Dataset:     job.Dataset,
+		Format:      job.Format,
+		From:        job.From,
+		To:          job.To,
+		Status:      job.Status,
+		RowCount:    job.RowCount,
+		SizeBytes:   job.Size,
+		Error:       job.Error,
+		CreatedBy:   job.CreatedBy,
+		CreatedAt:   job.CreatedAt,
+		CompletedAt: job.CompletedAt,
+	}
+	if job.Status == StatusCompleted {
+		expiresAt := time.Now().Add(time.Duration(config.ConfigDetails.ExportLinkTTLMinutes) * time.Minute).Truncate(time.Second)
+		response.DownloadURL = downloadURL(job.JobID, expiresAt)
+		response.DownloadExpiresAt = &expiresAt
+	}
+	return response
+}
@@ -0,0 +1,161 @@
+package externalwallets
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/respond"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+	"github.com/gorilla/mux"
+)
+
+// LinkWalletRequest names an external address to link in watch-only mode
+type LinkWalletRequest struct {
+	Address string `json:"address"`
+	Label   string `json:"label"`
+}
+
+// LinkChallengeResponse carries the message to sign with the address, e.g. with personal_sign
+type LinkChallengeResponse struct {
+	Address   string    `json:"address"`
+	Message   string    `json:"message"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// VerifyWalletRequest carries the hex signature of the challenge message
+type VerifyWalletRequest struct {
+	Signature string `json:"signature"`
+}
+
+// ExternalWalletResponse represents a linked address, unverified until the challenge is signed
+type ExternalWalletResponse struct {
+	Address    string     `json:"address"`
+	Label      string     `json:"label,omitempty"`
+	Verified   bool       `json:"verified"`
+	VerifiedAt *time.Time `json:"verified_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// PortfolioResponse combines the platform wallet and the verified external wallets, amounts in wei
+type PortfolioResponse struct {
+	TotalBalanceWei string             `json:"total_balance_wei"`
+	Wallets         []PortfolioWallet  `json:"wallets"`
+	RecentActivity  []repo.Transaction `json:"recent_activity"`
+}
+
+// PortfolioWallet is one wallet of the portfolio, SentCount is the number of transactions it sent
+type PortfolioWallet struct {
+	Address    string  `json:"address"`
+	Label      string  `json:"label,omitempty"`
+	WatchOnly  bool    `json:"watch_only"`
+	BalanceWei string  `json:"balance_wei,omitempty"`
+	SentCount  *uint64 `json:"sent_count,omitempty"`
+	Error      string  `json:"error,omitempty"`
+}
+
+type Handler struct {
+	service Service
+}
+
+// Constructor function
+func NewHandler(service Service) Handler {
+	return Handler{service: service}
+}
+
+// LinkWalletHandler starts linking an external address of the authenticated user
+func (hd Handler) LinkWalletHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req LinkWalletRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	challenge, err := hd.service.LinkWallet(userInfo.UserID, req)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, challenge)
+}
+
+// VerifyWalletHandler completes linking an address with the signed challenge
+func (hd Handler) VerifyWalletHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req VerifyWalletRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	wallet, err := hd.service.VerifyWallet(userInfo.UserID, mux.Vars(r)["address"], req)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, wallet)
+}
+
+// ListWalletsHandler lists the external addresses of the authenticated user
+func (hd Handler) ListWalletsHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	wallets, err := hd.service.ListWallets(userInfo.UserID)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.List(w, r, wallets, respond.Pagination{Count: len(wallets)})
+}
+
+// UnlinkWalletHandler removes an external address of the authenticated user
+func (hd Handler) UnlinkWalletHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	if err := hd.service.UnlinkWallet(userInfo.UserID, mux.Vars(r)["address"]); err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetPortfolioHandler returns the consolidated balances and activity of the authenticated user
+func (hd Handler) GetPortfolioHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	portfolio, err := hd.service.GetPortfolio(userInfo.UserID)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, portfolio)
+}
@@ -0,0 +1,251 @@
+package externalwallets
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/ethereum"
+	"github.com/CodeWithKrushnal/ChainBank/internal/privacy"
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+const (
+	challengeTTL       = 15 * time.Minute
+	maxExternalWallets = 10
+	maxLabelLength     = 64
+	portfolioActivity  = 20
+)
+
+type service struct {
+	externalWalletRepo repo.ExternalWalletStorer
+	walletRepo         repo.WalletStorer
+	transactionRepo    repo.TransactionStorer
+	ethRepo            ethereum.EthRepo
+}
+
+type Service interface {
+	LinkWallet(userID string, req LinkWalletRequest) (LinkChallengeResponse, error)
+	VerifyWallet(userID, address string, req VerifyWalletRequest) (ExternalWalletResponse, error)
+	ListWallets(userID string) ([]ExternalWalletResponse, error)
+	UnlinkWallet(userID, address string) error
+	GetPortfolio(userID string) (PortfolioResponse, error)
+}
+
+// Constructor function
+func NewService(externalWalletRepo repo.ExternalWalletStorer, walletRepo repo.WalletStorer, transactionRepo repo.TransactionStorer, ethRepo ethereum.EthRepo) Service {
+	return service{
+		externalWalletRepo: externalWalletRepo,
+		walletRepo:         walletRepo,
+		transactionRepo:    transactionRepo,
+		ethRepo:            ethRepo,
+	}
+}
+
+// LinkWallet starts linking an external address and returns the message to sign with it. Asking
+// again before verifying replaces the challenge.
+func (sd service) LinkWallet(userID string, req LinkWalletRequest) (LinkChallengeResponse, error) {
+	address, err := parseAddress(req.Address)
+	if err != nil {
+		return LinkChallengeResponse{}, err
+	}
+	label := strings.TrimSpace(req.Label)
+	if len(label) > maxLabelLength {
+		return LinkChallengeResponse{}, utils.Validationf("label cannot be longer than %d characters", maxLabelLength)
+	}
+
+	// Only a new address counts towards the limit, refreshing a pending challenge does not
+	if _, err := sd.externalWalletRepo.GetExternalWallet(userID, address); errors.Is(err, utils.ErrNotFound) {
+		count, err := sd.externalWalletRepo.CountExternalWallets(userID)
+		if err != nil {
+			return LinkChallengeResponse{}, err
+		}
+		if count >= maxExternalWallets {
+			return LinkChallengeResponse{}, utils.Validationf("at most %d external wallets can be linked", maxExternalWallets)
+		}
+	} else if err != nil {
+		return LinkChallengeResponse{}, err
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return LinkChallengeResponse{}, fmt.Errorf("failed to generate challenge: %v", err)
+	}
+	expiresAt := time.Now().Add(challengeTTL).UTC().Truncate(time.Second)
+
+	wallet, err := sd.externalWalletRepo.SaveExternalWalletChallenge(repo.ExternalWallet{
+		UserID:             userID,
+		Address:            address,
+		Label:              label,
+		Challenge:          challengeMessage(address, hex.EncodeToString(nonce), expiresAt),
+		ChallengeExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return LinkChallengeResponse{}, err
+	}
+
+	return LinkChallengeResponse{
+		Address:   wallet.Address,
+		Message:   wallet.Challenge,
+		ExpiresAt: wallet.ChallengeExpiresAt,
+	}, nil
+}
+
+// VerifyWallet completes the link once the challenge was signed with the address
+func (sd service) VerifyWallet(userID, address string, req VerifyWalletRequest) (ExternalWalletResponse, error) {
+	address, err := parseAddress(address)
+	if err != nil {
+		return ExternalWalletResponse{}, err
+	}
+
+	wallet, err := sd.externalWalletRepo.GetExternalWallet(userID, address)
+	if err != nil {
+		return ExternalWalletResponse{}, err
+	}
+	if wallet.VerifiedAt != nil {
+		return ExternalWalletResponse{}, utils.Conflict("address is already linked")
+	}
+	if time.Now().After(wallet.ChallengeExpiresAt) {
+		return ExternalWalletResponse{}, utils.Validation("challenge has expired, request a new one")
+	}
+
+	signer, err := recoverSigner(wallet.Challenge, req.Signature)
+	if err != nil {
+		return ExternalWalletResponse{}, err
+	}
+	if signer != address {
+		return ExternalWalletResponse{}, utils.Unauthorized("signature was not made with the linked address")
+	}
+
+	verified, err := sd.externalWalletRepo.VerifyExternalWallet(userID, address, wallet.Challenge)
+	if err != nil {
+		return ExternalWalletResponse{}, err
+	}
+	if !verified {
+		return ExternalWalletResponse{}, utils.Conflict("challenge was replaced or has expired, request a new one")
+	}
+
+	wallet, err = sd.externalWalletRepo.GetExternalWallet(userID, address)
+	if err != nil {
+		return ExternalWalletResponse{}, err
+	}
+	return newExternalWalletResponse(wallet), nil
+}
+
+// ListWallets returns every address the user linked, including the ones awaiting a signature
+func (sd service) ListWallets(userID string) ([]ExternalWalletResponse, error) {
+	wallets, err := sd.externalWalletRepo.GetExternalWallets(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]ExternalWalletResponse, len(wallets))
+	for i, wallet := range wallets {
+		response[i] = newExternalWalletResponse(wallet)
+	}
+	return response, nil
+}
+
+// UnlinkWallet removes a linked address, verified or not
+func (sd service) UnlinkWallet(userID, address string) error {
+	address, err := parseAddress(address)
+	if err != nil {
+		return err
+	}
+	return sd.externalWalletRepo.DeleteExternalWallet(userID, address)
+}
+
+// GetPortfolio combines the platform wallet with the verified external wallets. Balances that
+// cannot be fetched are reported per wallet and left out of the total.
+func (sd service) GetPortfolio(userID string) (PortfolioResponse, error) {
+	platformWalletID, err := sd.walletRepo.GetWalletID("", userID)
+	if err != nil {
+		return PortfolioResponse{}, utils.NotFound("wallet not found", err)
+	}
+	linked, err := sd.externalWalletRepo.GetExternalWallets(userID)
+	if err != nil {
+		return PortfolioResponse{}, err
+	}
+
+	wallets := []PortfolioWallet{{Address: platformWalletID}}
+	for _, wallet := range linked {
+		if wallet.VerifiedAt != nil {
+			wallets = append(wallets, PortfolioWallet{Address: wallet.Address, Label: wallet.Label, WatchOnly: true})
+		}
+	}
+
+	total := new(big.Int)
+	walletIDs := make([]string, len(wallets))
+	for i := range wallets {
+		walletIDs[i] = wallets[i].Address
+		balance, err := sd.ethRepo.GetBalance(wallets[i].Address)
+		if err != nil {
+			log.Printf("Error fetching balance of %s: %v", wallets[i].Address, err)
+			wallets[i].Error = "balance unavailable"
+			continue
+		}
+		wallets[i].BalanceWei = balance.String()
+		total.Add(total, balance)
+
+		if sent, err := sd.ethRepo.GetTransactionCount(wallets[i].Address); err == nil {
+			wallets[i].SentCount = &sent
+		}
+	}
+
+	// Only activity the platform recorded is known, e.g. transfers and sweeps to a linked address
+	activity, err := sd.transactionRepo.GetWalletActivity(walletIDs, portfolioActivity)
+	if err != nil {
+		return PortfolioResponse{}, err
+	}
+	viewer := privacy.Viewer{UserID: userID}
+
+	return PortfolioResponse{
+		TotalBalanceWei: total.String(),
+		Wallets:         wallets,
+		RecentActivity:  viewer.Transactions(activity),
+	}, nil
+}
+
+// parseAddress validates an address and returns it in checksum casing, the form it is stored in
+func parseAddress(address string) (string, error) {
+	if !common.IsHexAddress(address) {
+		return "", utils.Validation("address is not a valid Ethereum address")
+	}
+	return common.HexToAddress(address).Hex(), nil
+}
+
+// challengeMessage is the text the user signs to prove they control the address
+func challengeMessage(address, nonce string, expiresAt time.Time) string {
+	return fmt.Sprintf("Link %s to ChainBank in watch-only mode.\n\nChainBank will never ask for your private key.\n\nNonce: %s\nExpires: %s",
+		address, nonce, expiresAt.Format(time.RFC3339))
+}
+
+// recoverSigner returns the address that signed message with personal_sign
+func recoverSigner(message, signature string) (string, error) {
+	signer, err := ethereum.RecoverTextSigner(message, signature)
+	if errors.Is(err, ethereum.ErrMalformedSignature) {
+		return "", utils.Validation(err.Error())
+	}
+	if err != nil {
+		return "", utils.Unauthorized("invalid signature")
+	}
+	return signer, nil
+}
+
+func newExternalWalletResponse(wallet repo.ExternalWallet) ExternalWalletResponse {
+	return ExternalWalletResponse{
+		Address:    wallet.Address,
+		Label:      wallet.Label,
+		Verified:   wallet.VerifiedAt != nil,
+		VerifiedAt: wallet.VerifiedAt,
+		CreatedAt:  wallet.CreatedAt,
+	}
+}
@@ -0,0 +1,73 @@
+package features
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/respond"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+	"github.com/gorilla/mux"
+)
+
+// FlagResponse represents a feature flag and its rollout state
+type FlagResponse struct {
+	Key               string     `json:"key"`
+	Enabled           bool       `json:"enabled"`
+	RolloutPercentage int        `json:"rollout_percentage"`
+	AllowedUserIDs    []string   `json:"allowed_user_ids"`
+	Source            string     `json:"source"`
+	UpdatedAt         *time.Time `json:"updated_at,omitempty"`
+}
+
+type Handler struct {
+	service Service
+}
+
+// Constructor function
+func NewHandler(service Service) Handler {
+	return Handler{service: service}
+}
+
+// Require only lets requests through when the flag is enabled for the authenticated user
+func (hd Handler) Require(flag string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userInfo, _ := utils.UserFromContext(r.Context())
+
+		if !hd.service.IsEnabled(flag, userInfo.UserID) {
+			http.Error(w, "Feature not available", http.StatusNotFound)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ListFlagsHandler returns every feature flag, needs feature.manage
+func (hd Handler) ListFlagsHandler(w http.ResponseWriter, r *http.Request) {
+	flags := hd.service.List()
+	respond.List(w, r, flags, respond.Pagination{Count: len(flags)})
+}
+
+// UpdateFlagHandler creates or changes a feature flag, needs feature.manage
+func (hd Handler) UpdateFlagHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req FlagResponse
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Key = mux.Vars(r)["key"]
+
+	flag, err := hd.service.Update(req, userInfo.UserID)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, flag)
+}
@@ -0,0 +1,184 @@
+package features
+
+import (
+	"fmt"
+	"hash/fnv"
+	"log"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/config"
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+// Feature flag keys checked by the application
+const (
+	TransactionHistory = "transaction_history"
+)
+
+// How often flags are reloaded so changes made on another instance are picked up
+const refreshInterval = 30 * time.Second
+
+var ErrInvalidFlag = utils.NewError(utils.ErrValidation, "invalid feature flag", nil)
+
+type service struct {
+	featureRepo repo.FeatureStorer
+
+	mu    sync.RWMutex
+	flags map[string]FlagResponse
+}
+
+type Service interface {
+	Load() error
+	IsEnabled(flag, userID string) bool
+	List() []FlagResponse
+	Update(flag FlagResponse, updatedBy string) (FlagResponse, error)
+	RunRefresher(stop <-chan struct{})
+}
+
+// Constructor function
+func NewService(featureRepo repo.FeatureStorer) Service {
+	return &service{
+		featureRepo: featureRepo,
+		flags:       configFlags(),
+	}
+}
+
+// configFlags builds the defaults from FEATURE_FLAGS, e.g. "transaction_history=100,new_feature=10"
+func configFlags() map[string]FlagResponse {
+	flags := map[string]FlagResponse{}
+	for key, percentage := range config.ConfigDetails.FeatureFlags {
+		flags[key] = FlagResponse{
+			Key:               key,
+			Enabled:           percentage > 0,
+			RolloutPercentage: percentage,
+			AllowedUserIDs:    []string{},
+			Source:            "config",
+		}
+	}
+	return flags
+}
+
+// Load rebuilds the flag cache from config defaults overridden by the database
+func (sd *service) Load() error {
+	stored, err := sd.featureRepo.GetFeatureFlags()
+	if err != nil {
+		return err
+	}
+
+	flags := configFlags()
+	for _, flag := range stored {
+		updatedAt := flag.UpdatedAt
+		flags[flag.Key] = FlagResponse{
+			Key:               flag.Key,
+			Enabled:           flag.Enabled,
+			RolloutPercentage: flag.RolloutPercentage,
+			AllowedUserIDs:    flag.AllowedUserIDs,
+			Source:            "database",
+			UpdatedAt:         &updatedAt,
+		}
+	}
+
+	sd.mu.Lock()
+	sd.flags = flags
+	sd.mu.Unlock()
+	return nil
+}
+
+// IsEnabled reports whether the feature is on for the user. Users on the allow list always
+// get the feature, everyone else is bucketed by a stable hash of flag and user ID so a user
+// keeps the same answer while the rollout percentage only grows.
+func (sd *service) IsEnabled(flag, userID string) bool {
+	sd.mu.RLock()
+	state, ok := sd.flags[flag]
+	sd.mu.RUnlock()
+
+	if !ok {
+		return false
+	}
+	if userID != "" && slices.Contains(state.AllowedUserIDs, userID) {
+		return true
+	}
+	if !state.Enabled {
+		return false
+	}
+	if state.RolloutPercentage >= 100 {
+		return true
+	}
+
+	return rolloutBucket(flag, userID) < state.RolloutPercentage
+}
+
+// List returns every flag sorted by key
+func (sd *service) List() []FlagResponse {
+	sd.mu.RLock()
+	defer sd.mu.RUnlock()
+
+	flags := make([]FlagResponse, 0, len(sd.flags))
+	for _, flag := range sd.flags {
+		flags = append(flags, flag)
+	}
+	sort.Slice(flags, func(i, j int) bool { return flags[i].Key < flags[j].Key })
+	return flags
+}
+
+// Update persists a flag and applies it to the local cache immediately
+func (sd *service) Update(flag FlagResponse, updatedBy string) (FlagResponse, error) {
+	if flag.Key == "" {
+		return FlagResponse{}, fmt.Errorf("%w: key is required", ErrInvalidFlag)
+	}
+	if flag.RolloutPercentage < 0 || flag.RolloutPercentage > 100 {
+		return FlagResponse{}, fmt.Errorf("%w: rollout_percentage must be between 0 and 100", ErrInvalidFlag)
+	}
+	if flag.AllowedUserIDs == nil {
+		flag.AllowedUserIDs = []string{}
+	}
+
+	err := sd.featureRepo.UpsertFeatureFlag(repo.FeatureFlag{
+		Key:               flag.Key,
+		Enabled:           flag.Enabled,
+		RolloutPercentage: flag.RolloutPercentage,
+		AllowedUserIDs:    flag.AllowedUserIDs,
+	}, updatedBy)
+	if err != nil {
+		return FlagResponse{}, err
+	}
+
+	now := time.Now()
+	flag.Source = "database"
+	flag.UpdatedAt = &now
+
+	sd.mu.Lock()
+	sd.flags[flag.Key] = flag
+	sd.mu.Unlock()
+
+	log.Printf("Feature flag %s set to enabled=%t rollout=%d%% by %s", flag.Key, flag.Enabled, flag.RolloutPercentage, updatedBy)
+	return flag, nil
+}
+
+// RunRefresher periodically reloads flags until stop is closed
+func (sd *service) RunRefresher(stop <-chan struct{}) {
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := sd.Load(); err != nil {
+				log.Printf("Error refreshing feature flags: %v", err)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// rolloutBucket maps a flag and user to a stable bucket in [0, 100)
+func rolloutBucket(flag, userID string) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(flag + ":" + userID))
+	return int(hash.Sum32() % 100)
+}
@@ -0,0 +1,158 @@
+package app
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/features"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/user"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/wallet"
+	"github.com/CodeWithKrushnal/ChainBank/internal/config"
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+)
+
+// Migrated database the end-to-end tests run against, they are skipped while it is unset
+const testDatabaseEnv = "TEST_DATABASE_URL"
+
+const flowPassword = "Flow-Test-Password-1"
+
+// TestSignupTransferHistory runs signup, sign-in, a transfer and the history listing through the
+// router in process, on the simulated chain, e.g.
+//
+//	TEST_DATABASE_URL=postgres://... go test ./internal/app -run TestSignupTransferHistory
+func TestSignupTransferHistory(t *testing.T) {
+	server := newFlowServer(t)
+	runID := strconv.FormatInt(time.Now().UnixNano(), 36)
+
+	senderEmail := "flow_sender_" + runID + "@example.com"
+	recipientEmail := "flow_recipient_" + runID + "@example.com"
+	for _, email := range []string{senderEmail, recipientEmail} {
+		var signup user.SignupResponse
+		flowRequest(t, server, http.MethodPost, "/signup", "", user.SignupRequest{
+			Username: email[:len(email)-len("@example.com")],
+			Email:    email,
+			Password: flowPassword,
+			FullName: "Flow Test User",
+			DOB:      "1990-01-01",
+			Role:     "1",
+		}, &signup)
+		if signup.WalletAddress == "" {
+			t.Fatalf("signup of %s returned no wallet address", email)
+		}
+	}
+
+	senderToken := flowSignIn(t, server, senderEmail)
+	recipientToken := flowSignIn(t, server, recipientEmail)
+	var recipient user.UserResponse
+	flowRequest(t, server, http.MethodGet, "/api/me", recipientToken, nil, &recipient)
+
+	var transfer map[string]string
+	flowRequest(t, server, http.MethodPost, "/api/transfer", senderToken, wallet.TransferRequest{
+		RecipientUserID: recipient.UserID,
+		AmountETH:       "1000000000000000",
+		Password:        flowPassword,
+	}, &transfer)
+	txHash := transfer["transaction_hash"]
+	if txHash == "" {
+		t.Fatal("transfer returned no transaction hash")
+	}
+
+	// Both sides see the transfer in their history
+	for _, token := range []string{senderToken, recipientToken} {
+		var transactions []repo.Transaction
+		flowRequest(t, server, http.MethodGet, "/api/transactions?limit=10", token, nil, &transactions)
+		if len(transactions) != 1 || transactions[0].TxHash != txHash {
+			t.Fatalf("history = %+v, want the transfer %s only", transactions, txHash)
+		}
+		if transactions[0].ReceiverUserID != recipient.UserID || transactions[0].Amount != "1000000000000000" {
+			t.Errorf("transaction = %+v, want 1000000000000000 wei to %s", transactions[0], recipient.UserID)
+		}
+	}
+}
+
+// newFlowServer serves the full router with the dependencies the server builds on the simulated chain
+func newFlowServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	databaseURL := os.Getenv(testDatabaseEnv)
+	if databaseURL == "" {
+		t.Skipf("%s is not set", testDatabaseEnv)
+	}
+
+	db, err := repo.InitDB(databaseURL)
+	if err != nil {
+		t.Fatalf("connecting to %s: %v", testDatabaseEnv, err)
+	}
+	t.Cleanup(func() { repo.CloseDB(db) })
+
+	previous := config.ConfigDetails
+	t.Cleanup(func() { config.ConfigDetails = previous })
+	config.ConfigDetails.SimulatedChain = true
+	config.ConfigDetails.JWTSecretKey = "flow-test-secret"
+	config.ConfigDetails.WalletKeys = map[int]string{2: base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32))}
+	config.ConfigDetails.WalletKeyVersion = 2
+	config.ConfigDetails.FeatureFlags = map[string]int{features.TransactionHistory: 100}
+
+	server := httptest.NewServer(SetupRoutes(NewDependencies(repo.NewDBRouter(db), nil)))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// flowSignIn returns the access token of a sign-in with the flow password
+func flowSignIn(t *testing.T, server *httptest.Server, email string) string {
+	t.Helper()
+	var auth user.AuthResponse
+	flowRequest(t, server, http.MethodPost, "/signin", "", user.Credentials{Email: email, Password: flowPassword}, &auth)
+	if auth.AccessToken == "" {
+		t.Fatalf("sign-in of %s returned no access token", email)
+	}
+	return auth.AccessToken
+}
+
+// flowRequest sends body as JSON and decodes the data of the response envelope into data, failing
+// the test on any status but 200
+func flowRequest(t *testing.T, server *httptest.Server, method, path, token string, body, data any) {
+	t.Helper()
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	req, err := http.NewRequest(method, server.URL+path, &payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	var envelope struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if resp.StatusCode != http.StatusOK {
+		var message bytes.Buffer
+		message.ReadFrom(resp.Body)
+		t.Fatalf("%s %s = %d: %s", method, path, resp.StatusCode, message.String())
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		t.Fatalf("%s %s: decoding response: %v", method, path, err)
+	}
+	if err := json.Unmarshal(envelope.Data, data); err != nil {
+		t.Fatalf("%s %s: decoding data: %v", method, path, err)
+	}
+}
@@ -0,0 +1,44 @@
+package hdwallets
+
+import (
+	"net/http"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/respond"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+// AuditResponse reports how the stored user wallets relate to the HD seed. Imported keys were
+// generated before the seed was configured and keep working from their encrypted copy.
+type AuditResponse struct {
+	DerivedWallets int64              `json:"derived_wallets"`
+	ImportedKeys   int64              `json:"imported_keys"`
+	Mismatched     []MismatchedWallet `json:"mismatched"`
+}
+
+// MismatchedWallet is a derived wallet whose address the seed no longer regenerates
+type MismatchedWallet struct {
+	WalletID        string `json:"wallet_id"`
+	DerivationIndex uint32 `json:"derivation_index"`
+	DerivationPath  string `json:"derivation_path"`
+	DerivedAddress  string `json:"derived_address,omitempty"`
+}
+
+type Handler struct {
+	service Service
+}
+
+// Constructor function
+func NewHandler(service Service) Handler {
+	return Handler{service: service}
+}
+
+// AuditHandler checks every derived wallet against the HD seed, needs wallet.audit
+func (hd Handler) AuditHandler(w http.ResponseWriter, r *http.Request) {
+	audit, err := hd.service.Audit()
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, audit)
+}
@@ -0,0 +1,66 @@
+package hdwallets
+
+import (
+	"strings"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/ethereum"
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+const auditBatchSize = 500
+
+type service struct {
+	walletRepo repo.WalletStorer
+	// nil while no HD seed is configured
+	hdWallet *ethereum.HDWallet
+}
+
+type Service interface {
+	Audit() (AuditResponse, error)
+}
+
+// Constructor function
+func NewService(walletRepo repo.WalletStorer, hdWallet *ethereum.HDWallet) Service {
+	return service{walletRepo: walletRepo, hdWallet: hdWallet}
+}
+
+// Audit regenerates the address of every derived wallet from the seed and reports the ones that
+// do not match, along with how many keys were imported rather than derived
+func (sd service) Audit() (AuditResponse, error) {
+	if sd.hdWallet == nil {
+		return AuditResponse{}, utils.Validation("HD wallets are not configured")
+	}
+
+	imported, err := sd.walletRepo.CountImportedPrivateKeys()
+	if err != nil {
+		return AuditResponse{}, err
+	}
+	response := AuditResponse{ImportedKeys: imported, Mismatched: []MismatchedWallet{}}
+
+	afterIndex := int64(-1)
+	for {
+		wallets, err := sd.walletRepo.GetDerivedWallets(afterIndex, auditBatchSize)
+		if err != nil {
+			return AuditResponse{}, err
+		}
+
+		for _, wallet := range wallets {
+			response.DerivedWallets++
+			address, err := sd.hdWallet.Address(wallet.DerivationIndex)
+			if err != nil || !strings.EqualFold(address, wallet.WalletID) {
+				response.Mismatched = append(response.Mismatched, MismatchedWallet{
+					WalletID:        wallet.WalletID,
+					DerivationIndex: wallet.DerivationIndex,
+					DerivationPath:  ethereum.HDPath(wallet.DerivationIndex),
+					DerivedAddress:  address,
+				})
+			}
+			afterIndex = int64(wallet.DerivationIndex)
+		}
+
+		if len(wallets) < auditBatchSize {
+			return response, nil
+		}
+	}
+}
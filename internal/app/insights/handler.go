@@ -0,0 +1,68 @@
+package insights
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/respond"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+const (
+	defaultMonths = 6
+	maxMonths     = 12
+)
+
+// MonthlyInsight summarizes a user's transfers in one calendar month of time_zone, amounts in wei
+type MonthlyInsight struct {
+	Month         string `json:"month"`
+	OutgoingWei   string `json:"outgoing_wei"`
+	OutgoingCount int    `json:"outgoing_count"`
+	IncomingWei   string `json:"incoming_wei"`
+	IncomingCount int    `json:"incoming_count"`
+	FeesPaidWei   string `json:"fees_paid_wei"`
+	NetWei        string `json:"net_wei"`
+	TimeZone      string `json:"time_zone"`
+}
+
+type Handler struct {
+	service Service
+}
+
+// Constructor function
+func NewHandler(service Service) Handler {
+	return Handler{service: service}
+}
+
+// GetMonthlyHandler returns one summary per month for the last ?months=6 months including the
+// current one, oldest first. Admins may pass userid to look at another user.
+func (hd Handler) GetMonthlyHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	months := defaultMonths
+	if monthsParam := r.URL.Query().Get("months"); monthsParam != "" {
+		parsed, err := strconv.Atoi(monthsParam)
+		if err != nil || parsed < 1 || parsed > maxMonths {
+			utils.WriteError(w, r, utils.Validationf("months must be between 1 and %d", maxMonths))
+			return
+		}
+		months = parsed
+	}
+
+	userID := userInfo.UserID
+	if queryUserID := r.URL.Query().Get("userid"); userInfo.UserRole == 3 && queryUserID != "" {
+		userID = queryUserID
+	}
+
+	insights, err := hd.service.GetMonthly(userID, months)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.List(w, r, insights, respond.Pagination{Count: len(insights)})
+}
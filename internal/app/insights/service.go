@@ -0,0 +1,120 @@
+package insights
+
+import (
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/ethereum"
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+)
+
+// Summaries are served from memory for this long, a new transfer shows up after at most this delay
+const cacheTTL = 5 * time.Minute
+
+type cacheKey struct {
+	userID string
+	months int
+}
+
+type cacheEntry struct {
+	insights  []MonthlyInsight
+	expiresAt time.Time
+}
+
+type service struct {
+	transactionRepo repo.TransactionStorer
+	userRepo        repo.UserStorer
+	mu              *sync.Mutex
+	cache           map[cacheKey]cacheEntry
+}
+
+type Service interface {
+	GetMonthly(userID string, months int) ([]MonthlyInsight, error)
+}
+
+// Constructor function
+func NewService(transactionRepo repo.TransactionStorer, userRepo repo.UserStorer) Service {
+	return service{
+		transactionRepo: transactionRepo,
+		userRepo:        userRepo,
+		mu:              &sync.Mutex{},
+		cache:           map[cacheKey]cacheEntry{},
+	}
+}
+
+// GetMonthly returns a summary for each of the last months in the user's time zone, months without
+// transfers are zero
+func (sd service) GetMonthly(userID string, months int) ([]MonthlyInsight, error) {
+	key := cacheKey{userID: userID, months: months}
+	now := time.Now()
+
+	sd.mu.Lock()
+	entry, ok := sd.cache[key]
+	sd.mu.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.insights, nil
+	}
+
+	location, err := sd.userRepo.GetUserLocation(userID)
+	if err != nil {
+		return nil, err
+	}
+	local := now.In(location)
+	firstMonth := time.Date(local.Year(), local.Month(), 1, 0, 0, 0, 0, location).AddDate(0, 1-months, 0)
+	summaries, err := sd.transactionRepo.GetMonthlySummaries(userID, firstMonth, ethereum.TransferGasLimit, location.String())
+	if err != nil {
+		return nil, err
+	}
+
+	byMonth := map[string]repo.MonthlySummary{}
+	for _, summary := range summaries {
+		byMonth[summary.Month.Format("2006-01")] = summary
+	}
+
+	insights := make([]MonthlyInsight, 0, months)
+	for i := 0; i < months; i++ {
+		month := firstMonth.AddDate(0, i, 0).Format("2006-01")
+		insights = append(insights, toInsight(month, location.String(), byMonth[month]))
+	}
+
+	sd.mu.Lock()
+	sd.evictExpired(now)
+	sd.cache[key] = cacheEntry{insights: insights, expiresAt: now.Add(cacheTTL)}
+	sd.mu.Unlock()
+
+	return insights, nil
+}
+
+// evictExpired drops stale entries so the cache only holds recently active users, callers must hold the lock
+func (sd service) evictExpired(now time.Time) {
+	for key, entry := range sd.cache {
+		if !now.Before(entry.expiresAt) {
+			delete(sd.cache, key)
+		}
+	}
+}
+
+func toInsight(month, timeZone string, summary repo.MonthlySummary) MonthlyInsight {
+	outgoing, incoming, fees := orZero(summary.OutgoingTotal), orZero(summary.IncomingTotal), orZero(summary.FeesPaid)
+	net := new(big.Int).Sub(incoming, outgoing)
+	net.Sub(net, fees)
+
+	return MonthlyInsight{
+		Month:         month,
+		OutgoingWei:   outgoing.String(),
+		OutgoingCount: summary.OutgoingCount,
+		IncomingWei:   incoming.String(),
+		IncomingCount: summary.IncomingCount,
+		FeesPaidWei:   fees.String(),
+		NetWei:        net.String(),
+		TimeZone:      timeZone,
+	}
+}
+
+func orZero(value *big.Int) *big.Int {
+	if value == nil {
+		return new(big.Int)
+	}
+	return value
+}
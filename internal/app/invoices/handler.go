@@ -0,0 +1,148 @@
+package invoices
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/respond"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+	"github.com/gorilla/mux"
+)
+
+// CreateInvoiceRequest issues an invoice to a user or to an email address, due_date as YYYY-MM-DD
+type CreateInvoiceRequest struct {
+	RecipientUserID string     `json:"recipient_user_id"`
+	RecipientEmail  string     `json:"recipient_email"`
+	Title           string     `json:"title"`
+	Notes           string     `json:"notes"`
+	DueDate         string     `json:"due_date"`
+	LineItems       []LineItem `json:"line_items"`
+}
+
+// LineItem is one line of an invoice, amounts in wei. Amount is quantity times unit_amount and only
+// set in responses.
+type LineItem struct {
+	Description string `json:"description"`
+	Quantity    int    `json:"quantity"`
+	UnitAmount  string `json:"unit_amount"`
+	Amount      string `json:"amount,omitempty"`
+}
+
+// InvoiceResponse represents an invoice, it is paid by a transfer of total_amount to payee_wallet_id
+type InvoiceResponse struct {
+	InvoiceID       string     `json:"invoice_id"`
+	IssuerUserID    string     `json:"issuer_user_id"`
+	RecipientUserID string     `json:"recipient_user_id,omitempty"`
+	RecipientEmail  string     `json:"recipient_email,omitempty"`
+	PayeeWalletID   string     `json:"payee_wallet_id"`
+	Title           string     `json:"title"`
+	Notes           string     `json:"notes,omitempty"`
+	LineItems       []LineItem `json:"line_items"`
+	TotalAmount     string     `json:"total_amount"`
+	DueDate         string     `json:"due_date"`
+	Status          string     `json:"status"`
+	TxHash          string     `json:"transaction_hash,omitempty"`
+	PaidAt          *time.Time `json:"paid_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+type Handler struct {
+	service Service
+}
+
+// Constructor function
+func NewHandler(service Service) Handler {
+	return Handler{service: service}
+}
+
+// CreateInvoiceHandler issues an invoice from the authenticated user
+func (hd Handler) CreateInvoiceHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req CreateInvoiceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	invoice, err := hd.service.CreateInvoice(userInfo.UserID, req)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, invoice)
+}
+
+// ListIssuedInvoicesHandler lists the invoices the authenticated user issued
+func (hd Handler) ListIssuedInvoicesHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	invoices, err := hd.service.ListIssuedInvoices(userInfo.UserID)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.List(w, r, invoices, respond.Pagination{Count: len(invoices)})
+}
+
+// ListIncomingInvoicesHandler lists the invoices addressed to the authenticated user
+func (hd Handler) ListIncomingInvoicesHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	invoices, err := hd.service.ListIncomingInvoices(userInfo)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.List(w, r, invoices, respond.Pagination{Count: len(invoices)})
+}
+
+// GetInvoiceHandler returns an invoice the authenticated user issued or received
+func (hd Handler) GetInvoiceHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	invoice, err := hd.service.GetInvoice(userInfo, mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, invoice)
+}
+
+// CancelInvoiceHandler cancels an open invoice of the authenticated user
+func (hd Handler) CancelInvoiceHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	invoice, err := hd.service.CancelInvoice(userInfo.UserID, mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, invoice)
+}
@@ -0,0 +1,427 @@
+package invoices
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"math/big"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/calendar"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/notification"
+	"github.com/CodeWithKrushnal/ChainBank/internal/config"
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+// Invoice statuses. Overdue is never stored, an open invoice past its due date is reported as overdue.
+const (
+	StatusOpen      = "open"
+	StatusOverdue   = "overdue"
+	StatusPaid      = "paid"
+	StatusCancelled = "cancelled"
+)
+
+const (
+	maxDueDays            = 365
+	maxLineItems          = 50
+	maxQuantity           = 1000000
+	maxTitleLength        = 100
+	maxNotesLength        = 500
+	maxDescriptionLength  = 140
+	invoiceListLimit      = 100
+	reminderLeadDays      = 3
+	reminderInterval      = 72 * time.Hour
+	maxReminders          = 5
+	reminderBatchSize     = 100
+	maxRecipientEmailSize = 255
+)
+
+type service struct {
+	invoiceRepo   repo.InvoiceStorer
+	walletRepo    repo.WalletStorer
+	userRepo      repo.UserStorer
+	calendar      calendar.Service
+	notifications notification.Service
+}
+
+type Service interface {
+	CreateInvoice(userID string, req CreateInvoiceRequest) (InvoiceResponse, error)
+	GetInvoice(userInfo utils.User, invoiceID string) (InvoiceResponse, error)
+	ListIssuedInvoices(userID string) ([]InvoiceResponse, error)
+	ListIncomingInvoices(userInfo utils.User) ([]InvoiceResponse, error)
+	CancelInvoice(userID, invoiceID string) (InvoiceResponse, error)
+	RunScheduler(stop <-chan struct{})
+}
+
+// Constructor function
+func NewService(invoiceRepo repo.InvoiceStorer, walletRepo repo.WalletStorer, userRepo repo.UserStorer, calendarService calendar.Service,
+	notificationService notification.Service) Service {
+	return service{
+		invoiceRepo:   invoiceRepo,
+		walletRepo:    walletRepo,
+		userRepo:      userRepo,
+		calendar:      calendarService,
+		notifications: notificationService,
+	}
+}
+
+// CreateInvoice issues an invoice payable to the issuer's wallet. An invoice sent to the email of a
+// registered user is addressed to that user, other addresses receive it by email. A due date on a
+// weekend or holiday moves to the next business day.
+func (sd service) CreateInvoice(userID string, req CreateInvoiceRequest) (InvoiceResponse, error) {
+	title := strings.TrimSpace(req.Title)
+	if title == "" || len(title) > maxTitleLength {
+		return InvoiceResponse{}, utils.Validationf("title is required and must be at most %d characters", maxTitleLength)
+	}
+	notes := strings.TrimSpace(req.Notes)
+	if len(notes) > maxNotesLength {
+		return InvoiceResponse{}, utils.Validationf("notes cannot be longer than %d characters", maxNotesLength)
+	}
+
+	dueDate, err := time.Parse(time.DateOnly, req.DueDate)
+	today := startOfDayUTC(time.Now())
+	if err != nil || dueDate.Before(today) || dueDate.After(today.AddDate(0, 0, maxDueDays)) {
+		return InvoiceResponse{}, utils.Validationf("due_date must be a date between today and %d days from now", maxDueDays)
+	}
+
+	lineItems, total, err := validateLineItems(req.LineItems)
+	if err != nil {
+		return InvoiceResponse{}, err
+	}
+
+	recipientUserID, recipientEmail, err := sd.resolveRecipient(req)
+	if err != nil {
+		return InvoiceResponse{}, err
+	}
+	if recipientUserID == userID {
+		return InvoiceResponse{}, utils.Validation("you cannot send an invoice to yourself")
+	}
+
+	walletID, err := sd.walletRepo.GetWalletID("", userID)
+	if err != nil {
+		return InvoiceResponse{}, utils.NotFound("wallet not found", err)
+	}
+
+	invoice, err := sd.invoiceRepo.CreateInvoice(repo.Invoice{
+		IssuerUserID:    userID,
+		RecipientUserID: recipientUserID,
+		RecipientEmail:  recipientEmail,
+		PayeeWalletID:   walletID,
+		Title:           title,
+		Notes:           notes,
+		TotalAmount:     total.String(),
+		DueDate:         sd.calendar.NextBusinessDay(dueDate),
+		LineItems:       lineItems,
+	})
+	if err != nil {
+		return InvoiceResponse{}, err
+	}
+
+	sd.notifyRecipient(invoice, "Invoice received", "You received an invoice %s for %s ETH due on %s.")
+	return newInvoiceResponse(invoice), nil
+}
+
+// GetInvoice returns an invoice the user issued or received
+func (sd service) GetInvoice(userInfo utils.User, invoiceID string) (InvoiceResponse, error) {
+	invoice, err := sd.invoiceRepo.GetInvoice(invoiceID)
+	if err != nil {
+		return InvoiceResponse{}, err
+	}
+	if invoice.IssuerUserID != userInfo.UserID && !isRecipient(invoice, userInfo.UserID, userInfo.UserEmail) {
+		return InvoiceResponse{}, utils.NotFound("invoice not found", nil)
+	}
+	return newInvoiceResponse(invoice), nil
+}
+
+// ListIssuedInvoices returns the latest invoices the user issued
+func (sd service) ListIssuedInvoices(userID string) ([]InvoiceResponse, error) {
+	invoices, err := sd.invoiceRepo.GetIssuedInvoices(userID, invoiceListLimit)
+	if err != nil {
+		return nil, err
+	}
+	return newInvoiceResponses(invoices), nil
+}
+
+// ListIncomingInvoices returns the latest invoices addressed to the user or their email
+func (sd service) ListIncomingInvoices(userInfo utils.User) ([]InvoiceResponse, error) {
+	invoices, err := sd.invoiceRepo.GetIncomingInvoices(userInfo.UserID, userInfo.UserEmail, invoiceListLimit)
+	if err != nil {
+		return nil, err
+	}
+	return newInvoiceResponses(invoices), nil
+}
+
+// CancelInvoice withdraws an open invoice of the user
+func (sd service) CancelInvoice(userID, invoiceID string) (InvoiceResponse, error) {
+	invoice, err := sd.invoiceRepo.GetInvoice(invoiceID)
+	if err != nil {
+		return InvoiceResponse{}, err
+	}
+	if invoice.IssuerUserID != userID {
+		return InvoiceResponse{}, utils.NotFound("invoice not found", nil)
+	}
+
+	cancelled, err := sd.invoiceRepo.CancelInvoice(invoiceID, userID)
+	if err != nil {
+		return InvoiceResponse{}, err
+	}
+	if !cancelled {
+		return InvoiceResponse{}, utils.Conflict("invoice is no longer open")
+	}
+
+	invoice.Status = StatusCancelled
+	return newInvoiceResponse(invoice), nil
+}
+
+// RunScheduler matches incoming transfers to open invoices and sends due reminders on the configured
+// interval until stop is closed
+func (sd service) RunScheduler(stop <-chan struct{}) {
+	interval := time.Duration(config.ConfigDetails.InvoiceCheckIntervalMinutes) * time.Minute
+	if interval <= 0 {
+		log.Println("Scheduled invoice checks disabled")
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			sd.matchPayments()
+			sd.sendReminders()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// matchPayments marks open invoices paid by the oldest transfer that matches them. Each transfer
+// settles a single invoice, so two equal invoices need two transfers.
+func (sd service) matchPayments() {
+	matches, err := sd.invoiceRepo.GetInvoiceMatches()
+	if err != nil {
+		log.Printf("Error matching invoice payments: %v", err)
+		return
+	}
+
+	settledInvoices, usedTransfers := map[string]bool{}, map[string]bool{}
+	for _, match := range matches {
+		if settledInvoices[match.InvoiceID] || usedTransfers[match.TxHash] {
+			continue
+		}
+
+		paid, err := sd.invoiceRepo.MarkInvoicePaid(match.InvoiceID, match.TxHash, match.PaidAt)
+		if err != nil {
+			// Another scheduler may have used the transfer first, the invoice can still match another one
+			log.Printf("Error settling invoice %s with %s: %v", match.InvoiceID, match.TxHash, err)
+			usedTransfers[match.TxHash] = true
+			continue
+		}
+		settledInvoices[match.InvoiceID] = true
+		if paid {
+			usedTransfers[match.TxHash] = true
+			sd.notifyIssuer(match.InvoiceID)
+		}
+	}
+}
+
+// sendReminders reminds recipients of open invoices that are due soon or overdue, reminders wait
+// for the next business day
+func (sd service) sendReminders() {
+	if !sd.calendar.IsBusinessDay(time.Now()) {
+		return
+	}
+
+	invoices, err := sd.invoiceRepo.ClaimInvoiceReminders(reminderLeadDays, maxReminders, reminderInterval, reminderBatchSize)
+	if err != nil {
+		log.Printf("Error loading invoice reminders: %v", err)
+		return
+	}
+
+	for _, invoice := range invoices {
+		if invoice.DueDate.Before(sd.recipientToday(invoice)) {
+			sd.notifyRecipient(invoice, "Invoice overdue", "Your invoice %s for %s ETH was due on %s.")
+		} else {
+			sd.notifyRecipient(invoice, "Invoice due soon", "Your invoice %s for %s ETH is due on %s.")
+		}
+	}
+}
+
+// resolveRecipient returns the user or, for email addresses without an account, the email an
+// invoice is addressed to
+func (sd service) resolveRecipient(req CreateInvoiceRequest) (string, string, error) {
+	if (req.RecipientUserID == "") == (req.RecipientEmail == "") {
+		return "", "", utils.Validation("set either recipient_user_id or recipient_email")
+	}
+
+	if req.RecipientUserID != "" {
+		if _, err := sd.userRepo.GetUserByID(req.RecipientUserID); err != nil {
+			return "", "", err
+		}
+		return req.RecipientUserID, "", nil
+	}
+
+	address, err := mail.ParseAddress(req.RecipientEmail)
+	if err != nil || address.Address != req.RecipientEmail || len(address.Address) > maxRecipientEmailSize {
+		return "", "", utils.Validation("recipient_email must be a valid email address")
+	}
+	user, err := sd.userRepo.GetUserByEmail(address.Address)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", address.Address, nil
+	}
+	if err != nil {
+		return "", "", utils.FromDBError("user", err)
+	}
+	return user.ID, "", nil
+}
+
+// notifyRecipient sends an invoice notification to its recipient, by email when they have no account
+func (sd service) notifyRecipient(invoice repo.Invoice, title, body string) {
+	n := notification.Notification{
+		UserID:   invoice.RecipientUserID,
+		Category: notification.CategoryInvoice,
+		Title:    title,
+		Body:     body,
+		BodyArgs: []any{invoice.Title, weiToETH(invoice.TotalAmount), invoice.DueDate.Format(time.DateOnly)},
+		Data:     map[string]string{"invoice_id": invoice.InvoiceID, "amount": invoice.TotalAmount, "payee_wallet_id": invoice.PayeeWalletID},
+	}
+
+	if n.UserID == "" {
+		// The recipient may have registered since the invoice was issued
+		user, err := sd.userRepo.GetUserByEmail(invoice.RecipientEmail)
+		if err != nil {
+			sd.notifications.NotifyEmail(invoice.RecipientEmail, n)
+			return
+		}
+		n.UserID = user.ID
+	}
+	sd.notifications.Notify(n)
+}
+
+// notifyIssuer tells the issuer their invoice was paid
+func (sd service) notifyIssuer(invoiceID string) {
+	invoice, err := sd.invoiceRepo.GetInvoice(invoiceID)
+	if err != nil {
+		log.Printf("Error loading paid invoice %s: %v", invoiceID, err)
+		return
+	}
+
+	sd.notifications.Notify(notification.Notification{
+		UserID:   invoice.IssuerUserID,
+		Category: notification.CategoryInvoice,
+		Title:    "Invoice paid",
+		Body:     "Your invoice %s for %s ETH was paid.",
+		BodyArgs: []any{invoice.Title, weiToETH(invoice.TotalAmount)},
+		Data:     map[string]string{"invoice_id": invoice.InvoiceID, "transaction_hash": invoice.TxHash},
+	})
+}
+
+// isRecipient reports whether the invoice is addressed to the user or to their email
+func isRecipient(invoice repo.Invoice, userID, email string) bool {
+	if invoice.RecipientUserID != "" {
+		return invoice.RecipientUserID == userID
+	}
+	return email != "" && strings.EqualFold(invoice.RecipientEmail, email)
+}
+
+// validateLineItems returns the line items with their total, every item needs a description, a
+// quantity and a unit amount in wei
+func validateLineItems(items []LineItem) ([]repo.InvoiceLineItem, *big.Int, error) {
+	if len(items) == 0 || len(items) > maxLineItems {
+		return nil, nil, utils.Validationf("an invoice needs between 1 and %d line items", maxLineItems)
+	}
+
+	lineItems := make([]repo.InvoiceLineItem, len(items))
+	total := new(big.Int)
+	for i, item := range items {
+		description := strings.TrimSpace(item.Description)
+		if description == "" || len(description) > maxDescriptionLength {
+			return nil, nil, utils.Validationf("description is required and must be at most %d characters", maxDescriptionLength)
+		}
+		if item.Quantity < 1 || item.Quantity > maxQuantity {
+			return nil, nil, utils.Validationf("quantity must be between 1 and %d", maxQuantity)
+		}
+		unitAmount, ok := new(big.Int).SetString(item.UnitAmount, 10)
+		if !ok || unitAmount.Sign() <= 0 {
+			return nil, nil, utils.Validation("unit_amount must be a positive integer in wei")
+		}
+
+		total.Add(total, new(big.Int).Mul(unitAmount, big.NewInt(int64(item.Quantity))))
+		lineItems[i] = repo.InvoiceLineItem{Description: description, Quantity: item.Quantity, UnitAmount: unitAmount.String()}
+	}
+	return lineItems, total, nil
+}
+
+// EffectiveStatus returns the status of an invoice, overdue for an open invoice past its due date
+func EffectiveStatus(invoice repo.Invoice) string {
+	if invoice.Status == StatusOpen && invoice.DueDate.Before(startOfDayUTC(time.Now())) {
+		return StatusOverdue
+	}
+	return invoice.Status
+}
+
+// recipientToday returns the current date in the recipient's time zone at midnight UTC, like due
+// dates. Recipients without an account use UTC.
+func (sd service) recipientToday(invoice repo.Invoice) time.Time {
+	if invoice.RecipientUserID == "" {
+		return startOfDayUTC(time.Now())
+	}
+	location, err := sd.userRepo.GetUserLocation(invoice.RecipientUserID)
+	if err != nil {
+		log.Printf("Error loading time zone of %s: %v", invoice.RecipientUserID, err)
+		return startOfDayUTC(time.Now())
+	}
+	now := time.Now().In(location)
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func startOfDayUTC(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func weiToETH(wei string) string {
+	amount, _ := new(big.Int).SetString(wei, 10)
+	return new(big.Float).Quo(new(big.Float).SetInt(amount), big.NewFloat(1e18)).Text('f', -1)
+}
+
+func newInvoiceResponses(invoices []repo.Invoice) []InvoiceResponse {
+	response := make([]InvoiceResponse, len(invoices))
+	for i, invoice := range invoices {
+		response[i] = newInvoiceResponse(invoice)
+	}
+	return response
+}
+
+func newInvoiceResponse(invoice repo.Invoice) InvoiceResponse {
+	response := InvoiceResponse{
+		InvoiceID:       invoice.InvoiceID,
+		IssuerUserID:    invoice.IssuerUserID,
+		RecipientUserID: invoice.RecipientUserID,
+		RecipientEmail:  invoice.RecipientEmail,
+		PayeeWalletID:   invoice.PayeeWalletID,
+		Title:           invoice.Title,
+		Notes:           invoice.Notes,
+		LineItems:       make([]LineItem, len(invoice.LineItems)),
+		TotalAmount:     invoice.TotalAmount,
+		DueDate:         invoice.DueDate.Format(time.DateOnly),
+		Status:          EffectiveStatus(invoice),
+		TxHash:          invoice.TxHash,
+		PaidAt:          invoice.PaidAt,
+		CreatedAt:       invoice.CreatedAt,
+	}
+	for i, item := range invoice.LineItems {
+		unitAmount, _ := new(big.Int).SetString(item.UnitAmount, 10)
+		response.LineItems[i] = LineItem{
+			Description: item.Description,
+			Quantity:    item.Quantity,
+			UnitAmount:  item.UnitAmount,
+			Amount:      new(big.Int).Mul(unitAmount, big.NewInt(int64(item.Quantity))).String(),
+		}
+	}
+	return response
+}
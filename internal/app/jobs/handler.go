@@ -0,0 +1,79 @@
+package jobs
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/respond"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+	"github.com/gorilla/mux"
+)
+
+// JobResponse represents a background job. next_attempt_at is set while the job is pending,
+// max_attempts is left out for queues this instance does not run. The payload is only included
+// when a single job is fetched.
+type JobResponse struct {
+	JobID         string          `json:"job_id"`
+	Queue         string          `json:"queue"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	MaxAttempts   int             `json:"max_attempts,omitempty"`
+	NextAttemptAt *time.Time      `json:"next_attempt_at,omitempty"`
+	LastError     string          `json:"last_error,omitempty"`
+	RequeuedBy    string          `json:"requeued_by,omitempty"`
+	Payload       json.RawMessage `json:"payload,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+	CompletedAt   *time.Time      `json:"completed_at,omitempty"`
+}
+
+type Handler struct {
+	service Service
+}
+
+// Constructor function
+func NewHandler(service Service) Handler {
+	return Handler{service: service}
+}
+
+// ListJobsHandler lists the latest background jobs, filtered by the status and queue query
+// parameters, needs job.manage
+func (hd Handler) ListJobsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	jobs, err := hd.service.ListJobs(query.Get("status"), query.Get("queue"))
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.List(w, r, jobs, respond.Pagination{Count: len(jobs)})
+}
+
+// GetJobHandler returns a background job with its payload, needs job.manage
+func (hd Handler) GetJobHandler(w http.ResponseWriter, r *http.Request) {
+	job, err := hd.service.GetJob(mux.Vars(r)["jobID"])
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, job)
+}
+
+// RequeueJobHandler gives a dead job a fresh set of attempts, needs job.manage
+func (hd Handler) RequeueJobHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	job, err := hd.service.RequeueJob(userInfo.UserID, mux.Vars(r)["jobID"])
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, job)
+}
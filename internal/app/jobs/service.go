@@ -0,0 +1,281 @@
+package jobs
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+// Job statuses
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusDead      = "dead"
+)
+
+const (
+	pollInterval   = 10 * time.Second
+	claimBatchSize = 20
+	// A claimed job not finished within its lease is run again, by this or another instance
+	jobLease        = 5 * time.Minute
+	firstRetryDelay = time.Minute
+	maxRetryDelay   = 12 * time.Hour
+	purgeInterval   = time.Hour
+	// Succeeded jobs are kept this long for inspection, dead jobs until they are requeued
+	succeededRetention = 7 * 24 * time.Hour
+	listLimit          = 100
+)
+
+// Attempt is one run of a job. RetryAt is when the job runs again should this attempt fail, nil on
+// the last attempt before the job is dead.
+type Attempt struct {
+	JobID   string
+	Payload json.RawMessage
+	Number  int
+	RetryAt *time.Time
+}
+
+// Processor runs a job of a queue, an error fails the attempt
+type Processor func(attempt Attempt) error
+
+// registration is a queue the worker runs with its processor
+type registration struct {
+	maxAttempts int
+	process     Processor
+}
+
+type service struct {
+	jobRepo repo.JobStorer
+
+	mu     sync.RWMutex
+	queues map[string]registration
+}
+
+type Service interface {
+	Register(name string, maxAttempts int, process Processor)
+	Enqueue(queue string, payload any) error
+	ListJobs(status, queue string) ([]JobResponse, error)
+	GetJob(jobID string) (JobResponse, error)
+	RequeueJob(adminID, jobID string) (JobResponse, error)
+	RunWorker(stop <-chan struct{})
+}
+
+// Constructor function
+func NewService(jobRepo repo.JobStorer) Service {
+	return &service{
+		jobRepo: jobRepo,
+		queues:  map[string]registration{},
+	}
+}
+
+// Register makes the worker run the jobs of a queue, each with up to maxAttempts attempts. Queues
+// are registered by the services owning them while the dependencies are set up.
+func (sd *service) Register(name string, maxAttempts int, process Processor) {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	sd.queues[name] = registration{maxAttempts: maxAttempts, process: process}
+}
+
+// Enqueue stores a job for the worker, payload is encoded as JSON
+func (sd *service) Enqueue(queue string, payload any) error {
+	if _, ok := sd.lookup(queue); !ok {
+		return fmt.Errorf("unknown job queue %s", queue)
+	}
+
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("error encoding %s job: %v", queue, err)
+	}
+	return sd.jobRepo.EnqueueJob(queue, encoded)
+}
+
+// ListJobs returns the most recently updated jobs, optionally of one status or queue
+func (sd *service) ListJobs(status, queue string) ([]JobResponse, error) {
+	if status != "" && !slices.Contains([]string{StatusPending, StatusSucceeded, StatusDead}, status) {
+		return nil, utils.Validation("status must be pending, succeeded or dead")
+	}
+
+	jobs, err := sd.jobRepo.GetJobs(repo.JobFilter{Status: status, Queue: queue, Limit: listLimit})
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]JobResponse, len(jobs))
+	for i, job := range jobs {
+		response[i] = sd.newJobResponse(job, false)
+	}
+	return response, nil
+}
+
+// GetJob returns a job with its payload
+func (sd *service) GetJob(jobID string) (JobResponse, error) {
+	job, err := sd.jobRepo.GetJob(jobID)
+	if err != nil {
+		return JobResponse{}, err
+	}
+	return sd.newJobResponse(job, true), nil
+}
+
+// RequeueJob gives a dead job a fresh set of attempts, the first one right away
+func (sd *service) RequeueJob(adminID, jobID string) (JobResponse, error) {
+	requeued, err := sd.jobRepo.RequeueJob(jobID, adminID)
+	if err != nil {
+		return JobResponse{}, err
+	}
+	if !requeued {
+		if _, err := sd.jobRepo.GetJob(jobID); err != nil {
+			return JobResponse{}, err
+		}
+		return JobResponse{}, utils.Conflict("only dead jobs can be requeued")
+	}
+	log.Printf("Job %s requeued by %s", jobID, adminID)
+	return sd.GetJob(jobID)
+}
+
+// RunWorker runs due jobs of the registered queues and deletes old succeeded ones until stop is closed
+func (sd *service) RunWorker(stop <-chan struct{}) {
+	poll := time.NewTicker(pollInterval)
+	defer poll.Stop()
+	purge := time.NewTicker(purgeInterval)
+	defer purge.Stop()
+
+	for {
+		select {
+		case <-poll.C:
+			sd.runDueJobs(stop)
+		case <-purge.C:
+			sd.purgeSucceeded()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// runDueJobs runs batches of due jobs until none are left or stop is closed
+func (sd *service) runDueJobs(stop <-chan struct{}) {
+	sd.mu.RLock()
+	names := make([]string, 0, len(sd.queues))
+	for name := range sd.queues {
+		names = append(names, name)
+	}
+	sd.mu.RUnlock()
+	if len(names) == 0 {
+		return
+	}
+	sort.Strings(names)
+
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		jobs, err := sd.jobRepo.ClaimDueJobs(names, claimBatchSize, jobLease)
+		if err != nil || len(jobs) == 0 {
+			return
+		}
+		for _, job := range jobs {
+			sd.run(job)
+		}
+	}
+}
+
+// run makes one attempt at a job and records its outcome, failed jobs are retried with exponential
+// backoff until they run out of attempts
+func (sd *service) run(job repo.Job) {
+	registration, ok := sd.lookup(job.Queue)
+	if !ok {
+		return
+	}
+
+	attempt := Attempt{JobID: job.JobID, Payload: job.Payload, Number: job.Attempts + 1}
+	if attempt.Number < registration.maxAttempts {
+		retryAt := time.Now().Add(retryDelay(attempt.Number))
+		attempt.RetryAt = &retryAt
+	}
+
+	if err := runProcessor(registration.process, attempt); err != nil {
+		if attempt.RetryAt == nil {
+			log.Printf("Job %s of %s is dead after %d attempts: %v", job.JobID, job.Queue, attempt.Number, err)
+		}
+		if err := sd.jobRepo.RecordJobFailure(job.JobID, err.Error(), attempt.RetryAt); err != nil {
+			log.Printf("Error recording job failure: %v", err)
+		}
+		return
+	}
+	if err := sd.jobRepo.MarkJobSucceeded(job.JobID); err != nil {
+		log.Printf("Error recording job success: %v", err)
+	}
+}
+
+// purgeSucceeded deletes the succeeded jobs past their retention
+func (sd *service) purgeSucceeded() {
+	deleted, err := sd.jobRepo.DeleteSucceededJobsBefore(time.Now().Add(-succeededRetention))
+	if err != nil {
+		log.Printf("Error purging succeeded jobs: %v", err)
+		return
+	}
+	if deleted > 0 {
+		log.Printf("Purged %d succeeded jobs", deleted)
+	}
+}
+
+// lookup returns the registration of a queue, false when this instance does not run it
+func (sd *service) lookup(queue string) (registration, bool) {
+	sd.mu.RLock()
+	defer sd.mu.RUnlock()
+	found, ok := sd.queues[queue]
+	return found, ok
+}
+
+// newJobResponse converts a job, the payload is only included when asked for
+func (sd *service) newJobResponse(job repo.Job, withPayload bool) JobResponse {
+	response := JobResponse{
+		JobID:       job.JobID,
+		Queue:       job.Queue,
+		Status:      job.Status,
+		Attempts:    job.Attempts,
+		LastError:   job.LastError,
+		RequeuedBy:  job.RequeuedBy,
+		CreatedAt:   job.CreatedAt,
+		UpdatedAt:   job.UpdatedAt,
+		CompletedAt: job.CompletedAt,
+	}
+	if registration, ok := sd.lookup(job.Queue); ok {
+		response.MaxAttempts = registration.maxAttempts
+	}
+	if job.Status == StatusPending {
+		response.NextAttemptAt = &job.NextAttemptAt
+	}
+	if withPayload {
+		response.Payload = job.Payload
+	}
+	return response
+}
+
+// runProcessor runs a processor, turning a panic into a failed attempt so that it does not stop the worker
+func runProcessor(process Processor, attempt Attempt) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("job panicked: %v", recovered)
+		}
+	}()
+	return process(attempt)
+}
+
+// retryDelay doubles the delay after every failed attempt, up to maxRetryDelay
+func retryDelay(attempts int) time.Duration {
+	delay := firstRetryDelay
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryDelay)
+}
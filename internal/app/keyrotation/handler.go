@@ -0,0 +1,52 @@
+package keyrotation
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/respond"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+// RotationProgress reports the state of the latest private key rotation
+type RotationProgress struct {
+	TargetVersion int           `json:"target_version"`
+	Running       bool          `json:"running"`
+	Rotated       int64         `json:"rotated"`
+	Remaining     int64         `json:"remaining"`
+	KeysByVersion map[int]int64 `json:"keys_by_version"`
+	StartedAt     *time.Time    `json:"started_at,omitempty"`
+	FinishedAt    *time.Time    `json:"finished_at,omitempty"`
+	Error         string        `json:"error,omitempty"`
+}
+
+type Handler struct {
+	service Service
+}
+
+// Constructor function
+func NewHandler(service Service) Handler {
+	return Handler{service: service}
+}
+
+// StartRotationHandler starts re-encrypting wallet private keys with the current key version, needs encryption.manage
+func (hd Handler) StartRotationHandler(w http.ResponseWriter, r *http.Request) {
+	progress, err := hd.service.StartRotation()
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, progress)
+}
+
+// ProgressHandler reports how far the rotation has come, needs encryption.manage
+func (hd Handler) ProgressHandler(w http.ResponseWriter, r *http.Request) {
+	progress, err := hd.service.Progress()
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, progress)
+}
@@ -0,0 +1,119 @@
+package keyrotation
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+const rotationBatchSize = 100
+
+type service struct {
+	walletRepo repo.WalletStorer
+	// Guards progress and keeps a second rotation from starting while one is running
+	mu       *sync.Mutex
+	progress *RotationProgress
+}
+
+type Service interface {
+	StartRotation() (RotationProgress, error)
+	Progress() (RotationProgress, error)
+}
+
+// Constructor function
+func NewService(walletRepo repo.WalletStorer) Service {
+	return service{
+		walletRepo: walletRepo,
+		mu:         &sync.Mutex{},
+		progress:   &RotationProgress{},
+	}
+}
+
+// StartRotation re-encrypts every private key not yet on the current key version or still on
+// the legacy AES-CFB cipher in the background. A rotation that failed or was cut short by a restart continues where it stopped.
+func (sd service) StartRotation() (RotationProgress, error) {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+
+	if sd.progress.Running {
+		return RotationProgress{}, utils.Conflict("key rotation already in progress")
+	}
+
+	counts, err := sd.walletRepo.CountPrivateKeysByVersion()
+	if err != nil {
+		return RotationProgress{}, err
+	}
+	remaining, err := sd.walletRepo.CountPrivateKeysToRotate()
+	if err != nil {
+		return RotationProgress{}, err
+	}
+	target := sd.walletRepo.CurrentKeyVersion()
+	startedAt := time.Now()
+	*sd.progress = RotationProgress{
+		TargetVersion: target,
+		Running:       true,
+		Remaining:     remaining,
+		KeysByVersion: counts,
+		StartedAt:     &startedAt,
+	}
+	if sd.progress.Remaining == 0 {
+		sd.progress.Running = false
+		sd.progress.FinishedAt = &startedAt
+		return *sd.progress, nil
+	}
+
+	log.Printf("Rotating %d private keys to key version %d", sd.progress.Remaining, target)
+	go sd.rotate()
+	return *sd.progress, nil
+}
+
+// Progress returns the latest rotation with key counts refreshed from the database
+func (sd service) Progress() (RotationProgress, error) {
+	counts, err := sd.walletRepo.CountPrivateKeysByVersion()
+	if err != nil {
+		return RotationProgress{}, err
+	}
+	remaining, err := sd.walletRepo.CountPrivateKeysToRotate()
+	if err != nil {
+		return RotationProgress{}, err
+	}
+
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	progress := *sd.progress
+	progress.TargetVersion = sd.walletRepo.CurrentKeyVersion()
+	progress.KeysByVersion = counts
+	progress.Remaining = remaining
+	return progress, nil
+}
+
+// rotate works through the keys batch by batch until none are left on an old version
+func (sd service) rotate() {
+	for {
+		rotated, err := sd.walletRepo.RotatePrivateKeys(rotationBatchSize)
+
+		sd.mu.Lock()
+		sd.progress.Rotated += rotated
+		sd.progress.Remaining -= rotated
+		if sd.progress.Remaining < 0 {
+			sd.progress.Remaining = 0
+		}
+		if err != nil || rotated == 0 {
+			finishedAt := time.Now()
+			sd.progress.Running = false
+			sd.progress.FinishedAt = &finishedAt
+			if err != nil {
+				sd.progress.Error = err.Error()
+				log.Printf("Key rotation stopped after %d keys: %v", sd.progress.Rotated, err)
+			} else {
+				log.Printf("Key rotation finished, %d keys rotated", sd.progress.Rotated)
+			}
+			sd.mu.Unlock()
+			return
+		}
+		sd.mu.Unlock()
+	}
+}
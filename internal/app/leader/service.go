@@ -0,0 +1,68 @@
+package leader
+
+import (
+	"log"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+)
+
+const (
+	// Every instance competes for this lock, the one holding it runs the schedulers
+	lockName = "chainbank.schedulers"
+	// How often a follower tries to take over and the leader checks it still holds the lock, which
+	// bounds how long the schedulers stay idle after the leader stops
+	electionInterval = 15 * time.Second
+)
+
+type service struct {
+	lockRepo repo.LockStorer
+}
+
+type Service interface {
+	Run(stop <-chan struct{}, lead func(stop <-chan struct{}))
+}
+
+// Constructor function
+func NewService(lockRepo repo.LockStorer) Service {
+	return service{lockRepo: lockRepo}
+}
+
+// Run elects a single leader among the instances sharing the database until stop is closed. lead starts
+// the periodic work that must run once across the fleet whenever this instance becomes the leader, the
+// stop channel it gets is closed when the leadership is lost or given up.
+func (sd service) Run(stop <-chan struct{}, lead func(stop <-chan struct{})) {
+	ticker := time.NewTicker(electionInterval)
+	defer ticker.Stop()
+
+	var lock *repo.Lock
+	var stopLeading chan struct{}
+	resign := func() {
+		close(stopLeading)
+		lock.Release()
+		lock = nil
+	}
+
+	for {
+		if lock == nil {
+			taken, ok, err := sd.lockRepo.TryLock(lockName)
+			if err == nil && ok {
+				log.Println("Elected scheduler leader")
+				lock, stopLeading = taken, make(chan struct{})
+				lead(stopLeading)
+			}
+		} else if !lock.Held() {
+			log.Println("Lost scheduler leadership")
+			resign()
+		}
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			if lock != nil {
+				resign()
+			}
+			return
+		}
+	}
+}
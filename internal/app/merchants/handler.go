@@ -0,0 +1,313 @@
+package merchants
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/respond"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+	"github.com/gorilla/mux"
+)
+
+// MerchantRequest registers or updates a merchant, webhooks are off while webhook_url is empty
+type MerchantRequest struct {
+	Name       string `json:"name"`
+	WebhookURL string `json:"webhook_url"`
+}
+
+// MerchantResponse represents a merchant, WebhookSecret is only set when it was just generated
+type MerchantResponse struct {
+	MerchantID    string    `json:"merchant_id"`
+	Name          string    `json:"name"`
+	WebhookURL    string    `json:"webhook_url"`
+	WebhookSecret string    `json:"webhook_secret,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// CreateIntentRequest asks for amount wei, the intent expires after an hour unless expires_at is set.
+// Reference is the merchant's own order ID, reusing it returns the existing intent.
+type CreateIntentRequest struct {
+	Amount      string     `json:"amount"`
+	Description string     `json:"description"`
+	Reference   string     `json:"reference"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+}
+
+// PayIntentRequest carries the confirmation of the customer's transfer, the same fields as a transfer takes
+type PayIntentRequest struct {
+	Password    string `json:"password"`
+	TOTPCode    string `json:"totp_code"`
+	DeviceToken string `json:"device_token"`
+	PIN         string `json:"pin"`
+}
+
+// PaymentIntentResponse represents a payment intent, QRPayload is only set while it can be paid
+type PaymentIntentResponse struct {
+	IntentID        string     `json:"payment_intent_id"`
+	MerchantID      string     `json:"merchant_id"`
+	MerchantName    string     `json:"merchant_name"`
+	RecipientWallet string     `json:"recipient_wallet_id"`
+	Amount          string     `json:"amount"`
+	Description     string     `json:"description,omitempty"`
+	Reference       string     `json:"reference,omitempty"`
+	Status          string     `json:"status"`
+	ExpiresAt       time.Time  `json:"expires_at"`
+	QRPayload       string     `json:"qr_payload,omitempty"`
+	CustomerUserID  string     `json:"customer_user_id,omitempty"`
+	TxHash          string     `json:"transaction_hash,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	CompletedAt     *time.Time `json:"completed_at,omitempty"`
+}
+
+// SettlementResponse totals the payments of one UTC day, amount in wei
+type SettlementResponse struct {
+	Date         string    `json:"date"`
+	PaymentCount int       `json:"payment_count"`
+	GrossAmount  string    `json:"gross_amount"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// WebhookEventResponse represents a webhook event and its delivery
+type WebhookEventResponse struct {
+	EventID       string          `json:"event_id"`
+	EventType     string          `json:"type"`
+	Data          json.RawMessage `json:"data"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	LastError     string          `json:"last_error,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	DeliveredAt   *time.Time      `json:"delivered_at,omitempty"`
+}
+
+type Handler struct {
+	service Service
+}
+
+// Constructor function
+func NewHandler(service Service) Handler {
+	return Handler{service: service}
+}
+
+// RegisterMerchantHandler registers the authenticated user as a merchant
+func (hd Handler) RegisterMerchantHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req MerchantRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	merchant, err := hd.service.RegisterMerchant(userInfo.UserID, req)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, merchant)
+}
+
+// GetMerchantHandler returns the merchant of the authenticated user
+func (hd Handler) GetMerchantHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	merchant, err := hd.service.GetMerchant(userInfo.UserID)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, merchant)
+}
+
+// UpdateMerchantHandler changes the name and webhook URL of the authenticated user's merchant
+func (hd Handler) UpdateMerchantHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req MerchantRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	merchant, err := hd.service.UpdateMerchant(userInfo.UserID, req)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, merchant)
+}
+
+// RotateWebhookSecretHandler generates a new webhook secret and returns it once
+func (hd Handler) RotateWebhookSecretHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	merchant, err := hd.service.RotateWebhookSecret(userInfo.UserID)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, merchant)
+}
+
+// CreateIntentHandler creates a payment intent of the authenticated user's merchant
+func (hd Handler) CreateIntentHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req CreateIntentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	intent, err := hd.service.CreateIntent(userInfo.UserID, req)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, intent)
+}
+
+// ListIntentsHandler lists the payment intents of the authenticated user's merchant
+func (hd Handler) ListIntentsHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	intents, err := hd.service.ListIntents(userInfo.UserID)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.List(w, r, intents, respond.Pagination{Count: len(intents)})
+}
+
+// GetMerchantIntentHandler returns a payment intent of the authenticated user's merchant
+func (hd Handler) GetMerchantIntentHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	intent, err := hd.service.GetMerchantIntent(userInfo.UserID, mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, intent)
+}
+
+// CancelIntentHandler cancels an unpaid payment intent of the authenticated user's merchant
+func (hd Handler) CancelIntentHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	intent, err := hd.service.CancelIntent(userInfo.UserID, mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, intent)
+}
+
+// ListSettlementsHandler lists the daily settlements of the authenticated user's merchant
+func (hd Handler) ListSettlementsHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	settlements, err := hd.service.ListSettlements(userInfo.UserID)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.List(w, r, settlements, respond.Pagination{Count: len(settlements)})
+}
+
+// ListWebhookEventsHandler lists the webhook events of the authenticated user's merchant
+func (hd Handler) ListWebhookEventsHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	events, err := hd.service.ListWebhookEvents(userInfo.UserID)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.List(w, r, events, respond.Pagination{Count: len(events)})
+}
+
+// GetIntentHandler returns a payment intent for the customer to review before paying
+func (hd Handler) GetIntentHandler(w http.ResponseWriter, r *http.Request) {
+	intent, err := hd.service.GetIntent(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, intent)
+}
+
+// PayIntentHandler pays a payment intent from the authenticated user's wallet
+func (hd Handler) PayIntentHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req PayIntentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	intent, err := hd.service.PayIntent(userInfo, mux.Vars(r)["id"], req)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, intent)
+}
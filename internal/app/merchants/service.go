@@ -0,0 +1,523 @@
+package merchants
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/ethereum"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/jobs"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/wallet"
+	"github.com/CodeWithKrushnal/ChainBank/internal/config"
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+// Intent statuses, an intent is processing while the customer's transfer is confirmed and broadcast.
+// Expired is never stored, an unpaid intent past its expiry is reported as expired.
+const (
+	StatusRequiresPayment = "requires_payment"
+	StatusProcessing      = "processing"
+	StatusSucceeded       = "succeeded"
+	StatusCancelled       = "cancelled"
+	StatusExpired         = "expired"
+)
+
+// Webhook event types
+const (
+	EventIntentSucceeded   = "payment_intent.succeeded"
+	EventIntentCancelled   = "payment_intent.cancelled"
+	EventSettlementCreated = "settlement.created"
+)
+
+const (
+	defaultExpiry        = time.Hour
+	maxExpiry            = 7 * 24 * time.Hour
+	maxNameLength        = 100
+	maxDescriptionLength = 255
+	maxReferenceLength   = 100
+	maxWebhookURLLength  = 2048
+	listLimit            = 100
+)
+
+type service struct {
+	merchantRepo repo.MerchantStorer
+	walletRepo   repo.WalletStorer
+	wallets      wallet.Service
+	jobs         jobs.Service
+	client       *http.Client
+}
+
+type Service interface {
+	RegisterMerchant(userID string, req MerchantRequest) (MerchantResponse, error)
+	GetMerchant(userID string) (MerchantResponse, error)
+	UpdateMerchant(userID string, req MerchantRequest) (MerchantResponse, error)
+	RotateWebhookSecret(userID string) (MerchantResponse, error)
+	CreateIntent(userID string, req CreateIntentRequest) (PaymentIntentResponse, error)
+	ListIntents(userID string) ([]PaymentIntentResponse, error)
+	GetMerchantIntent(userID, intentID string) (PaymentIntentResponse, error)
+	CancelIntent(userID, intentID string) (PaymentIntentResponse, error)
+	GetIntent(intentID string) (PaymentIntentResponse, error)
+	PayIntent(userInfo utils.User, intentID string, req PayIntentRequest) (PaymentIntentResponse, error)
+	ListSettlements(userID string) ([]SettlementResponse, error)
+	ListWebhookEvents(userID string) ([]WebhookEventResponse, error)
+	RunSettlementScheduler(stop <-chan struct{})
+}
+
+// Constructor function, registers the webhook and settlement job queues
+func NewService(merchantRepo repo.MerchantStorer, walletRepo repo.WalletStorer, walletService wallet.Service, jobService jobs.Service) Service {
+	sd := service{
+		merchantRepo: merchantRepo,
+		walletRepo:   walletRepo,
+		wallets:      walletService,
+		jobs:         jobService,
+		client:       newWebhookClient(config.ConfigDetails.MerchantWebhookAllowPrivate),
+	}
+	jobService.Register(repo.WebhookJobQueue, maxWebhookAttempts, sd.processWebhook)
+	jobService.Register(settlementJobQueue, maxSettlementAttempts, sd.processSettlement)
+	return sd
+}
+
+// RegisterMerchant makes the user a merchant, payments to the merchant go to the user's wallet.
+// The webhook secret is only returned here and when it is rotated.
+func (sd service) RegisterMerchant(userID string, req MerchantRequest) (MerchantResponse, error) {
+	name, webhookURL, err := validateMerchant(req)
+	if err != nil {
+		return MerchantResponse{}, err
+	}
+	if _, err := sd.walletRepo.GetWalletID("", userID); err != nil {
+		return MerchantResponse{}, utils.NotFound("wallet not found", err)
+	}
+
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return MerchantResponse{}, err
+	}
+	merchant, err := sd.merchantRepo.CreateMerchant(repo.Merchant{
+		OwnerUserID: userID,
+		Name:        name,
+		WebhookURL:  webhookURL,
+	}, secret)
+	if err != nil {
+		return MerchantResponse{}, err
+	}
+
+	response := newMerchantResponse(merchant)
+	response.WebhookSecret = secret
+	return response, nil
+}
+
+// GetMerchant returns the merchant of the user
+func (sd service) GetMerchant(userID string) (MerchantResponse, error) {
+	merchant, err := sd.merchantRepo.GetMerchantByOwner(userID)
+	if err != nil {
+		return MerchantResponse{}, err
+	}
+	return newMerchantResponse(merchant), nil
+}
+
+// UpdateMerchant changes the name and webhook URL, an empty URL turns webhooks off
+func (sd service) UpdateMerchant(userID string, req MerchantRequest) (MerchantResponse, error) {
+	name, webhookURL, err := validateMerchant(req)
+	if err != nil {
+		return MerchantResponse{}, err
+	}
+	merchant, err := sd.merchantRepo.GetMerchantByOwner(userID)
+	if err != nil {
+		return MerchantResponse{}, err
+	}
+
+	merchant.Name = name
+	merchant.WebhookURL = webhookURL
+	if merchant, err = sd.merchantRepo.UpdateMerchant(merchant); err != nil {
+		return MerchantResponse{}, err
+	}
+	return newMerchantResponse(merchant), nil
+}
+
+// RotateWebhookSecret replaces the webhook secret, webhooks sent from now on are signed with the new one
+func (sd service) RotateWebhookSecret(userID string) (MerchantResponse, error) {
+	merchant, err := sd.merchantRepo.GetMerchantByOwner(userID)
+	if err != nil {
+		return MerchantResponse{}, err
+	}
+
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return MerchantResponse{}, err
+	}
+	if err := sd.merchantRepo.SaveWebhookSecret(merchant.MerchantID, secret); err != nil {
+		return MerchantResponse{}, err
+	}
+
+	response := newMerchantResponse(merchant)
+	response.WebhookSecret = secret
+	return response, nil
+}
+
+// CreateIntent asks for a payment to the merchant. Creating an intent with a reference the merchant
+// already used returns the existing intent, so retried requests do not charge a customer twice.
+func (sd service) CreateIntent(userID string, req CreateIntentRequest) (PaymentIntentResponse, error) {
+	amount, ok := new(big.Int).SetString(req.Amount, 10)
+	if !ok || amount.Sign() <= 0 {
+		return PaymentIntentResponse{}, utils.Validation("amount must be a positive integer in wei")
+	}
+	description := strings.TrimSpace(req.Description)
+	if len(description) > maxDescriptionLength {
+		return PaymentIntentResponse{}, utils.Validationf("description cannot be longer than %d characters", maxDescriptionLength)
+	}
+	reference := strings.TrimSpace(req.Reference)
+	if len(reference) > maxReferenceLength {
+		return PaymentIntentResponse{}, utils.Validationf("reference cannot be longer than %d characters", maxReferenceLength)
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(defaultExpiry)
+	if req.ExpiresAt != nil {
+		expiresAt = *req.ExpiresAt
+		if !expiresAt.After(now) || expiresAt.After(now.Add(maxExpiry)) {
+			return PaymentIntentResponse{}, utils.Validationf("expires_at must be in the future and within %d days", int(maxExpiry.Hours()/24))
+		}
+	}
+
+	merchant, err := sd.merchantRepo.GetMerchantByOwner(userID)
+	if err != nil {
+		return PaymentIntentResponse{}, err
+	}
+	intent, created, err := sd.merchantRepo.CreatePaymentIntent(repo.PaymentIntent{
+		MerchantID:  merchant.MerchantID,
+		Amount:      amount.String(),
+		Description: description,
+		Reference:   reference,
+		ExpiresAt:   expiresAt.UTC().Truncate(time.Second),
+	})
+	if err != nil {
+		return PaymentIntentResponse{}, err
+	}
+	if !created && intent.Amount != amount.String() {
+		return PaymentIntentResponse{}, utils.Conflict("reference was already used for a different amount")
+	}
+	return sd.toResponse(intent, merchant)
+}
+
+// ListIntents returns the latest payment intents of the user's merchant
+func (sd service) ListIntents(userID string) ([]PaymentIntentResponse, error) {
+	merchant, err := sd.merchantRepo.GetMerchantByOwner(userID)
+	if err != nil {
+		return nil, err
+	}
+	intents, err := sd.merchantRepo.GetPaymentIntents(merchant.MerchantID, listLimit)
+	if err != nil {
+		return nil, err
+	}
+	walletID, err := sd.walletRepo.GetWalletID("", merchant.OwnerUserID)
+	if err != nil {
+		return nil, utils.NotFound("wallet not found", err)
+	}
+
+	response := make([]PaymentIntentResponse, len(intents))
+	for i, intent := range intents {
+		response[i] = newPaymentIntentResponse(intent, merchant, walletID)
+	}
+	return response, nil
+}
+
+// GetMerchantIntent returns a payment intent of the user's merchant
+func (sd service) GetMerchantIntent(userID, intentID string) (PaymentIntentResponse, error) {
+	intent, merchant, err := sd.merchantIntent(userID, intentID)
+	if err != nil {
+		return PaymentIntentResponse{}, err
+	}
+	return sd.toResponse(intent, merchant)
+}
+
+// CancelIntent withdraws an unpaid intent of the user's merchant
+func (sd service) CancelIntent(userID, intentID string) (PaymentIntentResponse, error) {
+	intent, merchant, err := sd.merchantIntent(userID, intentID)
+	if err != nil {
+		return PaymentIntentResponse{}, err
+	}
+	if err := checkPayable(intent); err != nil {
+		return PaymentIntentResponse{}, err
+	}
+
+	intent.Status = StatusCancelled
+	event, err := sd.intentEvent(EventIntentCancelled, intent, merchant)
+	if err != nil {
+		return PaymentIntentResponse{}, err
+	}
+	cancelled, err := sd.merchantRepo.CancelPaymentIntent(intentID, merchant.MerchantID, event)
+	if err != nil {
+		return PaymentIntentResponse{}, err
+	}
+	if !cancelled {
+		return PaymentIntentResponse{}, utils.Conflict("payment intent can no longer be cancelled")
+	}
+
+	if intent, err = sd.merchantRepo.GetPaymentIntent(intentID); err != nil {
+		return PaymentIntentResponse{}, err
+	}
+	return sd.toResponse(intent, merchant)
+}
+
+// GetIntent returns a payment intent as the customer sees it at checkout
+func (sd service) GetIntent(intentID string) (PaymentIntentResponse, error) {
+	intent, err := sd.merchantRepo.GetPaymentIntent(intentID)
+	if err != nil {
+		return PaymentIntentResponse{}, err
+	}
+	merchant, err := sd.merchantRepo.GetMerchant(intent.MerchantID)
+	if err != nil {
+		return PaymentIntentResponse{}, err
+	}
+	return sd.toResponse(intent, merchant)
+}
+
+// PayIntent pays an intent through the regular transfer, so the customer confirms it the way they
+// confirm any transfer and the transfer limits apply. The intent is claimed first so it is paid once,
+// the merchant's webhook event is queued together with the payment being recorded.
+func (sd service) PayIntent(userInfo utils.User, intentID string, req PayIntentRequest) (PaymentIntentResponse, error) {
+	intent, err := sd.merchantRepo.GetPaymentIntent(intentID)
+	if err != nil {
+		return PaymentIntentResponse{}, err
+	}
+	merchant, err := sd.merchantRepo.GetMerchant(intent.MerchantID)
+	if err != nil {
+		return PaymentIntentResponse{}, err
+	}
+	if merchant.OwnerUserID == userInfo.UserID {
+		return PaymentIntentResponse{}, utils.Validation("you cannot pay your own payment intent")
+	}
+	if err := checkPayable(intent); err != nil {
+		return PaymentIntentResponse{}, err
+	}
+
+	claimed, err := sd.merchantRepo.ClaimPaymentIntent(intentID, userInfo.UserID)
+	if err != nil {
+		return PaymentIntentResponse{}, err
+	}
+	if !claimed {
+		return PaymentIntentResponse{}, utils.Conflict("payment intent can no longer be paid")
+	}
+
+	txHash, err := sd.wallets.TransferFunds(userInfo, wallet.TransferRequest{
+		RecipientUserID: merchant.OwnerUserID,
+		AmountETH:       intent.Amount,
+		Password:        req.Password,
+		TOTPCode:        req.TOTPCode,
+		DeviceToken:     req.DeviceToken,
+		PIN:             req.PIN,
+	})
+	if err != nil {
+		if releaseErr := sd.merchantRepo.ReleasePaymentIntent(intentID); releaseErr != nil {
+			log.Printf("Error reopening payment intent %s: %v", intentID, releaseErr)
+		}
+		return PaymentIntentResponse{}, err
+	}
+
+	completedAt := time.Now().UTC()
+	intent.Status = StatusSucceeded
+	intent.CustomerUserID = userInfo.UserID
+	intent.TxHash = txHash
+	intent.CompletedAt = &completedAt
+	event, err := sd.intentEvent(EventIntentSucceeded, intent, merchant)
+	if err == nil {
+		err = sd.merchantRepo.CompletePaymentIntent(intentID, txHash, completedAt, event)
+	}
+	if err != nil {
+		// The transfer is already broadcast, only its record is behind
+		log.Printf("Error recording transaction %s of payment intent %s: %v", txHash, intentID, err)
+	}
+	return sd.toResponse(intent, merchant)
+}
+
+// ListSettlements returns the latest daily settlements of the user's merchant
+func (sd service) ListSettlements(userID string) ([]SettlementResponse, error) {
+	merchant, err := sd.merchantRepo.GetMerchantByOwner(userID)
+	if err != nil {
+		return nil, err
+	}
+	settlements, err := sd.merchantRepo.GetSettlements(merchant.MerchantID, listLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]SettlementResponse, len(settlements))
+	for i, settlement := range settlements {
+		response[i] = newSettlementResponse(settlement)
+	}
+	return response, nil
+}
+
+// ListWebhookEvents returns the latest webhook events of the user's merchant with their delivery status
+func (sd service) ListWebhookEvents(userID string) ([]WebhookEventResponse, error) {
+	merchant, err := sd.merchantRepo.GetMerchantByOwner(userID)
+	if err != nil {
+		return nil, err
+	}
+	events, err := sd.merchantRepo.GetWebhookEvents(merchant.MerchantID, listLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]WebhookEventResponse, len(events))
+	for i, event := range events {
+		response[i] = WebhookEventResponse{
+			EventID:       event.EventID,
+			EventType:     event.EventType,
+			Data:          event.Payload,
+			Status:        event.Status,
+			Attempts:      event.Attempts,
+			NextAttemptAt: event.NextAttemptAt,
+			LastError:     event.LastError,
+			CreatedAt:     event.CreatedAt,
+			DeliveredAt:   event.DeliveredAt,
+		}
+	}
+	return response, nil
+}
+
+// merchantIntent returns the intent if it belongs to the user's merchant, intents of others are not disclosed
+func (sd service) merchantIntent(userID, intentID string) (repo.PaymentIntent, repo.Merchant, error) {
+	merchant, err := sd.merchantRepo.GetMerchantByOwner(userID)
+	if err != nil {
+		return repo.PaymentIntent{}, merchant, err
+	}
+	intent, err := sd.merchantRepo.GetPaymentIntent(intentID)
+	if err != nil {
+		return intent, merchant, err
+	}
+	if intent.MerchantID != merchant.MerchantID {
+		return intent, merchant, utils.NotFound("payment intent not found", nil)
+	}
+	return intent, merchant, nil
+}
+
+// intentEvent builds the webhook event for an intent, nil when the merchant has no webhook
+func (sd service) intentEvent(eventType string, intent repo.PaymentIntent, merchant repo.Merchant) (*repo.WebhookEvent, error) {
+	if merchant.WebhookURL == "" {
+		return nil, nil
+	}
+	data, err := sd.toResponse(intent, merchant)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding webhook event: %v", err)
+	}
+	return &repo.WebhookEvent{MerchantID: merchant.MerchantID, EventType: eventType, Payload: payload}, nil
+}
+
+func (sd service) toResponse(intent repo.PaymentIntent, merchant repo.Merchant) (PaymentIntentResponse, error) {
+	walletID, err := sd.walletRepo.GetWalletID("", merchant.OwnerUserID)
+	if err != nil {
+		return PaymentIntentResponse{}, utils.NotFound("wallet not found", err)
+	}
+	return newPaymentIntentResponse(intent, merchant, walletID), nil
+}
+
+// checkPayable rejects intents that were paid, cancelled or have expired
+func checkPayable(intent repo.PaymentIntent) error {
+	switch EffectiveStatus(intent) {
+	case StatusSucceeded, StatusProcessing:
+		return utils.Conflict("payment intent was already paid")
+	case StatusCancelled:
+		return utils.Conflict("payment intent was cancelled")
+	case StatusExpired:
+		return utils.Conflict("payment intent has expired")
+	}
+	return nil
+}
+
+// EffectiveStatus returns the status of an intent, expired for an unpaid intent past its expiry
+func EffectiveStatus(intent repo.PaymentIntent) string {
+	if intent.Status == StatusRequiresPayment && time.Now().After(intent.ExpiresAt) {
+		return StatusExpired
+	}
+	return intent.Status
+}
+
+// validateMerchant returns the trimmed name and webhook URL. Webhooks go to https URLs only, plain
+// http is accepted together with private addresses for local development.
+func validateMerchant(req MerchantRequest) (string, string, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > maxNameLength {
+		return "", "", utils.Validationf("name is required and must be at most %d characters", maxNameLength)
+	}
+
+	webhookURL := strings.TrimSpace(req.WebhookURL)
+	if webhookURL == "" {
+		return name, "", nil
+	}
+	parsed, err := url.Parse(webhookURL)
+	if err != nil || len(webhookURL) > maxWebhookURLLength || parsed.Host == "" || parsed.User != nil {
+		return "", "", utils.Validation("webhook_url must be an absolute https URL")
+	}
+	if parsed.Scheme != "https" && !(parsed.Scheme == "http" && config.ConfigDetails.MerchantWebhookAllowPrivate) {
+		return "", "", utils.Validation("webhook_url must be an absolute https URL")
+	}
+	return name, webhookURL, nil
+}
+
+// newWebhookSecret returns a random secret for signing webhooks
+func newWebhookSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("error generating webhook secret: %v", err)
+	}
+	return "whsec_" + hex.EncodeToString(secret), nil
+}
+
+// qrPayload encodes the intent as an EIP-681 payment URI like payment requests do, the app pays it
+// through the intent endpoint using payment_intent_id
+func qrPayload(intent repo.PaymentIntent, walletID string) string {
+	return fmt.Sprintf("ethereum:%s@%s?value=%s&payment_intent_id=%s", walletID, ethereum.ChainID.String(), intent.Amount, intent.IntentID)
+}
+
+func newMerchantResponse(merchant repo.Merchant) MerchantResponse {
+	return MerchantResponse{
+		MerchantID: merchant.MerchantID,
+		Name:       merchant.Name,
+		WebhookURL: merchant.WebhookURL,
+		CreatedAt:  merchant.CreatedAt,
+	}
+}
+
+func newPaymentIntentResponse(intent repo.PaymentIntent, merchant repo.Merchant, walletID string) PaymentIntentResponse {
+	response := PaymentIntentResponse{
+		IntentID:        intent.IntentID,
+		MerchantID:      merchant.MerchantID,
+		MerchantName:    merchant.Name,
+		RecipientWallet: walletID,
+		Amount:          intent.Amount,
+		Description:     intent.Description,
+		Reference:       intent.Reference,
+		Status:          EffectiveStatus(intent),
+		ExpiresAt:       intent.ExpiresAt,
+		CustomerUserID:  intent.CustomerUserID,
+		TxHash:          intent.TxHash,
+		CreatedAt:       intent.CreatedAt,
+		CompletedAt:     intent.CompletedAt,
+	}
+	if response.Status == StatusRequiresPayment {
+		response.QRPayload = qrPayload(intent, walletID)
+	}
+	return response
+}
+
+func newSettlementResponse(settlement repo.MerchantSettlement) SettlementResponse {
+	return SettlementResponse{
+		Date:         settlement.SettlementDate.Format(time.DateOnly),
+		PaymentCount: settlement.PaymentCount,
+		GrossAmount:  settlement.GrossAmount,
+		CreatedAt:    settlement.CreatedAt,
+	}
+}
@@ -0,0 +1,239 @@
+package merchants
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/jobs"
+	"github.com/CodeWithKrushnal/ChainBank/internal/config"
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+)
+
+const (
+	// SignatureHeader carries t=<unix time>,v1=<hex HMAC-SHA256 of "<t>.<body>"> keyed with the
+	// merchant's webhook secret. Merchants should reject stale timestamps to stop replays.
+	SignatureHeader = "X-ChainBank-Signature"
+
+	webhookTimeout     = 10 * time.Second
+	maxWebhookAttempts = 10
+
+	// Background job queue of the daily settlements, its jobs carry the UTC day to settle
+	settlementJobQueue    = "merchant.settlement"
+	maxSettlementAttempts = 5
+)
+
+var errPrivateAddress = errors.New("webhook address is not public")
+
+// webhookBody is what a merchant's webhook receives, Data is the intent or settlement of the event
+type webhookBody struct {
+	EventID   string          `json:"id"`
+	EventType string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// webhookJob is the payload of a webhook delivery job
+type webhookJob struct {
+	EventID string `json:"event_id"`
+}
+
+// settlementJob is the payload of a settlement job
+type settlementJob struct {
+	Day string `json:"day"`
+}
+
+// RunSettlementScheduler queues the settlement of the previous UTC day once a day at the configured
+// hour until stop is closed
+func (sd service) RunSettlementScheduler(stop <-chan struct{}) {
+	hour := config.ConfigDetails.MerchantSettlementHourUTC
+	if hour < 0 {
+		log.Println("Scheduled merchant settlements disabled")
+		return
+	}
+
+	for {
+		timer := time.NewTimer(time.Until(nextSettlementTime(time.Now(), hour)))
+		select {
+		case <-timer.C:
+			day := time.Now().UTC().AddDate(0, 0, -1).Format(time.DateOnly)
+			if err := sd.jobs.Enqueue(settlementJobQueue, settlementJob{Day: day}); err != nil {
+				log.Printf("Error queueing merchant settlement of %s: %v", day, err)
+			}
+		case <-stop:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// processSettlement runs a settlement job
+func (sd service) processSettlement(attempt jobs.Attempt) error {
+	var job settlementJob
+	if err := json.Unmarshal(attempt.Payload, &job); err != nil {
+		return fmt.Errorf("invalid settlement job: %v", err)
+	}
+	day, err := time.Parse(time.DateOnly, job.Day)
+	if err != nil {
+		return fmt.Errorf("invalid settlement day: %v", err)
+	}
+	return sd.settle(day)
+}
+
+// settle records the settlements of day and queues a webhook event for each of them. Merchants
+// settled before are skipped, so a failed run can be repeated.
+func (sd service) settle(day time.Time) error {
+	settlements, err := sd.merchantRepo.CreateSettlements(day)
+	if err != nil {
+		return fmt.Errorf("error creating settlements: %v", err)
+	}
+
+	for _, settlement := range settlements {
+		merchant, err := sd.merchantRepo.GetMerchant(settlement.MerchantID)
+		if err != nil {
+			log.Printf("Error loading merchant %s for its settlement: %v", settlement.MerchantID, err)
+			continue
+		}
+		if merchant.WebhookURL == "" {
+			continue
+		}
+
+		payload, err := json.Marshal(newSettlementResponse(settlement))
+		if err != nil {
+			log.Printf("Error encoding settlement of merchant %s: %v", settlement.MerchantID, err)
+			continue
+		}
+		if err := sd.merchantRepo.EnqueueWebhookEvent(repo.WebhookEvent{
+			MerchantID: settlement.MerchantID,
+			EventType:  EventSettlementCreated,
+			Payload:    payload,
+		}); err != nil {
+			log.Printf("Error queueing settlement of merchant %s: %v", settlement.MerchantID, err)
+		}
+	}
+	log.Printf("Settled %d merchants for %s", len(settlements), day.Format(time.DateOnly))
+	return nil
+}
+
+// processWebhook runs a webhook delivery job and mirrors the outcome on the event the merchant sees
+func (sd service) processWebhook(attempt jobs.Attempt) error {
+	var job webhookJob
+	if err := json.Unmarshal(attempt.Payload, &job); err != nil {
+		return fmt.Errorf("invalid webhook job: %v", err)
+	}
+	event, err := sd.merchantRepo.GetWebhookEvent(job.EventID)
+	if err != nil {
+		return err
+	}
+	if event.Status == "delivered" {
+		return nil
+	}
+
+	if err := sd.deliver(event); err != nil {
+		if err := sd.merchantRepo.RecordWebhookFailure(event.EventID, err.Error(), attempt.RetryAt); err != nil {
+			log.Printf("Error recording webhook failure: %v", err)
+		}
+		return err
+	}
+	return sd.merchantRepo.MarkWebhookDelivered(event.EventID)
+}
+
+// deliver posts a signed event to the merchant's current webhook URL, any 2xx response counts as delivered
+func (sd service) deliver(event repo.WebhookEvent) error {
+	merchant, err := sd.merchantRepo.GetMerchant(event.MerchantID)
+	if err != nil {
+		return err
+	}
+	if merchant.WebhookURL == "" {
+		return errors.New("webhook URL was removed")
+	}
+	secret, err := sd.merchantRepo.GetWebhookSecret(event.MerchantID)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(webhookBody{
+		EventID:   event.EventID,
+		EventType: event.EventType,
+		CreatedAt: event.CreatedAt,
+		Data:      event.Payload,
+	})
+	if err != nil {
+		return fmt.Errorf("error encoding webhook: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, merchant.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error creating webhook request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, signWebhook(secret, time.Now(), body))
+
+	resp, err := sd.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending webhook: %v", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// signWebhook returns the SignatureHeader value for body sent at now
+func signWebhook(secret string, now time.Time, body []byte) string {
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// nextSettlementTime returns the next occurrence of hour UTC after now
+func nextSettlementTime(now time.Time, hour int) time.Time {
+	now = now.UTC()
+	next := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, time.UTC)
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// newWebhookClient returns the client for webhooks. Merchants choose the URL, so unless private
+// addresses are allowed the client refuses to connect to loopback, private and link-local
+// addresses, checked after DNS resolution and on every redirect.
+func newWebhookClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: webhookTimeout}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+				ip.IsMulticast() || ip.IsUnspecified() {
+				return errPrivateAddress
+			}
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: webhookTimeout, Transport: transport}
+}
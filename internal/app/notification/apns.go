@@ -0,0 +1,142 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	apnsProductionHost = "https://api.push.apple.com"
+	apnsSandboxHost    = "https://api.sandbox.push.apple.com"
+	// Apple rejects provider tokens older than an hour, refresh well before that
+	apnsTokenLifetime = 50 * time.Minute
+)
+
+// apnsProvider sends iOS notifications through APNs using token based authentication
+type apnsProvider struct {
+	host   string
+	keyID  string
+	teamID string
+	topic  string
+	key    *ecdsa.PrivateKey
+	client *http.Client
+
+	mu          sync.Mutex
+	token       string
+	tokenIssued time.Time
+}
+
+// newAPNsProvider loads the .p8 signing key issued by Apple
+func newAPNsProvider(keyFile, keyID, teamID, topic string, production bool) (*apnsProvider, error) {
+	keyPEM, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading APNs key: %w", err)
+	}
+
+	key, err := jwt.ParseECPrivateKeyFromPEM(keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing APNs key: %w", err)
+	}
+
+	host := apnsSandboxHost
+	if production {
+		host = apnsProductionHost
+	}
+
+	return &apnsProvider{
+		host:   host,
+		keyID:  keyID,
+		teamID: teamID,
+		topic:  topic,
+		key:    key,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// providerToken returns a cached ES256 provider token, signing a new one when it is about to expire
+func (apns *apnsProvider) providerToken() (string, error) {
+	apns.mu.Lock()
+	defer apns.mu.Unlock()
+
+	if apns.token != "" && time.Since(apns.tokenIssued) < apnsTokenLifetime {
+		return apns.token, nil
+	}
+
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"iss": apns.teamID,
+		"iat": now.Unix(),
+	})
+	token.Header["kid"] = apns.keyID
+
+	signed, err := token.SignedString(apns.key)
+	if err != nil {
+		return "", err
+	}
+
+	apns.token = signed
+	apns.tokenIssued = now
+	return signed, nil
+}
+
+func (apns *apnsProvider) Send(ctx context.Context, deviceToken string, message PushMessage) error {
+	body := map[string]interface{}{
+		"aps": map[string]interface{}{
+			"alert": map[string]string{
+				"title": message.Title,
+				"body":  message.Body,
+			},
+			"sound": "default",
+		},
+	}
+	for key, value := range message.Data {
+		body[key] = value
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	token, err := apns.providerToken()
+	if err != nil {
+		return fmt.Errorf("error signing APNs token: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apns.host+"/3/device/"+deviceToken, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("authorization", "bearer "+token)
+	req.Header.Set("apns-topic", apns.topic)
+	req.Header.Set("apns-push-type", "alert")
+
+	resp, err := apns.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error calling APNs: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var apnsErr struct {
+		Reason string `json:"reason"`
+	}
+	json.NewDecoder(resp.Body).Decode(&apnsErr)
+
+	if resp.StatusCode == http.StatusGone || apnsErr.Reason == "BadDeviceToken" || apnsErr.Reason == "Unregistered" {
+		return ErrInvalidDeviceToken
+	}
+	return fmt.Errorf("APNs responded %d: %s", resp.StatusCode, apnsErr.Reason)
+}
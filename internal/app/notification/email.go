@@ -0,0 +1,36 @@
+package notification
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+)
+
+// emailSender delivers notifications over SMTP
+type emailSender struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+func newEmailSender(addr, username, password, from string) *emailSender {
+	var auth smtp.Auth
+	if username != "" {
+		host, _, _ := net.SplitHostPort(addr)
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return &emailSender{addr: addr, from: from, auth: auth}
+}
+
+func (es *emailSender) Send(to, subject, body string) error {
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", es.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n\r\n")
+	msg.WriteString(body)
+
+	return smtp.SendMail(es.addr, es.auth, es.from, []string{to}, []byte(msg.String()))
+}
@@ -0,0 +1,79 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+const fcmScope = "https://www.googleapis.com/auth/firebase.messaging"
+
+// fcmProvider sends Android notifications through the Firebase Cloud Messaging HTTP v1 API
+type fcmProvider struct {
+	endpoint string
+	client   *http.Client
+}
+
+// newFCMProvider authenticates with a Firebase service account key file
+func newFCMProvider(projectID, credentialsFile string) (*fcmProvider, error) {
+	credentialsJSON, err := os.ReadFile(credentialsFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading FCM credentials: %w", err)
+	}
+
+	credentials, err := google.CredentialsFromJSON(context.Background(), credentialsJSON, fcmScope)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing FCM credentials: %w", err)
+	}
+
+	return &fcmProvider{
+		endpoint: fmt.Sprintf("https://fcm.googleapis.com/v1/projects/%s/messages:send", projectID),
+		client:   oauth2.NewClient(context.Background(), credentials.TokenSource),
+	}, nil
+}
+
+func (fcm *fcmProvider) Send(ctx context.Context, deviceToken string, message PushMessage) error {
+	payload, err := json.Marshal(map[string]interface{}{
+		"message": map[string]interface{}{
+			"token": deviceToken,
+			"notification": map[string]string{
+				"title": message.Title,
+				"body":  message.Body,
+			},
+			"data": message.Data,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fcm.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := fcm.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error calling FCM: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode == http.StatusNotFound || strings.Contains(string(body), "UNREGISTERED") {
+		return ErrInvalidDeviceToken
+	}
+	return fmt.Errorf("FCM responded %d: %s", resp.StatusCode, body)
+}
@@ -0,0 +1,113 @@
+package notification
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/respond"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+	"github.com/gorilla/mux"
+)
+
+// RegisterDeviceRequest represents a push token registration
+type RegisterDeviceRequest struct {
+	Token    string `json:"token"`
+	Platform string `json:"platform"`
+}
+
+// PreferencesResponse represents the channels, muted categories and delivery mode of a user
+type PreferencesResponse struct {
+	Email           bool     `json:"email"`
+	Push            bool     `json:"push"`
+	SMS             bool     `json:"sms"`
+	MutedCategories []string `json:"muted_categories"`
+	Delivery        string   `json:"delivery"`
+	Locale          string   `json:"locale"`
+}
+
+type Handler struct {
+	service Service
+}
+
+// Constructor function
+func NewHandler(service Service) Handler {
+	return Handler{service: service}
+}
+
+// RegisterDeviceHandler registers a push token for the authenticated user
+func (hd Handler) RegisterDeviceHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req RegisterDeviceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := hd.service.RegisterDevice(userInfo.UserID, req); err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UnregisterDeviceHandler removes a push token of the authenticated user
+func (hd Handler) UnregisterDeviceHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	if err := hd.service.UnregisterDevice(userInfo.UserID, mux.Vars(r)["token"]); err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetPreferencesHandler returns the authenticated user's notification preferences
+func (hd Handler) GetPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	preferences, err := hd.service.GetPreferences(userInfo.UserID)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, preferences)
+}
+
+// UpdatePreferencesHandler replaces the authenticated user's notification preferences
+func (hd Handler) UpdatePreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req PreferencesResponse
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	preferences, err := hd.service.UpdatePreferences(userInfo.UserID, req)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, preferences)
+}
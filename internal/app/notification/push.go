@@ -0,0 +1,21 @@
+package notification
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrInvalidDeviceToken is returned by a push provider when the token should be discarded
+var ErrInvalidDeviceToken = errors.New("device token is no longer valid")
+
+// PushMessage is the platform independent content of a push notification
+type PushMessage struct {
+	Title string
+	Body  string
+	Data  map[string]string
+}
+
+// pushProvider delivers a message to a single device
+type pushProvider interface {
+	Send(ctx context.Context, deviceToken string, message PushMessage) error
+}
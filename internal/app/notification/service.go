@@ -0,0 +1,516 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/jobs"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/outbox"
+	"github.com/CodeWithKrushnal/ChainBank/internal/config"
+	"github.com/CodeWithKrushnal/ChainBank/internal/i18n"
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+// Notification categories
+const (
+	CategoryTransfer       = "transfer"
+	CategoryBudget         = "budget"
+	CategoryBalance        = "balance"
+	CategoryPaymentRequest = "payment_request"
+	CategoryInvoice        = "invoice"
+	// Security alerts are always sent right away, they cannot be muted or batched
+	CategorySecurity = "security"
+)
+
+// Delivery modes
+const (
+	DeliveryImmediate   = "immediate"
+	DeliveryDailyDigest = "daily_digest"
+)
+
+// Categories users may mute or batch
+var knownCategories = []string{CategoryTransfer, CategoryBudget, CategoryBalance, CategoryPaymentRequest, CategoryInvoice}
+
+// Device platforms
+const (
+	PlatformAndroid = "android"
+	PlatformIOS     = "ios"
+)
+
+// TopicNotification is the outbox topic notifications are published on
+const TopicNotification = "notification"
+
+// Background job queues of the delivery channels
+const (
+	pushJobQueue    = "notification.push"
+	emailJobQueue   = "notification.email"
+	maxSendAttempts = 6
+)
+
+const (
+	deliveryTimeout = 30 * time.Second
+	// How often the digest scheduler looks for users whose local digest hour has started
+	digestRunInterval = 15 * time.Minute
+	// How long delivered notifications stay in the inbox clients sync from
+	inboxRetention = 30 * 24 * time.Hour
+)
+
+// Notification is an event addressed to a single user. Title and Body are written in English
+// and translated into the user's locale on delivery, Body may hold fmt verbs filled from BodyArgs.
+type Notification struct {
+	UserID   string
+	Category string
+	Title    string
+	Body     string
+	BodyArgs []any
+	Data     map[string]string
+}
+
+// sendJob is the payload of a push or email job, a notification already translated for its
+// recipient. Email jobs to someone without an account carry the address instead of a user ID.
+type sendJob struct {
+	UserID   string            `json:"user_id,omitempty"`
+	Address  string            `json:"address,omitempty"`
+	Category string            `json:"category"`
+	Title    string            `json:"title"`
+	Body     string            `json:"body"`
+	Data     map[string]string `json:"data,omitempty"`
+}
+
+// notificationEvent is the outbox payload of a notification that is not translated yet
+type notificationEvent struct {
+	UserID   string            `json:"user_id"`
+	Category string            `json:"category"`
+	Title    string            `json:"title"`
+	Body     string            `json:"body"`
+	BodyArgs []any             `json:"body_args,omitempty"`
+	Data     map[string]string `json:"data,omitempty"`
+}
+
+type service struct {
+	notificationRepo repo.NotificationStorer
+	userRepo         repo.UserStorer
+	jobs             jobs.Service
+	outbox           outbox.Service
+	pushProviders    map[string]pushProvider
+	email            *emailSender
+}
+
+type Service interface {
+	Notify(notification Notification)
+	NotifyEmail(address string, notification Notification)
+	RegisterDevice(userID string, req RegisterDeviceRequest) error
+	UnregisterDevice(userID, deviceToken string) error
+	GetPreferences(userID string) (PreferencesResponse, error)
+	UpdatePreferences(userID string, req PreferencesResponse) (PreferencesResponse, error)
+	GetInboxNotifications(userID string, cursor *repo.ChangeCursor, limit int) ([]repo.InboxNotification, error)
+	RunDigestScheduler(stop <-chan struct{})
+}
+
+// Constructor function, channels without configuration are left disabled. Subscribes to the
+// notification topic and registers the job queues sending push notifications and emails.
+func NewService(notificationRepo repo.NotificationStorer, userRepo repo.UserStorer, jobService jobs.Service, outboxService outbox.Service) Service {
+	cfg := config.ConfigDetails
+	sd := service{
+		notificationRepo: notificationRepo,
+		userRepo:         userRepo,
+		jobs:             jobService,
+		outbox:           outboxService,
+		pushProviders:    map[string]pushProvider{},
+	}
+
+	if cfg.FCMProjectID != "" {
+		fcm, err := newFCMProvider(cfg.FCMProjectID, cfg.FCMCredentialsFile)
+		if err != nil {
+			log.Printf("Android push disabled: %v", err)
+		} else {
+			sd.pushProviders[PlatformAndroid] = fcm
+		}
+	}
+
+	if cfg.APNsKeyFile != "" {
+		apns, err := newAPNsProvider(cfg.APNsKeyFile, cfg.APNsKeyID, cfg.APNsTeamID, cfg.APNsTopic, cfg.APNsProduction)
+		if err != nil {
+			log.Printf("iOS push disabled: %v", err)
+		} else {
+			sd.pushProviders[PlatformIOS] = apns
+		}
+	}
+
+	if cfg.SMTPAddr != "" {
+		sd.email = newEmailSender(cfg.SMTPAddr, cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPFrom)
+	}
+
+	outboxService.Subscribe(TopicNotification, sd.handleEvent)
+	jobService.Register(pushJobQueue, maxSendAttempts, sd.processPush)
+	jobService.Register(emailJobQueue, maxSendAttempts, sd.processEmail)
+	return sd
+}
+
+// Notify stores the notification and delivers it in the background on every channel the user enabled.
+// A stored notification is delivered even if the process stops right after.
+func (sd service) Notify(notification Notification) {
+	if err := sd.outbox.Publish(TopicNotification, notificationEvent(notification)); err != nil {
+		log.Printf("Error storing notification for %s, delivering it right away: %v", notification.UserID, err)
+		go func() {
+			if err := sd.deliver(notification); err != nil {
+				log.Printf("Error delivering notification to %s: %v", notification.UserID, err)
+			}
+		}()
+	}
+}
+
+// NewEvent returns the outbox event of a notification, for a repo to store in the same transaction as
+// the change the notification reports
+func NewEvent(notification Notification) (repo.OutboxEvent, error) {
+	return outbox.NewEvent(TopicNotification, notificationEvent(notification))
+}
+
+// NotifyEmail emails a notification in the default locale to someone without an account, nothing is
+// sent while email is not configured
+func (sd service) NotifyEmail(address string, notification Notification) {
+	if sd.email == nil {
+		return
+	}
+
+	err := sd.jobs.Enqueue(emailJobQueue, sendJob{
+		Address:  address,
+		Category: notification.Category,
+		Title:    i18n.T(i18n.DefaultLocale, notification.Title),
+		Body:     i18n.T(i18n.DefaultLocale, notification.Body, notification.BodyArgs...),
+	})
+	if err != nil {
+		log.Printf("Error queueing email notification to %s: %v", address, err)
+	}
+}
+
+// handleEvent delivers a notification published through the outbox
+func (sd service) handleEvent(payload json.RawMessage) error {
+	notification, err := decodeNotificationEvent(payload)
+	if err != nil {
+		return err
+	}
+	return sd.deliver(notification)
+}
+
+// deliver applies the user's preferences to a notification and queues it on the enabled channels
+func (sd service) deliver(notification Notification) error {
+	preferences, err := sd.notificationRepo.GetNotificationPreferences(notification.UserID)
+	if err != nil {
+		return fmt.Errorf("error loading notification preferences: %v", err)
+	}
+
+	security := notification.Category == CategorySecurity
+	if !security && slices.Contains(preferences.MutedCategories, notification.Category) {
+		return nil
+	}
+
+	notification.Title = i18n.T(preferences.Locale, notification.Title)
+	notification.Body = i18n.T(preferences.Locale, notification.Body, notification.BodyArgs...)
+	notification.BodyArgs = nil
+
+	err = sd.notificationRepo.AddInboxNotification(repo.InboxNotification{
+		UserID:   notification.UserID,
+		Category: notification.Category,
+		Title:    notification.Title,
+		Body:     notification.Body,
+		Data:     notification.Data,
+	})
+	if err != nil {
+		return fmt.Errorf("error storing notification in inbox: %v", err)
+	}
+
+	if !security && preferences.DeliveryMode == DeliveryDailyDigest {
+		err := sd.notificationRepo.QueueDigestItem(repo.DigestItem{
+			UserID:   notification.UserID,
+			Category: notification.Category,
+			Title:    notification.Title,
+			Body:     notification.Body,
+		})
+		if err != nil {
+			return fmt.Errorf("error queueing digest notification: %v", err)
+		}
+		return nil
+	}
+
+	return sd.send(notification, preferences)
+}
+
+// send queues a translated notification on every enabled channel, each channel is retried on its own
+func (sd service) send(notification Notification, preferences repo.NotificationPreferences) error {
+	job := sendJob{
+		UserID:   notification.UserID,
+		Category: notification.Category,
+		Title:    notification.Title,
+		Body:     notification.Body,
+		Data:     notification.Data,
+	}
+
+	var failures []error
+	if preferences.PushEnabled && len(sd.pushProviders) > 0 {
+		if err := sd.jobs.Enqueue(pushJobQueue, job); err != nil {
+			failures = append(failures, fmt.Errorf("error queueing push notification: %v", err))
+		}
+	}
+
+	if preferences.EmailEnabled && sd.email != nil {
+		if err := sd.jobs.Enqueue(emailJobQueue, job); err != nil {
+			failures = append(failures, fmt.Errorf("error queueing email notification: %v", err))
+		}
+	}
+
+	if preferences.SMSEnabled {
+		log.Printf("SMS notification for %s skipped, no SMS provider configured", notification.UserID)
+	}
+	return errors.Join(failures...)
+}
+
+// processPush runs a push job. It delivers to every registered device and drops tokens the provider
+// rejected, a retry goes to every device again.
+func (sd service) processPush(attempt jobs.Attempt) error {
+	var job sendJob
+	if err := json.Unmarshal(attempt.Payload, &job); err != nil {
+		return fmt.Errorf("invalid push job: %v", err)
+	}
+
+	tokens, err := sd.notificationRepo.GetDeviceTokens(job.UserID)
+	if err != nil {
+		return fmt.Errorf("error loading device tokens: %v", err)
+	}
+
+	data := map[string]string{"category": job.Category}
+	for key, value := range job.Data {
+		data[key] = value
+	}
+	message := PushMessage{Title: job.Title, Body: job.Body, Data: data}
+
+	ctx, cancel := context.WithTimeout(context.Background(), deliveryTimeout)
+	defer cancel()
+
+	var failures []error
+	for _, token := range tokens {
+		provider, ok := sd.pushProviders[token.Platform]
+		if !ok {
+			continue
+		}
+
+		err := provider.Send(ctx, token.Token, message)
+		if errors.Is(err, ErrInvalidDeviceToken) {
+			log.Printf("Removing invalid %s device token of %s", token.Platform, job.UserID)
+			sd.notificationRepo.DeleteDeviceToken(token.Token)
+			continue
+		}
+		if err != nil {
+			failures = append(failures, fmt.Errorf("error sending %s push notification: %v", token.Platform, err))
+		}
+	}
+	return errors.Join(failures...)
+}
+
+// processEmail runs an email job, the address of a user is looked up when the email is sent
+func (sd service) processEmail(attempt jobs.Attempt) error {
+	var job sendJob
+	if err := json.Unmarshal(attempt.Payload, &job); err != nil {
+		return fmt.Errorf("invalid email job: %v", err)
+	}
+	if sd.email == nil {
+		return errors.New("email is not configured")
+	}
+
+	address := job.Address
+	if address == "" {
+		user, err := sd.userRepo.GetUserByID(job.UserID)
+		if err != nil {
+			return fmt.Errorf("error loading user for email notification: %v", err)
+		}
+		address = user.Email
+	}
+	return sd.email.Send(address, job.Title, job.Body)
+}
+
+// RegisterDevice stores a push token for the user
+func (sd service) RegisterDevice(userID string, req RegisterDeviceRequest) error {
+	if req.Token == "" {
+		return utils.Validation("token is required")
+	}
+	if req.Platform != PlatformAndroid && req.Platform != PlatformIOS {
+		return utils.Validation("platform must be android or ios")
+	}
+
+	return sd.notificationRepo.UpsertDeviceToken(repo.DeviceToken{Token: req.Token, UserID: userID, Platform: req.Platform})
+}
+
+// UnregisterDevice removes one of the user's push tokens
+func (sd service) UnregisterDevice(userID, deviceToken string) error {
+	return sd.notificationRepo.DeleteUserDeviceToken(userID, deviceToken)
+}
+
+// GetPreferences returns the user's notification channel preferences
+func (sd service) GetPreferences(userID string) (PreferencesResponse, error) {
+	preferences, err := sd.notificationRepo.GetNotificationPreferences(userID)
+	if err != nil {
+		return PreferencesResponse{}, err
+	}
+	return PreferencesResponse{
+		Email:           preferences.EmailEnabled,
+		Push:            preferences.PushEnabled,
+		SMS:             preferences.SMSEnabled,
+		MutedCategories: preferences.MutedCategories,
+		Delivery:        preferences.DeliveryMode,
+		Locale:          preferences.Locale,
+	}, nil
+}
+
+// UpdatePreferences replaces the user's notification preferences
+func (sd service) UpdatePreferences(userID string, req PreferencesResponse) (PreferencesResponse, error) {
+	if req.Delivery == "" {
+		req.Delivery = DeliveryImmediate
+	}
+	if req.Delivery != DeliveryImmediate && req.Delivery != DeliveryDailyDigest {
+		return PreferencesResponse{}, utils.Validation("delivery must be immediate or daily_digest")
+	}
+	if req.Locale == "" {
+		req.Locale = i18n.DefaultLocale
+	}
+	if !i18n.IsSupported(req.Locale) {
+		return PreferencesResponse{}, utils.Validationf("unsupported locale %q", req.Locale)
+	}
+	if req.MutedCategories == nil {
+		req.MutedCategories = []string{}
+	}
+	for _, category := range req.MutedCategories {
+		if !slices.Contains(knownCategories, category) {
+			return PreferencesResponse{}, utils.Validationf("unknown category %q", category)
+		}
+	}
+
+	err := sd.notificationRepo.UpsertNotificationPreferences(repo.NotificationPreferences{
+		UserID:          userID,
+		EmailEnabled:    req.Email,
+		PushEnabled:     req.Push,
+		SMSEnabled:      req.SMS,
+		MutedCategories: req.MutedCategories,
+		DeliveryMode:    req.Delivery,
+		Locale:          req.Locale,
+	})
+	if err != nil {
+		return PreferencesResponse{}, err
+	}
+	return req, nil
+}
+
+// GetInboxNotifications returns the notifications delivered to the user after the cursor, oldest first
+func (sd service) GetInboxNotifications(userID string, cursor *repo.ChangeCursor, limit int) ([]repo.InboxNotification, error) {
+	return sd.notificationRepo.GetInboxNotificationsAfter(userID, cursor, limit)
+}
+
+// RunDigestScheduler runs every quarter hour and sends the queued digests of the users whose local
+// time just reached the configured hour, until stop is closed. Each run also purges the inbox
+// notifications past their retention.
+func (sd service) RunDigestScheduler(stop <-chan struct{}) {
+	for {
+		next := nextDigestRun(time.Now())
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-timer.C:
+			sd.sendDigests(next)
+			sd.purgeInbox(next)
+		case <-stop:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// sendDigests combines the queued notifications of each user whose digest is due at the given run
+// into a single message
+func (sd service) sendDigests(at time.Time) {
+	userIDs, err := sd.notificationRepo.GetDigestUserIDs(at, config.ConfigDetails.DigestHour, digestRunInterval)
+	if err != nil {
+		log.Printf("Error loading digest recipients: %v", err)
+		return
+	}
+
+	for _, userID := range userIDs {
+		items, err := sd.notificationRepo.TakeDigestItems(userID)
+		if err != nil {
+			log.Printf("Error loading digest for %s: %v", userID, err)
+			continue
+		}
+		if len(items) == 0 {
+			continue
+		}
+
+		preferences, err := sd.notificationRepo.GetNotificationPreferences(userID)
+		if err != nil {
+			log.Printf("Error loading notification preferences for %s: %v", userID, err)
+			continue
+		}
+
+		var body strings.Builder
+		for _, item := range items {
+			fmt.Fprintf(&body, "- %s: %s\n", item.Title, item.Body)
+		}
+
+		err = sd.send(Notification{
+			UserID:   userID,
+			Category: "digest",
+			Title:    i18n.T(preferences.Locale, "Your daily summary (%d updates)", len(items)),
+			Body:     body.String(),
+		}, preferences)
+		if err != nil {
+			log.Printf("Error sending digest to %s: %v", userID, err)
+		}
+	}
+	log.Printf("Sent notification digests to %d users", len(userIDs))
+}
+
+// purgeInbox removes the inbox notifications older than inboxRetention
+func (sd service) purgeInbox(at time.Time) {
+	deleted, err := sd.notificationRepo.DeleteInboxNotificationsBefore(at.Add(-inboxRetention))
+	if err != nil {
+		log.Printf("Error purging notification inbox: %v", err)
+		return
+	}
+	if deleted > 0 {
+		log.Printf("Purged %d expired inbox notifications", deleted)
+	}
+}
+
+// nextDigestRun returns the next quarter hour after now. Every time zone is offset from UTC by a
+// multiple of 15 minutes, so each user's digest hour starts at exactly one run a day.
+func nextDigestRun(now time.Time) time.Time {
+	return now.Truncate(digestRunInterval).Add(digestRunInterval)
+}
+
+// decodeNotificationEvent reads a notification from its outbox payload. Numbers among the body
+// arguments come back as integers where they fit so that %d verbs still format them.
+func decodeNotificationEvent(payload json.RawMessage) (Notification, error) {
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+
+	var event notificationEvent
+	if err := decoder.Decode(&event); err != nil {
+		return Notification{}, fmt.Errorf("invalid notification event: %v", err)
+	}
+	for i, arg := range event.BodyArgs {
+		number, ok := arg.(json.Number)
+		if !ok {
+			continue
+		}
+		if value, err := number.Int64(); err == nil {
+			event.BodyArgs[i] = value
+		} else if value, err := number.Float64(); err == nil {
+			event.BodyArgs[i] = value
+		}
+	}
+	return Notification(event), nil
+}
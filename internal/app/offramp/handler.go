@@ -0,0 +1,177 @@
+package offramp
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/respond"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+	"github.com/gorilla/mux"
+)
+
+// WithdrawalRequest asks for amount_wei to be paid out to a bank account. bank_account_token is the
+// token the payout provider issued for the account, the account number itself is never sent. The
+// confirmation fields are those of a transfer.
+type WithdrawalRequest struct {
+	AmountWei        string `json:"amount_wei"`
+	FiatCurrency     string `json:"fiat_currency"`
+	BankAccountToken string `json:"bank_account_token"`
+	BankAccountLast4 string `json:"bank_account_last4"`
+	Password         string `json:"password"`
+	TOTPCode         string `json:"totp_code"`
+	DeviceToken      string `json:"device_token"`
+	PIN              string `json:"pin"`
+}
+
+// CompleteRequest records the bank payout of a withdrawal
+type CompleteRequest struct {
+	PayoutReference string `json:"payout_reference"`
+}
+
+// RejectRequest carries why ops rejected a withdrawal, the user sees it
+type RejectRequest struct {
+	Reason string `json:"reason"`
+}
+
+// WithdrawalResponse represents a fiat withdrawal, tx_hash is the transfer to the settlement address
+// and reason why the withdrawal was rejected or failed. bank_account_token is only shown to admins.
+type WithdrawalResponse struct {
+	WithdrawalID     string    `json:"withdrawal_id"`
+	UserID           string    `json:"user_id"`
+	WalletID         string    `json:"wallet_id"`
+	AmountWei        string    `json:"amount_wei"`
+	FiatCurrency     string    `json:"fiat_currency"`
+	BankAccountToken string    `json:"bank_account_token,omitempty"`
+	BankAccountLast4 string    `json:"bank_account_last4"`
+	Status           string    `json:"status"`
+	TxHash           string    `json:"tx_hash,omitempty"`
+	PayoutReference  string    `json:"payout_reference,omitempty"`
+	Reason           string    `json:"reason,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+type Handler struct {
+	service Service
+}
+
+// Constructor function
+func NewHandler(service Service) Handler {
+	return Handler{service: service}
+}
+
+// RequestWithdrawalHandler requests a withdrawal of the caller's funds to a bank account
+func (hd Handler) RequestWithdrawalHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req WithdrawalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	withdrawal, err := hd.service.RequestWithdrawal(userInfo, req)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, withdrawal)
+}
+
+// ListWithdrawalsHandler lists the caller's latest fiat withdrawals
+func (hd Handler) ListWithdrawalsHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	withdrawals, err := hd.service.ListWithdrawals(userInfo.UserID)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.List(w, r, withdrawals, respond.Pagination{Count: len(withdrawals)})
+}
+
+// ListQueueHandler lists the latest withdrawals of all users, filtered by the status query
+// parameter, needs fiat_withdrawal.manage
+func (hd Handler) ListQueueHandler(w http.ResponseWriter, r *http.Request) {
+	withdrawals, err := hd.service.ListQueue(r.URL.Query().Get("status"))
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.List(w, r, withdrawals, respond.Pagination{Count: len(withdrawals)})
+}
+
+// ApproveWithdrawalHandler approves a pending withdrawal and sends it to the settlement address, needs fiat_withdrawal.manage
+func (hd Handler) ApproveWithdrawalHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	withdrawal, err := hd.service.ApproveWithdrawal(userInfo.UserID, mux.Vars(r)["withdrawalID"])
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, withdrawal)
+}
+
+// CompleteWithdrawalHandler records the bank payout of an approved withdrawal, needs fiat_withdrawal.manage
+func (hd Handler) CompleteWithdrawalHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req CompleteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	withdrawal, err := hd.service.CompleteWithdrawal(userInfo.UserID, mux.Vars(r)["withdrawalID"], req)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, withdrawal)
+}
+
+// RejectWithdrawalHandler rejects a pending withdrawal, needs fiat_withdrawal.manage
+func (hd Handler) RejectWithdrawalHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req RejectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	withdrawal, err := hd.service.RejectWithdrawal(userInfo.UserID, mux.Vars(r)["withdrawalID"], req)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, withdrawal)
+}
@@ -0,0 +1,418 @@
+package offramp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/confirmation"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/ethereum"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/notification"
+	"github.com/CodeWithKrushnal/ChainBank/internal/config"
+	"github.com/CodeWithKrushnal/ChainBank/internal/domain"
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+// Webhook event types sent to the merchant webhook of a user who owns a merchant
+const (
+	EventWithdrawalCompleted = "fiat_withdrawal.completed"
+	EventWithdrawalRejected  = "fiat_withdrawal.rejected"
+	EventWithdrawalFailed    = "fiat_withdrawal.failed"
+)
+
+// Withdrawal statuses, kept in sync with the fiat_withdrawals status check. A pending withdrawal waits
+// for ops, approval sends it to the settlement address and leaves it processing until ops complete
+// the bank payout. The amount is held while the withdrawal is pending or sending.
+const (
+	statusPending    = "pending"
+	statusSending    = "sending"
+	statusProcessing = "processing"
+	statusCompleted  = "completed"
+	statusRejected   = "rejected"
+	statusFailed     = "failed"
+)
+
+var withdrawalStatuses = []string{statusPending, statusSending, statusProcessing, statusCompleted, statusRejected, statusFailed}
+
+const (
+	withdrawalListLimit      = 100
+	maxPayoutReferenceLength = 128
+	maxReasonLength          = 500
+)
+
+var (
+	fiatCurrencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+	// Tokens of the payout provider, an all digit value is an account number sent by mistake
+	bankTokenPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{8,128}$`)
+	digitsPattern    = regexp.MustCompile(`^\d+$`)
+	last4Pattern     = regexp.MustCompile(`^\d{4}$`)
+)
+
+type service struct {
+	withdrawalRepo  repo.FiatWithdrawalStorer
+	userRepo        repo.UserStorer
+	walletRepo      repo.WalletStorer
+	transactionRepo repo.TransactionStorer
+	merchantRepo    repo.MerchantStorer
+	lockRepo        repo.LockStorer
+	ethRepo         ethereum.EthRepo
+	confirmations   confirmation.Service
+	notifications   notification.Service
+}
+
+type Service interface {
+	RequestWithdrawal(userInfo utils.User, req WithdrawalRequest) (WithdrawalResponse, error)
+	ListWithdrawals(userID string) ([]WithdrawalResponse, error)
+	ListQueue(status string) ([]WithdrawalResponse, error)
+	ApproveWithdrawal(adminID, withdrawalID string) (WithdrawalResponse, error)
+	CompleteWithdrawal(adminID, withdrawalID string, req CompleteRequest) (WithdrawalResponse, error)
+	RejectWithdrawal(adminID, withdrawalID string, req RejectRequest) (WithdrawalResponse, error)
+}
+
+// Constructor function
+func NewService(withdrawalRepo repo.FiatWithdrawalStorer, userRepo repo.UserStorer, walletRepo repo.WalletStorer, transactionRepo repo.TransactionStorer,
+	merchantRepo repo.MerchantStorer, lockRepo repo.LockStorer, ethRepo ethereum.EthRepo, confirmationService confirmation.Service, notificationService notification.Service) Service {
+	return service{
+		withdrawalRepo:  withdrawalRepo,
+		userRepo:        userRepo,
+		walletRepo:      walletRepo,
+		transactionRepo: transactionRepo,
+		merchantRepo:    merchantRepo,
+		lockRepo:        lockRepo,
+		ethRepo:         ethRepo,
+		confirmations:   confirmationService,
+		notifications:   notificationService,
+	}
+}
+
+// RequestWithdrawal records a withdrawal of amount_wei to the tokenized bank account for ops to
+// review. It is confirmed like a transfer and holds the amount in the wallet until it is sent or
+// rejected.
+func (sd service) RequestWithdrawal(userInfo utils.User, req WithdrawalRequest) (WithdrawalResponse, error) {
+	if config.ConfigDetails.FiatOfframpSettlementAddress == "" {
+		return WithdrawalResponse{}, utils.NotFound("fiat off-ramp is not configured", nil)
+	}
+
+	amount, ok := new(big.Int).SetString(req.AmountWei, 10)
+	if !ok || amount.Sign() <= 0 {
+		return WithdrawalResponse{}, utils.Validation("amount_wei must be a positive integer")
+	}
+	if !fiatCurrencyPattern.MatchString(req.FiatCurrency) {
+		return WithdrawalResponse{}, utils.Validation("fiat_currency must be a three letter ISO 4217 code")
+	}
+	if !bankTokenPattern.MatchString(req.BankAccountToken) || digitsPattern.MatchString(req.BankAccountToken) {
+		return WithdrawalResponse{}, utils.Validation("bank_account_token must be the token issued by the payout provider, not the account number")
+	}
+	if !last4Pattern.MatchString(req.BankAccountLast4) {
+		return WithdrawalResponse{}, utils.Validation("bank_account_last4 must be the last four digits of the bank account")
+	}
+
+	user, err := sd.userRepo.GetUserByID(userInfo.UserID)
+	if err != nil {
+		return WithdrawalResponse{}, err
+	}
+	if user.AccountStatus != domain.AccountActive {
+		return WithdrawalResponse{}, utils.Conflict("the account is closed")
+	}
+	walletID, err := sd.walletRepo.GetWalletID("", user.ID)
+	if err != nil {
+		return WithdrawalResponse{}, utils.NotFound("wallet not found", err)
+	}
+
+	err = sd.confirmations.VerifyTransfer(user.ID, amount, confirmation.Proof{
+		Password:    req.Password,
+		TOTPCode:    req.TOTPCode,
+		DeviceToken: req.DeviceToken,
+		PIN:         req.PIN,
+	})
+	if err != nil {
+		return WithdrawalResponse{}, err
+	}
+
+	// The withdrawal is checked and held under the transfer lock of the user, so a concurrent transfer,
+	// sweep or withdrawal cannot spend the balance it was checked against
+	lock, err := sd.lockRepo.Lock(repo.TransferLockName(user.ID))
+	if err != nil {
+		return WithdrawalResponse{}, err
+	}
+	defer lock.Release()
+
+	// The wallet has to cover the withdrawal and its gas on top of its transfers waiting to be mined
+	// and the withdrawals already held
+	balance, err := sd.ethRepo.GetBalance(walletID)
+	if err != nil {
+		return WithdrawalResponse{}, utils.Upstream("failed to fetch balance", err)
+	}
+	pending, err := sd.transactionRepo.GetPendingTotals(walletID, ethereum.TransferGasLimit)
+	if err != nil {
+		return WithdrawalResponse{}, err
+	}
+	held, err := sd.withdrawalRepo.GetHeldWithdrawalTotal(walletID)
+	if err != nil {
+		return WithdrawalResponse{}, err
+	}
+	available := new(big.Int).Sub(balance, pending.OutgoingTotal)
+	available.Sub(available, held)
+	if available.Cmp(new(big.Int).Add(amount, gasCost())) < 0 {
+		return WithdrawalResponse{}, utils.Conflict("the wallet cannot cover the withdrawal and its gas")
+	}
+
+	withdrawal, err := sd.withdrawalRepo.CreateFiatWithdrawal(repo.FiatWithdrawal{
+		UserID:           user.ID,
+		WalletID:         walletID,
+		Amount:           amount.String(),
+		FiatCurrency:     req.FiatCurrency,
+		BankAccountToken: req.BankAccountToken,
+		BankAccountLast4: req.BankAccountLast4,
+	})
+	if err != nil {
+		return WithdrawalResponse{}, err
+	}
+
+	log.Printf("User %s requested fiat withdrawal %s of %s wei", user.ID, withdrawal.WithdrawalID, withdrawal.Amount)
+	return newWithdrawalResponse(withdrawal, false), nil
+}
+
+// ListWithdrawals returns the user's latest fiat withdrawals
+func (sd service) ListWithdrawals(userID string) ([]WithdrawalResponse, error) {
+	withdrawals, err := sd.withdrawalRepo.GetFiatWithdrawals(userID, "", withdrawalListLimit)
+	if err != nil {
+		return nil, err
+	}
+	return newWithdrawalResponses(withdrawals, false), nil
+}
+
+// ListQueue returns the latest withdrawals of all users for ops, optionally only those with the given
+// status. Unlike the users' own list it carries the bank account tokens ops pay out to.
+func (sd service) ListQueue(status string) ([]WithdrawalResponse, error) {
+	if status != "" && !slices.Contains(withdrawalStatuses, status) {
+		return nil, utils.Validation("unknown withdrawal status")
+	}
+
+	withdrawals, err := sd.withdrawalRepo.GetFiatWithdrawals("", status, withdrawalListLimit)
+	if err != nil {
+		return nil, err
+	}
+	return newWithdrawalResponses(withdrawals, true), nil
+}
+
+// ApproveWithdrawal sends the held amount from the user's wallet to the settlement address, the
+// withdrawal then stays processing until ops complete the bank payout. A wallet that no longer
+// covers the amount leaves the withdrawal pending. A failed transfer is not retried since it may
+// have gone out after all, the withdrawal is marked failed and its hold released.
+func (sd service) ApproveWithdrawal(adminID, withdrawalID string) (WithdrawalResponse, error) {
+	withdrawal, err := sd.withdrawalInStatus(withdrawalID, statusPending)
+	if err != nil {
+		return WithdrawalResponse{}, err
+	}
+
+	amount, _ := new(big.Int).SetString(withdrawal.Amount, 10)
+	balance, err := sd.ethRepo.GetBalance(withdrawal.WalletID)
+	if err != nil {
+		return WithdrawalResponse{}, utils.Upstream("failed to fetch balance", err)
+	}
+	if balance.Cmp(new(big.Int).Add(amount, gasCost())) < 0 {
+		return WithdrawalResponse{}, utils.Conflict("the wallet cannot cover the withdrawal and its gas")
+	}
+
+	// Claims the withdrawal so that concurrent approvals send it once
+	withdrawal, claimed, err := sd.withdrawalRepo.UpdateFiatWithdrawalStatus(withdrawalID, statusPending, repo.FiatWithdrawal{Status: statusSending, DecidedBy: adminID})
+	if err != nil {
+		return WithdrawalResponse{}, err
+	}
+	if !claimed {
+		return WithdrawalResponse{}, statusConflict(statusPending)
+	}
+
+	txHash, err := sd.send(withdrawal, amount)
+	if err != nil {
+		log.Printf("Fiat withdrawal %s failed: %v", withdrawalID, err)
+		failed, _, updateErr := sd.withdrawalRepo.UpdateFiatWithdrawalStatus(withdrawalID, statusSending, repo.FiatWithdrawal{Status: statusFailed, Reason: err.Error()})
+		if updateErr != nil {
+			log.Printf("Error marking fiat withdrawal %s failed: %v", withdrawalID, updateErr)
+		} else {
+			sd.announce(failed, EventWithdrawalFailed)
+		}
+		return WithdrawalResponse{}, err
+	}
+
+	processing, _, err := sd.withdrawalRepo.UpdateFiatWithdrawalStatus(withdrawalID, statusSending, repo.FiatWithdrawal{Status: statusProcessing, TxHash: txHash})
+	if err != nil {
+		// The transfer is already broadcast, only its record is behind
+		log.Printf("Error marking fiat withdrawal %s sent in %s: %v", withdrawalID, txHash, err)
+		withdrawal.Status, withdrawal.TxHash = statusProcessing, txHash
+		processing = withdrawal
+	}
+
+	log.Printf("Admin %s approved fiat withdrawal %s, sent in %s", adminID, withdrawalID, txHash)
+	return newWithdrawalResponse(processing, true), nil
+}
+
+// CompleteWithdrawal records the bank payout of a processing withdrawal and tells the user
+func (sd service) CompleteWithdrawal(adminID, withdrawalID string, req CompleteRequest) (WithdrawalResponse, error) {
+	reference := strings.TrimSpace(req.PayoutReference)
+	if reference == "" || len(reference) > maxPayoutReferenceLength {
+		return WithdrawalResponse{}, utils.Validationf("payout_reference is required and may have at most %d characters", maxPayoutReferenceLength)
+	}
+	if _, err := sd.withdrawalInStatus(withdrawalID, statusProcessing); err != nil {
+		return WithdrawalResponse{}, err
+	}
+
+	completed, ok, err := sd.withdrawalRepo.UpdateFiatWithdrawalStatus(withdrawalID, statusProcessing, repo.FiatWithdrawal{Status: statusCompleted, PayoutReference: reference})
+	if err != nil {
+		return WithdrawalResponse{}, err
+	}
+	if !ok {
+		return WithdrawalResponse{}, statusConflict(statusProcessing)
+	}
+
+	log.Printf("Admin %s completed fiat withdrawal %s, payout %s", adminID, withdrawalID, reference)
+	sd.announce(completed, EventWithdrawalCompleted)
+	return newWithdrawalResponse(completed, true), nil
+}
+
+// RejectWithdrawal closes a pending withdrawal without moving funds, releasing its hold
+func (sd service) RejectWithdrawal(adminID, withdrawalID string, req RejectRequest) (WithdrawalResponse, error) {
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" || len(reason) > maxReasonLength {
+		return WithdrawalResponse{}, utils.Validationf("reason is required and may have at most %d characters", maxReasonLength)
+	}
+	if _, err := sd.withdrawalInStatus(withdrawalID, statusPending); err != nil {
+		return WithdrawalResponse{}, err
+	}
+
+	rejected, ok, err := sd.withdrawalRepo.UpdateFiatWithdrawalStatus(withdrawalID, statusPending, repo.FiatWithdrawal{Status: statusRejected, DecidedBy: adminID, Reason: reason})
+	if err != nil {
+		return WithdrawalResponse{}, err
+	}
+	if !ok {
+		return WithdrawalResponse{}, statusConflict(statusPending)
+	}
+
+	log.Printf("Admin %s rejected fiat withdrawal %s", adminID, withdrawalID)
+	sd.announce(rejected, EventWithdrawalRejected)
+	return newWithdrawalResponse(rejected, true), nil
+}
+
+// withdrawalInStatus returns a withdrawal that is still in the given status
+func (sd service) withdrawalInStatus(withdrawalID, status string) (repo.FiatWithdrawal, error) {
+	withdrawal, err := sd.withdrawalRepo.GetFiatWithdrawal(withdrawalID)
+	if err != nil {
+		return withdrawal, err
+	}
+	if withdrawal.Status != status {
+		return withdrawal, statusConflict(status)
+	}
+	return withdrawal, nil
+}
+
+// send signs the transfer to the settlement address with the user's key and broadcasts it
+func (sd service) send(withdrawal repo.FiatWithdrawal, amount *big.Int) (string, error) {
+	privateKeyHex, err := sd.walletRepo.RetrievePrivateKey(withdrawal.UserID, "")
+	if err != nil {
+		return "", fmt.Errorf("error retrieving private key: %w", err)
+	}
+
+	settlement := config.ConfigDetails.FiatOfframpSettlementAddress
+	signedTx, err := sd.ethRepo.TransferFunds(privateKeyHex, withdrawal.WalletID, settlement, amount, ethereum.DefaultGasPrice, ethereum.TransferGasLimit, ethereum.ChainID)
+	if err != nil {
+		return "", utils.Upstream("transaction failed", err)
+	}
+	if err := sd.ethRepo.SendTransaction(signedTx); err != nil {
+		return "", utils.Upstream("failed to broadcast transaction", err)
+	}
+	return signedTx.Hash().Hex(), nil
+}
+
+// announce tells the user that a withdrawal completed, was rejected or failed, and sends the event
+// to the user's merchant webhook if the user owns a merchant with one
+func (sd service) announce(withdrawal repo.FiatWithdrawal, eventType string) {
+	amount, _ := new(big.Int).SetString(withdrawal.Amount, 10)
+	n := notification.Notification{
+		UserID:   withdrawal.UserID,
+		Category: notification.CategoryTransfer,
+		BodyArgs: []any{weiToETH(amount), withdrawal.BankAccountLast4},
+		Data:     map[string]string{"withdrawal_id": withdrawal.WithdrawalID, "amount": withdrawal.Amount, "status": withdrawal.Status},
+	}
+	switch eventType {
+	case EventWithdrawalCompleted:
+		n.Title = "Withdrawal completed"
+		n.Body = "Your withdrawal of %s ETH was paid out to the bank account ending in %s."
+		n.Data["payout_reference"] = withdrawal.PayoutReference
+	case EventWithdrawalRejected:
+		n.Title = "Withdrawal rejected"
+		n.Body = "Your withdrawal of %s ETH to the bank account ending in %s was rejected and the held funds were released."
+		n.Data["reason"] = withdrawal.Reason
+	case EventWithdrawalFailed:
+		n.Title = "Withdrawal failed"
+		n.Body = "Your withdrawal of %s ETH to the bank account ending in %s could not be sent and the held funds were released."
+	}
+	sd.notifications.Notify(n)
+
+	merchant, err := sd.merchantRepo.GetMerchantByOwner(withdrawal.UserID)
+	if err != nil {
+		if !errors.Is(err, utils.ErrNotFound) {
+			log.Printf("Error loading merchant of %s for withdrawal event: %v", withdrawal.UserID, err)
+		}
+		return
+	}
+	if merchant.WebhookURL == "" {
+		return
+	}
+	payload, err := json.Marshal(newWithdrawalResponse(withdrawal, false))
+	if err != nil {
+		log.Printf("Error encoding withdrawal event: %v", err)
+		return
+	}
+	if err := sd.merchantRepo.EnqueueWebhookEvent(repo.WebhookEvent{MerchantID: merchant.MerchantID, EventType: eventType, Payload: payload}); err != nil {
+		log.Printf("Error queueing %s event of withdrawal %s: %v", eventType, withdrawal.WithdrawalID, err)
+	}
+}
+
+func statusConflict(status string) error {
+	return utils.Conflictf("withdrawal is no longer %s", status)
+}
+
+func gasCost() *big.Int {
+	return new(big.Int).Mul(ethereum.DefaultGasPrice, new(big.Int).SetUint64(ethereum.TransferGasLimit))
+}
+
+func weiToETH(wei *big.Int) string {
+	return new(big.Float).Quo(new(big.Float).SetInt(wei), big.NewFloat(1e18)).Text('f', -1)
+}
+
+func newWithdrawalResponses(withdrawals []repo.FiatWithdrawal, withToken bool) []WithdrawalResponse {
+	response := make([]WithdrawalResponse, len(withdrawals))
+	for i, withdrawal := range withdrawals {
+		response[i] = newWithdrawalResponse(withdrawal, withToken)
+	}
+	return response
+}
+
+// newWithdrawalResponse converts a withdrawal, the bank account token is only included for ops
+func newWithdrawalResponse(withdrawal repo.FiatWithdrawal, withToken bool) WithdrawalResponse {
+	response := WithdrawalResponse{
+		WithdrawalID:     withdrawal.WithdrawalID,
+		UserID:           withdrawal.UserID,
+		WalletID:         withdrawal.WalletID,
+		AmountWei:        withdrawal.Amount,
+		FiatCurrency:     withdrawal.FiatCurrency,
+		BankAccountLast4: withdrawal.BankAccountLast4,
+		Status:           withdrawal.Status,
+		TxHash:           withdrawal.TxHash,
+		PayoutReference:  withdrawal.PayoutReference,
+		Reason:           withdrawal.Reason,
+		CreatedAt:        withdrawal.CreatedAt,
+		UpdatedAt:        withdrawal.UpdatedAt,
+	}
+	if withToken {
+		response.BankAccountToken = withdrawal.BankAccountToken
+	}
+	return response
+}
@@ -0,0 +1,89 @@
+package onramp
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/respond"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+// Webhook bodies larger than this are rejected unread
+const maxWebhookBodySize = 64 << 10
+
+// WebhookEvent is what the on-ramp provider posts, Data depends on the event type
+type WebhookEvent struct {
+	EventID string          `json:"id"`
+	Type    string          `json:"type"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// DepositData is the data of a deposit.confirmed event. user_id is the ChainBank user the deposit
+// was started for, crypto_amount_wei the ETH the provider bought with it, left out or "0" when the
+// deposit only holds fiat.
+type DepositData struct {
+	DepositID       string `json:"deposit_id"`
+	UserID          string `json:"user_id"`
+	FiatAmount      string `json:"fiat_amount"`
+	FiatCurrency    string `json:"fiat_currency"`
+	CryptoAmountWei string `json:"crypto_amount_wei"`
+}
+
+// DepositResponse represents a fiat deposit, tx_hash is the transfer crediting it and error why
+// that transfer failed
+type DepositResponse struct {
+	DepositID         string    `json:"deposit_id"`
+	ProviderDepositID string    `json:"provider_deposit_id"`
+	UserID            string    `json:"user_id"`
+	WalletID          string    `json:"wallet_id"`
+	FiatAmount        string    `json:"fiat_amount"`
+	FiatCurrency      string    `json:"fiat_currency"`
+	CryptoAmountWei   string    `json:"crypto_amount_wei"`
+	Status            string    `json:"status"`
+	TxHash            string    `json:"tx_hash,omitempty"`
+	Error             string    `json:"error,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+type Handler struct {
+	service Service
+}
+
+// Constructor function
+func NewHandler(service Service) Handler {
+	return Handler{service: service}
+}
+
+// WebhookHandler receives the events of the fiat on-ramp provider, authenticated by the signature
+// in SignatureHeader instead of a token. Any 2xx response tells the provider to stop retrying.
+func (hd Handler) WebhookHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Incoming Request On Fiat On-Ramp Webhook Handler")
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBodySize))
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := hd.service.ReceiveEvent(body, r.Header.Get(SignatureHeader)); err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListDepositsHandler lists the latest fiat deposits, filtered by "status" when given, needs fiat_deposit.read
+func (hd Handler) ListDepositsHandler(w http.ResponseWriter, r *http.Request) {
+	deposits, err := hd.service.ListDeposits(r.URL.Query().Get("status"))
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.List(w, r, deposits, respond.Pagination{Count: len(deposits)})
+}
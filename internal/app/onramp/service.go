@@ -0,0 +1,308 @@
+package onramp
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/ethereum"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/jobs"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/notification"
+	"github.com/CodeWithKrushnal/ChainBank/internal/config"
+	"github.com/CodeWithKrushnal/ChainBank/internal/domain"
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+const (
+	// SignatureHeader carries t=<unix time>,v1=<hex HMAC-SHA256 of "<t>.<body>"> keyed with the
+	// webhook secret, the scheme of the webhooks sent to merchants
+	SignatureHeader = "X-Onramp-Signature"
+	// Signed events older or further ahead than this are rejected as replays
+	signatureTolerance = 5 * time.Minute
+
+	// EventDepositConfirmed is the event type of a deposit the provider received, other events are
+	// acknowledged and ignored
+	EventDepositConfirmed = "deposit.confirmed"
+
+	// Background job queue crediting deposits that bought ETH, its jobs carry the deposit ID
+	creditJobQueue    = "onramp.credit"
+	maxCreditAttempts = 5
+
+	depositListLimit = 100
+)
+
+// Deposit statuses, kept in sync with the fiat_deposits status check. A fiat only deposit stays
+// recorded, a deposit that bought ETH goes from pending through crediting to credited or failed.
+const (
+	statusRecorded  = "recorded"
+	statusPending   = "pending"
+	statusCrediting = "crediting"
+	statusCredited  = "credited"
+	statusFailed    = "failed"
+)
+
+var depositStatuses = []string{statusRecorded, statusPending, statusCrediting, statusCredited, statusFailed}
+
+var (
+	fiatAmountPattern   = regexp.MustCompile(`^\d{1,18}(\.\d{1,6})?$`)
+	fiatCurrencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+)
+
+// creditJob is the payload of a credit job
+type creditJob struct {
+	DepositID string `json:"deposit_id"`
+}
+
+type service struct {
+	depositRepo   repo.FiatDepositStorer
+	userRepo      repo.UserStorer
+	walletRepo    repo.WalletStorer
+	ethRepo       ethereum.EthRepo
+	notifications notification.Service
+	jobs          jobs.Service
+}
+
+type Service interface {
+	ReceiveEvent(body []byte, signature string) error
+	ListDeposits(status string) ([]DepositResponse, error)
+}
+
+// Constructor function, registers the job queue crediting deposits
+func NewService(depositRepo repo.FiatDepositStorer, userRepo repo.UserStorer, walletRepo repo.WalletStorer, ethRepo ethereum.EthRepo,
+	notificationService notification.Service, jobService jobs.Service) Service {
+	sd := service{
+		depositRepo:   depositRepo,
+		userRepo:      userRepo,
+		walletRepo:    walletRepo,
+		ethRepo:       ethRepo,
+		notifications: notificationService,
+		jobs:          jobService,
+	}
+	jobService.Register(creditJobQueue, maxCreditAttempts, sd.processCredit)
+	return sd
+}
+
+// ReceiveEvent checks the signature of an event posted by the on-ramp provider and records the
+// deposit it confirms. A deposit delivered again is not recorded twice.
+func (sd service) ReceiveEvent(body []byte, signature string) error {
+	secret := config.ConfigDetails.FiatOnrampWebhookSecret
+	if secret == "" {
+		return utils.NotFound("fiat on-ramp is not configured", nil)
+	}
+	if !validSignature(secret, signature, body, time.Now()) {
+		return utils.Unauthorized("invalid webhook signature")
+	}
+
+	var event WebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return utils.Validation("invalid webhook body")
+	}
+	if event.Type != EventDepositConfirmed {
+		log.Printf("Ignoring fiat on-ramp event %s of type %s", event.EventID, event.Type)
+		return nil
+	}
+
+	var data DepositData
+	if err := json.Unmarshal(event.Data, &data); err != nil {
+		return utils.Validation("invalid webhook body")
+	}
+	return sd.recordDeposit(data)
+}
+
+// recordDeposit records a confirmed deposit, notifies the user of a fiat only deposit and queues the
+// credit of a deposit that bought ETH
+func (sd service) recordDeposit(data DepositData) error {
+	if data.DepositID == "" || len(data.DepositID) > 128 {
+		return utils.Validation("deposit_id is required and may have at most 128 characters")
+	}
+	if !fiatAmountPattern.MatchString(data.FiatAmount) || strings.Trim(data.FiatAmount, "0.") == "" {
+		return utils.Validation("fiat_amount must be a positive decimal with at most 6 decimal places")
+	}
+	if !fiatCurrencyPattern.MatchString(data.FiatCurrency) {
+		return utils.Validation("fiat_currency must be a three letter ISO 4217 code")
+	}
+	cryptoAmount := big.NewInt(0)
+	if data.CryptoAmountWei != "" {
+		var ok bool
+		if cryptoAmount, ok = new(big.Int).SetString(data.CryptoAmountWei, 10); !ok || cryptoAmount.Sign() < 0 {
+			return utils.Validation("crypto_amount_wei must be a non-negative integer")
+		}
+	}
+
+	user, err := sd.userRepo.GetUserByID(data.UserID)
+	if err != nil {
+		return err
+	}
+	if user.AccountStatus != domain.AccountActive {
+		return utils.Conflict("the account of the deposit is closed")
+	}
+	walletID, err := sd.walletRepo.GetWalletID("", user.ID)
+	if err != nil {
+		return err
+	}
+
+	status := statusRecorded
+	if cryptoAmount.Sign() > 0 {
+		status = statusPending
+	}
+	deposit, created, err := sd.depositRepo.RecordFiatDeposit(repo.FiatDeposit{
+		ProviderDepositID: data.DepositID,
+		UserID:            user.ID,
+		WalletID:          walletID,
+		FiatAmount:        data.FiatAmount,
+		FiatCurrency:      data.FiatCurrency,
+		CryptoAmount:      cryptoAmount.String(),
+		Status:            status,
+	})
+	if err != nil {
+		return err
+	}
+	if created {
+		log.Printf("Recorded fiat deposit %s of %s %s for %s", deposit.DepositID, deposit.FiatAmount, deposit.FiatCurrency, deposit.UserID)
+	}
+
+	// Queued again when the provider repeats a deposit whose credit could not be queued before,
+	// the job claims the deposit so that it is credited once
+	if deposit.Status == statusPending {
+		if err := sd.jobs.Enqueue(creditJobQueue, creditJob{DepositID: deposit.DepositID}); err != nil {
+			return fmt.Errorf("error queueing credit of fiat deposit: %v", err)
+		}
+		return nil
+	}
+
+	if created && deposit.Status == statusRecorded {
+		sd.notifications.Notify(notification.Notification{
+			UserID:   deposit.UserID,
+			Category: notification.CategoryTransfer,
+			Title:    "Deposit received",
+			Body:     "Your deposit of %s %s was received.",
+			BodyArgs: []any{deposit.FiatAmount, deposit.FiatCurrency},
+			Data:     map[string]string{"deposit_id": deposit.DepositID},
+		})
+	}
+	return nil
+}
+
+// processCredit runs a credit job, sending the ETH a deposit bought from the funding account to the
+// user's wallet. A failed transfer is not retried since it may have gone out after all, the deposit
+// is marked failed for an admin to settle by hand.
+func (sd service) processCredit(attempt jobs.Attempt) error {
+	var job creditJob
+	if err := json.Unmarshal(attempt.Payload, &job); err != nil {
+		return fmt.Errorf("invalid credit job: %v", err)
+	}
+	deposit, err := sd.depositRepo.GetFiatDeposit(job.DepositID)
+	if err != nil {
+		return err
+	}
+
+	// Claims the deposit so that repeated jobs credit it once
+	claimed, err := sd.depositRepo.UpdateFiatDepositStatus(deposit.DepositID, statusPending, statusCrediting, "", "")
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return nil
+	}
+
+	amount, _ := new(big.Int).SetString(deposit.CryptoAmount, 10)
+	txHash, err := sd.ethRepo.PreloadTokens(deposit.WalletID, amount)
+	if err != nil {
+		log.Printf("Credit of fiat deposit %s failed: %v", deposit.DepositID, err)
+		if _, updateErr := sd.depositRepo.UpdateFiatDepositStatus(deposit.DepositID, statusCrediting, statusFailed, "", err.Error()); updateErr != nil {
+			log.Printf("Error marking fiat deposit %s failed: %v", deposit.DepositID, updateErr)
+		}
+		return nil
+	}
+
+	if _, err := sd.depositRepo.UpdateFiatDepositStatus(deposit.DepositID, statusCrediting, statusCredited, txHash, ""); err != nil {
+		log.Printf("Error marking fiat deposit %s credited: %v", deposit.DepositID, err)
+	}
+	log.Printf("Credited fiat deposit %s with %s wei, transaction %s", deposit.DepositID, deposit.CryptoAmount, txHash)
+
+	sd.notifications.Notify(notification.Notification{
+		UserID:   deposit.UserID,
+		Category: notification.CategoryTransfer,
+		Title:    "Deposit credited",
+		Body:     "Your deposit of %s %s was credited to your wallet as %s ETH.",
+		BodyArgs: []any{deposit.FiatAmount, deposit.FiatCurrency, weiToETH(amount)},
+		Data:     map[string]string{"deposit_id": deposit.DepositID, "transaction_hash": txHash, "amount": deposit.CryptoAmount},
+	})
+	return nil
+}
+
+// ListDeposits returns the latest fiat deposits, optionally only those with the given status
+func (sd service) ListDeposits(status string) ([]DepositResponse, error) {
+	if status != "" && !slices.Contains(depositStatuses, status) {
+		return nil, utils.Validation("unknown deposit status")
+	}
+
+	deposits, err := sd.depositRepo.GetFiatDeposits(status, depositListLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]DepositResponse, len(deposits))
+	for i, deposit := range deposits {
+		response[i] = newDepositResponse(deposit)
+	}
+	return response, nil
+}
+
+// validSignature checks a SignatureHeader value against body, now bounds the age of its timestamp
+func validSignature(secret, header string, body []byte, now time.Time) bool {
+	var timestamp, signature string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signature = value
+		}
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || signature == "" {
+		return false
+	}
+	if age := now.Sub(time.Unix(unix, 0)); age > signatureTolerance || age < -signatureTolerance {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hmac.Equal([]byte(signature), []byte(hex.EncodeToString(mac.Sum(nil))))
+}
+
+func weiToETH(wei *big.Int) string {
+	return new(big.Float).Quo(new(big.Float).SetInt(wei), big.NewFloat(1e18)).Text('f', -1)
+}
+
+func newDepositResponse(deposit repo.FiatDeposit) DepositResponse {
+	return DepositResponse{
+		DepositID:         deposit.DepositID,
+		ProviderDepositID: deposit.ProviderDepositID,
+		UserID:            deposit.UserID,
+		WalletID:          deposit.WalletID,
+		FiatAmount:        deposit.FiatAmount,
+		FiatCurrency:      deposit.FiatCurrency,
+		CryptoAmountWei:   deposit.CryptoAmount,
+		Status:            deposit.Status,
+		TxHash:            deposit.TxHash,
+		Error:             deposit.Error,
+		CreatedAt:         deposit.CreatedAt,
+		UpdatedAt:         deposit.UpdatedAt,
+	}
+}
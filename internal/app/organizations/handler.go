@@ -0,0 +1,279 @@
+package organizations
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/respond"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+	"github.com/gorilla/mux"
+)
+
+// CreateOrganizationRequest represents a new organization, its wallet is created with it
+type CreateOrganizationRequest struct {
+	Name              string `json:"name"`
+	ApprovalsRequired int    `json:"approvals_required"`
+}
+
+// OrganizationResponse represents an organization, the balance is only filled in for its details
+type OrganizationResponse struct {
+	OrgID             string    `json:"org_id"`
+	Name              string    `json:"name"`
+	OwnerUserID       string    `json:"owner_user_id"`
+	WalletID          string    `json:"wallet_id"`
+	ApprovalsRequired int       `json:"approvals_required"`
+	BalanceWei        string    `json:"balance_wei,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// InviteMemberRequest invites a user by email, or changes the permissions of a member
+type InviteMemberRequest struct {
+	Email       string   `json:"email"`
+	Permissions []string `json:"permissions"`
+}
+
+// MemberResponse represents a member of an organization
+type MemberResponse struct {
+	UserID      string    `json:"user_id"`
+	Permissions []string  `json:"permissions"`
+	Status      string    `json:"status"`
+	InvitedBy   string    `json:"invited_by,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// InitiatePaymentRequest represents a payment out of the organization wallet
+type InitiatePaymentRequest struct {
+	RecipientUserID string `json:"recipient_user_id"`
+	AmountWei       string `json:"amount_wei"`
+}
+
+// PaymentResponse represents an organization payment and who approved it so far
+type PaymentResponse struct {
+	PaymentID         string     `json:"payment_id"`
+	InitiatedBy       string     `json:"initiated_by"`
+	RecipientUserID   string     `json:"recipient_user_id"`
+	AmountWei         string     `json:"amount_wei"`
+	Status            string     `json:"status"`
+	Approvals         []string   `json:"approvals"`
+	ApprovalsRequired int        `json:"approvals_required"`
+	TxHash            string     `json:"tx_hash,omitempty"`
+	RejectedBy        string     `json:"rejected_by,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	CompletedAt       *time.Time `json:"completed_at,omitempty"`
+}
+
+type Handler struct {
+	service Service
+}
+
+// Constructor function
+func NewHandler(service Service) Handler {
+	return Handler{service: service}
+}
+
+// CreateOrganizationHandler creates an organization owned by the authenticated user
+func (hd Handler) CreateOrganizationHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req CreateOrganizationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	org, err := hd.service.CreateOrganization(userInfo.UserID, req)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, org)
+}
+
+// ListOrganizationsHandler lists the organizations the authenticated user is a member of
+func (hd Handler) ListOrganizationsHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	orgs, err := hd.service.ListOrganizations(userInfo.UserID)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, orgs)
+}
+
+// GetOrganizationHandler returns an organization with the balance of its wallet
+func (hd Handler) GetOrganizationHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	org, err := hd.service.GetOrganization(userInfo.UserID, mux.Vars(r)["orgID"])
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, org)
+}
+
+// ListMembersHandler lists the members and pending invitations of an organization
+func (hd Handler) ListMembersHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	members, err := hd.service.ListMembers(userInfo.UserID, mux.Vars(r)["orgID"])
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, members)
+}
+
+// InviteMemberHandler invites a user to the organization or updates a member's permissions
+func (hd Handler) InviteMemberHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req InviteMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	member, err := hd.service.InviteMember(userInfo.UserID, mux.Vars(r)["orgID"], req)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, member)
+}
+
+// AcceptInvitationHandler makes the authenticated user an active member of the organization
+func (hd Handler) AcceptInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	if err := hd.service.AcceptInvitation(userInfo.UserID, mux.Vars(r)["orgID"]); err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RemoveMemberHandler removes a member, members may also remove themselves
+func (hd Handler) RemoveMemberHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	vars := mux.Vars(r)
+	if err := hd.service.RemoveMember(userInfo.UserID, vars["orgID"], vars["userID"]); err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// InitiatePaymentHandler starts a payment from the organization wallet, it is sent once approved
+func (hd Handler) InitiatePaymentHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req InitiatePaymentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	payment, err := hd.service.InitiatePayment(userInfo.UserID, mux.Vars(r)["orgID"], req)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, payment)
+}
+
+// ListPaymentsHandler lists the latest payments of an organization
+func (hd Handler) ListPaymentsHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	payments, err := hd.service.ListPayments(userInfo.UserID, mux.Vars(r)["orgID"])
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, payments)
+}
+
+// ApprovePaymentHandler approves a payment, the last required approval sends it
+func (hd Handler) ApprovePaymentHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	vars := mux.Vars(r)
+	payment, err := hd.service.ApprovePayment(userInfo.UserID, vars["orgID"], vars["paymentID"])
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, payment)
+}
+
+// RejectPaymentHandler rejects a payment waiting for approval
+func (hd Handler) RejectPaymentHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	vars := mux.Vars(r)
+	payment, err := hd.service.RejectPayment(userInfo.UserID, vars["orgID"], vars["paymentID"])
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, payment)
+}
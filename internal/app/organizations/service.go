@@ -0,0 +1,479 @@
+package organizations
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"slices"
+	"strings"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/ethereum"
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Permissions a member can be given, the owner holds all of them and alone manages members
+const (
+	PermissionView     = "view"
+	PermissionInitiate = "initiate_transfer"
+	PermissionApprove  = "approve_transfer"
+)
+
+var knownPermissions = []string{PermissionView, PermissionInitiate, PermissionApprove}
+
+// Invited members have no access until they accept
+const memberActive = "active"
+
+// Payment statuses, a payment is executing while its transaction is being signed and broadcast
+const (
+	paymentPending   = "pending_approval"
+	paymentExecuting = "executing"
+	paymentExecuted  = "executed"
+	paymentRejected  = "rejected"
+	paymentFailed    = "failed"
+)
+
+const (
+	maxApprovalsRequired = 10
+	maxNameLength        = 255
+	paymentListLimit     = 100
+)
+
+type service struct {
+	orgRepo    repo.OrganizationStorer
+	userRepo   repo.UserStorer
+	walletRepo repo.WalletStorer
+	ethRepo    ethereum.EthRepo
+}
+
+type Service interface {
+	CreateOrganization(userID string, req CreateOrganizationRequest) (OrganizationResponse, error)
+	ListOrganizations(userID string) ([]OrganizationResponse, error)
+	GetOrganization(userID, orgID string) (OrganizationResponse, error)
+	ListMembers(userID, orgID string) ([]MemberResponse, error)
+	InviteMember(userID, orgID string, req InviteMemberRequest) (MemberResponse, error)
+	AcceptInvitation(userID, orgID string) error
+	RemoveMember(userID, orgID, memberID string) error
+	InitiatePayment(userID, orgID string, req InitiatePaymentRequest) (PaymentResponse, error)
+	ListPayments(userID, orgID string) ([]PaymentResponse, error)
+	ApprovePayment(userID, orgID, paymentID string) (PaymentResponse, error)
+	RejectPayment(userID, orgID, paymentID string) (PaymentResponse, error)
+}
+
+// Constructor function
+func NewService(orgRepo repo.OrganizationStorer, userRepo repo.UserStorer, walletRepo repo.WalletStorer, ethRepo ethereum.EthRepo) Service {
+	return service{
+		orgRepo:    orgRepo,
+		userRepo:   userRepo,
+		walletRepo: walletRepo,
+		ethRepo:    ethRepo,
+	}
+}
+
+// CreateOrganization creates the organization wallet, stores its key and makes the caller the owner
+func (sd service) CreateOrganization(userID string, req CreateOrganizationRequest) (OrganizationResponse, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > maxNameLength {
+		return OrganizationResponse{}, utils.Validationf("name must be between 1 and %d characters", maxNameLength)
+	}
+	if req.ApprovalsRequired == 0 {
+		req.ApprovalsRequired = 1
+	}
+	if req.ApprovalsRequired < 1 || req.ApprovalsRequired > maxApprovalsRequired {
+		return OrganizationResponse{}, utils.Validationf("approvals_required must be between 1 and %d", maxApprovalsRequired)
+	}
+
+	// The key is kept encrypted in the database, the keystore copy is never unlocked
+	keystorePassword, err := randomPassword()
+	if err != nil {
+		return OrganizationResponse{}, err
+	}
+	walletID, privateKey, err := sd.ethRepo.CreateWallet(keystorePassword)
+	if err != nil {
+		return OrganizationResponse{}, err
+	}
+	if err := sd.walletRepo.InsertOrganizationKey(walletID, fmt.Sprintf("%x", crypto.FromECDSA(privateKey))); err != nil {
+		return OrganizationResponse{}, err
+	}
+
+	org, err := sd.orgRepo.CreateOrganization(repo.Organization{
+		Name:              name,
+		OwnerUserID:       userID,
+		WalletID:          walletID,
+		ApprovalsRequired: req.ApprovalsRequired,
+	}, knownPermissions)
+	if err != nil {
+		return OrganizationResponse{}, err
+	}
+
+	log.Printf("Organization %s created by %s with wallet %s", org.OrgID, userID, walletID)
+	return toOrganizationResponse(org), nil
+}
+
+// ListOrganizations returns the organizations the user is an active member of
+func (sd service) ListOrganizations(userID string) ([]OrganizationResponse, error) {
+	orgs, err := sd.orgRepo.GetUserOrganizations(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]OrganizationResponse, 0, len(orgs))
+	for _, org := range orgs {
+		response = append(response, toOrganizationResponse(org))
+	}
+	return response, nil
+}
+
+// GetOrganization returns the organization with the current balance of its wallet
+func (sd service) GetOrganization(userID, orgID string) (OrganizationResponse, error) {
+	org, err := sd.authorize(userID, orgID, PermissionView)
+	if err != nil {
+		return OrganizationResponse{}, err
+	}
+
+	balance, err := sd.ethRepo.GetBalance(org.WalletID)
+	if err != nil {
+		return OrganizationResponse{}, utils.Upstream("failed to fetch balance", err)
+	}
+
+	response := toOrganizationResponse(org)
+	response.BalanceWei = balance.String()
+	return response, nil
+}
+
+// ListMembers returns the members and pending invitations of the organization
+func (sd service) ListMembers(userID, orgID string) ([]MemberResponse, error) {
+	if _, err := sd.authorize(userID, orgID, PermissionView); err != nil {
+		return nil, err
+	}
+
+	members, err := sd.orgRepo.GetMembers(orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]MemberResponse, 0, len(members))
+	for _, member := range members {
+		response = append(response, toMemberResponse(member))
+	}
+	return response, nil
+}
+
+// InviteMember invites a user by email, inviting an existing member replaces their permissions
+func (sd service) InviteMember(userID, orgID string, req InviteMemberRequest) (MemberResponse, error) {
+	org, err := sd.authorizeOwner(userID, orgID)
+	if err != nil {
+		return MemberResponse{}, err
+	}
+
+	for _, permission := range req.Permissions {
+		if !slices.Contains(knownPermissions, permission) {
+			return MemberResponse{}, utils.Validationf("unknown permission %q", permission)
+		}
+	}
+	if len(req.Permissions) == 0 {
+		return MemberResponse{}, utils.Validation("at least one permission is required")
+	}
+
+	invitee, err := sd.userRepo.GetUserByEmail(req.Email)
+	if err != nil {
+		return MemberResponse{}, err
+	}
+	if invitee.ID == org.OwnerUserID {
+		return MemberResponse{}, utils.Conflict("the owner already holds every permission")
+	}
+
+	permissions := slices.Clone(req.Permissions)
+	slices.Sort(permissions)
+	member, err := sd.orgRepo.UpsertMember(repo.OrganizationMember{
+		OrgID:       orgID,
+		UserID:      invitee.ID,
+		Permissions: slices.Compact(permissions),
+		InvitedBy:   userID,
+	})
+	if err != nil {
+		return MemberResponse{}, err
+	}
+	return toMemberResponse(member), nil
+}
+
+// AcceptInvitation activates a pending invitation of the user
+func (sd service) AcceptInvitation(userID, orgID string) error {
+	return sd.orgRepo.ActivateMember(orgID, userID)
+}
+
+// RemoveMember lets the owner remove any member but themselves, and members leave on their own
+func (sd service) RemoveMember(userID, orgID, memberID string) error {
+	org, err := sd.orgRepo.GetOrganization(orgID)
+	if err != nil {
+		return err
+	}
+	if memberID == org.OwnerUserID {
+		return utils.Conflict("the owner cannot be removed")
+	}
+	if userID != memberID && userID != org.OwnerUserID {
+		return utils.Forbidden("only the owner can manage members")
+	}
+	return sd.orgRepo.RemoveMember(orgID, memberID)
+}
+
+// InitiatePayment records a payment from the organization wallet waiting for its approvals
+func (sd service) InitiatePayment(userID, orgID string, req InitiatePaymentRequest) (PaymentResponse, error) {
+	org, err := sd.authorize(userID, orgID, PermissionInitiate)
+	if err != nil {
+		return PaymentResponse{}, err
+	}
+
+	amount, ok := new(big.Int).SetString(req.AmountWei, 10)
+	if !ok || amount.Sign() <= 0 {
+		return PaymentResponse{}, utils.Validation("amount_wei must be a positive integer")
+	}
+
+	recipientWalletID, err := sd.walletRepo.GetWalletID("", req.RecipientUserID)
+	if err != nil {
+		return PaymentResponse{}, utils.NotFound("recipient wallet not found", err)
+	}
+
+	payment, err := sd.orgRepo.CreatePayment(repo.OrganizationPayment{
+		OrgID:             orgID,
+		InitiatedBy:       userID,
+		RecipientUserID:   req.RecipientUserID,
+		RecipientWalletID: recipientWalletID,
+		Amount:            amount.String(),
+	})
+	if err != nil {
+		return PaymentResponse{}, err
+	}
+
+	log.Printf("Payment %s of organization %s initiated by %s", payment.PaymentID, orgID, userID)
+	return toPaymentResponse(payment, org.ApprovalsRequired), nil
+}
+
+// ListPayments returns the latest payments of the organization
+func (sd service) ListPayments(userID, orgID string) ([]PaymentResponse, error) {
+	org, err := sd.authorize(userID, orgID, PermissionView)
+	if err != nil {
+		return nil, err
+	}
+
+	payments, err := sd.orgRepo.GetPayments(orgID, paymentListLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]PaymentResponse, 0, len(payments))
+	for _, payment := range payments {
+		response = append(response, toPaymentResponse(payment, org.ApprovalsRequired))
+	}
+	return response, nil
+}
+
+// ApprovePayment records the caller's approval and sends the payment once it has enough of them.
+// Whoever initiated a payment cannot approve it.
+func (sd service) ApprovePayment(userID, orgID, paymentID string) (PaymentResponse, error) {
+	org, err := sd.authorize(userID, orgID, PermissionApprove)
+	if err != nil {
+		return PaymentResponse{}, err
+	}
+
+	payment, err := sd.pendingPayment(orgID, paymentID)
+	if err != nil {
+		return PaymentResponse{}, err
+	}
+	if payment.InitiatedBy == userID {
+		return PaymentResponse{}, utils.Forbidden("a payment cannot be approved by its initiator")
+	}
+
+	approvals, err := sd.orgRepo.ApprovePayment(paymentID, userID)
+	if err != nil {
+		return PaymentResponse{}, err
+	}
+	if approvals >= org.ApprovalsRequired {
+		if err := sd.executePayment(org, payment); err != nil {
+			return PaymentResponse{}, err
+		}
+	}
+
+	payment, err = sd.orgRepo.GetPayment(orgID, paymentID)
+	if err != nil {
+		return PaymentResponse{}, err
+	}
+	return toPaymentResponse(payment, org.ApprovalsRequired), nil
+}
+
+// RejectPayment cancels a payment waiting for approval
+func (sd service) RejectPayment(userID, orgID, paymentID string) (PaymentResponse, error) {
+	org, err := sd.authorize(userID, orgID, PermissionApprove)
+	if err != nil {
+		return PaymentResponse{}, err
+	}
+
+	if _, err := sd.pendingPayment(orgID, paymentID); err != nil {
+		return PaymentResponse{}, err
+	}
+	updated, err := sd.orgRepo.UpdatePaymentStatus(paymentID, paymentPending, paymentRejected, "", userID)
+	if err != nil {
+		return PaymentResponse{}, err
+	}
+	if !updated {
+		return PaymentResponse{}, utils.Conflict("payment is no longer waiting for approval")
+	}
+
+	payment, err := sd.orgRepo.GetPayment(orgID, paymentID)
+	if err != nil {
+		return PaymentResponse{}, err
+	}
+	return toPaymentResponse(payment, org.ApprovalsRequired), nil
+}
+
+// executePayment claims the payment so that concurrent approvals send it once, then signs it with
+// the organization key and broadcasts it
+func (sd service) executePayment(org repo.Organization, payment repo.OrganizationPayment) error {
+	claimed, err := sd.orgRepo.UpdatePaymentStatus(payment.PaymentID, paymentPending, paymentExecuting, "", "")
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return nil
+	}
+
+	txHash, err := sd.sendPayment(org, payment)
+	if err != nil {
+		log.Printf("Payment %s of organization %s failed: %v", payment.PaymentID, org.OrgID, err)
+		if _, updateErr := sd.orgRepo.UpdatePaymentStatus(payment.PaymentID, paymentExecuting, paymentFailed, "", ""); updateErr != nil {
+			log.Printf("Error marking payment %s failed: %v", payment.PaymentID, updateErr)
+		}
+		return err
+	}
+
+	if _, err := sd.orgRepo.UpdatePaymentStatus(payment.PaymentID, paymentExecuting, paymentExecuted, txHash, ""); err != nil {
+		// The transfer is already broadcast, only its record is behind
+		log.Printf("Error recording transaction %s of payment %s: %v", txHash, payment.PaymentID, err)
+	}
+	log.Printf("Payment %s of organization %s sent in %s", payment.PaymentID, org.OrgID, txHash)
+	return nil
+}
+
+// sendPayment signs and broadcasts the payment from the organization wallet
+func (sd service) sendPayment(org repo.Organization, payment repo.OrganizationPayment) (string, error) {
+	privateKeyHex, err := sd.walletRepo.RetrieveOrganizationKey(org.WalletID)
+	if err != nil {
+		return "", fmt.Errorf("error retrieving private key: %w", err)
+	}
+
+	amount, _ := new(big.Int).SetString(payment.Amount, 10)
+	signedTx, err := sd.ethRepo.TransferFunds(privateKeyHex, org.WalletID, payment.RecipientWalletID, amount, ethereum.DefaultGasPrice, ethereum.TransferGasLimit, ethereum.ChainID)
+	if err != nil {
+		return "", utils.Upstream("transaction failed", err)
+	}
+
+	if err := sd.ethRepo.SendTransaction(signedTx); err != nil {
+		return "", utils.Upstream("failed to broadcast transaction", err)
+	}
+	return signedTx.Hash().Hex(), nil
+}
+
+// pendingPayment returns a payment of the organization that is still waiting for approval
+func (sd service) pendingPayment(orgID, paymentID string) (repo.OrganizationPayment, error) {
+	payment, err := sd.orgRepo.GetPayment(orgID, paymentID)
+	if err != nil {
+		return payment, err
+	}
+	if payment.Status != paymentPending {
+		return payment, utils.Conflict("payment is no longer waiting for approval")
+	}
+	return payment, nil
+}
+
+// authorize returns the organization when the user is an active member holding the permission.
+// Non-members get a not found error, so organizations are not disclosed to outsiders.
+func (sd service) authorize(userID, orgID, permission string) (repo.Organization, error) {
+	org, err := sd.orgRepo.GetOrganization(orgID)
+	if err != nil {
+		return org, err
+	}
+	if userID == org.OwnerUserID {
+		return org, nil
+	}
+
+	member, err := sd.orgRepo.GetMember(orgID, userID)
+	if errors.Is(err, utils.ErrNotFound) {
+		return org, utils.NotFound("organization not found", err)
+	}
+	if err != nil {
+		return org, err
+	}
+	if member.Status != memberActive {
+		return org, utils.Forbidden("accept the invitation to the organization first")
+	}
+	if !slices.Contains(member.Permissions, permission) {
+		return org, utils.Forbiddenf("the %s permission is required", permission)
+	}
+	return org, nil
+}
+
+// authorizeOwner returns the organization when the user owns it
+func (sd service) authorizeOwner(userID, orgID string) (repo.Organization, error) {
+	org, err := sd.authorize(userID, orgID, PermissionView)
+	if err != nil {
+		return org, err
+	}
+	if userID != org.OwnerUserID {
+		return org, utils.Forbidden("only the owner can manage members")
+	}
+	return org, nil
+}
+
+// randomPassword returns a throwaway password for the keystore file of an organization wallet
+func randomPassword() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("error generating keystore password: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func toOrganizationResponse(org repo.Organization) OrganizationResponse {
+	return OrganizationResponse{
+		OrgID:             org.OrgID,
+		Name:              org.Name,
+		OwnerUserID:       org.OwnerUserID,
+		WalletID:          org.WalletID,
+		ApprovalsRequired: org.ApprovalsRequired,
+		CreatedAt:         org.CreatedAt,
+	}
+}
+
+func toMemberResponse(member repo.OrganizationMember) MemberResponse {
+	return MemberResponse{
+		UserID:      member.UserID,
+		Permissions: member.Permissions,
+		Status:      member.Status,
+		InvitedBy:   member.InvitedBy,
+		CreatedAt:   member.CreatedAt,
+	}
+}
+
+func toPaymentResponse(payment repo.OrganizationPayment, approvalsRequired int) PaymentResponse {
+	approvals := payment.Approvals
+	if approvals == nil {
+		approvals = []string{}
+	}
+	return PaymentResponse{
+		PaymentID:         payment.PaymentID,
+		InitiatedBy:       payment.InitiatedBy,
+		RecipientUserID:   payment.RecipientUserID,
+		AmountWei:         payment.Amount,
+		Status:            payment.Status,
+		Approvals:         approvals,
+		ApprovalsRequired: approvalsRequired,
+		TxHash:            payment.TxHash,
+		RejectedBy:        payment.RejectedBy,
+		CreatedAt:         payment.CreatedAt,
+		CompletedAt:       payment.CompletedAt,
+	}
+}
@@ -0,0 +1,185 @@
+package outbox
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+)
+
+const (
+	relayInterval  = 2 * time.Second
+	relayBatchSize = 50
+	// A claimed event not published within its lease is published again, by this or another instance
+	relayLease      = time.Minute
+	firstRetryDelay = 5 * time.Second
+	maxRetryDelay   = time.Hour
+	purgeInterval   = time.Hour
+	// Published events are kept this long for troubleshooting
+	publishedRetention = 3 * 24 * time.Hour
+)
+
+// Subscriber handles the events of a topic. An error makes the relay publish the event again later,
+// to every subscriber of the topic, so subscribers must tolerate seeing an event twice.
+type Subscriber func(payload json.RawMessage) error
+
+type service struct {
+	outboxRepo repo.OutboxStorer
+
+	mu          sync.RWMutex
+	subscribers map[string][]Subscriber
+	// Wakes the relay when an event was stored, so that it does not wait for the next poll
+	wake chan struct{}
+}
+
+type Service interface {
+	Subscribe(topic string, subscriber Subscriber)
+	Publish(topic string, payload any) error
+	RunRelay(stop <-chan struct{})
+}
+
+// Constructor function
+func NewService(outboxRepo repo.OutboxStorer) Service {
+	return &service{
+		outboxRepo:  outboxRepo,
+		subscribers: map[string][]Subscriber{},
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// NewEvent encodes an event for a repo to store in the same transaction as the change it announces
+func NewEvent(topic string, payload any) (repo.OutboxEvent, error) {
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return repo.OutboxEvent{}, fmt.Errorf("error encoding %s event: %v", topic, err)
+	}
+	return repo.OutboxEvent{Topic: topic, Payload: encoded}, nil
+}
+
+// Subscribe adds a subscriber to a topic. Subscribers are added by the services owning them while the
+// dependencies are set up.
+func (sd *service) Subscribe(topic string, subscriber Subscriber) {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	sd.subscribers[topic] = append(sd.subscribers[topic], subscriber)
+}
+
+// Publish stores an event on its own, for changes that were not made in a transaction of their own.
+// It returns once the event is stored, the subscribers get it from the relay.
+func (sd *service) Publish(topic string, payload any) error {
+	event, err := NewEvent(topic, payload)
+	if err != nil {
+		return err
+	}
+	if err := sd.outboxRepo.AddOutboxEvent(event); err != nil {
+		return err
+	}
+
+	select {
+	case sd.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// RunRelay publishes stored events to their subscribers and deletes old published ones until stop is closed
+func (sd *service) RunRelay(stop <-chan struct{}) {
+	poll := time.NewTicker(relayInterval)
+	defer poll.Stop()
+	purge := time.NewTicker(purgeInterval)
+	defer purge.Stop()
+
+	for {
+		select {
+		case <-poll.C:
+			sd.relay(stop)
+		case <-sd.wake:
+			sd.relay(stop)
+		case <-purge.C:
+			sd.purgePublished()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// relay publishes batches of events until none are left or stop is closed
+func (sd *service) relay(stop <-chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		events, err := sd.outboxRepo.ClaimOutboxEvents(relayBatchSize, relayLease)
+		if err != nil || len(events) == 0 {
+			return
+		}
+		for _, event := range events {
+			sd.publish(event)
+		}
+	}
+}
+
+// publish hands an event to every subscriber of its topic, a failed event is retried with exponential
+// backoff. Events of a topic without subscribers are published to nobody.
+func (sd *service) publish(event repo.OutboxEvent) {
+	sd.mu.RLock()
+	subscribers := sd.subscribers[event.Topic]
+	sd.mu.RUnlock()
+
+	var failures []error
+	for _, subscriber := range subscribers {
+		if err := runSubscriber(subscriber, event.Payload); err != nil {
+			failures = append(failures, err)
+		}
+	}
+
+	if err := errors.Join(failures...); err != nil {
+		log.Printf("Error publishing %s event %s: %v", event.Topic, event.EventID, err)
+		nextAttemptAt := time.Now().Add(retryDelay(event.Attempts + 1))
+		if err := sd.outboxRepo.RecordOutboxFailure(event.EventID, err.Error(), nextAttemptAt); err != nil {
+			log.Printf("Error recording outbox failure: %v", err)
+		}
+		return
+	}
+	if err := sd.outboxRepo.MarkOutboxEventPublished(event.EventID); err != nil {
+		log.Printf("Error recording outbox publication: %v", err)
+	}
+}
+
+// purgePublished deletes the published events past their retention
+func (sd *service) purgePublished() {
+	deleted, err := sd.outboxRepo.DeletePublishedOutboxEvents(time.Now().Add(-publishedRetention))
+	if err != nil {
+		log.Printf("Error purging published outbox events: %v", err)
+		return
+	}
+	if deleted > 0 {
+		log.Printf("Purged %d published outbox events", deleted)
+	}
+}
+
+// runSubscriber runs a subscriber, turning a panic into a failure so that it does not stop the relay
+func runSubscriber(subscriber Subscriber, payload json.RawMessage) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("subscriber panicked: %v", recovered)
+		}
+	}()
+	return subscriber(payload)
+}
+
+// retryDelay doubles the delay after every failed attempt, up to maxRetryDelay
+func retryDelay(attempts int) time.Duration {
+	delay := firstRetryDelay
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryDelay)
+}
@@ -0,0 +1,166 @@
+package paymentrequests
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/respond"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+	"github.com/gorilla/mux"
+)
+
+// CreatePaymentRequest asks for amount wei, the request expires after a day unless expires_at is set
+type CreatePaymentRequest struct {
+	Amount    string     `json:"amount"`
+	Memo      string     `json:"memo"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// PayRequest carries the confirmation of the payer's transfer, the same fields as a transfer takes
+type PayRequest struct {
+	Password    string `json:"password"`
+	TOTPCode    string `json:"totp_code"`
+	DeviceToken string `json:"device_token"`
+	PIN         string `json:"pin"`
+}
+
+// PaymentRequestResponse represents a payment request, QRPayload is only set while it can be paid
+type PaymentRequestResponse struct {
+	RequestID       string     `json:"request_id"`
+	RequesterUserID string     `json:"requester_user_id"`
+	BillID          string     `json:"bill_id,omitempty"`
+	PayerUserID     string     `json:"payer_user_id,omitempty"`
+	RecipientWallet string     `json:"recipient_wallet_id"`
+	Amount          string     `json:"amount"`
+	Memo            string     `json:"memo,omitempty"`
+	Status          string     `json:"status"`
+	ExpiresAt       time.Time  `json:"expires_at"`
+	QRPayload       string     `json:"qr_payload,omitempty"`
+	PaidBy          string     `json:"paid_by,omitempty"`
+	TxHash          string     `json:"transaction_hash,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	PaidAt          *time.Time `json:"paid_at,omitempty"`
+}
+
+type Handler struct {
+	service Service
+}
+
+// Constructor function
+func NewHandler(service Service) Handler {
+	return Handler{service: service}
+}
+
+// CreateRequestHandler opens a payment request to the authenticated user
+func (hd Handler) CreateRequestHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req CreatePaymentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	request, err := hd.service.CreateRequest(userInfo.UserID, req)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, request)
+}
+
+// ListRequestsHandler lists the payment requests the authenticated user created
+func (hd Handler) ListRequestsHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	requests, err := hd.service.ListRequests(userInfo.UserID)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.List(w, r, requests, respond.Pagination{Count: len(requests)})
+}
+
+// ListIncomingRequestsHandler lists the payment requests addressed to the authenticated user
+func (hd Handler) ListIncomingRequestsHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	requests, err := hd.service.ListIncomingRequests(userInfo.UserID)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.List(w, r, requests, respond.Pagination{Count: len(requests)})
+}
+
+// GetRequestHandler returns a payment request, e.g. after the payer scanned its QR code
+func (hd Handler) GetRequestHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	request, err := hd.service.GetRequest(userInfo.UserID, mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, request)
+}
+
+// PayRequestHandler pays a payment request from the authenticated user's wallet
+func (hd Handler) PayRequestHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req PayRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	request, err := hd.service.PayRequest(userInfo, mux.Vars(r)["id"], req)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, request)
+}
+
+// CancelRequestHandler withdraws an open payment request of the authenticated user
+func (hd Handler) CancelRequestHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	if err := hd.service.CancelRequest(userInfo.UserID, mux.Vars(r)["id"]); err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,320 @@
+package paymentrequests
+
+import (
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/ethereum"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/notification"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/wallet"
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+// Request statuses, a request is paying while the payer's transfer is confirmed and broadcast.
+// Expired is never stored, an open request past its expiry is reported as expired.
+const (
+	StatusOpen      = "open"
+	StatusPaying    = "paying"
+	StatusPaid      = "paid"
+	StatusCancelled = "cancelled"
+	StatusExpired   = "expired"
+)
+
+const (
+	defaultExpiry    = 24 * time.Hour
+	maxExpiry        = 30 * 24 * time.Hour
+	maxMemoLength    = 140
+	requestListLimit = 100
+)
+
+type service struct {
+	paymentRequestRepo repo.PaymentRequestStorer
+	walletRepo         repo.WalletStorer
+	wallets            wallet.Service
+	notifications      notification.Service
+}
+
+type Service interface {
+	CreateRequest(userID string, req CreatePaymentRequest) (PaymentRequestResponse, error)
+	GetRequest(userID, requestID string) (PaymentRequestResponse, error)
+	ListRequests(userID string) ([]PaymentRequestResponse, error)
+	ListIncomingRequests(userID string) ([]PaymentRequestResponse, error)
+	PayRequest(userInfo utils.User, requestID string, req PayRequest) (PaymentRequestResponse, error)
+	CancelRequest(userID, requestID string) error
+}
+
+// Constructor function
+func NewService(paymentRequestRepo repo.PaymentRequestStorer, walletRepo repo.WalletStorer, walletService wallet.Service, notificationService notification.Service) Service {
+	return service{
+		paymentRequestRepo: paymentRequestRepo,
+		walletRepo:         walletRepo,
+		wallets:            walletService,
+		notifications:      notificationService,
+	}
+}
+
+// CreateRequest opens a request for a payment to the user's wallet
+func (sd service) CreateRequest(userID string, req CreatePaymentRequest) (PaymentRequestResponse, error) {
+	amount, ok := new(big.Int).SetString(req.Amount, 10)
+	if !ok || amount.Sign() <= 0 {
+		return PaymentRequestResponse{}, utils.Validation("amount must be a positive integer in wei")
+	}
+	memo := strings.TrimSpace(req.Memo)
+	if len(memo) > maxMemoLength {
+		return PaymentRequestResponse{}, utils.Validationf("memo cannot be longer than %d characters", maxMemoLength)
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(defaultExpiry)
+	if req.ExpiresAt != nil {
+		expiresAt = *req.ExpiresAt
+		if !expiresAt.After(now) || expiresAt.After(now.Add(maxExpiry)) {
+			return PaymentRequestResponse{}, utils.Validationf("expires_at must be in the future and within %d days", int(maxExpiry.Hours()/24))
+		}
+	}
+
+	walletID, err := sd.walletRepo.GetWalletID("", userID)
+	if err != nil {
+		return PaymentRequestResponse{}, utils.NotFound("wallet not found", err)
+	}
+
+	request, err := sd.paymentRequestRepo.CreatePaymentRequest(repo.PaymentRequest{
+		RequesterUserID: userID,
+		Amount:          amount.String(),
+		Memo:            memo,
+		ExpiresAt:       expiresAt.UTC().Truncate(time.Second),
+	})
+	if err != nil {
+		return PaymentRequestResponse{}, err
+	}
+	return newPaymentRequestResponse(request, walletID), nil
+}
+
+// GetRequest returns a request as the payer sees it after scanning its QR code
+func (sd service) GetRequest(userID, requestID string) (PaymentRequestResponse, error) {
+	request, err := sd.visibleRequest(userID, requestID)
+	if err != nil {
+		return PaymentRequestResponse{}, err
+	}
+	return sd.toResponse(request)
+}
+
+// ListRequests returns the latest requests the user created
+func (sd service) ListRequests(userID string) ([]PaymentRequestResponse, error) {
+	requests, err := sd.paymentRequestRepo.GetPaymentRequests(userID, requestListLimit)
+	if err != nil {
+		return nil, err
+	}
+	walletID, err := sd.walletRepo.GetWalletID("", userID)
+	if err != nil {
+		return nil, utils.NotFound("wallet not found", err)
+	}
+
+	response := make([]PaymentRequestResponse, len(requests))
+	for i, request := range requests {
+		response[i] = newPaymentRequestResponse(request, walletID)
+	}
+	return response, nil
+}
+
+// ListIncomingRequests returns the latest requests addressed to the user, e.g. their bill shares
+func (sd service) ListIncomingRequests(userID string) ([]PaymentRequestResponse, error) {
+	requests, err := sd.paymentRequestRepo.GetIncomingPaymentRequests(userID, requestListLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]PaymentRequestResponse, len(requests))
+	for i, request := range requests {
+		if response[i], err = sd.toResponse(request); err != nil {
+			return nil, err
+		}
+	}
+	return response, nil
+}
+
+// PayRequest pays a request through the regular transfer, so the payer confirms it the way they
+// confirm any transfer and the transfer limits apply. The request is claimed first so it is paid once.
+func (sd service) PayRequest(userInfo utils.User, requestID string, req PayRequest) (PaymentRequestResponse, error) {
+	request, err := sd.visibleRequest(userInfo.UserID, requestID)
+	if err != nil {
+		return PaymentRequestResponse{}, err
+	}
+	if request.RequesterUserID == userInfo.UserID {
+		return PaymentRequestResponse{}, utils.Validation("you cannot pay your own payment request")
+	}
+	if err := checkPayable(request); err != nil {
+		return PaymentRequestResponse{}, err
+	}
+
+	claimed, err := sd.paymentRequestRepo.ClaimPaymentRequest(requestID, userInfo.UserID)
+	if err != nil {
+		return PaymentRequestResponse{}, err
+	}
+	if !claimed {
+		return PaymentRequestResponse{}, utils.Conflict("payment request is no longer open")
+	}
+
+	txHash, err := sd.wallets.TransferFunds(userInfo, wallet.TransferRequest{
+		RecipientUserID: request.RequesterUserID,
+		AmountETH:       request.Amount,
+		Password:        req.Password,
+		TOTPCode:        req.TOTPCode,
+		DeviceToken:     req.DeviceToken,
+		PIN:             req.PIN,
+	})
+	if err != nil {
+		if releaseErr := sd.paymentRequestRepo.ReleasePaymentRequest(requestID); releaseErr != nil {
+			log.Printf("Error reopening payment request %s: %v", requestID, releaseErr)
+		}
+		return PaymentRequestResponse{}, err
+	}
+
+	if err := sd.paymentRequestRepo.CompletePaymentRequest(requestID, txHash); err != nil {
+		// The transfer is already broadcast, only its record is behind
+		log.Printf("Error recording transaction %s of payment request %s: %v", txHash, requestID, err)
+	}
+	sd.notifyRequester(request, txHash)
+	if request.BillID != "" {
+		sd.checkBillPaid(request)
+	}
+
+	request, err = sd.paymentRequestRepo.GetPaymentRequest(requestID)
+	if err != nil {
+		return PaymentRequestResponse{}, err
+	}
+	return sd.toResponse(request)
+}
+
+// CancelRequest withdraws an open request of the user
+func (sd service) CancelRequest(userID, requestID string) error {
+	cancelled, err := sd.paymentRequestRepo.CancelPaymentRequest(requestID, userID)
+	if err != nil {
+		return err
+	}
+	if cancelled {
+		return nil
+	}
+
+	request, err := sd.paymentRequestRepo.GetPaymentRequest(requestID)
+	if err != nil {
+		return err
+	}
+	if request.RequesterUserID != userID {
+		return utils.NotFound("payment request not found", nil)
+	}
+	return utils.Conflict("payment request is no longer open")
+}
+
+// notifyRequester tells the requester their request was paid, on top of the usual transfer notification
+func (sd service) notifyRequester(request repo.PaymentRequest, txHash string) {
+	amount, _ := new(big.Int).SetString(request.Amount, 10)
+	ethAmount := new(big.Float).Quo(new(big.Float).SetInt(amount), big.NewFloat(1e18)).Text('f', -1)
+
+	sd.notifications.Notify(notification.Notification{
+		UserID:   request.RequesterUserID,
+		Category: notification.CategoryPaymentRequest,
+		Title:    "Payment request paid",
+		Body:     "Your request for %s ETH was paid.",
+		BodyArgs: []any{ethAmount},
+		Data:     map[string]string{"payment_request_id": request.RequestID, "transaction_hash": txHash, "amount": request.Amount},
+	})
+}
+
+// checkBillPaid tells the organizer once every share of the bill has been paid
+func (sd service) checkBillPaid(request repo.PaymentRequest) {
+	shares, err := sd.paymentRequestRepo.GetBillPaymentRequests(request.BillID)
+	if err != nil {
+		log.Printf("Error checking shares of bill %s: %v", request.BillID, err)
+		return
+	}
+	for _, share := range shares {
+		if share.Status != StatusPaid {
+			return
+		}
+	}
+
+	sd.notifications.Notify(notification.Notification{
+		UserID:   request.RequesterUserID,
+		Category: notification.CategoryPaymentRequest,
+		Title:    "Bill paid in full",
+		Body:     "All %d participants paid their share of %s.",
+		BodyArgs: []any{len(shares), request.Memo},
+		Data:     map[string]string{"bill_id": request.BillID},
+	})
+}
+
+// visibleRequest returns a request the user may see. Requests addressed to a participant are only
+// shown to the requester and that participant, to anyone else they do not exist.
+func (sd service) visibleRequest(userID, requestID string) (repo.PaymentRequest, error) {
+	request, err := sd.paymentRequestRepo.GetPaymentRequest(requestID)
+	if err != nil {
+		return request, err
+	}
+	if request.PayerUserID != "" && userID != request.PayerUserID && userID != request.RequesterUserID {
+		return request, utils.NotFound("payment request not found", nil)
+	}
+	return request, nil
+}
+
+func (sd service) toResponse(request repo.PaymentRequest) (PaymentRequestResponse, error) {
+	walletID, err := sd.walletRepo.GetWalletID("", request.RequesterUserID)
+	if err != nil {
+		return PaymentRequestResponse{}, utils.NotFound("wallet not found", err)
+	}
+	return newPaymentRequestResponse(request, walletID), nil
+}
+
+// checkPayable rejects requests that were paid, cancelled or have expired
+func checkPayable(request repo.PaymentRequest) error {
+	switch EffectiveStatus(request) {
+	case StatusPaid, StatusPaying:
+		return utils.Conflict("payment request was already paid")
+	case StatusCancelled:
+		return utils.Conflict("payment request was cancelled")
+	case StatusExpired:
+		return utils.Conflict("payment request has expired")
+	}
+	return nil
+}
+
+// EffectiveStatus returns the status of a request, expired for an open request past its expiry
+func EffectiveStatus(request repo.PaymentRequest) string {
+	if request.Status == StatusOpen && time.Now().After(request.ExpiresAt) {
+		return StatusExpired
+	}
+	return request.Status
+}
+
+// qrPayload encodes the request as an EIP-681 payment URI. The app pays it through the request
+// endpoint using request_id, other wallets ignore the parameter and transfer to the address directly,
+// which does not mark the request paid.
+func qrPayload(request repo.PaymentRequest, walletID string) string {
+	return fmt.Sprintf("ethereum:%s@%s?value=%s&request_id=%s", walletID, ethereum.ChainID.String(), request.Amount, request.RequestID)
+}
+
+func newPaymentRequestResponse(request repo.PaymentRequest, walletID string) PaymentRequestResponse {
+	response := PaymentRequestResponse{
+		RequestID:       request.RequestID,
+		RequesterUserID: request.RequesterUserID,
+		BillID:          request.BillID,
+		PayerUserID:     request.PayerUserID,
+		RecipientWallet: walletID,
+		Amount:          request.Amount,
+		Memo:            request.Memo,
+		Status:          EffectiveStatus(request),
+		ExpiresAt:       request.ExpiresAt,
+		PaidBy:          request.PaidBy,
+		TxHash:          request.TxHash,
+		CreatedAt:       request.CreatedAt,
+		PaidAt:          request.PaidAt,
+	}
+	if response.Status == StatusOpen {
+		response.QRPayload = qrPayload(request, walletID)
+	}
+	return response
+}
@@ -0,0 +1,50 @@
+package piibackfill
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/respond"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+// BackfillProgress reports the state of the latest encryption of plaintext personal data
+type BackfillProgress struct {
+	Running    bool       `json:"running"`
+	Encrypted  int64      `json:"encrypted"`
+	Remaining  int64      `json:"remaining"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+type Handler struct {
+	service Service
+}
+
+// Constructor function
+func NewHandler(service Service) Handler {
+	return Handler{service: service}
+}
+
+// StartBackfillHandler starts encrypting the personal data still stored in plaintext, needs encryption.manage
+func (hd Handler) StartBackfillHandler(w http.ResponseWriter, r *http.Request) {
+	progress, err := hd.service.StartBackfill()
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, progress)
+}
+
+// ProgressHandler reports how far the backfill has come, needs encryption.manage
+func (hd Handler) ProgressHandler(w http.ResponseWriter, r *http.Request) {
+	progress, err := hd.service.Progress()
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, progress)
+}
@@ -0,0 +1,106 @@
+package piibackfill
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+const backfillBatchSize = 100
+
+type service struct {
+	userRepo repo.UserStorer
+	// Guards progress and keeps a second backfill from starting while one is running
+	mu       *sync.Mutex
+	progress *BackfillProgress
+}
+
+type Service interface {
+	StartBackfill() (BackfillProgress, error)
+	Progress() (BackfillProgress, error)
+}
+
+// Constructor function
+func NewService(userRepo repo.UserStorer) Service {
+	return service{
+		userRepo: userRepo,
+		mu:       &sync.Mutex{},
+		progress: &BackfillProgress{},
+	}
+}
+
+// StartBackfill encrypts the names and dates of birth users signed up with before personal data was
+// encrypted in the background. A backfill that failed or was cut short by a restart continues where it stopped.
+func (sd service) StartBackfill() (BackfillProgress, error) {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+
+	if sd.progress.Running {
+		return BackfillProgress{}, utils.Conflict("PII backfill already in progress")
+	}
+
+	remaining, err := sd.userRepo.CountUserPIIToEncrypt()
+	if err != nil {
+		return BackfillProgress{}, err
+	}
+	startedAt := time.Now()
+	*sd.progress = BackfillProgress{
+		Running:   true,
+		Remaining: remaining,
+		StartedAt: &startedAt,
+	}
+	if sd.progress.Remaining == 0 {
+		sd.progress.Running = false
+		sd.progress.FinishedAt = &startedAt
+		return *sd.progress, nil
+	}
+
+	log.Printf("Encrypting personal data of %d users", sd.progress.Remaining)
+	go sd.backfill()
+	return *sd.progress, nil
+}
+
+// Progress returns the latest backfill with the users left refreshed from the database
+func (sd service) Progress() (BackfillProgress, error) {
+	remaining, err := sd.userRepo.CountUserPIIToEncrypt()
+	if err != nil {
+		return BackfillProgress{}, err
+	}
+
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	progress := *sd.progress
+	progress.Remaining = remaining
+	return progress, nil
+}
+
+// backfill works through the users batch by batch until none are left in plaintext
+func (sd service) backfill() {
+	for {
+		encrypted, err := sd.userRepo.EncryptUserPII(backfillBatchSize)
+
+		sd.mu.Lock()
+		sd.progress.Encrypted += encrypted
+		sd.progress.Remaining -= encrypted
+		if sd.progress.Remaining < 0 {
+			sd.progress.Remaining = 0
+		}
+		if err != nil || encrypted == 0 {
+			finishedAt := time.Now()
+			sd.progress.Running = false
+			sd.progress.FinishedAt = &finishedAt
+			if err != nil {
+				sd.progress.Error = err.Error()
+				log.Printf("PII backfill stopped after %d users: %v", sd.progress.Encrypted, err)
+			} else {
+				log.Printf("PII backfill finished, %d users encrypted", sd.progress.Encrypted)
+			}
+			sd.mu.Unlock()
+			return
+		}
+		sd.mu.Unlock()
+	}
+}
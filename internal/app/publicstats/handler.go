@@ -0,0 +1,42 @@
+package publicstats
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/respond"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+// Stats are aggregate, anonymized platform figures for the current UTC month. The average is
+// left out until the month has enough transfers for it not to single out anyone.
+type Stats struct {
+	Month              string    `json:"month"`
+	TransfersThisMonth int64     `json:"transfers_this_month"`
+	VolumeThisMonthWei string    `json:"volume_this_month_wei"`
+	AverageTransferWei string    `json:"average_transfer_wei,omitempty"`
+	ActiveSenders      int64     `json:"active_senders_this_month"`
+	GeneratedAt        time.Time `json:"generated_at"`
+}
+
+type Handler struct {
+	service Service
+}
+
+// Constructor function
+func NewHandler(service Service) Handler {
+	return Handler{service: service}
+}
+
+// GetStatsHandler returns the public platform statistics, no authentication needed
+func (hd Handler) GetStatsHandler(w http.ResponseWriter, r *http.Request) {
+	stats, err := hd.service.GetStats()
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(cacheTTL.Seconds())))
+	respond.CachedJSON(w, r, stats)
+}
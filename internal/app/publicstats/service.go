@@ -0,0 +1,65 @@
+package publicstats
+
+import (
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+)
+
+const (
+	// Statistics are recomputed at most this often however many clients ask for them
+	cacheTTL = 5 * time.Minute
+	// Averages over fewer transfers could reveal the amount of an individual transfer
+	minSampleSize = 10
+)
+
+type service struct {
+	transactionRepo repo.TransactionStorer
+	mu              sync.Mutex
+	cached          Stats
+	expiresAt       time.Time
+}
+
+type Service interface {
+	GetStats() (Stats, error)
+}
+
+// Constructor function
+func NewService(transactionRepo repo.TransactionStorer) Service {
+	return &service{transactionRepo: transactionRepo}
+}
+
+// GetStats returns the platform statistics of the current month, served from memory for cacheTTL
+func (sd *service) GetStats() (Stats, error) {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+
+	now := time.Now().UTC()
+	if now.Before(sd.expiresAt) {
+		return sd.cached, nil
+	}
+
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	platform, err := sd.transactionRepo.GetPlatformStats(monthStart)
+	if err != nil {
+		return Stats{}, err
+	}
+
+	stats := Stats{
+		Month:              monthStart.Format("2006-01"),
+		TransfersThisMonth: platform.TransferCount,
+		VolumeThisMonthWei: platform.Volume.String(),
+		ActiveSenders:      platform.ActiveSenders,
+		GeneratedAt:        now,
+	}
+	if platform.TransferCount >= minSampleSize {
+		average := new(big.Int).Quo(platform.Volume, big.NewInt(platform.TransferCount))
+		stats.AverageTransferWei = average.String()
+	}
+
+	sd.cached = stats
+	sd.expiresAt = now.Add(cacheTTL)
+	return stats, nil
+}
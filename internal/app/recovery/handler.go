@@ -0,0 +1,107 @@
+package recovery
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/respond"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+	"github.com/gorilla/mux"
+)
+
+// StuckTransfer represents a broadcast transfer that has gone without a receipt for too long
+type StuckTransfer struct {
+	TransactionID    string    `json:"transaction_id"`
+	TxHash           string    `json:"tx_hash"`
+	SenderUserID     string    `json:"sender_user_id"`
+	ReceiverUserID   string    `json:"receiver_user_id"`
+	Amount           string    `json:"amount"`
+	Nonce            *int64    `json:"nonce"`
+	GasPriceWei      string    `json:"gas_price_wei"`
+	PreviousTxHashes []string  `json:"previous_tx_hashes"`
+	CancelRequested  bool      `json:"cancel_requested"`
+	CreatedAt        time.Time `json:"created_at"`
+	BroadcastAt      time.Time `json:"broadcast_at"`
+}
+
+// ReplacementRequest optionally sets the gas price of a replacement, a default bump is used otherwise
+type ReplacementRequest struct {
+	GasPriceWei string `json:"gas_price_wei"`
+}
+
+// ReplacementResponse represents a broadcast replacement of a stuck transfer
+type ReplacementResponse struct {
+	TransactionID   string `json:"transaction_id"`
+	ReplacedTxHash  string `json:"replaced_tx_hash"`
+	TxHash          string `json:"tx_hash"`
+	GasPriceWei     string `json:"gas_price_wei"`
+	CancelRequested bool   `json:"cancel_requested"`
+}
+
+type Handler struct {
+	service Service
+}
+
+// Constructor function
+func NewHandler(service Service) Handler {
+	return Handler{service: service}
+}
+
+// ListStuckHandler lists transfers without a receipt, needs transaction.recover
+func (hd Handler) ListStuckHandler(w http.ResponseWriter, r *http.Request) {
+	stuck, err := hd.service.ListStuck()
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.List(w, r, stuck, respond.Pagination{Count: len(stuck)})
+}
+
+// BumpGasHandler rebroadcasts a stuck transfer with a higher gas price, needs transaction.recover
+func (hd Handler) BumpGasHandler(w http.ResponseWriter, r *http.Request) {
+	hd.handleReplacement(w, r, hd.service.BumpGas)
+}
+
+// CancelHandler replaces a stuck transfer with a cancellation, needs transaction.recover
+func (hd Handler) CancelHandler(w http.ResponseWriter, r *http.Request) {
+	hd.handleReplacement(w, r, hd.service.Cancel)
+}
+
+// handleReplacement decodes the optional request body and runs the given replacement
+func (hd Handler) handleReplacement(w http.ResponseWriter, r *http.Request, replace func(string, ReplacementRequest) (ReplacementResponse, error)) {
+	var req ReplacementRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	result, err := replace(mux.Vars(r)["transactionID"], req)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, result)
+}
+
+// newStuckTransfer maps a pending transfer to its response
+func newStuckTransfer(transfer repo.PendingTransfer) StuckTransfer {
+	return StuckTransfer{
+		TransactionID:    transfer.TransactionID,
+		TxHash:           transfer.TxHash,
+		SenderUserID:     transfer.SenderUserID,
+		ReceiverUserID:   transfer.ReceiverUserID,
+		Amount:           transfer.Amount,
+		Nonce:            transfer.Nonce,
+		GasPriceWei:      transfer.GasPrice,
+		PreviousTxHashes: transfer.PreviousTxHashes,
+		CancelRequested:  transfer.CancelRequested,
+		CreatedAt:        transfer.CreatedAt,
+		BroadcastAt:      transfer.BroadcastAt,
+	}
+}
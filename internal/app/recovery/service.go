@@ -0,0 +1,337 @@
+package recovery
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+
+	goethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/ethereum"
+	"github.com/CodeWithKrushnal/ChainBank/internal/config"
+	"github.com/CodeWithKrushnal/ChainBank/internal/domain"
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+const (
+	// Nodes reject a replacement unless its fee is at least 10% higher
+	minGasBumpPercent     = 110
+	defaultGasBumpPercent = 125
+	// Upper bound on the transactions checked in one detection run
+	maxPendingChecked = 500
+	maxSettledChecked = 500
+)
+
+type service struct {
+	transactionRepo repo.TransactionStorer
+	walletRepo      repo.WalletStorer
+	ethRepo         ethereum.EthRepo
+}
+
+type Service interface {
+	ListStuck() ([]StuckTransfer, error)
+	BumpGas(transactionID string, req ReplacementRequest) (ReplacementResponse, error)
+	Cancel(transactionID string, req ReplacementRequest) (ReplacementResponse, error)
+	DetectReorgs() (int, error)
+	RunScheduler(stop <-chan struct{})
+}
+
+// Constructor function
+func NewService(transactionRepo repo.TransactionStorer, walletRepo repo.WalletStorer, ethRepo ethereum.EthRepo) Service {
+	return service{
+		transactionRepo: transactionRepo,
+		walletRepo:      walletRepo,
+		ethRepo:         ethRepo,
+	}
+}
+
+// ListStuck settles the pending transfers that got a receipt and returns the ones that are
+// still without a receipt after the configured number of minutes
+func (sd service) ListStuck() ([]StuckTransfer, error) {
+	cutoff := time.Now().Add(-stuckAfter())
+	pending, err := sd.transactionRepo.GetPendingBroadcastBefore(cutoff, maxPendingChecked)
+	if err != nil {
+		return nil, err
+	}
+
+	stuck := []StuckTransfer{}
+	for _, transfer := range pending {
+		settled, err := sd.settle(transfer)
+		if err != nil {
+			log.Printf("Error checking receipt of transaction %s: %v", transfer.TransactionID, err)
+		}
+		if !settled {
+			stuck = append(stuck, newStuckTransfer(transfer))
+		}
+	}
+	return stuck, nil
+}
+
+// BumpGas rebroadcasts a stuck transfer with the same nonce and a higher gas price
+func (sd service) BumpGas(transactionID string, req ReplacementRequest) (ReplacementResponse, error) {
+	return sd.replace(transactionID, req, false)
+}
+
+// Cancel replaces a stuck transfer with a zero value transaction to the sender itself
+func (sd service) Cancel(transactionID string, req ReplacementRequest) (ReplacementResponse, error) {
+	return sd.replace(transactionID, req, true)
+}
+
+// DetectReorgs compares the block of every transfer settled in the latest blocks with the
+// canonical chain. Transfers whose block was orphaned go back to pending and are settled
+// again from their receipt on the new chain. It returns how many were reverted.
+func (sd service) DetectReorgs() (int, error) {
+	depth := uint64(config.ConfigDetails.ReorgCheckBlocks)
+	if depth == 0 {
+		return 0, nil
+	}
+
+	head, err := sd.ethRepo.GetBlockNumber()
+	if err != nil {
+		return 0, utils.Upstream("failed to fetch the latest block", err)
+	}
+	var since uint64
+	if head > depth {
+		since = head - depth
+	}
+
+	settled, err := sd.transactionRepo.GetSettledSince(since, maxSettledChecked)
+	if err != nil {
+		return 0, err
+	}
+
+	reverted := 0
+	canonical := map[uint64]string{}
+	for _, transfer := range settled {
+		blockHash, ok := canonical[transfer.BlockNumber]
+		if !ok {
+			blockHash, err = sd.ethRepo.GetBlockHash(transfer.BlockNumber)
+			if err != nil && !errors.Is(err, goethereum.NotFound) {
+				return reverted, utils.Upstream("failed to fetch block hash", err)
+			}
+			canonical[transfer.BlockNumber] = blockHash
+		}
+		if blockHash == transfer.BlockHash {
+			continue
+		}
+
+		ok, err = sd.transactionRepo.RevertSettlement(transfer.TransactionID, transfer.BlockHash)
+		if err != nil {
+			return reverted, err
+		}
+		if ok {
+			log.Printf("Block %d (%s) of transaction %s was orphaned, %s reverted to pending",
+				transfer.BlockNumber, transfer.BlockHash, transfer.TransactionID, transfer.Status)
+			reverted++
+		}
+	}
+	return reverted, nil
+}
+
+// RunScheduler settles and reports stuck transfers on the configured interval until stop is closed
+func (sd service) RunScheduler(stop <-chan struct{}) {
+	interval := stuckAfter()
+	if interval <= 0 {
+		log.Println("Stuck transaction detection disabled")
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			// Reorged transfers are pending again and get settled by ListStuck right after
+			if _, err := sd.DetectReorgs(); err != nil {
+				log.Printf("Reorg detection failed: %v", err)
+			}
+			stuck, err := sd.ListStuck()
+			if err != nil {
+				log.Printf("Stuck transaction detection failed: %v", err)
+				continue
+			}
+			if len(stuck) > 0 {
+				log.Printf("%d transactions have no receipt after %s", len(stuck), interval)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// replace signs and broadcasts a replacement for a pending transfer, either the same transfer
+// with a higher fee or a cancellation
+func (sd service) replace(transactionID string, req ReplacementRequest, cancel bool) (ReplacementResponse, error) {
+	transfer, err := sd.transactionRepo.GetPendingTransfer(transactionID)
+	if err != nil {
+		return ReplacementResponse{}, err
+	}
+	if transfer.Status.IsFinal() {
+		return ReplacementResponse{}, utils.Conflictf("transaction is already %s", transfer.Status)
+	}
+	if transfer.CancelRequested && !cancel {
+		return ReplacementResponse{}, utils.Conflict("transaction is being cancelled")
+	}
+	if transfer.Nonce == nil || transfer.GasPrice == "" {
+		return ReplacementResponse{}, utils.Validation("transaction was recorded without its nonce and cannot be replaced")
+	}
+
+	// The transfer may have been mined since it was listed
+	settled, err := sd.settle(transfer)
+	if err != nil {
+		return ReplacementResponse{}, utils.Upstream("failed to check transaction receipt", err)
+	}
+	if settled {
+		return ReplacementResponse{}, utils.Conflict("transaction has already been mined")
+	}
+
+	gasPrice, err := replacementGasPrice(transfer.GasPrice, req.GasPriceWei)
+	if err != nil {
+		return ReplacementResponse{}, err
+	}
+
+	privateKeyHex, err := sd.walletRepo.RetrievePrivateKey(transfer.SenderUserID, "")
+	if err != nil {
+		return ReplacementResponse{}, fmt.Errorf("error retrieving private key: %w", err)
+	}
+
+	to, amount := transfer.ReceiverWalletID, new(big.Int)
+	if cancel {
+		to = transfer.SenderWalletID
+	} else if _, ok := amount.SetString(transfer.Amount, 10); !ok {
+		return ReplacementResponse{}, fmt.Errorf("invalid stored amount %q", transfer.Amount)
+	}
+
+	signedTx, err := sd.ethRepo.SignWithNonce(privateKeyHex, to, amount, gasPrice, ethereum.TransferGasLimit, uint64(*transfer.Nonce), ethereum.ChainID)
+	if err != nil {
+		return ReplacementResponse{}, utils.Upstream("failed to sign replacement transaction", err)
+	}
+	if err := sd.ethRepo.SendTransaction(signedTx); err != nil {
+		return ReplacementResponse{}, utils.Upstream("failed to broadcast replacement transaction", err)
+	}
+
+	newTxHash := signedTx.Hash().Hex()
+	if err := sd.transactionRepo.ReplaceBroadcast(transfer.TransactionID, transfer.TxHash, newTxHash, gasPrice.String(), cancel); err != nil {
+		return ReplacementResponse{}, err
+	}
+
+	log.Printf("Replaced transaction %s: %s -> %s at %s wei gas price (cancel: %t)", transfer.TransactionID, transfer.TxHash, newTxHash, gasPrice, cancel)
+	return ReplacementResponse{
+		TransactionID:   transfer.TransactionID,
+		ReplacedTxHash:  transfer.TxHash,
+		TxHash:          newTxHash,
+		GasPriceWei:     gasPrice.String(),
+		CancelRequested: cancel || transfer.CancelRequested,
+	}, nil
+}
+
+// settle looks for a receipt of the current broadcast or any broadcast it replaced and records
+// the final status once the transfer has its required confirmations, it reports whether the
+// transfer was mined
+func (sd service) settle(transfer repo.PendingTransfer) (bool, error) {
+	receipt, err := sd.receipt(transfer.TxHash)
+	if err != nil {
+		return false, err
+	}
+	if receipt != nil {
+		status := receiptStatus(receipt)
+		// A replacement marked as cancel is the zero value transaction, not the transfer
+		if transfer.CancelRequested && receipt.Status == types.ReceiptStatusSuccessful {
+			status = domain.TransactionCancelled
+		}
+		return true, sd.complete(transfer, receipt, status)
+	}
+
+	for _, txHash := range transfer.PreviousTxHashes {
+		receipt, err := sd.receipt(txHash)
+		if err != nil {
+			return false, err
+		}
+		if receipt != nil {
+			return true, sd.complete(transfer, receipt, receiptStatus(receipt))
+		}
+	}
+	return false, nil
+}
+
+// complete records the final status of a mined transfer, or only its block while it has fewer
+// confirmations than its policy requires
+func (sd service) complete(transfer repo.PendingTransfer, receipt *types.Receipt, status domain.TransactionStatus) error {
+	blockNumber, blockHash := receipt.BlockNumber.Uint64(), receipt.BlockHash.Hex()
+
+	if transfer.RequiredConfirmations > 1 {
+		head, err := sd.ethRepo.GetBlockNumber()
+		if err != nil {
+			return err
+		}
+		if confirmations(head, blockNumber) < uint64(transfer.RequiredConfirmations) {
+			return sd.transactionRepo.RecordInclusion(transfer.TransactionID, blockNumber, blockHash)
+		}
+	}
+	return sd.transactionRepo.SettleTransaction(transfer.TransactionID, status, blockNumber, blockHash)
+}
+
+// confirmations counts the block of a transaction and every block mined on top of it
+func confirmations(head, blockNumber uint64) uint64 {
+	if head < blockNumber {
+		return 0
+	}
+	return head - blockNumber + 1
+}
+
+// receipt returns the receipt of txHash, or nil while it is not mined
+func (sd service) receipt(txHash string) (*types.Receipt, error) {
+	receipt, err := sd.ethRepo.GetReceipt(txHash)
+	if errors.Is(err, goethereum.NotFound) {
+		return nil, nil
+	}
+	return receipt, err
+}
+
+// receiptStatus maps a receipt to the final status of the transfer
+func receiptStatus(receipt *types.Receipt) domain.TransactionStatus {
+	if receipt.Status == types.ReceiptStatusSuccessful {
+		return domain.TransactionConfirmed
+	}
+	return domain.TransactionFailed
+}
+
+// replacementGasPrice returns the requested gas price, or a default bump of the current one,
+// rejecting anything the node would refuse as underpriced
+func replacementGasPrice(currentWei, requestedWei string) (*big.Int, error) {
+	current, ok := new(big.Int).SetString(currentWei, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid stored gas price %q", currentWei)
+	}
+	minimum := percentOf(current, minGasBumpPercent)
+
+	if requestedWei == "" {
+		return percentOf(current, defaultGasBumpPercent), nil
+	}
+
+	requested, ok := new(big.Int).SetString(requestedWei, 10)
+	if !ok || requested.Sign() <= 0 {
+		return nil, utils.Validation("gas_price_wei must be a positive integer")
+	}
+	if requested.Cmp(minimum) < 0 {
+		return nil, utils.Validationf("gas_price_wei must be at least %s", minimum)
+	}
+	return requested, nil
+}
+
+// percentOf returns value * percent / 100, rounded up
+func percentOf(value *big.Int, percent int64) *big.Int {
+	result := new(big.Int).Mul(value, big.NewInt(percent))
+	result.Add(result, big.NewInt(99))
+	return result.Div(result, big.NewInt(100))
+}
+
+// stuckAfter is how long a broadcast may go without a receipt before it counts as stuck
+func stuckAfter() time.Duration {
+	return time.Duration(config.ConfigDetails.StuckTransactionMinutes) * time.Minute
+}
@@ -0,0 +1,137 @@
+package reversals
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/respond"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+	"github.com/gorilla/mux"
+)
+
+// ReversalRequest asks for the reversal of a transfer, amount_wei defaults to the whole transfer
+type ReversalRequest struct {
+	ReasonCode string `json:"reason_code"`
+	Note       string `json:"note"`
+	AmountWei  string `json:"amount_wei"`
+}
+
+// ReversalResponse represents a reversal, reversal_transaction_id is the compensating transfer
+// from the original receiver back to the original sender
+type ReversalResponse struct {
+	ReversalID            string     `json:"reversal_id"`
+	OriginalTransactionID string     `json:"original_transaction_id"`
+	ReversalTransactionID string     `json:"reversal_transaction_id,omitempty"`
+	SenderUserID          string     `json:"sender_user_id"`
+	ReceiverUserID        string     `json:"receiver_user_id"`
+	AmountWei             string     `json:"amount_wei"`
+	ReasonCode            string     `json:"reason_code"`
+	Note                  string     `json:"note,omitempty"`
+	Status                string     `json:"status"`
+	RequestedBy           string     `json:"requested_by"`
+	DecidedBy             string     `json:"decided_by,omitempty"`
+	TxHash                string     `json:"tx_hash,omitempty"`
+	Error                 string     `json:"error,omitempty"`
+	CreatedAt             time.Time  `json:"created_at"`
+	DecidedAt             *time.Time `json:"decided_at,omitempty"`
+}
+
+type Handler struct {
+	service Service
+}
+
+// Constructor function
+func NewHandler(service Service) Handler {
+	return Handler{service: service}
+}
+
+// RequestReversalHandler requests the reversal of a transfer, needs reversal.manage
+func (hd Handler) RequestReversalHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req ReversalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	reversal, err := hd.service.RequestReversal(userInfo.UserID, mux.Vars(r)["transactionID"], req)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, reversal)
+}
+
+// ListTransactionReversalsHandler lists the reversals of a transfer, needs reversal.manage
+func (hd Handler) ListTransactionReversalsHandler(w http.ResponseWriter, r *http.Request) {
+	reversals, err := hd.service.ListTransactionReversals(mux.Vars(r)["transactionID"])
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.List(w, r, reversals, respond.Pagination{Count: len(reversals)})
+}
+
+// ListReversalsHandler lists the latest reversals, filtered by the status query parameter, needs reversal.manage
+func (hd Handler) ListReversalsHandler(w http.ResponseWriter, r *http.Request) {
+	reversals, err := hd.service.ListReversals(r.URL.Query().Get("status"))
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.List(w, r, reversals, respond.Pagination{Count: len(reversals)})
+}
+
+// GetReversalHandler returns a reversal, needs reversal.manage
+func (hd Handler) GetReversalHandler(w http.ResponseWriter, r *http.Request) {
+	reversal, err := hd.service.GetReversal(mux.Vars(r)["reversalID"])
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, reversal)
+}
+
+// ApproveReversalHandler approves and executes a reversal requested by another admin, needs reversal.manage
+func (hd Handler) ApproveReversalHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	reversal, err := hd.service.ApproveReversal(userInfo.UserID, mux.Vars(r)["reversalID"])
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, reversal)
+}
+
+// RejectReversalHandler rejects a reversal waiting for approval, needs reversal.manage and not the one who requested it
+func (hd Handler) RejectReversalHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	reversal, err := hd.service.RejectReversal(userInfo.UserID, mux.Vars(r)["reversalID"])
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, reversal)
+}
@@ -0,0 +1,318 @@
+package reversals
+
+import (
+	"fmt"
+	"log"
+	"math/big"
+	"slices"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/ethereum"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/notification"
+	"github.com/CodeWithKrushnal/ChainBank/internal/config"
+	"github.com/CodeWithKrushnal/ChainBank/internal/domain"
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+// Reversal reason codes, every reversal needs one
+const (
+	ReasonDuplicatePayment = "duplicate_payment"
+	ReasonWrongRecipient   = "wrong_recipient"
+	ReasonWrongAmount      = "wrong_amount"
+	ReasonFraud            = "fraud"
+	ReasonSystemError      = "system_error"
+)
+
+var reasonCodes = []string{ReasonDuplicatePayment, ReasonWrongRecipient, ReasonWrongAmount, ReasonFraud, ReasonSystemError}
+
+// Reversal statuses, kept in sync with the transfer_reversals status check
+const (
+	statusPending   = "pending_approval"
+	statusExecuting = "executing"
+	statusExecuted  = "executed"
+	statusRejected  = "rejected"
+	statusFailed    = "failed"
+)
+
+var reversalStatuses = []string{statusPending, statusExecuting, statusExecuted, statusRejected, statusFailed}
+
+const reversalListLimit = 100
+
+type service struct {
+	reversalRepo    repo.ReversalStorer
+	transactionRepo repo.TransactionStorer
+	walletRepo      repo.WalletStorer
+	ethRepo         ethereum.EthRepo
+	notifications   notification.Service
+}
+
+type Service interface {
+	RequestReversal(adminID, transactionID string, req ReversalRequest) (ReversalResponse, error)
+	GetReversal(reversalID string) (ReversalResponse, error)
+	ListReversals(status string) ([]ReversalResponse, error)
+	ListTransactionReversals(transactionID string) ([]ReversalResponse, error)
+	ApproveReversal(adminID, reversalID string) (ReversalResponse, error)
+	RejectReversal(adminID, reversalID string) (ReversalResponse, error)
+}
+
+// Constructor function
+func NewService(reversalRepo repo.ReversalStorer, transactionRepo repo.TransactionStorer, walletRepo repo.WalletStorer, ethRepo ethereum.EthRepo,
+	notificationService notification.Service) Service {
+	return service{
+		reversalRepo:    reversalRepo,
+		transactionRepo: transactionRepo,
+		walletRepo:      walletRepo,
+		ethRepo:         ethRepo,
+		notifications:   notificationService,
+	}
+}
+
+// RequestReversal records a reversal of a confirmed transfer waiting for a second admin to approve
+// it. The whole amount is returned unless a smaller amount_wei is given.
+func (sd service) RequestReversal(adminID, transactionID string, req ReversalRequest) (ReversalResponse, error) {
+	if !slices.Contains(reasonCodes, req.ReasonCode) {
+		return ReversalResponse{}, utils.Validationf("reason_code must be one of %s", strings.Join(reasonCodes, ", "))
+	}
+
+	transfer, err := sd.transactionRepo.GetPendingTransfer(transactionID)
+	if err != nil {
+		return ReversalResponse{}, err
+	}
+	if transfer.Status != domain.TransactionConfirmed {
+		return ReversalResponse{}, utils.Conflictf("only confirmed transfers can be reversed, this one is %s", transfer.Status)
+	}
+
+	amount, _ := new(big.Int).SetString(transfer.Amount, 10)
+	if req.AmountWei != "" {
+		requested, ok := new(big.Int).SetString(req.AmountWei, 10)
+		if !ok || requested.Sign() <= 0 || requested.Cmp(amount) > 0 {
+			return ReversalResponse{}, utils.Validation("amount_wei must be a positive integer no larger than the transfer amount")
+		}
+		amount = requested
+	}
+
+	reversal, err := sd.reversalRepo.CreateReversal(repo.TransferReversal{
+		OriginalTransactionID: transfer.TransactionID,
+		SenderUserID:          transfer.SenderUserID,
+		ReceiverUserID:        transfer.ReceiverUserID,
+		SenderWalletID:        transfer.SenderWalletID,
+		ReceiverWalletID:      transfer.ReceiverWalletID,
+		Amount:                amount.String(),
+		ReasonCode:            req.ReasonCode,
+		Note:                  strings.TrimSpace(req.Note),
+		RequestedBy:           adminID,
+	})
+	if err != nil {
+		return ReversalResponse{}, err
+	}
+
+	log.Printf("Admin %s requested reversal %s of transaction %s (%s)", adminID, reversal.ReversalID, transactionID, req.ReasonCode)
+	return newReversalResponse(reversal), nil
+}
+
+// GetReversal returns a reversal
+func (sd service) GetReversal(reversalID string) (ReversalResponse, error) {
+	reversal, err := sd.reversalRepo.GetReversal(reversalID)
+	if err != nil {
+		return ReversalResponse{}, err
+	}
+	return newReversalResponse(reversal), nil
+}
+
+// ListReversals returns the latest reversals, optionally only those with the given status
+func (sd service) ListReversals(status string) ([]ReversalResponse, error) {
+	if status != "" && !slices.Contains(reversalStatuses, status) {
+		return nil, utils.Validation("unknown reversal status")
+	}
+
+	reversals, err := sd.reversalRepo.GetReversals(status, reversalListLimit)
+	if err != nil {
+		return nil, err
+	}
+	return newReversalResponses(reversals), nil
+}
+
+// ListTransactionReversals returns every reversal of a transfer
+func (sd service) ListTransactionReversals(transactionID string) ([]ReversalResponse, error) {
+	reversals, err := sd.reversalRepo.GetTransactionReversals(transactionID)
+	if err != nil {
+		return nil, err
+	}
+	return newReversalResponses(reversals), nil
+}
+
+// ApproveReversal returns the amount from the receiver's wallet to the sender's and records the
+// return as the compensating transaction. The admin who requested a reversal cannot approve it.
+// A receiver wallet that no longer holds the amount leaves the reversal waiting for approval.
+func (sd service) ApproveReversal(adminID, reversalID string) (ReversalResponse, error) {
+	reversal, err := sd.pendingReversal(reversalID)
+	if err != nil {
+		return ReversalResponse{}, err
+	}
+	if reversal.RequestedBy == adminID {
+		return ReversalResponse{}, utils.Forbidden("a reversal cannot be approved by the admin who requested it")
+	}
+
+	amount, _ := new(big.Int).SetString(reversal.Amount, 10)
+	balance, err := sd.ethRepo.GetBalance(reversal.ReceiverWalletID)
+	if err != nil {
+		return ReversalResponse{}, utils.Upstream("failed to fetch the receiver balance", err)
+	}
+	gasCost := new(big.Int).Mul(ethereum.DefaultGasPrice, new(big.Int).SetUint64(ethereum.TransferGasLimit))
+	if balance.Cmp(new(big.Int).Add(amount, gasCost)) < 0 {
+		return ReversalResponse{}, utils.Conflict("the receiver wallet cannot cover the returned amount and its gas")
+	}
+
+	// Claims the reversal so that concurrent approvals send it once
+	claimed, err := sd.reversalRepo.UpdateReversalStatus(reversalID, statusPending, statusExecuting, adminID, "")
+	if err != nil {
+		return ReversalResponse{}, err
+	}
+	if !claimed {
+		return ReversalResponse{}, utils.Conflict("reversal is no longer waiting for approval")
+	}
+
+	signedTx, err := sd.sendReturn(reversal, amount)
+	if err != nil {
+		log.Printf("Reversal %s failed: %v", reversalID, err)
+		if _, updateErr := sd.reversalRepo.UpdateReversalStatus(reversalID, statusExecuting, statusFailed, "", err.Error()); updateErr != nil {
+			log.Printf("Error marking reversal %s failed: %v", reversalID, updateErr)
+		}
+		return ReversalResponse{}, err
+	}
+
+	nonce := int64(signedTx.Nonce())
+	completed, err := sd.reversalRepo.CompleteReversal(reversalID, repo.Transaction{
+		TxHash:                signedTx.Hash().Hex(),
+		SenderUserID:          reversal.ReceiverUserID,
+		ReceiverUserID:        reversal.SenderUserID,
+		SenderWalletID:        reversal.ReceiverWalletID,
+		ReceiverWalletID:      reversal.SenderWalletID,
+		Amount:                reversal.Amount,
+		Status:                domain.TransactionPending,
+		Nonce:                 &nonce,
+		GasPrice:              signedTx.GasPrice().String(),
+		RequiredConfirmations: config.TransferConfirmations(amount),
+	})
+	if err != nil {
+		// The return is already broadcast, only its record is behind
+		log.Printf("Error recording transaction %s of reversal %s: %v", signedTx.Hash().Hex(), reversalID, err)
+		reversal.Status, reversal.DecidedBy, reversal.TxHash = statusExecuting, adminID, signedTx.Hash().Hex()
+		completed = reversal
+	}
+
+	log.Printf("Admin %s approved reversal %s, returned in %s", adminID, reversalID, signedTx.Hash().Hex())
+	sd.notifyReversal(completed, amount)
+	return newReversalResponse(completed), nil
+}
+
+// RejectReversal closes a reversal waiting for approval without moving funds. Like approvals,
+// rejections are left to an admin other than the one who requested the reversal.
+func (sd service) RejectReversal(adminID, reversalID string) (ReversalResponse, error) {
+	reversal, err := sd.pendingReversal(reversalID)
+	if err != nil {
+		return ReversalResponse{}, err
+	}
+	if reversal.RequestedBy == adminID {
+		return ReversalResponse{}, utils.Forbidden("a reversal cannot be rejected by the admin who requested it")
+	}
+
+	rejected, err := sd.reversalRepo.UpdateReversalStatus(reversalID, statusPending, statusRejected, adminID, "")
+	if err != nil {
+		return ReversalResponse{}, err
+	}
+	if !rejected {
+		return ReversalResponse{}, utils.Conflict("reversal is no longer waiting for approval")
+	}
+
+	log.Printf("Admin %s rejected reversal %s", adminID, reversalID)
+	return sd.GetReversal(reversalID)
+}
+
+// pendingReversal returns a reversal that is still waiting for approval
+func (sd service) pendingReversal(reversalID string) (repo.TransferReversal, error) {
+	reversal, err := sd.reversalRepo.GetReversal(reversalID)
+	if err != nil {
+		return reversal, err
+	}
+	if reversal.Status != statusPending {
+		return reversal, utils.Conflict("reversal is no longer waiting for approval")
+	}
+	return reversal, nil
+}
+
+// sendReturn signs the return transfer with the receiver's key and broadcasts it
+func (sd service) sendReturn(reversal repo.TransferReversal, amount *big.Int) (*types.Transaction, error) {
+	privateKeyHex, err := sd.walletRepo.RetrievePrivateKey(reversal.ReceiverUserID, "")
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving private key: %w", err)
+	}
+
+	signedTx, err := sd.ethRepo.TransferFunds(privateKeyHex, reversal.ReceiverWalletID, reversal.SenderWalletID, amount, ethereum.DefaultGasPrice, ethereum.TransferGasLimit, ethereum.ChainID)
+	if err != nil {
+		return nil, utils.Upstream("transaction failed", err)
+	}
+	if err := sd.ethRepo.SendTransaction(signedTx); err != nil {
+		return nil, utils.Upstream("failed to broadcast transaction", err)
+	}
+	return signedTx, nil
+}
+
+// notifyReversal tells both parties of the original transfer that it was reversed
+func (sd service) notifyReversal(reversal repo.TransferReversal, amount *big.Int) {
+	ethAmount := new(big.Float).Quo(new(big.Float).SetInt(amount), big.NewFloat(1e18)).Text('f', -1)
+	data := map[string]string{
+		"reversal_id":             reversal.ReversalID,
+		"original_transaction_id": reversal.OriginalTransactionID,
+		"transaction_hash":        reversal.TxHash,
+		"amount":                  reversal.Amount,
+	}
+
+	sd.notifications.Notify(notification.Notification{
+		UserID:   reversal.SenderUserID,
+		Category: notification.CategoryTransfer,
+		Title:    "Transfer reversed",
+		Body:     "%s ETH from a transfer you sent was returned to you.",
+		BodyArgs: []any{ethAmount},
+		Data:     data,
+	})
+	sd.notifications.Notify(notification.Notification{
+		UserID:   reversal.ReceiverUserID,
+		Category: notification.CategoryTransfer,
+		Title:    "Transfer reversed",
+		Body:     "%s ETH you received in error was returned to the sender.",
+		BodyArgs: []any{ethAmount},
+		Data:     data,
+	})
+}
+
+func newReversalResponses(reversals []repo.TransferReversal) []ReversalResponse {
+	response := make([]ReversalResponse, len(reversals))
+	for i, reversal := range reversals {
+		response[i] = newReversalResponse(reversal)
+	}
+	return response
+}
+
+func newReversalResponse(reversal repo.TransferReversal) ReversalResponse {
+	return ReversalResponse{
+		ReversalID:            reversal.ReversalID,
+		OriginalTransactionID: reversal.OriginalTransactionID,
+		ReversalTransactionID: reversal.ReversalTransactionID,
+		SenderUserID:          reversal.SenderUserID,
+		ReceiverUserID:        reversal.ReceiverUserID,
+		AmountWei:             reversal.Amount,
+		ReasonCode:            reversal.ReasonCode,
+		Note:                  reversal.Note,
+		Status:                reversal.Status,
+		RequestedBy:           reversal.RequestedBy,
+		DecidedBy:             reversal.DecidedBy,
+		TxHash:                reversal.TxHash,
+		Error:                 reversal.Error,
+		CreatedAt:             reversal.CreatedAt,
+		DecidedAt:             reversal.DecidedAt,
+	}
+}
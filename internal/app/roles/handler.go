@@ -0,0 +1,179 @@
+package roles
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/respond"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+	"github.com/gorilla/mux"
+)
+
+// CreateRoleRequest defines a custom role
+type CreateRoleRequest struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+// SetPermissionsRequest replaces the permissions of a custom role
+type SetPermissionsRequest struct {
+	Permissions []string `json:"permissions"`
+}
+
+// AssignRoleRequest gives a custom role to a user
+type AssignRoleRequest struct {
+	RoleID int `json:"role_id"`
+}
+
+// RoleResponse represents a role and the permissions it grants. The built-in admin role grants
+// every permission.
+type RoleResponse struct {
+	RoleID      int       `json:"role_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	BuiltIn     bool      `json:"built_in"`
+	Permissions []string  `json:"permissions"`
+	CreatedBy   string    `json:"created_by,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type Handler struct {
+	service Service
+}
+
+// Constructor function
+func NewHandler(service Service) Handler {
+	return Handler{service: service}
+}
+
+// ListPermissionsHandler lists the permissions roles can grant
+func (hd Handler) ListPermissionsHandler(w http.ResponseWriter, r *http.Request) {
+	permissions := hd.service.ListPermissions()
+	respond.List(w, r, permissions, respond.Pagination{Count: len(permissions)})
+}
+
+// ListRolesHandler lists the built-in and custom roles
+func (hd Handler) ListRolesHandler(w http.ResponseWriter, r *http.Request) {
+	roles, err := hd.service.ListRoles()
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.List(w, r, roles, respond.Pagination{Count: len(roles)})
+}
+
+// CreateRoleHandler defines a custom role
+func (hd Handler) CreateRoleHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req CreateRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	role, err := hd.service.CreateRole(userInfo.UserID, req)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, role)
+}
+
+// SetPermissionsHandler replaces the permissions of a custom role
+func (hd Handler) SetPermissionsHandler(w http.ResponseWriter, r *http.Request) {
+	roleID, ok := roleIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req SetPermissionsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	role, err := hd.service.SetPermissions(roleID, req)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, role)
+}
+
+// DeleteRoleHandler deletes a custom role
+func (hd Handler) DeleteRoleHandler(w http.ResponseWriter, r *http.Request) {
+	roleID, ok := roleIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	if err := hd.service.DeleteRole(roleID); err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AssignRoleHandler gives a custom role to a user
+func (hd Handler) AssignRoleHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req AssignRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := hd.service.AssignRole(userInfo.UserID, mux.Vars(r)["userID"], req); err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UnassignRoleHandler takes a custom role away from a user
+func (hd Handler) UnassignRoleHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+	roleID, ok := roleIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	if err := hd.service.UnassignRole(userInfo.UserID, mux.Vars(r)["userID"], roleID); err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// roleIDParam parses the roleID path variable, or writes an error response and returns false
+func roleIDParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	roleID, err := strconv.Atoi(mux.Vars(r)["roleID"])
+	if err != nil {
+		http.Error(w, "Invalid role ID", http.StatusBadRequest)
+		return 0, false
+	}
+	return roleID, true
+}
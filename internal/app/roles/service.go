@@ -0,0 +1,200 @@
+package roles
+
+import (
+	"log"
+	"regexp"
+	"slices"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/domain"
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+const maxDescriptionLength = 255
+
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_.-]{1,63}$`)
+
+type service struct {
+	roleRepo repo.RoleStorer
+	userRepo repo.UserStorer
+}
+
+type Service interface {
+	ListPermissions() []string
+	ListRoles() ([]RoleResponse, error)
+	CreateRole(adminID string, req CreateRoleRequest) (RoleResponse, error)
+	SetPermissions(roleID int, req SetPermissionsRequest) (RoleResponse, error)
+	DeleteRole(roleID int) error
+	AssignRole(adminID, userID string, req AssignRoleRequest) error
+	UnassignRole(adminID, userID string, roleID int) error
+}
+
+// Constructor function
+func NewService(roleRepo repo.RoleStorer, userRepo repo.UserStorer) Service {
+	return service{
+		roleRepo: roleRepo,
+		userRepo: userRepo,
+	}
+}
+
+// ListPermissions returns every permission roles can grant
+func (sd service) ListPermissions() []string {
+	return domain.Permissions
+}
+
+// ListRoles returns the built-in and custom roles with their permissions
+func (sd service) ListRoles() ([]RoleResponse, error) {
+	roles, err := sd.roleRepo.GetRoles()
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]RoleResponse, len(roles))
+	for i, role := range roles {
+		responses[i] = newRoleResponse(role)
+	}
+	return responses, nil
+}
+
+// CreateRole defines a custom role granting the given permissions
+func (sd service) CreateRole(adminID string, req CreateRoleRequest) (RoleResponse, error) {
+	if !roleNamePattern.MatchString(req.Name) {
+		return RoleResponse{}, utils.Validation("name must be 2 to 64 lowercase letters, digits, dots, hyphens or underscores, starting with a letter")
+	}
+	if len(req.Description) > maxDescriptionLength {
+		return RoleResponse{}, utils.Validationf("description must be at most %d characters", maxDescriptionLength)
+	}
+	permissions, err := checkPermissions(req.Permissions)
+	if err != nil {
+		return RoleResponse{}, err
+	}
+
+	role, err := sd.roleRepo.CreateRole(repo.Role{
+		Name:        req.Name,
+		Description: req.Description,
+		Permissions: permissions,
+		CreatedBy:   adminID,
+	})
+	if err != nil {
+		return RoleResponse{}, err
+	}
+	log.Printf("Role %s (%d) created by %s with %v", role.Name, role.RoleID, adminID, role.Permissions)
+	return newRoleResponse(role), nil
+}
+
+// SetPermissions replaces the permissions of a custom role, its users gain and lose them on their next request
+func (sd service) SetPermissions(roleID int, req SetPermissionsRequest) (RoleResponse, error) {
+	if _, err := sd.customRole(roleID); err != nil {
+		return RoleResponse{}, err
+	}
+	permissions, err := checkPermissions(req.Permissions)
+	if err != nil {
+		return RoleResponse{}, err
+	}
+
+	role, err := sd.roleRepo.SetRolePermissions(roleID, permissions)
+	if err != nil {
+		return RoleResponse{}, err
+	}
+	log.Printf("Permissions of role %s (%d) set to %v", role.Name, roleID, role.Permissions)
+	return newRoleResponse(role), nil
+}
+
+// DeleteRole removes a custom role from every user holding it and deletes it
+func (sd service) DeleteRole(roleID int) error {
+	role, err := sd.customRole(roleID)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := sd.roleRepo.DeleteRole(roleID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return utils.NotFound("role not found", nil)
+	}
+	log.Printf("Role %s (%d) deleted", role.Name, roleID)
+	return nil
+}
+
+// AssignRole gives a custom role to a user. Built-in roles are chosen at signup and are not
+// handed out here.
+func (sd service) AssignRole(adminID, userID string, req AssignRoleRequest) error {
+	role, err := sd.customRole(req.RoleID)
+	if err != nil {
+		return err
+	}
+	if _, err := sd.userRepo.GetUserByID(userID); err != nil {
+		return err
+	}
+
+	if err := sd.roleRepo.AssignRole(userID, role.RoleID); err != nil {
+		return err
+	}
+	log.Printf("Role %s (%d) assigned to %s by %s", role.Name, role.RoleID, userID, adminID)
+	return nil
+}
+
+// UnassignRole takes a custom role away from a user
+func (sd service) UnassignRole(adminID, userID string, roleID int) error {
+	role, err := sd.customRole(roleID)
+	if err != nil {
+		return err
+	}
+
+	removed, err := sd.roleRepo.UnassignRole(userID, roleID)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return utils.NotFound("user does not hold this role", nil)
+	}
+	log.Printf("Role %s (%d) taken from %s by %s", role.Name, roleID, userID, adminID)
+	return nil
+}
+
+// customRole returns a role that may be changed, built-in roles are refused
+func (sd service) customRole(roleID int) (repo.Role, error) {
+	role, err := sd.roleRepo.GetRole(roleID)
+	if err != nil {
+		return role, err
+	}
+	if role.BuiltIn {
+		return role, utils.Forbidden("built-in roles cannot be changed or assigned")
+	}
+	return role, nil
+}
+
+// checkPermissions rejects unknown permissions and drops duplicates
+func checkPermissions(permissions []string) ([]string, error) {
+	checked := []string{}
+	for _, permission := range permissions {
+		if !slices.Contains(domain.Permissions, permission) {
+			return nil, utils.Validationf("unknown permission %q", permission)
+		}
+		if !slices.Contains(checked, permission) {
+			checked = append(checked, permission)
+		}
+	}
+	return checked, nil
+}
+
+func newRoleResponse(role repo.Role) RoleResponse {
+	permissions := role.Permissions
+	if role.RoleID == domain.RoleAdmin {
+		permissions = domain.Permissions
+	}
+	if permissions == nil {
+		permissions = []string{}
+	}
+	return RoleResponse{
+		RoleID:      role.RoleID,
+		Name:        role.Name,
+		Description: role.Description,
+		BuiltIn:     role.BuiltIn,
+		Permissions: permissions,
+		CreatedBy:   role.CreatedBy,
+		CreatedAt:   role.CreatedAt,
+	}
+}
@@ -3,30 +3,268 @@ package app
 import (
 	"net/http"
 
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/activity"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/apikeys"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/archive"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/balancealerts"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/balancehistory"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/banklinks"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/bills"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/budgets"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/calendar"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/confirmation"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/delegations"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/deltasync"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/exports"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/externalwallets"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/features"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/hdwallets"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/insights"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/invoices"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/jobs"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/keyrotation"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/merchants"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/notification"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/offramp"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/onramp"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/organizations"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/paymentrequests"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/piibackfill"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/publicstats"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/recovery"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/reversals"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/roles"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/settings"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/siwe"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/sweeps"
 	"github.com/CodeWithKrushnal/ChainBank/internal/app/user"
 	"github.com/CodeWithKrushnal/ChainBank/internal/app/wallet"
+	"github.com/CodeWithKrushnal/ChainBank/internal/domain"
 	"github.com/CodeWithKrushnal/ChainBank/middleware"
 	"github.com/gorilla/mux"
 )
 
 func SetupRoutes(deps *Dependencies) *mux.Router {
 	router := mux.NewRouter()
+	router.Use(middleware.RequestIDMiddleware)
+	router.Use(middleware.CompressionMiddleware)
+	router.Use(middleware.LocaleMiddleware)
+	router.Use(middleware.RateLimitMiddleware(deps.RateLimiter))
 	// Inject dependencies into handlers
-	userHandler := user.NewHandler(deps.UserService)
+	userHandler := user.NewHandler(deps.UserService, deps.CaptchaService)
 	walletHandler := wallet.NewHandler(deps.WalletService)
 	middlewareHandler := middleware.NewHandler(deps.MiddlewareService)
+	archiveHandler := archive.NewHandler(deps.ArchiveService)
+	settingsHandler := settings.NewHandler(deps.SettingsService)
+	featureHandler := features.NewHandler(deps.FeatureService)
+	calendarHandler := calendar.NewHandler(deps.CalendarService)
+	notificationHandler := notification.NewHandler(deps.NotificationService)
+	recoveryHandler := recovery.NewHandler(deps.RecoveryService)
+	apiKeyHandler := apikeys.NewHandler(deps.APIKeyService)
+	keyRotationHandler := keyrotation.NewHandler(deps.KeyRotationService)
+	piiBackfillHandler := piibackfill.NewHandler(deps.PIIBackfillService)
+	hdWalletHandler := hdwallets.NewHandler(deps.HDWalletService)
+	balanceHistoryHandler := balancehistory.NewHandler(deps.BalanceHistoryService)
+	insightsHandler := insights.NewHandler(deps.InsightsService)
+	budgetHandler := budgets.NewHandler(deps.BudgetService)
+	balanceAlertHandler := balancealerts.NewHandler(deps.BalanceAlertService)
+	organizationHandler := organizations.NewHandler(deps.OrganizationService)
+	delegationHandler := delegations.NewHandler(deps.DelegationService)
+	publicStatsHandler := publicstats.NewHandler(deps.PublicStatsService)
+	confirmationHandler := confirmation.NewHandler(deps.ConfirmationService)
+	externalWalletHandler := externalwallets.NewHandler(deps.ExternalWalletService)
+	siweHandler := siwe.NewHandler(deps.SIWEService)
+	paymentRequestHandler := paymentrequests.NewHandler(deps.PaymentRequestService)
+	billHandler := bills.NewHandler(deps.BillService)
+	merchantHandler := merchants.NewHandler(deps.MerchantService)
+	invoiceHandler := invoices.NewHandler(deps.InvoiceService)
+	sweepHandler := sweeps.NewHandler(deps.SweepService)
+	reversalHandler := reversals.NewHandler(deps.ReversalService)
+	onrampHandler := onramp.NewHandler(deps.OnrampService)
+	offrampHandler := offramp.NewHandler(deps.OfframpService)
+	bankLinkHandler := banklinks.NewHandler(deps.BankLinkService)
+	exportHandler := exports.NewHandler(deps.ExportService)
+	activityHandler := activity.NewHandler(deps.ActivityService)
+	roleHandler := roles.NewHandler(deps.RoleService)
+	syncHandler := deltasync.NewHandler(deps.SyncService)
+	jobHandler := jobs.NewHandler(deps.JobService)
 
 	//Signup Endpoint
 	router.HandleFunc("/signup", userHandler.SignupHandler).Methods(http.MethodPost)
 	//SignIn Endpoint
 	router.HandleFunc("/signin", userHandler.SignInHandler).Methods(http.MethodPost)
+	//SSO Endpoints
+	router.HandleFunc("/auth/oidc/login", userHandler.OIDCLoginHandler).Methods(http.MethodGet)
+	router.HandleFunc("/auth/oidc/callback", userHandler.OIDCCallbackHandler).Methods(http.MethodGet)
+	//Sign-In with Ethereum Endpoints, unauthenticated so rate limited like the public routes
+	siweRoutes := router.PathPrefix("/auth/siwe").Subrouter()
+	siweRoutes.Use(middleware.RateLimitMiddleware(deps.PublicRateLimiter))
+	siweRoutes.HandleFunc("/nonce", siweHandler.NonceHandler).Methods(http.MethodPost)
+	siweRoutes.HandleFunc("/verify", siweHandler.LoginHandler).Methods(http.MethodPost)
+	//"This wasn't me" link of login alerts, password reset and token refresh, unauthenticated so rate limited like the public routes
+	publicRateLimit := middleware.RateLimitMiddleware(deps.PublicRateLimiter)
+	router.Handle("/auth/logins/{eventID}/disown", publicRateLimit(http.HandlerFunc(userHandler.DisownLoginHandler))).Methods(http.MethodGet)
+	router.Handle("/auth/password-reset", publicRateLimit(http.HandlerFunc(userHandler.ResetPasswordHandler))).Methods(http.MethodPost)
+	router.Handle("/auth/refresh", publicRateLimit(http.HandlerFunc(userHandler.RefreshTokensHandler))).Methods(http.MethodPost)
+
+	// Public routes, unauthenticated and rate limited more tightly
+	publicRoutes := router.PathPrefix("/public").Subrouter()
+	publicRoutes.Use(middleware.RateLimitMiddleware(deps.PublicRateLimiter))
+	publicRoutes.HandleFunc("/stats", publicStatsHandler.GetStatsHandler).Methods(http.MethodGet)
+
+	// Export downloads, authenticated by the signed link instead of a token
+	exportRoutes := router.PathPrefix("/exports").Subrouter()
+	exportRoutes.Use(middleware.RateLimitMiddleware(deps.PublicRateLimiter))
+	exportRoutes.HandleFunc("/{jobID}", exportHandler.DownloadHandler).Methods(http.MethodGet)
+
+	// Fiat on-ramp deposit events, authenticated by the provider's signature instead of a token
+	router.HandleFunc("/webhooks/fiat-onramp", onrampHandler.WebhookHandler).Methods(http.MethodPost)
 
 	// Protected routes (Require authentication)
 	protectedRoutes := router.PathPrefix("/api").Subrouter()
 	protectedRoutes.Use(middleware.AuthMiddleware(middlewareHandler))
 
+	protectedRoutes.HandleFunc("/me", userHandler.GetMeHandler).Methods(http.MethodGet)
+	protectedRoutes.HandleFunc("/me", userHandler.CloseAccountHandler).Methods(http.MethodDelete)
+	protectedRoutes.HandleFunc("/me/time-zone", userHandler.GetTimeZoneHandler).Methods(http.MethodGet)
+	protectedRoutes.HandleFunc("/me/time-zone", userHandler.SetTimeZoneHandler).Methods(http.MethodPut)
+	protectedRoutes.HandleFunc("/me/devices", notificationHandler.RegisterDeviceHandler).Methods(http.MethodPost)
+	protectedRoutes.HandleFunc("/me/devices/{token}", notificationHandler.UnregisterDeviceHandler).Methods(http.MethodDelete)
+	protectedRoutes.HandleFunc("/me/notification-preferences", notificationHandler.GetPreferencesHandler).Methods(http.MethodGet)
+	protectedRoutes.HandleFunc("/me/notification-preferences", notificationHandler.UpdatePreferencesHandler).Methods(http.MethodPut)
+	protectedRoutes.HandleFunc("/me/budget", budgetHandler.GetBudgetHandler).Methods(http.MethodGet)
+	protectedRoutes.HandleFunc("/me/budget", budgetHandler.SetBudgetHandler).Methods(http.MethodPut)
+	protectedRoutes.HandleFunc("/me/budget", budgetHandler.DeleteBudgetHandler).Methods(http.MethodDelete)
+	protectedRoutes.HandleFunc("/me/balance-alerts", balanceAlertHandler.GetAlertsHandler).Methods(http.MethodGet)
+	protectedRoutes.HandleFunc("/me/balance-alerts", balanceAlertHandler.UpdateAlertsHandler).Methods(http.MethodPut)
+	protectedRoutes.HandleFunc("/me/api-keys", apiKeyHandler.CreateKeyHandler).Methods(http.MethodPost)
+	protectedRoutes.HandleFunc("/me/api-keys", apiKeyHandler.ListKeysHandler).Methods(http.MethodGet)
+	protectedRoutes.HandleFunc("/me/api-keys/{keyID}", apiKeyHandler.RevokeKeyHandler).Methods(http.MethodDelete)
+	protectedRoutes.HandleFunc("/me/delegations", delegationHandler.GrantDelegationHandler).Methods(http.MethodPost)
+	protectedRoutes.HandleFunc("/me/delegations", delegationHandler.ListGrantedHandler).Methods(http.MethodGet)
+	protectedRoutes.HandleFunc("/me/delegations/received", delegationHandler.ListReceivedHandler).Methods(http.MethodGet)
+	protectedRoutes.HandleFunc("/me/delegations/audit", delegationHandler.AuditLogHandler).Methods(http.MethodGet)
+	protectedRoutes.HandleFunc("/me/delegations/{delegationID}", delegationHandler.RevokeDelegationHandler).Methods(http.MethodDelete)
+	protectedRoutes.HandleFunc("/me/transfer-confirmation", confirmationHandler.GetSettingsHandler).Methods(http.MethodGet)
+	protectedRoutes.HandleFunc("/me/transfer-confirmation", confirmationHandler.SetModeHandler).Methods(http.MethodPut)
+	protectedRoutes.HandleFunc("/me/transfer-confirmation/totp", confirmationHandler.EnrollTOTPHandler).Methods(http.MethodPost)
+	protectedRoutes.HandleFunc("/me/transfer-confirmation/totp/confirm", confirmationHandler.ConfirmTOTPHandler).Methods(http.MethodPost)
+	protectedRoutes.HandleFunc("/me/transfer-confirmation/devices", confirmationHandler.AddDeviceHandler).Methods(http.MethodPost)
+	protectedRoutes.HandleFunc("/me/transfer-confirmation/devices/{deviceID}", confirmationHandler.RevokeDeviceHandler).Methods(http.MethodDelete)
+	protectedRoutes.HandleFunc("/me/transfer-confirmation/pin", confirmationHandler.SetPINHandler).Methods(http.MethodPut)
+	protectedRoutes.HandleFunc("/me/transfer-confirmation/pin", confirmationHandler.DeletePINHandler).Methods(http.MethodDelete)
+	protectedRoutes.HandleFunc("/me/external-wallets", externalWalletHandler.ListWalletsHandler).Methods(http.MethodGet)
+	protectedRoutes.HandleFunc("/me/external-wallets", externalWalletHandler.LinkWalletHandler).Methods(http.MethodPost)
+	protectedRoutes.HandleFunc("/me/external-wallets/{address}/verify", externalWalletHandler.VerifyWalletHandler).Methods(http.MethodPost)
+	protectedRoutes.HandleFunc("/me/external-wallets/{address}", externalWalletHandler.UnlinkWalletHandler).Methods(http.MethodDelete)
+	protectedRoutes.HandleFunc("/me/portfolio", externalWalletHandler.GetPortfolioHandler).Methods(http.MethodGet)
+	protectedRoutes.HandleFunc("/me/sweep-rule", sweepHandler.GetRuleHandler).Methods(http.MethodGet)
+	protectedRoutes.HandleFunc("/me/sweep-rule", sweepHandler.SetRuleHandler).Methods(http.MethodPut)
+	protectedRoutes.HandleFunc("/me/sweep-rule", sweepHandler.DeleteRuleHandler).Methods(http.MethodDelete)
+	protectedRoutes.HandleFunc("/me/sweeps", sweepHandler.ListSweepsHandler).Methods(http.MethodGet)
+	protectedRoutes.HandleFunc("/me/bank-links/link-token", bankLinkHandler.CreateLinkTokenHandler).Methods(http.MethodPost)
+	protectedRoutes.HandleFunc("/me/bank-links", bankLinkHandler.LinkAccountHandler).Methods(http.MethodPost)
+	protectedRoutes.HandleFunc("/me/bank-links", bankLinkHandler.ListLinksHandler).Methods(http.MethodGet)
+	protectedRoutes.HandleFunc("/me/bank-links/summary", bankLinkHandler.GetSummaryHandler).Methods(http.MethodGet)
+	protectedRoutes.HandleFunc("/me/bank-links/{linkID}", bankLinkHandler.UnlinkAccountHandler).Methods(http.MethodDelete)
 	protectedRoutes.HandleFunc("/balance", walletHandler.GetBalanceHandler).Methods(http.MethodGet)
+	protectedRoutes.HandleFunc("/balance/history", balanceHistoryHandler.GetHistoryHandler).Methods(http.MethodGet)
 	protectedRoutes.HandleFunc("/transfer", walletHandler.TransferFundsHandler).Methods(http.MethodPost)
+	protectedRoutes.HandleFunc("/withdrawals/fiat", offrampHandler.RequestWithdrawalHandler).Methods(http.MethodPost)
+	protectedRoutes.HandleFunc("/withdrawals/fiat", offrampHandler.ListWithdrawalsHandler).Methods(http.MethodGet)
+	protectedRoutes.HandleFunc("/payment-requests", paymentRequestHandler.CreateRequestHandler).Methods(http.MethodPost)
+	protectedRoutes.HandleFunc("/payment-requests", paymentRequestHandler.ListRequestsHandler).Methods(http.MethodGet)
+	protectedRoutes.HandleFunc("/payment-requests/incoming", paymentRequestHandler.ListIncomingRequestsHandler).Methods(http.MethodGet)
+	protectedRoutes.HandleFunc("/payment-requests/{id}", paymentRequestHandler.GetRequestHandler).Methods(http.MethodGet)
+	protectedRoutes.HandleFunc("/payment-requests/{id}", paymentRequestHandler.CancelRequestHandler).Methods(http.MethodDelete)
+	protectedRoutes.HandleFunc("/payment-requests/{id}/pay", paymentRequestHandler.PayRequestHandler).Methods(http.MethodPost)
+	protectedRoutes.HandleFunc("/bills", billHandler.CreateBillHandler).Methods(http.MethodPost)
+	protectedRoutes.HandleFunc("/bills", billHandler.ListBillsHandler).Methods(http.MethodGet)
+	protectedRoutes.HandleFunc("/bills/{id}", billHandler.GetBillHandler).Methods(http.MethodGet)
+	protectedRoutes.HandleFunc("/bills/{id}", billHandler.CancelBillHandler).Methods(http.MethodDelete)
+	protectedRoutes.HandleFunc("/merchants", merchantHandler.RegisterMerchantHandler).Methods(http.MethodPost)
+	protectedRoutes.HandleFunc("/merchants/me", merchantHandler.GetMerchantHandler).Methods(http.MethodGet)
+	protectedRoutes.HandleFunc("/merchants/me", merchantHandler.UpdateMerchantHandler).Methods(http.MethodPut)
+	protectedRoutes.HandleFunc("/merchants/me/webhook-secret", merchantHandler.RotateWebhookSecretHandler).Methods(http.MethodPost)
+	protectedRoutes.HandleFunc("/merchants/me/webhook-events", merchantHandler.ListWebhookEventsHandler).Methods(http.MethodGet)
+	protectedRoutes.HandleFunc("/merchants/me/settlements", merchantHandler.ListSettlementsHandler).Methods(http.MethodGet)
+	protectedRoutes.HandleFunc("/merchants/me/payment-intents", merchantHandler.CreateIntentHandler).Methods(http.MethodPost)
+	protectedRoutes.HandleFunc("/merchants/me/payment-intents", merchantHandler.ListIntentsHandler).Methods(http.MethodGet)
+	protectedRoutes.HandleFunc("/merchants/me/payment-intents/{id}", merchantHandler.GetMerchantIntentHandler).Methods(http.MethodGet)
+	protectedRoutes.HandleFunc("/merchants/me/payment-intents/{id}/cancel", merchantHandler.CancelIntentHandler).Methods(http.MethodPost)
+	protectedRoutes.HandleFunc("/payment-intents/{id}", merchantHandler.GetIntentHandler).Methods(http.MethodGet)
+	protectedRoutes.HandleFunc("/payment-intents/{id}/pay", merchantHandler.PayIntentHandler).Methods(http.MethodPost)
+	protectedRoutes.HandleFunc("/invoices", invoiceHandler.CreateInvoiceHandler).Methods(http.MethodPost)
+	protectedRoutes.HandleFunc("/invoices", invoiceHandler.ListIssuedInvoicesHandler).Methods(http.MethodGet)
+	protectedRoutes.HandleFunc("/invoices/incoming", invoiceHandler.ListIncomingInvoicesHandler).Methods(http.MethodGet)
+	protectedRoutes.HandleFunc("/invoices/{id}", invoiceHandler.GetInvoiceHandler).Methods(http.MethodGet)
+	protectedRoutes.HandleFunc("/invoices/{id}", invoiceHandler.CancelInvoiceHandler).Methods(http.MethodDelete)
+	protectedRoutes.HandleFunc("/insights/monthly", insightsHandler.GetMonthlyHandler).Methods(http.MethodGet)
+	protectedRoutes.HandleFunc("/activity", activityHandler.GetFeedHandler).Methods(http.MethodGet)
+	protectedRoutes.HandleFunc("/orgs", organizationHandler.CreateOrganizationHandler).Methods(http.MethodPost)
+	protectedRoutes.HandleFunc("/orgs", organizationHandler.ListOrganizationsHandler).Methods(http.MethodGet)
+	protectedRoutes.HandleFunc("/orgs/{orgID}", organizationHandler.GetOrganizationHandler).Methods(http.MethodGet)
+	protectedRoutes.HandleFunc("/orgs/{orgID}/members", organizationHandler.ListMembersHandler).Methods(http.MethodGet)
+	protectedRoutes.HandleFunc("/orgs/{orgID}/members", organizationHandler.InviteMemberHandler).Methods(http.MethodPut)
+	protectedRoutes.HandleFunc("/orgs/{orgID}/members/{userID}", organizationHandler.RemoveMemberHandler).Methods(http.MethodDelete)
+	protectedRoutes.HandleFunc("/orgs/{orgID}/invitation", organizationHandler.AcceptInvitationHandler).Methods(http.MethodPost)
+	protectedRoutes.HandleFunc("/orgs/{orgID}/payments", organizationHandler.InitiatePaymentHandler).Methods(http.MethodPost)
+	protectedRoutes.HandleFunc("/orgs/{orgID}/payments", organizationHandler.ListPaymentsHandler).Methods(http.MethodGet)
+	protectedRoutes.HandleFunc("/orgs/{orgID}/payments/{paymentID}/approve", organizationHandler.ApprovePaymentHandler).Methods(http.MethodPost)
+	protectedRoutes.HandleFunc("/orgs/{orgID}/payments/{paymentID}/reject", organizationHandler.RejectPaymentHandler).Methods(http.MethodPost)
+	protectedRoutes.Handle("/transactions", featureHandler.Require(features.TransactionHistory, http.HandlerFunc(walletHandler.GetTransactionsHandler))).Methods(http.MethodGet)
+	protectedRoutes.Handle("/sync", featureHandler.Require(features.TransactionHistory, http.HandlerFunc(syncHandler.SyncHandler))).Methods(http.MethodGet)
+
+	// Admin routes, each needs the permission of its area. Users are shown to holders of user.manage
+	// or pii.read, the handler checks which.
+	requirePermission := func(permission string, handler http.HandlerFunc) http.Handler {
+		return middleware.RequirePermission(permission)(handler)
+	}
+	protectedRoutes.HandleFunc("/admin/users/{userID}", userHandler.GetUserHandler).Methods(http.MethodGet)
+	protectedRoutes.Handle("/admin/users/{userID}", requirePermission(domain.PermissionUserManage, userHandler.AdminCloseAccountHandler)).Methods(http.MethodDelete)
+	protectedRoutes.Handle("/admin/user-imports", requirePermission(domain.PermissionUserManage, userHandler.ImportUsersHandler)).Methods(http.MethodPost)
+	protectedRoutes.Handle("/admin/user-imports/{importID}", requirePermission(domain.PermissionUserManage, userHandler.GetImportHandler)).Methods(http.MethodGet)
+	protectedRoutes.Handle("/admin/permissions", requirePermission(domain.PermissionRoleManage, roleHandler.ListPermissionsHandler)).Methods(http.MethodGet)
+	protectedRoutes.Handle("/admin/roles", requirePermission(domain.PermissionRoleManage, roleHandler.ListRolesHandler)).Methods(http.MethodGet)
+	protectedRoutes.Handle("/admin/roles", requirePermission(domain.PermissionRoleManage, roleHandler.CreateRoleHandler)).Methods(http.MethodPost)
+	protectedRoutes.Handle("/admin/roles/{roleID}/permissions", requirePermission(domain.PermissionRoleManage, roleHandler.SetPermissionsHandler)).Methods(http.MethodPut)
+	protectedRoutes.Handle("/admin/roles/{roleID}", requirePermission(domain.PermissionRoleManage, roleHandler.DeleteRoleHandler)).Methods(http.MethodDelete)
+	protectedRoutes.Handle("/admin/users/{userID}/roles", requirePermission(domain.PermissionRoleManage, roleHandler.AssignRoleHandler)).Methods(http.MethodPost)
+	protectedRoutes.Handle("/admin/users/{userID}/roles/{roleID}", requirePermission(domain.PermissionRoleManage, roleHandler.UnassignRoleHandler)).Methods(http.MethodDelete)
+	protectedRoutes.Handle("/admin/settings", requirePermission(domain.PermissionSettingManage, settingsHandler.ListSettingsHandler)).Methods(http.MethodGet)
+	protectedRoutes.Handle("/admin/settings/{key}", requirePermission(domain.PermissionSettingManage, settingsHandler.UpdateSettingHandler)).Methods(http.MethodPut)
+	protectedRoutes.Handle("/admin/features", requirePermission(domain.PermissionFeatureManage, featureHandler.ListFlagsHandler)).Methods(http.MethodGet)
+	protectedRoutes.Handle("/admin/features/{key}", requirePermission(domain.PermissionFeatureManage, featureHandler.UpdateFlagHandler)).Methods(http.MethodPut)
+	protectedRoutes.Handle("/admin/holidays", requirePermission(domain.PermissionHolidayManage, calendarHandler.ListHolidaysHandler)).Methods(http.MethodGet)
+	protectedRoutes.Handle("/admin/holidays", requirePermission(domain.PermissionHolidayManage, calendarHandler.AddHolidayHandler)).Methods(http.MethodPost)
+	protectedRoutes.Handle("/admin/holidays/{region}/{date}", requirePermission(domain.PermissionHolidayManage, calendarHandler.DeleteHolidayHandler)).Methods(http.MethodDelete)
+	protectedRoutes.Handle("/admin/jobs", requirePermission(domain.PermissionJobManage, jobHandler.ListJobsHandler)).Methods(http.MethodGet)
+	protectedRoutes.Handle("/admin/jobs/{jobID}", requirePermission(domain.PermissionJobManage, jobHandler.GetJobHandler)).Methods(http.MethodGet)
+	protectedRoutes.Handle("/admin/jobs/{jobID}/requeue", requirePermission(domain.PermissionJobManage, jobHandler.RequeueJobHandler)).Methods(http.MethodPost)
+	protectedRoutes.Handle("/admin/archive", requirePermission(domain.PermissionArchiveManage, archiveHandler.TriggerArchivalHandler)).Methods(http.MethodPost)
+	protectedRoutes.Handle("/admin/exports", requirePermission(domain.PermissionExportManage, exportHandler.CreateExportHandler)).Methods(http.MethodPost)
+	protectedRoutes.Handle("/admin/exports", requirePermission(domain.PermissionExportManage, exportHandler.ListExportsHandler)).Methods(http.MethodGet)
+	protectedRoutes.Handle("/admin/exports/{jobID}", requirePermission(domain.PermissionExportManage, exportHandler.GetExportHandler)).Methods(http.MethodGet)
+	protectedRoutes.Handle("/admin/balance-snapshots", requirePermission(domain.PermissionBalanceSnapshotManage, balanceHistoryHandler.TriggerSnapshotHandler)).Methods(http.MethodPost)
+	protectedRoutes.Handle("/admin/transactions/stuck", requirePermission(domain.PermissionTransactionRecover, recoveryHandler.ListStuckHandler)).Methods(http.MethodGet)
+	protectedRoutes.Handle("/admin/transactions/{transactionID}/bump", requirePermission(domain.PermissionTransactionRecover, recoveryHandler.BumpGasHandler)).Methods(http.MethodPost)
+	protectedRoutes.Handle("/admin/transactions/{transactionID}/cancel", requirePermission(domain.PermissionTransactionRecover, recoveryHandler.CancelHandler)).Methods(http.MethodPost)
+	protectedRoutes.Handle("/admin/transactions/{transactionID}/reversals", requirePermission(domain.PermissionReversalManage, reversalHandler.RequestReversalHandler)).Methods(http.MethodPost)
+	protectedRoutes.Handle("/admin/transactions/{transactionID}/reversals", requirePermission(domain.PermissionReversalManage, reversalHandler.ListTransactionReversalsHandler)).Methods(http.MethodGet)
+	protectedRoutes.Handle("/admin/reversals", requirePermission(domain.PermissionReversalManage, reversalHandler.ListReversalsHandler)).Methods(http.MethodGet)
+	protectedRoutes.Handle("/admin/reversals/{reversalID}", requirePermission(domain.PermissionReversalManage, reversalHandler.GetReversalHandler)).Methods(http.MethodGet)
+	protectedRoutes.Handle("/admin/reversals/{reversalID}/approve", requirePermission(domain.PermissionReversalManage, reversalHandler.ApproveReversalHandler)).Methods(http.MethodPost)
+	protectedRoutes.Handle("/admin/reversals/{reversalID}/reject", requirePermission(domain.PermissionReversalManage, reversalHandler.RejectReversalHandler)).Methods(http.MethodPost)
+	protectedRoutes.Handle("/admin/fiat-deposits", requirePermission(domain.PermissionFiatDepositRead, onrampHandler.ListDepositsHandler)).Methods(http.MethodGet)
+	protectedRoutes.Handle("/admin/fiat-withdrawals", requirePermission(domain.PermissionFiatWithdrawalManage, offrampHandler.ListQueueHandler)).Methods(http.MethodGet)
+	protectedRoutes.Handle("/admin/fiat-withdrawals/{withdrawalID}/approve", requirePermission(domain.PermissionFiatWithdrawalManage, offrampHandler.ApproveWithdrawalHandler)).Methods(http.MethodPost)
+	protectedRoutes.Handle("/admin/fiat-withdrawals/{withdrawalID}/complete", requirePermission(domain.PermissionFiatWithdrawalManage, offrampHandler.CompleteWithdrawalHandler)).Methods(http.MethodPost)
+	protectedRoutes.Handle("/admin/fiat-withdrawals/{withdrawalID}/reject", requirePermission(domain.PermissionFiatWithdrawalManage, offrampHandler.RejectWithdrawalHandler)).Methods(http.MethodPost)
+	protectedRoutes.Handle("/admin/key-rotation", requirePermission(domain.PermissionEncryptionManage, keyRotationHandler.StartRotationHandler)).Methods(http.MethodPost)
+	protectedRoutes.Handle("/admin/key-rotation", requirePermission(domain.PermissionEncryptionManage, keyRotationHandler.ProgressHandler)).Methods(http.MethodGet)
+	protectedRoutes.Handle("/admin/pii-backfill", requirePermission(domain.PermissionEncryptionManage, piiBackfillHandler.StartBackfillHandler)).Methods(http.MethodPost)
+	protectedRoutes.Handle("/admin/pii-backfill", requirePermission(domain.PermissionEncryptionManage, piiBackfillHandler.ProgressHandler)).Methods(http.MethodGet)
+	protectedRoutes.Handle("/admin/hd-wallets/audit", requirePermission(domain.PermissionWalletAudit, hdWalletHandler.AuditHandler)).Methods(http.MethodGet)
 
 	return router
 }
@@ -0,0 +1,64 @@
+package settings
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/respond"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+	"github.com/gorilla/mux"
+)
+
+// SettingResponse represents a runtime setting and its current value
+type SettingResponse struct {
+	Key         string     `json:"key"`
+	Value       string     `json:"value"`
+	Default     string     `json:"default"`
+	Description string     `json:"description"`
+	UpdatedBy   string     `json:"updated_by,omitempty"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+}
+
+// UpdateSettingRequest represents the body of a setting change
+type UpdateSettingRequest struct {
+	Value string `json:"value"`
+}
+
+type Handler struct {
+	service Service
+}
+
+// Constructor function
+func NewHandler(service Service) Handler {
+	return Handler{service: service}
+}
+
+// ListSettingsHandler returns every runtime setting, needs setting.manage
+func (hd Handler) ListSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	settings := hd.service.List()
+	respond.List(w, r, settings, respond.Pagination{Count: len(settings)})
+}
+
+// UpdateSettingHandler changes a runtime setting, needs setting.manage
+func (hd Handler) UpdateSettingHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req UpdateSettingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	setting, err := hd.service.Update(mux.Vars(r)["key"], req.Value, userInfo.UserID)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, setting)
+}
@@ -0,0 +1,244 @@
+package settings
+
+import (
+	"fmt"
+	"log"
+	"math/big"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+// Runtime setting keys
+const (
+	TransferMaxAmountWei       = "transfer.max_amount_wei"
+	TransferDailyLimitWei      = "transfer.daily_limit_wei"
+	TransferPINMaxAmountWei    = "transfer.pin_max_amount_wei"
+	RateLimitRequestsPerMinute = "rate_limit.requests_per_minute"
+	PublicRequestsPerMinute    = "rate_limit.public_requests_per_minute"
+)
+
+// How often settings are reloaded so changes made on another instance are picked up
+const refreshInterval = 30 * time.Second
+
+var (
+	ErrUnknownSetting      = utils.NewError(utils.ErrNotFound, "unknown setting", nil)
+	ErrInvalidSettingValue = utils.NewError(utils.ErrValidation, "invalid setting value", nil)
+)
+
+// definition describes a tunable, its default and how its values are validated
+type definition struct {
+	defaultValue string
+	description  string
+	validate     func(value string) error
+}
+
+var definitions = map[string]definition{
+	TransferMaxAmountWei: {
+		defaultValue: "0",
+		description:  "Largest single transfer in wei, 0 for no limit",
+		validate:     nonNegativeBigInt,
+	},
+	TransferDailyLimitWei: {
+		defaultValue: "0",
+		description:  "Total a user may send per day in their time zone in wei, 0 for no limit",
+		validate:     nonNegativeBigInt,
+	},
+	TransferPINMaxAmountWei: {
+		defaultValue: "100000000000000000",
+		description:  "Largest transfer in wei a transaction PIN can confirm instead of the password, 0 disables PINs",
+		validate:     nonNegativeBigInt,
+	},
+	RateLimitRequestsPerMinute: {
+		defaultValue: "120",
+		description:  "Requests allowed per client per minute, 0 disables rate limiting",
+		validate:     nonNegativeInt,
+	},
+	PublicRequestsPerMinute: {
+		defaultValue: "30",
+		description:  "Requests allowed per client per minute on the unauthenticated /public endpoints, 0 for no extra limit",
+		validate:     nonNegativeInt,
+	},
+}
+
+type service struct {
+	settingsRepo repo.SettingsStorer
+
+	mu          sync.RWMutex
+	values      map[string]SettingResponse
+	subscribers map[string][]func(value string)
+}
+
+type Service interface {
+	Load() error
+	Get(key string) string
+	GetInt(key string) int
+	GetBigInt(key string) *big.Int
+	List() []SettingResponse
+	Update(key, value, updatedBy string) (SettingResponse, error)
+	Subscribe(key string, onChange func(value string))
+	RunRefresher(stop <-chan struct{})
+}
+
+// Constructor function
+func NewService(settingsRepo repo.SettingsStorer) Service {
+	values := map[string]SettingResponse{}
+	for key, def := range definitions {
+		values[key] = SettingResponse{Key: key, Value: def.defaultValue, Default: def.defaultValue, Description: def.description}
+	}
+
+	return &service{
+		settingsRepo: settingsRepo,
+		values:       values,
+		subscribers:  map[string][]func(value string){},
+	}
+}
+
+// Load refreshes the cache from the database and notifies subscribers of changed values
+func (sd *service) Load() error {
+	stored, err := sd.settingsRepo.GetRuntimeSettings()
+	if err != nil {
+		return err
+	}
+
+	for _, setting := range stored {
+		def, ok := definitions[setting.Key]
+		if !ok {
+			continue
+		}
+		if err := def.validate(setting.Value); err != nil {
+			log.Printf("Ignoring stored value of %s: %v", setting.Key, err)
+			continue
+		}
+		sd.apply(setting.Key, setting.Value, setting.UpdatedBy.String, setting.UpdatedAt)
+	}
+	return nil
+}
+
+// Get returns the current value of a setting
+func (sd *service) Get(key string) string {
+	sd.mu.RLock()
+	defer sd.mu.RUnlock()
+	return sd.values[key].Value
+}
+
+// GetInt returns the current value of an integer setting
+func (sd *service) GetInt(key string) int {
+	value, err := strconv.Atoi(sd.Get(key))
+	if err != nil {
+		log.Printf("Setting %s is not an integer: %v", key, err)
+	}
+	return value
+}
+
+// GetBigInt returns the current value of a wei amount setting
+func (sd *service) GetBigInt(key string) *big.Int {
+	value, ok := new(big.Int).SetString(sd.Get(key), 10)
+	if !ok {
+		log.Printf("Setting %s is not an integer", key)
+		return new(big.Int)
+	}
+	return value
+}
+
+// List returns every known setting sorted by key
+func (sd *service) List() []SettingResponse {
+	sd.mu.RLock()
+	defer sd.mu.RUnlock()
+
+	settings := make([]SettingResponse, 0, len(sd.values))
+	for _, setting := range sd.values {
+		settings = append(settings, setting)
+	}
+	sort.Slice(settings, func(i, j int) bool { return settings[i].Key < settings[j].Key })
+	return settings
+}
+
+// Update validates and persists a new value, then notifies subscribers
+func (sd *service) Update(key, value, updatedBy string) (SettingResponse, error) {
+	def, ok := definitions[key]
+	if !ok {
+		return SettingResponse{}, fmt.Errorf("%w: %s", ErrUnknownSetting, key)
+	}
+	if err := def.validate(value); err != nil {
+		return SettingResponse{}, fmt.Errorf("%w: %s %v", ErrInvalidSettingValue, key, err)
+	}
+
+	if err := sd.settingsRepo.UpsertRuntimeSetting(key, value, updatedBy); err != nil {
+		return SettingResponse{}, err
+	}
+
+	log.Printf("Runtime setting %s changed to %s by %s", key, value, updatedBy)
+	sd.apply(key, value, updatedBy, time.Now())
+
+	sd.mu.RLock()
+	defer sd.mu.RUnlock()
+	return sd.values[key], nil
+}
+
+// Subscribe registers a callback for changes of a setting and calls it once with the current value
+func (sd *service) Subscribe(key string, onChange func(value string)) {
+	sd.mu.Lock()
+	sd.subscribers[key] = append(sd.subscribers[key], onChange)
+	value := sd.values[key].Value
+	sd.mu.Unlock()
+
+	onChange(value)
+}
+
+// RunRefresher periodically reloads settings until stop is closed
+func (sd *service) RunRefresher(stop <-chan struct{}) {
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := sd.Load(); err != nil {
+				log.Printf("Error refreshing runtime settings: %v", err)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// apply stores a value in the cache and notifies subscribers when it changed
+func (sd *service) apply(key, value, updatedBy string, updatedAt time.Time) {
+	sd.mu.Lock()
+	current := sd.values[key]
+	changed := current.Value != value
+	current.Value = value
+	current.UpdatedBy = updatedBy
+	current.UpdatedAt = &updatedAt
+	sd.values[key] = current
+	subscribers := append([]func(string){}, sd.subscribers[key]...)
+	sd.mu.Unlock()
+
+	if !changed {
+		return
+	}
+	for _, onChange := range subscribers {
+		onChange(value)
+	}
+}
+
+func nonNegativeInt(value string) error {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return fmt.Errorf("must be a non-negative integer")
+	}
+	return nil
+}
+
+func nonNegativeBigInt(value string) error {
+	n, ok := new(big.Int).SetString(value, 10)
+	if !ok || n.Sign() < 0 {
+		return fmt.Errorf("must be a non-negative integer")
+	}
+	return nil
+}
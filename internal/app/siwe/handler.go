@@ -0,0 +1,62 @@
+package siwe
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/user"
+	"github.com/CodeWithKrushnal/ChainBank/internal/respond"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+// NonceResponse carries the nonce and the values the EIP-4361 message must contain
+type NonceResponse struct {
+	Nonce     string    `json:"nonce"`
+	Domain    string    `json:"domain"`
+	ChainID   int64     `json:"chain_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// LoginRequest carries the EIP-4361 message and its hex personal_sign signature
+type LoginRequest struct {
+	Message   string `json:"message"`
+	Signature string `json:"signature"`
+}
+
+type Handler struct {
+	service Service
+}
+
+// Constructor function
+func NewHandler(service Service) Handler {
+	return Handler{service: service}
+}
+
+// NonceHandler issues a nonce for a new sign in message
+func (hd Handler) NonceHandler(w http.ResponseWriter, r *http.Request) {
+	nonce, err := hd.service.IssueNonce()
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, nonce)
+}
+
+// LoginHandler verifies a signed message and returns the same tokens as /signin
+func (hd Handler) LoginHandler(w http.ResponseWriter, r *http.Request) {
+	var req LoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	response, err := hd.service.Login(req, user.NewLoginClient(r))
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, response)
+}
@@ -0,0 +1,166 @@
+package siwe
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	headerSuffix   = " wants you to sign in with your Ethereum account:"
+	minNonceLength = 8
+)
+
+// Message is a parsed EIP-4361 message, the optional times are nil when the message leaves them out
+type Message struct {
+	Scheme         string
+	Domain         string
+	Address        string
+	Statement      string
+	URI            string
+	Version        string
+	ChainID        *big.Int
+	Nonce          string
+	IssuedAt       time.Time
+	ExpirationTime *time.Time
+	NotBefore      *time.Time
+	RequestID      string
+	Resources      []string
+}
+
+// ParseMessage parses the text of an EIP-4361 message. The fields must appear in the order the
+// standard defines, so the text the wallet showed is exactly what gets verified.
+func ParseMessage(text string) (Message, error) {
+	var msg Message
+	lines := strings.Split(strings.TrimRight(strings.ReplaceAll(text, "\r\n", "\n"), "\n"), "\n")
+	if len(lines) < 8 {
+		return msg, fmt.Errorf("message is too short")
+	}
+
+	authority, ok := strings.CutSuffix(lines[0], headerSuffix)
+	if !ok || authority == "" {
+		return msg, fmt.Errorf("message does not start with the sign in request")
+	}
+	if scheme, domain, ok := strings.Cut(authority, "://"); ok {
+		msg.Scheme, authority = scheme, domain
+	}
+	msg.Domain = authority
+
+	// The address has to be in EIP-55 checksum casing
+	msg.Address = lines[1]
+	if !common.IsHexAddress(msg.Address) || common.HexToAddress(msg.Address).Hex() != msg.Address {
+		return msg, fmt.Errorf("address must be an EIP-55 checksummed Ethereum address")
+	}
+	if lines[2] != "" {
+		return msg, fmt.Errorf("address must be followed by an empty line")
+	}
+
+	// The statement is optional and framed by empty lines, some wallets drop the second empty line
+	// when there is no statement
+	rest := lines[3:]
+	switch {
+	case strings.HasPrefix(rest[0], "URI: "):
+	case rest[0] == "":
+		rest = rest[1:]
+	default:
+		if rest[1] != "" {
+			return msg, fmt.Errorf("statement must be a single line followed by an empty line")
+		}
+		msg.Statement, rest = rest[0], rest[2:]
+	}
+
+	fields := fieldReader{lines: rest}
+	msg.URI = fields.required("URI")
+	msg.Version = fields.required("Version")
+	chainID := fields.required("Chain ID")
+	msg.Nonce = fields.required("Nonce")
+	issuedAt := fields.required("Issued At")
+	expirationTime, hasExpirationTime := fields.optional("Expiration Time")
+	notBefore, hasNotBefore := fields.optional("Not Before")
+	msg.RequestID, _ = fields.optional("Request ID")
+	if fields.err != nil {
+		return msg, fields.err
+	}
+
+	if len(fields.lines) > 0 {
+		if fields.lines[0] != "Resources:" {
+			return msg, fmt.Errorf("unexpected line %q", fields.lines[0])
+		}
+		for _, line := range fields.lines[1:] {
+			resource, ok := strings.CutPrefix(line, "- ")
+			if !ok {
+				return msg, fmt.Errorf("resources must be listed as \"- <uri>\"")
+			}
+			msg.Resources = append(msg.Resources, resource)
+		}
+	}
+
+	if msg.Version != "1" {
+		return msg, fmt.Errorf("version must be 1")
+	}
+	var valid bool
+	if msg.ChainID, valid = new(big.Int).SetString(chainID, 10); !valid {
+		return msg, fmt.Errorf("chain ID must be a number")
+	}
+	if len(msg.Nonce) < minNonceLength || !isAlphanumeric(msg.Nonce) {
+		return msg, fmt.Errorf("nonce must be at least %d alphanumeric characters", minNonceLength)
+	}
+
+	var err error
+	if msg.IssuedAt, err = time.Parse(time.RFC3339, issuedAt); err != nil {
+		return msg, fmt.Errorf("issued at must be an RFC 3339 time")
+	}
+	if hasExpirationTime {
+		parsed, err := time.Parse(time.RFC3339, expirationTime)
+		if err != nil {
+			return msg, fmt.Errorf("expiration time must be an RFC 3339 time")
+		}
+		msg.ExpirationTime = &parsed
+	}
+	if hasNotBefore {
+		parsed, err := time.Parse(time.RFC3339, notBefore)
+		if err != nil {
+			return msg, fmt.Errorf("not before must be an RFC 3339 time")
+		}
+		msg.NotBefore = &parsed
+	}
+	return msg, nil
+}
+
+// fieldReader consumes the "Name: value" lines of a message in order, keeping the first error
+type fieldReader struct {
+	lines []string
+	err   error
+}
+
+func (fr *fieldReader) required(name string) string {
+	value, ok := fr.optional(name)
+	if !ok && fr.err == nil {
+		fr.err = fmt.Errorf("%s is missing", name)
+	}
+	return value
+}
+
+func (fr *fieldReader) optional(name string) (string, bool) {
+	if fr.err != nil || len(fr.lines) == 0 {
+		return "", false
+	}
+	value, ok := strings.CutPrefix(fr.lines[0], name+": ")
+	if !ok {
+		return "", false
+	}
+	fr.lines = fr.lines[1:]
+	return value, true
+}
+
+func isAlphanumeric(value string) bool {
+	for _, c := range value {
+		if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
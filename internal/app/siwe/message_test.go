@@ -0,0 +1,127 @@
+package siwe
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const testAddress = "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"
+
+// testMessage returns the lines of a message for testAddress with every optional field set
+func testMessage() []string {
+	return []string{
+		"https://app.chainbank.io wants you to sign in with your Ethereum account:",
+		testAddress,
+		"",
+		"Sign in to ChainBank",
+		"",
+		"URI: https://app.chainbank.io/login",
+		"Version: 1",
+		"Chain ID: 1337",
+		"Nonce: 32891756abcdef",
+		"Issued At: 2026-10-16T10:00:00Z",
+		"Expiration Time: 2026-10-16T10:10:00Z",
+		"Not Before: 2026-10-16T09:59:00Z",
+		"Request ID: req-1",
+		"Resources:",
+		"- https://app.chainbank.io/terms",
+	}
+}
+
+func TestParseMessage(t *testing.T) {
+	msg, err := ParseMessage(strings.Join(testMessage(), "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issuedAt := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	switch {
+	case msg.Scheme != "https" || msg.Domain != "app.chainbank.io":
+		t.Errorf("scheme %q and domain %q", msg.Scheme, msg.Domain)
+	case msg.Address != testAddress || msg.Statement != "Sign in to ChainBank":
+		t.Errorf("address %q and statement %q", msg.Address, msg.Statement)
+	case msg.URI != "https://app.chainbank.io/login" || msg.Version != "1" || msg.ChainID.Int64() != 1337:
+		t.Errorf("URI %q, version %q and chain ID %v", msg.URI, msg.Version, msg.ChainID)
+	case msg.Nonce != "32891756abcdef" || msg.RequestID != "req-1":
+		t.Errorf("nonce %q and request ID %q", msg.Nonce, msg.RequestID)
+	case !msg.IssuedAt.Equal(issuedAt):
+		t.Errorf("issued at %v", msg.IssuedAt)
+	case msg.ExpirationTime == nil || !msg.ExpirationTime.Equal(issuedAt.Add(10*time.Minute)):
+		t.Errorf("expiration time %v", msg.ExpirationTime)
+	case msg.NotBefore == nil || !msg.NotBefore.Equal(issuedAt.Add(-time.Minute)):
+		t.Errorf("not before %v", msg.NotBefore)
+	case len(msg.Resources) != 1 || msg.Resources[0] != "https://app.chainbank.io/terms":
+		t.Errorf("resources %v", msg.Resources)
+	}
+}
+
+func TestParseMessageOptionalParts(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+	}{
+		{"minimal", append(testMessage()[:3], "", "URI: https://app.chainbank.io/login", "Version: 1", "Chain ID: 1337", "Nonce: 32891756abcdef", "Issued At: 2026-10-16T10:00:00Z")},
+		// Some wallets drop the second empty line when there is no statement
+		{"no statement, one empty line", append(testMessage()[:3], "URI: https://app.chainbank.io/login", "Version: 1", "Chain ID: 1337", "Nonce: 32891756abcdef", "Issued At: 2026-10-16T10:00:00Z")},
+		{"domain without scheme", append([]string{"app.chainbank.io wants you to sign in with your Ethereum account:"}, testMessage()[1:]...)},
+		{"windows line endings", strings.Split(strings.Join(testMessage(), "\r\n"), "\n")},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			msg, err := ParseMessage(strings.Join(test.lines, "\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if msg.Domain != "app.chainbank.io" || msg.Nonce != "32891756abcdef" {
+				t.Errorf("domain %q and nonce %q", msg.Domain, msg.Nonce)
+			}
+		})
+	}
+}
+
+func TestParseMessageMalformed(t *testing.T) {
+	// replace returns the test message with line i replaced, or removed when line is empty
+	replace := func(i int, line string) []string {
+		lines := testMessage()
+		if line == "" {
+			return append(lines[:i], lines[i+1:]...)
+		}
+		lines[i] = line
+		return lines
+	}
+
+	tests := []struct {
+		name  string
+		lines []string
+	}{
+		{"too short", testMessage()[:6]},
+		{"missing header", replace(0, "https://app.chainbank.io asks you to sign in:")},
+		{"empty domain", replace(0, " wants you to sign in with your Ethereum account:")},
+		{"lowercase address", replace(1, strings.ToLower(testAddress))},
+		{"invalid address", replace(1, "0x1234")},
+		{"no empty line after address", replace(2, "Sign in to ChainBank")},
+		{"statement over two lines", replace(4, "and more")},
+		{"missing URI", replace(5, "")},
+		{"missing nonce", replace(8, "")},
+		{"fields out of order", replace(6, "Chain ID: 1337")},
+		{"version 2", replace(6, "Version: 2")},
+		{"chain ID not a number", replace(7, "Chain ID: mainnet")},
+		{"short nonce", replace(8, "Nonce: abc123")},
+		{"nonce with symbols", replace(8, "Nonce: 32891756-abcdef")},
+		{"issued at not RFC 3339", replace(9, "Issued At: 16 Oct 2026")},
+		{"expiration time not RFC 3339", replace(10, "Expiration Time: tomorrow")},
+		{"not before not RFC 3339", replace(11, "Not Before: 2026-10-16")},
+		{"unknown field", replace(13, "Comment: hello")},
+		{"resource without dash", replace(14, "https://app.chainbank.io/terms")},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := ParseMessage(strings.Join(test.lines, "\n")); err == nil {
+				t.Error("malformed message parsed")
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package siwe
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/ethereum"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/user"
+	"github.com/CodeWithKrushnal/ChainBank/internal/config"
+	"github.com/CodeWithKrushnal/ChainBank/internal/domain"
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+const (
+	nonceTTL = 10 * time.Minute
+	// Tolerated difference between the wallet's clock and ours for Issued At and Not Before
+	clockSkew = time.Minute
+)
+
+type service struct {
+	domain             string
+	siweNonceRepo      repo.SIWENonceStorer
+	externalWalletRepo repo.ExternalWalletStorer
+	userRepo           repo.UserStorer
+	userService        user.Service
+}
+
+type Service interface {
+	IssueNonce() (NonceResponse, error)
+	Login(req LoginRequest, client user.LoginClient) (user.AuthResponse, error)
+}
+
+// Constructor function
+func NewService(siweNonceRepo repo.SIWENonceStorer, externalWalletRepo repo.ExternalWalletStorer, userRepo repo.UserStorer, userService user.Service) Service {
+	return service{
+		domain:             config.ConfigDetails.SIWEDomain,
+		siweNonceRepo:      siweNonceRepo,
+		externalWalletRepo: externalWalletRepo,
+		userRepo:           userRepo,
+		userService:        userService,
+	}
+}
+
+// IssueNonce hands out a single use nonce for the client to put in the message it asks the wallet to sign
+func (sd service) IssueNonce() (NonceResponse, error) {
+	if sd.domain == "" {
+		return NonceResponse{}, utils.NotFound("Sign-In with Ethereum is not configured", nil)
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return NonceResponse{}, fmt.Errorf("failed to generate nonce: %v", err)
+	}
+	nonce := hex.EncodeToString(buf)
+	expiresAt := time.Now().Add(nonceTTL).UTC().Truncate(time.Second)
+
+	if err := sd.siweNonceRepo.CreateSIWENonce(nonce, expiresAt); err != nil {
+		return NonceResponse{}, err
+	}
+
+	return NonceResponse{
+		Nonce:     nonce,
+		Domain:    sd.domain,
+		ChainID:   ethereum.ChainID.Int64(),
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// Login signs in with a signed EIP-4361 message. The signing address has to be linked and verified
+// as an external wallet of exactly one account, which is the account that gets signed in.
+func (sd service) Login(req LoginRequest, client user.LoginClient) (user.AuthResponse, error) {
+	if sd.domain == "" {
+		return user.AuthResponse{}, utils.NotFound("Sign-In with Ethereum is not configured", nil)
+	}
+
+	msg, err := ParseMessage(req.Message)
+	if err != nil {
+		return user.AuthResponse{}, utils.Validationf("invalid sign in message: %v", err)
+	}
+	if err := sd.checkMessage(msg); err != nil {
+		return user.AuthResponse{}, err
+	}
+
+	signer, err := ethereum.RecoverTextSigner(req.Message, req.Signature)
+	if errors.Is(err, ethereum.ErrMalformedSignature) {
+		return user.AuthResponse{}, utils.Validation(err.Error())
+	}
+	if err != nil || signer != msg.Address {
+		return user.AuthResponse{}, utils.Unauthorized("invalid signature")
+	}
+
+	// Claimed only once the signature checks out, so a forged message cannot burn a nonce
+	claimed, err := sd.siweNonceRepo.UseSIWENonce(msg.Nonce)
+	if err != nil {
+		return user.AuthResponse{}, err
+	}
+	if !claimed {
+		return user.AuthResponse{}, utils.Unauthorized("nonce is invalid, expired or was already used")
+	}
+
+	owner, err := sd.accountOf(msg.Address)
+	if err != nil {
+		return user.AuthResponse{}, err
+	}
+	if owner.AccountStatus == domain.AccountClosed {
+		return user.AuthResponse{}, utils.Unauthorized("account is closed")
+	}
+
+	response, err := sd.userService.IssueTokens(owner)
+	if err != nil {
+		return user.AuthResponse{}, err
+	}
+	log.Printf("User %s signed in with Ethereum address %s", owner.ID, msg.Address)
+	sd.userService.RecordLogin(owner.ID, user.LoginMethodSIWE, client)
+
+	return response, nil
+}
+
+// checkMessage rejects messages meant for another site or chain and messages outside their validity window
+func (sd service) checkMessage(msg Message) error {
+	if msg.Domain != sd.domain {
+		return utils.Unauthorized("message was not issued for this site")
+	}
+	if msg.ChainID.Cmp(ethereum.ChainID) != 0 {
+		return utils.Unauthorized("message was signed for another chain")
+	}
+
+	now := time.Now()
+	if msg.IssuedAt.After(now.Add(clockSkew)) {
+		return utils.Unauthorized("message is not valid yet")
+	}
+	if msg.NotBefore != nil && msg.NotBefore.After(now.Add(clockSkew)) {
+		return utils.Unauthorized("message is not valid yet")
+	}
+	if msg.ExpirationTime != nil && now.After(*msg.ExpirationTime) {
+		return utils.Unauthorized("message has expired")
+	}
+	return nil
+}
+
+// accountOf finds the account that verified a link of address. An address linked by several
+// accounts is ambiguous and cannot sign in.
+func (sd service) accountOf(address string) (repo.UserWithRole, error) {
+	owners, err := sd.externalWalletRepo.GetVerifiedWalletOwners(address)
+	if err != nil {
+		return repo.UserWithRole{}, err
+	}
+	switch len(owners) {
+	case 0:
+		return repo.UserWithRole{}, utils.Unauthorized("no account is linked to this address, link it as an external wallet first")
+	case 1:
+		return sd.userRepo.GetUserWithRoleByID(owners[0])
+	default:
+		return repo.UserWithRole{}, utils.Unauthorized("address is linked to several accounts, sign in with email instead")
+	}
+}
@@ -0,0 +1,170 @@
+package siwe
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/ethereum"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/user"
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+const testDomain = "app.chainbank.io"
+
+func TestCheckMessage(t *testing.T) {
+	now := time.Now()
+	at := func(offset time.Duration) *time.Time {
+		value := now.Add(offset)
+		return &value
+	}
+	valid := func() Message {
+		return Message{Domain: testDomain, ChainID: ethereum.ChainID, IssuedAt: now}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(msg *Message)
+		wantErr bool
+	}{
+		{"valid", func(msg *Message) {}, false},
+		{"other domain", func(msg *Message) { msg.Domain = "evil.example.com" }, true},
+		{"other chain", func(msg *Message) { msg.ChainID = new(big.Int).Add(ethereum.ChainID, big.NewInt(1)) }, true},
+		// The wallet's clock may run up to clockSkew ahead of ours
+		{"issued within the clock skew", func(msg *Message) { msg.IssuedAt = *at(clockSkew / 2) }, false},
+		{"issued in the future", func(msg *Message) { msg.IssuedAt = *at(2 * clockSkew) }, true},
+		{"not valid yet", func(msg *Message) { msg.NotBefore = at(2 * clockSkew) }, true},
+		{"valid from now", func(msg *Message) { msg.NotBefore = at(0) }, false},
+		{"expired", func(msg *Message) { msg.ExpirationTime = at(-time.Second) }, true},
+		{"expires later", func(msg *Message) { msg.ExpirationTime = at(time.Minute) }, false},
+	}
+
+	sd := service{domain: testDomain}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			msg := valid()
+			test.modify(&msg)
+			err := sd.checkMessage(msg)
+			if test.wantErr && !errors.Is(err, utils.ErrUnauthorized) {
+				t.Errorf("checkMessage = %v, want unauthorized", err)
+			}
+			if !test.wantErr && err != nil {
+				t.Errorf("checkMessage = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestLogin(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := crypto.PubkeyToAddress(key.PublicKey).Hex()
+
+	message := func(domain, nonce string) string {
+		return strings.Join([]string{
+			"https://" + domain + headerSuffix,
+			address,
+			"",
+			"URI: https://" + domain + "/login",
+			"Version: 1",
+			"Chain ID: " + ethereum.ChainID.String(),
+			"Nonce: " + nonce,
+			"Issued At: " + time.Now().UTC().Format(time.RFC3339),
+		}, "\n")
+	}
+
+	tests := []struct {
+		name    string
+		message string
+		signer  *ecdsa.PrivateKey
+		wantErr error
+		// Part of the error message, telling apart the checks failing with the same kind
+		wantText string
+		// Left unchecked when the login fails before the nonce is claimed
+		wantNonceUsed bool
+	}{
+		{"malformed message", "hello", key, utils.ErrValidation, "too short", false},
+		{"other domain", message("evil.example.com", "issuednonce1"), key, utils.ErrUnauthorized, "this site", false},
+		{"signed by another key", message(testDomain, "issuednonce1"), otherKey, utils.ErrUnauthorized, "invalid signature", false},
+		{"nonce never issued", message(testDomain, "unknownnonce1"), key, utils.ErrUnauthorized, "nonce", false},
+		// The nonce is spent, the login then fails as the address is linked to no account
+		{"issued nonce", message(testDomain, "issuednonce1"), key, utils.ErrUnauthorized, "no account is linked", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			nonces := &memoryNonceRepo{issued: map[string]bool{"issuednonce1": true}}
+			sd := service{domain: testDomain, siweNonceRepo: nonces, externalWalletRepo: unlinkedWalletRepo{}}
+
+			_, err := sd.Login(LoginRequest{Message: test.message, Signature: signText(t, test.message, test.signer)}, user.LoginClient{})
+			if !errors.Is(err, test.wantErr) || !strings.Contains(err.Error(), test.wantText) {
+				t.Errorf("Login = %v, want %v mentioning %q", err, test.wantErr, test.wantText)
+			}
+			if used := !nonces.issued["issuednonce1"]; used != test.wantNonceUsed {
+				t.Errorf("nonce used = %v, want %v", used, test.wantNonceUsed)
+			}
+		})
+	}
+
+	// A nonce signs in once, replaying the message fails on the nonce
+	nonces := &memoryNonceRepo{issued: map[string]bool{"issuednonce1": true}}
+	sd := service{domain: testDomain, siweNonceRepo: nonces, externalWalletRepo: unlinkedWalletRepo{}}
+	req := LoginRequest{Message: message(testDomain, "issuednonce1")}
+	req.Signature = signText(t, req.Message, key)
+	sd.Login(req, user.LoginClient{})
+	if _, err := sd.Login(req, user.LoginClient{}); err == nil || !strings.Contains(err.Error(), "nonce") {
+		t.Errorf("replayed Login = %v, want a nonce error", err)
+	}
+}
+
+// signText signs a message the way wallets answer personal_sign
+func signText(t *testing.T, message string, key *ecdsa.PrivateKey) string {
+	t.Helper()
+	signature, err := crypto.Sign(accounts.TextHash([]byte(message)), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature[crypto.RecoveryIDOffset] += 27
+	return hexutil.Encode(signature)
+}
+
+// memoryNonceRepo holds the nonces issued and not used yet
+type memoryNonceRepo struct {
+	issued map[string]bool
+}
+
+func (m *memoryNonceRepo) CreateSIWENonce(nonce string, expiresAt time.Time) error {
+	m.issued[nonce] = true
+	return nil
+}
+
+func (m *memoryNonceRepo) UseSIWENonce(nonce string) (bool, error) {
+	if !m.issued[nonce] {
+		return false, nil
+	}
+	delete(m.issued, nonce)
+	return true, nil
+}
+
+// unlinkedWalletRepo links no address to any account, methods the tests do not use panic
+type unlinkedWalletRepo struct {
+	repo.ExternalWalletStorer
+}
+
+func (unlinkedWalletRepo) GetVerifiedWalletOwners(address string) ([]string, error) {
+	return nil, nil
+}
@@ -0,0 +1,124 @@
+package sweeps
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/respond"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+// SetSweepRuleRequest sweeps the balance above threshold_wei to a verified linked external wallet.
+// Sweeps below min_sweep_wei are skipped, the rule is enabled unless enabled is false.
+type SetSweepRuleRequest struct {
+	ThresholdWei       string `json:"threshold_wei"`
+	MinSweepWei        string `json:"min_sweep_wei"`
+	DestinationAddress string `json:"destination_address"`
+	Enabled            *bool  `json:"enabled"`
+}
+
+// SweepRuleResponse represents a sweep rule
+type SweepRuleResponse struct {
+	ThresholdWei       string     `json:"threshold_wei"`
+	MinSweepWei        string     `json:"min_sweep_wei"`
+	DestinationAddress string     `json:"destination_address"`
+	Enabled            bool       `json:"enabled"`
+	LastRunAt          *time.Time `json:"last_run_at,omitempty"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+}
+
+// SweepResponse represents one sweep, the balance and threshold are those it was computed from
+type SweepResponse struct {
+	SweepID            string    `json:"sweep_id"`
+	DestinationAddress string    `json:"destination_address"`
+	BalanceWei         string    `json:"balance_wei"`
+	ThresholdWei       string    `json:"threshold_wei"`
+	AmountWei          string    `json:"amount_wei"`
+	Status             string    `json:"status"`
+	TxHash             string    `json:"transaction_hash,omitempty"`
+	Error              string    `json:"error,omitempty"`
+	CreatedAt          time.Time `json:"created_at"`
+}
+
+type Handler struct {
+	service Service
+}
+
+// Constructor function
+func NewHandler(service Service) Handler {
+	return Handler{service: service}
+}
+
+// GetRuleHandler returns the authenticated user's sweep rule
+func (hd Handler) GetRuleHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	rule, err := hd.service.GetRule(userInfo.UserID)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, rule)
+}
+
+// SetRuleHandler creates or replaces the authenticated user's sweep rule
+func (hd Handler) SetRuleHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req SetSweepRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	rule, err := hd.service.SetRule(userInfo.UserID, req)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, rule)
+}
+
+// DeleteRuleHandler removes the authenticated user's sweep rule
+func (hd Handler) DeleteRuleHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	if err := hd.service.DeleteRule(userInfo.UserID); err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListSweepsHandler lists the sweeps of the authenticated user
+func (hd Handler) ListSweepsHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	sweeps, err := hd.service.ListSweeps(userInfo.UserID)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.List(w, r, sweeps, respond.Pagination{Count: len(sweeps)})
+}
@@ -0,0 +1,305 @@
+package sweeps
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/ethereum"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/notification"
+	"github.com/CodeWithKrushnal/ChainBank/internal/config"
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+// Sweep statuses
+const (
+	StatusSent   = "sent"
+	StatusFailed = "failed"
+)
+
+const (
+	sweepListLimit = 100
+	ruleBatchSize  = 100
+)
+
+type service struct {
+	sweepRepo          repo.SweepStorer
+	walletRepo         repo.WalletStorer
+	externalWalletRepo repo.ExternalWalletStorer
+	transactionRepo    repo.TransactionStorer
+	withdrawalRepo     repo.FiatWithdrawalStorer
+	lockRepo           repo.LockStorer
+	ethRepo            ethereum.EthRepo
+	notifications      notification.Service
+}
+
+type Service interface {
+	GetRule(userID string) (SweepRuleResponse, error)
+	SetRule(userID string, req SetSweepRuleRequest) (SweepRuleResponse, error)
+	DeleteRule(userID string) error
+	ListSweeps(userID string) ([]SweepResponse, error)
+	RunScheduler(stop <-chan struct{})
+}
+
+// Constructor function
+func NewService(sweepRepo repo.SweepStorer, walletRepo repo.WalletStorer, externalWalletRepo repo.ExternalWalletStorer, transactionRepo repo.TransactionStorer,
+	withdrawalRepo repo.FiatWithdrawalStorer, lockRepo repo.LockStorer, ethRepo ethereum.EthRepo, notificationService notification.Service) Service {
+	return service{
+		sweepRepo:          sweepRepo,
+		walletRepo:         walletRepo,
+		externalWalletRepo: externalWalletRepo,
+		transactionRepo:    transactionRepo,
+		withdrawalRepo:     withdrawalRepo,
+		lockRepo:           lockRepo,
+		ethRepo:            ethRepo,
+		notifications:      notificationService,
+	}
+}
+
+// GetRule returns the user's sweep rule
+func (sd service) GetRule(userID string) (SweepRuleResponse, error) {
+	rule, err := sd.sweepRepo.GetSweepRule(userID)
+	if err != nil {
+		return SweepRuleResponse{}, err
+	}
+	return newSweepRuleResponse(rule), nil
+}
+
+// SetRule creates or replaces the user's sweep rule. Funds are only swept to a linked external
+// wallet the user proved to control, so a stolen session cannot redirect them elsewhere.
+func (sd service) SetRule(userID string, req SetSweepRuleRequest) (SweepRuleResponse, error) {
+	threshold, ok := new(big.Int).SetString(req.ThresholdWei, 10)
+	if !ok || threshold.Sign() < 0 {
+		return SweepRuleResponse{}, utils.Validation("threshold_wei must be a non-negative integer")
+	}
+	minSweep := new(big.Int)
+	if req.MinSweepWei != "" {
+		if _, ok := minSweep.SetString(req.MinSweepWei, 10); !ok || minSweep.Sign() < 0 {
+			return SweepRuleResponse{}, utils.Validation("min_sweep_wei must be a non-negative integer")
+		}
+	}
+	if !common.IsHexAddress(req.DestinationAddress) {
+		return SweepRuleResponse{}, utils.Validation("destination_address is not a valid Ethereum address")
+	}
+	destination := common.HexToAddress(req.DestinationAddress).Hex()
+	if err := sd.checkDestination(userID, destination); err != nil {
+		return SweepRuleResponse{}, err
+	}
+
+	enabled := true
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+	rule, err := sd.sweepRepo.UpsertSweepRule(repo.SweepRule{
+		UserID:             userID,
+		Threshold:          threshold.String(),
+		MinSweepAmount:     minSweep.String(),
+		DestinationAddress: destination,
+		Enabled:            enabled,
+	})
+	if err != nil {
+		return SweepRuleResponse{}, err
+	}
+	return newSweepRuleResponse(rule), nil
+}
+
+// DeleteRule removes the user's sweep rule
+func (sd service) DeleteRule(userID string) error {
+	return sd.sweepRepo.DeleteSweepRule(userID)
+}
+
+// ListSweeps returns the latest sweeps of the user
+func (sd service) ListSweeps(userID string) ([]SweepResponse, error) {
+	sweeps, err := sd.sweepRepo.GetSweeps(userID, sweepListLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]SweepResponse, len(sweeps))
+	for i, sweep := range sweeps {
+		response[i] = SweepResponse{
+			SweepID:            sweep.SweepID,
+			DestinationAddress: sweep.DestinationAddress,
+			BalanceWei:         sweep.Balance,
+			ThresholdWei:       sweep.Threshold,
+			AmountWei:          sweep.Amount,
+			Status:             sweep.Status,
+			TxHash:             sweep.TxHash,
+			Error:              sweep.Error,
+			CreatedAt:          sweep.CreatedAt,
+		}
+	}
+	return response, nil
+}
+
+// RunScheduler applies every enabled rule on the configured interval until stop is closed
+func (sd service) RunScheduler(stop <-chan struct{}) {
+	interval := time.Duration(config.ConfigDetails.SweepCheckIntervalMinutes) * time.Minute
+	if interval <= 0 {
+		log.Println("Scheduled sweeps disabled")
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			sd.runRules(interval)
+		case <-stop:
+			return
+		}
+	}
+}
+
+// runRules claims the rules that did not run within half an interval, leaving room for timer drift
+// while keeping other instances from running them in the same round
+func (sd service) runRules(interval time.Duration) {
+	for {
+		rules, err := sd.sweepRepo.ClaimDueSweepRules(interval/2, ruleBatchSize)
+		if err != nil {
+			log.Printf("Error loading sweep rules: %v", err)
+			return
+		}
+		for _, rule := range rules {
+			sd.applyRule(rule)
+		}
+		if len(rules) < ruleBatchSize {
+			return
+		}
+	}
+}
+
+// applyRule moves the balance above the threshold to the destination, keeping the threshold and the
+// funds held for fiat withdrawals in the wallet after gas. Wallets with pending transfers wait for the next round so nonces do not collide.
+func (sd service) applyRule(rule repo.SweepRule) {
+	if err := sd.checkDestination(rule.UserID, rule.DestinationAddress); err != nil {
+		log.Printf("Sweep of %s skipped: %v", rule.UserID, err)
+		return
+	}
+
+	// The sweep holds the transfer lock of the user from the pending check until it is recorded, so a
+	// transfer or withdrawal of the user cannot spend the balance it was sized against
+	lock, err := sd.lockRepo.Lock(repo.TransferLockName(rule.UserID))
+	if err != nil {
+		log.Printf("Sweep of %s skipped: %v", rule.UserID, err)
+		return
+	}
+	defer lock.Release()
+
+	pending, err := sd.transactionRepo.CountPending(rule.UserID)
+	if err != nil || pending > 0 {
+		return
+	}
+
+	walletID, err := sd.walletRepo.GetWalletID("", rule.UserID)
+	if err != nil {
+		log.Printf("Error loading wallet of %s for sweep: %v", rule.UserID, err)
+		return
+	}
+	balance, err := sd.ethRepo.GetBalance(walletID)
+	if err != nil {
+		log.Printf("Error fetching balance of %s for sweep: %v", walletID, err)
+		return
+	}
+
+	held, err := sd.withdrawalRepo.GetHeldWithdrawalTotal(walletID)
+	if err != nil {
+		return
+	}
+
+	threshold, _ := new(big.Int).SetString(rule.Threshold, 10)
+	minSweep, _ := new(big.Int).SetString(rule.MinSweepAmount, 10)
+	gasCost := new(big.Int).Mul(ethereum.DefaultGasPrice, new(big.Int).SetUint64(ethereum.TransferGasLimit))
+	amount := new(big.Int).Sub(balance, threshold)
+	amount.Sub(amount, gasCost)
+	amount.Sub(amount, held)
+	if amount.Sign() <= 0 || amount.Cmp(minSweep) < 0 {
+		return
+	}
+
+	sweep := repo.Sweep{
+		UserID:             rule.UserID,
+		WalletID:           walletID,
+		DestinationAddress: rule.DestinationAddress,
+		Balance:            balance.String(),
+		Threshold:          rule.Threshold,
+		Amount:             amount.String(),
+		Status:             StatusSent,
+	}
+	sweep.TxHash, err = sd.send(rule.UserID, walletID, rule.DestinationAddress, amount)
+	if err != nil {
+		log.Printf("Sweep of %s failed: %v", rule.UserID, err)
+		sweep.Status = StatusFailed
+		sweep.Error = err.Error()
+	}
+
+	if sweep, err = sd.sweepRepo.RecordSweep(sweep); err != nil {
+		return
+	}
+	if sweep.Status == StatusSent {
+		sd.notifySweep(sweep)
+	}
+}
+
+// send signs and broadcasts the sweep from the user's wallet
+func (sd service) send(userID, walletID, destination string, amount *big.Int) (string, error) {
+	privateKeyHex, err := sd.walletRepo.RetrievePrivateKey(userID, "")
+	if err != nil {
+		return "", fmt.Errorf("error retrieving private key: %w", err)
+	}
+
+	signedTx, err := sd.ethRepo.TransferFunds(privateKeyHex, walletID, destination, amount, ethereum.DefaultGasPrice, ethereum.TransferGasLimit, ethereum.ChainID)
+	if err != nil {
+		return "", fmt.Errorf("sweep transaction failed: %w", err)
+	}
+	if err := sd.ethRepo.SendTransaction(signedTx); err != nil {
+		return "", fmt.Errorf("failed to broadcast sweep transaction: %w", err)
+	}
+	return signedTx.Hash().Hex(), nil
+}
+
+// checkDestination requires the address to be a verified external wallet of the user
+func (sd service) checkDestination(userID, address string) error {
+	wallet, err := sd.externalWalletRepo.GetExternalWallet(userID, address)
+	if errors.Is(err, utils.ErrNotFound) || (err == nil && wallet.VerifiedAt == nil) {
+		return utils.Validation("destination_address must be a verified linked external wallet")
+	}
+	return err
+}
+
+// notifySweep tells the user how much was moved out of their wallet
+func (sd service) notifySweep(sweep repo.Sweep) {
+	amount, _ := new(big.Int).SetString(sweep.Amount, 10)
+	threshold, _ := new(big.Int).SetString(sweep.Threshold, 10)
+
+	sd.notifications.Notify(notification.Notification{
+		UserID:   sweep.UserID,
+		Category: notification.CategoryBalance,
+		Title:    "Excess balance swept",
+		Body:     "%s ETH above your threshold of %s ETH was moved to %s.",
+		BodyArgs: []any{weiToETH(amount), weiToETH(threshold), sweep.DestinationAddress},
+		Data:     map[string]string{"sweep_id": sweep.SweepID, "transaction_hash": sweep.TxHash, "amount": sweep.Amount},
+	})
+}
+
+func weiToETH(wei *big.Int) string {
+	return new(big.Float).Quo(new(big.Float).SetInt(wei), big.NewFloat(1e18)).Text('f', -1)
+}
+
+func newSweepRuleResponse(rule repo.SweepRule) SweepRuleResponse {
+	return SweepRuleResponse{
+		ThresholdWei:       rule.Threshold,
+		MinSweepWei:        rule.MinSweepAmount,
+		DestinationAddress: rule.DestinationAddress,
+		Enabled:            rule.Enabled,
+		LastRunAt:          rule.LastRunAt,
+		UpdatedAt:          rule.UpdatedAt,
+	}
+}
@@ -1,8 +1,19 @@
 package user
 
 import (
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strings"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/captcha"
+	"github.com/CodeWithKrushnal/ChainBank/internal/domain"
+	"github.com/CodeWithKrushnal/ChainBank/internal/respond"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+	"github.com/CodeWithKrushnal/ChainBank/middleware"
+	"github.com/gorilla/mux"
 )
 
 // SignupRequest represents the signup request body
@@ -27,17 +38,145 @@ type Credentials struct {
 	Password string `json:"password"`
 }
 
+// AuthResponse is returned by every sign-in, expires_in is the lifetime of the access token in seconds.
+// The reset token sets a new password through the password reset endpoint within the hour.
+type AuthResponse struct {
+	AccessToken  string   `json:"access_token"`
+	RefreshToken string   `json:"refresh_token"`
+	ResetToken   string   `json:"reset_token"`
+	TokenType    string   `json:"token_type"`
+	ExpiresIn    int64    `json:"expires_in"`
+	User         AuthUser `json:"user"`
+}
+
+// AuthUser identifies the user a sign-in is for
+type AuthUser struct {
+	ID    string `json:"id"`
+	Email string `json:"email"`
+	Role  int    `json:"role"`
+}
+
+// RefreshRequest carries the refresh token of an earlier sign-in
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// DisownLoginResponse is returned for the "this wasn't me" link of a login alert. The reset token
+// sets a new password through the password reset endpoint.
+type DisownLoginResponse struct {
+	LoginEventID    string `json:"login_event_id"`
+	SessionsRevoked bool   `json:"sessions_revoked"`
+	ResetToken      string `json:"reset_token"`
+}
+
+// PasswordResetRequest sets a new password with a reset token
+type PasswordResetRequest struct {
+	ResetToken  string `json:"reset_token"`
+	NewPassword string `json:"new_password"`
+}
+
+// CloseAccountRequest represents the account closure request body
+type CloseAccountRequest struct {
+	Password     string `json:"password"`
+	SweepAddress string `json:"sweep_address"`
+}
+
+// AdminCloseAccountRequest represents an admin closing another user's account
+type AdminCloseAccountRequest struct {
+	SweepAddress string `json:"sweep_address"`
+	Reason       string `json:"reason"`
+	Force        bool   `json:"force"`
+}
+
+// CloseAccountResponse represents the outcome of an account closure
+type CloseAccountResponse struct {
+	UserID      string               `json:"user_id"`
+	Status      domain.AccountStatus `json:"status"`
+	SweepTxHash string               `json:"sweep_tx_hash,omitempty"`
+	SweptAmount string               `json:"swept_amount,omitempty"`
+}
+
+// UserImportResponse represents the progress of a bulk user import
+type UserImportResponse struct {
+	ImportID    string              `json:"import_id"`
+	Status      string              `json:"status"`
+	Total       int                 `json:"total"`
+	Pending     int                 `json:"pending"`
+	Created     int                 `json:"created"`
+	Skipped     int                 `json:"skipped"`
+	Failed      int                 `json:"failed"`
+	Rows        []ImportRowResponse `json:"rows"`
+	CreatedAt   time.Time           `json:"created_at"`
+	CompletedAt *time.Time          `json:"completed_at,omitempty"`
+}
+
+// ImportRowResponse represents the outcome of one row of an import
+type ImportRowResponse struct {
+	Row      int    `json:"row"`
+	Email    string `json:"email"`
+	Status   string `json:"status"`
+	UserID   string `json:"user_id,omitempty"`
+	WalletID string `json:"wallet_id,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Largest CSV file accepted by the user import
+const maxImportFileBytes = 5 << 20
+
+// TimeZoneRequest sets the IANA time zone, e.g. Asia/Kolkata, the user's days and months are counted in
+type TimeZoneRequest struct {
+	TimeZone string `json:"time_zone"`
+}
+
+// TimeZoneResponse represents the user's time zone with its current offset from UTC
+type TimeZoneResponse struct {
+	TimeZone  string    `json:"time_zone"`
+	UTCOffset string    `json:"utc_offset"`
+	LocalTime time.Time `json:"local_time"`
+}
+
+// UserResponse represents a user as the viewer may see it, fields the viewer may not see are left out
+type UserResponse struct {
+	UserID                string               `json:"user_id"`
+	Username              string               `json:"username"`
+	Email                 string               `json:"email,omitempty"`
+	Role                  int                  `json:"role,omitempty"`
+	AccountStatus         domain.AccountStatus `json:"account_status,omitempty"`
+	CreatedAt             *time.Time           `json:"created_at,omitempty"`
+	FullName              string               `json:"full_name,omitempty"`
+	DateOfBirth           string               `json:"date_of_birth,omitempty"`
+	PasswordResetRequired *bool                `json:"password_reset_required,omitempty"`
+	TokensValidAfter      *time.Time           `json:"tokens_valid_after,omitempty"`
+}
+
+// OIDCLoginStart carries what the SSO callback needs to finish a login
+type OIDCLoginStart struct {
+	URL      string
+	State    string
+	Verifier string
+}
+
+// Cookie holding the state and PKCE verifier between the SSO redirect and its callback
+const oidcStateCookie = "oidc_state"
+
 type Handler struct {
 	Service Service
+	// Asks suspicious clients to solve a CAPTCHA on signup and sign-in
+	Captcha captcha.Service
 }
 
 // Constructor function
-func NewHandler(service Service) *Handler {
-	return &Handler{Service: service}
+func NewHandler(service Service, captchaService captcha.Service) *Handler {
+	return &Handler{Service: service, Captcha: captchaService}
 }
 
 // Handlers
 func (hd *Handler) SignupHandler(w http.ResponseWriter, r *http.Request) {
+	if err := hd.Captcha.Check(r); err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
 	var req SignupRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
@@ -46,7 +185,7 @@ func (hd *Handler) SignupHandler(w http.ResponseWriter, r *http.Request) {
 
 	walletAddress, err := hd.Service.CreateUserAccount(req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		utils.WriteError(w, r, err)
 		return
 	}
 
@@ -54,11 +193,15 @@ func (hd *Handler) SignupHandler(w http.ResponseWriter, r *http.Request) {
 		Message:       "User registered successfully",
 		WalletAddress: walletAddress,
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resp)
+	respond.JSON(w, r, resp)
 }
 
 func (hd *Handler) SignInHandler(w http.ResponseWriter, r *http.Request) {
+	if err := hd.Captcha.Check(r); err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
 	var credentials Credentials
 
 	if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
@@ -69,12 +212,276 @@ func (hd *Handler) SignInHandler(w http.ResponseWriter, r *http.Request) {
 	response, err := hd.Service.AuthenticateUser(struct {
 		Email    string
 		Password string
-	}(credentials))
+	}(credentials), NewLoginClient(r))
+	if errors.Is(err, utils.ErrForbidden) {
+		utils.WriteError(w, r, err)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	respond.JSON(w, r, response)
+}
+
+// RefreshTokensHandler trades a refresh token for a new access and refresh token
+func (hd *Handler) RefreshTokensHandler(w http.ResponseWriter, r *http.Request) {
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	response, err := hd.Service.RefreshTokens(req)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, response)
+}
+
+// DisownLoginHandler serves the "this wasn't me" link of a login alert, the signed link stands in
+// for credentials
+func (hd *Handler) DisownLoginHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	response, err := hd.Service.DisownLogin(mux.Vars(r)["eventID"], query.Get("expires"), query.Get("signature"))
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "private, no-store")
+	respond.JSON(w, r, response)
+}
+
+// ResetPasswordHandler sets a new password with a reset token
+func (hd *Handler) ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var req PasswordResetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := hd.Service.ResetPassword(req); err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetTimeZoneHandler returns the authenticated user's time zone
+func (hd *Handler) GetTimeZoneHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	response, err := hd.Service.GetTimeZone(userInfo.UserID)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, response)
+}
+
+// GetMeHandler returns the authenticated user, including the personal data it signed up with
+func (hd *Handler) GetMeHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	response, err := hd.Service.GetUser(viewerOf(r, userInfo), userInfo.UserID)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, response)
+}
+
+// GetUserHandler returns any user to holders of user.manage or pii.read, the fields shown follow
+// the permissions held
+func (hd *Handler) GetUserHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+	viewer := viewerOf(r, userInfo)
+	if !viewer.CanManageUsers && !viewer.CanReadPII {
+		http.Error(w, "Forbidden: user.manage or pii.read permission required", http.StatusForbidden)
+		return
+	}
+
+	response, err := hd.Service.GetUser(viewer, mux.Vars(r)["userID"])
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, response)
+}
+
+// viewerOf describes the authenticated user as a viewer of other users
+func viewerOf(r *http.Request, userInfo utils.User) Viewer {
+	return Viewer{
+		UserID:         userInfo.UserID,
+		CanManageUsers: middleware.HasPermission(r, domain.PermissionUserManage),
+		CanReadPII:     middleware.HasPermission(r, domain.PermissionPIIRead),
+	}
+}
+
+// SetTimeZoneHandler changes the authenticated user's time zone
+func (hd *Handler) SetTimeZoneHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req TimeZoneRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	response, err := hd.Service.SetTimeZone(userInfo.UserID, req)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, response)
+}
+
+// CloseAccountHandler closes the authenticated user's account
+func (hd *Handler) CloseAccountHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req CloseAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	response, err := hd.Service.CloseAccount(userInfo.UserID, req)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, response)
+}
+
+// AdminCloseAccountHandler lets a holder of user.manage close any account, optionally overriding
+// the checks
+func (hd *Handler) AdminCloseAccountHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req AdminCloseAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	response, err := hd.Service.AdminCloseAccount(userInfo.UserID, mux.Vars(r)["userID"], req)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, response)
+}
+
+// ImportUsersHandler starts a bulk import of the users in the CSV request body, needs user.manage
+func (hd *Handler) ImportUsersHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	response, err := hd.Service.ImportUsers(userInfo.UserID, http.MaxBytesReader(w, r.Body, maxImportFileBytes))
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, response)
+}
+
+// GetImportHandler reports the progress of a user import with the outcome of each row, needs user.manage
+func (hd *Handler) GetImportHandler(w http.ResponseWriter, r *http.Request) {
+	response, err := hd.Service.GetImport(mux.Vars(r)["importID"])
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, response)
+}
+
+// OIDCLoginHandler redirects the browser to the SSO provider
+func (hd *Handler) OIDCLoginHandler(w http.ResponseWriter, r *http.Request) {
+	start, err := hd.Service.StartOIDCLogin(r.Context())
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    start.State + "." + start.Verifier,
+		Path:     "/auth/oidc",
+		MaxAge:   int((10 * time.Minute).Seconds()),
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, start.URL, http.StatusFound)
+}
+
+// OIDCCallbackHandler finishes an SSO login and returns the same tokens as /signin
+func (hd *Handler) OIDCCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if providerError := query.Get("error"); providerError != "" {
+		http.Error(w, "SSO login was not completed: "+providerError, http.StatusUnauthorized)
+		return
+	}
+
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil {
+		http.Error(w, "SSO login expired, please try again", http.StatusUnauthorized)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Path: "/auth/oidc", MaxAge: -1, HttpOnly: true, Secure: true})
+
+	state, verifier, ok := strings.Cut(cookie.Value, ".")
+	if !ok || subtle.ConstantTimeCompare([]byte(state), []byte(query.Get("state"))) != 1 {
+		http.Error(w, "Invalid SSO state", http.StatusUnauthorized)
+		return
+	}
+
+	response, err := hd.Service.CompleteOIDCLogin(r.Context(), query.Get("code"), verifier, NewLoginClient(r))
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.JSON(w, r, response)
 }
@@ -0,0 +1,274 @@
+package user
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"log"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+// Outcome of an import row
+const (
+	importRowPending = "pending"
+	importRowCreated = "created"
+	importRowSkipped = "skipped"
+	importRowFailed  = "failed"
+)
+
+const maxImportRows = 5000
+
+// Columns of an import file, initial_funding may be left out
+var (
+	requiredImportColumns = []string{"email", "name", "role"}
+	fundingImportColumn   = "initial_funding"
+)
+
+// ImportUsers stores the rows of a CSV file and creates their accounts in the background. Rows
+// failing validation are recorded as failed straight away. Emails that already have an account
+// are skipped, so uploading the same file again only creates what is missing.
+func (sd service) ImportUsers(adminID string, file io.Reader) (UserImportResponse, error) {
+	rows, err := parseImportFile(file)
+	if err != nil {
+		return UserImportResponse{}, err
+	}
+
+	userImport, err := sd.userImportRepo.CreateImport(adminID, rows)
+	if err != nil {
+		return UserImportResponse{}, err
+	}
+	log.Printf("User import %s of %d rows started by %s", userImport.ImportID, len(rows), adminID)
+
+	go sd.runImport(userImport.ImportID)
+	return newUserImportResponse(userImport, rows), nil
+}
+
+// GetImport returns the progress of an import with the outcome of every row
+func (sd service) GetImport(importID string) (UserImportResponse, error) {
+	userImport, err := sd.userImportRepo.GetImport(importID)
+	if err != nil {
+		return UserImportResponse{}, err
+	}
+	rows, err := sd.userImportRepo.GetImportRows(importID, false)
+	if err != nil {
+		return UserImportResponse{}, err
+	}
+	return newUserImportResponse(userImport, rows), nil
+}
+
+// ResumeImports picks up imports interrupted by a restart, their pending rows are processed again
+func (sd service) ResumeImports() {
+	importIDs, err := sd.userImportRepo.GetRunningImportIDs()
+	if err != nil {
+		log.Printf("Error looking for interrupted user imports: %v", err)
+		return
+	}
+	for _, importID := range importIDs {
+		log.Printf("Resuming user import %s", importID)
+		sd.runImport(importID)
+	}
+}
+
+// runImport creates the accounts of the pending rows one at a time
+func (sd service) runImport(importID string) {
+	rows, err := sd.userImportRepo.GetImportRows(importID, true)
+	if err != nil {
+		log.Printf("Error loading rows of user import %s: %v", importID, err)
+		return
+	}
+
+	unrecorded := 0
+	for _, row := range rows {
+		row = sd.importRow(row)
+		if err := sd.userImportRepo.UpdateImportRow(importID, row); err != nil {
+			// The row stays pending and is retried, or skipped if created, when the import is resumed
+			log.Printf("Error recording row %d of user import %s: %v", row.RowNumber, importID, err)
+			unrecorded++
+		}
+	}
+	if unrecorded > 0 {
+		log.Printf("User import %s left %d rows pending", importID, unrecorded)
+		return
+	}
+
+	if err := sd.userImportRepo.CompleteImport(importID); err != nil {
+		log.Printf("Error completing user import %s: %v", importID, err)
+		return
+	}
+	log.Printf("User import %s completed", importID)
+}
+
+// importRow creates the account of one row, or skips it when the email is already registered
+func (sd service) importRow(row repo.UserImportRow) repo.UserImportRow {
+	existing, err := sd.userRepo.GetUserByEmail(row.Email)
+	if err == nil {
+		row.Status = importRowSkipped
+		row.UserID = existing.ID
+		return row
+	}
+	if !errors.Is(err, utils.ErrNotFound) {
+		row.Status = importRowFailed
+		row.Error = err.Error()
+		return row
+	}
+
+	// Imported users sign in through SSO, their password is random and never disclosed
+	password, err := randomToken(32)
+	if err != nil {
+		row.Status = importRowFailed
+		row.Error = err.Error()
+		return row
+	}
+	suffix, err := randomToken(3)
+	if err != nil {
+		row.Status = importRowFailed
+		row.Error = err.Error()
+		return row
+	}
+	localPart, _, _ := strings.Cut(row.Email, "@")
+
+	var funding *big.Int
+	if row.InitialFunding != "" {
+		funding, _ = new(big.Int).SetString(row.InitialFunding, 10)
+	}
+
+	walletAddress, err := sd.createAccount(SignupRequest{
+		Username: localPart + "_" + suffix,
+		Email:    row.Email,
+		Password: password,
+		FullName: row.FullName,
+		Role:     strconv.Itoa(row.Role),
+	}, funding)
+	if err != nil {
+		row.Status = importRowFailed
+		row.Error = err.Error()
+		return row
+	}
+
+	user, err := sd.userRepo.GetUserByEmail(row.Email)
+	if err != nil {
+		log.Printf("Error retrieving imported user %s: %v", row.Email, err)
+	}
+	row.Status = importRowCreated
+	row.UserID = user.ID
+	row.WalletID = walletAddress
+	return row
+}
+
+// parseImportFile reads the CSV header and rows, recording invalid rows as failed
+func parseImportFile(file io.Reader) ([]repo.UserImportRow, error) {
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, utils.Validation("the file must start with a CSV header")
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range requiredImportColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, utils.Validationf("missing column %q", name)
+		}
+	}
+
+	var rows []repo.UserImportRow
+	for rowNumber := 1; ; rowNumber++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, utils.Validationf("row %d is not valid CSV", rowNumber)
+		}
+		if len(rows) == maxImportRows {
+			return nil, utils.Validationf("at most %d rows can be imported at once", maxImportRows)
+		}
+		rows = append(rows, parseImportRecord(rowNumber, record, columns))
+	}
+	if len(rows) == 0 {
+		return nil, utils.Validation("the file has no rows to import")
+	}
+	return rows, nil
+}
+
+// parseImportRecord validates one CSV record
+func parseImportRecord(rowNumber int, record []string, columns map[string]int) repo.UserImportRow {
+	field := func(name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	row := repo.UserImportRow{
+		RowNumber:      rowNumber,
+		Email:          strings.ToLower(field("email")),
+		FullName:       field("name"),
+		InitialFunding: field(fundingImportColumn),
+		Status:         importRowPending,
+	}
+
+	role, err := strconv.Atoi(field("role"))
+	switch {
+	case !strings.Contains(row.Email, "@"):
+		row.Error = "invalid email"
+	case err != nil || !slices.Contains([]int{1, 2}, role):
+		row.Error = "role must be 1 or 2"
+	case row.InitialFunding != "" && !isNonNegativeInteger(row.InitialFunding):
+		row.Error = "initial_funding must be a whole number of wei"
+	}
+	row.Role = role
+	if row.Error != "" {
+		row.Status = importRowFailed
+		row.InitialFunding = ""
+	}
+	return row
+}
+
+func isNonNegativeInteger(value string) bool {
+	amount, ok := new(big.Int).SetString(value, 10)
+	return ok && amount.Sign() >= 0
+}
+
+func newUserImportResponse(userImport repo.UserImport, rows []repo.UserImportRow) UserImportResponse {
+	response := UserImportResponse{
+		ImportID:    userImport.ImportID,
+		Status:      userImport.Status,
+		Total:       len(rows),
+		Rows:        make([]ImportRowResponse, len(rows)),
+		CreatedAt:   userImport.CreatedAt,
+		CompletedAt: userImport.CompletedAt,
+	}
+	for i, row := range rows {
+		switch row.Status {
+		case importRowPending:
+			response.Pending++
+		case importRowCreated:
+			response.Created++
+		case importRowSkipped:
+			response.Skipped++
+		case importRowFailed:
+			response.Failed++
+		}
+		response.Rows[i] = ImportRowResponse{
+			Row:      row.RowNumber,
+			Email:    row.Email,
+			Status:   row.Status,
+			UserID:   row.UserID,
+			WalletID: row.WalletID,
+			Error:    row.Error,
+		}
+	}
+	return response
+}
@@ -0,0 +1,323 @@
+package user
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/notification"
+	"github.com/CodeWithKrushnal/ChainBank/internal/config"
+	"github.com/CodeWithKrushnal/ChainBank/internal/domain"
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+	"github.com/CodeWithKrushnal/ChainBank/middleware"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Ways of signing in
+const (
+	LoginMethodPassword = "password"
+	LoginMethodOIDC     = "oidc"
+	LoginMethodSIWE     = "siwe"
+)
+
+// Earlier sign-ins a new one is compared with to tell whether it comes from a known device
+const knownDeviceLookback = 50
+
+// LoginClient identifies the device a sign-in came from
+type LoginClient struct {
+	IPAddress string
+	UserAgent string
+}
+
+// NewLoginClient reads the client address and user agent of a sign-in request
+func NewLoginClient(r *http.Request) LoginClient {
+	return LoginClient{IPAddress: middleware.ClientIP(r), UserAgent: r.UserAgent()}
+}
+
+// RecordLogin stores a sign-in for the user's activity feed and alerts the user when it comes from a
+// network or user agent they had not signed in from before. A failure is only logged, it does not
+// fail the sign-in.
+func (sd service) RecordLogin(userID, method string, client LoginClient) {
+	newDevice := false
+	previous, err := sd.loginEventRepo.GetLoginEvents(userID, knownDeviceLookback)
+	if err != nil {
+		log.Printf("Error loading earlier logins of %s, skipping the new device check: %v", userID, err)
+	} else {
+		newDevice = isNewDevice(previous, client)
+	}
+
+	event, err := sd.loginEventRepo.RecordLogin(repo.LoginEvent{
+		UserID:    userID,
+		Method:    method,
+		IPAddress: client.IPAddress,
+		UserAgent: client.UserAgent,
+		NewDevice: newDevice,
+	})
+	if err != nil {
+		log.Printf("Error recording %s login of %s: %v", method, userID, err)
+		return
+	}
+
+	if newDevice {
+		sd.sendLoginAlert(event)
+	}
+}
+
+// DisownLogin handles the "this wasn't me" link of a login alert. The first use revokes every login
+// token of the account and blocks password sign-in, each use until the password is reset returns a
+// reset token for ResetPassword.
+func (sd service) DisownLogin(eventID, expires, signature string) (DisownLoginResponse, error) {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt || !hmac.Equal([]byte(signature), []byte(signDisownLogin(eventID, expiresAt))) {
+		return DisownLoginResponse{}, utils.Forbidden("link is invalid or has expired")
+	}
+
+	event, err := sd.loginEventRepo.GetLoginEvent(eventID)
+	if err != nil {
+		return DisownLoginResponse{}, err
+	}
+
+	disowned, err := sd.loginEventRepo.DisownLogin(eventID)
+	if err != nil {
+		return DisownLoginResponse{}, err
+	}
+	if disowned {
+		if err := sd.userRepo.RevokeSessions(event.UserID); err != nil {
+			return DisownLoginResponse{}, err
+		}
+		log.Printf("User %s reported login %s from %s, sessions revoked", event.UserID, eventID, event.IPAddress)
+	}
+
+	user, err := sd.userRepo.GetUserByID(event.UserID)
+	if err != nil {
+		return DisownLoginResponse{}, err
+	}
+	if !user.PasswordResetRequired {
+		return DisownLoginResponse{}, utils.Conflict("this sign-in was already reported and the password has been reset")
+	}
+
+	resetToken, err := newResetToken(user.Email)
+	if err != nil {
+		return DisownLoginResponse{}, err
+	}
+	return DisownLoginResponse{LoginEventID: eventID, SessionsRevoked: true, ResetToken: resetToken}, nil
+}
+
+// ResetPassword replaces the password of the reset token's user and revokes the user's login tokens.
+// Reset tokens issued before the sessions were last revoked are refused.
+func (sd service) ResetPassword(req PasswordResetRequest) error {
+	email, issuedAt, err := parseResetToken(req.ResetToken)
+	if err != nil {
+		return utils.NewError(utils.ErrUnauthorized, "reset token is invalid or has expired", err)
+	}
+
+	user, err := sd.userRepo.GetUserByEmail(email)
+	if err != nil {
+		return utils.Unauthorized("reset token is invalid or has expired")
+	}
+	if user.AccountStatus == domain.AccountClosed {
+		return utils.Unauthorized("account is closed")
+	}
+	if user.TokensValidAfter != nil && issuedAt.Before(user.TokensValidAfter.Truncate(time.Second)) {
+		return utils.Unauthorized("reset token is invalid or has expired")
+	}
+	if problems := passwordProblems(req.NewPassword, user.Username); len(problems) > 0 {
+		for i := range problems {
+			problems[i].Field = "new_password"
+		}
+		return utils.ValidationFields(problems)
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if err := sd.userRepo.ResetPassword(user.ID, string(hashedPassword)); err != nil {
+		return err
+	}
+	log.Printf("Password of %s was reset", user.ID)
+	return nil
+}
+
+// sendLoginAlert notifies the user of a sign-in from a new device with a link to report it
+func (sd service) sendLoginAlert(event repo.LoginEvent) {
+	expiresAt := time.Now().Add(time.Duration(config.ConfigDetails.LoginAlertLinkTTLHours) * time.Hour)
+	link := disownLoginURL(event.EventID, expiresAt)
+
+	sd.notificationService.Notify(notification.Notification{
+		UserID:   event.UserID,
+		Category: notification.CategorySecurity,
+		Title:    "New sign-in to your account",
+		Body:     "Your account was signed in from a new device or network (%s, %s) at %s. If this wasn't you, open %s to sign out everywhere and reset your password.",
+		BodyArgs: []any{event.IPAddress, describeUserAgent(event.UserAgent), event.CreatedAt.UTC().Format(time.RFC1123), link},
+		Data:     map[string]string{"login_event_id": event.EventID, "ip_address": event.IPAddress, "disown_url": link},
+	})
+}
+
+// isNewDevice reports whether the client's network or user agent is missing from the user's earlier
+// sign-ins. The first sign-in and sign-ins the user reported are not compared against.
+func isNewDevice(previous []repo.LoginEvent, client LoginClient) bool {
+	network := networkOf(client.IPAddress)
+	compared, knownNetwork, knownUserAgent := false, false, false
+	for _, event := range previous {
+		if event.DisownedAt != nil {
+			continue
+		}
+		compared = true
+		knownNetwork = knownNetwork || networkOf(event.IPAddress) == network
+		knownUserAgent = knownUserAgent || event.UserAgent == client.UserAgent
+	}
+	return compared && !(knownNetwork && knownUserAgent)
+}
+
+// networkOf returns the /24 of an IPv4 address or the /48 of an IPv6 address, so that addresses
+// handed out by the same provider at the same place count as one network
+func networkOf(ipAddress string) string {
+	addr, err := netip.ParseAddr(ipAddress)
+	if err != nil {
+		return ipAddress
+	}
+	addr = addr.Unmap()
+
+	bits := 48
+	if addr.Is4() {
+		bits = 24
+	}
+	prefix, err := addr.Prefix(bits)
+	if err != nil {
+		return ipAddress
+	}
+	return prefix.String()
+}
+
+// describeUserAgent shortens a user agent for display in an alert
+func describeUserAgent(userAgent string) string {
+	if userAgent == "" {
+		return "unknown client"
+	}
+	const maxLength = 120
+	if len(userAgent) > maxLength {
+		return strings.ToValidUTF8(userAgent[:maxLength], "") + "…"
+	}
+	return userAgent
+}
+
+// disownLoginURL returns the "this wasn't me" link of a sign-in, valid until expiresAt
+func disownLoginURL(eventID string, expiresAt time.Time) string {
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expiresAt.Unix(), 10))
+	query.Set("signature", signDisownLogin(eventID, expiresAt.Unix()))
+	return strings.TrimSuffix(config.ConfigDetails.PublicBaseURL, "/") + "/auth/logins/" + url.PathEscape(eventID) + "/disown?" + query.Encode()
+}
+
+// signDisownLogin authenticates a "this wasn't me" link of eventID expiring at the given Unix time
+func signDisownLogin(eventID string, expiresAt int64) string {
+	mac := hmac.New(sha256.New, []byte(config.ConfigDetails.JWTSecretKey))
+	fmt.Fprintf(mac, "login-disown:%s:%d", eventID, expiresAt)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// RefreshTokens issues new tokens for a refresh token and uses the old one up. A refresh token
+// presented again after its use may have been stolen, every open refresh token of the user is then
+// revoked. Refresh tokens are also revoked along with the access tokens, and refused while the
+// account is closed or waits for a password reset.
+func (sd service) RefreshTokens(req RefreshRequest) (AuthResponse, error) {
+	email, tokenID, issuedAt, err := parseRefreshToken(req.RefreshToken)
+	if err != nil {
+		return AuthResponse{}, utils.NewError(utils.ErrUnauthorized, "refresh token is invalid or has expired", err)
+	}
+
+	user, err := sd.userRepo.GetUserWithRoleByEmail(email)
+	if err != nil {
+		return AuthResponse{}, utils.Unauthorized("refresh token is invalid or has expired")
+	}
+
+	claimed, err := sd.refreshTokenRepo.UseRefreshToken(tokenID, user.ID)
+	if err != nil {
+		return AuthResponse{}, err
+	}
+	if !claimed {
+		if err := sd.refreshTokenRepo.RevokeRefreshTokens(user.ID); err != nil {
+			return AuthResponse{}, err
+		}
+		log.Printf("Refresh token %s of user %s was used again, refresh tokens revoked", tokenID, user.ID)
+		return AuthResponse{}, utils.Unauthorized("refresh token is invalid or has expired")
+	}
+
+	if user.AccountStatus == domain.AccountClosed {
+		return AuthResponse{}, utils.Unauthorized("account is closed")
+	}
+	if user.TokensValidAfter != nil && issuedAt.Before(user.TokensValidAfter.Truncate(time.Second)) {
+		return AuthResponse{}, utils.Unauthorized("refresh token is invalid or has expired")
+	}
+	if user.PasswordResetRequired {
+		return AuthResponse{}, utils.Forbidden("password reset required, use the link from the security alert")
+	}
+
+	return sd.IssueTokens(user)
+}
+
+// parseRefreshToken checks a refresh token and returns its email, token ID and issue time
+func parseRefreshToken(tokenString string) (string, string, time.Time, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		return []byte(config.ConfigDetails.JWTSecretKey), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired(), jwt.WithIssuedAt())
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return "", "", time.Time{}, errors.New("invalid token claims")
+	}
+	if refresh, _ := claims["refresh"].(bool); !refresh {
+		return "", "", time.Time{}, errors.New("not a refresh token")
+	}
+	email, _ := claims["email"].(string)
+	// Tokens are looked up by their ID, one that is no UUID was not issued here
+	tokenID, _ := claims["jti"].(string)
+	issuedAt, err := claims.GetIssuedAt()
+	if email == "" || err != nil || issuedAt == nil {
+		return "", "", time.Time{}, errors.New("invalid token claims")
+	}
+	if parsedID, err := uuid.Parse(tokenID); err != nil || parsedID.String() != tokenID {
+		return "", "", time.Time{}, errors.New("invalid token ID")
+	}
+	return email, tokenID, issuedAt.Time, nil
+}
+
+// parseResetToken checks a reset token and returns its email and issue time
+func parseResetToken(tokenString string) (string, time.Time, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		return []byte(config.ConfigDetails.JWTResetSecretKey), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired(), jwt.WithIssuedAt())
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return "", time.Time{}, errors.New("invalid token claims")
+	}
+	if reset, _ := claims["reset"].(bool); !reset {
+		return "", time.Time{}, errors.New("not a reset token")
+	}
+	email, _ := claims["email"].(string)
+	issuedAt, err := claims.GetIssuedAt()
+	if email == "" || err != nil || issuedAt == nil {
+		return "", time.Time{}, errors.New("invalid token claims")
+	}
+	return email, issuedAt.Time, nil
+}
@@ -0,0 +1,151 @@
+package user
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/config"
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestRefreshTokensRotation(t *testing.T) {
+	setTestSecrets(t)
+	tokens := &memoryRefreshTokenRepo{}
+	sd := service{userRepo: signedInUserRepo{user: testUser}, refreshTokenRepo: tokens}
+
+	first, err := sd.IssueTokens(testUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := sd.RefreshTokens(RefreshRequest{RefreshToken: first.RefreshToken})
+	if err != nil {
+		t.Fatalf("refreshing a fresh token: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken || second.User.ID != testUser.ID {
+		t.Fatalf("refresh returned %+v, want a new refresh token for %s", second, testUser.ID)
+	}
+
+	// The used token is refused and takes the token issued for it along
+	if _, err := sd.RefreshTokens(RefreshRequest{RefreshToken: first.RefreshToken}); !errors.Is(err, utils.ErrUnauthorized) {
+		t.Fatalf("reusing a refresh token: %v, want unauthorized", err)
+	}
+	if _, err := sd.RefreshTokens(RefreshRequest{RefreshToken: second.RefreshToken}); !errors.Is(err, utils.ErrUnauthorized) {
+		t.Errorf("refreshing after a reuse: %v, want unauthorized", err)
+	}
+}
+
+func TestRefreshTokensRejectsOtherTokens(t *testing.T) {
+	setTestSecrets(t)
+	tokens := &memoryRefreshTokenRepo{}
+	sd := service{userRepo: signedInUserRepo{user: testUser}, refreshTokenRepo: tokens}
+
+	issued, err := sd.IssueTokens(testUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Signed with the right key and claims, but never stored
+	unknown, err := GenerateTokens(testUser, "0b6c2a4e-1d2f-4b8e-9c3a-5e7f9a1b3c5d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	withoutID := signTestToken(t, jwt.MapClaims{
+		"email": testUser.Email, "exp": time.Now().Add(time.Hour).Unix(), "iat": time.Now().Unix(), "refresh": true,
+	})
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"access token", issued.AccessToken},
+		{"reset token", issued.ResetToken},
+		{"no token ID", withoutID},
+		{"garbage", "not a token"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := sd.RefreshTokens(RefreshRequest{RefreshToken: test.token}); !errors.Is(err, utils.ErrUnauthorized) {
+				t.Errorf("refresh: %v, want unauthorized", err)
+			}
+		})
+	}
+
+	// Tokens that are not refresh tokens of this server leave the issued one alone
+	if _, err := sd.RefreshTokens(RefreshRequest{RefreshToken: issued.RefreshToken}); err != nil {
+		t.Errorf("refreshing the issued token: %v", err)
+	}
+
+	// A token signed here but not on record counts as reused
+	if _, err := sd.RefreshTokens(RefreshRequest{RefreshToken: unknown.RefreshToken}); !errors.Is(err, utils.ErrUnauthorized) {
+		t.Errorf("refreshing an unknown token ID: %v, want unauthorized", err)
+	}
+}
+
+var testUser = repo.UserWithRole{User: repo.User{ID: "7f1c1f1e-8f55-4c55-9b8e-6a4f2c0d9a11", Email: "refresh@example.com"}, Role: 1}
+
+func setTestSecrets(t *testing.T) {
+	previous := config.ConfigDetails
+	t.Cleanup(func() { config.ConfigDetails = previous })
+	config.ConfigDetails.JWTSecretKey = "login-test-secret"
+	config.ConfigDetails.JWTResetSecretKey = "login-test-reset-secret"
+}
+
+func signTestToken(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(config.ConfigDetails.JWTSecretKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// signedInUserRepo finds the one user every token here is issued for
+type signedInUserRepo struct {
+	repo.UserStorer
+	user repo.UserWithRole
+}
+
+func (users signedInUserRepo) GetUserWithRoleByEmail(email string) (repo.UserWithRole, error) {
+	if email != users.user.Email {
+		return repo.UserWithRole{}, utils.NotFound("user not found", nil)
+	}
+	return users.user, nil
+}
+
+// memoryRefreshTokenRepo keeps refresh tokens like the refresh_tokens table
+type memoryRefreshTokenRepo struct {
+	tokens []memoryRefreshToken
+}
+
+type memoryRefreshToken struct {
+	tokenID, userID string
+	expiresAt       time.Time
+	used            bool
+}
+
+func (tokens *memoryRefreshTokenRepo) CreateRefreshToken(tokenID, userID string, expiresAt time.Time) error {
+	tokens.tokens = append(tokens.tokens, memoryRefreshToken{tokenID: tokenID, userID: userID, expiresAt: expiresAt})
+	return nil
+}
+
+func (tokens *memoryRefreshTokenRepo) UseRefreshToken(tokenID, userID string) (bool, error) {
+	for i, token := range tokens.tokens {
+		if token.tokenID == tokenID && token.userID == userID && !token.used && token.expiresAt.After(time.Now()) {
+			tokens.tokens[i].used = true
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (tokens *memoryRefreshTokenRepo) RevokeRefreshTokens(userID string) error {
+	for i, token := range tokens.tokens {
+		if token.userID == userID {
+			tokens.tokens[i].used = true
+		}
+	}
+	return nil
+}
@@ -0,0 +1,278 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/domain"
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+const oidcDiscoveryTimeout = 10 * time.Second
+
+// oidcClaims are the standard claims read from the provider's userinfo endpoint
+type oidcClaims struct {
+	Subject       string `json:"sub"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+	Birthdate     string `json:"birthdate"`
+}
+
+// oidcProvider signs users in with an OpenID Connect provider using the authorization code flow
+// with PKCE. The provider metadata is discovered on first use so startup never waits on it.
+type oidcProvider struct {
+	issuer       string
+	clientID     string
+	clientSecret string
+	redirectURL  string
+
+	mu          sync.Mutex
+	oauthConfig *oauth2.Config
+	userInfoURL string
+}
+
+// discovery is the part of /.well-known/openid-configuration the login flow needs
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+}
+
+func newOIDCProvider(issuer, clientID, clientSecret, redirectURL string) *oidcProvider {
+	return &oidcProvider{
+		issuer:       strings.TrimRight(issuer, "/"),
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		redirectURL:  redirectURL,
+	}
+}
+
+// authCodeURL returns the provider login URL for the given state and PKCE verifier
+func (op *oidcProvider) authCodeURL(ctx context.Context, state, verifier string) (string, error) {
+	oauthConfig, _, err := op.metadata(ctx)
+	if err != nil {
+		return "", err
+	}
+	return oauthConfig.AuthCodeURL(state, oauth2.S256ChallengeOption(verifier)), nil
+}
+
+// claims exchanges the authorization code and reads the signed-in user's claims
+func (op *oidcProvider) claims(ctx context.Context, code, verifier string) (oidcClaims, error) {
+	oauthConfig, userInfoURL, err := op.metadata(ctx)
+	if err != nil {
+		return oidcClaims{}, err
+	}
+
+	token, err := oauthConfig.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return oidcClaims{}, fmt.Errorf("exchanging authorization code: %w", err)
+	}
+
+	resp, err := oauthConfig.Client(ctx, token).Get(userInfoURL)
+	if err != nil {
+		return oidcClaims{}, fmt.Errorf("fetching userinfo: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return oidcClaims{}, fmt.Errorf("userinfo returned %s", resp.Status)
+	}
+
+	var claims oidcClaims
+	if err := json.NewDecoder(resp.Body).Decode(&claims); err != nil {
+		return oidcClaims{}, fmt.Errorf("decoding userinfo: %w", err)
+	}
+	if claims.Subject == "" {
+		return oidcClaims{}, fmt.Errorf("userinfo has no subject")
+	}
+	return claims, nil
+}
+
+// metadata discovers the provider endpoints once and caches them
+func (op *oidcProvider) metadata(ctx context.Context) (*oauth2.Config, string, error) {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+
+	if op.oauthConfig != nil {
+		return op.oauthConfig, op.userInfoURL, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, oidcDiscoveryTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, op.issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("discovering OIDC provider: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("discovering OIDC provider: %s", resp.Status)
+	}
+
+	var metadata discovery
+	if err := json.NewDecoder(resp.Body).Decode(&metadata); err != nil {
+		return nil, "", fmt.Errorf("decoding OIDC discovery document: %w", err)
+	}
+	if strings.TrimRight(metadata.Issuer, "/") != op.issuer {
+		return nil, "", fmt.Errorf("OIDC issuer mismatch: configured %s, provider reports %s", op.issuer, metadata.Issuer)
+	}
+
+	op.oauthConfig = &oauth2.Config{
+		ClientID:     op.clientID,
+		ClientSecret: op.clientSecret,
+		RedirectURL:  op.redirectURL,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  metadata.AuthorizationEndpoint,
+			TokenURL: metadata.TokenEndpoint,
+		},
+		Scopes: []string{"openid", "email", "profile"},
+	}
+	op.userInfoURL = metadata.UserinfoEndpoint
+	return op.oauthConfig, op.userInfoURL, nil
+}
+
+// StartOIDCLogin returns the provider login URL along with the state and PKCE verifier the
+// callback has to present
+func (sd service) StartOIDCLogin(ctx context.Context) (OIDCLoginStart, error) {
+	if sd.oidc == nil {
+		return OIDCLoginStart{}, utils.NotFound("SSO login is not configured", nil)
+	}
+
+	state, err := randomToken(16)
+	if err != nil {
+		return OIDCLoginStart{}, err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	loginURL, err := sd.oidc.authCodeURL(ctx, state, verifier)
+	if err != nil {
+		return OIDCLoginStart{}, utils.Upstream("SSO provider is unavailable", err)
+	}
+	return OIDCLoginStart{URL: loginURL, State: state, Verifier: verifier}, nil
+}
+
+// CompleteOIDCLogin signs in the user behind the authorization code. A known identity signs in its
+// linked account, otherwise the identity is linked to the account with the same verified email,
+// or a new account and wallet are created for it.
+func (sd service) CompleteOIDCLogin(ctx context.Context, code, verifier string, client LoginClient) (AuthResponse, error) {
+	if sd.oidc == nil {
+		return AuthResponse{}, utils.NotFound("SSO login is not configured", nil)
+	}
+
+	claims, err := sd.oidc.claims(ctx, code, verifier)
+	if err != nil {
+		return AuthResponse{}, utils.NewError(utils.ErrUnauthorized, "SSO login failed", err)
+	}
+
+	identityUser, err := sd.userForIdentity(claims)
+	if err != nil {
+		return AuthResponse{}, err
+	}
+	user, err := sd.userRepo.GetUserWithRoleByID(identityUser.ID)
+	if err != nil {
+		return AuthResponse{}, err
+	}
+	if user.AccountStatus == domain.AccountClosed {
+		return AuthResponse{}, utils.Unauthorized("account is closed")
+	}
+
+	response, err := sd.IssueTokens(user)
+	if err != nil {
+		return AuthResponse{}, err
+	}
+	sd.RecordLogin(user.ID, LoginMethodOIDC, client)
+
+	return response, nil
+}
+
+// userForIdentity finds, links or creates the local account of an SSO identity
+func (sd service) userForIdentity(claims oidcClaims) (repo.User, error) {
+	issuer := sd.oidc.issuer
+
+	userID, err := sd.userRepo.GetIdentityUserID(issuer, claims.Subject)
+	if err == nil {
+		return sd.userRepo.GetUserByID(userID)
+	}
+	if !errors.Is(err, utils.ErrNotFound) {
+		return repo.User{}, err
+	}
+
+	// Linking by email is only safe when the provider vouches for the address
+	if claims.Email == "" || !claims.EmailVerified {
+		return repo.User{}, utils.Unauthorized("SSO account has no verified email")
+	}
+
+	user, err := sd.userRepo.GetUserByEmail(claims.Email)
+	if errors.Is(err, sql.ErrNoRows) {
+		if user, err = sd.createSSOUser(claims); err != nil {
+			return repo.User{}, err
+		}
+		log.Printf("Created account %s on first SSO login", user.ID)
+	} else if err != nil {
+		return repo.User{}, err
+	}
+
+	if err := sd.userRepo.LinkIdentity(issuer, claims.Subject, user.ID, claims.Email); err != nil {
+		return repo.User{}, err
+	}
+	return user, nil
+}
+
+// createSSOUser signs up an SSO user with a generated username and an unusable random password
+func (sd service) createSSOUser(claims oidcClaims) (repo.User, error) {
+	password, err := randomToken(32)
+	if err != nil {
+		return repo.User{}, err
+	}
+	suffix, err := randomToken(3)
+	if err != nil {
+		return repo.User{}, err
+	}
+	localPart, _, _ := strings.Cut(claims.Email, "@")
+
+	_, err = sd.createAccount(SignupRequest{
+		Username: localPart + "_" + suffix,
+		Email:    claims.Email,
+		Password: password,
+		FullName: claims.Name,
+		DOB:      claims.Birthdate,
+		Role:     "1",
+	}, big.NewInt(1e18))
+	if err != nil {
+		return repo.User{}, err
+	}
+
+	user, err := sd.userRepo.GetUserByEmail(claims.Email)
+	if err != nil {
+		return repo.User{}, utils.FromDBError("user", err)
+	}
+	return user, nil
+}
+
+// randomToken returns n random bytes in hex
+func randomToken(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,61 @@
+package user
+
+import "github.com/CodeWithKrushnal/ChainBank/internal/repo"
+
+// Viewer is who a user is serialized for, what it may see of other users follows from its permissions
+type Viewer struct {
+	UserID string
+	// Holders of user.manage see the state of accounts and sessions
+	CanManageUsers bool
+	// Holders of pii.read see the name and date of birth
+	CanReadPII bool
+}
+
+// GetUser returns a user as the viewer may see it. Personal data is only decrypted for the user
+// itself and holders of pii.read.
+func (sd service) GetUser(viewer Viewer, userID string) (UserResponse, error) {
+	user, err := sd.userRepo.GetUserWithRoleByID(userID)
+	if err != nil {
+		return UserResponse{}, err
+	}
+
+	var pii *repo.UserPII
+	if viewer.UserID == user.ID || viewer.CanReadPII {
+		userPII, err := sd.userRepo.GetUserPII(user.ID)
+		if err != nil {
+			return UserResponse{}, err
+		}
+		pii = &userPII
+	}
+	return newUserResponse(user, pii, viewer), nil
+}
+
+// newUserResponse is the one place users are turned into responses. Fields are copied one by one
+// for the viewer, so the password hash and fields added to repo.User later never serialize unless
+// they are added here.
+func newUserResponse(user repo.UserWithRole, pii *repo.UserPII, viewer Viewer) UserResponse {
+	response := UserResponse{UserID: user.ID, Username: user.Username}
+	self := viewer.UserID == user.ID
+
+	if self || viewer.CanManageUsers || viewer.CanReadPII {
+		createdAt := user.CreatedAt
+		response.Email = user.Email
+		response.Role = user.Role
+		response.AccountStatus = user.AccountStatus
+		response.CreatedAt = &createdAt
+	}
+	if self || viewer.CanManageUsers {
+		passwordResetRequired := user.PasswordResetRequired
+		response.PasswordResetRequired = &passwordResetRequired
+	}
+	if viewer.CanManageUsers {
+		response.TokensValidAfter = user.TokensValidAfter
+	}
+	if pii != nil && (self || viewer.CanReadPII) {
+		response.FullName = pii.FullName
+		if pii.DateOfBirth != nil {
+			response.DateOfBirth = pii.DateOfBirth.Format(dateLayout)
+		}
+	}
+	return response
+}
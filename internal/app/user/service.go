@@ -1,64 +1,152 @@
 package user
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"encoding/hex"
+	"fmt"
+	"io"
 	"log"
 	"math/big"
 	"strconv"
 	"time"
 
 	"github.com/CodeWithKrushnal/ChainBank/internal/app/ethereum"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/notification"
 	"github.com/CodeWithKrushnal/ChainBank/internal/config"
+	"github.com/CodeWithKrushnal/ChainBank/internal/domain"
 	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type service struct {
-	userRepo   repo.UserStorer
-	walletRepo repo.WalletStorer
-	ethRepo    ethereum.EthRepo
+	userRepo            repo.UserStorer
+	walletRepo          repo.WalletStorer
+	transactionRepo     repo.TransactionStorer
+	userImportRepo      repo.UserImportStorer
+	loginEventRepo      repo.LoginEventStorer
+	refreshTokenRepo    repo.RefreshTokenStorer
+	notificationService notification.Service
+	ethRepo             ethereum.EthRepo
+	// nil while no HD seed is configured, wallets then get a random keystore key
+	hdWallet *ethereum.HDWallet
+	// nil while SSO login is not configured
+	oidc *oidcProvider
 }
 
 // Constructor function
-func NewService(userRepo repo.UserStorer, walletRepo repo.WalletStorer, ethRepo ethereum.EthRepo) Service {
-	return service{
-		userRepo:   userRepo,
-		walletRepo: walletRepo,
-		ethRepo:    ethRepo,
+func NewService(userRepo repo.UserStorer, walletRepo repo.WalletStorer, transactionRepo repo.TransactionStorer, userImportRepo repo.UserImportStorer, loginEventRepo repo.LoginEventStorer, refreshTokenRepo repo.RefreshTokenStorer, notificationService notification.Service, ethRepo ethereum.EthRepo, hdWallet *ethereum.HDWallet) Service {
+	sd := service{
+		userRepo:            userRepo,
+		walletRepo:          walletRepo,
+		transactionRepo:     transactionRepo,
+		userImportRepo:      userImportRepo,
+		loginEventRepo:      loginEventRepo,
+		refreshTokenRepo:    refreshTokenRepo,
+		notificationService: notificationService,
+		ethRepo:             ethRepo,
+		hdWallet:            hdWallet,
 	}
+
+	cfg := config.ConfigDetails
+	if cfg.OIDCIssuerURL != "" {
+		sd.oidc = newOIDCProvider(cfg.OIDCIssuerURL, cfg.OIDCClientID, cfg.OIDCClientSecret, cfg.OIDCRedirectURL)
+	}
+	return sd
 }
 
 // Add necesary method signature to be made accesible by service layer
 type Service interface {
 	CreateUserAccount(req SignupRequest) (string, error)
-	AuthenticateUser(credentials struct{ Email, Password string }) (map[string]string, error)
+	AuthenticateUser(credentials struct{ Email, Password string }, client LoginClient) (AuthResponse, error)
+	RefreshTokens(req RefreshRequest) (AuthResponse, error)
+	IssueTokens(user repo.UserWithRole) (AuthResponse, error)
+	CloseAccount(userID string, req CloseAccountRequest) (CloseAccountResponse, error)
+	AdminCloseAccount(adminID, userID string, req AdminCloseAccountRequest) (CloseAccountResponse, error)
+	StartOIDCLogin(ctx context.Context) (OIDCLoginStart, error)
+	CompleteOIDCLogin(ctx context.Context, code, verifier string, client LoginClient) (AuthResponse, error)
+	ImportUsers(adminID string, file io.Reader) (UserImportResponse, error)
+	GetImport(importID string) (UserImportResponse, error)
+	ResumeImports()
+	GetTimeZone(userID string) (TimeZoneResponse, error)
+	SetTimeZone(userID string, req TimeZoneRequest) (TimeZoneResponse, error)
+	GetUser(viewer Viewer, userID string) (UserResponse, error)
+	RecordLogin(userID, method string, client LoginClient)
+	DisownLogin(eventID, expires, signature string) (DisownLoginResponse, error)
+	ResetPassword(req PasswordResetRequest) error
+}
+
+// Lifetimes of the tokens a sign-in returns
+const (
+	accessTokenTTL  = 24 * time.Hour
+	refreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// IssueTokens signs the tokens of a sign-in and stores the ID of its refresh token, which refreshes once
+func (sd service) IssueTokens(user repo.UserWithRole) (AuthResponse, error) {
+	refreshTokenID := uuid.NewString()
+	if err := sd.refreshTokenRepo.CreateRefreshToken(refreshTokenID, user.ID, time.Now().Add(refreshTokenTTL)); err != nil {
+		return AuthResponse{}, err
+	}
+	return GenerateTokens(user, refreshTokenID)
 }
 
-func GenerateTokens(email string) (string, string, error) {
+// GenerateTokens signs the access, refresh and reset tokens of a sign-in. Refresh tokens carry the
+// refresh claim, the auth middleware refuses them as access tokens, and the refresh token ID as jti.
+func GenerateTokens(user repo.UserWithRole, refreshTokenID string) (AuthResponse, error) {
 
 	JWT_SECRET := []byte(config.ConfigDetails.JWTSecretKey)
-	JWT_RESET_SECRET := []byte(config.ConfigDetails.JWTResetSecretKey)
+	now := time.Now()
 
-	// Define expiration times
-	loginExpiration := time.Now().Add(time.Hour * 24) // 24 hours
-	resetExpiration := time.Now().Add(time.Hour * 1)  // 1 hour
+	// Create Access Token
+	accessClaims := jwt.MapClaims{
+		"email": user.Email,
+		"exp":   now.Add(accessTokenTTL).Unix(),
+		"iat":   now.Unix(),
+	}
+	accessToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, accessClaims).SignedString(JWT_SECRET)
+	if err != nil {
+		return AuthResponse{}, err
+	}
 
-	// Create Login Token
-	loginClaims := jwt.MapClaims{
-		"email": email,
-		"exp":   loginExpiration.Unix(),
-		"iat":   time.Now().Unix(),
+	// Create Refresh Token
+	refreshClaims := jwt.MapClaims{
+		"email":   user.Email,
+		"exp":     now.Add(refreshTokenTTL).Unix(),
+		"iat":     now.Unix(),
+		"jti":     refreshTokenID,
+		"refresh": true,
+	}
+	refreshToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims).SignedString(JWT_SECRET)
+	if err != nil {
+		return AuthResponse{}, err
 	}
-	loginToken := jwt.NewWithClaims(jwt.SigningMethodHS256, loginClaims)
-	loginTokenString, err := loginToken.SignedString(JWT_SECRET)
+
+	resetToken, err := newResetToken(user.Email)
 	if err != nil {
-		return "", "", err
+		return AuthResponse{}, err
 	}
 
-	// Create Reset Token
+	return AuthResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ResetToken:   resetToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(accessTokenTTL.Seconds()),
+		User:         AuthUser{ID: user.ID, Email: user.Email, Role: user.Role},
+	}, nil
+}
+
+// newResetToken signs a password reset token for email, valid for an hour
+func newResetToken(email string) (string, error) {
+	JWT_RESET_SECRET := []byte(config.ConfigDetails.JWTResetSecretKey)
+	resetExpiration := time.Now().Add(time.Hour * 1) // 1 hour
+
 	resetClaims := jwt.MapClaims{
 		"email": email,
 		"exp":   resetExpiration.Unix(),
@@ -66,12 +154,7 @@ func GenerateTokens(email string) (string, string, error) {
 		"reset": true,
 	}
 	resetToken := jwt.NewWithClaims(jwt.SigningMethodHS256, resetClaims)
-	resetTokenString, err := resetToken.SignedString(JWT_RESET_SECRET)
-	if err != nil {
-		return "", "", err
-	}
-
-	return loginTokenString, resetTokenString, nil
+	return resetToken.SignedString(JWT_RESET_SECRET)
 }
 
 func PrivateKeyToHex(privateKey *ecdsa.PrivateKey) string {
@@ -81,36 +164,44 @@ func PrivateKeyToHex(privateKey *ecdsa.PrivateKey) string {
 
 // Service functions
 func (sd service) CreateUserAccount(req SignupRequest) (string, error) {
+	if err := validateSignup(req, time.Now()); err != nil {
+		return "", err
+	}
+	return sd.createAccount(req, big.NewInt(1e18))
+}
+
+// createAccount signs up a user with a new wallet preloaded with preloadAmount wei, nil or zero
+// leaves the wallet empty. Only the role is checked, accounts created by imports and SSO skip the
+// self-service signup rules and keep their date of birth only when it is a full date.
+func (sd service) createAccount(req SignupRequest, preloadAmount *big.Int) (string, error) {
 	digitRole, err := strconv.Atoi(req.Role)
 	if err != nil || (digitRole != 1 && digitRole != 2) {
-		return "", err
+		return "", utils.Validation("role must be 1 or 2")
 	}
 
-	usernameExists, emailExists, err := sd.userRepo.UserExists(req.Username, req.Email)
-	if err != nil {
+	// Fails fast before a wallet is funded, CreateUser settles signups racing past this check
+	if err := sd.userRepo.CheckAvailable(req.Username, req.Email); err != nil {
 		return "", err
 	}
-	if usernameExists || emailExists {
-		return "Username or email already taken", nil
-	}
 
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
 		return "", err
 	}
 
-	walletAddress, privateKey, err := sd.ethRepo.CreateWallet(req.Password)
+	walletAddress, privateKey, derivationIndex, err := sd.newWallet(req.Password)
 	if err != nil {
 		return "", err
 	}
 
 	privateKeyHex := PrivateKeyToHex(privateKey)
-	testnetAmount := big.NewInt(1e18)
-	if err := sd.ethRepo.PreloadTokens(walletAddress, testnetAmount); err != nil {
-		return "", err
+	if preloadAmount != nil && preloadAmount.Sign() > 0 {
+		if _, err := sd.ethRepo.PreloadTokens(walletAddress, preloadAmount); err != nil {
+			return "", utils.Upstream("failed to preload tokens", err)
+		}
 	}
 
-	if err := sd.userRepo.CreateUser(req.Username, req.Email, string(hashedPassword), req.FullName, req.DOB, walletAddress, digitRole); err != nil {
+	if err := sd.userRepo.CreateUser(req.Username, req.Email, string(hashedPassword), req.FullName, parseDateOfBirth(req.DOB), walletAddress, digitRole); err != nil {
 		return "", err
 	}
 
@@ -119,28 +210,175 @@ func (sd service) CreateUserAccount(req SignupRequest) (string, error) {
 		log.Println("Error Retrieving User ID: ", err.Error())
 	}
 
-	sd.walletRepo.InsertPrivateKey(user.ID, walletAddress, privateKeyHex)
+	if derivationIndex != nil {
+		err = sd.walletRepo.InsertDerivedPrivateKey(user.ID, walletAddress, privateKeyHex, *derivationIndex)
+	} else {
+		err = sd.walletRepo.InsertPrivateKey(user.ID, walletAddress, privateKeyHex)
+	}
+	if err != nil {
+		log.Printf("Error storing private key of wallet %s: %v", walletAddress, err)
+	}
 
 	return walletAddress, nil
 }
 
-func (sd service) AuthenticateUser(credentials struct{ Email, Password string }) (map[string]string, error) {
-	user, err := sd.userRepo.GetUserByEmail(credentials.Email)
+// newWallet derives the next account of the HD seed, or creates a keystore account while no seed
+// is configured. The derivation index is nil for keystore accounts.
+func (sd service) newWallet(password string) (string, *ecdsa.PrivateKey, *uint32, error) {
+	if sd.hdWallet == nil {
+		walletAddress, privateKey, err := sd.ethRepo.CreateWallet(password)
+		return walletAddress, privateKey, nil, err
+	}
+
+	index, err := sd.walletRepo.NextDerivationIndex()
+	if err != nil {
+		return "", nil, nil, err
+	}
+	privateKey, err := sd.hdWallet.Derive(index)
 	if err != nil {
-		return nil, err
+		return "", nil, nil, fmt.Errorf("error deriving HD wallet %d: %v", index, err)
+	}
+	log.Printf("Derived wallet at %s", ethereum.HDPath(index))
+	return crypto.PubkeyToAddress(privateKey.PublicKey).Hex(), privateKey, &index, nil
+}
+
+func (sd service) AuthenticateUser(credentials struct{ Email, Password string }, client LoginClient) (AuthResponse, error) {
+	user, err := sd.userRepo.GetUserWithRoleByEmail(credentials.Email)
+	if err != nil {
+		return AuthResponse{}, err
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(credentials.Password)); err != nil {
-		return nil, err
+		return AuthResponse{}, err
+	}
+
+	if user.AccountStatus == domain.AccountClosed {
+		return AuthResponse{}, utils.Unauthorized("account is closed")
+	}
+	// Set once a sign-in was reported as not the user's, the password may be known to someone else
+	if user.PasswordResetRequired {
+		return AuthResponse{}, utils.Forbidden("password reset required, use the link from the security alert")
 	}
 
-	loginToken, resetToken, err := GenerateTokens(user.Email)
+	response, err := sd.IssueTokens(user)
 	if err != nil {
-		return nil, err
+		return AuthResponse{}, err
 	}
+	sd.RecordLogin(user.ID, LoginMethodPassword, client)
 
-	return map[string]string{
-		"login_token": loginToken,
-		"reset_token": resetToken,
-	}, nil
+	return response, nil
+}
+
+// CloseAccount closes the caller's own account after confirming the password. The account must
+// have no pending transactions and any remaining funds are swept to the given address.
+func (sd service) CloseAccount(userID string, req CloseAccountRequest) (CloseAccountResponse, error) {
+	user, err := sd.userRepo.GetUserByID(userID)
+	if err != nil {
+		return CloseAccountResponse{}, err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		return CloseAccountResponse{}, utils.Unauthorized("invalid password")
+	}
+
+	return sd.closeAccount(user, req.SweepAddress, user.ID, "closed by account holder", false)
+}
+
+// AdminCloseAccount closes another user's account. With Force set the pending-transaction
+// check is skipped and the account may be closed without sweeping its funds.
+func (sd service) AdminCloseAccount(adminID, userID string, req AdminCloseAccountRequest) (CloseAccountResponse, error) {
+	user, err := sd.userRepo.GetUserByID(userID)
+	if err != nil {
+		return CloseAccountResponse{}, err
+	}
+
+	if req.Reason == "" {
+		return CloseAccountResponse{}, utils.Validation("reason is required")
+	}
+
+	return sd.closeAccount(user, req.SweepAddress, adminID, req.Reason, req.Force)
+}
+
+// closeAccount checks outstanding obligations, sweeps the wallet and marks the account closed
+func (sd service) closeAccount(user repo.User, sweepAddress, closedBy, reason string, force bool) (CloseAccountResponse, error) {
+	if user.AccountStatus == domain.AccountClosed {
+		return CloseAccountResponse{}, utils.Conflict("account is already closed")
+	}
+
+	if !force {
+		pending, err := sd.transactionRepo.CountPending(user.ID)
+		if err != nil {
+			return CloseAccountResponse{}, err
+		}
+		if pending > 0 {
+			return CloseAccountResponse{}, utils.Conflictf("account has %d pending transactions", pending)
+		}
+	}
+
+	if sweepAddress != "" && !common.IsHexAddress(sweepAddress) {
+		return CloseAccountResponse{}, utils.Validation("invalid sweep_address")
+	}
+
+	walletID, err := sd.walletRepo.GetWalletID("", user.ID)
+	if err != nil {
+		return CloseAccountResponse{}, err
+	}
+
+	balance, err := sd.ethRepo.GetBalance(walletID)
+	if err != nil {
+		return CloseAccountResponse{}, utils.Upstream("failed to fetch balance", err)
+	}
+
+	// Only the balance left after paying for gas can be swept
+	gasCost := new(big.Int).Mul(ethereum.DefaultGasPrice, new(big.Int).SetUint64(ethereum.TransferGasLimit))
+	sweepAmount := new(big.Int).Sub(balance, gasCost)
+
+	response := CloseAccountResponse{UserID: user.ID, Status: domain.AccountClosed}
+	if sweepAmount.Sign() > 0 {
+		if sweepAddress == "" {
+			if !force {
+				return CloseAccountResponse{}, utils.Validation("sweep_address is required while the wallet holds funds")
+			}
+		} else {
+			txHash, err := sd.sweepWallet(user.ID, walletID, sweepAddress, sweepAmount)
+			if err != nil {
+				return CloseAccountResponse{}, err
+			}
+			response.SweepTxHash = txHash
+			response.SweptAmount = sweepAmount.String()
+		}
+	}
+
+	err = sd.userRepo.CloseAccount(repo.AccountClosure{
+		UserID:       user.ID,
+		SweepAddress: sweepAddress,
+		SweepTxHash:  response.SweepTxHash,
+		ClosedBy:     closedBy,
+		Reason:       reason,
+	})
+	if err != nil {
+		return CloseAccountResponse{}, err
+	}
+
+	log.Printf("Account %s closed by %s", user.ID, closedBy)
+	return response, nil
+}
+
+// sweepWallet sends amount from the user's wallet to an external address
+func (sd service) sweepWallet(userID, walletID, sweepAddress string, amount *big.Int) (string, error) {
+	privateKeyHex, err := sd.walletRepo.RetrievePrivateKey(userID, "")
+	if err != nil {
+		return "", fmt.Errorf("error retrieving private key: %w", err)
+	}
+
+	signedTx, err := sd.ethRepo.TransferFunds(privateKeyHex, walletID, sweepAddress, amount, ethereum.DefaultGasPrice, ethereum.TransferGasLimit, ethereum.ChainID)
+	if err != nil {
+		return "", utils.Upstream("sweep transaction failed", err)
+	}
+
+	if err := sd.ethRepo.SendTransaction(signedTx); err != nil {
+		return "", utils.Upstream("failed to broadcast sweep transaction", err)
+	}
+
+	return signedTx.Hash().Hex(), nil
 }
@@ -0,0 +1,41 @@
+package user
+
+import (
+	"strings"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+// GetTimeZone returns the user's time zone and the current local time in it
+func (sd service) GetTimeZone(userID string) (TimeZoneResponse, error) {
+	location, err := sd.userRepo.GetUserLocation(userID)
+	if err != nil {
+		return TimeZoneResponse{}, err
+	}
+	return newTimeZoneResponse(location), nil
+}
+
+// SetTimeZone changes the zone the user's daily limits, budgets, summaries and reminders follow.
+// Only IANA names are accepted, fixed offsets would ignore daylight saving time.
+func (sd service) SetTimeZone(userID string, req TimeZoneRequest) (TimeZoneResponse, error) {
+	name := strings.TrimSpace(req.TimeZone)
+	location, err := time.LoadLocation(name)
+	if name == "" || name == "Local" || err != nil {
+		return TimeZoneResponse{}, utils.Validation("time_zone must be an IANA time zone such as Asia/Kolkata")
+	}
+
+	if err := sd.userRepo.SetUserTimeZone(userID, location.String()); err != nil {
+		return TimeZoneResponse{}, err
+	}
+	return newTimeZoneResponse(location), nil
+}
+
+func newTimeZoneResponse(location *time.Location) TimeZoneResponse {
+	now := time.Now().In(location)
+	return TimeZoneResponse{
+		TimeZone:  location.String(),
+		UTCOffset: now.Format("-07:00"),
+		LocalTime: now,
+	}
+}
@@ -0,0 +1,145 @@
+package user
+
+import (
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+// Signup constraints
+const (
+	dateLayout        = "2006-01-02"
+	minUsernameLength = 3
+	maxUsernameLength = 32
+	maxEmailLength    = 254
+	maxFullNameLength = 255
+	minPasswordLength = 10
+	// bcrypt ignores everything past 72 bytes
+	maxPasswordLength = 72
+	minimumAge        = 18
+	maximumAge        = 120
+)
+
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// validateSignup checks every field of a self-service signup and reports all problems at once
+func validateSignup(req SignupRequest, now time.Time) error {
+	var problems []utils.FieldError
+	add := func(field, message string, args ...any) {
+		problems = append(problems, utils.FieldError{Field: field, Message: message, Args: args})
+	}
+
+	switch {
+	case len(req.Username) < minUsernameLength || len(req.Username) > maxUsernameLength:
+		add("username", "must be between %d and %d characters", minUsernameLength, maxUsernameLength)
+	case !usernamePattern.MatchString(req.Username):
+		add("username", "may only contain letters, digits, dots, hyphens and underscores, and must start with a letter or digit")
+	}
+
+	if !isEmailAddress(req.Email) {
+		add("email", "must be a valid email address")
+	}
+
+	for _, problem := range passwordProblems(req.Password, req.Username) {
+		add("password", problem.Message, problem.Args...)
+	}
+
+	switch dob, err := time.Parse(dateLayout, req.DOB); {
+	case req.DOB == "":
+		add("dob", "is required")
+	case err != nil:
+		add("dob", "must be a date in YYYY-MM-DD format")
+	case dob.After(now):
+		add("dob", "cannot be in the future")
+	case ageOn(dob, now) > maximumAge:
+		add("dob", "is not a plausible date of birth")
+	case ageOn(dob, now) < minimumAge:
+		add("dob", "you must be at least %d years old", minimumAge)
+	}
+
+	if len(req.FullName) > maxFullNameLength {
+		add("full_name", "must be at most %d characters", maxFullNameLength)
+	}
+
+	if role, err := strconv.Atoi(req.Role); err != nil || (role != 1 && role != 2) {
+		add("role", "must be 1 or 2")
+	}
+
+	if len(problems) > 0 {
+		return utils.ValidationFields(problems)
+	}
+	return nil
+}
+
+// passwordProblems checks a new password against the password policy. The username, when given,
+// may not be part of the password.
+func passwordProblems(password, username string) []utils.FieldError {
+	var problems []utils.FieldError
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		problems = append(problems, utils.FieldError{Message: "must be between %d and %d characters", Args: []any{minPasswordLength, maxPasswordLength}})
+	}
+
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	classes := 0
+	for _, present := range []bool{lower, upper, digit, symbol} {
+		if present {
+			classes++
+		}
+	}
+	if classes < 3 {
+		problems = append(problems, utils.FieldError{Message: "must mix at least three of lowercase letters, uppercase letters, digits and symbols"})
+	}
+
+	if len(username) >= minUsernameLength && strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		problems = append(problems, utils.FieldError{Message: "must not contain the username"})
+	}
+	return problems
+}
+
+// isEmailAddress reports whether email is a bare RFC 5322 address with a dotted domain
+func isEmailAddress(email string) bool {
+	if len(email) > maxEmailLength {
+		return false
+	}
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email || address.Name != "" {
+		return false
+	}
+	_, domain, _ := strings.Cut(email, "@")
+	return strings.Contains(domain, ".") && !strings.HasPrefix(domain, ".") && !strings.HasSuffix(domain, ".")
+}
+
+// ageOn returns the age in whole years of someone born on dob
+func ageOn(dob, now time.Time) int {
+	age := now.Year() - dob.Year()
+	if now.Month() < dob.Month() || (now.Month() == dob.Month() && now.Day() < dob.Day()) {
+		age--
+	}
+	return age
+}
+
+// parseDateOfBirth parses an optional YYYY-MM-DD date, nil when it is empty or not a full date
+func parseDateOfBirth(dob string) *time.Time {
+	parsed, err := time.Parse(dateLayout, dob)
+	if err != nil {
+		return nil
+	}
+	return &parsed
+}
@@ -0,0 +1,18 @@
+package wallet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+)
+
+// Every history page decodes the request's cursor and encodes the next one
+func BenchmarkTransactionCursor(b *testing.B) {
+	cursor := repo.TransactionCursor{CreatedAt: time.Now(), TransactionID: "7f1c1f1e-8f55-4c55-9b8e-6a4f2c0d9a11"}
+	for i := 0; i < b.N; i++ {
+		if _, err := decodeTransactionCursor(encodeTransactionCursor(cursor)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
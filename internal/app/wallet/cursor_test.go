@@ -0,0 +1,55 @@
+package wallet
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+func TestTransactionCursorRoundTrip(t *testing.T) {
+	cursor := repo.TransactionCursor{
+		CreatedAt:     time.Date(2026, 10, 16, 10, 0, 0, 123456789, time.FixedZone("IST", 5*3600+1800)),
+		TransactionID: "7f1c1f1e-8f55-4c55-9b8e-6a4f2c0d9a11",
+	}
+
+	decoded, err := decodeTransactionCursor(encodeTransactionCursor(cursor))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Nanoseconds survive, rows created within the same microsecond still page correctly
+	if !decoded.CreatedAt.Equal(cursor.CreatedAt) || decoded.TransactionID != cursor.TransactionID {
+		t.Errorf("decoded %+v, want %+v", decoded, cursor)
+	}
+}
+
+func TestDecodeTransactionCursorInvalid(t *testing.T) {
+	encode := func(raw string) string { return base64.RawURLEncoding.EncodeToString([]byte(raw)) }
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"not base64", "not a cursor!"},
+		{"no separator", encode("2026-10-16T10:00:00Z")},
+		{"empty ID", encode("2026-10-16T10:00:00Z|")},
+		{"invalid time", encode("yesterday|7f1c1f1e-8f55-4c55-9b8e-6a4f2c0d9a11")},
+		// Edited tokens that would otherwise fail in the database with a 500
+		{"ID not a UUID", encode("2026-10-16T10:00:00Z|1 OR 1=1")},
+		{"ID with extra part", encode("2026-10-16T10:00:00Z|7f1c1f1e-8f55-4c55-9b8e-6a4f2c0d9a11|x")},
+		{"ID in braces", encode("2026-10-16T10:00:00Z|{7f1c1f1e-8f55-4c55-9b8e-6a4f2c0d9a11}")},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cursor, err := decodeTransactionCursor(test.token)
+			if !errors.Is(err, utils.ErrValidation) || utils.HTTPStatus(err) != http.StatusBadRequest {
+				t.Errorf("decoded %+v, %v, want a validation error", cursor, err)
+			}
+		})
+	}
+}
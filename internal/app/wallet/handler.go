@@ -4,12 +4,32 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
+	"strconv"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/respond"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+const (
+	defaultTransactionPageSize = 20
+	maxTransactionPageSize     = 100
 )
 
-// BalanceResponse defines the structure of the API response.
+// BalanceResponse defines the structure of the API response. Balance is the on-chain balance in
+// ETH. Transfers broadcast but not yet mined are missing from it: available_wei deducts the pending
+// outgoing amounts and their gas, pending incoming amounts cannot be spent yet. held_wei is held for
+// fiat withdrawals waiting to be sent and is deducted from available_wei as well.
 type BalanceResponse struct {
-	WalletID string `json:"wallet_id"`
-	Balance  string `json:"balance"`
+	WalletID             string `json:"wallet_id"`
+	Balance              string `json:"balance"`
+	BalanceWei           string `json:"balance_wei"`
+	AvailableWei         string `json:"available_wei"`
+	PendingOutgoingWei   string `json:"pending_outgoing_wei"`
+	PendingOutgoingCount int    `json:"pending_outgoing_count"`
+	PendingIncomingWei   string `json:"pending_incoming_wei"`
+	PendingIncomingCount int    `json:"pending_incoming_count"`
+	HeldWei              string `json:"held_wei"`
 }
 
 type Handler struct {
@@ -26,11 +46,7 @@ func (hd Handler) GetBalanceHandler(w http.ResponseWriter, r *http.Request) {
 	log.Println("Incoming Request On GetBalance Handler")
 
 	// Retrieve user info from context
-	userInfo, ok := r.Context().Value("userInfo").(struct {
-		UserID    string
-		UserEmail string
-		UserRole  int
-	})
+	userInfo, ok := utils.UserFromContext(r.Context())
 	if !ok {
 		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
 		return
@@ -43,41 +59,35 @@ func (hd Handler) GetBalanceHandler(w http.ResponseWriter, r *http.Request) {
 	// Get Wallet ID
 	walletID, err := hd.service.GetWalletIDForUser(userInfo, queryEmail, queryUserID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		utils.WriteError(w, r, err)
 		return
 	}
 
 	// Get Balance
-	balance, err := hd.service.GetBalanceByWalletID(walletID)
+	response, err := hd.service.GetBalanceByWalletID(walletID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		utils.WriteError(w, r, err)
 		return
 	}
 
-	// Send response
-	response := BalanceResponse{
-		WalletID: walletID,
-		Balance:  balance.String(),
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	respond.JSON(w, r, response)
 }
 
-// TransferRequest represents the structure of a transfer request.
+// TransferRequest represents the structure of a transfer request. Only the confirmation field of
+// the sender's confirmation mode is needed: password, totp_code or device_token. With password
+// confirmation a small transfer may send the transaction pin instead.
 type TransferRequest struct {
 	RecipientUserID string `json:"recipient_user_id"`
 	AmountETH       string `json:"amount"`
 	Password        string `json:"password"`
+	TOTPCode        string `json:"totp_code"`
+	DeviceToken     string `json:"device_token"`
+	PIN             string `json:"pin"`
 }
 
 // TransferFundsHandler handles fund transfer requests.
 func (hd *Handler) TransferFundsHandler(w http.ResponseWriter, r *http.Request) {
-	userInfo, ok := r.Context().Value("userInfo").(struct {
-		UserID    string
-		UserEmail string
-		UserRole  int
-	})
+	userInfo, ok := utils.UserFromContext(r.Context())
 	if !ok {
 		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
 		return
@@ -92,7 +102,7 @@ func (hd *Handler) TransferFundsHandler(w http.ResponseWriter, r *http.Request)
 	// Process fund transfer
 	txHash, err := hd.service.TransferFunds(userInfo, req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		utils.WriteError(w, r, err)
 		return
 	}
 
@@ -100,6 +110,78 @@ func (hd *Handler) TransferFundsHandler(w http.ResponseWriter, r *http.Request)
 	response := map[string]string{
 		"transaction_hash": txHash,
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	respond.JSON(w, r, response)
+}
+
+// TransactionPageRequest carries the pagination parameters of a transaction listing.
+// Offset is only set when the caller explicitly asked for offset mode.
+type TransactionPageRequest struct {
+	Limit  int
+	Offset *int
+	Cursor string
+}
+
+// TransactionListResponse represents a page of transaction history, sent as the data and pagination of a list envelope
+type TransactionListResponse struct {
+	Transactions []repo.Transaction
+	NextCursor   string
+}
+
+// GetTransactionsHandler lists the user's transactions. Pass "cursor" (from a previous
+// response's next_cursor) for keyset pagination or "offset" for the legacy mode. Responses carry
+// Last-Modified and honour If-Modified-Since.
+func (hd Handler) GetTransactionsHandler(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := utils.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: user info not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	page := TransactionPageRequest{Limit: defaultTransactionPageSize, Cursor: query.Get("cursor")}
+
+	if limitParam := query.Get("limit"); limitParam != "" {
+		limit, err := strconv.Atoi(limitParam)
+		if err != nil || limit <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		page.Limit = min(limit, maxTransactionPageSize)
+	}
+
+	if offsetParam := query.Get("offset"); offsetParam != "" {
+		if page.Cursor != "" {
+			http.Error(w, "cursor and offset cannot be combined", http.StatusBadRequest)
+			return
+		}
+		offset, err := strconv.Atoi(offsetParam)
+		if err != nil || offset < 0 {
+			http.Error(w, "Invalid offset", http.StatusBadRequest)
+			return
+		}
+		page.Offset = &offset
+	}
+
+	// Polling clients get 304 Not Modified until a transaction of the history changes
+	lastModified, ok, err := hd.service.TransactionsLastModified(userInfo, query.Get("userid"))
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	if ok && respond.NotModifiedSince(w, r, lastModified) {
+		return
+	}
+
+	response, err := hd.service.GetTransactions(userInfo, query.Get("userid"), page)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	respond.List(w, r, response.Transactions, respond.Pagination{
+		Count:      len(response.Transactions),
+		Limit:      page.Limit,
+		Offset:     page.Offset,
+		NextCursor: response.NextCursor,
+	})
 }
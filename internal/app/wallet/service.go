@@ -1,97 +1,148 @@
 package wallet
 
 import (
-	"context"
 	"crypto/ecdsa"
+	"encoding/base64"
 	"fmt"
+	"log"
 	"math/big"
+	"strings"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
 
 	"golang.org/x/crypto/bcrypt"
 
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/balancealerts"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/confirmation"
 	"github.com/CodeWithKrushnal/ChainBank/internal/app/ethereum"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/notification"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/settings"
+	"github.com/CodeWithKrushnal/ChainBank/internal/config"
+	"github.com/CodeWithKrushnal/ChainBank/internal/domain"
+	"github.com/CodeWithKrushnal/ChainBank/internal/privacy"
 	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
 )
 
 type service struct {
-	userRepo   repo.UserStorer
-	walletRepo repo.WalletStorer
-	ethRepo    ethereum.EthRepo
+	userRepo        repo.UserStorer
+	walletRepo      repo.WalletStorer
+	transactionRepo repo.TransactionStorer
+	withdrawalRepo  repo.FiatWithdrawalStorer
+	lockRepo        repo.LockStorer
+	ethRepo         ethereum.EthRepo
+	settings        settings.Service
+	notifications   notification.Service
+	balanceAlerts   balancealerts.Service
+	confirmations   confirmation.Service
 }
 
 type Service interface {
-	GetWalletIDForUser(userInfo struct {
-		UserID    string
-		UserEmail string
-		UserRole  int
-	}, queryEmail, queryUserID string) (string, error)
-	GetBalanceByWalletID(walletID string) (*big.Float, error)
-	TransferFunds(userInfo struct {
-		UserID    string
-		UserEmail string
-		UserRole  int
-	}, req TransferRequest) (string, error)
+	GetWalletIDForUser(userInfo utils.User, queryEmail, queryUserID string) (string, error)
+	GetBalanceByWalletID(walletID string) (BalanceResponse, error)
+	TransferFunds(userInfo utils.User, req TransferRequest) (string, error)
 	ValidateSenderAddress(senderWalletID string, privateKey *ecdsa.PrivateKey) error
 	ValidateUserPassword(email, password string) error
+	GetTransactions(userInfo utils.User, queryUserID string, page TransactionPageRequest) (TransactionListResponse, error)
+	TransactionsLastModified(userInfo utils.User, queryUserID string) (time.Time, bool, error)
+	GetTransactionChanges(userInfo utils.User, cursor *repo.ChangeCursor, limit int) ([]repo.TransactionChange, error)
 }
 
 // Constructor function
-func NewService(userRepo repo.UserStorer, walletRepo repo.WalletStorer, ethRepo ethereum.EthRepo) Service {
+func NewService(userRepo repo.UserStorer, walletRepo repo.WalletStorer, transactionRepo repo.TransactionStorer, withdrawalRepo repo.FiatWithdrawalStorer, lockRepo repo.LockStorer, ethRepo ethereum.EthRepo, settingsService settings.Service, notificationService notification.Service, balanceAlertService balancealerts.Service, confirmationService confirmation.Service) Service {
 	return service{
-		userRepo:   userRepo,
-		walletRepo: walletRepo,
-		ethRepo:    ethRepo,
+		userRepo:        userRepo,
+		walletRepo:      walletRepo,
+		transactionRepo: transactionRepo,
+		withdrawalRepo:  withdrawalRepo,
+		lockRepo:        lockRepo,
+		ethRepo:         ethRepo,
+		settings:        settingsService,
+		notifications:   notificationService,
+		balanceAlerts:   balanceAlertService,
+		confirmations:   confirmationService,
 	}
 }
 
 // GetWalletIDForUser retrieves the wallet ID based on user role and query params.
-func (sd service) GetWalletIDForUser(userInfo struct {
-	UserID    string
-	UserEmail string
-	UserRole  int
-}, queryEmail, queryUserID string) (string, error) {
+func (sd service) GetWalletIDForUser(userInfo utils.User, queryEmail, queryUserID string) (string, error) {
 	if userInfo.UserRole == 3 && (queryUserID != "" || queryEmail != "") {
 		return sd.walletRepo.GetWalletID(queryEmail, queryUserID)
 	}
 	return sd.walletRepo.GetWalletID(userInfo.UserEmail, userInfo.UserID)
 }
 
-// GetBalanceByWalletID retrieves the wallet balance from the blockchain.
-func (sd service) GetBalanceByWalletID(walletID string) (*big.Float, error) {
+// GetBalanceByWalletID retrieves the wallet balance from the blockchain along with the transfers
+// still waiting to be mined and the funds held for fiat withdrawals.
+func (sd service) GetBalanceByWalletID(walletID string) (BalanceResponse, error) {
 	if !common.IsHexAddress(walletID) {
-		return nil, fmt.Errorf("invalid wallet address")
+		return BalanceResponse{}, utils.Validation("invalid wallet address")
 	}
 
-	balance, err := ethereum.EthereumClient.BalanceAt(context.Background(), common.HexToAddress(walletID), nil)
+	balance, err := sd.ethRepo.GetBalance(walletID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch balance: %w", err)
+		return BalanceResponse{}, utils.Upstream("failed to fetch balance", err)
+	}
+
+	pending, err := sd.transactionRepo.GetPendingTotals(walletID, ethereum.TransferGasLimit)
+	if err != nil {
+		return BalanceResponse{}, err
+	}
+
+	held, err := sd.withdrawalRepo.GetHeldWithdrawalTotal(walletID)
+	if err != nil {
+		return BalanceResponse{}, err
+	}
+
+	available := new(big.Int).Sub(balance, pending.OutgoingTotal)
+	available.Sub(available, held)
+	if available.Sign() < 0 {
+		available.SetInt64(0)
 	}
 
 	ethBalance := new(big.Float).Quo(new(big.Float).SetInt(balance), big.NewFloat(1e18))
-	return ethBalance, nil
+	return BalanceResponse{
+		WalletID:             walletID,
+		Balance:              ethBalance.String(),
+		BalanceWei:           balance.String(),
+		AvailableWei:         available.String(),
+		PendingOutgoingWei:   pending.OutgoingTotal.String(),
+		PendingOutgoingCount: pending.OutgoingCount,
+		PendingIncomingWei:   pending.IncomingTotal.String(),
+		PendingIncomingCount: pending.IncomingCount,
+		HeldWei:              held.String(),
+	}, nil
 }
 
 // TransferFunds handles the fund transfer logic.
-func (sd service) TransferFunds(userInfo struct {
-	UserID    string
-	UserEmail string
-	UserRole  int
-}, req TransferRequest) (string, error) {
+func (sd service) TransferFunds(userInfo utils.User, req TransferRequest) (string, error) {
 	// Get sender and recipient wallet IDs
 	senderWalletID, err := sd.walletRepo.GetWalletID(userInfo.UserEmail, userInfo.UserID)
 	if err != nil {
-		return "", fmt.Errorf("sender wallet not found")
+		return "", utils.NotFound("sender wallet not found", err)
 	}
 
 	recipientWalletID, err := sd.walletRepo.GetWalletID("", req.RecipientUserID)
 	if err != nil {
-		return "", fmt.Errorf("recipient wallet not found")
+		return "", utils.NotFound("recipient wallet not found", err)
+	}
+
+	// Convert amount
+	amount, success := new(big.Int).SetString(req.AmountETH, 10)
+	if !success {
+		return "", utils.Validation("invalid amount format")
 	}
 
-	// Validate user password
-	err = sd.ValidateUserPassword(userInfo.UserEmail, req.Password)
+	// Confirm the transfer the way the user chose
+	err = sd.confirmations.VerifyTransfer(userInfo.UserID, amount, confirmation.Proof{
+		Password:    req.Password,
+		TOTPCode:    req.TOTPCode,
+		DeviceToken: req.DeviceToken,
+		PIN:         req.PIN,
+	})
 	if err != nil {
 		return "", err
 	}
@@ -112,34 +163,153 @@ func (sd service) TransferFunds(userInfo struct {
 		return "", err
 	}
 
-	// Convert amount
-	amount, success := new(big.Int).SetString(req.AmountETH, 10)
-	if !success {
-		return "", fmt.Errorf("invalid amount format")
+	// Transfers of a user run one at a time from the limit check until the transaction is recorded,
+	// so concurrent transfers cannot each fit the same remaining daily limit
+	lock, err := sd.lockRepo.Lock(repo.TransferLockName(userInfo.UserID))
+	if err != nil {
+		return "", err
 	}
+	defer lock.Release()
 
-	// Set gas details and chain ID
-	gasPrice := big.NewInt(20000000000) // 20 Gwei
-	gasLimit := uint64(21000)
-	chainID := big.NewInt(1337) // Ganache
+	// Enforce the transfer limits currently configured
+	if err := sd.checkTransferLimits(userInfo.UserID, amount); err != nil {
+		return "", err
+	}
+
+	if err := sd.checkHolds(senderWalletID, amount); err != nil {
+		return "", err
+	}
 
 	privateKeyHexStr := fmt.Sprintf("%x", crypto.FromECDSA(privateKey))
 
 	// Transfer funds
-	signedTx, err := sd.ethRepo.TransferFunds(privateKeyHexStr, senderWalletID, recipientWalletID, amount, gasPrice, gasLimit, chainID)
+	signedTx, err := sd.ethRepo.TransferFunds(privateKeyHexStr, senderWalletID, recipientWalletID, amount, ethereum.DefaultGasPrice, ethereum.TransferGasLimit, ethereum.ChainID)
 	if err != nil {
-		return "", fmt.Errorf("transaction failed: %w", err)
+		return "", utils.Upstream("transaction failed", err)
 	}
 
 	// Send transaction
-	err = ethereum.EthereumClient.SendTransaction(context.Background(), signedTx)
+	err = sd.ethRepo.SendTransaction(signedTx)
+	if err != nil {
+		return "", utils.Upstream("failed to broadcast transaction", err)
+	}
+
+	// Record transaction for history together with the notifications of both parties, the transfer
+	// itself has already been broadcast
+	notifications := transferNotifications(userInfo.UserID, req.RecipientUserID, amount, signedTx.Hash().Hex())
+	var events []repo.OutboxEvent
+	for _, n := range notifications {
+		event, err := notification.NewEvent(n)
+		if err != nil {
+			log.Printf("Error encoding transfer notification: %v", err)
+			continue
+		}
+		events = append(events, event)
+	}
+	nonce := int64(signedTx.Nonce())
+	_, err = sd.transactionRepo.CreateTransaction(repo.Transaction{
+		TxHash:           signedTx.Hash().Hex(),
+		SenderUserID:     userInfo.UserID,
+		ReceiverUserID:   req.RecipientUserID,
+		SenderWalletID:   senderWalletID,
+		ReceiverWalletID: recipientWalletID,
+		Amount:           amount.String(),
+		Status:           domain.TransactionPending,
+		Nonce:            &nonce,
+		GasPrice:         signedTx.GasPrice().String(),
+		// The policy in force at broadcast applies even if it changes before the transfer is mined
+		RequiredConfirmations: config.TransferConfirmations(amount),
+	}, events...)
 	if err != nil {
-		return "", fmt.Errorf("failed to broadcast transaction: %w", err)
+		log.Printf("Error recording transaction %s: %v", signedTx.Hash().Hex(), err)
+		for _, n := range notifications {
+			sd.notifications.Notify(n)
+		}
 	}
 
+	sd.balanceAlerts.CheckTransfer(userInfo.UserID, senderWalletID, req.RecipientUserID, amount)
+
 	return signedTx.Hash().Hex(), nil
 }
 
+// transferNotifications returns the alerts of both parties about a broadcast transfer
+func transferNotifications(senderUserID, recipientUserID string, amount *big.Int, txHash string) []notification.Notification {
+	ethAmount := new(big.Float).Quo(new(big.Float).SetInt(amount), big.NewFloat(1e18)).Text('f', -1)
+	data := map[string]string{"transaction_hash": txHash, "amount": amount.String()}
+
+	return []notification.Notification{{
+		UserID:   senderUserID,
+		Category: notification.CategoryTransfer,
+		Title:    "Transfer sent",
+		Body:     "You sent %s ETH.",
+		BodyArgs: []any{ethAmount},
+		Data:     data,
+	}, {
+		UserID:   recipientUserID,
+		Category: notification.CategoryTransfer,
+		Title:    "Funds received",
+		Body:     "You received %s ETH.",
+		BodyArgs: []any{ethAmount},
+		Data:     data,
+	}}
+}
+
+// checkTransferLimits rejects amounts above the per-transfer limit or the sender's remaining daily limit,
+// the day starts at midnight in the sender's time zone. The caller holds the transfer lock of the user
+// until the transfer is recorded, the sum sent today is only current under that lock.
+func (sd service) checkTransferLimits(userID string, amount *big.Int) error {
+	maxAmount := sd.settings.GetBigInt(settings.TransferMaxAmountWei)
+	if maxAmount.Sign() > 0 && amount.Cmp(maxAmount) > 0 {
+		return utils.Validationf("amount exceeds the maximum of %s wei per transfer", maxAmount)
+	}
+
+	dailyLimit := sd.settings.GetBigInt(settings.TransferDailyLimitWei)
+	if dailyLimit.Sign() > 0 {
+		location, err := sd.userRepo.GetUserLocation(userID)
+		if err != nil {
+			return err
+		}
+		now := time.Now().In(location)
+		startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, location)
+
+		sentToday, err := sd.transactionRepo.SumSentSince(userID, startOfDay)
+		if err != nil {
+			return err
+		}
+		if new(big.Int).Add(sentToday, amount).Cmp(dailyLimit) > 0 {
+			return utils.Validationf("amount exceeds the daily transfer limit of %s wei", dailyLimit)
+		}
+	}
+
+	return nil
+}
+
+// checkHolds rejects a transfer that would spend funds held for fiat withdrawals, wallets without
+// holds are left to the node to check
+func (sd service) checkHolds(walletID string, amount *big.Int) error {
+	held, err := sd.withdrawalRepo.GetHeldWithdrawalTotal(walletID)
+	if err != nil || held.Sign() == 0 {
+		return err
+	}
+
+	balance, err := sd.ethRepo.GetBalance(walletID)
+	if err != nil {
+		return utils.Upstream("failed to fetch balance", err)
+	}
+	pending, err := sd.transactionRepo.GetPendingTotals(walletID, ethereum.TransferGasLimit)
+	if err != nil {
+		return err
+	}
+
+	gasCost := new(big.Int).Mul(ethereum.DefaultGasPrice, new(big.Int).SetUint64(ethereum.TransferGasLimit))
+	available := new(big.Int).Sub(balance, pending.OutgoingTotal)
+	available.Sub(available, held)
+	if available.Cmp(new(big.Int).Add(amount, gasCost)) < 0 {
+		return utils.Conflictf("amount exceeds the available balance, %s wei are held for fiat withdrawals", held)
+	}
+	return nil
+}
+
 // ValidateSenderAddress ensures the sender's wallet matches the derived address.
 func (sd service) ValidateSenderAddress(senderWalletID string, privateKey *ecdsa.PrivateKey) error {
 	senderAddress := common.HexToAddress(senderWalletID)
@@ -147,7 +317,7 @@ func (sd service) ValidateSenderAddress(senderWalletID string, privateKey *ecdsa
 	derivedAddress := crypto.PubkeyToAddress(*publicKey)
 
 	if senderAddress != derivedAddress {
-		return fmt.Errorf("unauthorized: sender wallet mismatch")
+		return utils.Forbidden("unauthorized: sender wallet mismatch")
 	}
 
 	return nil
@@ -157,12 +327,152 @@ func (sd service) ValidateSenderAddress(senderWalletID string, privateKey *ecdsa
 func (sd service) ValidateUserPassword(email, password string) error {
 	user, err := sd.userRepo.GetUserByEmail(email)
 	if err != nil {
-		return fmt.Errorf("user not found")
+		return utils.NotFound("user not found", err)
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return fmt.Errorf("invalid password")
+		return utils.Unauthorized("invalid password")
 	}
 
 	return nil
 }
+
+// GetTransactions returns one page of transaction history. A non-nil Offset selects the
+// legacy LIMIT/OFFSET mode, otherwise keyset pagination continues from Cursor.
+func (sd service) GetTransactions(userInfo utils.User, queryUserID string, page TransactionPageRequest) (TransactionListResponse, error) {
+	userID := historyUserID(userInfo, queryUserID)
+
+	var transactions []repo.Transaction
+	var err error
+
+	if page.Offset != nil {
+		transactions, err = sd.transactionRepo.GetTransactions(userID, page.Limit, *page.Offset)
+	} else {
+		var cursor *repo.TransactionCursor
+		if page.Cursor != "" {
+			cursor, err = decodeTransactionCursor(page.Cursor)
+			if err != nil {
+				return TransactionListResponse{}, err
+			}
+		}
+		transactions, err = sd.transactionRepo.GetTransactionsAfter(userID, page.Limit, cursor)
+	}
+	if err != nil {
+		return TransactionListResponse{}, err
+	}
+
+	sd.countConfirmations(transactions)
+	response := TransactionListResponse{Transactions: transactions}
+
+	// A full page means there may be more rows, hand back a cursor pointing at the last one
+	if len(transactions) == page.Limit && page.Limit > 0 {
+		last := transactions[len(transactions)-1]
+		response.NextCursor = encodeTransactionCursor(repo.TransactionCursor{
+			CreatedAt:     last.CreatedAt,
+			TransactionID: last.TransactionID,
+		})
+	}
+
+	// Counterparties are only shown in full to admins
+	viewer := privacy.Viewer{UserID: userInfo.UserID, Admin: userInfo.UserRole == 3}
+	response.Transactions = viewer.Transactions(transactions)
+
+	return response, nil
+}
+
+// TransactionsLastModified returns when the transaction history GetTransactions serves last changed.
+// It is false without transactions, and while a mined transfer is waiting for confirmations since
+// its count changes with every block.
+func (sd service) TransactionsLastModified(userInfo utils.User, queryUserID string) (time.Time, bool, error) {
+	state, err := sd.transactionRepo.GetHistoryState(historyUserID(userInfo, queryUserID))
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	if state.LastModified == nil || state.Confirming > 0 {
+		return time.Time{}, false, nil
+	}
+	return *state.LastModified, true, nil
+}
+
+// GetTransactionChanges returns the caller's transactions that changed after the cursor, oldest change
+// first, shaped like the transaction history
+func (sd service) GetTransactionChanges(userInfo utils.User, cursor *repo.ChangeCursor, limit int) ([]repo.TransactionChange, error) {
+	changes, err := sd.transactionRepo.GetTransactionChanges(userInfo.UserID, cursor, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	transactions := make([]repo.Transaction, len(changes))
+	for i, change := range changes {
+		transactions[i] = change.Transaction
+	}
+	sd.countConfirmations(transactions)
+
+	viewer := privacy.Viewer{UserID: userInfo.UserID, Admin: userInfo.UserRole == 3}
+	for i, txn := range viewer.Transactions(transactions) {
+		changes[i].Transaction = txn
+	}
+	return changes, nil
+}
+
+// historyUserID returns the user whose transactions are listed, admins may look at any user's
+func historyUserID(userInfo utils.User, queryUserID string) string {
+	if userInfo.UserRole == 3 && queryUserID != "" {
+		return queryUserID
+	}
+	return userInfo.UserID
+}
+
+// countConfirmations fills in the confirmations of the mined transactions from the latest block,
+// they are left out when the node cannot be reached
+func (sd service) countConfirmations(transactions []repo.Transaction) {
+	var head uint64
+	for i, txn := range transactions {
+		if txn.BlockNumber == nil {
+			continue
+		}
+		if head == 0 {
+			var err error
+			if head, err = sd.ethRepo.GetBlockNumber(); err != nil {
+				log.Printf("Error fetching the latest block: %v", err)
+				return
+			}
+		}
+
+		var confirmations uint64
+		if blockNumber := uint64(*txn.BlockNumber); head >= blockNumber {
+			confirmations = head - blockNumber + 1
+		}
+		transactions[i].Confirmations = &confirmations
+	}
+}
+
+// encodeTransactionCursor turns a keyset position into an opaque URL-safe token
+func encodeTransactionCursor(cursor repo.TransactionCursor) string {
+	raw := cursor.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + cursor.TransactionID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeTransactionCursor parses a token produced by encodeTransactionCursor. Clients can edit
+// the token, so the transaction ID is checked to be a UUID before it reaches the query.
+func decodeTransactionCursor(token string) (*repo.TransactionCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, utils.Validation("invalid cursor")
+	}
+
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 {
+		return nil, utils.Validation("invalid cursor")
+	}
+	if id, err := uuid.Parse(parts[1]); err != nil || id.String() != parts[1] {
+		return nil, utils.Validation("invalid cursor")
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return nil, utils.Validation("invalid cursor")
+	}
+
+	return &repo.TransactionCursor{CreatedAt: createdAt, TransactionID: parts[1]}, nil
+}
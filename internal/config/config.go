@@ -3,9 +3,11 @@ package config
 import (
 	"database/sql"
 	"log"
+	"math/big"
 	"strings"
 
 	"crypto/ecdsa"
+	"encoding/base64"
 	"encoding/hex"
 
 	"github.com/CodeWithKrushnal/ChainBank/internal/app/ethereum"
@@ -16,14 +18,142 @@ import (
 )
 
 type ConfigStruct struct {
-	DatabaseURL       string `env:"DATABASE_URL"`
-	DatabaseUsername  string `env:"DB_USERNAME"`
-	DatabasePassword  string `env:"DB_PASSWORD"`
-	EthereumRPC       string `env:"ETHEREUM_RPC"`
-	JWTSecretKey      string `env:"JWT_SECRET"`
-	JWTResetSecretKey string `env:"JWT_RESET_SECRET"`
-	SuperUserEmail    string `env:"SUPER_USER_EMAIL"`
-	SuperUserPassword string `env:"SUPER_USER_PASSWORD"`
+	DatabaseURL       string   `env:"DATABASE_URL" redact:"url"`
+	DatabaseUsername  string   `env:"DB_USERNAME"`
+	DatabasePassword  string   `env:"DB_PASSWORD" redact:"secret"`
+	ReadReplicaURLs   []string `env:"READ_REPLICA_URLS" envSeparator:"," redact:"url"`
+	EthereumRPC       string   `env:"ETHEREUM_RPC" redact:"url"`
+	SimulatedChain    bool     `env:"SIMULATED_CHAIN" envDefault:"false"`
+	JWTSecretKey      string   `env:"JWT_SECRET" redact:"secret"`
+	JWTResetSecretKey string   `env:"JWT_RESET_SECRET" redact:"secret"`
+	SuperUserEmail    string   `env:"SUPER_USER_EMAIL"`
+	SuperUserPassword string   `env:"SUPER_USER_PASSWORD" redact:"secret"`
+
+	// OpenID Connect single sign-on, disabled while OIDC_ISSUER_URL is unset
+	OIDCIssuerURL    string `env:"OIDC_ISSUER_URL"`
+	OIDCClientID     string `env:"OIDC_CLIENT_ID"`
+	OIDCClientSecret string `env:"OIDC_CLIENT_SECRET" redact:"secret"`
+	OIDCRedirectURL  string `env:"OIDC_REDIRECT_URL"`
+
+	// Sign-In with Ethereum (EIP-4361), disabled while SIWE_DOMAIN is unset. Messages must name this
+	// host, e.g. app.chainbank.io, the way the wallet shows it to the user.
+	SIWEDomain string `env:"SIWE_DOMAIN"`
+
+	// Internal listener requiring client certificates, disabled while MTLS_LISTEN_ADDR is unset.
+	// Services calling through it authenticate with JWTs signed by SERVICE_JWT_SECRET.
+	MTLSListenAddr   string `env:"MTLS_LISTEN_ADDR"`
+	MTLSCertFile     string `env:"MTLS_CERT_FILE"`
+	MTLSKeyFile      string `env:"MTLS_KEY_FILE"`
+	MTLSClientCAFile string `env:"MTLS_CLIENT_CA_FILE"`
+	ServiceJWTSecret string `env:"SERVICE_JWT_SECRET" redact:"secret"`
+
+	// Platform account funding new wallets. Remote signers (e.g. Web3Signer in front of an HSM) are
+	// tried in order with FAUCET_PRIVATE_KEY as the last fallback, a Ganache development key is used
+	// when both are unset.
+	FaucetSignerURLs []string `env:"FAUCET_SIGNER_URLS" envSeparator:"," redact:"url"`
+	FaucetAddress    string   `env:"FAUCET_ADDRESS"`
+	FaucetPrivateKey string   `env:"FAUCET_PRIVATE_KEY" redact:"secret"`
+
+	// Keys encrypting wallet private keys and other secrets as version:base64 pairs, new secrets are
+	// sealed with WALLET_KEY_VERSION. Version 1 is the original built-in key, kept only to decrypt the
+	// rows from before key rotation, so configured keys start at version 2.
+	WalletKeys       map[int]string `env:"WALLET_KEYS" envKeyValSeparator:":" redact:"secret"`
+	WalletKeyVersion int            `env:"WALLET_KEY_VERSION"`
+
+	// Hex encoded BIP-32 master seed new user wallets are derived from along m/44'/60'/0'/0/index,
+	// best passed through HD_WALLET_SEED_FILE or encrypted. Wallets get a random keystore key while unset.
+	HDWalletSeed string `env:"HD_WALLET_SEED" redact:"secret"`
+
+	// Key of the public handles shown instead of other users' IDs, JWT_SECRET is used when unset
+	PublicHandleSecret string `env:"PUBLIC_HANDLE_SECRET" redact:"secret"`
+
+	// Transactions older than this many days are moved to transactions_archive, 0 disables archival
+	TransactionRetentionDays int `env:"TRANSACTION_RETENTION_DAYS" envDefault:"365"`
+	ArchivalIntervalHours    int `env:"ARCHIVAL_INTERVAL_HOURS" envDefault:"24"`
+
+	// Pending transfers without a receipt after this many minutes are reported as stuck, 0 disables detection
+	StuckTransactionMinutes int `env:"STUCK_TRANSACTION_MINUTES" envDefault:"15"`
+	// Settled transfers in this many latest blocks are re-checked for reorgs, 0 disables reorg detection
+	ReorgCheckBlocks int `env:"REORG_CHECK_BLOCKS" envDefault:"64"`
+
+	// Blocks a transfer must be buried under before it is marked confirmed, transfers of at least
+	// LARGE_TRANSFER_MIN_WEI use the large count. 0 uses the default of the network.
+	SmallTransferConfirmations int    `env:"SMALL_TRANSFER_CONFIRMATIONS" envDefault:"0"`
+	LargeTransferConfirmations int    `env:"LARGE_TRANSFER_CONFIRMATIONS" envDefault:"0"`
+	LargeTransferMinWei        string `env:"LARGE_TRANSFER_MIN_WEI" envDefault:"1000000000000000000"`
+
+	// Push and email notification channels, each one is disabled while left unset
+	FCMProjectID       string `env:"FCM_PROJECT_ID"`
+	FCMCredentialsFile string `env:"FCM_CREDENTIALS_FILE"`
+	APNsKeyFile        string `env:"APNS_KEY_FILE"`
+	APNsKeyID          string `env:"APNS_KEY_ID"`
+	APNsTeamID         string `env:"APNS_TEAM_ID"`
+	APNsTopic          string `env:"APNS_TOPIC"`
+	APNsProduction     bool   `env:"APNS_PRODUCTION" envDefault:"false"`
+	SMTPAddr           string `env:"SMTP_ADDR"`
+	SMTPUsername       string `env:"SMTP_USERNAME"`
+	SMTPPassword       string `env:"SMTP_PASSWORD" redact:"secret"`
+	SMTPFrom           string `env:"SMTP_FROM"`
+
+	// Hour of the daily notification digest, in each user's own time zone
+	DigestHour int `env:"DIGEST_HOUR" envDefault:"8"`
+
+	// Minutes between checks of monthly budgets against spending, 0 disables budget alerts
+	BudgetCheckIntervalMinutes int `env:"BUDGET_CHECK_INTERVAL_MINUTES" envDefault:"60"`
+
+	// UTC hour of the daily wallet balance snapshot, -1 disables the scheduler
+	BalanceSnapshotHourUTC int `env:"BALANCE_SNAPSHOT_HOUR_UTC" envDefault:"0"`
+
+	// UTC hour of the daily merchant settlement report for the previous day, -1 disables it.
+	// Webhooks go to public https URLs only, unless private addresses are allowed for local development.
+	MerchantSettlementHourUTC   int  `env:"MERCHANT_SETTLEMENT_HOUR_UTC" envDefault:"1"`
+	MerchantWebhookAllowPrivate bool `env:"MERCHANT_WEBHOOK_ALLOW_PRIVATE" envDefault:"false"`
+
+	// Secret the fiat on-ramp provider signs its deposit webhooks with, the webhook is disabled while unset
+	FiatOnrampWebhookSecret string `env:"FIAT_ONRAMP_WEBHOOK_SECRET" redact:"secret"`
+
+	// Address approved fiat withdrawals are sent to before ops pay them out, withdrawals are disabled while unset
+	FiatOfframpSettlementAddress string `env:"FIAT_OFFRAMP_SETTLEMENT_ADDRESS"`
+
+	// Provider bank accounts are linked through for income verification, "mock" generates accounts for
+	// development. Linking is disabled while unset.
+	BankLinkProvider string `env:"BANK_LINK_PROVIDER"`
+
+	// Minutes between matching incoming transfers to invoices and sending reminders, 0 disables both
+	InvoiceCheckIntervalMinutes int `env:"INVOICE_CHECK_INTERVAL_MINUTES" envDefault:"15"`
+
+	// Minutes between runs of the users' sweep rules, 0 disables automatic sweeps
+	SweepCheckIntervalMinutes int `env:"SWEEP_CHECK_INTERVAL_MINUTES" envDefault:"60"`
+
+	// Region whose holidays, along with weekends, are skipped when due dates and reminders are scheduled
+	BusinessCalendarRegion string `env:"BUSINESS_CALENDAR_REGION" envDefault:"IN"`
+
+	// Minutes a signed export download link stays valid, and days finished exports are kept
+	ExportLinkTTLMinutes int `env:"EXPORT_LINK_TTL_MINUTES" envDefault:"15"`
+	ExportRetentionDays  int `env:"EXPORT_RETENTION_DAYS" envDefault:"7"`
+
+	// Base URL of the API put in front of links sent in notifications, links stay relative while unset
+	PublicBaseURL string `env:"PUBLIC_BASE_URL"`
+
+	// Hours the "this wasn't me" link of a new device sign-in alert stays valid
+	LoginAlertLinkTTLHours int `env:"LOGIN_ALERT_LINK_TTL_HOURS" envDefault:"168"`
+
+	// CAPTCHA on signup and sign-in, hcaptcha or recaptcha, disabled while unset. A client has to solve
+	// one once it made more than CAPTCHA_AFTER_ATTEMPTS attempts in ten minutes, 0 asks on every attempt.
+	CaptchaProvider      string `env:"CAPTCHA_PROVIDER"`
+	CaptchaSecret        string `env:"CAPTCHA_SECRET" redact:"secret"`
+	CaptchaAfterAttempts int    `env:"CAPTCHA_AFTER_ATTEMPTS" envDefault:"5"`
+
+	// Where the rate limiters count requests, "database" shares the counts between the instances behind
+	// a load balancer, "memory" keeps them per instance
+	RateLimitStore string `env:"RATE_LIMIT_STORE" envDefault:"memory"`
+
+	// Seconds the permissions of an authenticated user are cached for their next requests, 0 looks them
+	// up on every request. Permission changes take up to this long to apply.
+	PermissionCacheTTLSeconds int `env:"PERMISSION_CACHE_TTL_SECONDS" envDefault:"0"`
+
+	// Default rollout percentage per feature flag, overridden by the feature_flags table
+	FeatureFlags map[string]int `env:"FEATURE_FLAGS" envKeyValSeparator:"=" envDefault:"transaction_history=100"`
 }
 
 var ConfigDetails ConfigStruct
@@ -75,24 +205,30 @@ type Dependencies struct {
 }
 
 // Inintialize all Configurations for the Server
-func InitConfig() (*sql.DB, *ethclient.Client) {
+func InitConfig() (*repo.DBRouter, *ethclient.Client) {
 
-	//Parse & Load Environment Variables
-	errenv := env.Parse(&ConfigDetails)
+	//Parse & Load Environment Variables, secrets may also come from <NAME>_FILE
+	environment, fileProblems := loadEnvironment()
+	errenv := env.ParseWithOptions(&ConfigDetails, env.Options{Environment: environment})
 	if errenv != nil {
 		log.Fatal("Error Parsing the Environment Variables", errenv)
 		return nil, nil
 	}
 
-	if len(ConfigDetails.DatabaseURL) == 0 || len(ConfigDetails.DatabasePassword) == 0 || len(ConfigDetails.DatabaseUsername) == 0 || len(ConfigDetails.EthereumRPC) == 0 || len(ConfigDetails.JWTSecretKey) == 0 || len(ConfigDetails.JWTResetSecretKey) == 0 || len(ConfigDetails.SuperUserEmail) == 0 || len(ConfigDetails.SuperUserPassword) == 0 {
-		log.Fatal("Missing Environment variable or file")
+	//Validate every setting and report all problems together
+	if err := validateConfig(ConfigDetails); err != nil || len(fileProblems) > 0 {
+		problems := fileProblems
+		if validationErr, ok := err.(ValidationError); ok {
+			problems = append(problems, validationErr.Problems...)
+		}
+		log.Fatal(ValidationError{Problems: problems}.Error())
 	}
 
 	log.Println("Environment Variables Loaded Successfully")
+	logEffectiveConfig(ConfigDetails)
 
 	//Start DB Connection
-	ConfigDetails.DatabaseURL = strings.Replace(ConfigDetails.DatabaseURL, "user", ConfigDetails.DatabaseUsername, 1)
-	ConfigDetails.DatabaseURL = strings.Replace(ConfigDetails.DatabaseURL, "password", ConfigDetails.DatabasePassword, 1)
+	ConfigDetails.DatabaseURL = withDatabaseCredentials(ConfigDetails.DatabaseURL)
 
 	postgresDB, err := repo.InitDB(ConfigDetails.DatabaseURL)
 
@@ -100,19 +236,94 @@ func InitConfig() (*sql.DB, *ethclient.Client) {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 
-	//Initialize Ethereum Client
-	ethClient, err := ethereum.InitEthereumClient(ConfigDetails.EthereumRPC)
-	if err != nil {
-		log.Fatalf("Error Connecting to Ethereum RPC Sever : %v", err.Error())
+	//Connect Read Replicas, an unreachable replica is skipped so reads fall back to the primary
+	var replicaDBs []*sql.DB
+	for i, replicaURL := range ConfigDetails.ReadReplicaURLs {
+		replicaDB, err := repo.InitDB(withDatabaseCredentials(strings.TrimSpace(replicaURL)))
+		if err != nil {
+			log.Printf("Skipping read replica %d: %v", i+1, err)
+			repo.CloseDB(replicaDB)
+			continue
+		}
+		replicaDBs = append(replicaDBs, replicaDB)
+	}
+	log.Printf("Read replicas in use: %d", len(replicaDBs))
+
+	//Initialize Ethereum Client, the simulated chain runs without one
+	var ethClient *ethclient.Client
+	if !ConfigDetails.SimulatedChain {
+		ethClient, err = ethereum.InitEthereumClient(ConfigDetails.EthereumRPC)
+		if err != nil {
+			log.Fatalf("Error Connecting to Ethereum RPC Sever : %v", err.Error())
+		}
 	}
 
 	//Creating Superuser
 	// CreateSuperUser()
-	return postgresDB, ethClient
+	return repo.NewDBRouter(postgresDB, replicaDBs...), ethClient
+}
+
+// Substitutes the user & password placeholders of a connection string with the configured credentials
+func withDatabaseCredentials(databaseURL string) string {
+	databaseURL = strings.Replace(databaseURL, "user", ConfigDetails.DatabaseUsername, 1)
+	return strings.Replace(databaseURL, "password", ConfigDetails.DatabasePassword, 1)
+}
+
+// PrivateKeyRing decodes WALLET_KEYS, which validateConfig has already checked
+func PrivateKeyRing() repo.PrivateKeyRing {
+	keys := map[int][]byte{}
+	for version, encoded := range ConfigDetails.WalletKeys {
+		keys[version], _ = base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	}
+	return repo.PrivateKeyRing{Current: ConfigDetails.WalletKeyVersion, Keys: keys}
+}
+
+// ConfirmationPolicy is the number of confirmations required per kind of transfer
+type ConfirmationPolicy struct {
+	SmallTransfer int `json:"small_transfer"`
+	LargeTransfer int `json:"large_transfer"`
+}
+
+// networkConfirmations are the defaults per chain ID. Development chains only mine a block per
+// transaction, so waiting for more than one would hold transfers until unrelated ones arrive.
+var networkConfirmations = map[int64]ConfirmationPolicy{
+	1:        {SmallTransfer: 3, LargeTransfer: 12}, // Mainnet
+	11155111: {SmallTransfer: 1, LargeTransfer: 6},  // Sepolia
+	17000:    {SmallTransfer: 1, LargeTransfer: 6},  // Holesky
+	1337:     {SmallTransfer: 1, LargeTransfer: 1},  // Ganache and the simulated chain
+}
+
+// defaultConfirmations applies to networks without their own defaults
+var defaultConfirmations = ConfirmationPolicy{SmallTransfer: 1, LargeTransfer: 6}
+
+// Confirmations returns the policy of the configured network with the overrides applied
+func Confirmations() ConfirmationPolicy {
+	policy, ok := networkConfirmations[ethereum.ChainID.Int64()]
+	if !ok {
+		policy = defaultConfirmations
+	}
+	if ConfigDetails.SmallTransferConfirmations > 0 {
+		policy.SmallTransfer = ConfigDetails.SmallTransferConfirmations
+	}
+	if ConfigDetails.LargeTransferConfirmations > 0 {
+		policy.LargeTransfer = ConfigDetails.LargeTransferConfirmations
+	}
+	return policy
+}
+
+// TransferConfirmations returns the confirmations a transfer of amount wei requires, LARGE_TRANSFER_MIN_WEI
+// has already been checked by validateConfig
+func TransferConfirmations(amount *big.Int) int {
+	policy := Confirmations()
+	largeMin, _ := new(big.Int).SetString(ConfigDetails.LargeTransferMinWei, 10)
+	if largeMin != nil && amount.Cmp(largeMin) >= 0 {
+		return policy.LargeTransfer
+	}
+	return policy.SmallTransfer
 }
 
-func ReleaseConfig(db *sql.DB) {
-	repo.CloseDB(db)
+func ReleaseConfig(dbRouter *repo.DBRouter) {
+	dbRouter.Close()
 }
 
 func PrivateKeyToHex(privateKey *ecdsa.PrivateKey) string {
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Encrypted values look like enc:v1:<wrapped data key>:<ciphertext>. Each value has its own
+// random data key, sealed with the master key, so the master key never encrypts config data directly.
+const (
+	encryptedPrefix  = "enc:v1:"
+	masterKeyEnvName = "CONFIG_MASTER_KEY_FILE"
+	masterKeyLength  = 32
+)
+
+// LoadMasterKey reads a base64 encoded 256-bit key from a file
+func LoadMasterKey(path string) ([]byte, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading master key: %w", err)
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(content)))
+	if err != nil {
+		return nil, fmt.Errorf("master key is not valid base64: %w", err)
+	}
+	if len(key) != masterKeyLength {
+		return nil, fmt.Errorf("master key must be %d bytes, got %d", masterKeyLength, len(key))
+	}
+	return key, nil
+}
+
+// EncryptValue seals a config value for use as enc:v1:... in the environment
+func EncryptValue(masterKey []byte, plaintext string) (string, error) {
+	dataKey := make([]byte, masterKeyLength)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+
+	wrappedKey, err := seal(masterKey, dataKey)
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := seal(dataKey, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+
+	return encryptedPrefix + base64.RawStdEncoding.EncodeToString(wrappedKey) + ":" + base64.RawStdEncoding.EncodeToString(ciphertext), nil
+}
+
+// DecryptValue opens a value produced by EncryptValue
+func DecryptValue(masterKey []byte, value string) (string, error) {
+	encoded, ok := strings.CutPrefix(value, encryptedPrefix)
+	if !ok {
+		return "", fmt.Errorf("value does not start with %s", encryptedPrefix)
+	}
+	wrappedPart, ciphertextPart, ok := strings.Cut(encoded, ":")
+	if !ok {
+		return "", fmt.Errorf("malformed encrypted value")
+	}
+
+	wrappedKey, err := base64.RawStdEncoding.DecodeString(wrappedPart)
+	if err != nil {
+		return "", fmt.Errorf("malformed data key: %w", err)
+	}
+	ciphertext, err := base64.RawStdEncoding.DecodeString(ciphertextPart)
+	if err != nil {
+		return "", fmt.Errorf("malformed ciphertext: %w", err)
+	}
+
+	dataKey, err := open(masterKey, wrappedKey)
+	if err != nil {
+		return "", fmt.Errorf("unwrapping data key: %w", err)
+	}
+	plaintext, err := open(dataKey, ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("decrypting value: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// isEncrypted reports whether a config value has to be decrypted
+func isEncrypted(value string) bool {
+	return strings.HasPrefix(value, "enc:")
+}
+
+// seal encrypts with AES-256-GCM and prepends the random nonce
+func seal(key, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// open reverses seal
+func open(key, sealed []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	return gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
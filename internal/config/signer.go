@@ -0,0 +1,51 @@
+package config
+
+import (
+	"encoding/hex"
+	"log"
+	"strings"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// FaucetSigner builds the signer of the account funding new wallets. The remote signers come
+// first in FAUCET_SIGNER_URLS order and FAUCET_PRIVATE_KEY is the last fallback. Without either
+// the Ganache development account is used.
+func FaucetSigner() (*ethereum.FailoverSigner, error) {
+	var signers []ethereum.TransactionSigner
+	for _, url := range ConfigDetails.FaucetSignerURLs {
+		signer, err := ethereum.NewRemoteSigner(strings.TrimSpace(url), common.HexToAddress(ConfigDetails.FaucetAddress))
+		if err != nil {
+			return nil, err
+		}
+		signers = append(signers, signer)
+	}
+
+	if ConfigDetails.FaucetPrivateKey != "" {
+		signer, err := ethereum.NewLocalSigner(ConfigDetails.FaucetPrivateKey)
+		if err != nil {
+			return nil, err
+		}
+		signers = append(signers, signer)
+	}
+
+	if len(signers) == 0 {
+		log.Println("No faucet signer configured, funding new wallets from the Ganache development account")
+		signers = append(signers, ethereum.NewDevFaucetSigner())
+	}
+	return ethereum.NewFailoverSigner(signers...)
+}
+
+// HDWallet derives user wallets from HD_WALLET_SEED, which validateConfig has already checked.
+// It returns nil while no seed is configured.
+func HDWallet() (*ethereum.HDWallet, error) {
+	if ConfigDetails.HDWalletSeed == "" {
+		return nil, nil
+	}
+	seed, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(ConfigDetails.HDWalletSeed), "0x"))
+	if err != nil {
+		return nil, err
+	}
+	return ethereum.NewHDWallet(seed)
+}
@@ -0,0 +1,33 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// MutualTLSConfig builds the TLS configuration of the internal listener. Clients must present a
+// certificate signed by MTLS_CLIENT_CA_FILE.
+func MutualTLSConfig() (*tls.Config, error) {
+	certificate, err := tls.LoadX509KeyPair(ConfigDetails.MTLSCertFile, ConfigDetails.MTLSKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("loading server certificate: %w", err)
+	}
+
+	caPEM, err := os.ReadFile(ConfigDetails.MTLSClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading client CA: %w", err)
+	}
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("no certificates found in %s", ConfigDetails.MTLSClientCAFile)
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		ClientCAs:    clientCAs,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
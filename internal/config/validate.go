@@ -0,0 +1,412 @@
+package config
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math/big"
+	"net/mail"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Suffix of the variable pointing at a file that holds a setting, e.g. DB_PASSWORD_FILE=/run/secrets/db_password
+const secretFileSuffix = "_FILE"
+
+// ValidationError lists every problem found in the configuration
+type ValidationError struct {
+	Problems []string
+}
+
+func (ve ValidationError) Error() string {
+	return fmt.Sprintf("invalid configuration (%d problems):\n  - %s", len(ve.Problems), strings.Join(ve.Problems, "\n  - "))
+}
+
+// loadEnvironment builds the variables used to parse the configuration. Values come from the
+// process environment, and a <NAME>_FILE variable takes precedence over <NAME> so that Docker
+// secrets override anything passed in plain text. Encrypted values are decrypted last.
+func loadEnvironment() (map[string]string, []string) {
+	environment := map[string]string{}
+	for _, pair := range os.Environ() {
+		if key, value, ok := strings.Cut(pair, "="); ok {
+			environment[key] = value
+		}
+	}
+
+	var problems []string
+	for _, name := range configVariableNames() {
+		path, ok := environment[name+secretFileSuffix]
+		if !ok || path == "" {
+			continue
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s%s: cannot read %q: %v", name, secretFileSuffix, path, err))
+			continue
+		}
+
+		if _, overridden := environment[name]; overridden {
+			log.Printf("Config: %s is set in both environment and %s%s, using the file", name, name, secretFileSuffix)
+		}
+		environment[name] = strings.TrimRight(string(content), "\r\n")
+	}
+
+	problems = append(problems, decryptEnvironment(environment)...)
+	return environment, problems
+}
+
+// decryptEnvironment replaces enc:v1:... values of config variables with their plaintext,
+// using the master key named by CONFIG_MASTER_KEY_FILE
+func decryptEnvironment(environment map[string]string) []string {
+	var encrypted []string
+	for _, name := range configVariableNames() {
+		if isEncrypted(environment[name]) {
+			encrypted = append(encrypted, name)
+		}
+	}
+	if len(encrypted) == 0 {
+		return nil
+	}
+
+	keyPath := environment[masterKeyEnvName]
+	if keyPath == "" {
+		return []string{fmt.Sprintf("%s is required to decrypt %s", masterKeyEnvName, strings.Join(encrypted, ", "))}
+	}
+	masterKey, err := LoadMasterKey(keyPath)
+	if err != nil {
+		return []string{fmt.Sprintf("%s: %v", masterKeyEnvName, err)}
+	}
+
+	var problems []string
+	for _, name := range encrypted {
+		plaintext, err := DecryptValue(masterKey, environment[name])
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		environment[name] = plaintext
+	}
+	return problems
+}
+
+// configVariableNames returns the environment variable of every ConfigStruct field
+func configVariableNames() []string {
+	var names []string
+	configType := reflect.TypeOf(ConfigStruct{})
+	for i := 0; i < configType.NumField(); i++ {
+		if name, _, _ := strings.Cut(configType.Field(i).Tag.Get("env"), ","); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// validateConfig checks every setting and reports all problems at once
+func validateConfig(cfg ConfigStruct) error {
+	var problems []string
+	addProblem := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	required := func(name, value string) bool {
+		if strings.TrimSpace(value) == "" {
+			addProblem("%s is required", name)
+			return false
+		}
+		return true
+	}
+
+	if required("DATABASE_URL", cfg.DatabaseURL) {
+		if err := checkURL(cfg.DatabaseURL, "postgres", "postgresql"); err != nil {
+			addProblem("DATABASE_URL %v", err)
+		}
+	}
+	required("DB_USERNAME", cfg.DatabaseUsername)
+	required("DB_PASSWORD", cfg.DatabasePassword)
+
+	for i, replicaURL := range cfg.ReadReplicaURLs {
+		if err := checkURL(strings.TrimSpace(replicaURL), "postgres", "postgresql"); err != nil {
+			addProblem("READ_REPLICA_URLS entry %d %v", i+1, err)
+		}
+	}
+
+	if !cfg.SimulatedChain && required("ETHEREUM_RPC", cfg.EthereumRPC) {
+		if err := checkURL(cfg.EthereumRPC, "http", "https", "ws", "wss"); err != nil {
+			addProblem("ETHEREUM_RPC %v", err)
+		}
+	}
+
+	if required("JWT_SECRET", cfg.JWTSecretKey) && len(cfg.JWTSecretKey) < 16 {
+		addProblem("JWT_SECRET must be at least 16 characters")
+	}
+	if required("JWT_RESET_SECRET", cfg.JWTResetSecretKey) && len(cfg.JWTResetSecretKey) < 16 {
+		addProblem("JWT_RESET_SECRET must be at least 16 characters")
+	}
+	if cfg.JWTSecretKey != "" && cfg.JWTSecretKey == cfg.JWTResetSecretKey {
+		addProblem("JWT_SECRET and JWT_RESET_SECRET must be different")
+	}
+
+	if cfg.OIDCIssuerURL != "" {
+		if err := checkURL(cfg.OIDCIssuerURL, "https", "http"); err != nil {
+			addProblem("OIDC_ISSUER_URL %v", err)
+		}
+		required("OIDC_CLIENT_ID", cfg.OIDCClientID)
+		required("OIDC_CLIENT_SECRET", cfg.OIDCClientSecret)
+		if required("OIDC_REDIRECT_URL", cfg.OIDCRedirectURL) {
+			if err := checkURL(cfg.OIDCRedirectURL, "https", "http"); err != nil {
+				addProblem("OIDC_REDIRECT_URL %v", err)
+			}
+		}
+	}
+
+	// The domain is an RFC 3986 authority, the host with an optional port
+	if cfg.SIWEDomain != "" {
+		if parsed, err := url.Parse("https://" + cfg.SIWEDomain); err != nil || parsed.Host != cfg.SIWEDomain {
+			addProblem("SIWE_DOMAIN must be a host with an optional port, without scheme or path")
+		}
+	}
+
+	if cfg.MTLSListenAddr != "" {
+		required("MTLS_CERT_FILE", cfg.MTLSCertFile)
+		required("MTLS_KEY_FILE", cfg.MTLSKeyFile)
+		required("MTLS_CLIENT_CA_FILE", cfg.MTLSClientCAFile)
+	}
+	if cfg.ServiceJWTSecret != "" {
+		if cfg.MTLSListenAddr == "" {
+			addProblem("SERVICE_JWT_SECRET requires MTLS_LISTEN_ADDR, service tokens are only accepted over mutual TLS")
+		}
+		if len(cfg.ServiceJWTSecret) < 32 {
+			addProblem("SERVICE_JWT_SECRET must be at least 32 characters")
+		}
+		if cfg.ServiceJWTSecret == cfg.JWTSecretKey || cfg.ServiceJWTSecret == cfg.JWTResetSecretKey {
+			addProblem("SERVICE_JWT_SECRET must differ from the user JWT secrets")
+		}
+	}
+
+	for i, signerURL := range cfg.FaucetSignerURLs {
+		if err := checkURL(strings.TrimSpace(signerURL), "http", "https"); err != nil {
+			addProblem("FAUCET_SIGNER_URLS entry %d %v", i+1, err)
+		}
+	}
+	if len(cfg.FaucetSignerURLs) > 0 && required("FAUCET_ADDRESS", cfg.FaucetAddress) && !common.IsHexAddress(cfg.FaucetAddress) {
+		addProblem("FAUCET_ADDRESS is not a valid Ethereum address")
+	}
+	if cfg.FiatOfframpSettlementAddress != "" && !common.IsHexAddress(cfg.FiatOfframpSettlementAddress) {
+		addProblem("FIAT_OFFRAMP_SETTLEMENT_ADDRESS is not a valid Ethereum address")
+	}
+	if cfg.FaucetPrivateKey != "" {
+		if _, err := hex.DecodeString(cfg.FaucetPrivateKey); err != nil || len(cfg.FaucetPrivateKey) != 64 {
+			addProblem("FAUCET_PRIVATE_KEY must be 64 hex characters")
+		}
+	}
+
+	for version, encoded := range cfg.WalletKeys {
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if version <= 1 {
+			addProblem("WALLET_KEYS versions start at 2, version 1 is the built-in key, got %d", version)
+		} else if err != nil || len(key) != 32 {
+			addProblem("WALLET_KEYS version %d must be a base64 encoded 32 byte key", version)
+		}
+	}
+	if cfg.WalletKeyVersion == 0 {
+		addProblem("WALLET_KEY_VERSION is required")
+	} else if _, ok := cfg.WalletKeys[cfg.WalletKeyVersion]; !ok {
+		addProblem("WALLET_KEY_VERSION %d has no key in WALLET_KEYS", cfg.WalletKeyVersion)
+	}
+
+	if cfg.HDWalletSeed != "" {
+		seed, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(cfg.HDWalletSeed), "0x"))
+		if err != nil || len(seed) < ethereum.MinHDSeedBytes || len(seed) > ethereum.MaxHDSeedBytes {
+			addProblem("HD_WALLET_SEED must be %d to %d hex encoded bytes", ethereum.MinHDSeedBytes, ethereum.MaxHDSeedBytes)
+		}
+	}
+
+	if cfg.PublicHandleSecret != "" && len(cfg.PublicHandleSecret) < 16 {
+		addProblem("PUBLIC_HANDLE_SECRET must be at least 16 characters")
+	}
+
+	if required("SUPER_USER_EMAIL", cfg.SuperUserEmail) {
+		if _, err := mail.ParseAddress(cfg.SuperUserEmail); err != nil {
+			addProblem("SUPER_USER_EMAIL is not a valid email address")
+		}
+	}
+	required("SUPER_USER_PASSWORD", cfg.SuperUserPassword)
+
+	if cfg.TransactionRetentionDays < 0 {
+		addProblem("TRANSACTION_RETENTION_DAYS cannot be negative")
+	}
+	if cfg.ArchivalIntervalHours < 0 {
+		addProblem("ARCHIVAL_INTERVAL_HOURS cannot be negative")
+	}
+	if cfg.StuckTransactionMinutes < 0 {
+		addProblem("STUCK_TRANSACTION_MINUTES cannot be negative")
+	}
+	if cfg.ReorgCheckBlocks < 0 {
+		addProblem("REORG_CHECK_BLOCKS cannot be negative")
+	}
+	if cfg.SmallTransferConfirmations < 0 {
+		addProblem("SMALL_TRANSFER_CONFIRMATIONS cannot be negative")
+	}
+	if cfg.LargeTransferConfirmations < 0 {
+		addProblem("LARGE_TRANSFER_CONFIRMATIONS cannot be negative")
+	}
+	if largeMin, ok := new(big.Int).SetString(cfg.LargeTransferMinWei, 10); !ok || largeMin.Sign() < 0 {
+		addProblem("LARGE_TRANSFER_MIN_WEI must be a non-negative integer")
+	}
+
+	if cfg.FCMProjectID != "" && cfg.FCMCredentialsFile == "" {
+		addProblem("FCM_CREDENTIALS_FILE is required when FCM_PROJECT_ID is set")
+	}
+	if cfg.APNsKeyFile != "" && (cfg.APNsKeyID == "" || cfg.APNsTeamID == "" || cfg.APNsTopic == "") {
+		addProblem("APNS_KEY_ID, APNS_TEAM_ID and APNS_TOPIC are required when APNS_KEY_FILE is set")
+	}
+	if cfg.SMTPAddr != "" {
+		if _, err := mail.ParseAddress(cfg.SMTPFrom); err != nil {
+			addProblem("SMTP_FROM must be a valid email address when SMTP_ADDR is set")
+		}
+	}
+
+	if cfg.DigestHour < 0 || cfg.DigestHour > 23 {
+		addProblem("DIGEST_HOUR must be between 0 and 23")
+	}
+	if cfg.BudgetCheckIntervalMinutes < 0 {
+		addProblem("BUDGET_CHECK_INTERVAL_MINUTES cannot be negative")
+	}
+	if cfg.BalanceSnapshotHourUTC < -1 || cfg.BalanceSnapshotHourUTC > 23 {
+		addProblem("BALANCE_SNAPSHOT_HOUR_UTC must be between 0 and 23, or -1 to disable snapshots")
+	}
+	if cfg.MerchantSettlementHourUTC < -1 || cfg.MerchantSettlementHourUTC > 23 {
+		addProblem("MERCHANT_SETTLEMENT_HOUR_UTC must be between 0 and 23, or -1 to disable settlements")
+	}
+	if cfg.InvoiceCheckIntervalMinutes < 0 {
+		addProblem("INVOICE_CHECK_INTERVAL_MINUTES cannot be negative")
+	}
+	if cfg.SweepCheckIntervalMinutes < 0 {
+		addProblem("SWEEP_CHECK_INTERVAL_MINUTES cannot be negative")
+	}
+	if !isRegionCode(cfg.BusinessCalendarRegion) {
+		addProblem("BUSINESS_CALENDAR_REGION must be 2 to 8 uppercase letters")
+	}
+	if cfg.ExportLinkTTLMinutes <= 0 {
+		addProblem("EXPORT_LINK_TTL_MINUTES must be positive")
+	}
+	if cfg.ExportRetentionDays <= 0 {
+		addProblem("EXPORT_RETENTION_DAYS must be positive")
+	}
+	if cfg.PublicBaseURL != "" {
+		if err := checkURL(cfg.PublicBaseURL, "https", "http"); err != nil {
+			addProblem("PUBLIC_BASE_URL %v", err)
+		}
+	}
+	if cfg.LoginAlertLinkTTLHours <= 0 {
+		addProblem("LOGIN_ALERT_LINK_TTL_HOURS must be positive")
+	}
+	if cfg.CaptchaProvider != "" {
+		if cfg.CaptchaProvider != "hcaptcha" && cfg.CaptchaProvider != "recaptcha" {
+			addProblem("CAPTCHA_PROVIDER must be hcaptcha or recaptcha")
+		}
+		required("CAPTCHA_SECRET", cfg.CaptchaSecret)
+	}
+	if cfg.CaptchaAfterAttempts < 0 {
+		addProblem("CAPTCHA_AFTER_ATTEMPTS cannot be negative")
+	}
+	if cfg.BankLinkProvider != "" && cfg.BankLinkProvider != "mock" {
+		addProblem("BANK_LINK_PROVIDER must be mock")
+	}
+	if cfg.RateLimitStore != "memory" && cfg.RateLimitStore != "database" {
+		addProblem("RATE_LIMIT_STORE must be memory or database")
+	}
+	if cfg.PermissionCacheTTLSeconds < 0 {
+		addProblem("PERMISSION_CACHE_TTL_SECONDS cannot be negative")
+	}
+
+	for flag, percentage := range cfg.FeatureFlags {
+		if percentage < 0 || percentage > 100 {
+			addProblem("FEATURE_FLAGS %s must have a rollout percentage between 0 and 100", flag)
+		}
+	}
+
+	if len(problems) > 0 {
+		return ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// checkURL verifies that rawURL is absolute and uses one of the allowed schemes
+func checkURL(rawURL string, schemes ...string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return fmt.Errorf("is not a valid URL")
+	}
+	for _, scheme := range schemes {
+		if parsed.Scheme == scheme {
+			return nil
+		}
+	}
+	return fmt.Errorf("must use one of the schemes %s", strings.Join(schemes, ", "))
+}
+
+// isRegionCode reports whether region is 2 to 8 uppercase ASCII letters
+func isRegionCode(region string) bool {
+	if len(region) < 2 || len(region) > 8 {
+		return false
+	}
+	for _, c := range region {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// logEffectiveConfig prints the loaded settings, masking fields tagged redact:"secret"
+// and stripping passwords from fields tagged redact:"url"
+func logEffectiveConfig(cfg ConfigStruct) {
+	var sb strings.Builder
+	sb.WriteString("Effective configuration:")
+
+	configValue := reflect.ValueOf(cfg)
+	configType := configValue.Type()
+	for i := 0; i < configType.NumField(); i++ {
+		field := configType.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("env"), ",")
+		if name == "" {
+			continue
+		}
+		fmt.Fprintf(&sb, "\n  %s=%s", name, redactValue(field.Tag.Get("redact"), configValue.Field(i)))
+	}
+
+	log.Println(sb.String())
+}
+
+// redactValue formats a config value according to its redact tag
+func redactValue(mode string, value reflect.Value) string {
+	if value.Kind() == reflect.Slice {
+		items := make([]string, value.Len())
+		for i := range items {
+			items[i] = redactValue(mode, value.Index(i))
+		}
+		return strings.Join(items, ",")
+	}
+
+	formatted := fmt.Sprint(value.Interface())
+	switch mode {
+	case "secret":
+		if formatted == "" {
+			return "<unset>"
+		}
+		return "******"
+	case "url":
+		if parsed, err := url.Parse(formatted); err == nil {
+			return parsed.Redacted()
+		}
+		return "******"
+	}
+	return formatted
+}
@@ -0,0 +1,46 @@
+package domain
+
+// Built-in roles, chosen at signup or held by administrators
+const (
+	RoleUser   = 1
+	RoleMember = 2
+	RoleAdmin  = 3
+)
+
+// All Permissions roles can grant, one for each admin area. The built-in admin role holds every one
+// of them.
+const (
+	// Users and their accounts, imports included
+	PermissionUserManage = "user.manage"
+	// Names and dates of birth of other users
+	PermissionPIIRead    = "pii.read"
+	PermissionRoleManage = "role.manage"
+	// Runtime settings, feature flags and the holiday calendar
+	PermissionSettingManage = "setting.manage"
+	PermissionFeatureManage = "feature.manage"
+	PermissionHolidayManage = "holiday.manage"
+	// Background jobs, transaction archival, exports and balance snapshots
+	PermissionJobManage             = "job.manage"
+	PermissionArchiveManage         = "archive.manage"
+	PermissionExportManage          = "export.manage"
+	PermissionBalanceSnapshotManage = "balance_snapshot.manage"
+	// Stuck transactions and reversals of transfers
+	PermissionTransactionRecover = "transaction.recover"
+	PermissionReversalManage     = "reversal.manage"
+	// Fiat deposits and the withdrawal ops queue
+	PermissionFiatDepositRead      = "fiat_deposit.read"
+	PermissionFiatWithdrawalManage = "fiat_withdrawal.manage"
+	// Key rotation, PII encryption and the HD wallet audit
+	PermissionEncryptionManage = "encryption.manage"
+	PermissionWalletAudit      = "wallet.audit"
+)
+
+// Permissions lists every permission roles can grant
+var Permissions = []string{
+	PermissionUserManage, PermissionPIIRead, PermissionRoleManage,
+	PermissionSettingManage, PermissionFeatureManage, PermissionHolidayManage,
+	PermissionJobManage, PermissionArchiveManage, PermissionExportManage, PermissionBalanceSnapshotManage,
+	PermissionTransactionRecover, PermissionReversalManage,
+	PermissionFiatDepositRead, PermissionFiatWithdrawalManage,
+	PermissionEncryptionManage, PermissionWalletAudit,
+}
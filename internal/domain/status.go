@@ -0,0 +1,111 @@
+// Package domain holds the value types shared by the repo, service and handler layers
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// TransactionStatus is the lifecycle state of a transfer
+type TransactionStatus string
+
+// All Transaction Statuses, kept in sync with the transactions_status_check constraint
+const (
+	TransactionPending   TransactionStatus = "pending"
+	TransactionConfirmed TransactionStatus = "confirmed"
+	TransactionFailed    TransactionStatus = "failed"
+	TransactionCancelled TransactionStatus = "cancelled"
+)
+
+var transactionStatuses = []TransactionStatus{TransactionPending, TransactionConfirmed, TransactionFailed, TransactionCancelled}
+
+// AccountStatus is the state of a user account
+type AccountStatus string
+
+// All Account Statuses, kept in sync with the users_account_status_check constraint
+const (
+	AccountActive AccountStatus = "active"
+	AccountClosed AccountStatus = "closed"
+)
+
+var accountStatuses = []AccountStatus{AccountActive, AccountClosed}
+
+// ParseTransactionStatus converts a string in any casing to a known transaction status
+func ParseTransactionStatus(value string) (TransactionStatus, error) {
+	return parseStatus("transaction", value, transactionStatuses)
+}
+
+// ParseAccountStatus converts a string in any casing to a known account status
+func ParseAccountStatus(value string) (AccountStatus, error) {
+	return parseStatus("account", value, accountStatuses)
+}
+
+// IsFinal reports whether the transfer will not change status anymore
+func (s TransactionStatus) IsFinal() bool {
+	return s != TransactionPending
+}
+
+// UnmarshalJSON rejects unknown transaction statuses
+func (s *TransactionStatus) UnmarshalJSON(data []byte) error {
+	return unmarshalStatus(data, s, ParseTransactionStatus)
+}
+
+// Scan reads a transaction status from the database
+func (s *TransactionStatus) Scan(src any) error {
+	return scanStatus(src, s, ParseTransactionStatus)
+}
+
+// UnmarshalJSON rejects unknown account statuses
+func (s *AccountStatus) UnmarshalJSON(data []byte) error {
+	return unmarshalStatus(data, s, ParseAccountStatus)
+}
+
+// Scan reads an account status from the database
+func (s *AccountStatus) Scan(src any) error {
+	return scanStatus(src, s, ParseAccountStatus)
+}
+
+// parseStatus normalises value and matches it against the known statuses
+func parseStatus[T ~string](kind, value string, known []T) (T, error) {
+	normalized := T(strings.ToLower(strings.TrimSpace(value)))
+	for _, status := range known {
+		if status == normalized {
+			return status, nil
+		}
+	}
+	return "", fmt.Errorf("unknown %s status %q", kind, value)
+}
+
+// unmarshalStatus decodes a JSON string and parses it into dest
+func unmarshalStatus[T ~string](data []byte, dest *T, parse func(string) (T, error)) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	status, err := parse(value)
+	if err != nil {
+		return err
+	}
+	*dest = status
+	return nil
+}
+
+// scanStatus reads a text column and parses it into dest
+func scanStatus[T ~string](src any, dest *T, parse func(string) (T, error)) error {
+	var value string
+	switch v := src.(type) {
+	case string:
+		value = v
+	case []byte:
+		value = string(v)
+	default:
+		return fmt.Errorf("cannot scan %T into a status", src)
+	}
+	status, err := parse(value)
+	if err != nil {
+		return err
+	}
+	*dest = status
+	return nil
+}
@@ -0,0 +1,328 @@
+package i18n
+
+// hindi holds the Hindi translations of client-facing errors and notifications
+var hindi = map[string]string{
+	// Errors
+	"not found":         "नहीं मिला",
+	"validation failed": "सत्यापन विफल",
+	"unauthorized":      "अनधिकृत",
+	"forbidden":         "निषिद्ध",
+	"conflict":          "विरोध",
+	"upstream failure":  "बाहरी सेवा विफल",
+	"internal error":    "आंतरिक त्रुटि",
+
+	"user not found":             "उपयोगकर्ता नहीं मिला",
+	"wallet not found":           "वॉलेट नहीं मिला",
+	"sender wallet not found":    "प्रेषक का वॉलेट नहीं मिला",
+	"recipient wallet not found": "प्राप्तकर्ता का वॉलेट नहीं मिला",
+	"transaction not found":      "लेन-देन नहीं मिला",
+	"device token not found":     "डिवाइस टोकन नहीं मिला",
+	"unknown setting":            "अज्ञात सेटिंग",
+
+	"invalid password":                     "अमान्य पासवर्ड",
+	"account is closed":                    "खाता बंद है",
+	"unauthorized: sender wallet mismatch": "अनधिकृत: प्रेषक का वॉलेट मेल नहीं खाता",
+
+	"Username or email already taken":                                          "उपयोगकर्ता नाम या ईमेल पहले से लिया जा चुका है",
+	"account is already closed":                                                "खाता पहले से बंद है",
+	"account has %d pending transactions":                                      "खाते में %d लंबित लेन-देन हैं",
+	"archival already in progress":                                             "संग्रहण पहले से चल रहा है",
+	"transaction archival is disabled":                                         "लेन-देन संग्रहण बंद है",
+	"transaction has already been mined":                                       "लेन-देन पहले ही माइन हो चुका है",
+	"transaction is being cancelled":                                           "लेन-देन रद्द किया जा रहा है",
+	"transaction is already %s":                                                "लेन-देन पहले से %s है",
+	"transaction was settled or replaced concurrently":                         "लेन-देन इसी बीच निपटाया या बदला गया",
+	"key rotation already in progress":                                         "कुंजी रोटेशन पहले से चल रहा है",
+	"HD wallets are not configured":                                            "HD वॉलेट कॉन्फ़िगर नहीं हैं",
+	"label cannot be longer than %d characters":                                "लेबल %d अक्षरों से लंबा नहीं हो सकता",
+	"at most %d external wallets can be linked":                                "अधिकतम %d बाहरी वॉलेट जोड़े जा सकते हैं",
+	"address is already linked":                                                "पता पहले से जुड़ा हुआ है",
+	"challenge has expired, request a new one":                                 "चुनौती की समय-सीमा समाप्त हो गई है, नई चुनौती का अनुरोध करें",
+	"signature was not made with the linked address":                           "हस्ताक्षर जोड़े गए पते से नहीं किया गया था",
+	"challenge was replaced or has expired, request a new one":                 "चुनौती बदल दी गई है या उसकी समय-सीमा समाप्त हो गई है, नई चुनौती का अनुरोध करें",
+	"address is not a valid Ethereum address":                                  "पता मान्य Ethereum पता नहीं है",
+	"signature must be a 65 byte hex string":                                   "हस्ताक्षर 65 बाइट की hex स्ट्रिंग होना चाहिए",
+	"invalid signature":                                                        "अमान्य हस्ताक्षर",
+	"external wallet not found":                                                "बाहरी वॉलेट नहीं मिला",
+	"balance snapshot already in progress":                                     "शेष राशि स्नैपशॉट पहले से चल रहा है",
+	"amount must be a positive integer in wei":                                 "राशि wei में एक धनात्मक पूर्णांक होनी चाहिए",
+	"memo cannot be longer than %d characters":                                 "मेमो %d अक्षरों से लंबा नहीं हो सकता",
+	"expires_at must be in the future and within %d days":                      "expires_at भविष्य में और %d दिनों के भीतर होना चाहिए",
+	"you cannot pay your own payment request":                                  "आप अपने स्वयं के भुगतान अनुरोध का भुगतान नहीं कर सकते",
+	"payment request is no longer open":                                        "भुगतान अनुरोध अब खुला नहीं है",
+	"payment request was already paid":                                         "भुगतान अनुरोध का पहले ही भुगतान हो चुका है",
+	"payment request was cancelled":                                            "भुगतान अनुरोध रद्द कर दिया गया था",
+	"payment request has expired":                                              "भुगतान अनुरोध की समय-सीमा समाप्त हो गई है",
+	"payment request not found":                                                "भुगतान अनुरोध नहीं मिला",
+	"title is required and must be at most %d characters":                      "शीर्षक आवश्यक है और अधिकतम %d अक्षरों का हो सकता है",
+	"the organizer cannot be a participant":                                    "आयोजक प्रतिभागी नहीं हो सकता",
+	"participant wallet not found":                                             "प्रतिभागी का वॉलेट नहीं मिला",
+	"bill not found":                                                           "बिल नहीं मिला",
+	"you are already registered as a merchant":                                 "आप पहले से ही व्यापारी के रूप में पंजीकृत हैं",
+	"merchant not found":                                                       "व्यापारी नहीं मिला",
+	"name is required and must be at most %d characters":                       "नाम आवश्यक है और अधिकतम %d अक्षरों का हो सकता है",
+	"webhook_url must be an absolute https URL":                                "webhook_url एक पूर्ण https URL होना चाहिए",
+	"description cannot be longer than %d characters":                          "विवरण %d अक्षरों से लंबा नहीं हो सकता",
+	"reference cannot be longer than %d characters":                            "संदर्भ %d अक्षरों से लंबा नहीं हो सकता",
+	"reference was already used for a different amount":                        "यह संदर्भ पहले ही किसी अन्य राशि के लिए उपयोग किया जा चुका है",
+	"payment intent not found":                                                 "भुगतान इंटेंट नहीं मिला",
+	"payment intent can no longer be cancelled":                                "भुगतान इंटेंट अब रद्द नहीं किया जा सकता",
+	"payment intent can no longer be paid":                                     "भुगतान इंटेंट का अब भुगतान नहीं किया जा सकता",
+	"payment intent was already paid":                                          "भुगतान इंटेंट का पहले ही भुगतान हो चुका है",
+	"payment intent was cancelled":                                             "भुगतान इंटेंट रद्द कर दिया गया था",
+	"payment intent has expired":                                               "भुगतान इंटेंट की समय-सीमा समाप्त हो गई है",
+	"you cannot pay your own payment intent":                                   "आप अपने स्वयं के भुगतान इंटेंट का भुगतान नहीं कर सकते",
+	"notes cannot be longer than %d characters":                                "टिप्पणियाँ %d अक्षरों से लंबी नहीं हो सकतीं",
+	"due_date must be a date between today and %d days from now":               "due_date आज से %d दिनों के भीतर की तारीख होनी चाहिए",
+	"an invoice needs between 1 and %d line items":                             "चालान में 1 से %d पंक्ति मदें होनी चाहिए",
+	"description is required and must be at most %d characters":                "विवरण आवश्यक है और अधिकतम %d अक्षरों का हो सकता है",
+	"quantity must be between 1 and %d":                                        "मात्रा 1 और %d के बीच होनी चाहिए",
+	"unit_amount must be a positive integer in wei":                            "unit_amount wei में एक धनात्मक पूर्णांक होना चाहिए",
+	"set either recipient_user_id or recipient_email":                          "recipient_user_id या recipient_email में से कोई एक सेट करें",
+	"recipient_email must be a valid email address":                            "recipient_email एक मान्य ईमेल पता होना चाहिए",
+	"time_zone must be an IANA time zone such as Asia/Kolkata":                 "time_zone एक IANA समय क्षेत्र होना चाहिए, जैसे Asia/Kolkata",
+	"you cannot send an invoice to yourself":                                   "आप स्वयं को चालान नहीं भेज सकते",
+	"invoice not found":                                                        "चालान नहीं मिला",
+	"invoice is no longer open":                                                "चालान अब खुला नहीं है",
+	"threshold_wei must be a non-negative integer":                             "threshold_wei एक गैर-ऋणात्मक पूर्णांक होना चाहिए",
+	"min_sweep_wei must be a non-negative integer":                             "min_sweep_wei एक गैर-ऋणात्मक पूर्णांक होना चाहिए",
+	"destination_address is not a valid Ethereum address":                      "destination_address मान्य Ethereum पता नहीं है",
+	"destination_address must be a verified linked external wallet":            "destination_address एक सत्यापित जुड़ा हुआ बाहरी वॉलेट होना चाहिए",
+	"sweep rule not found":                                                     "स्वीप नियम नहीं मिला",
+	"reason_code must be one of %s":                                            "reason_code इनमें से एक होना चाहिए: %s",
+	"dataset must be one of %s":                                                "dataset इनमें से एक होना चाहिए: %s",
+	"format must be one of %s":                                                 "format इनमें से एक होना चाहिए: %s",
+	"from must be before to":                                                   "from, to से पहले होना चाहिए",
+	"download link is invalid or has expired":                                  "डाउनलोड लिंक अमान्य है या इसकी समय-सीमा समाप्त हो गई है",
+	"only confirmed transfers can be reversed, this one is %s":                 "केवल पुष्टि किए गए ट्रांसफर वापस किए जा सकते हैं, यह %s है",
+	"amount_wei must be a positive integer no larger than the transfer amount": "amount_wei एक धनात्मक पूर्णांक होना चाहिए जो ट्रांसफर राशि से अधिक न हो",
+	"unknown reversal status":                                                  "अज्ञात रिवर्सल स्थिति",
+	"transfer already has an open or executed reversal":                        "इस ट्रांसफर का पहले से एक खुला या पूरा हुआ रिवर्सल है",
+	"reversal not found":                                                       "रिवर्सल नहीं मिला",
+	"export not found":                                                         "निर्यात नहीं मिला",
+	"job not found":                                                            "जॉब नहीं मिला",
+	"status must be pending, succeeded or dead":                                "स्थिति pending, succeeded या dead होनी चाहिए",
+	"only dead jobs can be requeued":                                           "केवल dead जॉब ही फिर से कतार में डाले जा सकते हैं",
+	"login not found":                                                          "लॉगिन नहीं मिला",
+	"link is invalid or has expired":                                           "लिंक अमान्य है या इसकी समय-सीमा समाप्त हो गई है",
+	"this sign-in was already reported and the password has been reset":        "यह साइन-इन पहले ही रिपोर्ट किया जा चुका है और पासवर्ड रीसेट हो गया है",
+	"reset token is invalid or has expired":                                    "रीसेट टोकन अमान्य है या इसकी समय-सीमा समाप्त हो गई है",
+	"password reset required, use the link from the security alert":            "पासवर्ड रीसेट आवश्यक है, सुरक्षा चेतावनी में दिए गए लिंक का उपयोग करें",
+	"captcha required":                                                         "कैप्चा आवश्यक है",
+	"captcha verification failed":                                              "कैप्चा सत्यापन विफल",
+	"captcha verification unavailable":                                         "कैप्चा सत्यापन अभी उपलब्ध नहीं है",
+
+	// Signup fields
+	"must be between %d and %d characters": "%d से %d अक्षरों के बीच होना चाहिए",
+	"may only contain letters, digits, dots, hyphens and underscores, and must start with a letter or digit": "केवल अक्षर, अंक, बिंदु, हाइफ़न और अंडरस्कोर हो सकते हैं, और शुरुआत अक्षर या अंक से होनी चाहिए",
+	"must be a valid email address": "मान्य ईमेल पता होना चाहिए",
+	"must mix at least three of lowercase letters, uppercase letters, digits and symbols": "छोटे अक्षर, बड़े अक्षर, अंक और चिह्न में से कम से कम तीन का मेल होना चाहिए",
+	"must not contain the username":       "इसमें उपयोगकर्ता नाम नहीं होना चाहिए",
+	"is required":                         "आवश्यक है",
+	"must be a date in YYYY-MM-DD format": "YYYY-MM-DD प्रारूप में तिथि होनी चाहिए",
+	"cannot be in the future":             "भविष्य में नहीं हो सकती",
+	"is not a plausible date of birth":    "संभावित जन्म तिथि नहीं है",
+	"you must be at least %d years old":   "आपकी आयु कम से कम %d वर्ष होनी चाहिए",
+	"must be at most %d characters":       "अधिकतम %d अक्षरों का होना चाहिए",
+	"must be 1 or 2":                      "1 या 2 होना चाहिए",
+	"is already taken":                    "पहले से लिया जा चुका है",
+
+	"reversal is no longer waiting for approval":                       "रिवर्सल अब अनुमोदन की प्रतीक्षा में नहीं है",
+	"reversal is no longer executing":                                  "रिवर्सल अब निष्पादित नहीं हो रहा है",
+	"a reversal cannot be approved by the admin who requested it":      "रिवर्सल का अनुरोध करने वाला एडमिन उसे अनुमोदित नहीं कर सकता",
+	"a reversal cannot be rejected by the admin who requested it":      "रिवर्सल का अनुरोध करने वाला एडमिन उसे अस्वीकार नहीं कर सकता",
+	"failed to fetch the receiver balance":                             "प्राप्तकर्ता की शेष राशि प्राप्त करने में विफल",
+	"the receiver wallet cannot cover the returned amount and its gas": "प्राप्तकर्ता का वॉलेट लौटाई जाने वाली राशि और उसकी गैस को कवर नहीं कर सकता",
+	"date must be formatted as YYYY-MM-DD":                             "date का प्रारूप YYYY-MM-DD होना चाहिए",
+	"region must be 2 to 8 letters":                                    "region 2 से 8 अक्षरों का होना चाहिए",
+	"a holiday already exists on that date":                            "उस तारीख पर पहले से एक अवकाश है",
+	"role %s already exists":                                           "भूमिका %s पहले से मौजूद है",
+	"role not found":                                                   "भूमिका नहीं मिली",
+	"user does not hold this role":                                     "उपयोगकर्ता के पास यह भूमिका नहीं है",
+	"built-in roles cannot be changed or assigned":                     "अंतर्निहित भूमिकाएँ बदली या सौंपी नहीं जा सकतीं",
+	"name must be 2 to 64 lowercase letters, digits, dots, hyphens or underscores, starting with a letter": "नाम 2 से 64 छोटे अक्षरों, अंकों, बिंदुओं, हाइफ़न या अंडरस्कोर का होना चाहिए और किसी अक्षर से शुरू होना चाहिए",
+	"description must be at most %d characters":                                                            "विवरण अधिकतम %d वर्णों का हो सकता है",
+	"holiday not found":                                                         "अवकाश नहीं मिला",
+	"a bill needs between 1 and %d participants":                                "बिल में 1 से %d प्रतिभागी होने चाहिए",
+	"user_id is required for every participant":                                 "हर प्रतिभागी के लिए user_id आवश्यक है",
+	"participants must be distinct":                                             "प्रतिभागी अलग-अलग होने चाहिए",
+	"amount must equal the sum of the participant amounts":                      "राशि प्रतिभागियों की राशियों के योग के बराबर होनी चाहिए",
+	"amount is too small to split among the participants":                       "राशि प्रतिभागियों में बाँटने के लिए बहुत छोटी है",
+	"set the amount of every participant or of none":                            "या तो हर प्रतिभागी की राशि दें या किसी की नहीं",
+	"Sign-In with Ethereum is not configured":                                   "Ethereum से साइन-इन कॉन्फ़िगर नहीं है",
+	"invalid sign in message: %v":                                               "अमान्य साइन-इन संदेश: %v",
+	"message was not issued for this site":                                      "संदेश इस साइट के लिए जारी नहीं किया गया था",
+	"message was signed for another chain":                                      "संदेश किसी अन्य चेन के लिए हस्ताक्षरित था",
+	"message is not valid yet":                                                  "संदेश अभी मान्य नहीं है",
+	"message has expired":                                                       "संदेश की समय-सीमा समाप्त हो गई है",
+	"nonce is invalid, expired or was already used":                             "nonce अमान्य है, उसकी समय-सीमा समाप्त हो गई है या उसका पहले ही उपयोग हो चुका है",
+	"no account is linked to this address, link it as an external wallet first": "इस पते से कोई खाता जुड़ा नहीं है, पहले इसे बाहरी वॉलेट के रूप में जोड़ें",
+	"address is linked to several accounts, sign in with email instead":         "यह पता कई खातों से जुड़ा है, इसके बजाय ईमेल से साइन इन करें",
+
+	"both email and userID cannot be empty":                                    "ईमेल और userID दोनों खाली नहीं हो सकते",
+	"delivery must be immediate or daily_digest":                               "delivery का मान immediate या daily_digest होना चाहिए",
+	"gas_price_wei must be a positive integer":                                 "gas_price_wei एक धनात्मक पूर्णांक होना चाहिए",
+	"gas_price_wei must be at least %s":                                        "gas_price_wei कम से कम %s होना चाहिए",
+	"invalid amount format":                                                    "राशि का प्रारूप अमान्य है",
+	"invalid cursor":                                                           "अमान्य cursor",
+	"invalid feature flag":                                                     "अमान्य फ़ीचर फ़्लैग",
+	"invalid setting value":                                                    "सेटिंग का मान अमान्य है",
+	"invalid sweep_address":                                                    "अमान्य sweep_address",
+	"invalid wallet address":                                                   "अमान्य वॉलेट पता",
+	"platform must be android or ios":                                          "platform का मान android या ios होना चाहिए",
+	"reason is required":                                                       "कारण आवश्यक है",
+	"role must be 1 or 2":                                                      "role का मान 1 या 2 होना चाहिए",
+	"sweep_address is required while the wallet holds funds":                   "वॉलेट में धनराशि होने पर sweep_address आवश्यक है",
+	"token is required":                                                        "टोकन आवश्यक है",
+	"name is required and must be at most 100 characters":                      "नाम आवश्यक है और अधिकतम 100 अक्षरों का हो सकता है",
+	"at least one scope is required":                                           "कम से कम एक scope आवश्यक है",
+	"unknown scope %q":                                                         "अज्ञात scope %q",
+	"at most %d active API keys are allowed":                                   "अधिकतम %d सक्रिय API कुंजियों की अनुमति है",
+	"API key not found":                                                        "API कुंजी नहीं मिली",
+	"SSO login is not configured":                                              "SSO लॉगिन कॉन्फ़िगर नहीं है",
+	"SSO provider is unavailable":                                              "SSO प्रदाता उपलब्ध नहीं है",
+	"SSO login failed":                                                         "SSO लॉगिन विफल रहा",
+	"SSO account has no verified email":                                        "SSO खाते में कोई सत्यापित ईमेल नहीं है",
+	"unsupported locale %q":                                                    "असमर्थित भाषा %q",
+	"unknown category %q":                                                      "अज्ञात श्रेणी %q",
+	"range must be between 1d and %dd":                                         "range 1d और %dd के बीच होनी चाहिए",
+	"months must be between 1 and %d":                                          "months 1 और %d के बीच होना चाहिए",
+	"monthly_limit_wei must be a positive integer":                             "monthly_limit_wei एक धनात्मक पूर्णांक होना चाहिए",
+	"%s must be a positive integer":                                            "%s एक धनात्मक पूर्णांक होना चाहिए",
+	"organization not found":                                                   "संगठन नहीं मिला",
+	"organization member not found":                                            "संगठन सदस्य नहीं मिला",
+	"organization payment not found":                                           "संगठन भुगतान नहीं मिला",
+	"invitation not found":                                                     "आमंत्रण नहीं मिला",
+	"name must be between 1 and %d characters":                                 "नाम 1 से %d अक्षरों के बीच होना चाहिए",
+	"approvals_required must be between 1 and %d":                              "approvals_required 1 और %d के बीच होना चाहिए",
+	"unknown permission %q":                                                    "अज्ञात अनुमति %q",
+	"at least one permission is required":                                      "कम से कम एक अनुमति आवश्यक है",
+	"the owner already holds every permission":                                 "स्वामी के पास पहले से सभी अनुमतियाँ हैं",
+	"the owner cannot be removed":                                              "स्वामी को हटाया नहीं जा सकता",
+	"only the owner can manage members":                                        "केवल स्वामी सदस्यों का प्रबंधन कर सकता है",
+	"amount_wei must be a positive integer":                                    "amount_wei एक धनात्मक पूर्णांक होना चाहिए",
+	"a payment cannot be approved by its initiator":                            "भुगतान शुरू करने वाला उसे स्वीकृत नहीं कर सकता",
+	"payment is no longer waiting for approval":                                "भुगतान अब स्वीकृति की प्रतीक्षा में नहीं है",
+	"accept the invitation to the organization first":                          "पहले संगठन का आमंत्रण स्वीकार करें",
+	"the %s permission is required":                                            "%s अनुमति आवश्यक है",
+	"delegation not found":                                                     "प्रतिनिधिमंडल नहीं मिला",
+	"expires_at must be in the next %d days":                                   "expires_at अगले %d दिनों के भीतर होना चाहिए",
+	"access cannot be delegated to yourself":                                   "आप स्वयं को पहुँच नहीं सौंप सकते",
+	"user import not found":                                                    "उपयोगकर्ता आयात नहीं मिला",
+	"the file must start with a CSV header":                                    "फ़ाइल CSV हेडर से शुरू होनी चाहिए",
+	"missing column %q":                                                        "कॉलम %q अनुपलब्ध है",
+	"row %d is not valid CSV":                                                  "पंक्ति %d मान्य CSV नहीं है",
+	"at most %d rows can be imported at once":                                  "एक बार में अधिकतम %d पंक्तियाँ आयात की जा सकती हैं",
+	"the file has no rows to import":                                           "फ़ाइल में आयात करने के लिए कोई पंक्ति नहीं है",
+	"password is required to confirm the transfer":                             "स्थानांतरण की पुष्टि के लिए पासवर्ड आवश्यक है",
+	"totp_code is required to confirm the transfer":                            "स्थानांतरण की पुष्टि के लिए totp_code आवश्यक है",
+	"device_token is required to confirm the transfer":                         "स्थानांतरण की पुष्टि के लिए device_token आवश्यक है",
+	"invalid authenticator code":                                               "अमान्य प्रमाणक कोड",
+	"authenticator code was already used":                                      "प्रमाणक कोड पहले ही उपयोग हो चुका है",
+	"invalid device token":                                                     "अमान्य डिवाइस टोकन",
+	"authenticator not found":                                                  "प्रमाणक नहीं मिला",
+	"device not found":                                                         "डिवाइस नहीं मिला",
+	"unknown confirmation mode %q":                                             "अज्ञात पुष्टि मोड %q",
+	"enroll and confirm an authenticator first":                                "पहले प्रमाणक पंजीकृत करें और उसकी पुष्टि करें",
+	"add a device first":                                                       "पहले एक डिवाइस जोड़ें",
+	"switch to another confirmation mode before enrolling a new authenticator": "नया प्रमाणक पंजीकृत करने से पहले किसी अन्य पुष्टि मोड पर जाएँ",
+	"authenticator is already confirmed":                                       "प्रमाणक की पुष्टि पहले ही हो चुकी है",
+	"at most %d devices are allowed":                                           "अधिकतम %d डिवाइस की अनुमति है",
+	"switch to another confirmation mode before revoking your last device":     "अपना अंतिम डिवाइस हटाने से पहले किसी अन्य पुष्टि मोड पर जाएँ",
+	"pin is required to confirm the transfer":                                  "स्थानांतरण की पुष्टि के लिए PIN आवश्यक है",
+	"too many wrong PIN attempts, try again later":                             "बहुत अधिक गलत PIN प्रयास, बाद में पुनः प्रयास करें",
+	"invalid PIN":                                                              "अमान्य PIN",
+	"transaction PIN not found":                                                "लेन-देन PIN नहीं मिला",
+	"transaction PINs are disabled, confirm with your password":                "लेन-देन PIN अक्षम हैं, अपने पासवर्ड से पुष्टि करें",
+	"a PIN only confirms transfers up to %s wei, confirm with your password":   "PIN केवल %s wei तक के स्थानांतरण की पुष्टि करता है, अपने पासवर्ड से पुष्टि करें",
+	"the PIN must be %d digits":                                                "PIN %d अंकों का होना चाहिए",
+	"the PIN is too easy to guess":                                             "PIN का अनुमान लगाना बहुत आसान है",
+	"budget not found":                                                         "बजट नहीं मिला",
+	"amount exceeds the daily transfer limit of %s wei":                        "राशि %s wei की दैनिक स्थानांतरण सीमा से अधिक है",
+	"amount exceeds the maximum of %s wei per transfer":                        "राशि प्रति स्थानांतरण %s wei की अधिकतम सीमा से अधिक है",
+	"transaction was recorded without its nonce and cannot be replaced":        "लेन-देन nonce के बिना दर्ज हुआ था और बदला नहीं जा सकता",
+	"fiat on-ramp is not configured":                                           "फ़िएट ऑन-रैम्प कॉन्फ़िगर नहीं है",
+	"invalid webhook signature":                                                "अमान्य वेबहुक हस्ताक्षर",
+	"invalid webhook body":                                                     "अमान्य वेबहुक सामग्री",
+	"deposit_id is required and may have at most 128 characters":               "deposit_id आवश्यक है और इसमें अधिकतम 128 वर्ण हो सकते हैं",
+	"fiat_amount must be a positive decimal with at most 6 decimal places":     "fiat_amount अधिकतम 6 दशमलव स्थानों वाली धनात्मक दशमलव संख्या होनी चाहिए",
+	"fiat_currency must be a three letter ISO 4217 code":                       "fiat_currency तीन अक्षरों का ISO 4217 कोड होना चाहिए",
+	"crypto_amount_wei must be a non-negative integer":                         "crypto_amount_wei एक गैर-ऋणात्मक पूर्णांक होना चाहिए",
+	"the account of the deposit is closed":                                     "जमा का खाता बंद है",
+	"unknown deposit status":                                                   "अज्ञात जमा स्थिति",
+	"fiat off-ramp is not configured":                                          "फ़िएट ऑफ़-रैम्प कॉन्फ़िगर नहीं है",
+	"bank_account_token must be the token issued by the payout provider, not the account number": "bank_account_token भुगतान प्रदाता द्वारा जारी टोकन होना चाहिए, खाता संख्या नहीं",
+	"bank_account_last4 must be the last four digits of the bank account":                        "bank_account_last4 बैंक खाते के अंतिम चार अंक होने चाहिए",
+	"the account is closed":                                                      "खाता बंद है",
+	"the wallet cannot cover the withdrawal and its gas":                         "वॉलेट निकासी और उसकी गैस का भुगतान नहीं कर सकता",
+	"amount exceeds the available balance, %s wei are held for fiat withdrawals": "राशि उपलब्ध शेष से अधिक है, %s wei फ़िएट निकासी के लिए रोके गए हैं",
+	"unknown withdrawal status":                                                  "अज्ञात निकासी स्थिति",
+	"fiat withdrawal not found":                                                  "फ़िएट निकासी नहीं मिली",
+	"withdrawal is no longer %s":                                                 "निकासी अब %s स्थिति में नहीं है",
+	"payout_reference is required and may have at most %d characters":            "payout_reference आवश्यक है और इसमें अधिकतम %d वर्ण हो सकते हैं",
+	"reason is required and may have at most %d characters":                      "कारण आवश्यक है और इसमें अधिकतम %d वर्ण हो सकते हैं",
+	"bank linking is not configured":                                             "बैंक लिंकिंग कॉन्फ़िगर नहीं है",
+	"bank data provider unavailable":                                             "बैंक डेटा प्रदाता उपलब्ध नहीं है",
+	"public_token is required":                                                   "public_token आवश्यक है",
+	"invalid public token":                                                       "अमान्य public token",
+	"at most %d bank accounts can be linked":                                     "अधिकतम %d बैंक खाते लिंक किए जा सकते हैं",
+	"this bank account is already linked":                                        "यह बैंक खाता पहले से लिंक है",
+	"bank link not found":                                                        "बैंक लिंक नहीं मिला",
+	"PII backfill already in progress":                                           "व्यक्तिगत डेटा का एन्क्रिप्शन पहले से चल रहा है",
+	"refresh token is invalid or has expired":                                    "रीफ़्रेश टोकन अमान्य है या इसकी समय-सीमा समाप्त हो गई है",
+
+	"failed to fetch balance":                     "शेष राशि प्राप्त नहीं हो सकी",
+	"failed to preload tokens":                    "टोकन पहले से लोड नहीं हो सके",
+	"transaction failed":                          "लेन-देन विफल रहा",
+	"failed to broadcast transaction":             "लेन-देन प्रसारित नहीं हो सका",
+	"sweep transaction failed":                    "स्वीप लेन-देन विफल रहा",
+	"failed to broadcast sweep transaction":       "स्वीप लेन-देन प्रसारित नहीं हो सका",
+	"failed to check transaction receipt":         "लेन-देन की रसीद जाँची नहीं जा सकी",
+	"failed to sign replacement transaction":      "प्रतिस्थापन लेन-देन पर हस्ताक्षर नहीं हो सके",
+	"failed to broadcast replacement transaction": "प्रतिस्थापन लेन-देन प्रसारित नहीं हो सका",
+
+	// Notifications
+	"Transfer sent":                   "स्थानांतरण भेजा गया",
+	"You sent %s ETH.":                "आपने %s ETH भेजे।",
+	"Funds received":                  "धनराशि प्राप्त हुई",
+	"You received %s ETH.":            "आपको %s ETH प्राप्त हुए।",
+	"Your daily summary (%d updates)": "आपका दैनिक सारांश (%d अपडेट)",
+	"Large credit received":           "बड़ी राशि प्राप्त हुई",
+	"You received %s ETH, above your alert threshold of %s ETH.": "आपको %s ETH प्राप्त हुए, जो आपकी %s ETH की चेतावनी सीमा से अधिक है।",
+	"Low balance": "कम शेष राशि",
+	"Your balance is %s ETH, below your alert threshold of %s ETH.": "आपकी शेष राशि %s ETH है, जो आपकी %s ETH की चेतावनी सीमा से कम है।",
+	"Budget alert":         "बजट चेतावनी",
+	"Payment request paid": "भुगतान अनुरोध का भुगतान हो गया",
+	"Invoice received":     "चालान प्राप्त हुआ",
+	"You received an invoice %s for %s ETH due on %s.": "आपको %[3]s तक देय %[2]s ETH का चालान %[1]s प्राप्त हुआ।",
+	"Invoice due soon":                                         "चालान जल्द देय है",
+	"Your invoice %s for %s ETH is due on %s.":                 "%[2]s ETH का आपका चालान %[1]s %[3]s को देय है।",
+	"Invoice overdue":                                          "चालान अतिदेय है",
+	"Your invoice %s for %s ETH was due on %s.":                "%[2]s ETH का आपका चालान %[1]s %[3]s को देय था।",
+	"Invoice paid":                                             "चालान का भुगतान हो गया",
+	"Your invoice %s for %s ETH was paid.":                     "%[2]s ETH के आपके चालान %[1]s का भुगतान हो गया।",
+	"Excess balance swept":                                     "अतिरिक्त शेष राशि स्थानांतरित की गई",
+	"Transfer reversed":                                        "ट्रांसफर वापस किया गया",
+	"%s ETH from a transfer you sent was returned to you.":     "आपके भेजे गए ट्रांसफर से %s ETH आपको लौटा दिए गए।",
+	"%s ETH you received in error was returned to the sender.": "गलती से प्राप्त %s ETH प्रेषक को लौटा दिए गए।",
+	"%s ETH above your threshold of %s ETH was moved to %s.":   "%[2]s ETH की आपकी सीमा से ऊपर के %[1]s ETH %[3]s में भेजे गए।",
+	"New sign-in to your account":                              "आपके खाते में नया साइन-इन",
+	"Your account was signed in from a new device or network (%s, %s) at %s. If this wasn't you, open %s to sign out everywhere and reset your password.": "आपके खाते में %[3]s को एक नए डिवाइस या नेटवर्क (%[1]s, %[2]s) से साइन-इन किया गया। यदि यह आप नहीं थे, तो हर जगह से साइन आउट करने और अपना पासवर्ड रीसेट करने के लिए %[4]s खोलें।",
+	"Payment requested":                                              "भुगतान का अनुरोध किया गया",
+	"You were asked to pay %s ETH for %s.":                           "आपसे %[2]s के लिए %[1]s ETH का भुगतान करने का अनुरोध किया गया है।",
+	"Bill paid in full":                                              "बिल का पूरा भुगतान हो गया",
+	"All %d participants paid their share of %s.":                    "सभी %d प्रतिभागियों ने %s में अपने हिस्से का भुगतान कर दिया।",
+	"Your request for %s ETH was paid.":                              "%s ETH के आपके अनुरोध का भुगतान हो गया।",
+	"You have used %d%% of your monthly budget: %s of %s ETH spent.": "आपने अपने मासिक बजट का %d%% उपयोग कर लिया है: %s में से %s ETH खर्च हुए।",
+	"Deposit received":                                               "जमा प्राप्त हुई",
+	"Your deposit of %s %s was received.":                            "आपकी %s %s की जमा प्राप्त हुई।",
+	"Deposit credited":                                               "जमा आपके वॉलेट में जोड़ी गई",
+	"Your deposit of %s %s was credited to your wallet as %s ETH.":   "आपकी %s %s की जमा आपके वॉलेट में %s ETH के रूप में जोड़ी गई।",
+
+	"Withdrawal completed": "निकासी पूरी हुई",
+	"Your withdrawal of %s ETH was paid out to the bank account ending in %s.": "आपकी %s ETH की निकासी %s पर समाप्त होने वाले बैंक खाते में भेज दी गई।",
+	"Withdrawal rejected": "निकासी अस्वीकार की गई",
+	"Your withdrawal of %s ETH to the bank account ending in %s was rejected and the held funds were released.": "%[2]s पर समाप्त होने वाले बैंक खाते में आपकी %[1]s ETH की निकासी अस्वीकार कर दी गई और रोकी गई राशि मुक्त कर दी गई।",
+	"Withdrawal failed": "निकासी विफल रही",
+	"Your withdrawal of %s ETH to the bank account ending in %s could not be sent and the held funds were released.": "%[2]s पर समाप्त होने वाले बैंक खाते में आपकी %[1]s ETH की निकासी भेजी नहीं जा सकी और रोकी गई राशि मुक्त कर दी गई।",
+}
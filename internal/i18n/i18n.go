@@ -0,0 +1,82 @@
+// Package i18n translates user-facing messages. The English text of a message is its key in
+// the catalogs, so a message missing from a catalog is shown in English.
+package i18n
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Supported locales
+const (
+	English = "en"
+	Hindi   = "hi"
+
+	DefaultLocale = English
+)
+
+// catalogs maps a locale to its translations, keyed by the English message
+var catalogs = map[string]map[string]string{
+	Hindi: hindi,
+}
+
+// IsSupported reports whether messages can be shown in the locale
+func IsSupported(locale string) bool {
+	_, ok := catalogs[locale]
+	return ok || locale == English
+}
+
+// T translates message into the locale and fills in the fmt verbs of the translation with args
+func T(locale, message string, args ...any) string {
+	if translated, ok := catalogs[locale][message]; ok {
+		message = translated
+	}
+	if len(args) == 0 {
+		return message
+	}
+	return fmt.Sprintf(message, args...)
+}
+
+// FromContext returns the locale stored by the locale middleware, English when there is none
+func FromContext(ctx context.Context) string {
+	if locale, ok := ctx.Value("locale").(string); ok {
+		return locale
+	}
+	return DefaultLocale
+}
+
+// Negotiate picks the supported locale the client prefers most from an Accept-Language header
+func Negotiate(acceptLanguage string) string {
+	type candidate struct {
+		locale  string
+		quality float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		quality := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+
+		// Only the primary language matters, "hi-IN" is served from the "hi" catalog
+		language, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		if quality > 0 && IsSupported(language) {
+			candidates = append(candidates, candidate{locale: language, quality: quality})
+		}
+	}
+
+	if len(candidates) == 0 {
+		return DefaultLocale
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].quality > candidates[j].quality })
+	return candidates[0].locale
+}
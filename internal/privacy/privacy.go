@@ -0,0 +1,64 @@
+// Package privacy shapes responses that show other users' data. Admins see everything, other
+// viewers get counterparties as opaque public handles and masked wallet addresses.
+package privacy
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base32"
+	"strings"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/config"
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+)
+
+const handlePrefix = "usr_"
+
+var handleEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Viewer is the user a response is shaped for
+type Viewer struct {
+	UserID string
+	Admin  bool
+}
+
+// Transactions masks the counterparty of every transaction the viewer is not an admin for
+func (v Viewer) Transactions(transactions []repo.Transaction) []repo.Transaction {
+	if v.Admin {
+		return transactions
+	}
+
+	shaped := make([]repo.Transaction, len(transactions))
+	for i, txn := range transactions {
+		if txn.SenderUserID != v.UserID {
+			txn.SenderUserID = PublicHandle(txn.SenderUserID)
+			txn.SenderWalletID = MaskAddress(txn.SenderWalletID)
+		}
+		if txn.ReceiverUserID != v.UserID {
+			txn.ReceiverUserID = PublicHandle(txn.ReceiverUserID)
+			txn.ReceiverWalletID = MaskAddress(txn.ReceiverWalletID)
+		}
+		shaped[i] = txn
+	}
+	return shaped
+}
+
+// PublicHandle returns a stable identifier for the user that cannot be turned back into the user ID
+func PublicHandle(userID string) string {
+	secret := config.ConfigDetails.PublicHandleSecret
+	if secret == "" {
+		secret = config.ConfigDetails.JWTSecretKey
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(userID))
+	return handlePrefix + strings.ToLower(handleEncoding.EncodeToString(mac.Sum(nil)[:10]))
+}
+
+// MaskAddress keeps the first and last four hex digits of a wallet address, e.g. 0x1a2b...9f0e
+func MaskAddress(address string) string {
+	if len(address) <= 10 {
+		return strings.Repeat("*", len(address))
+	}
+	return address[:6] + "..." + address[len(address)-4:]
+}
@@ -0,0 +1,119 @@
+package repo
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+// ActivityEvent is one entry of a user's activity feed. ReferenceID points at the record behind
+// the event, Amount in wei is empty for events without one.
+type ActivityEvent struct {
+	EventID            string
+	Type               string
+	OccurredAt         time.Time
+	ReferenceID        string
+	Amount             string
+	Detail             string
+	CounterpartyUserID string
+}
+
+// ActivityCursor points at the last event of a page in (occurred_at, event_id) order
+type ActivityCursor struct {
+	OccurredAt time.Time
+	EventID    string
+}
+
+// All Activity Queries
+const (
+	// Merges the user's events recorded across the tables, newest first. Event IDs are prefixed with
+	// their type so they stay unique across sources.
+	getActivityQuery = `SELECT event_id, event_type, occurred_at, reference_id, amount, detail, counterparty_user_id FROM (
+			SELECT 'login:' || event_id AS event_id, 'login' AS event_type, created_at AS occurred_at, event_id::TEXT AS reference_id,
+				'' AS amount, method || ' ' || ip_address AS detail, '' AS counterparty_user_id
+				FROM login_events WHERE user_id = $1
+			UNION ALL
+			SELECT 'transfer:' || transaction_id, CASE WHEN sender_user_id = $1 THEN 'transfer_sent' ELSE 'transfer_received' END, created_at,
+				transaction_id::TEXT, amount::TEXT, status, CASE WHEN sender_user_id = $1 THEN receiver_user_id::TEXT ELSE sender_user_id::TEXT END
+				FROM transactions WHERE sender_user_id = $1 OR receiver_user_id = $1
+			UNION ALL
+			SELECT 'invoice_issued:' || invoice_id, 'invoice_issued', created_at, invoice_id::TEXT, total_amount::TEXT, title,
+				COALESCE(recipient_user_id::TEXT, '')
+				FROM invoices WHERE issuer_user_id = $1
+			UNION ALL
+			SELECT 'invoice_received:' || invoice_id, 'invoice_received', created_at, invoice_id::TEXT, total_amount::TEXT, title, issuer_user_id::TEXT
+				FROM invoices WHERE recipient_user_id = $1
+			UNION ALL
+			SELECT 'sweep:' || sweep_id, 'sweep', created_at, sweep_id::TEXT, amount::TEXT, status, ''
+				FROM sweeps WHERE user_id = $1
+			UNION ALL
+			SELECT 'fiat_deposit:' || deposit_id, 'fiat_deposit', created_at, deposit_id::TEXT,
+				CASE WHEN crypto_amount > 0 THEN crypto_amount::TEXT ELSE '' END, fiat_amount::TEXT || ' ' || fiat_currency || ' ' || status, ''
+				FROM fiat_deposits WHERE user_id = $1
+			UNION ALL
+			SELECT 'fiat_withdrawal:' || withdrawal_id, 'fiat_withdrawal', created_at, withdrawal_id::TEXT, amount::TEXT,
+				fiat_currency || ' ' || status, ''
+				FROM fiat_withdrawals WHERE user_id = $1
+			UNION ALL
+			SELECT 'api_key_created:' || key_id, 'api_key_created', created_at, key_id::TEXT, '', name, ''
+				FROM api_keys WHERE user_id = $1
+			UNION ALL
+			SELECT 'api_key_revoked:' || key_id, 'api_key_revoked', revoked_at, key_id::TEXT, '', name, ''
+				FROM api_keys WHERE user_id = $1 AND revoked_at IS NOT NULL
+			UNION ALL
+			SELECT 'external_wallet_linked:' || address, 'external_wallet_linked', verified_at, address, '', label, ''
+				FROM external_wallets WHERE user_id = $1 AND verified_at IS NOT NULL
+			UNION ALL
+			SELECT 'delegation_granted:' || delegation_id, 'delegation_granted', created_at, delegation_id::TEXT, '',
+				array_to_string(scopes, ','), delegate_user_id::TEXT
+				FROM delegations WHERE grantor_user_id = $1
+			UNION ALL
+			SELECT 'delegation_revoked:' || delegation_id, 'delegation_revoked', revoked_at, delegation_id::TEXT, '',
+				array_to_string(scopes, ','), delegate_user_id::TEXT
+				FROM delegations WHERE grantor_user_id = $1 AND revoked_at IS NOT NULL
+		) AS activity
+		WHERE $2::TIMESTAMPTZ IS NULL OR (occurred_at, event_id) < ($2::TIMESTAMPTZ, $3)
+		ORDER BY occurred_at DESC, event_id DESC LIMIT $4`
+)
+
+type activityRepo struct {
+	DB *sql.DB
+}
+
+type ActivityStorer interface {
+	GetActivity(userID string, limit int, cursor *ActivityCursor) ([]ActivityEvent, error)
+}
+
+// Constructor function
+func NewActivityRepo(db *sql.DB) ActivityStorer {
+	return &activityRepo{DB: db}
+}
+
+// Returns a page of the user's events strictly older than the cursor, newest first. A nil cursor
+// returns the first page.
+func (repoDep *activityRepo) GetActivity(userID string, limit int, cursor *ActivityCursor) ([]ActivityEvent, error) {
+	var before *time.Time
+	var beforeID string
+	if cursor != nil {
+		before, beforeID = &cursor.OccurredAt, cursor.EventID
+	}
+
+	rows, err := repoDep.DB.Query(getActivityQuery, userID, before, beforeID, limit)
+	if err != nil {
+		log.Printf("Error executing query: %v", err)
+		return nil, fmt.Errorf("error fetching activity: %v", err)
+	}
+	defer rows.Close()
+
+	events := []ActivityEvent{}
+	for rows.Next() {
+		var event ActivityEvent
+		if err := rows.Scan(&event.EventID, &event.Type, &event.OccurredAt, &event.ReferenceID, &event.Amount, &event.Detail,
+			&event.CounterpartyUserID); err != nil {
+			return nil, fmt.Errorf("error reading activity: %v", err)
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
@@ -0,0 +1,130 @@
+package repo
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+	"github.com/lib/pq"
+)
+
+// APIKey Regular struct, only the SHA-256 hash of the key itself is stored
+type APIKey struct {
+	KeyID      string
+	UserID     string
+	Name       string
+	Prefix     string
+	KeyHash    string
+	Scopes     []string
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+}
+
+// All API Key Queries
+const (
+	insertAPIKeyQuery      = `INSERT INTO api_keys (user_id, name, key_prefix, key_hash, scopes) VALUES ($1, $2, $3, $4, $5) RETURNING key_id, created_at`
+	getUserAPIKeysQuery    = `SELECT key_id, user_id, name, key_prefix, key_hash, scopes, created_at, last_used_at, revoked_at FROM api_keys WHERE user_id = $1 ORDER BY created_at DESC`
+	getAPIKeyByHashQuery   = `SELECT key_id, user_id, name, key_prefix, key_hash, scopes, created_at, last_used_at, revoked_at FROM api_keys WHERE key_hash = $1 AND revoked_at IS NULL`
+	countActiveAPIKeyQuery = `SELECT COUNT(*) FROM api_keys WHERE user_id = $1 AND revoked_at IS NULL`
+	revokeAPIKeyQuery      = `UPDATE api_keys SET revoked_at = NOW() WHERE key_id = $1 AND user_id = $2 AND revoked_at IS NULL`
+	touchAPIKeyQuery       = `UPDATE api_keys SET last_used_at = NOW() WHERE key_id = $1`
+)
+
+type apiKeyRepo struct {
+	DB *sql.DB
+}
+
+type APIKeyStorer interface {
+	CreateAPIKey(key APIKey) (APIKey, error)
+	GetUserAPIKeys(userID string) ([]APIKey, error)
+	GetAPIKeyByHash(keyHash string) (APIKey, error)
+	CountActiveAPIKeys(userID string) (int, error)
+	RevokeAPIKey(userID, keyID string) error
+	TouchAPIKey(keyID string) error
+}
+
+// Constructor function
+func NewAPIKeyRepo(db *sql.DB) APIKeyStorer {
+	return &apiKeyRepo{DB: db}
+}
+
+// Stores a new API key and returns it with the generated ID and timestamp
+func (repoDep *apiKeyRepo) CreateAPIKey(key APIKey) (APIKey, error) {
+	err := repoDep.DB.QueryRow(insertAPIKeyQuery, key.UserID, key.Name, key.Prefix, key.KeyHash, pq.Array(key.Scopes)).Scan(&key.KeyID, &key.CreatedAt)
+	if err != nil {
+		log.Printf("Error inserting API key: %v", err)
+		return key, fmt.Errorf("error creating API key: %v", err)
+	}
+	return key, nil
+}
+
+// Returns every API key of the user, revoked ones included, newest first
+func (repoDep *apiKeyRepo) GetUserAPIKeys(userID string) ([]APIKey, error) {
+	rows, err := repoDep.DB.Query(getUserAPIKeysQuery, userID)
+	if err != nil {
+		log.Printf("Error executing query: %v", err)
+		return nil, fmt.Errorf("error fetching API keys: %v", err)
+	}
+	defer rows.Close()
+
+	keys := []APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error reading API keys: %v", err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// Returns the active API key with the given hash
+func (repoDep *apiKeyRepo) GetAPIKeyByHash(keyHash string) (APIKey, error) {
+	key, err := scanAPIKey(repoDep.DB.QueryRow(getAPIKeyByHashQuery, keyHash))
+	if err != nil {
+		return key, utils.FromDBError("API key", err)
+	}
+	return key, nil
+}
+
+// Returns how many API keys of the user are not revoked
+func (repoDep *apiKeyRepo) CountActiveAPIKeys(userID string) (int, error) {
+	var count int
+	if err := repoDep.DB.QueryRow(countActiveAPIKeyQuery, userID).Scan(&count); err != nil {
+		log.Printf("Error executing query: %v", err)
+		return 0, fmt.Errorf("error counting API keys: %v", err)
+	}
+	return count, nil
+}
+
+// Revokes one of the user's API keys
+func (repoDep *apiKeyRepo) RevokeAPIKey(userID, keyID string) error {
+	result, err := repoDep.DB.Exec(revokeAPIKeyQuery, keyID, userID)
+	if err != nil {
+		log.Printf("Error revoking API key: %v", err)
+		return fmt.Errorf("error revoking API key: %v", err)
+	}
+	if rowsAffected, err := result.RowsAffected(); err == nil && rowsAffected == 0 {
+		return utils.NotFound("API key not found", nil)
+	}
+	return nil
+}
+
+// Records that the API key was just used
+func (repoDep *apiKeyRepo) TouchAPIKey(keyID string) error {
+	if _, err := repoDep.DB.Exec(touchAPIKeyQuery, keyID); err != nil {
+		log.Printf("Error updating API key usage: %v", err)
+		return fmt.Errorf("error updating API key usage: %v", err)
+	}
+	return nil
+}
+
+// Scans one row of an API key query
+func scanAPIKey(row interface{ Scan(dest ...any) error }) (APIKey, error) {
+	var key APIKey
+	err := row.Scan(&key.KeyID, &key.UserID, &key.Name, &key.Prefix, &key.KeyHash, pq.Array(&key.Scopes), &key.CreatedAt, &key.LastUsedAt, &key.RevokedAt)
+	return key, err
+}
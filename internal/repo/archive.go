@@ -0,0 +1,60 @@
+package repo
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// All Archive Queries
+const (
+	// Moves one batch of settled transactions in a single statement so a row is never in both tables
+	archiveTransactionsBatchQuery = `WITH moved AS (
+		DELETE FROM transactions WHERE transaction_id IN (
+			SELECT transaction_id FROM transactions
+			WHERE created_at < $1 AND status <> 'pending'
+			ORDER BY created_at LIMIT $2
+		)
+		RETURNING transaction_id, tx_hash, sender_user_id, receiver_user_id, sender_wallet_id, receiver_wallet_id, amount, status, created_at
+	)
+	INSERT INTO transactions_archive (transaction_id, tx_hash, sender_user_id, receiver_user_id, sender_wallet_id, receiver_wallet_id, amount, status, created_at)
+	SELECT transaction_id, tx_hash, sender_user_id, receiver_user_id, sender_wallet_id, receiver_wallet_id, amount, status, created_at FROM moved`
+)
+
+type archiveRepo struct {
+	DB *DBRouter
+}
+
+type ArchiveStorer interface {
+	ArchiveTransactionsBefore(cutoff time.Time, batchSize int) (int64, error)
+}
+
+// Constructor function
+func NewArchiveRepo(db *DBRouter) ArchiveStorer {
+	return &archiveRepo{DB: db}
+}
+
+// Moves settled transactions created before cutoff into transactions_archive, batch by batch,
+// and returns the number of rows moved
+func (repoDep *archiveRepo) ArchiveTransactionsBefore(cutoff time.Time, batchSize int) (int64, error) {
+	var total int64
+
+	for {
+		result, err := repoDep.DB.Writer().Exec(archiveTransactionsBatchQuery, cutoff, batchSize)
+		if err != nil {
+			log.Printf("Error archiving transactions: %v", err)
+			return total, fmt.Errorf("error archiving transactions: %v", err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			log.Printf("Error checking affected rows: %v", err)
+			return total, fmt.Errorf("error checking affected rows: %v", err)
+		}
+
+		total += rowsAffected
+		if rowsAffected < int64(batchSize) {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package repo
+
+import (
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+)
+
+// BalanceSnapshot is the balance of a wallet recorded on a UTC day, in wei
+type BalanceSnapshot struct {
+	WalletID string
+	Date     time.Time
+	Balance  *big.Int
+}
+
+// All Balance Snapshot Queries
+const (
+	getActiveWalletIDsQuery = `SELECT w.wallet_id FROM wallets w INNER JOIN users u ON w.user_id = u.user_id
+		WHERE u.account_status = 'active' ORDER BY w.wallet_id`
+	upsertBalanceSnapshotQuery = `INSERT INTO wallet_balance_snapshots (wallet_id, snapshot_date, balance) VALUES ($1, $2, $3::NUMERIC)
+		ON CONFLICT (wallet_id, snapshot_date) DO UPDATE SET balance = EXCLUDED.balance, recorded_at = NOW()`
+	getBalanceSnapshotsQuery = `SELECT wallet_id, snapshot_date, balance::TEXT FROM wallet_balance_snapshots
+		WHERE wallet_id = $1 AND snapshot_date >= $2 ORDER BY snapshot_date`
+)
+
+type balanceRepo struct {
+	DB *DBRouter
+}
+
+type BalanceStorer interface {
+	GetActiveWalletIDs() ([]string, error)
+	SaveBalanceSnapshot(snapshot BalanceSnapshot) error
+	GetBalanceSnapshots(walletID string, since time.Time) ([]BalanceSnapshot, error)
+}
+
+// Constructor function
+func NewBalanceRepo(db *DBRouter) BalanceStorer {
+	return &balanceRepo{DB: db}
+}
+
+// Returns the wallet of every account that is still open
+func (repoDep *balanceRepo) GetActiveWalletIDs() ([]string, error) {
+	rows, err := repoDep.DB.Reader().Query(getActiveWalletIDsQuery)
+	if err != nil {
+		log.Printf("Error fetching wallet IDs: %v", err)
+		return nil, fmt.Errorf("error fetching wallet IDs: %v", err)
+	}
+	defer rows.Close()
+
+	var walletIDs []string
+	for rows.Next() {
+		var walletID string
+		if err := rows.Scan(&walletID); err != nil {
+			return nil, fmt.Errorf("error reading wallet IDs: %v", err)
+		}
+		walletIDs = append(walletIDs, walletID)
+	}
+	return walletIDs, rows.Err()
+}
+
+// Stores the balance of a wallet for a day, replacing an earlier snapshot of the same day
+func (repoDep *balanceRepo) SaveBalanceSnapshot(snapshot BalanceSnapshot) error {
+	_, err := repoDep.DB.Writer().Exec(upsertBalanceSnapshotQuery, snapshot.WalletID, snapshot.Date.Format(time.DateOnly), snapshot.Balance.String())
+	if err != nil {
+		log.Printf("Error saving balance snapshot of %s: %v", snapshot.WalletID, err)
+		return fmt.Errorf("error saving balance snapshot: %v", err)
+	}
+	return nil
+}
+
+// Returns the snapshots of a wallet from since onwards, oldest first
+func (repoDep *balanceRepo) GetBalanceSnapshots(walletID string, since time.Time) ([]BalanceSnapshot, error) {
+	rows, err := repoDep.DB.Reader().Query(getBalanceSnapshotsQuery, walletID, since.Format(time.DateOnly))
+	if err != nil {
+		log.Printf("Error fetching balance snapshots: %v", err)
+		return nil, fmt.Errorf("error fetching balance snapshots: %v", err)
+	}
+	defer rows.Close()
+
+	var snapshots []BalanceSnapshot
+	for rows.Next() {
+		var snapshot BalanceSnapshot
+		var balance string
+		if err := rows.Scan(&snapshot.WalletID, &snapshot.Date, &balance); err != nil {
+			return nil, fmt.Errorf("error reading balance snapshots: %v", err)
+		}
+		snapshot.Balance, _ = new(big.Int).SetString(balance, 10)
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, rows.Err()
+}
@@ -0,0 +1,91 @@
+package repo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+)
+
+// BalanceAlert holds a user's standing alert thresholds in wei, nil leaves an alert off
+type BalanceAlert struct {
+	UserID            string
+	LowBalanceWei     *big.Int
+	LargeCreditWei    *big.Int
+	LowBalanceAlerted bool
+}
+
+// All Balance Alert Queries
+const (
+	getBalanceAlertQuery    = `SELECT user_id, COALESCE(low_balance_wei::TEXT, ''), COALESCE(large_credit_wei::TEXT, ''), low_balance_alerted FROM balance_alerts WHERE user_id = $1`
+	upsertBalanceAlertQuery = `INSERT INTO balance_alerts (user_id, low_balance_wei, large_credit_wei) VALUES ($1, NULLIF($2, '')::NUMERIC, NULLIF($3, '')::NUMERIC)
+		ON CONFLICT (user_id) DO UPDATE SET low_balance_wei = EXCLUDED.low_balance_wei, large_credit_wei = EXCLUDED.large_credit_wei,
+		low_balance_alerted = FALSE, updated_at = NOW()`
+	setLowBalanceAlertedQuery = `UPDATE balance_alerts SET low_balance_alerted = $2 WHERE user_id = $1 AND low_balance_alerted <> $2`
+)
+
+type balanceAlertRepo struct {
+	DB *sql.DB
+}
+
+type BalanceAlertStorer interface {
+	GetBalanceAlert(userID string) (BalanceAlert, error)
+	UpsertBalanceAlert(alert BalanceAlert) error
+	SetLowBalanceAlerted(userID string, alerted bool) (bool, error)
+}
+
+// Constructor function
+func NewBalanceAlertRepo(db *sql.DB) BalanceAlertStorer {
+	return &balanceAlertRepo{DB: db}
+}
+
+// Returns the user's alert thresholds, both off when the user never set any
+func (repoDep *balanceAlertRepo) GetBalanceAlert(userID string) (BalanceAlert, error) {
+	alert := BalanceAlert{UserID: userID}
+	var lowBalance, largeCredit string
+	err := repoDep.DB.QueryRow(getBalanceAlertQuery, userID).Scan(&alert.UserID, &lowBalance, &largeCredit, &alert.LowBalanceAlerted)
+	if errors.Is(err, sql.ErrNoRows) {
+		return alert, nil
+	}
+	if err != nil {
+		log.Printf("Error executing query: %v", err)
+		return alert, fmt.Errorf("error fetching balance alerts: %v", err)
+	}
+
+	alert.LowBalanceWei, _ = new(big.Int).SetString(lowBalance, 10)
+	alert.LargeCreditWei, _ = new(big.Int).SetString(largeCredit, 10)
+	return alert, nil
+}
+
+// Replaces the user's thresholds and re-arms the low balance alert
+func (repoDep *balanceAlertRepo) UpsertBalanceAlert(alert BalanceAlert) error {
+	_, err := repoDep.DB.Exec(upsertBalanceAlertQuery, alert.UserID, optionalWei(alert.LowBalanceWei), optionalWei(alert.LargeCreditWei))
+	if err != nil {
+		log.Printf("Error saving balance alerts of %s: %v", alert.UserID, err)
+		return fmt.Errorf("error saving balance alerts: %v", err)
+	}
+	return nil
+}
+
+// Marks the low balance alert as fired or re-armed, false when it already was in that state
+func (repoDep *balanceAlertRepo) SetLowBalanceAlerted(userID string, alerted bool) (bool, error) {
+	result, err := repoDep.DB.Exec(setLowBalanceAlertedQuery, userID, alerted)
+	if err != nil {
+		log.Printf("Error updating low balance alert of %s: %v", userID, err)
+		return false, fmt.Errorf("error updating low balance alert: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error checking affected rows: %v", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+// optionalWei formats an optional amount, empty for nil
+func optionalWei(value *big.Int) string {
+	if value == nil {
+		return ""
+	}
+	return value.String()
+}
@@ -0,0 +1,176 @@
+package repo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+// BankLink is a bank account the user linked through the bank data provider, ProviderAccountID is the
+// provider's ID of the account and Mask its last digits
+type BankLink struct {
+	LinkID            string
+	UserID            string
+	Provider          string
+	ProviderAccountID string
+	InstitutionName   string
+	Mask              string
+	AccountType       string
+	Currency          string
+	CreatedAt         time.Time
+}
+
+// All Bank Link Queries
+const (
+	bankLinkColumns = `link_id, user_id, provider, provider_account_id, institution_name, account_mask, account_type, currency, created_at`
+	// Returns no row when the account is already linked to the user
+	insertBankLinkQuery = `INSERT INTO bank_links (user_id, provider, provider_account_id, institution_name, account_mask, account_type, currency)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (user_id, provider, provider_account_id) WHERE unlinked_at IS NULL DO NOTHING
+		RETURNING ` + bankLinkColumns
+	saveBankLinkTokenQuery   = `INSERT INTO bank_link_tokens (link_id, access_token, key_version, cipher) VALUES ($1, $2, $3, $4)`
+	getBankLinkTokenQuery    = `SELECT access_token, key_version, cipher FROM bank_link_tokens WHERE link_id = $1`
+	getBankLinkQuery         = `SELECT ` + bankLinkColumns + ` FROM bank_links WHERE link_id = $1 AND unlinked_at IS NULL`
+	getBankLinksQuery        = `SELECT ` + bankLinkColumns + ` FROM bank_links WHERE user_id = $1 AND unlinked_at IS NULL ORDER BY created_at`
+	unlinkBankLinkQuery      = `UPDATE bank_links SET unlinked_at = NOW() WHERE link_id = $1 AND unlinked_at IS NULL`
+	deleteBankLinkTokenQuery = `DELETE FROM bank_link_tokens WHERE link_id = $1`
+)
+
+type bankLinkRepo struct {
+	DB      *sql.DB
+	keyRing PrivateKeyRing
+}
+
+type BankLinkStorer interface {
+	CreateBankLink(link BankLink, accessToken string) (BankLink, error)
+	GetBankLink(linkID string) (BankLink, error)
+	GetBankLinks(userID string) ([]BankLink, error)
+	GetAccessToken(linkID string) (string, error)
+	UnlinkBankLink(linkID string) error
+}
+
+// Constructor function
+func NewBankLinkRepo(db *sql.DB, keyRing PrivateKeyRing) BankLinkStorer {
+	return &bankLinkRepo{DB: db, keyRing: keyRing}
+}
+
+// Records a linked account with its encrypted access token in one transaction
+func (repoDep *bankLinkRepo) CreateBankLink(link BankLink, accessToken string) (BankLink, error) {
+	tx, err := repoDep.DB.Begin()
+	if err != nil {
+		return link, fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	created, err := scanBankLink(tx.QueryRow(insertBankLinkQuery, link.UserID, link.Provider, link.ProviderAccountID, link.InstitutionName,
+		link.Mask, link.AccountType, link.Currency))
+	if errors.Is(err, sql.ErrNoRows) {
+		return link, utils.Conflict("this bank account is already linked")
+	}
+	if err != nil {
+		log.Printf("Error linking bank account of %s: %v", link.UserID, err)
+		return link, fmt.Errorf("error linking bank account: %v", err)
+	}
+
+	key, err := repoDep.keyRing.sealingKey()
+	if err != nil {
+		return link, fmt.Errorf("failed to encrypt access token: %v", err)
+	}
+	encryptedToken, err := sealPrivateKey(accessToken, key, created.LinkID, repoDep.keyRing.Current)
+	if err != nil {
+		return link, fmt.Errorf("failed to encrypt access token: %v", err)
+	}
+	if _, err := tx.Exec(saveBankLinkTokenQuery, created.LinkID, encryptedToken, repoDep.keyRing.Current, cipherGCM); err != nil {
+		log.Printf("Error storing access token of bank link %s: %v", created.LinkID, err)
+		return link, fmt.Errorf("error storing access token: %v", err)
+	}
+
+	return created, tx.Commit()
+}
+
+// Returns a bank link that is still linked
+func (repoDep *bankLinkRepo) GetBankLink(linkID string) (BankLink, error) {
+	link, err := scanBankLink(repoDep.DB.QueryRow(getBankLinkQuery, linkID))
+	if err != nil {
+		return link, utils.FromDBError("bank link", err)
+	}
+	return link, nil
+}
+
+// Returns the accounts the user has linked, oldest first
+func (repoDep *bankLinkRepo) GetBankLinks(userID string) ([]BankLink, error) {
+	rows, err := repoDep.DB.Query(getBankLinksQuery, userID)
+	if err != nil {
+		log.Printf("Error executing query: %v", err)
+		return nil, fmt.Errorf("error fetching bank links: %v", err)
+	}
+	defer rows.Close()
+
+	links := []BankLink{}
+	for rows.Next() {
+		link, err := scanBankLink(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error reading bank links: %v", err)
+		}
+		links = append(links, link)
+	}
+	return links, rows.Err()
+}
+
+// Retrieves and decrypts the provider's access token of a bank link
+func (repoDep *bankLinkRepo) GetAccessToken(linkID string) (string, error) {
+	var encryptedToken, cipherName string
+	var keyVersion int
+	err := repoDep.DB.QueryRow(getBankLinkTokenQuery, linkID).Scan(&encryptedToken, &keyVersion, &cipherName)
+	if err != nil {
+		return "", utils.FromDBError("access token", err)
+	}
+	if cipherName != cipherGCM {
+		return "", fmt.Errorf("unknown cipher %q", cipherName)
+	}
+
+	key, err := repoDep.keyRing.key(keyVersion)
+	if err != nil {
+		return "", err
+	}
+	accessToken, err := openPrivateKey(encryptedToken, key, linkID, keyVersion)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt access token: %v", err)
+	}
+	return accessToken, nil
+}
+
+// Marks a bank link unlinked and deletes its access token
+func (repoDep *bankLinkRepo) UnlinkBankLink(linkID string) error {
+	tx, err := repoDep.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(unlinkBankLinkQuery, linkID)
+	if err != nil {
+		log.Printf("Error unlinking bank link %s: %v", linkID, err)
+		return fmt.Errorf("error unlinking bank account: %v", err)
+	}
+	if rowsAffected, err := result.RowsAffected(); err != nil || rowsAffected == 0 {
+		return utils.NotFound("bank link not found", err)
+	}
+	if _, err := tx.Exec(deleteBankLinkTokenQuery, linkID); err != nil {
+		log.Printf("Error deleting access token of bank link %s: %v", linkID, err)
+		return fmt.Errorf("error unlinking bank account: %v", err)
+	}
+	return tx.Commit()
+}
+
+// Scans one row of the bankLinkColumns
+func scanBankLink(row interface{ Scan(dest ...any) error }) (BankLink, error) {
+	var link BankLink
+	err := row.Scan(&link.LinkID, &link.UserID, &link.Provider, &link.ProviderAccountID, &link.InstitutionName, &link.Mask,
+		&link.AccountType, &link.Currency, &link.CreatedAt)
+	return link, err
+}
@@ -0,0 +1,105 @@
+package repo
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+// Bill is an amount the organizer split among participants, each share is a payment request
+type Bill struct {
+	BillID          string
+	OrganizerUserID string
+	Title           string
+	TotalAmount     string
+	CreatedAt       time.Time
+}
+
+// All Bill Queries
+const (
+	billColumns     = `bill_id, organizer_user_id, title, total_amount::TEXT, created_at`
+	insertBillQuery = `INSERT INTO bills (organizer_user_id, title, total_amount) VALUES ($1, $2, $3::NUMERIC) RETURNING ` + billColumns
+	getBillQuery    = `SELECT ` + billColumns + ` FROM bills WHERE bill_id = $1`
+	getBillsQuery   = `SELECT ` + billColumns + ` FROM bills WHERE organizer_user_id = $1 ORDER BY created_at DESC LIMIT $2`
+)
+
+type billRepo struct {
+	DB *sql.DB
+}
+
+type BillStorer interface {
+	CreateBill(bill Bill, shares []PaymentRequest) (Bill, []PaymentRequest, error)
+	GetBill(billID string) (Bill, error)
+	GetBills(organizerUserID string, limit int) ([]Bill, error)
+}
+
+// Constructor function
+func NewBillRepo(db *sql.DB) BillStorer {
+	return &billRepo{DB: db}
+}
+
+// Stores the bill and the payment request of every share in one transaction
+func (repoDep *billRepo) CreateBill(bill Bill, shares []PaymentRequest) (Bill, []PaymentRequest, error) {
+	tx, err := repoDep.DB.Begin()
+	if err != nil {
+		return bill, nil, fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	created, err := scanBill(tx.QueryRow(insertBillQuery, bill.OrganizerUserID, bill.Title, bill.TotalAmount))
+	if err != nil {
+		log.Printf("Error creating bill of %s: %v", bill.OrganizerUserID, err)
+		return bill, nil, fmt.Errorf("error creating bill: %v", err)
+	}
+
+	requests := make([]PaymentRequest, len(shares))
+	for i, share := range shares {
+		requests[i], err = scanPaymentRequest(tx.QueryRow(insertPaymentRequestQuery, created.OrganizerUserID, created.BillID, share.PayerUserID,
+			share.Amount, share.Memo, share.ExpiresAt))
+		if err != nil {
+			log.Printf("Error creating payment request of bill %s: %v", created.BillID, err)
+			return bill, nil, fmt.Errorf("error creating payment request: %v", err)
+		}
+	}
+
+	return created, requests, tx.Commit()
+}
+
+// Returns a bill without its shares
+func (repoDep *billRepo) GetBill(billID string) (Bill, error) {
+	bill, err := scanBill(repoDep.DB.QueryRow(getBillQuery, billID))
+	if err != nil {
+		return bill, utils.FromDBError("bill", err)
+	}
+	return bill, nil
+}
+
+// Returns the latest bills the user organized, newest first
+func (repoDep *billRepo) GetBills(organizerUserID string, limit int) ([]Bill, error) {
+	rows, err := repoDep.DB.Query(getBillsQuery, organizerUserID, limit)
+	if err != nil {
+		log.Printf("Error executing query: %v", err)
+		return nil, fmt.Errorf("error fetching bills: %v", err)
+	}
+	defer rows.Close()
+
+	bills := []Bill{}
+	for rows.Next() {
+		bill, err := scanBill(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error reading bills: %v", err)
+		}
+		bills = append(bills, bill)
+	}
+	return bills, rows.Err()
+}
+
+// Scans one row of the billColumns
+func scanBill(row interface{ Scan(dest ...any) error }) (Bill, error) {
+	var bill Bill
+	err := row.Scan(&bill.BillID, &bill.OrganizerUserID, &bill.Title, &bill.TotalAmount, &bill.CreatedAt)
+	return bill, err
+}
@@ -0,0 +1,123 @@
+package repo
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+// Budget is the monthly spending limit of a user in wei
+type Budget struct {
+	UserID       string
+	MonthlyLimit *big.Int
+	UpdatedAt    time.Time
+}
+
+// All Budget Queries
+const (
+	getBudgetQuery    = `SELECT user_id, monthly_limit::TEXT, updated_at FROM budgets WHERE user_id = $1`
+	getBudgetsQuery   = `SELECT b.user_id, b.monthly_limit::TEXT, b.updated_at FROM budgets b INNER JOIN users u ON b.user_id = u.user_id WHERE u.account_status = 'active'`
+	upsertBudgetQuery = `INSERT INTO budgets (user_id, monthly_limit) VALUES ($1, $2::NUMERIC)
+		ON CONFLICT (user_id) DO UPDATE SET monthly_limit = EXCLUDED.monthly_limit, updated_at = NOW() RETURNING updated_at`
+	deleteBudgetQuery = `DELETE FROM budgets WHERE user_id = $1`
+	// Inserts nothing when the threshold was already alerted on this month
+	insertBudgetAlertQuery = `INSERT INTO budget_alerts (user_id, month, threshold) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`
+)
+
+type budgetRepo struct {
+	DB *sql.DB
+}
+
+type BudgetStorer interface {
+	GetBudget(userID string) (Budget, error)
+	GetBudgets() ([]Budget, error)
+	UpsertBudget(userID string, monthlyLimit *big.Int) (Budget, error)
+	DeleteBudget(userID string) error
+	RecordBudgetAlert(userID string, month time.Time, threshold int) (bool, error)
+}
+
+// Constructor function
+func NewBudgetRepo(db *sql.DB) BudgetStorer {
+	return &budgetRepo{DB: db}
+}
+
+// Returns the budget of a user
+func (repoDep *budgetRepo) GetBudget(userID string) (Budget, error) {
+	budget, err := scanBudget(repoDep.DB.QueryRow(getBudgetQuery, userID))
+	if err != nil {
+		return budget, utils.FromDBError("budget", err)
+	}
+	return budget, nil
+}
+
+// Returns the budgets of every open account
+func (repoDep *budgetRepo) GetBudgets() ([]Budget, error) {
+	rows, err := repoDep.DB.Query(getBudgetsQuery)
+	if err != nil {
+		log.Printf("Error executing query: %v", err)
+		return nil, fmt.Errorf("error fetching budgets: %v", err)
+	}
+	defer rows.Close()
+
+	var budgets []Budget
+	for rows.Next() {
+		budget, err := scanBudget(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error reading budgets: %v", err)
+		}
+		budgets = append(budgets, budget)
+	}
+	return budgets, rows.Err()
+}
+
+// Sets the monthly limit of a user, replacing the previous one
+func (repoDep *budgetRepo) UpsertBudget(userID string, monthlyLimit *big.Int) (Budget, error) {
+	budget := Budget{UserID: userID, MonthlyLimit: monthlyLimit}
+	if err := repoDep.DB.QueryRow(upsertBudgetQuery, userID, monthlyLimit.String()).Scan(&budget.UpdatedAt); err != nil {
+		log.Printf("Error saving budget of %s: %v", userID, err)
+		return budget, fmt.Errorf("error saving budget: %v", err)
+	}
+	return budget, nil
+}
+
+// Removes the budget of a user
+func (repoDep *budgetRepo) DeleteBudget(userID string) error {
+	result, err := repoDep.DB.Exec(deleteBudgetQuery, userID)
+	if err != nil {
+		log.Printf("Error deleting budget of %s: %v", userID, err)
+		return fmt.Errorf("error deleting budget: %v", err)
+	}
+	if rowsAffected, err := result.RowsAffected(); err == nil && rowsAffected == 0 {
+		return utils.NotFound("budget not found", nil)
+	}
+	return nil
+}
+
+// Records that a threshold was alerted on for the month, false when it already was
+func (repoDep *budgetRepo) RecordBudgetAlert(userID string, month time.Time, threshold int) (bool, error) {
+	result, err := repoDep.DB.Exec(insertBudgetAlertQuery, userID, month.Format(time.DateOnly), threshold)
+	if err != nil {
+		log.Printf("Error recording budget alert of %s: %v", userID, err)
+		return false, fmt.Errorf("error recording budget alert: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error checking affected rows: %v", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+// Scans one row of getBudgetQuery or getBudgetsQuery
+func scanBudget(row interface{ Scan(dest ...any) error }) (Budget, error) {
+	var budget Budget
+	var monthlyLimit string
+	if err := row.Scan(&budget.UserID, &monthlyLimit, &budget.UpdatedAt); err != nil {
+		return budget, err
+	}
+	budget.MonthlyLimit, _ = new(big.Int).SetString(monthlyLimit, 10)
+	return budget, nil
+}
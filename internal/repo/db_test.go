@@ -0,0 +1,27 @@
+package repo
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+)
+
+// testDatabaseEnv names a migrated Postgres database the database-backed tests and benchmarks run
+// against, e.g. one filled by cmd/seed. They are skipped while it is not set.
+const testDatabaseEnv = "TEST_DATABASE_URL"
+
+// testDB connects to the test database and closes the connection when the test ends
+func testDB(tb testing.TB) *sql.DB {
+	tb.Helper()
+	databaseURL := os.Getenv(testDatabaseEnv)
+	if databaseURL == "" {
+		tb.Skipf("%s is not set", testDatabaseEnv)
+	}
+
+	db, err := InitDB(databaseURL)
+	if err != nil {
+		tb.Fatalf("connecting to %s: %v", testDatabaseEnv, err)
+	}
+	tb.Cleanup(func() { CloseDB(db) })
+	return db
+}
@@ -0,0 +1,201 @@
+package repo
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+	"github.com/lib/pq"
+)
+
+// Delegation grants the delegate access to the grantor's account until it expires or is revoked
+type Delegation struct {
+	DelegationID   string
+	GrantorUserID  string
+	DelegateUserID string
+	Scopes         []string
+	ExpiresAt      time.Time
+	CreatedAt      time.Time
+	RevokedAt      *time.Time
+}
+
+// DelegationAuditEntry records a grant, a revocation or a request made under a delegation
+type DelegationAuditEntry struct {
+	AuditID      int64
+	DelegationID string
+	ActorUserID  string
+	Action       string
+	Method       string
+	Path         string
+	CreatedAt    time.Time
+}
+
+// Delegation audit actions
+const (
+	DelegationGranted = "granted"
+	DelegationRevoked = "revoked"
+	DelegationUsed    = "used"
+)
+
+// All Delegation Queries
+const (
+	delegationColumns     = `delegation_id, grantor_user_id, delegate_user_id, scopes, expires_at, created_at, revoked_at`
+	insertDelegationQuery = `INSERT INTO delegations (grantor_user_id, delegate_user_id, scopes, expires_at) VALUES ($1, $2, $3, $4)
+		RETURNING delegation_id, created_at`
+	getGrantedDelegationsQuery  = `SELECT ` + delegationColumns + ` FROM delegations WHERE grantor_user_id = $1 ORDER BY created_at DESC`
+	getReceivedDelegationsQuery = `SELECT ` + delegationColumns + ` FROM delegations WHERE delegate_user_id = $1 ORDER BY created_at DESC`
+	// Checked on every delegated request, so a revocation or expiry takes effect immediately
+	getActiveDelegationQuery = `SELECT ` + delegationColumns + ` FROM delegations
+		WHERE grantor_user_id = $1 AND delegate_user_id = $2 AND revoked_at IS NULL AND expires_at > NOW()
+		ORDER BY expires_at DESC LIMIT 1`
+	// Either party can end a delegation
+	revokeDelegationQuery = `UPDATE delegations SET revoked_at = NOW()
+		WHERE delegation_id = $1 AND (grantor_user_id = $2 OR delegate_user_id = $2) AND revoked_at IS NULL`
+	insertDelegationAuditQuery = `INSERT INTO delegation_audit_log (delegation_id, actor_user_id, action, method, path)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''))`
+	getDelegationAuditLogQuery = `SELECT a.audit_id, a.delegation_id, a.actor_user_id, a.action, COALESCE(a.method, ''), COALESCE(a.path, ''), a.created_at
+		FROM delegation_audit_log a INNER JOIN delegations d ON a.delegation_id = d.delegation_id
+		WHERE d.grantor_user_id = $1 OR d.delegate_user_id = $1 ORDER BY a.created_at DESC, a.audit_id DESC LIMIT $2`
+)
+
+type delegationRepo struct {
+	DB *sql.DB
+}
+
+type DelegationStorer interface {
+	CreateDelegation(delegation Delegation) (Delegation, error)
+	GetGrantedDelegations(userID string) ([]Delegation, error)
+	GetReceivedDelegations(userID string) ([]Delegation, error)
+	GetActiveDelegation(grantorUserID, delegateUserID string) (Delegation, error)
+	RevokeDelegation(delegationID, userID string) error
+	RecordDelegationUse(delegation Delegation, method, path string) error
+	GetDelegationAuditLog(userID string, limit int) ([]DelegationAuditEntry, error)
+}
+
+// Constructor function
+func NewDelegationRepo(db *sql.DB) DelegationStorer {
+	return &delegationRepo{DB: db}
+}
+
+// Stores a delegation and its grant in the audit log in one transaction
+func (repoDep *delegationRepo) CreateDelegation(delegation Delegation) (Delegation, error) {
+	tx, err := repoDep.DB.Begin()
+	if err != nil {
+		return delegation, fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(insertDelegationQuery, delegation.GrantorUserID, delegation.DelegateUserID, pq.Array(delegation.Scopes), delegation.ExpiresAt).
+		Scan(&delegation.DelegationID, &delegation.CreatedAt)
+	if err != nil {
+		log.Printf("Error inserting delegation: %v", err)
+		return delegation, fmt.Errorf("error creating delegation: %v", err)
+	}
+	if _, err := tx.Exec(insertDelegationAuditQuery, delegation.DelegationID, delegation.GrantorUserID, DelegationGranted, "", ""); err != nil {
+		log.Printf("Error auditing delegation %s: %v", delegation.DelegationID, err)
+		return delegation, fmt.Errorf("error recording delegation grant: %v", err)
+	}
+
+	return delegation, tx.Commit()
+}
+
+// Returns the delegations the user granted, newest first
+func (repoDep *delegationRepo) GetGrantedDelegations(userID string) ([]Delegation, error) {
+	return repoDep.queryDelegations(getGrantedDelegationsQuery, userID)
+}
+
+// Returns the delegations granted to the user, newest first
+func (repoDep *delegationRepo) GetReceivedDelegations(userID string) ([]Delegation, error) {
+	return repoDep.queryDelegations(getReceivedDelegationsQuery, userID)
+}
+
+// Returns the unexpired, unrevoked delegation from the grantor to the delegate
+func (repoDep *delegationRepo) GetActiveDelegation(grantorUserID, delegateUserID string) (Delegation, error) {
+	delegation, err := scanDelegation(repoDep.DB.QueryRow(getActiveDelegationQuery, grantorUserID, delegateUserID))
+	if err != nil {
+		return delegation, utils.FromDBError("delegation", err)
+	}
+	return delegation, nil
+}
+
+// Revokes a delegation the user granted or received and records who revoked it
+func (repoDep *delegationRepo) RevokeDelegation(delegationID, userID string) error {
+	tx, err := repoDep.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(revokeDelegationQuery, delegationID, userID)
+	if err != nil {
+		log.Printf("Error revoking delegation: %v", err)
+		return fmt.Errorf("error revoking delegation: %v", err)
+	}
+	if rowsAffected, err := result.RowsAffected(); err == nil && rowsAffected == 0 {
+		return utils.NotFound("delegation not found", nil)
+	}
+	if _, err := tx.Exec(insertDelegationAuditQuery, delegationID, userID, DelegationRevoked, "", ""); err != nil {
+		log.Printf("Error auditing delegation %s: %v", delegationID, err)
+		return fmt.Errorf("error recording delegation revocation: %v", err)
+	}
+
+	return tx.Commit()
+}
+
+// Records a request the delegate made under the delegation
+func (repoDep *delegationRepo) RecordDelegationUse(delegation Delegation, method, path string) error {
+	if _, err := repoDep.DB.Exec(insertDelegationAuditQuery, delegation.DelegationID, delegation.DelegateUserID, DelegationUsed, method, path); err != nil {
+		log.Printf("Error auditing delegation %s: %v", delegation.DelegationID, err)
+		return fmt.Errorf("error recording delegated request: %v", err)
+	}
+	return nil
+}
+
+// Returns the latest audit entries of delegations the user granted or received
+func (repoDep *delegationRepo) GetDelegationAuditLog(userID string, limit int) ([]DelegationAuditEntry, error) {
+	rows, err := repoDep.DB.Query(getDelegationAuditLogQuery, userID, limit)
+	if err != nil {
+		log.Printf("Error executing query: %v", err)
+		return nil, fmt.Errorf("error fetching delegation audit log: %v", err)
+	}
+	defer rows.Close()
+
+	entries := []DelegationAuditEntry{}
+	for rows.Next() {
+		var entry DelegationAuditEntry
+		if err := rows.Scan(&entry.AuditID, &entry.DelegationID, &entry.ActorUserID, &entry.Action, &entry.Method, &entry.Path, &entry.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error reading delegation audit log: %v", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+func (repoDep *delegationRepo) queryDelegations(query, userID string) ([]Delegation, error) {
+	rows, err := repoDep.DB.Query(query, userID)
+	if err != nil {
+		log.Printf("Error executing query: %v", err)
+		return nil, fmt.Errorf("error fetching delegations: %v", err)
+	}
+	defer rows.Close()
+
+	delegations := []Delegation{}
+	for rows.Next() {
+		delegation, err := scanDelegation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error reading delegations: %v", err)
+		}
+		delegations = append(delegations, delegation)
+	}
+	return delegations, rows.Err()
+}
+
+// Scans one row of a delegation query
+func scanDelegation(row interface{ Scan(dest ...any) error }) (Delegation, error) {
+	var delegation Delegation
+	err := row.Scan(&delegation.DelegationID, &delegation.GrantorUserID, &delegation.DelegateUserID, pq.Array(&delegation.Scopes),
+		&delegation.ExpiresAt, &delegation.CreatedAt, &delegation.RevokedAt)
+	return delegation, err
+}
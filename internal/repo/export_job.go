@@ -0,0 +1,171 @@
+package repo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+// ExportJob is a bulk export requested by an admin. From and To bound the rows by creation time when
+// set, the gzipped file is stored with the job once it is completed.
+type ExportJob struct {
+	JobID       string
+	CreatedBy   string
+	Dataset     string
+	Format      string
+	From        *time.Time
+	To          *time.Time
+	Status      string
+	RowCount    int
+	Size        int64
+	Error       string
+	CreatedAt   time.Time
+	StartedAt   *time.Time
+	CompletedAt *time.Time
+}
+
+// All Export Job Queries
+const (
+	exportJobColumns = `job_id, created_by, dataset, format, from_time, to_time, status, row_count, COALESCE(LENGTH(content), 0),
+		error, created_at, started_at, completed_at`
+	insertExportJobQuery = `INSERT INTO export_jobs (created_by, dataset, format, from_time, to_time) VALUES ($1, $2, $3, $4, $5)
+		RETURNING ` + exportJobColumns
+	selectExportJobsQuery = `SELECT ` + exportJobColumns + ` FROM export_jobs`
+	// Takes the oldest queued job, or a running one whose worker stopped before finishing it
+	claimExportJobQuery = `UPDATE export_jobs SET status = 'running', started_at = NOW()
+		WHERE job_id = (SELECT job_id FROM export_jobs
+			WHERE status = 'queued' OR (status = 'running' AND started_at < NOW() - $1 * INTERVAL '1 second')
+			ORDER BY created_at LIMIT 1 FOR UPDATE SKIP LOCKED)
+		RETURNING ` + exportJobColumns
+	completeExportJobQuery = `UPDATE export_jobs SET status = 'completed', row_count = $2, content = $3, completed_at = NOW()
+		WHERE job_id = $1 AND status = 'running'`
+	failExportJobQuery       = `UPDATE export_jobs SET status = 'failed', error = $2, completed_at = NOW() WHERE job_id = $1 AND status = 'running'`
+	getExportContentQuery    = `SELECT content FROM export_jobs WHERE job_id = $1 AND status = 'completed'`
+	deleteExportsBeforeQuery = `DELETE FROM export_jobs WHERE status IN ('completed', 'failed') AND completed_at < $1`
+)
+
+type exportJobRepo struct {
+	DB *sql.DB
+}
+
+type ExportJobStorer interface {
+	CreateExportJob(job ExportJob) (ExportJob, error)
+	GetExportJob(jobID string) (ExportJob, error)
+	GetExportJobs(limit int) ([]ExportJob, error)
+	ClaimExportJob(staleAfter time.Duration) (ExportJob, bool, error)
+	CompleteExportJob(jobID string, rowCount int, content []byte) error
+	FailExportJob(jobID, errMsg string) error
+	GetExportContent(jobID string) ([]byte, error)
+	DeleteExportsBefore(cutoff time.Time) (int64, error)
+}
+
+// Constructor function
+func NewExportJobRepo(db *sql.DB) ExportJobStorer {
+	return &exportJobRepo{DB: db}
+}
+
+// Queues an export for the worker
+func (repoDep *exportJobRepo) CreateExportJob(job ExportJob) (ExportJob, error) {
+	created, err := scanExportJob(repoDep.DB.QueryRow(insertExportJobQuery, job.CreatedBy, job.Dataset, job.Format, job.From, job.To))
+	if err != nil {
+		log.Printf("Error creating export job: %v", err)
+		return job, fmt.Errorf("error creating export job: %v", err)
+	}
+	return created, nil
+}
+
+// Returns an export job without its content
+func (repoDep *exportJobRepo) GetExportJob(jobID string) (ExportJob, error) {
+	query, args := newSelectQuery(selectExportJobsQuery).where("job_id = ?", jobID).build()
+
+	job, err := scanExportJob(repoDep.DB.QueryRow(query, args...))
+	if err != nil {
+		return job, utils.FromDBError("export", err)
+	}
+	return job, nil
+}
+
+// Returns the latest export jobs, newest first
+func (repoDep *exportJobRepo) GetExportJobs(limit int) ([]ExportJob, error) {
+	query, args := newSelectQuery(selectExportJobsQuery).order("created_at DESC").page(limit, 0).build()
+
+	rows, err := repoDep.DB.Query(query, args...)
+	if err != nil {
+		log.Printf("Error executing query: %v", err)
+		return nil, fmt.Errorf("error fetching export jobs: %v", err)
+	}
+	defer rows.Close()
+
+	jobs := []ExportJob{}
+	for rows.Next() {
+		job, err := scanExportJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error reading export jobs: %v", err)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// Marks the next job to work on as running, false when there is none. Jobs left running for longer
+// than staleAfter are taken over since their worker is assumed to have stopped.
+func (repoDep *exportJobRepo) ClaimExportJob(staleAfter time.Duration) (ExportJob, bool, error) {
+	job, err := scanExportJob(repoDep.DB.QueryRow(claimExportJobQuery, int(staleAfter.Seconds())))
+	if errors.Is(err, sql.ErrNoRows) {
+		return job, false, nil
+	}
+	if err != nil {
+		log.Printf("Error claiming export job: %v", err)
+		return job, false, fmt.Errorf("error claiming export job: %v", err)
+	}
+	return job, true, nil
+}
+
+// Stores the file of a running job and marks it completed
+func (repoDep *exportJobRepo) CompleteExportJob(jobID string, rowCount int, content []byte) error {
+	if _, err := repoDep.DB.Exec(completeExportJobQuery, jobID, rowCount, content); err != nil {
+		log.Printf("Error completing export job %s: %v", jobID, err)
+		return fmt.Errorf("error completing export job: %v", err)
+	}
+	return nil
+}
+
+// Marks a running job failed with the reason
+func (repoDep *exportJobRepo) FailExportJob(jobID, errMsg string) error {
+	if _, err := repoDep.DB.Exec(failExportJobQuery, jobID, errMsg); err != nil {
+		log.Printf("Error failing export job %s: %v", jobID, err)
+		return fmt.Errorf("error failing export job: %v", err)
+	}
+	return nil
+}
+
+// Returns the gzipped file of a completed job
+func (repoDep *exportJobRepo) GetExportContent(jobID string) ([]byte, error) {
+	var content []byte
+	if err := repoDep.DB.QueryRow(getExportContentQuery, jobID).Scan(&content); err != nil {
+		return nil, utils.FromDBError("export", err)
+	}
+	return content, nil
+}
+
+// Removes finished jobs and their files completed before cutoff
+func (repoDep *exportJobRepo) DeleteExportsBefore(cutoff time.Time) (int64, error) {
+	result, err := repoDep.DB.Exec(deleteExportsBeforeQuery, cutoff)
+	if err != nil {
+		log.Printf("Error deleting old export jobs: %v", err)
+		return 0, fmt.Errorf("error deleting old export jobs: %v", err)
+	}
+	return result.RowsAffected()
+}
+
+// Scans one row of the exportJobColumns
+func scanExportJob(row interface{ Scan(dest ...any) error }) (ExportJob, error) {
+	var job ExportJob
+	err := row.Scan(&job.JobID, &job.CreatedBy, &job.Dataset, &job.Format, &job.From, &job.To, &job.Status, &job.RowCount, &job.Size,
+		&job.Error, &job.CreatedAt, &job.StartedAt, &job.CompletedAt)
+	return job, err
+}
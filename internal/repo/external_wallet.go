@@ -0,0 +1,166 @@
+package repo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+// ExternalWallet is an address a user linked in watch-only mode, VerifiedAt is set once the
+// user signed the challenge with it
+type ExternalWallet struct {
+	UserID             string
+	Address            string
+	Label              string
+	Challenge          string
+	ChallengeExpiresAt time.Time
+	VerifiedAt         *time.Time
+	CreatedAt          time.Time
+}
+
+// All External Wallet Queries
+const (
+	externalWalletColumns = `user_id, address, label, challenge, challenge_expires_at, verified_at, created_at`
+	// A new challenge replaces the previous one of an unverified link, verified links stay untouched
+	upsertExternalWalletQuery = `INSERT INTO external_wallets (user_id, address, label, challenge, challenge_expires_at) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, address) DO UPDATE SET label = EXCLUDED.label, challenge = EXCLUDED.challenge, challenge_expires_at = EXCLUDED.challenge_expires_at
+		WHERE external_wallets.verified_at IS NULL
+		RETURNING ` + externalWalletColumns
+	getExternalWalletQuery    = `SELECT ` + externalWalletColumns + ` FROM external_wallets WHERE user_id = $1 AND address = $2`
+	getExternalWalletsQuery   = `SELECT ` + externalWalletColumns + ` FROM external_wallets WHERE user_id = $1 ORDER BY created_at`
+	countExternalWalletsQuery = `SELECT COUNT(*) FROM external_wallets WHERE user_id = $1`
+	// Only the challenge that was signed verifies the link, so a refreshed challenge voids older signatures
+	verifyExternalWalletQuery = `UPDATE external_wallets SET verified_at = NOW()
+		WHERE user_id = $1 AND address = $2 AND challenge = $3 AND verified_at IS NULL AND challenge_expires_at > NOW()`
+	deleteExternalWalletQuery = `DELETE FROM external_wallets WHERE user_id = $1 AND address = $2`
+	getVerifiedOwnersQuery    = `SELECT user_id FROM external_wallets WHERE address = $1 AND verified_at IS NOT NULL`
+)
+
+type externalWalletRepo struct {
+	DB *sql.DB
+}
+
+type ExternalWalletStorer interface {
+	SaveExternalWalletChallenge(wallet ExternalWallet) (ExternalWallet, error)
+	GetExternalWallet(userID, address string) (ExternalWallet, error)
+	GetExternalWallets(userID string) ([]ExternalWallet, error)
+	CountExternalWallets(userID string) (int, error)
+	VerifyExternalWallet(userID, address, challenge string) (bool, error)
+	DeleteExternalWallet(userID, address string) error
+	GetVerifiedWalletOwners(address string) ([]string, error)
+}
+
+// Constructor function
+func NewExternalWalletRepo(db *sql.DB) ExternalWalletStorer {
+	return &externalWalletRepo{DB: db}
+}
+
+// Stores an unverified link with its challenge, fails with a conflict when the address is already verified
+func (repoDep *externalWalletRepo) SaveExternalWalletChallenge(wallet ExternalWallet) (ExternalWallet, error) {
+	saved, err := scanExternalWallet(repoDep.DB.QueryRow(upsertExternalWalletQuery, wallet.UserID, wallet.Address, wallet.Label, wallet.Challenge, wallet.ChallengeExpiresAt))
+	if errors.Is(err, sql.ErrNoRows) {
+		return saved, utils.Conflict("address is already linked")
+	}
+	if err != nil {
+		log.Printf("Error saving external wallet of %s: %v", wallet.UserID, err)
+		return saved, fmt.Errorf("error saving external wallet: %v", err)
+	}
+	return saved, nil
+}
+
+// Returns one linked address of the user, verified or not
+func (repoDep *externalWalletRepo) GetExternalWallet(userID, address string) (ExternalWallet, error) {
+	wallet, err := scanExternalWallet(repoDep.DB.QueryRow(getExternalWalletQuery, userID, address))
+	if err != nil {
+		return wallet, utils.FromDBError("external wallet", err)
+	}
+	return wallet, nil
+}
+
+// Returns every address the user linked, oldest first
+func (repoDep *externalWalletRepo) GetExternalWallets(userID string) ([]ExternalWallet, error) {
+	rows, err := repoDep.DB.Query(getExternalWalletsQuery, userID)
+	if err != nil {
+		log.Printf("Error executing query: %v", err)
+		return nil, fmt.Errorf("error fetching external wallets: %v", err)
+	}
+	defer rows.Close()
+
+	wallets := []ExternalWallet{}
+	for rows.Next() {
+		wallet, err := scanExternalWallet(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error reading external wallets: %v", err)
+		}
+		wallets = append(wallets, wallet)
+	}
+	return wallets, rows.Err()
+}
+
+// Returns how many addresses the user linked, verified or not
+func (repoDep *externalWalletRepo) CountExternalWallets(userID string) (int, error) {
+	var count int
+	if err := repoDep.DB.QueryRow(countExternalWalletsQuery, userID).Scan(&count); err != nil {
+		log.Printf("Error counting external wallets of %s: %v", userID, err)
+		return 0, fmt.Errorf("error counting external wallets: %v", err)
+	}
+	return count, nil
+}
+
+// Marks the link verified if challenge is still its current, unexpired challenge
+func (repoDep *externalWalletRepo) VerifyExternalWallet(userID, address, challenge string) (bool, error) {
+	result, err := repoDep.DB.Exec(verifyExternalWalletQuery, userID, address, challenge)
+	if err != nil {
+		log.Printf("Error verifying external wallet of %s: %v", userID, err)
+		return false, fmt.Errorf("error verifying external wallet: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error checking affected rows: %v", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+// Unlinks an address
+func (repoDep *externalWalletRepo) DeleteExternalWallet(userID, address string) error {
+	result, err := repoDep.DB.Exec(deleteExternalWalletQuery, userID, address)
+	if err != nil {
+		log.Printf("Error deleting external wallet of %s: %v", userID, err)
+		return fmt.Errorf("error deleting external wallet: %v", err)
+	}
+	if rowsAffected, err := result.RowsAffected(); err == nil && rowsAffected == 0 {
+		return utils.NotFound("external wallet not found", nil)
+	}
+	return nil
+}
+
+// Returns the users that verified a link of the address, an address can be linked by several users
+func (repoDep *externalWalletRepo) GetVerifiedWalletOwners(address string) ([]string, error) {
+	rows, err := repoDep.DB.Query(getVerifiedOwnersQuery, address)
+	if err != nil {
+		log.Printf("Error executing query: %v", err)
+		return nil, fmt.Errorf("error fetching wallet owners: %v", err)
+	}
+	defer rows.Close()
+
+	userIDs := []string{}
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("error reading wallet owners: %v", err)
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
+
+// Scans one row of the externalWalletColumns
+func scanExternalWallet(row interface{ Scan(dest ...any) error }) (ExternalWallet, error) {
+	var wallet ExternalWallet
+	err := row.Scan(&wallet.UserID, &wallet.Address, &wallet.Label, &wallet.Challenge, &wallet.ChallengeExpiresAt, &wallet.VerifiedAt, &wallet.CreatedAt)
+	return wallet, err
+}
@@ -0,0 +1,71 @@
+package repo
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// FeatureFlag Regular struct
+type FeatureFlag struct {
+	Key               string
+	Enabled           bool
+	RolloutPercentage int
+	AllowedUserIDs    []string
+	UpdatedAt         time.Time
+}
+
+// All Feature Flag Queries
+const (
+	getFeatureFlagsQuery   = `SELECT flag_key, enabled, rollout_percentage, allowed_user_ids, updated_at FROM feature_flags`
+	upsertFeatureFlagQuery = `INSERT INTO feature_flags (flag_key, enabled, rollout_percentage, allowed_user_ids, updated_by, updated_at) VALUES ($1, $2, $3, $4, $5, NOW())
+		ON CONFLICT (flag_key) DO UPDATE SET enabled = EXCLUDED.enabled, rollout_percentage = EXCLUDED.rollout_percentage,
+		allowed_user_ids = EXCLUDED.allowed_user_ids, updated_by = EXCLUDED.updated_by, updated_at = EXCLUDED.updated_at`
+)
+
+type featureRepo struct {
+	DB *sql.DB
+}
+
+type FeatureStorer interface {
+	GetFeatureFlags() ([]FeatureFlag, error)
+	UpsertFeatureFlag(flag FeatureFlag, updatedBy string) error
+}
+
+// Constructor function
+func NewFeatureRepo(db *sql.DB) FeatureStorer {
+	return &featureRepo{DB: db}
+}
+
+// Returns every feature flag stored in the database
+func (repoDep *featureRepo) GetFeatureFlags() ([]FeatureFlag, error) {
+	rows, err := repoDep.DB.Query(getFeatureFlagsQuery)
+	if err != nil {
+		log.Printf("Error executing query: %v", err)
+		return nil, fmt.Errorf("error fetching feature flags: %v", err)
+	}
+	defer rows.Close()
+
+	var flags []FeatureFlag
+	for rows.Next() {
+		var flag FeatureFlag
+		if err := rows.Scan(&flag.Key, &flag.Enabled, &flag.RolloutPercentage, pq.Array(&flag.AllowedUserIDs), &flag.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("error reading feature flags: %v", err)
+		}
+		flags = append(flags, flag)
+	}
+	return flags, rows.Err()
+}
+
+// Creates or replaces a feature flag
+func (repoDep *featureRepo) UpsertFeatureFlag(flag FeatureFlag, updatedBy string) error {
+	_, err := repoDep.DB.Exec(upsertFeatureFlagQuery, flag.Key, flag.Enabled, flag.RolloutPercentage, pq.Array(flag.AllowedUserIDs), updatedBy)
+	if err != nil {
+		log.Printf("Error updating feature flag %s: %v", flag.Key, err)
+		return fmt.Errorf("error updating feature flag: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package repo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+// FiatDeposit is a deposit confirmed by the fiat on-ramp provider. CryptoAmount is the ETH in wei the
+// provider bought with it, "0" for a deposit that only holds fiat.
+type FiatDeposit struct {
+	DepositID         string
+	ProviderDepositID string
+	UserID            string
+	WalletID          string
+	FiatAmount        string
+	FiatCurrency      string
+	CryptoAmount      string
+	Status            string
+	TxHash            string
+	Error             string
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
+// All Fiat Deposit Queries
+const (
+	fiatDepositColumns = `deposit_id, provider_deposit_id, user_id, wallet_id, fiat_amount::TEXT, fiat_currency, crypto_amount::TEXT, status,
+		COALESCE(tx_hash, ''), error, created_at, updated_at`
+	// Returns no row when the provider already delivered the deposit
+	insertFiatDepositQuery = `INSERT INTO fiat_deposits (provider_deposit_id, user_id, wallet_id, fiat_amount, fiat_currency, crypto_amount, status)
+		VALUES ($1, $2, $3, $4::NUMERIC, $5, $6::NUMERIC, $7)
+		ON CONFLICT (provider_deposit_id) DO NOTHING
+		RETURNING ` + fiatDepositColumns
+	selectFiatDepositsQuery = `SELECT ` + fiatDepositColumns + ` FROM fiat_deposits`
+	updateFiatDepositQuery  = `UPDATE fiat_deposits SET status = $3, tx_hash = NULLIF($4, ''), error = $5, updated_at = NOW() WHERE deposit_id = $1 AND status = $2`
+)
+
+type fiatDepositRepo struct {
+	DB *sql.DB
+}
+
+type FiatDepositStorer interface {
+	RecordFiatDeposit(deposit FiatDeposit) (FiatDeposit, bool, error)
+	GetFiatDeposit(depositID string) (FiatDeposit, error)
+	GetFiatDeposits(status string, limit int) ([]FiatDeposit, error)
+	UpdateFiatDepositStatus(depositID, fromStatus, toStatus, txHash, errMsg string) (bool, error)
+}
+
+// Constructor function
+func NewFiatDepositRepo(db *sql.DB) FiatDepositStorer {
+	return &fiatDepositRepo{DB: db}
+}
+
+// Records a deposit and returns it, false along with the deposit recorded before when the provider
+// already delivered it
+func (repoDep *fiatDepositRepo) RecordFiatDeposit(deposit FiatDeposit) (FiatDeposit, bool, error) {
+	recorded, err := scanFiatDeposit(repoDep.DB.QueryRow(insertFiatDepositQuery, deposit.ProviderDepositID, deposit.UserID, deposit.WalletID,
+		deposit.FiatAmount, deposit.FiatCurrency, deposit.CryptoAmount, deposit.Status))
+	if errors.Is(err, sql.ErrNoRows) {
+		query, args := newSelectQuery(selectFiatDepositsQuery).where("provider_deposit_id = ?", deposit.ProviderDepositID).build()
+		existing, err := scanFiatDeposit(repoDep.DB.QueryRow(query, args...))
+		if err != nil {
+			return deposit, false, utils.FromDBError("fiat deposit", err)
+		}
+		return existing, false, nil
+	}
+	if err != nil {
+		log.Printf("Error recording fiat deposit %s: %v", deposit.ProviderDepositID, err)
+		return deposit, false, fmt.Errorf("error recording fiat deposit: %v", err)
+	}
+	return recorded, true, nil
+}
+
+// Returns a fiat deposit
+func (repoDep *fiatDepositRepo) GetFiatDeposit(depositID string) (FiatDeposit, error) {
+	query, args := newSelectQuery(selectFiatDepositsQuery).where("deposit_id = ?", depositID).build()
+
+	deposit, err := scanFiatDeposit(repoDep.DB.QueryRow(query, args...))
+	if err != nil {
+		return deposit, utils.FromDBError("fiat deposit", err)
+	}
+	return deposit, nil
+}
+
+// Returns the latest fiat deposits with the given status, or of any status when it is empty
+func (repoDep *fiatDepositRepo) GetFiatDeposits(status string, limit int) ([]FiatDeposit, error) {
+	q := newSelectQuery(selectFiatDepositsQuery)
+	if status != "" {
+		q.where("status = ?", status)
+	}
+	query, args := q.order("created_at DESC").page(limit, 0).build()
+
+	rows, err := repoDep.DB.Query(query, args...)
+	if err != nil {
+		log.Printf("Error executing query: %v", err)
+		return nil, fmt.Errorf("error fetching fiat deposits: %v", err)
+	}
+	defer rows.Close()
+
+	deposits := []FiatDeposit{}
+	for rows.Next() {
+		deposit, err := scanFiatDeposit(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error reading fiat deposits: %v", err)
+		}
+		deposits = append(deposits, deposit)
+	}
+	return deposits, rows.Err()
+}
+
+// Moves a deposit from fromStatus to toStatus, false if it was no longer in fromStatus
+func (repoDep *fiatDepositRepo) UpdateFiatDepositStatus(depositID, fromStatus, toStatus, txHash, errMsg string) (bool, error) {
+	result, err := repoDep.DB.Exec(updateFiatDepositQuery, depositID, fromStatus, toStatus, txHash, errMsg)
+	if err != nil {
+		log.Printf("Error updating fiat deposit %s: %v", depositID, err)
+		return false, fmt.Errorf("error updating fiat deposit: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	return rowsAffected > 0, err
+}
+
+// Scans one row of the fiatDepositColumns
+func scanFiatDeposit(row interface{ Scan(dest ...any) error }) (FiatDeposit, error) {
+	var deposit FiatDeposit
+	err := row.Scan(&deposit.DepositID, &deposit.ProviderDepositID, &deposit.UserID, &deposit.WalletID, &deposit.FiatAmount, &deposit.FiatCurrency,
+		&deposit.CryptoAmount, &deposit.Status, &deposit.TxHash, &deposit.Error, &deposit.CreatedAt, &deposit.UpdatedAt)
+	return deposit, err
+}
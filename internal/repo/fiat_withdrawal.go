@@ -0,0 +1,156 @@
+package repo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+// FiatWithdrawal is a request to pay out Amount in wei to the user's bank account through the fiat
+// off-ramp. BankAccountToken is the payout provider's token for the account, never its number.
+type FiatWithdrawal struct {
+	WithdrawalID     string
+	UserID           string
+	WalletID         string
+	Amount           string
+	FiatCurrency     string
+	BankAccountToken string
+	BankAccountLast4 string
+	Status           string
+	DecidedBy        string
+	TxHash           string
+	PayoutReference  string
+	Reason           string
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+// All Fiat Withdrawal Queries
+const (
+	fiatWithdrawalColumns = `withdrawal_id, user_id, wallet_id, amount::TEXT, fiat_currency, bank_account_token, bank_account_last4, status,
+		COALESCE(decided_by::TEXT, ''), COALESCE(tx_hash, ''), COALESCE(payout_reference, ''), reason, created_at, updated_at`
+	insertFiatWithdrawalQuery = `INSERT INTO fiat_withdrawals (user_id, wallet_id, amount, fiat_currency, bank_account_token, bank_account_last4)
+		VALUES ($1, $2, $3::NUMERIC, $4, $5, $6)
+		RETURNING ` + fiatWithdrawalColumns
+	selectFiatWithdrawalsQuery = `SELECT ` + fiatWithdrawalColumns + ` FROM fiat_withdrawals`
+	// Empty values leave the column as it is
+	updateFiatWithdrawalQuery = `UPDATE fiat_withdrawals SET status = $3,
+			decided_by = COALESCE(NULLIF($4, '')::UUID, decided_by),
+			tx_hash = COALESCE(NULLIF($5, ''), tx_hash),
+			payout_reference = COALESCE(NULLIF($6, ''), payout_reference),
+			reason = CASE WHEN $7 = '' THEN reason ELSE $7 END,
+			updated_at = NOW()
+		WHERE withdrawal_id = $1 AND status = $2
+		RETURNING ` + fiatWithdrawalColumns
+	heldWithdrawalTotalQuery = `SELECT COALESCE(SUM(amount), 0)::TEXT FROM fiat_withdrawals
+		WHERE LOWER(wallet_id) = LOWER($1) AND status IN ('pending', 'sending')`
+)
+
+type fiatWithdrawalRepo struct {
+	DB *sql.DB
+}
+
+type FiatWithdrawalStorer interface {
+	CreateFiatWithdrawal(withdrawal FiatWithdrawal) (FiatWithdrawal, error)
+	GetFiatWithdrawal(withdrawalID string) (FiatWithdrawal, error)
+	GetFiatWithdrawals(userID, status string, limit int) ([]FiatWithdrawal, error)
+	UpdateFiatWithdrawalStatus(withdrawalID, fromStatus string, update FiatWithdrawal) (FiatWithdrawal, bool, error)
+	GetHeldWithdrawalTotal(walletID string) (*big.Int, error)
+}
+
+// Constructor function
+func NewFiatWithdrawalRepo(db *sql.DB) FiatWithdrawalStorer {
+	return &fiatWithdrawalRepo{DB: db}
+}
+
+// Records a pending withdrawal and returns it with the generated ID and timestamps
+func (repoDep *fiatWithdrawalRepo) CreateFiatWithdrawal(withdrawal FiatWithdrawal) (FiatWithdrawal, error) {
+	created, err := scanFiatWithdrawal(repoDep.DB.QueryRow(insertFiatWithdrawalQuery, withdrawal.UserID, withdrawal.WalletID, withdrawal.Amount,
+		withdrawal.FiatCurrency, withdrawal.BankAccountToken, withdrawal.BankAccountLast4))
+	if err != nil {
+		log.Printf("Error recording fiat withdrawal of %s: %v", withdrawal.UserID, err)
+		return withdrawal, fmt.Errorf("error recording fiat withdrawal: %v", err)
+	}
+	return created, nil
+}
+
+// Returns a fiat withdrawal
+func (repoDep *fiatWithdrawalRepo) GetFiatWithdrawal(withdrawalID string) (FiatWithdrawal, error) {
+	query, args := newSelectQuery(selectFiatWithdrawalsQuery).where("withdrawal_id = ?", withdrawalID).build()
+
+	withdrawal, err := scanFiatWithdrawal(repoDep.DB.QueryRow(query, args...))
+	if err != nil {
+		return withdrawal, utils.FromDBError("fiat withdrawal", err)
+	}
+	return withdrawal, nil
+}
+
+// Returns the latest fiat withdrawals, of the user and with the status when they are not empty
+func (repoDep *fiatWithdrawalRepo) GetFiatWithdrawals(userID, status string, limit int) ([]FiatWithdrawal, error) {
+	q := newSelectQuery(selectFiatWithdrawalsQuery)
+	if userID != "" {
+		q.where("user_id = ?", userID)
+	}
+	if status != "" {
+		q.where("status = ?", status)
+	}
+	query, args := q.order("created_at DESC").page(limit, 0).build()
+
+	rows, err := repoDep.DB.Query(query, args...)
+	if err != nil {
+		log.Printf("Error executing query: %v", err)
+		return nil, fmt.Errorf("error fetching fiat withdrawals: %v", err)
+	}
+	defer rows.Close()
+
+	withdrawals := []FiatWithdrawal{}
+	for rows.Next() {
+		withdrawal, err := scanFiatWithdrawal(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error reading fiat withdrawals: %v", err)
+		}
+		withdrawals = append(withdrawals, withdrawal)
+	}
+	return withdrawals, rows.Err()
+}
+
+// Moves a withdrawal from fromStatus to update.Status, setting the DecidedBy, TxHash, PayoutReference
+// and Reason of update that are not empty. Returns the updated withdrawal, false if it was no longer
+// in fromStatus.
+func (repoDep *fiatWithdrawalRepo) UpdateFiatWithdrawalStatus(withdrawalID, fromStatus string, update FiatWithdrawal) (FiatWithdrawal, bool, error) {
+	updated, err := scanFiatWithdrawal(repoDep.DB.QueryRow(updateFiatWithdrawalQuery, withdrawalID, fromStatus, update.Status,
+		update.DecidedBy, update.TxHash, update.PayoutReference, update.Reason))
+	if errors.Is(err, sql.ErrNoRows) {
+		return updated, false, nil
+	}
+	if err != nil {
+		log.Printf("Error updating fiat withdrawal %s: %v", withdrawalID, err)
+		return updated, false, fmt.Errorf("error updating fiat withdrawal: %v", err)
+	}
+	return updated, true, nil
+}
+
+// Returns the wei held in the wallet by withdrawals that are pending or being sent
+func (repoDep *fiatWithdrawalRepo) GetHeldWithdrawalTotal(walletID string) (*big.Int, error) {
+	var total string
+	if err := repoDep.DB.QueryRow(heldWithdrawalTotalQuery, walletID).Scan(&total); err != nil {
+		log.Printf("Error executing query: %v", err)
+		return nil, fmt.Errorf("error summing held withdrawals: %v", err)
+	}
+	held, _ := new(big.Int).SetString(total, 10)
+	return held, nil
+}
+
+// Scans one row of the fiatWithdrawalColumns
+func scanFiatWithdrawal(row interface{ Scan(dest ...any) error }) (FiatWithdrawal, error) {
+	var withdrawal FiatWithdrawal
+	err := row.Scan(&withdrawal.WithdrawalID, &withdrawal.UserID, &withdrawal.WalletID, &withdrawal.Amount, &withdrawal.FiatCurrency,
+		&withdrawal.BankAccountToken, &withdrawal.BankAccountLast4, &withdrawal.Status, &withdrawal.DecidedBy, &withdrawal.TxHash,
+		&withdrawal.PayoutReference, &withdrawal.Reason, &withdrawal.CreatedAt, &withdrawal.UpdatedAt)
+	return withdrawal, err
+}
@@ -0,0 +1,98 @@
+package repo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+// Holiday is a non-business day of a calendar region
+type Holiday struct {
+	Region    string
+	Date      time.Time
+	Name      string
+	CreatedBy string
+	CreatedAt time.Time
+}
+
+// All Holiday Queries
+const (
+	holidayColumns     = `region, holiday_date, name, COALESCE(created_by::TEXT, ''), created_at`
+	getHolidaysQuery   = `SELECT ` + holidayColumns + ` FROM business_holidays WHERE region = $1 ORDER BY holiday_date`
+	insertHolidayQuery = `INSERT INTO business_holidays (region, holiday_date, name, created_by) VALUES ($1, $2, $3, NULLIF($4, '')::UUID)
+		ON CONFLICT (region, holiday_date) DO NOTHING
+		RETURNING ` + holidayColumns
+	deleteHolidayQuery = `DELETE FROM business_holidays WHERE region = $1 AND holiday_date = $2`
+)
+
+type holidayRepo struct {
+	DB *sql.DB
+}
+
+type HolidayStorer interface {
+	GetHolidays(region string) ([]Holiday, error)
+	AddHoliday(holiday Holiday) (Holiday, error)
+	DeleteHoliday(region string, date time.Time) error
+}
+
+// Constructor function
+func NewHolidayRepo(db *sql.DB) HolidayStorer {
+	return &holidayRepo{DB: db}
+}
+
+// Returns every holiday of a region, earliest first
+func (repoDep *holidayRepo) GetHolidays(region string) ([]Holiday, error) {
+	rows, err := repoDep.DB.Query(getHolidaysQuery, region)
+	if err != nil {
+		log.Printf("Error executing query: %v", err)
+		return nil, fmt.Errorf("error fetching holidays: %v", err)
+	}
+	defer rows.Close()
+
+	holidays := []Holiday{}
+	for rows.Next() {
+		holiday, err := scanHoliday(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error reading holidays: %v", err)
+		}
+		holidays = append(holidays, holiday)
+	}
+	return holidays, rows.Err()
+}
+
+// Adds a holiday, fails with a conflict when the region already has one on that date
+func (repoDep *holidayRepo) AddHoliday(holiday Holiday) (Holiday, error) {
+	added, err := scanHoliday(repoDep.DB.QueryRow(insertHolidayQuery, holiday.Region, holiday.Date, holiday.Name, holiday.CreatedBy))
+	if errors.Is(err, sql.ErrNoRows) {
+		return holiday, utils.Conflict("a holiday already exists on that date")
+	}
+	if err != nil {
+		log.Printf("Error adding holiday %s of %s: %v", holiday.Date.Format(time.DateOnly), holiday.Region, err)
+		return holiday, fmt.Errorf("error adding holiday: %v", err)
+	}
+	return added, nil
+}
+
+// Removes a holiday of a region
+func (repoDep *holidayRepo) DeleteHoliday(region string, date time.Time) error {
+	result, err := repoDep.DB.Exec(deleteHolidayQuery, region, date)
+	if err != nil {
+		log.Printf("Error deleting holiday %s of %s: %v", date.Format(time.DateOnly), region, err)
+		return fmt.Errorf("error deleting holiday: %v", err)
+	}
+	if rowsAffected, err := result.RowsAffected(); err == nil && rowsAffected == 0 {
+		return utils.NotFound("holiday not found", nil)
+	}
+	return nil
+}
+
+// Scans one row of the holidayColumns
+func scanHoliday(row interface{ Scan(dest ...any) error }) (Holiday, error) {
+	var holiday Holiday
+	err := row.Scan(&holiday.Region, &holiday.Date, &holiday.Name, &holiday.CreatedBy, &holiday.CreatedAt)
+	return holiday, err
+}
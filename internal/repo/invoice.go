@@ -0,0 +1,291 @@
+package repo
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+	"github.com/lib/pq"
+)
+
+// Invoice asks the recipient, a user or an email address, to pay TotalAmount wei to PayeeWalletID
+// by DueDate. TxHash and PaidAt are set once a matching transfer arrived.
+type Invoice struct {
+	InvoiceID       string
+	IssuerUserID    string
+	RecipientUserID string
+	RecipientEmail  string
+	PayeeWalletID   string
+	Title           string
+	Notes           string
+	TotalAmount     string
+	DueDate         time.Time
+	Status          string
+	TxHash          string
+	PaidAt          *time.Time
+	ReminderCount   int
+	LastReminderAt  *time.Time
+	CreatedAt       time.Time
+	LineItems       []InvoiceLineItem
+}
+
+// InvoiceLineItem is one line of an invoice, UnitAmount in wei
+type InvoiceLineItem struct {
+	Description string
+	Quantity    int
+	UnitAmount  string
+}
+
+// InvoiceMatch is a confirmed transfer that pays an open invoice
+type InvoiceMatch struct {
+	InvoiceID string
+	TxHash    string
+	PaidAt    time.Time
+}
+
+// All Invoice Queries
+const (
+	invoiceColumns = `invoice_id, issuer_user_id, COALESCE(recipient_user_id::TEXT, ''), recipient_email, payee_wallet_id, title, notes,
+		total_amount::TEXT, due_date, status, COALESCE(tx_hash, ''), paid_at, reminder_count, last_reminder_at, created_at`
+	insertInvoiceQuery = `INSERT INTO invoices (issuer_user_id, recipient_user_id, recipient_email, payee_wallet_id, title, notes, total_amount, due_date)
+		VALUES ($1, NULLIF($2, '')::UUID, $3, $4, $5, $6, $7::NUMERIC, $8::DATE)
+		RETURNING ` + invoiceColumns
+	insertInvoiceLineItemQuery = `INSERT INTO invoice_line_items (invoice_id, position, description, quantity, unit_amount)
+		VALUES ($1, $2, $3, $4, $5::NUMERIC)`
+	getInvoiceQuery        = `SELECT ` + invoiceColumns + ` FROM invoices WHERE invoice_id = $1`
+	getIssuedInvoicesQuery = `SELECT ` + invoiceColumns + ` FROM invoices WHERE issuer_user_id = $1
+		ORDER BY created_at DESC LIMIT $2`
+	// Invoices sent to an email address reach the user who registered with it
+	getIncomingInvoicesQuery = `SELECT ` + invoiceColumns + ` FROM invoices
+		WHERE recipient_user_id = $1 OR (recipient_user_id IS NULL AND LOWER(recipient_email) = LOWER($2))
+		ORDER BY created_at DESC LIMIT $3`
+	getInvoiceLineItemsQuery = `SELECT invoice_id, description, quantity, unit_amount::TEXT FROM invoice_line_items
+		WHERE invoice_id = ANY($1) ORDER BY invoice_id, position`
+	cancelInvoiceQuery = `UPDATE invoices SET status = 'cancelled' WHERE invoice_id = $1 AND issuer_user_id = $2 AND status = 'open'`
+
+	// A transfer matches an open invoice when the recipient sent exactly the total to the payee wallet
+	// after the invoice was issued, transfers that already paid an invoice are skipped. Oldest first.
+	getInvoiceMatchesQuery = `SELECT i.invoice_id, t.tx_hash, t.created_at FROM invoices i
+		JOIN transactions t ON LOWER(t.receiver_wallet_id) = LOWER(i.payee_wallet_id) AND t.amount = i.total_amount
+			AND t.created_at >= i.created_at AND t.status = 'confirmed'
+		JOIN users u ON u.user_id = t.sender_user_id
+		WHERE i.status = 'open'
+			AND (t.sender_user_id = i.recipient_user_id OR (i.recipient_user_id IS NULL AND LOWER(u.email) = LOWER(i.recipient_email)))
+			AND NOT EXISTS (SELECT 1 FROM invoices p WHERE p.tx_hash = t.tx_hash)
+		ORDER BY i.created_at, t.created_at`
+	markInvoicePaidQuery = `UPDATE invoices SET status = 'paid', tx_hash = $2, paid_at = $3 WHERE invoice_id = $1 AND status = 'open'`
+
+	// Open invoices due within the lead days are reminded every interval, up to the maximum.
+	// They are claimed in the same statement so concurrent schedulers do not remind twice.
+	// Due dates are compared with the recipient's local date, recipients without an account use UTC
+	claimInvoiceRemindersQuery = `UPDATE invoices SET reminder_count = reminder_count + 1, last_reminder_at = NOW()
+		WHERE invoice_id IN (SELECT i.invoice_id FROM invoices i LEFT JOIN users u ON i.recipient_user_id = u.user_id
+			WHERE i.status = 'open' AND i.due_date <= (NOW() AT TIME ZONE COALESCE(u.time_zone, 'UTC'))::DATE + $1::INT
+			AND i.reminder_count < $2 AND (i.last_reminder_at IS NULL OR i.last_reminder_at <= NOW() - $3 * INTERVAL '1 second')
+			ORDER BY i.due_date LIMIT $4 FOR UPDATE OF i SKIP LOCKED)
+		RETURNING ` + invoiceColumns
+)
+
+type invoiceRepo struct {
+	DB *sql.DB
+}
+
+type InvoiceStorer interface {
+	CreateInvoice(invoice Invoice) (Invoice, error)
+	GetInvoice(invoiceID string) (Invoice, error)
+	GetIssuedInvoices(issuerUserID string, limit int) ([]Invoice, error)
+	GetIncomingInvoices(userID, email string, limit int) ([]Invoice, error)
+	CancelInvoice(invoiceID, issuerUserID string) (bool, error)
+	GetInvoiceMatches() ([]InvoiceMatch, error)
+	MarkInvoicePaid(invoiceID, txHash string, paidAt time.Time) (bool, error)
+	ClaimInvoiceReminders(leadDays, maxReminders int, interval time.Duration, limit int) ([]Invoice, error)
+}
+
+// Constructor function
+func NewInvoiceRepo(db *sql.DB) InvoiceStorer {
+	return &invoiceRepo{DB: db}
+}
+
+// Stores an open invoice with its line items in one transaction
+func (repoDep *invoiceRepo) CreateInvoice(invoice Invoice) (Invoice, error) {
+	tx, err := repoDep.DB.Begin()
+	if err != nil {
+		return invoice, fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	created, err := scanInvoice(tx.QueryRow(insertInvoiceQuery, invoice.IssuerUserID, invoice.RecipientUserID, invoice.RecipientEmail,
+		invoice.PayeeWalletID, invoice.Title, invoice.Notes, invoice.TotalAmount, invoice.DueDate.Format(time.DateOnly)))
+	if err != nil {
+		log.Printf("Error creating invoice of %s: %v", invoice.IssuerUserID, err)
+		return invoice, fmt.Errorf("error creating invoice: %v", err)
+	}
+
+	for i, item := range invoice.LineItems {
+		if _, err := tx.Exec(insertInvoiceLineItemQuery, created.InvoiceID, i+1, item.Description, item.Quantity, item.UnitAmount); err != nil {
+			log.Printf("Error creating line item of invoice %s: %v", created.InvoiceID, err)
+			return invoice, fmt.Errorf("error creating invoice line item: %v", err)
+		}
+	}
+	created.LineItems = invoice.LineItems
+
+	return created, tx.Commit()
+}
+
+// Returns an invoice in any status with its line items
+func (repoDep *invoiceRepo) GetInvoice(invoiceID string) (Invoice, error) {
+	invoice, err := scanInvoice(repoDep.DB.QueryRow(getInvoiceQuery, invoiceID))
+	if err != nil {
+		return invoice, utils.FromDBError("invoice", err)
+	}
+
+	invoices := []Invoice{invoice}
+	if err := repoDep.loadLineItems(invoices); err != nil {
+		return invoice, err
+	}
+	return invoices[0], nil
+}
+
+// Returns the latest invoices the user issued, newest first
+func (repoDep *invoiceRepo) GetIssuedInvoices(issuerUserID string, limit int) ([]Invoice, error) {
+	return repoDep.queryInvoices(getIssuedInvoicesQuery, issuerUserID, limit)
+}
+
+// Returns the latest invoices addressed to the user or their email, newest first
+func (repoDep *invoiceRepo) GetIncomingInvoices(userID, email string, limit int) ([]Invoice, error) {
+	return repoDep.queryInvoices(getIncomingInvoicesQuery, userID, email, limit)
+}
+
+func (repoDep *invoiceRepo) queryInvoices(query string, args ...any) ([]Invoice, error) {
+	rows, err := repoDep.DB.Query(query, args...)
+	if err != nil {
+		log.Printf("Error executing query: %v", err)
+		return nil, fmt.Errorf("error fetching invoices: %v", err)
+	}
+	defer rows.Close()
+
+	invoices := []Invoice{}
+	for rows.Next() {
+		invoice, err := scanInvoice(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error reading invoices: %v", err)
+		}
+		invoices = append(invoices, invoice)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading invoices: %v", err)
+	}
+
+	return invoices, repoDep.loadLineItems(invoices)
+}
+
+// loadLineItems fills in the line items of the invoices with a single query
+func (repoDep *invoiceRepo) loadLineItems(invoices []Invoice) error {
+	if len(invoices) == 0 {
+		return nil
+	}
+	index := make(map[string]int, len(invoices))
+	invoiceIDs := make([]string, len(invoices))
+	for i, invoice := range invoices {
+		index[invoice.InvoiceID] = i
+		invoiceIDs[i] = invoice.InvoiceID
+	}
+
+	rows, err := repoDep.DB.Query(getInvoiceLineItemsQuery, pq.Array(invoiceIDs))
+	if err != nil {
+		log.Printf("Error executing query: %v", err)
+		return fmt.Errorf("error fetching invoice line items: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var invoiceID string
+		var item InvoiceLineItem
+		if err := rows.Scan(&invoiceID, &item.Description, &item.Quantity, &item.UnitAmount); err != nil {
+			return fmt.Errorf("error reading invoice line items: %v", err)
+		}
+		i := index[invoiceID]
+		invoices[i].LineItems = append(invoices[i].LineItems, item)
+	}
+	return rows.Err()
+}
+
+// Cancels an open invoice of the issuer and reports whether it was still open
+func (repoDep *invoiceRepo) CancelInvoice(invoiceID, issuerUserID string) (bool, error) {
+	result, err := repoDep.DB.Exec(cancelInvoiceQuery, invoiceID, issuerUserID)
+	if err != nil {
+		log.Printf("Error cancelling invoice %s: %v", invoiceID, err)
+		return false, fmt.Errorf("error cancelling invoice: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error checking affected rows: %v", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+// Returns every pairing of an open invoice with a confirmed transfer that could pay it. An invoice or
+// a transfer may appear more than once, the caller settles each of them once.
+func (repoDep *invoiceRepo) GetInvoiceMatches() ([]InvoiceMatch, error) {
+	rows, err := repoDep.DB.Query(getInvoiceMatchesQuery)
+	if err != nil {
+		log.Printf("Error executing query: %v", err)
+		return nil, fmt.Errorf("error matching invoices: %v", err)
+	}
+	defer rows.Close()
+
+	matches := []InvoiceMatch{}
+	for rows.Next() {
+		var match InvoiceMatch
+		if err := rows.Scan(&match.InvoiceID, &match.TxHash, &match.PaidAt); err != nil {
+			return nil, fmt.Errorf("error reading invoice matches: %v", err)
+		}
+		matches = append(matches, match)
+	}
+	return matches, rows.Err()
+}
+
+// Marks an open invoice paid by the transfer and reports whether it was still open
+func (repoDep *invoiceRepo) MarkInvoicePaid(invoiceID, txHash string, paidAt time.Time) (bool, error) {
+	result, err := repoDep.DB.Exec(markInvoicePaidQuery, invoiceID, txHash, paidAt)
+	if err != nil {
+		log.Printf("Error marking invoice %s paid: %v", invoiceID, err)
+		return false, fmt.Errorf("error marking invoice paid: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error checking affected rows: %v", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+// Records a reminder for up to limit open invoices that are due one and returns them
+func (repoDep *invoiceRepo) ClaimInvoiceReminders(leadDays, maxReminders int, interval time.Duration, limit int) ([]Invoice, error) {
+	rows, err := repoDep.DB.Query(claimInvoiceRemindersQuery, leadDays, maxReminders, int(interval.Seconds()), limit)
+	if err != nil {
+		log.Printf("Error claiming invoice reminders: %v", err)
+		return nil, fmt.Errorf("error claiming invoice reminders: %v", err)
+	}
+	defer rows.Close()
+
+	invoices := []Invoice{}
+	for rows.Next() {
+		invoice, err := scanInvoice(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error reading invoices: %v", err)
+		}
+		invoices = append(invoices, invoice)
+	}
+	return invoices, rows.Err()
+}
+
+// Scans one row of the invoiceColumns
+func scanInvoice(row interface{ Scan(dest ...any) error }) (Invoice, error) {
+	var invoice Invoice
+	err := row.Scan(&invoice.InvoiceID, &invoice.IssuerUserID, &invoice.RecipientUserID, &invoice.RecipientEmail, &invoice.PayeeWalletID,
+		&invoice.Title, &invoice.Notes, &invoice.TotalAmount, &invoice.DueDate, &invoice.Status, &invoice.TxHash, &invoice.PaidAt,
+		&invoice.ReminderCount, &invoice.LastReminderAt, &invoice.CreatedAt)
+	return invoice, err
+}
@@ -0,0 +1,191 @@
+package repo
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+	"github.com/lib/pq"
+)
+
+// Job is a unit of background work of a queue. Payload is the JSON input of the job, Status is
+// pending, succeeded or dead. Dead jobs ran out of attempts and stay put until they are requeued.
+type Job struct {
+	JobID         string
+	Queue         string
+	Payload       json.RawMessage
+	Status        string
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+	RequeuedBy    string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	CompletedAt   *time.Time
+}
+
+// JobFilter narrows a job listing, empty fields match every job
+type JobFilter struct {
+	Status string
+	Queue  string
+	Limit  int
+}
+
+// All Job Queries
+const (
+	jobColumns = `job_id, queue, payload, status, attempts, next_attempt_at, last_error, COALESCE(requeued_by::TEXT, ''), created_at,
+		updated_at, completed_at`
+	insertJobQuery  = `INSERT INTO background_jobs (queue, payload) VALUES ($1, $2)`
+	selectJobsQuery = `SELECT ` + jobColumns + ` FROM background_jobs`
+	// Due jobs are leased for a while so that concurrent workers do not run them twice, a job whose
+	// worker stopped is picked up again once its lease ends
+	claimDueJobsQuery = `UPDATE background_jobs SET next_attempt_at = NOW() + $3 * INTERVAL '1 second'
+		WHERE job_id IN (SELECT job_id FROM background_jobs WHERE status = 'pending' AND queue = ANY($1) AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at LIMIT $2 FOR UPDATE SKIP LOCKED)
+		RETURNING ` + jobColumns
+	markJobSucceededQuery = `UPDATE background_jobs SET status = 'succeeded', attempts = attempts + 1, last_error = '', updated_at = NOW(),
+		completed_at = NOW() WHERE job_id = $1`
+	// Without a next attempt the job is dead
+	recordJobFailureQuery = `UPDATE background_jobs SET attempts = attempts + 1, last_error = $2, updated_at = NOW(),
+		status = CASE WHEN $3::TIMESTAMPTZ IS NULL THEN 'dead' ELSE 'pending' END, next_attempt_at = COALESCE($3, next_attempt_at),
+		completed_at = CASE WHEN $3::TIMESTAMPTZ IS NULL THEN NOW() END
+		WHERE job_id = $1`
+	requeueJobQuery = `UPDATE background_jobs SET status = 'pending', attempts = 0, next_attempt_at = NOW(), requeued_by = $2, updated_at = NOW(),
+		completed_at = NULL WHERE job_id = $1 AND status = 'dead'`
+	deleteSucceededJobsBeforeQuery = `DELETE FROM background_jobs WHERE status = 'succeeded' AND completed_at < $1`
+)
+
+type jobRepo struct {
+	DB *sql.DB
+}
+
+type JobStorer interface {
+	EnqueueJob(queue string, payload []byte) error
+	ClaimDueJobs(queues []string, limit int, lease time.Duration) ([]Job, error)
+	MarkJobSucceeded(jobID string) error
+	RecordJobFailure(jobID, lastError string, nextAttemptAt *time.Time) error
+	GetJob(jobID string) (Job, error)
+	GetJobs(filter JobFilter) ([]Job, error)
+	RequeueJob(jobID, requeuedBy string) (bool, error)
+	DeleteSucceededJobsBefore(cutoff time.Time) (int64, error)
+}
+
+// Constructor function
+func NewJobRepo(db *sql.DB) JobStorer {
+	return &jobRepo{DB: db}
+}
+
+// Queues a job that is due right away
+func (repoDep *jobRepo) EnqueueJob(queue string, payload []byte) error {
+	if _, err := repoDep.DB.Exec(insertJobQuery, queue, payload); err != nil {
+		log.Printf("Error queueing %s job: %v", queue, err)
+		return fmt.Errorf("error queueing job: %v", err)
+	}
+	return nil
+}
+
+// Leases up to limit due jobs of the queues
+func (repoDep *jobRepo) ClaimDueJobs(queues []string, limit int, lease time.Duration) ([]Job, error) {
+	rows, err := repoDep.DB.Query(claimDueJobsQuery, pq.Array(queues), limit, int(lease.Seconds()))
+	if err != nil {
+		log.Printf("Error claiming jobs: %v", err)
+		return nil, fmt.Errorf("error claiming jobs: %v", err)
+	}
+	return scanJobs(rows)
+}
+
+// Records a successful run
+func (repoDep *jobRepo) MarkJobSucceeded(jobID string) error {
+	if _, err := repoDep.DB.Exec(markJobSucceededQuery, jobID); err != nil {
+		log.Printf("Error marking job %s succeeded: %v", jobID, err)
+		return fmt.Errorf("error updating job: %v", err)
+	}
+	return nil
+}
+
+// Records a failed run, the job is retried at nextAttemptAt or dead when it is nil
+func (repoDep *jobRepo) RecordJobFailure(jobID, lastError string, nextAttemptAt *time.Time) error {
+	if _, err := repoDep.DB.Exec(recordJobFailureQuery, jobID, lastError, nextAttemptAt); err != nil {
+		log.Printf("Error recording failure of job %s: %v", jobID, err)
+		return fmt.Errorf("error updating job: %v", err)
+	}
+	return nil
+}
+
+// Returns a job with its payload
+func (repoDep *jobRepo) GetJob(jobID string) (Job, error) {
+	query, args := newSelectQuery(selectJobsQuery).where("job_id = ?", jobID).build()
+
+	job, err := scanJob(repoDep.DB.QueryRow(query, args...))
+	if err != nil {
+		return job, utils.FromDBError("job", err)
+	}
+	return job, nil
+}
+
+// Returns the jobs matching the filter, most recently updated first
+func (repoDep *jobRepo) GetJobs(filter JobFilter) ([]Job, error) {
+	query := newSelectQuery(selectJobsQuery)
+	if filter.Status != "" {
+		query.where("status = ?", filter.Status)
+	}
+	if filter.Queue != "" {
+		query.where("queue = ?", filter.Queue)
+	}
+	sqlQuery, args := query.order("updated_at DESC").page(filter.Limit, 0).build()
+
+	rows, err := repoDep.DB.Query(sqlQuery, args...)
+	if err != nil {
+		log.Printf("Error executing query: %v", err)
+		return nil, fmt.Errorf("error fetching jobs: %v", err)
+	}
+	return scanJobs(rows)
+}
+
+// Gives a dead job a fresh set of attempts starting now, false when the job is not dead
+func (repoDep *jobRepo) RequeueJob(jobID, requeuedBy string) (bool, error) {
+	result, err := repoDep.DB.Exec(requeueJobQuery, jobID, requeuedBy)
+	if err != nil {
+		log.Printf("Error requeueing job %s: %v", jobID, err)
+		return false, fmt.Errorf("error requeueing job: %v", err)
+	}
+	requeued, err := result.RowsAffected()
+	return requeued > 0, err
+}
+
+// Removes succeeded jobs completed before cutoff, dead jobs are kept until they are dealt with
+func (repoDep *jobRepo) DeleteSucceededJobsBefore(cutoff time.Time) (int64, error) {
+	result, err := repoDep.DB.Exec(deleteSucceededJobsBeforeQuery, cutoff)
+	if err != nil {
+		log.Printf("Error deleting old jobs: %v", err)
+		return 0, fmt.Errorf("error deleting old jobs: %v", err)
+	}
+	return result.RowsAffected()
+}
+
+// Scans one row of the jobColumns
+func scanJob(row interface{ Scan(dest ...any) error }) (Job, error) {
+	var job Job
+	var payload []byte
+	err := row.Scan(&job.JobID, &job.Queue, &payload, &job.Status, &job.Attempts, &job.NextAttemptAt, &job.LastError, &job.RequeuedBy,
+		&job.CreatedAt, &job.UpdatedAt, &job.CompletedAt)
+	job.Payload = payload
+	return job, err
+}
+
+func scanJobs(rows *sql.Rows) ([]Job, error) {
+	defer rows.Close()
+
+	jobs := []Job{}
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error reading jobs: %v", err)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
@@ -0,0 +1,114 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"log"
+	"time"
+)
+
+// All Lock Queries
+const (
+	// Advisory locks are keyed by a hash of their name, shared by every instance on the database
+	tryAdvisoryLockQuery = `SELECT pg_try_advisory_lock(hashtext($1))`
+	advisoryLockQuery    = `SELECT pg_advisory_lock(hashtext($1))`
+	advisoryUnlockQuery  = `SELECT pg_advisory_unlock(hashtext($1))`
+)
+
+const lockCheckTimeout = 5 * time.Second
+
+// How long Lock waits for another session to release the lock by default
+const lockWaitTimeout = 30 * time.Second
+
+// Lock is a session level advisory lock. It stays held for as long as the connection it was taken on
+// is open, so it is given up when its holder dies along with that connection.
+type Lock struct {
+	name string
+	conn *sql.Conn
+}
+
+// TransferLockName names the lock serializing everything that spends from the wallet of a user
+func TransferLockName(userID string) string {
+	return "transfer:" + userID
+}
+
+type lockRepo struct {
+	DB *sql.DB
+	// How long Lock waits for another session to release a lock
+	waitTimeout time.Duration
+}
+
+type LockStorer interface {
+	TryLock(name string) (*Lock, bool, error)
+	Lock(name string) (*Lock, error)
+}
+
+// Constructor function
+func NewLockRepo(db *sql.DB) LockStorer {
+	return &lockRepo{DB: db, waitTimeout: lockWaitTimeout}
+}
+
+// Takes the named lock on a connection of its own, false when another session holds it
+func (repoDep *lockRepo) TryLock(name string) (*Lock, bool, error) {
+	ctx := context.Background()
+	conn, err := repoDep.DB.Conn(ctx)
+	if err != nil {
+		log.Printf("Error opening connection for lock %s: %v", name, err)
+		return nil, false, fmt.Errorf("error taking lock: %v", err)
+	}
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, tryAdvisoryLockQuery, name).Scan(&locked); err != nil {
+		conn.Close()
+		log.Printf("Error taking lock %s: %v", name, err)
+		return nil, false, fmt.Errorf("error taking lock: %v", err)
+	}
+	if !locked {
+		conn.Close()
+		return nil, false, nil
+	}
+	return &Lock{name: name, conn: conn}, true, nil
+}
+
+// Takes the named lock on a connection of its own, waiting while another session holds it. The wait
+// can time out just as the lock is granted, so a connection that failed to lock is dropped rather
+// than returned to the pool, which could leave the lock held by an idle connection.
+func (repoDep *lockRepo) Lock(name string) (*Lock, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), repoDep.waitTimeout)
+	defer cancel()
+	conn, err := repoDep.DB.Conn(ctx)
+	if err != nil {
+		log.Printf("Error opening connection for lock %s: %v", name, err)
+		return nil, fmt.Errorf("error taking lock: %v", err)
+	}
+
+	if _, err := conn.ExecContext(ctx, advisoryLockQuery, name); err != nil {
+		conn.Raw(func(any) error { return driver.ErrBadConn })
+		conn.Close()
+		log.Printf("Error taking lock %s: %v", name, err)
+		return nil, fmt.Errorf("error taking lock: %v", err)
+	}
+	return &Lock{name: name, conn: conn}, nil
+}
+
+// Held reports whether the connection of the lock is still alive, the lock is lost once it is not
+func (lock *Lock) Held() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), lockCheckTimeout)
+	defer cancel()
+	return lock.conn.PingContext(ctx) == nil
+}
+
+// Release unlocks the lock and returns its connection to the pool. A connection that could not be
+// unlocked is closed instead, which releases the lock as well.
+func (lock *Lock) Release() {
+	ctx, cancel := context.WithTimeout(context.Background(), lockCheckTimeout)
+	defer cancel()
+
+	if _, err := lock.conn.ExecContext(ctx, advisoryUnlockQuery, lock.name); err != nil {
+		log.Printf("Error releasing lock %s, dropping its connection: %v", lock.name, err)
+		lock.conn.Raw(func(any) error { return driver.ErrBadConn })
+	}
+	lock.conn.Close()
+}
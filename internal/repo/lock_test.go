@@ -0,0 +1,75 @@
+package repo
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+// TestLockWaitsForHolder takes a lock twice, the second Lock returns only once the first is released
+func TestLockWaitsForHolder(t *testing.T) {
+	lockRepo := NewLockRepo(testDB(t))
+	name := "lock-test:" + strconv.FormatInt(time.Now().UnixNano(), 36)
+
+	first, err := lockRepo.Lock(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, locked, err := lockRepo.TryLock(name); err != nil || locked {
+		t.Fatalf("TryLock while held = %v, %v, want false", locked, err)
+	}
+
+	acquired := make(chan *Lock)
+	go func() {
+		second, err := lockRepo.Lock(name)
+		if err != nil {
+			t.Error(err)
+		}
+		acquired <- second
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("second Lock returned while the first was held")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	first.Release()
+	select {
+	case second := <-acquired:
+		if second != nil {
+			second.Release()
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("second Lock did not return after the first was released")
+	}
+}
+
+// TestLockTimeout gives up waiting for a held lock, without leaving a connection behind that holds it
+func TestLockTimeout(t *testing.T) {
+	db := testDB(t)
+	// Two connections, the pool hands the one that waited out again to TryLock below
+	db.SetMaxOpenConns(2)
+	locks := NewLockRepo(db)
+	waiting := &lockRepo{DB: db, waitTimeout: 200 * time.Millisecond}
+	name := "lock-test:" + strconv.FormatInt(time.Now().UnixNano(), 36)
+
+	first, err := locks.Lock(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second, err := waiting.Lock(name); err == nil {
+		second.Release()
+		t.Fatal("Lock of a held lock returned before the holder released it")
+	}
+	first.Release()
+
+	// Every pooled connection is free of the lock once its holder released it
+	for i := 0; i < 5; i++ {
+		lock, locked, err := locks.TryLock(name)
+		if err != nil || !locked {
+			t.Fatalf("TryLock after the timeout = %v, %v, want the lock", locked, err)
+		}
+		lock.Release()
+	}
+}
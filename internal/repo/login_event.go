@@ -0,0 +1,109 @@
+package repo
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+// LoginEvent is a successful sign-in of a user, Method is password, oidc or siwe. NewDevice marks
+// sign-ins from a network or user agent not seen before, DisownedAt is set once the user reported
+// the sign-in as not theirs.
+type LoginEvent struct {
+	EventID    string
+	UserID     string
+	Method     string
+	IPAddress  string
+	UserAgent  string
+	NewDevice  bool
+	CreatedAt  time.Time
+	DisownedAt *time.Time
+}
+
+// All Login Event Queries
+const (
+	loginEventColumns     = `event_id, user_id, method, ip_address, user_agent, new_device, created_at, disowned_at`
+	insertLoginEventQuery = `INSERT INTO login_events (user_id, method, ip_address, user_agent, new_device) VALUES ($1, $2, $3, LEFT($4, 512), $5)
+		RETURNING ` + loginEventColumns
+	getLoginEventsQuery = `SELECT ` + loginEventColumns + ` FROM login_events WHERE user_id = $1 ORDER BY created_at DESC LIMIT $2`
+	getLoginEventQuery  = `SELECT ` + loginEventColumns + ` FROM login_events WHERE event_id = $1`
+	disownLoginQuery    = `UPDATE login_events SET disowned_at = NOW() WHERE event_id = $1 AND disowned_at IS NULL`
+)
+
+type loginEventRepo struct {
+	DB *sql.DB
+}
+
+type LoginEventStorer interface {
+	RecordLogin(event LoginEvent) (LoginEvent, error)
+	GetLoginEvents(userID string, limit int) ([]LoginEvent, error)
+	GetLoginEvent(eventID string) (LoginEvent, error)
+	DisownLogin(eventID string) (bool, error)
+}
+
+// Constructor function
+func NewLoginEventRepo(db *sql.DB) LoginEventStorer {
+	return &loginEventRepo{DB: db}
+}
+
+// Records a sign-in
+func (repoDep *loginEventRepo) RecordLogin(event LoginEvent) (LoginEvent, error) {
+	recorded, err := scanLoginEvent(repoDep.DB.QueryRow(insertLoginEventQuery, event.UserID, event.Method, event.IPAddress, event.UserAgent,
+		event.NewDevice))
+	if err != nil {
+		log.Printf("Error recording login of %s: %v", event.UserID, err)
+		return event, fmt.Errorf("error recording login: %v", err)
+	}
+	return recorded, nil
+}
+
+// Returns the user's latest sign-ins, newest first
+func (repoDep *loginEventRepo) GetLoginEvents(userID string, limit int) ([]LoginEvent, error) {
+	rows, err := repoDep.DB.Query(getLoginEventsQuery, userID, limit)
+	if err != nil {
+		log.Printf("Error executing query: %v", err)
+		return nil, fmt.Errorf("error fetching logins: %v", err)
+	}
+	defer rows.Close()
+
+	events := []LoginEvent{}
+	for rows.Next() {
+		event, err := scanLoginEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error reading logins: %v", err)
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// Returns a sign-in
+func (repoDep *loginEventRepo) GetLoginEvent(eventID string) (LoginEvent, error) {
+	event, err := scanLoginEvent(repoDep.DB.QueryRow(getLoginEventQuery, eventID))
+	if err != nil {
+		return event, utils.FromDBError("login", err)
+	}
+	return event, nil
+}
+
+// Marks a sign-in as reported by the user, false when it already was
+func (repoDep *loginEventRepo) DisownLogin(eventID string) (bool, error) {
+	result, err := repoDep.DB.Exec(disownLoginQuery, eventID)
+	if err != nil {
+		log.Printf("Error disowning login %s: %v", eventID, err)
+		return false, fmt.Errorf("error disowning login: %v", err)
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// Scans one row of the loginEventColumns
+func scanLoginEvent(row interface{ Scan(dest ...any) error }) (LoginEvent, error) {
+	var event LoginEvent
+	err := row.Scan(&event.EventID, &event.UserID, &event.Method, &event.IPAddress, &event.UserAgent, &event.NewDevice, &event.CreatedAt,
+		&event.DisownedAt)
+	return event, err
+}
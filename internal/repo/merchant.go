@@ -0,0 +1,488 @@
+package repo
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+// Merchant accepts payments from customers into the wallet of its owner
+type Merchant struct {
+	MerchantID  string
+	OwnerUserID string
+	Name        string
+	WebhookURL  string
+	CreatedAt   time.Time
+}
+
+// PaymentIntent is an amount in wei a merchant wants to collect, CustomerUserID and TxHash are set
+// once a customer picked it up
+type PaymentIntent struct {
+	IntentID       string
+	MerchantID     string
+	Amount         string
+	Description    string
+	Reference      string
+	Status         string
+	ExpiresAt      time.Time
+	CustomerUserID string
+	TxHash         string
+	CreatedAt      time.Time
+	CompletedAt    *time.Time
+}
+
+// MerchantSettlement totals the payments a merchant received on one UTC day
+type MerchantSettlement struct {
+	MerchantID     string
+	SettlementDate time.Time
+	PaymentCount   int
+	GrossAmount    string
+	CreatedAt      time.Time
+}
+
+// WebhookJobQueue is the background job queue delivering webhook events, its jobs carry the event_id
+const WebhookJobQueue = "merchant.webhook"
+
+// WebhookEvent is an event for the merchant's webhook, Payload is the JSON data of the event
+type WebhookEvent struct {
+	EventID       string
+	MerchantID    string
+	EventType     string
+	Payload       json.RawMessage
+	Status        string
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+	CreatedAt     time.Time
+	DeliveredAt   *time.Time
+}
+
+// All Merchant Queries
+const (
+	merchantColumns     = `merchant_id, owner_user_id, name, webhook_url, created_at`
+	insertMerchantQuery = `INSERT INTO merchants (owner_user_id, name, webhook_url) VALUES ($1, $2, $3)
+		ON CONFLICT (owner_user_id) DO NOTHING RETURNING ` + merchantColumns
+	getMerchantQuery        = `SELECT ` + merchantColumns + ` FROM merchants WHERE merchant_id = $1`
+	getMerchantByOwnerQuery = `SELECT ` + merchantColumns + ` FROM merchants WHERE owner_user_id = $1`
+	updateMerchantQuery     = `UPDATE merchants SET name = $2, webhook_url = $3 WHERE merchant_id = $1 RETURNING ` + merchantColumns
+	saveWebhookSecretQuery  = `INSERT INTO merchant_webhook_secrets (merchant_id, secret, key_version, cipher) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (merchant_id) DO UPDATE SET secret = EXCLUDED.secret, key_version = EXCLUDED.key_version, cipher = EXCLUDED.cipher, created_at = NOW()`
+	getWebhookSecretQuery = `SELECT secret, key_version, cipher FROM merchant_webhook_secrets WHERE merchant_id = $1`
+
+	paymentIntentColumns = `intent_id, merchant_id, amount::TEXT, description, reference, status, expires_at,
+		COALESCE(customer_user_id::TEXT, ''), COALESCE(tx_hash, ''), created_at, completed_at`
+	// A reference already used by the merchant returns nothing, the caller then loads that intent
+	insertPaymentIntentQuery = `INSERT INTO payment_intents (merchant_id, amount, description, reference, expires_at)
+		VALUES ($1, $2::NUMERIC, $3, $4, $5) ON CONFLICT (merchant_id, reference) WHERE reference <> '' DO NOTHING
+		RETURNING ` + paymentIntentColumns
+	getPaymentIntentQuery            = `SELECT ` + paymentIntentColumns + ` FROM payment_intents WHERE intent_id = $1`
+	getPaymentIntentByReferenceQuery = `SELECT ` + paymentIntentColumns + ` FROM payment_intents WHERE merchant_id = $1 AND reference = $2`
+	getPaymentIntentsQuery           = `SELECT ` + paymentIntentColumns + ` FROM payment_intents WHERE merchant_id = $1
+		ORDER BY created_at DESC LIMIT $2`
+	// Only one customer can claim an intent, the claim is released again if their transfer fails
+	claimPaymentIntentQuery = `UPDATE payment_intents SET status = 'processing', customer_user_id = $2
+		WHERE intent_id = $1 AND status = 'requires_payment' AND expires_at > NOW()`
+	releasePaymentIntentQuery = `UPDATE payment_intents SET status = 'requires_payment', customer_user_id = NULL
+		WHERE intent_id = $1 AND status = 'processing'`
+	completePaymentIntentQuery = `UPDATE payment_intents SET status = 'succeeded', tx_hash = $2, completed_at = $3
+		WHERE intent_id = $1 AND status = 'processing'`
+	cancelPaymentIntentQuery = `UPDATE payment_intents SET status = 'cancelled', completed_at = NOW()
+		WHERE intent_id = $1 AND merchant_id = $2 AND status = 'requires_payment'`
+
+	// Settling a day twice inserts nothing, so only new settlements are returned
+	insertSettlementsQuery = `INSERT INTO merchant_settlements (merchant_id, settlement_date, payment_count, gross_amount)
+		SELECT merchant_id, $1::DATE, COUNT(*), SUM(amount) FROM payment_intents
+		WHERE status = 'succeeded' AND completed_at >= $1::DATE AND completed_at < $1::DATE + 1
+		GROUP BY merchant_id
+		ON CONFLICT (merchant_id, settlement_date) DO NOTHING
+		RETURNING merchant_id, settlement_date, payment_count, gross_amount::TEXT, created_at`
+	getSettlementsQuery = `SELECT merchant_id, settlement_date, payment_count, gross_amount::TEXT, created_at
+		FROM merchant_settlements WHERE merchant_id = $1 ORDER BY settlement_date DESC LIMIT $2`
+
+	// Webhook events are inserted together with the background job delivering them
+	webhookEventColumns     = `event_id, merchant_id, event_type, payload, status, attempts, next_attempt_at, last_error, created_at, delivered_at`
+	insertWebhookEventQuery = `WITH event AS (
+			INSERT INTO merchant_webhook_events (merchant_id, event_type, payload) VALUES ($1, $2, $3) RETURNING event_id
+		) INSERT INTO background_jobs (queue, payload) SELECT '` + WebhookJobQueue + `', jsonb_build_object('event_id', event_id) FROM event`
+	getWebhookEventQuery      = `SELECT ` + webhookEventColumns + ` FROM merchant_webhook_events WHERE event_id = $1`
+	markWebhookDeliveredQuery = `UPDATE merchant_webhook_events SET status = 'delivered', attempts = attempts + 1, last_error = '', delivered_at = NOW()
+		WHERE event_id = $1`
+	// Without a next attempt the event is given up
+	recordWebhookFailureQuery = `UPDATE merchant_webhook_events SET attempts = attempts + 1, last_error = $2,
+		status = CASE WHEN $3::TIMESTAMPTZ IS NULL THEN 'failed' ELSE 'pending' END, next_attempt_at = COALESCE($3, next_attempt_at)
+		WHERE event_id = $1`
+	getWebhookEventsQuery = `SELECT ` + webhookEventColumns + ` FROM merchant_webhook_events WHERE merchant_id = $1
+		ORDER BY created_at DESC LIMIT $2`
+)
+
+type merchantRepo struct {
+	DB      *sql.DB
+	keyRing PrivateKeyRing
+}
+
+type MerchantStorer interface {
+	CreateMerchant(merchant Merchant, webhookSecret string) (Merchant, error)
+	GetMerchant(merchantID string) (Merchant, error)
+	GetMerchantByOwner(ownerUserID string) (Merchant, error)
+	UpdateMerchant(merchant Merchant) (Merchant, error)
+	SaveWebhookSecret(merchantID, secret string) error
+	GetWebhookSecret(merchantID string) (string, error)
+	CreatePaymentIntent(intent PaymentIntent) (PaymentIntent, bool, error)
+	GetPaymentIntent(intentID string) (PaymentIntent, error)
+	GetPaymentIntents(merchantID string, limit int) ([]PaymentIntent, error)
+	ClaimPaymentIntent(intentID, customerUserID string) (bool, error)
+	ReleasePaymentIntent(intentID string) error
+	CompletePaymentIntent(intentID, txHash string, completedAt time.Time, event *WebhookEvent) error
+	CancelPaymentIntent(intentID, merchantID string, event *WebhookEvent) (bool, error)
+	CreateSettlements(day time.Time) ([]MerchantSettlement, error)
+	GetSettlements(merchantID string, limit int) ([]MerchantSettlement, error)
+	EnqueueWebhookEvent(event WebhookEvent) error
+	GetWebhookEvent(eventID string) (WebhookEvent, error)
+	MarkWebhookDelivered(eventID string) error
+	RecordWebhookFailure(eventID, lastError string, nextAttemptAt *time.Time) error
+	GetWebhookEvents(merchantID string, limit int) ([]WebhookEvent, error)
+}
+
+// Constructor function
+func NewMerchantRepo(db *sql.DB, keyRing PrivateKeyRing) MerchantStorer {
+	return &merchantRepo{DB: db, keyRing: keyRing}
+}
+
+// Registers a merchant with its webhook secret in one transaction, a user can own one merchant
+func (repoDep *merchantRepo) CreateMerchant(merchant Merchant, webhookSecret string) (Merchant, error) {
+	tx, err := repoDep.DB.Begin()
+	if err != nil {
+		return merchant, fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	created, err := scanMerchant(tx.QueryRow(insertMerchantQuery, merchant.OwnerUserID, merchant.Name, merchant.WebhookURL))
+	if errors.Is(err, sql.ErrNoRows) {
+		return merchant, utils.Conflict("you are already registered as a merchant")
+	}
+	if err != nil {
+		log.Printf("Error registering merchant of %s: %v", merchant.OwnerUserID, err)
+		return merchant, fmt.Errorf("error registering merchant: %v", err)
+	}
+	if err := repoDep.saveWebhookSecret(tx, created.MerchantID, webhookSecret); err != nil {
+		return merchant, err
+	}
+
+	return created, tx.Commit()
+}
+
+// Returns a merchant by ID
+func (repoDep *merchantRepo) GetMerchant(merchantID string) (Merchant, error) {
+	merchant, err := scanMerchant(repoDep.DB.QueryRow(getMerchantQuery, merchantID))
+	if err != nil {
+		return merchant, utils.FromDBError("merchant", err)
+	}
+	return merchant, nil
+}
+
+// Returns the merchant the user owns
+func (repoDep *merchantRepo) GetMerchantByOwner(ownerUserID string) (Merchant, error) {
+	merchant, err := scanMerchant(repoDep.DB.QueryRow(getMerchantByOwnerQuery, ownerUserID))
+	if err != nil {
+		return merchant, utils.FromDBError("merchant", err)
+	}
+	return merchant, nil
+}
+
+// Updates the name and webhook URL of a merchant
+func (repoDep *merchantRepo) UpdateMerchant(merchant Merchant) (Merchant, error) {
+	updated, err := scanMerchant(repoDep.DB.QueryRow(updateMerchantQuery, merchant.MerchantID, merchant.Name, merchant.WebhookURL))
+	if err != nil {
+		return merchant, utils.FromDBError("merchant", err)
+	}
+	return updated, nil
+}
+
+// Encrypts and stores a new webhook secret, replacing the previous one
+func (repoDep *merchantRepo) SaveWebhookSecret(merchantID, secret string) error {
+	return repoDep.saveWebhookSecret(repoDep.DB, merchantID, secret)
+}
+
+func (repoDep *merchantRepo) saveWebhookSecret(db interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}, merchantID, secret string) error {
+	key, err := repoDep.keyRing.sealingKey()
+	if err != nil {
+		return fmt.Errorf("failed to encrypt webhook secret: %v", err)
+	}
+	encryptedSecret, err := sealPrivateKey(secret, key, merchantID, repoDep.keyRing.Current)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt webhook secret: %v", err)
+	}
+
+	if _, err := db.Exec(saveWebhookSecretQuery, merchantID, encryptedSecret, repoDep.keyRing.Current, cipherGCM); err != nil {
+		log.Printf("Error storing webhook secret of merchant %s: %v", merchantID, err)
+		return fmt.Errorf("error storing webhook secret: %v", err)
+	}
+	return nil
+}
+
+// Retrieves and decrypts the webhook secret of a merchant
+func (repoDep *merchantRepo) GetWebhookSecret(merchantID string) (string, error) {
+	var encryptedSecret, cipherName string
+	var keyVersion int
+	err := repoDep.DB.QueryRow(getWebhookSecretQuery, merchantID).Scan(&encryptedSecret, &keyVersion, &cipherName)
+	if err != nil {
+		return "", utils.FromDBError("webhook secret", err)
+	}
+	if cipherName != cipherGCM {
+		return "", fmt.Errorf("unknown cipher %q", cipherName)
+	}
+
+	key, err := repoDep.keyRing.key(keyVersion)
+	if err != nil {
+		return "", err
+	}
+	secret, err := openPrivateKey(encryptedSecret, key, merchantID, keyVersion)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt webhook secret: %v", err)
+	}
+	return secret, nil
+}
+
+// Stores a payment intent and reports whether it is new. An intent whose reference the merchant
+// already used is not created again, the existing one is returned instead.
+func (repoDep *merchantRepo) CreatePaymentIntent(intent PaymentIntent) (PaymentIntent, bool, error) {
+	created, err := scanPaymentIntent(repoDep.DB.QueryRow(insertPaymentIntentQuery, intent.MerchantID, intent.Amount, intent.Description, intent.Reference, intent.ExpiresAt))
+	if errors.Is(err, sql.ErrNoRows) {
+		existing, err := scanPaymentIntent(repoDep.DB.QueryRow(getPaymentIntentByReferenceQuery, intent.MerchantID, intent.Reference))
+		if err != nil {
+			return existing, false, utils.FromDBError("payment intent", err)
+		}
+		return existing, false, nil
+	}
+	if err != nil {
+		log.Printf("Error creating payment intent of merchant %s: %v", intent.MerchantID, err)
+		return intent, false, fmt.Errorf("error creating payment intent: %v", err)
+	}
+	return created, true, nil
+}
+
+// Returns a payment intent in any status
+func (repoDep *merchantRepo) GetPaymentIntent(intentID string) (PaymentIntent, error) {
+	intent, err := scanPaymentIntent(repoDep.DB.QueryRow(getPaymentIntentQuery, intentID))
+	if err != nil {
+		return intent, utils.FromDBError("payment intent", err)
+	}
+	return intent, nil
+}
+
+// Returns the latest payment intents of the merchant, newest first
+func (repoDep *merchantRepo) GetPaymentIntents(merchantID string, limit int) ([]PaymentIntent, error) {
+	rows, err := repoDep.DB.Query(getPaymentIntentsQuery, merchantID, limit)
+	if err != nil {
+		log.Printf("Error executing query: %v", err)
+		return nil, fmt.Errorf("error fetching payment intents: %v", err)
+	}
+	defer rows.Close()
+
+	intents := []PaymentIntent{}
+	for rows.Next() {
+		intent, err := scanPaymentIntent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error reading payment intents: %v", err)
+		}
+		intents = append(intents, intent)
+	}
+	return intents, rows.Err()
+}
+
+// Reserves an unpaid, unexpired intent for the customer and reports whether it was still unpaid
+func (repoDep *merchantRepo) ClaimPaymentIntent(intentID, customerUserID string) (bool, error) {
+	result, err := repoDep.DB.Exec(claimPaymentIntentQuery, intentID, customerUserID)
+	if err != nil {
+		log.Printf("Error claiming payment intent %s: %v", intentID, err)
+		return false, fmt.Errorf("error claiming payment intent: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error checking affected rows: %v", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+// Reopens a claimed intent whose payment did not go through
+func (repoDep *merchantRepo) ReleasePaymentIntent(intentID string) error {
+	if _, err := repoDep.DB.Exec(releasePaymentIntentQuery, intentID); err != nil {
+		log.Printf("Error releasing payment intent %s: %v", intentID, err)
+		return fmt.Errorf("error releasing payment intent: %v", err)
+	}
+	return nil
+}
+
+// Marks a claimed intent succeeded and queues its webhook event in the same transaction, so the
+// merchant hears about every payment
+func (repoDep *merchantRepo) CompletePaymentIntent(intentID, txHash string, completedAt time.Time, event *WebhookEvent) error {
+	tx, err := repoDep.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(completePaymentIntentQuery, intentID, txHash, completedAt); err != nil {
+		log.Printf("Error completing payment intent %s: %v", intentID, err)
+		return fmt.Errorf("error completing payment intent: %v", err)
+	}
+	if event != nil {
+		if _, err := tx.Exec(insertWebhookEventQuery, event.MerchantID, event.EventType, []byte(event.Payload)); err != nil {
+			log.Printf("Error queueing webhook event of payment intent %s: %v", intentID, err)
+			return fmt.Errorf("error queueing webhook event: %v", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// Cancels an unpaid intent of the merchant with its webhook event and reports whether it was still unpaid
+func (repoDep *merchantRepo) CancelPaymentIntent(intentID, merchantID string, event *WebhookEvent) (bool, error) {
+	tx, err := repoDep.DB.Begin()
+	if err != nil {
+		return false, fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(cancelPaymentIntentQuery, intentID, merchantID)
+	if err != nil {
+		log.Printf("Error cancelling payment intent %s: %v", intentID, err)
+		return false, fmt.Errorf("error cancelling payment intent: %v", err)
+	}
+	if rowsAffected, err := result.RowsAffected(); err != nil || rowsAffected == 0 {
+		return false, err
+	}
+	if event != nil {
+		if _, err := tx.Exec(insertWebhookEventQuery, event.MerchantID, event.EventType, []byte(event.Payload)); err != nil {
+			log.Printf("Error queueing webhook event of payment intent %s: %v", intentID, err)
+			return false, fmt.Errorf("error queueing webhook event: %v", err)
+		}
+	}
+	return true, tx.Commit()
+}
+
+// Totals the payments every merchant received on day and returns the settlements not created before
+func (repoDep *merchantRepo) CreateSettlements(day time.Time) ([]MerchantSettlement, error) {
+	rows, err := repoDep.DB.Query(insertSettlementsQuery, day.Format(time.DateOnly))
+	if err != nil {
+		log.Printf("Error creating settlements of %s: %v", day.Format(time.DateOnly), err)
+		return nil, fmt.Errorf("error creating settlements: %v", err)
+	}
+	return scanSettlements(rows)
+}
+
+// Returns the latest settlements of the merchant, newest first
+func (repoDep *merchantRepo) GetSettlements(merchantID string, limit int) ([]MerchantSettlement, error) {
+	rows, err := repoDep.DB.Query(getSettlementsQuery, merchantID, limit)
+	if err != nil {
+		log.Printf("Error executing query: %v", err)
+		return nil, fmt.Errorf("error fetching settlements: %v", err)
+	}
+	return scanSettlements(rows)
+}
+
+// Queues an event for the merchant's webhook
+func (repoDep *merchantRepo) EnqueueWebhookEvent(event WebhookEvent) error {
+	if _, err := repoDep.DB.Exec(insertWebhookEventQuery, event.MerchantID, event.EventType, []byte(event.Payload)); err != nil {
+		log.Printf("Error queueing webhook event of merchant %s: %v", event.MerchantID, err)
+		return fmt.Errorf("error queueing webhook event: %v", err)
+	}
+	return nil
+}
+
+// Returns a webhook event
+func (repoDep *merchantRepo) GetWebhookEvent(eventID string) (WebhookEvent, error) {
+	event, err := scanWebhookEvent(repoDep.DB.QueryRow(getWebhookEventQuery, eventID))
+	if err != nil {
+		return event, utils.FromDBError("webhook event", err)
+	}
+	return event, nil
+}
+
+// Records a successful delivery
+func (repoDep *merchantRepo) MarkWebhookDelivered(eventID string) error {
+	if _, err := repoDep.DB.Exec(markWebhookDeliveredQuery, eventID); err != nil {
+		log.Printf("Error marking webhook event %s delivered: %v", eventID, err)
+		return fmt.Errorf("error updating webhook event: %v", err)
+	}
+	return nil
+}
+
+// Records a failed delivery, the event is retried at nextAttemptAt or given up when it is nil
+func (repoDep *merchantRepo) RecordWebhookFailure(eventID, lastError string, nextAttemptAt *time.Time) error {
+	if _, err := repoDep.DB.Exec(recordWebhookFailureQuery, eventID, lastError, nextAttemptAt); err != nil {
+		log.Printf("Error recording failure of webhook event %s: %v", eventID, err)
+		return fmt.Errorf("error updating webhook event: %v", err)
+	}
+	return nil
+}
+
+// Returns the latest webhook events of the merchant, newest first
+func (repoDep *merchantRepo) GetWebhookEvents(merchantID string, limit int) ([]WebhookEvent, error) {
+	rows, err := repoDep.DB.Query(getWebhookEventsQuery, merchantID, limit)
+	if err != nil {
+		log.Printf("Error executing query: %v", err)
+		return nil, fmt.Errorf("error fetching webhook events: %v", err)
+	}
+	return scanWebhookEvents(rows)
+}
+
+// Scans one row of the merchantColumns
+func scanMerchant(row interface{ Scan(dest ...any) error }) (Merchant, error) {
+	var merchant Merchant
+	err := row.Scan(&merchant.MerchantID, &merchant.OwnerUserID, &merchant.Name, &merchant.WebhookURL, &merchant.CreatedAt)
+	return merchant, err
+}
+
+// Scans one row of the paymentIntentColumns
+func scanPaymentIntent(row interface{ Scan(dest ...any) error }) (PaymentIntent, error) {
+	var intent PaymentIntent
+	err := row.Scan(&intent.IntentID, &intent.MerchantID, &intent.Amount, &intent.Description, &intent.Reference, &intent.Status,
+		&intent.ExpiresAt, &intent.CustomerUserID, &intent.TxHash, &intent.CreatedAt, &intent.CompletedAt)
+	return intent, err
+}
+
+func scanSettlements(rows *sql.Rows) ([]MerchantSettlement, error) {
+	defer rows.Close()
+
+	settlements := []MerchantSettlement{}
+	for rows.Next() {
+		var settlement MerchantSettlement
+		err := rows.Scan(&settlement.MerchantID, &settlement.SettlementDate, &settlement.PaymentCount, &settlement.GrossAmount, &settlement.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error reading settlements: %v", err)
+		}
+		settlements = append(settlements, settlement)
+	}
+	return settlements, rows.Err()
+}
+
+// Scans one row of the webhookEventColumns
+func scanWebhookEvent(row interface{ Scan(dest ...any) error }) (WebhookEvent, error) {
+	var event WebhookEvent
+	var payload []byte
+	err := row.Scan(&event.EventID, &event.MerchantID, &event.EventType, &payload, &event.Status, &event.Attempts,
+		&event.NextAttemptAt, &event.LastError, &event.CreatedAt, &event.DeliveredAt)
+	event.Payload = payload
+	return event, err
+}
+
+func scanWebhookEvents(rows *sql.Rows) ([]WebhookEvent, error) {
+	defer rows.Close()
+
+	events := []WebhookEvent{}
+	for rows.Next() {
+		event, err := scanWebhookEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error reading webhook events: %v", err)
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
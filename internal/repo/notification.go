@@ -0,0 +1,295 @@
+package repo
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+	"github.com/lib/pq"
+)
+
+// DeviceToken Regular struct
+type DeviceToken struct {
+	Token    string
+	UserID   string
+	Platform string
+}
+
+// NotificationPreferences holds the channels a user wants to be notified on
+type NotificationPreferences struct {
+	UserID          string
+	EmailEnabled    bool
+	PushEnabled     bool
+	SMSEnabled      bool
+	MutedCategories []string
+	DeliveryMode    string
+	Locale          string
+}
+
+// DigestItem is a notification held back for the user's daily digest
+type DigestItem struct {
+	UserID    string
+	Category  string
+	Title     string
+	Body      string
+	CreatedAt time.Time
+}
+
+// InboxNotification is a notification as it was delivered to the user, already translated
+type InboxNotification struct {
+	NotificationID string            `json:"notification_id"`
+	UserID         string            `json:"-"`
+	Category       string            `json:"category"`
+	Title          string            `json:"title"`
+	Body           string            `json:"body"`
+	Data           map[string]string `json:"data,omitempty"`
+	CreatedAt      time.Time         `json:"created_at"`
+}
+
+// All Notification Queries
+const (
+	upsertDeviceTokenQuery = `INSERT INTO device_tokens (device_token, user_id, platform) VALUES ($1, $2, $3)
+		ON CONFLICT (device_token) DO UPDATE SET user_id = EXCLUDED.user_id, platform = EXCLUDED.platform, last_seen_at = NOW()`
+	deleteUserDeviceTokenQuery         = `DELETE FROM device_tokens WHERE device_token = $1 AND user_id = $2`
+	deleteDeviceTokenQuery             = `DELETE FROM device_tokens WHERE device_token = $1`
+	getDeviceTokensQuery               = `SELECT device_token, user_id, platform FROM device_tokens WHERE user_id = $1`
+	getNotificationPreferencesQuery    = `SELECT user_id, email_enabled, push_enabled, sms_enabled, muted_categories, delivery_mode, locale FROM notification_preferences WHERE user_id = $1`
+	upsertNotificationPreferencesQuery = `INSERT INTO notification_preferences (user_id, email_enabled, push_enabled, sms_enabled, muted_categories, delivery_mode, locale, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+		ON CONFLICT (user_id) DO UPDATE SET email_enabled = EXCLUDED.email_enabled, push_enabled = EXCLUDED.push_enabled, sms_enabled = EXCLUDED.sms_enabled,
+		muted_categories = EXCLUDED.muted_categories, delivery_mode = EXCLUDED.delivery_mode, locale = EXCLUDED.locale, updated_at = NOW()`
+	insertDigestItemQuery = `INSERT INTO notification_digest_queue (user_id, category, title, body) VALUES ($1, $2, $3, $4)`
+	// Users whose local time at $1 falls in the first $3 minutes of hour $2
+	getDigestUsersQuery = `SELECT DISTINCT q.user_id FROM notification_digest_queue q INNER JOIN users u ON q.user_id = u.user_id
+		WHERE EXTRACT(HOUR FROM $1::TIMESTAMPTZ AT TIME ZONE u.time_zone) = $2 AND EXTRACT(MINUTE FROM $1::TIMESTAMPTZ AT TIME ZONE u.time_zone) < $3`
+	takeUserDigestItemsQuery = `DELETE FROM notification_digest_queue WHERE user_id = $1 RETURNING user_id, category, title, body, created_at`
+
+	insertInboxNotificationQuery  = `INSERT INTO notification_inbox (user_id, category, title, body, data) VALUES ($1, $2, $3, $4, $5)`
+	selectInboxNotificationsQuery = `SELECT notification_id, user_id, category, title, body, data, created_at FROM notification_inbox`
+	// Like transaction changes, a notification is only listed once its insert surely committed
+	settledInboxCondition         = `created_at < NOW() - INTERVAL '5 seconds'`
+	deleteInboxNotificationsQuery = `DELETE FROM notification_inbox WHERE created_at < $1`
+)
+
+type notificationRepo struct {
+	DB *sql.DB
+}
+
+type NotificationStorer interface {
+	UpsertDeviceToken(token DeviceToken) error
+	DeleteUserDeviceToken(userID, token string) error
+	DeleteDeviceToken(token string) error
+	GetDeviceTokens(userID string) ([]DeviceToken, error)
+	GetNotificationPreferences(userID string) (NotificationPreferences, error)
+	UpsertNotificationPreferences(preferences NotificationPreferences) error
+	QueueDigestItem(item DigestItem) error
+	GetDigestUserIDs(at time.Time, hour int, window time.Duration) ([]string, error)
+	TakeDigestItems(userID string) ([]DigestItem, error)
+	AddInboxNotification(notification InboxNotification) error
+	GetInboxNotificationsAfter(userID string, cursor *ChangeCursor, limit int) ([]InboxNotification, error)
+	DeleteInboxNotificationsBefore(cutoff time.Time) (int64, error)
+}
+
+// Constructor function
+func NewNotificationRepo(db *sql.DB) NotificationStorer {
+	return &notificationRepo{DB: db}
+}
+
+// Registers a device token for the user, moving it over if another user registered it before
+func (repoDep *notificationRepo) UpsertDeviceToken(token DeviceToken) error {
+	_, err := repoDep.DB.Exec(upsertDeviceTokenQuery, token.Token, token.UserID, token.Platform)
+	if err != nil {
+		log.Printf("Error registering device token: %v", err)
+		return fmt.Errorf("error registering device token: %v", err)
+	}
+	return nil
+}
+
+// Removes one of the user's device tokens
+func (repoDep *notificationRepo) DeleteUserDeviceToken(userID, token string) error {
+	result, err := repoDep.DB.Exec(deleteUserDeviceTokenQuery, token, userID)
+	if err != nil {
+		log.Printf("Error deleting device token: %v", err)
+		return fmt.Errorf("error deleting device token: %v", err)
+	}
+	if rowsAffected, err := result.RowsAffected(); err == nil && rowsAffected == 0 {
+		return utils.NotFound("device token not found", nil)
+	}
+	return nil
+}
+
+// Removes a token the push provider reported as no longer valid
+func (repoDep *notificationRepo) DeleteDeviceToken(token string) error {
+	_, err := repoDep.DB.Exec(deleteDeviceTokenQuery, token)
+	if err != nil {
+		log.Printf("Error deleting device token: %v", err)
+		return fmt.Errorf("error deleting device token: %v", err)
+	}
+	return nil
+}
+
+// Returns every device registered by the user
+func (repoDep *notificationRepo) GetDeviceTokens(userID string) ([]DeviceToken, error) {
+	rows, err := repoDep.DB.Query(getDeviceTokensQuery, userID)
+	if err != nil {
+		log.Printf("Error executing query: %v", err)
+		return nil, fmt.Errorf("error fetching device tokens: %v", err)
+	}
+	defer rows.Close()
+
+	var tokens []DeviceToken
+	for rows.Next() {
+		var token DeviceToken
+		if err := rows.Scan(&token.Token, &token.UserID, &token.Platform); err != nil {
+			return nil, fmt.Errorf("error reading device tokens: %v", err)
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+// Returns the user's channel preferences, falling back to the defaults when none are stored
+func (repoDep *notificationRepo) GetNotificationPreferences(userID string) (NotificationPreferences, error) {
+	preferences := NotificationPreferences{UserID: userID, EmailEnabled: true, PushEnabled: true, MutedCategories: []string{}, DeliveryMode: "immediate", Locale: "en"}
+
+	err := repoDep.DB.QueryRow(getNotificationPreferencesQuery, userID).Scan(&preferences.UserID, &preferences.EmailEnabled, &preferences.PushEnabled, &preferences.SMSEnabled, pq.Array(&preferences.MutedCategories), &preferences.DeliveryMode, &preferences.Locale)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("Error executing query: %v", err)
+		return preferences, fmt.Errorf("error fetching notification preferences: %v", err)
+	}
+	return preferences, nil
+}
+
+// Stores the user's channel preferences
+func (repoDep *notificationRepo) UpsertNotificationPreferences(preferences NotificationPreferences) error {
+	_, err := repoDep.DB.Exec(upsertNotificationPreferencesQuery, preferences.UserID, preferences.EmailEnabled, preferences.PushEnabled, preferences.SMSEnabled, pq.Array(preferences.MutedCategories), preferences.DeliveryMode, preferences.Locale)
+	if err != nil {
+		log.Printf("Error updating notification preferences: %v", err)
+		return fmt.Errorf("error updating notification preferences: %v", err)
+	}
+	return nil
+}
+
+// Holds a notification back until the next digest run
+func (repoDep *notificationRepo) QueueDigestItem(item DigestItem) error {
+	_, err := repoDep.DB.Exec(insertDigestItemQuery, item.UserID, item.Category, item.Title, item.Body)
+	if err != nil {
+		log.Printf("Error queueing digest item: %v", err)
+		return fmt.Errorf("error queueing digest item: %v", err)
+	}
+	return nil
+}
+
+// Returns the users that have notifications waiting for a digest and whose local time at the given
+// instant is within window of the start of hour
+func (repoDep *notificationRepo) GetDigestUserIDs(at time.Time, hour int, window time.Duration) ([]string, error) {
+	rows, err := repoDep.DB.Query(getDigestUsersQuery, at, hour, int(window.Minutes()))
+	if err != nil {
+		log.Printf("Error executing query: %v", err)
+		return nil, fmt.Errorf("error fetching digest users: %v", err)
+	}
+	defer rows.Close()
+
+	var userIDs []string
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("error reading digest users: %v", err)
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
+
+// Removes and returns every queued digest item of the user, oldest first
+func (repoDep *notificationRepo) TakeDigestItems(userID string) ([]DigestItem, error) {
+	rows, err := repoDep.DB.Query(takeUserDigestItemsQuery, userID)
+	if err != nil {
+		log.Printf("Error executing query: %v", err)
+		return nil, fmt.Errorf("error taking digest items: %v", err)
+	}
+	defer rows.Close()
+
+	var items []DigestItem
+	for rows.Next() {
+		var item DigestItem
+		if err := rows.Scan(&item.UserID, &item.Category, &item.Title, &item.Body, &item.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error reading digest items: %v", err)
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(items, func(i, j int) bool { return items[i].CreatedAt.Before(items[j].CreatedAt) })
+	return items, nil
+}
+
+// Keeps a delivered notification in the user's inbox
+func (repoDep *notificationRepo) AddInboxNotification(notification InboxNotification) error {
+	data := []byte("{}")
+	if notification.Data != nil {
+		var err error
+		if data, err = json.Marshal(notification.Data); err != nil {
+			return fmt.Errorf("error encoding notification data: %v", err)
+		}
+	}
+
+	_, err := repoDep.DB.Exec(insertInboxNotificationQuery, notification.UserID, notification.Category, notification.Title, notification.Body, data)
+	if err != nil {
+		log.Printf("Error storing inbox notification: %v", err)
+		return fmt.Errorf("error storing inbox notification: %v", err)
+	}
+	return nil
+}
+
+// Returns the user's inbox notifications created after the cursor, oldest first. A nil cursor starts
+// from the oldest notification kept.
+func (repoDep *notificationRepo) GetInboxNotificationsAfter(userID string, cursor *ChangeCursor, limit int) ([]InboxNotification, error) {
+	q := newSelectQuery(selectInboxNotificationsQuery).
+		where("user_id = ?", userID).
+		where(settledInboxCondition)
+	if cursor != nil {
+		q.where("(created_at, notification_id) > (?, ?)", cursor.ChangedAt, cursor.ID)
+	}
+	query, args := q.order("created_at, notification_id").page(limit, 0).build()
+
+	rows, err := repoDep.DB.Query(query, args...)
+	if err != nil {
+		log.Printf("Error executing query: %v", err)
+		return nil, fmt.Errorf("error fetching inbox notifications: %v", err)
+	}
+	defer rows.Close()
+
+	notifications := []InboxNotification{}
+	for rows.Next() {
+		var notification InboxNotification
+		var data []byte
+		if err := rows.Scan(&notification.NotificationID, &notification.UserID, &notification.Category, &notification.Title, &notification.Body, &data, &notification.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error reading inbox notifications: %v", err)
+		}
+		if err := json.Unmarshal(data, &notification.Data); err != nil {
+			return nil, fmt.Errorf("error decoding notification data: %v", err)
+		}
+		notifications = append(notifications, notification)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading inbox notifications: %v", err)
+	}
+	return notifications, nil
+}
+
+// Removes the inbox notifications created before cutoff
+func (repoDep *notificationRepo) DeleteInboxNotificationsBefore(cutoff time.Time) (int64, error) {
+	result, err := repoDep.DB.Exec(deleteInboxNotificationsQuery, cutoff)
+	if err != nil {
+		log.Printf("Error deleting old inbox notifications: %v", err)
+		return 0, fmt.Errorf("error deleting inbox notifications: %v", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,308 @@
+package repo
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+	"github.com/lib/pq"
+)
+
+// Organization is a business account owning its own wallet
+type Organization struct {
+	OrgID             string
+	Name              string
+	OwnerUserID       string
+	WalletID          string
+	ApprovalsRequired int
+	CreatedAt         time.Time
+}
+
+// OrganizationMember is a user's membership of an organization
+type OrganizationMember struct {
+	OrgID       string
+	UserID      string
+	Permissions []string
+	Status      string
+	InvitedBy   string
+	CreatedAt   time.Time
+}
+
+// OrganizationPayment is a transfer out of the organization wallet waiting for or past its approvals
+type OrganizationPayment struct {
+	PaymentID         string
+	OrgID             string
+	InitiatedBy       string
+	RecipientUserID   string
+	RecipientWalletID string
+	Amount            string
+	Status            string
+	TxHash            string
+	RejectedBy        string
+	Approvals         []string
+	CreatedAt         time.Time
+	CompletedAt       *time.Time
+}
+
+// All Organization Queries
+const (
+	insertOrganizationQuery = `INSERT INTO organizations (name, owner_user_id, wallet_id, approvals_required) VALUES ($1, $2, $3, $4)
+		RETURNING org_id, created_at`
+	insertOwnerMemberQuery    = `INSERT INTO organization_members (org_id, user_id, permissions, status, invited_by) VALUES ($1, $2, $3, 'active', $2)`
+	getOrganizationQuery      = `SELECT org_id, name, owner_user_id, wallet_id, approvals_required, created_at FROM organizations WHERE org_id = $1`
+	getUserOrganizationsQuery = `SELECT o.org_id, o.name, o.owner_user_id, o.wallet_id, o.approvals_required, o.created_at FROM organizations o
+		INNER JOIN organization_members m ON o.org_id = m.org_id WHERE m.user_id = $1 AND m.status = 'active' ORDER BY o.created_at`
+	getOrganizationMemberQuery = `SELECT org_id, user_id, permissions, status, COALESCE(invited_by::TEXT, ''), created_at
+		FROM organization_members WHERE org_id = $1 AND user_id = $2`
+	getOrganizationMembersQuery = `SELECT org_id, user_id, permissions, status, COALESCE(invited_by::TEXT, ''), created_at
+		FROM organization_members WHERE org_id = $1 ORDER BY created_at`
+	// Re-inviting an active member only changes their permissions
+	upsertOrganizationMemberQuery = `INSERT INTO organization_members (org_id, user_id, permissions, invited_by) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (org_id, user_id) DO UPDATE SET permissions = EXCLUDED.permissions
+		RETURNING org_id, user_id, permissions, status, COALESCE(invited_by::TEXT, ''), created_at`
+	activateOrganizationMemberQuery = `UPDATE organization_members SET status = 'active' WHERE org_id = $1 AND user_id = $2 AND status = 'invited'`
+	deleteOrganizationMemberQuery   = `DELETE FROM organization_members WHERE org_id = $1 AND user_id = $2`
+	insertOrganizationPaymentQuery  = `INSERT INTO organization_payments (org_id, initiated_by, recipient_user_id, recipient_wallet_id, amount)
+		VALUES ($1, $2, $3, $4, $5::NUMERIC) RETURNING payment_id, status, created_at`
+	organizationPaymentColumns = `p.payment_id, p.org_id, p.initiated_by, p.recipient_user_id, p.recipient_wallet_id, p.amount::TEXT, p.status,
+		COALESCE(p.tx_hash, ''), COALESCE(p.rejected_by::TEXT, ''),
+		ARRAY(SELECT a.user_id::TEXT FROM organization_payment_approvals a WHERE a.payment_id = p.payment_id ORDER BY a.approved_at),
+		p.created_at, p.completed_at`
+	getOrganizationPaymentQuery  = `SELECT ` + organizationPaymentColumns + ` FROM organization_payments p WHERE p.org_id = $1 AND p.payment_id = $2`
+	getOrganizationPaymentsQuery = `SELECT ` + organizationPaymentColumns + ` FROM organization_payments p WHERE p.org_id = $1
+		ORDER BY p.created_at DESC LIMIT $2`
+	insertPaymentApprovalQuery = `INSERT INTO organization_payment_approvals (payment_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+	countPaymentApprovalsQuery = `SELECT COUNT(*) FROM organization_payment_approvals WHERE payment_id = $1`
+	// Moves a payment on only from the expected status, so concurrent approvals execute it once
+	updatePaymentStatusQuery = `UPDATE organization_payments SET status = $3, tx_hash = NULLIF($4, ''), rejected_by = NULLIF($5, '')::UUID,
+		completed_at = CASE WHEN $3 IN ('executed', 'rejected', 'failed') THEN NOW() ELSE completed_at END
+		WHERE payment_id = $1 AND status = $2`
+)
+
+type organizationRepo struct {
+	DB *sql.DB
+}
+
+type OrganizationStorer interface {
+	CreateOrganization(org Organization, ownerPermissions []string) (Organization, error)
+	GetOrganization(orgID string) (Organization, error)
+	GetUserOrganizations(userID string) ([]Organization, error)
+	GetMember(orgID, userID string) (OrganizationMember, error)
+	GetMembers(orgID string) ([]OrganizationMember, error)
+	UpsertMember(member OrganizationMember) (OrganizationMember, error)
+	ActivateMember(orgID, userID string) error
+	RemoveMember(orgID, userID string) error
+	CreatePayment(payment OrganizationPayment) (OrganizationPayment, error)
+	GetPayment(orgID, paymentID string) (OrganizationPayment, error)
+	GetPayments(orgID string, limit int) ([]OrganizationPayment, error)
+	ApprovePayment(paymentID, userID string) (int, error)
+	UpdatePaymentStatus(paymentID, fromStatus, toStatus, txHash, rejectedBy string) (bool, error)
+}
+
+// Constructor function
+func NewOrganizationRepo(db *sql.DB) OrganizationStorer {
+	return &organizationRepo{DB: db}
+}
+
+// Creates the organization with its owner as the first active member in one transaction
+func (repoDep *organizationRepo) CreateOrganization(org Organization, ownerPermissions []string) (Organization, error) {
+	tx, err := repoDep.DB.Begin()
+	if err != nil {
+		return org, fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := tx.QueryRow(insertOrganizationQuery, org.Name, org.OwnerUserID, org.WalletID, org.ApprovalsRequired).Scan(&org.OrgID, &org.CreatedAt); err != nil {
+		log.Printf("Error creating organization %s: %v", org.Name, err)
+		return org, fmt.Errorf("error creating organization: %v", err)
+	}
+	if _, err := tx.Exec(insertOwnerMemberQuery, org.OrgID, org.OwnerUserID, pq.Array(ownerPermissions)); err != nil {
+		log.Printf("Error adding the owner of organization %s: %v", org.OrgID, err)
+		return org, fmt.Errorf("error adding organization owner: %v", err)
+	}
+
+	return org, tx.Commit()
+}
+
+// Returns an organization
+func (repoDep *organizationRepo) GetOrganization(orgID string) (Organization, error) {
+	org, err := scanOrganization(repoDep.DB.QueryRow(getOrganizationQuery, orgID))
+	if err != nil {
+		return org, utils.FromDBError("organization", err)
+	}
+	return org, nil
+}
+
+// Returns the organizations the user is an active member of
+func (repoDep *organizationRepo) GetUserOrganizations(userID string) ([]Organization, error) {
+	rows, err := repoDep.DB.Query(getUserOrganizationsQuery, userID)
+	if err != nil {
+		log.Printf("Error executing query: %v", err)
+		return nil, fmt.Errorf("error fetching organizations: %v", err)
+	}
+	defer rows.Close()
+
+	var orgs []Organization
+	for rows.Next() {
+		org, err := scanOrganization(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error reading organizations: %v", err)
+		}
+		orgs = append(orgs, org)
+	}
+	return orgs, rows.Err()
+}
+
+// Returns the membership of a user, invited or active
+func (repoDep *organizationRepo) GetMember(orgID, userID string) (OrganizationMember, error) {
+	member, err := scanOrganizationMember(repoDep.DB.QueryRow(getOrganizationMemberQuery, orgID, userID))
+	if err != nil {
+		return member, utils.FromDBError("organization member", err)
+	}
+	return member, nil
+}
+
+// Returns every member of an organization, invited ones included
+func (repoDep *organizationRepo) GetMembers(orgID string) ([]OrganizationMember, error) {
+	rows, err := repoDep.DB.Query(getOrganizationMembersQuery, orgID)
+	if err != nil {
+		log.Printf("Error executing query: %v", err)
+		return nil, fmt.Errorf("error fetching organization members: %v", err)
+	}
+	defer rows.Close()
+
+	var members []OrganizationMember
+	for rows.Next() {
+		member, err := scanOrganizationMember(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error reading organization members: %v", err)
+		}
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
+
+// Invites a user, or replaces the permissions of an existing member
+func (repoDep *organizationRepo) UpsertMember(member OrganizationMember) (OrganizationMember, error) {
+	saved, err := scanOrganizationMember(repoDep.DB.QueryRow(upsertOrganizationMemberQuery, member.OrgID, member.UserID, pq.Array(member.Permissions), member.InvitedBy))
+	if err != nil {
+		log.Printf("Error saving member %s of organization %s: %v", member.UserID, member.OrgID, err)
+		return saved, fmt.Errorf("error saving organization member: %v", err)
+	}
+	return saved, nil
+}
+
+// Accepts a pending invitation
+func (repoDep *organizationRepo) ActivateMember(orgID, userID string) error {
+	result, err := repoDep.DB.Exec(activateOrganizationMemberQuery, orgID, userID)
+	if err != nil {
+		log.Printf("Error accepting invitation to organization %s: %v", orgID, err)
+		return fmt.Errorf("error accepting invitation: %v", err)
+	}
+	if rowsAffected, err := result.RowsAffected(); err != nil || rowsAffected == 0 {
+		return utils.NotFound("invitation not found", nil)
+	}
+	return nil
+}
+
+// Removes a member or withdraws an invitation
+func (repoDep *organizationRepo) RemoveMember(orgID, userID string) error {
+	result, err := repoDep.DB.Exec(deleteOrganizationMemberQuery, orgID, userID)
+	if err != nil {
+		log.Printf("Error removing member %s of organization %s: %v", userID, orgID, err)
+		return fmt.Errorf("error removing organization member: %v", err)
+	}
+	if rowsAffected, err := result.RowsAffected(); err != nil || rowsAffected == 0 {
+		return utils.NotFound("organization member not found", nil)
+	}
+	return nil
+}
+
+// Records a payment waiting for approval
+func (repoDep *organizationRepo) CreatePayment(payment OrganizationPayment) (OrganizationPayment, error) {
+	err := repoDep.DB.QueryRow(insertOrganizationPaymentQuery, payment.OrgID, payment.InitiatedBy, payment.RecipientUserID, payment.RecipientWalletID, payment.Amount).
+		Scan(&payment.PaymentID, &payment.Status, &payment.CreatedAt)
+	if err != nil {
+		log.Printf("Error creating payment of organization %s: %v", payment.OrgID, err)
+		return payment, fmt.Errorf("error creating organization payment: %v", err)
+	}
+	return payment, nil
+}
+
+// Returns a payment of the organization with its approvers
+func (repoDep *organizationRepo) GetPayment(orgID, paymentID string) (OrganizationPayment, error) {
+	payment, err := scanOrganizationPayment(repoDep.DB.QueryRow(getOrganizationPaymentQuery, orgID, paymentID))
+	if err != nil {
+		return payment, utils.FromDBError("organization payment", err)
+	}
+	return payment, nil
+}
+
+// Returns the latest payments of the organization, newest first
+func (repoDep *organizationRepo) GetPayments(orgID string, limit int) ([]OrganizationPayment, error) {
+	rows, err := repoDep.DB.Query(getOrganizationPaymentsQuery, orgID, limit)
+	if err != nil {
+		log.Printf("Error executing query: %v", err)
+		return nil, fmt.Errorf("error fetching organization payments: %v", err)
+	}
+	defer rows.Close()
+
+	var payments []OrganizationPayment
+	for rows.Next() {
+		payment, err := scanOrganizationPayment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error reading organization payments: %v", err)
+		}
+		payments = append(payments, payment)
+	}
+	return payments, rows.Err()
+}
+
+// Records the approval of a user and returns the number of approvals of the payment
+func (repoDep *organizationRepo) ApprovePayment(paymentID, userID string) (int, error) {
+	if _, err := repoDep.DB.Exec(insertPaymentApprovalQuery, paymentID, userID); err != nil {
+		log.Printf("Error approving payment %s: %v", paymentID, err)
+		return 0, fmt.Errorf("error approving payment: %v", err)
+	}
+
+	var approvals int
+	if err := repoDep.DB.QueryRow(countPaymentApprovalsQuery, paymentID).Scan(&approvals); err != nil {
+		return 0, fmt.Errorf("error counting payment approvals: %v", err)
+	}
+	return approvals, nil
+}
+
+// Moves a payment from fromStatus to toStatus and reports whether it was still in fromStatus
+func (repoDep *organizationRepo) UpdatePaymentStatus(paymentID, fromStatus, toStatus, txHash, rejectedBy string) (bool, error) {
+	result, err := repoDep.DB.Exec(updatePaymentStatusQuery, paymentID, fromStatus, toStatus, txHash, rejectedBy)
+	if err != nil {
+		log.Printf("Error updating payment %s to %s: %v", paymentID, toStatus, err)
+		return false, fmt.Errorf("error updating payment status: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error updating payment status: %v", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+func scanOrganization(row interface{ Scan(dest ...any) error }) (Organization, error) {
+	var org Organization
+	err := row.Scan(&org.OrgID, &org.Name, &org.OwnerUserID, &org.WalletID, &org.ApprovalsRequired, &org.CreatedAt)
+	return org, err
+}
+
+func scanOrganizationMember(row interface{ Scan(dest ...any) error }) (OrganizationMember, error) {
+	var member OrganizationMember
+	err := row.Scan(&member.OrgID, &member.UserID, pq.Array(&member.Permissions), &member.Status, &member.InvitedBy, &member.CreatedAt)
+	return member, err
+}
+
+func scanOrganizationPayment(row interface{ Scan(dest ...any) error }) (OrganizationPayment, error) {
+	var payment OrganizationPayment
+	err := row.Scan(&payment.PaymentID, &payment.OrgID, &payment.InitiatedBy, &payment.RecipientUserID, &payment.RecipientWalletID,
+		&payment.Amount, &payment.Status, &payment.TxHash, &payment.RejectedBy, pq.Array(&payment.Approvals), &payment.CreatedAt, &payment.CompletedAt)
+	return payment, err
+}
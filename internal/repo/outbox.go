@@ -0,0 +1,121 @@
+package repo
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+)
+
+// OutboxEvent is an event waiting to be published to the subscribers of its topic. Payload is the
+// JSON data of the event.
+type OutboxEvent struct {
+	EventID     string
+	Topic       string
+	Payload     json.RawMessage
+	Attempts    int
+	LastError   string
+	CreatedAt   time.Time
+	PublishedAt *time.Time
+}
+
+// All Outbox Queries
+const (
+	outboxEventColumns     = `event_id, topic, payload, attempts, last_error, created_at, published_at`
+	insertOutboxEventQuery = `INSERT INTO outbox_events (topic, payload) VALUES ($1, $2)`
+	// Unpublished events are leased for a while so that concurrent relays do not publish them twice
+	claimOutboxEventsQuery = `UPDATE outbox_events SET next_attempt_at = NOW() + $2 * INTERVAL '1 second'
+		WHERE event_id IN (SELECT event_id FROM outbox_events WHERE published_at IS NULL AND next_attempt_at <= NOW()
+			ORDER BY created_at LIMIT $1 FOR UPDATE SKIP LOCKED)
+		RETURNING ` + outboxEventColumns
+	markOutboxEventPublishedQuery = `UPDATE outbox_events SET published_at = NOW(), attempts = attempts + 1, last_error = '' WHERE event_id = $1`
+	recordOutboxFailureQuery      = `UPDATE outbox_events SET attempts = attempts + 1, last_error = $2, next_attempt_at = $3 WHERE event_id = $1`
+	deletePublishedOutboxQuery    = `DELETE FROM outbox_events WHERE published_at < $1`
+)
+
+type outboxRepo struct {
+	DB *sql.DB
+}
+
+type OutboxStorer interface {
+	AddOutboxEvent(event OutboxEvent) error
+	ClaimOutboxEvents(limit int, lease time.Duration) ([]OutboxEvent, error)
+	MarkOutboxEventPublished(eventID string) error
+	RecordOutboxFailure(eventID, lastError string, nextAttemptAt time.Time) error
+	DeletePublishedOutboxEvents(cutoff time.Time) (int64, error)
+}
+
+// Constructor function
+func NewOutboxRepo(db *sql.DB) OutboxStorer {
+	return &outboxRepo{DB: db}
+}
+
+// Stores an event on its own, for events that do not belong to a change of another repo
+func (repoDep *outboxRepo) AddOutboxEvent(event OutboxEvent) error {
+	return insertOutboxEvents(repoDep.DB, []OutboxEvent{event})
+}
+
+// Leases up to limit unpublished events, oldest first
+func (repoDep *outboxRepo) ClaimOutboxEvents(limit int, lease time.Duration) ([]OutboxEvent, error) {
+	rows, err := repoDep.DB.Query(claimOutboxEventsQuery, limit, int(lease.Seconds()))
+	if err != nil {
+		log.Printf("Error claiming outbox events: %v", err)
+		return nil, fmt.Errorf("error claiming outbox events: %v", err)
+	}
+	defer rows.Close()
+
+	events := []OutboxEvent{}
+	for rows.Next() {
+		var event OutboxEvent
+		var payload []byte
+		if err := rows.Scan(&event.EventID, &event.Topic, &payload, &event.Attempts, &event.LastError, &event.CreatedAt,
+			&event.PublishedAt); err != nil {
+			return nil, fmt.Errorf("error reading outbox events: %v", err)
+		}
+		event.Payload = payload
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// Records that every subscriber handled an event
+func (repoDep *outboxRepo) MarkOutboxEventPublished(eventID string) error {
+	if _, err := repoDep.DB.Exec(markOutboxEventPublishedQuery, eventID); err != nil {
+		log.Printf("Error marking outbox event %s published: %v", eventID, err)
+		return fmt.Errorf("error updating outbox event: %v", err)
+	}
+	return nil
+}
+
+// Records a failed publication, the event is published again at nextAttemptAt
+func (repoDep *outboxRepo) RecordOutboxFailure(eventID, lastError string, nextAttemptAt time.Time) error {
+	if _, err := repoDep.DB.Exec(recordOutboxFailureQuery, eventID, lastError, nextAttemptAt); err != nil {
+		log.Printf("Error recording failure of outbox event %s: %v", eventID, err)
+		return fmt.Errorf("error updating outbox event: %v", err)
+	}
+	return nil
+}
+
+// Removes events published before cutoff
+func (repoDep *outboxRepo) DeletePublishedOutboxEvents(cutoff time.Time) (int64, error) {
+	result, err := repoDep.DB.Exec(deletePublishedOutboxQuery, cutoff)
+	if err != nil {
+		log.Printf("Error deleting published outbox events: %v", err)
+		return 0, fmt.Errorf("error deleting published outbox events: %v", err)
+	}
+	return result.RowsAffected()
+}
+
+// insertOutboxEvents stores events through db, a transaction when they announce a change made in it
+func insertOutboxEvents(db interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}, events []OutboxEvent) error {
+	for _, event := range events {
+		if _, err := db.Exec(insertOutboxEventQuery, event.Topic, []byte(event.Payload)); err != nil {
+			log.Printf("Error storing %s outbox event: %v", event.Topic, err)
+			return fmt.Errorf("error storing outbox event: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package repo
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+// PaymentRequest asks for a payment of Amount wei to the requester, PaidBy and TxHash are set
+// once a payer picked it up. Requests of a bill carry its BillID and can only be paid by PayerUserID.
+type PaymentRequest struct {
+	RequestID       string
+	RequesterUserID string
+	BillID          string
+	PayerUserID     string
+	Amount          string
+	Memo            string
+	Status          string
+	ExpiresAt       time.Time
+	PaidBy          string
+	TxHash          string
+	CreatedAt       time.Time
+	PaidAt          *time.Time
+}
+
+// All Payment Request Queries
+const (
+	paymentRequestColumns = `request_id, requester_user_id, COALESCE(bill_id::TEXT, ''), COALESCE(payer_user_id::TEXT, ''),
+		amount::TEXT, memo, status, expires_at, COALESCE(paid_by::TEXT, ''), COALESCE(tx_hash, ''), created_at, paid_at`
+	insertPaymentRequestQuery = `INSERT INTO payment_requests (requester_user_id, bill_id, payer_user_id, amount, memo, expires_at)
+		VALUES ($1, NULLIF($2, '')::UUID, NULLIF($3, '')::UUID, $4::NUMERIC, $5, $6)
+		RETURNING ` + paymentRequestColumns
+	getPaymentRequestQuery  = `SELECT ` + paymentRequestColumns + ` FROM payment_requests WHERE request_id = $1`
+	getPaymentRequestsQuery = `SELECT ` + paymentRequestColumns + ` FROM payment_requests WHERE requester_user_id = $1
+		ORDER BY created_at DESC LIMIT $2`
+	getIncomingPaymentRequestsQuery = `SELECT ` + paymentRequestColumns + ` FROM payment_requests WHERE payer_user_id = $1
+		ORDER BY created_at DESC LIMIT $2`
+	getBillPaymentRequestsQuery = `SELECT ` + paymentRequestColumns + ` FROM payment_requests WHERE bill_id = $1 ORDER BY created_at, request_id`
+	// Only one payer can claim an open request, the claim is released again if their transfer fails
+	claimPaymentRequestQuery = `UPDATE payment_requests SET status = 'paying', paid_by = $2
+		WHERE request_id = $1 AND status = 'open' AND expires_at > NOW() AND (payer_user_id IS NULL OR payer_user_id = $2)`
+	releasePaymentRequestQuery = `UPDATE payment_requests SET status = 'open', paid_by = NULL
+		WHERE request_id = $1 AND status = 'paying'`
+	completePaymentRequestQuery = `UPDATE payment_requests SET status = 'paid', tx_hash = $2, paid_at = NOW()
+		WHERE request_id = $1 AND status = 'paying'`
+	cancelPaymentRequestQuery = `UPDATE payment_requests SET status = 'cancelled'
+		WHERE request_id = $1 AND requester_user_id = $2 AND status = 'open'`
+	cancelBillPaymentRequestsQuery = `UPDATE payment_requests SET status = 'cancelled' WHERE bill_id = $1 AND status = 'open'`
+)
+
+type paymentRequestRepo struct {
+	DB *sql.DB
+}
+
+type PaymentRequestStorer interface {
+	CreatePaymentRequest(request PaymentRequest) (PaymentRequest, error)
+	GetPaymentRequest(requestID string) (PaymentRequest, error)
+	GetPaymentRequests(requesterUserID string, limit int) ([]PaymentRequest, error)
+	GetIncomingPaymentRequests(payerUserID string, limit int) ([]PaymentRequest, error)
+	GetBillPaymentRequests(billID string) ([]PaymentRequest, error)
+	ClaimPaymentRequest(requestID, payerUserID string) (bool, error)
+	ReleasePaymentRequest(requestID string) error
+	CompletePaymentRequest(requestID, txHash string) error
+	CancelPaymentRequest(requestID, requesterUserID string) (bool, error)
+	CancelBillPaymentRequests(billID string) (int, error)
+}
+
+// Constructor function
+func NewPaymentRequestRepo(db *sql.DB) PaymentRequestStorer {
+	return &paymentRequestRepo{DB: db}
+}
+
+// Stores an open payment request
+func (repoDep *paymentRequestRepo) CreatePaymentRequest(request PaymentRequest) (PaymentRequest, error) {
+	created, err := scanPaymentRequest(repoDep.DB.QueryRow(insertPaymentRequestQuery, request.RequesterUserID, request.BillID, request.PayerUserID,
+		request.Amount, request.Memo, request.ExpiresAt))
+	if err != nil {
+		log.Printf("Error creating payment request of %s: %v", request.RequesterUserID, err)
+		return created, fmt.Errorf("error creating payment request: %v", err)
+	}
+	return created, nil
+}
+
+// Returns a payment request in any status
+func (repoDep *paymentRequestRepo) GetPaymentRequest(requestID string) (PaymentRequest, error) {
+	request, err := scanPaymentRequest(repoDep.DB.QueryRow(getPaymentRequestQuery, requestID))
+	if err != nil {
+		return request, utils.FromDBError("payment request", err)
+	}
+	return request, nil
+}
+
+// Returns the latest requests the user created, newest first
+func (repoDep *paymentRequestRepo) GetPaymentRequests(requesterUserID string, limit int) ([]PaymentRequest, error) {
+	return repoDep.queryPaymentRequests(getPaymentRequestsQuery, requesterUserID, limit)
+}
+
+// Returns the latest requests addressed to the user, newest first
+func (repoDep *paymentRequestRepo) GetIncomingPaymentRequests(payerUserID string, limit int) ([]PaymentRequest, error) {
+	return repoDep.queryPaymentRequests(getIncomingPaymentRequestsQuery, payerUserID, limit)
+}
+
+// Returns every request of a bill, one per participant
+func (repoDep *paymentRequestRepo) GetBillPaymentRequests(billID string) ([]PaymentRequest, error) {
+	return repoDep.queryPaymentRequests(getBillPaymentRequestsQuery, billID)
+}
+
+func (repoDep *paymentRequestRepo) queryPaymentRequests(query string, args ...any) ([]PaymentRequest, error) {
+	rows, err := repoDep.DB.Query(query, args...)
+	if err != nil {
+		log.Printf("Error executing query: %v", err)
+		return nil, fmt.Errorf("error fetching payment requests: %v", err)
+	}
+	defer rows.Close()
+
+	requests := []PaymentRequest{}
+	for rows.Next() {
+		request, err := scanPaymentRequest(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error reading payment requests: %v", err)
+		}
+		requests = append(requests, request)
+	}
+	return requests, rows.Err()
+}
+
+// Reserves an open, unexpired request for the payer and reports whether it was still open
+func (repoDep *paymentRequestRepo) ClaimPaymentRequest(requestID, payerUserID string) (bool, error) {
+	result, err := repoDep.DB.Exec(claimPaymentRequestQuery, requestID, payerUserID)
+	if err != nil {
+		log.Printf("Error claiming payment request %s: %v", requestID, err)
+		return false, fmt.Errorf("error claiming payment request: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error checking affected rows: %v", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+// Reopens a claimed request whose payment did not go through
+func (repoDep *paymentRequestRepo) ReleasePaymentRequest(requestID string) error {
+	if _, err := repoDep.DB.Exec(releasePaymentRequestQuery, requestID); err != nil {
+		log.Printf("Error releasing payment request %s: %v", requestID, err)
+		return fmt.Errorf("error releasing payment request: %v", err)
+	}
+	return nil
+}
+
+// Marks a claimed request paid by the broadcast transaction
+func (repoDep *paymentRequestRepo) CompletePaymentRequest(requestID, txHash string) error {
+	if _, err := repoDep.DB.Exec(completePaymentRequestQuery, requestID, txHash); err != nil {
+		log.Printf("Error completing payment request %s: %v", requestID, err)
+		return fmt.Errorf("error completing payment request: %v", err)
+	}
+	return nil
+}
+
+// Cancels an open request of the requester and reports whether it was still open
+func (repoDep *paymentRequestRepo) CancelPaymentRequest(requestID, requesterUserID string) (bool, error) {
+	result, err := repoDep.DB.Exec(cancelPaymentRequestQuery, requestID, requesterUserID)
+	if err != nil {
+		log.Printf("Error cancelling payment request %s: %v", requestID, err)
+		return false, fmt.Errorf("error cancelling payment request: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error checking affected rows: %v", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+// Cancels the requests of a bill that are still open and returns how many were cancelled
+func (repoDep *paymentRequestRepo) CancelBillPaymentRequests(billID string) (int, error) {
+	result, err := repoDep.DB.Exec(cancelBillPaymentRequestsQuery, billID)
+	if err != nil {
+		log.Printf("Error cancelling payment requests of bill %s: %v", billID, err)
+		return 0, fmt.Errorf("error cancelling payment requests: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("error checking affected rows: %v", err)
+	}
+	return int(rowsAffected), nil
+}
+
+// Scans one row of the paymentRequestColumns
+func scanPaymentRequest(row interface{ Scan(dest ...any) error }) (PaymentRequest, error) {
+	var request PaymentRequest
+	err := row.Scan(&request.RequestID, &request.RequesterUserID, &request.BillID, &request.PayerUserID, &request.Amount, &request.Memo, &request.Status, &request.ExpiresAt,
+		&request.PaidBy, &request.TxHash, &request.CreatedAt, &request.PaidAt)
+	return request, err
+}
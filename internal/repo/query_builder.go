@@ -0,0 +1,84 @@
+package repo
+
+import (
+	"fmt"
+	"strings"
+)
+
+// selectQuery assembles a parameterised SELECT statement. Conditions are written with
+// "?" placeholders which are renumbered to PostgreSQL's $n form on build, so values
+// always travel as arguments and never get concatenated into the SQL text.
+type selectQuery struct {
+	base       string
+	conditions []string
+	args       []interface{}
+	orderBy    string
+	limit      int
+	offset     int
+}
+
+// newSelectQuery starts a query from a "SELECT ... FROM ..." clause
+func newSelectQuery(base string) *selectQuery {
+	return &selectQuery{base: base}
+}
+
+// where adds a condition joined with AND to the previous ones
+func (q *selectQuery) where(condition string, args ...interface{}) *selectQuery {
+	q.conditions = append(q.conditions, "("+condition+")")
+	q.args = append(q.args, args...)
+	return q
+}
+
+// order sets the ORDER BY clause
+func (q *selectQuery) order(orderBy string) *selectQuery {
+	q.orderBy = orderBy
+	return q
+}
+
+// page sets LIMIT and OFFSET, zero values are left out of the query
+func (q *selectQuery) page(limit, offset int) *selectQuery {
+	q.limit = limit
+	q.offset = offset
+	return q
+}
+
+// build returns the final SQL text together with its positional arguments
+func (q *selectQuery) build() (string, []interface{}) {
+	var sb strings.Builder
+	args := append([]interface{}{}, q.args...)
+
+	sb.WriteString(q.base)
+	if len(q.conditions) > 0 {
+		sb.WriteString(" WHERE ")
+		sb.WriteString(strings.Join(q.conditions, " AND "))
+	}
+	if q.orderBy != "" {
+		sb.WriteString(" ORDER BY ")
+		sb.WriteString(q.orderBy)
+	}
+	if q.limit > 0 {
+		args = append(args, q.limit)
+		sb.WriteString(" LIMIT ?")
+	}
+	if q.offset > 0 {
+		args = append(args, q.offset)
+		sb.WriteString(" OFFSET ?")
+	}
+
+	return numberPlaceholders(sb.String()), args
+}
+
+// numberPlaceholders rewrites each "?" into $1, $2, ... in order of appearance
+func numberPlaceholders(query string) string {
+	var sb strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			sb.WriteString(fmt.Sprintf("$%d", n))
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
@@ -0,0 +1,50 @@
+package repo
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+// All Rate Limit Queries
+const (
+	incrementRateLimitCounterQuery = `INSERT INTO rate_limit_counters (limiter, client_key, window_start) VALUES ($1, $2, $3)
+		ON CONFLICT (limiter, client_key, window_start) DO UPDATE SET hits = rate_limit_counters.hits + 1
+		RETURNING hits`
+	deleteRateLimitCountersBeforeQuery = `DELETE FROM rate_limit_counters WHERE limiter = $1 AND window_start < $2`
+)
+
+type rateLimitRepo struct {
+	DB *sql.DB
+}
+
+type RateLimitStorer interface {
+	IncrementRateLimitCounter(limiter, clientKey string, windowStart time.Time) (int, error)
+	DeleteRateLimitCountersBefore(limiter string, cutoff time.Time) (int64, error)
+}
+
+// Constructor function
+func NewRateLimitRepo(db *sql.DB) RateLimitStorer {
+	return &rateLimitRepo{DB: db}
+}
+
+// Counts a request of the client in the window and returns the requests counted so far
+func (repoDep *rateLimitRepo) IncrementRateLimitCounter(limiter, clientKey string, windowStart time.Time) (int, error) {
+	var hits int
+	if err := repoDep.DB.QueryRow(incrementRateLimitCounterQuery, limiter, clientKey, windowStart).Scan(&hits); err != nil {
+		log.Printf("Error counting %s request of %s: %v", limiter, clientKey, err)
+		return 0, fmt.Errorf("error counting request: %v", err)
+	}
+	return hits, nil
+}
+
+// Removes the counters of the limiter's windows that started before cutoff
+func (repoDep *rateLimitRepo) DeleteRateLimitCountersBefore(limiter string, cutoff time.Time) (int64, error) {
+	result, err := repoDep.DB.Exec(deleteRateLimitCountersBeforeQuery, limiter, cutoff)
+	if err != nil {
+		log.Printf("Error deleting old %s rate limit counters: %v", limiter, err)
+		return 0, fmt.Errorf("error deleting rate limit counters: %v", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,64 @@
+package repo
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+// All Refresh Token Queries
+const (
+	insertRefreshTokenQuery = `INSERT INTO refresh_tokens (token_id, user_id, expires_at) VALUES ($1, $2, $3)`
+	// Claiming is a single update so two refreshes racing with the same token cannot both succeed
+	useRefreshTokenQuery = `UPDATE refresh_tokens SET used_at = NOW()
+		WHERE token_id = $1 AND user_id = $2 AND used_at IS NULL AND expires_at > NOW()`
+	revokeRefreshTokensQuery = `UPDATE refresh_tokens SET used_at = NOW() WHERE user_id = $1 AND used_at IS NULL`
+)
+
+type refreshTokenRepo struct {
+	DB *sql.DB
+}
+
+type RefreshTokenStorer interface {
+	CreateRefreshToken(tokenID, userID string, expiresAt time.Time) error
+	UseRefreshToken(tokenID, userID string) (bool, error)
+	RevokeRefreshTokens(userID string) error
+}
+
+// Constructor function
+func NewRefreshTokenRepo(db *sql.DB) RefreshTokenStorer {
+	return &refreshTokenRepo{DB: db}
+}
+
+// Stores the ID of a newly issued refresh token
+func (repoDep *refreshTokenRepo) CreateRefreshToken(tokenID, userID string, expiresAt time.Time) error {
+	if _, err := repoDep.DB.Exec(insertRefreshTokenQuery, tokenID, userID, expiresAt); err != nil {
+		log.Printf("Error inserting refresh token: %v", err)
+		return fmt.Errorf("error issuing refresh token: %v", err)
+	}
+	return nil
+}
+
+// Marks the user's refresh token used, false when it is unknown, expired or was already used
+func (repoDep *refreshTokenRepo) UseRefreshToken(tokenID, userID string) (bool, error) {
+	result, err := repoDep.DB.Exec(useRefreshTokenQuery, tokenID, userID)
+	if err != nil {
+		log.Printf("Error claiming refresh token: %v", err)
+		return false, fmt.Errorf("error claiming refresh token: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error checking affected rows: %v", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+// Marks every open refresh token of the user used, none of them refreshes anymore
+func (repoDep *refreshTokenRepo) RevokeRefreshTokens(userID string) error {
+	if _, err := repoDep.DB.Exec(revokeRefreshTokensQuery, userID); err != nil {
+		log.Printf("Error revoking refresh tokens: %v", err)
+		return fmt.Errorf("error revoking refresh tokens: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package repo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+// TransferReversal returns Amount of an erroneous transfer from its receiver to its sender. It is
+// requested by one admin and approved by another, ReversalTransactionID is the compensating transfer.
+type TransferReversal struct {
+	ReversalID            string
+	OriginalTransactionID string
+	ReversalTransactionID string
+	SenderUserID          string
+	ReceiverUserID        string
+	SenderWalletID        string
+	ReceiverWalletID      string
+	Amount                string
+	ReasonCode            string
+	Note                  string
+	Status                string
+	RequestedBy           string
+	DecidedBy             string
+	TxHash                string
+	Error                 string
+	CreatedAt             time.Time
+	DecidedAt             *time.Time
+}
+
+// All Transfer Reversal Queries
+const (
+	transferReversalColumns = `reversal_id, original_transaction_id, COALESCE(reversal_transaction_id::TEXT, ''), sender_user_id, receiver_user_id,
+		sender_wallet_id, receiver_wallet_id, amount::TEXT, reason_code, note, status, requested_by, COALESCE(decided_by::TEXT, ''),
+		COALESCE(tx_hash, ''), error, created_at, decided_at`
+	// Nothing is returned when the transfer already has an open or executed reversal
+	insertTransferReversalQuery = `INSERT INTO transfer_reversals (original_transaction_id, sender_user_id, receiver_user_id, sender_wallet_id,
+			receiver_wallet_id, amount, reason_code, note, requested_by)
+		VALUES ($1, $2, $3, $4, $5, $6::NUMERIC, $7, $8, $9)
+		ON CONFLICT (original_transaction_id) WHERE status IN ('pending_approval', 'executing', 'executed') DO NOTHING
+		RETURNING ` + transferReversalColumns
+	selectTransferReversalsQuery = `SELECT ` + transferReversalColumns + ` FROM transfer_reversals`
+	// Moves a reversal on only from the expected status, so concurrent approvals execute it once
+	updateReversalStatusQuery = `UPDATE transfer_reversals SET status = $3, decided_by = COALESCE(NULLIF($4, '')::UUID, decided_by), error = $5,
+		decided_at = CASE WHEN $3 IN ('executing', 'rejected') THEN NOW() ELSE decided_at END
+		WHERE reversal_id = $1 AND status = $2`
+	completeReversalQuery = `UPDATE transfer_reversals SET status = 'executed', reversal_transaction_id = $2, tx_hash = $3
+		WHERE reversal_id = $1 AND status = 'executing'
+		RETURNING ` + transferReversalColumns
+)
+
+type reversalRepo struct {
+	DB *sql.DB
+}
+
+type ReversalStorer interface {
+	CreateReversal(reversal TransferReversal) (TransferReversal, error)
+	GetReversal(reversalID string) (TransferReversal, error)
+	GetReversals(status string, limit int) ([]TransferReversal, error)
+	GetTransactionReversals(transactionID string) ([]TransferReversal, error)
+	UpdateReversalStatus(reversalID, fromStatus, toStatus, decidedBy, errMsg string) (bool, error)
+	CompleteReversal(reversalID string, compensation Transaction) (TransferReversal, error)
+}
+
+// Constructor function
+func NewReversalRepo(db *sql.DB) ReversalStorer {
+	return &reversalRepo{DB: db}
+}
+
+// Records a reversal waiting for approval, fails with a conflict when the transfer already has one
+func (repoDep *reversalRepo) CreateReversal(reversal TransferReversal) (TransferReversal, error) {
+	created, err := scanTransferReversal(repoDep.DB.QueryRow(insertTransferReversalQuery, reversal.OriginalTransactionID, reversal.SenderUserID,
+		reversal.ReceiverUserID, reversal.SenderWalletID, reversal.ReceiverWalletID, reversal.Amount, reversal.ReasonCode, reversal.Note, reversal.RequestedBy))
+	if errors.Is(err, sql.ErrNoRows) {
+		return reversal, utils.Conflict("transfer already has an open or executed reversal")
+	}
+	if err != nil {
+		log.Printf("Error creating reversal of transaction %s: %v", reversal.OriginalTransactionID, err)
+		return reversal, fmt.Errorf("error creating reversal: %v", err)
+	}
+	return created, nil
+}
+
+// Returns a reversal
+func (repoDep *reversalRepo) GetReversal(reversalID string) (TransferReversal, error) {
+	query, args := newSelectQuery(selectTransferReversalsQuery).where("reversal_id = ?", reversalID).build()
+
+	reversal, err := scanTransferReversal(repoDep.DB.QueryRow(query, args...))
+	if err != nil {
+		return reversal, utils.FromDBError("reversal", err)
+	}
+	return reversal, nil
+}
+
+// Returns the latest reversals with the given status, or of any status when it is empty
+func (repoDep *reversalRepo) GetReversals(status string, limit int) ([]TransferReversal, error) {
+	q := newSelectQuery(selectTransferReversalsQuery)
+	if status != "" {
+		q.where("status = ?", status)
+	}
+	query, args := q.order("created_at DESC").page(limit, 0).build()
+
+	return repoDep.queryReversals(query, args)
+}
+
+// Returns every reversal of a transfer, rejected and failed ones included, newest first
+func (repoDep *reversalRepo) GetTransactionReversals(transactionID string) ([]TransferReversal, error) {
+	query, args := newSelectQuery(selectTransferReversalsQuery).
+		where("original_transaction_id = ?", transactionID).
+		order("created_at DESC").
+		build()
+
+	return repoDep.queryReversals(query, args)
+}
+
+// Moves a reversal from fromStatus to toStatus, false if it was no longer in fromStatus
+func (repoDep *reversalRepo) UpdateReversalStatus(reversalID, fromStatus, toStatus, decidedBy, errMsg string) (bool, error) {
+	result, err := repoDep.DB.Exec(updateReversalStatusQuery, reversalID, fromStatus, toStatus, decidedBy, errMsg)
+	if err != nil {
+		log.Printf("Error updating reversal %s: %v", reversalID, err)
+		return false, fmt.Errorf("error updating reversal: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	return rowsAffected > 0, err
+}
+
+// Records the broadcast return transfer as the compensating transaction and links it to the
+// executing reversal, both in one transaction
+func (repoDep *reversalRepo) CompleteReversal(reversalID string, compensation Transaction) (TransferReversal, error) {
+	tx, err := repoDep.DB.Begin()
+	if err != nil {
+		return TransferReversal{}, fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(insertTransactionQuery, compensation.TxHash, compensation.SenderUserID, compensation.ReceiverUserID, compensation.SenderWalletID,
+		compensation.ReceiverWalletID, compensation.Amount, compensation.Status, compensation.Nonce, compensation.GasPrice,
+		compensation.RequiredConfirmations).Scan(&compensation.TransactionID, &compensation.CreatedAt)
+	if err != nil {
+		log.Printf("Error recording compensating transaction of reversal %s: %v", reversalID, err)
+		return TransferReversal{}, fmt.Errorf("error recording compensating transaction: %v", err)
+	}
+
+	reversal, err := scanTransferReversal(tx.QueryRow(completeReversalQuery, reversalID, compensation.TransactionID, compensation.TxHash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return reversal, utils.Conflict("reversal is no longer executing")
+	}
+	if err != nil {
+		log.Printf("Error completing reversal %s: %v", reversalID, err)
+		return reversal, fmt.Errorf("error completing reversal: %v", err)
+	}
+
+	return reversal, tx.Commit()
+}
+
+// Runs a built reversal listing query and scans the result
+func (repoDep *reversalRepo) queryReversals(query string, args []interface{}) ([]TransferReversal, error) {
+	rows, err := repoDep.DB.Query(query, args...)
+	if err != nil {
+		log.Printf("Error executing query: %v", err)
+		return nil, fmt.Errorf("error fetching reversals: %v", err)
+	}
+	defer rows.Close()
+
+	reversals := []TransferReversal{}
+	for rows.Next() {
+		reversal, err := scanTransferReversal(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error reading reversals: %v", err)
+		}
+		reversals = append(reversals, reversal)
+	}
+	return reversals, rows.Err()
+}
+
+// Scans one row of the transferReversalColumns
+func scanTransferReversal(row interface{ Scan(dest ...any) error }) (TransferReversal, error) {
+	var reversal TransferReversal
+	err := row.Scan(&reversal.ReversalID, &reversal.OriginalTransactionID, &reversal.ReversalTransactionID, &reversal.SenderUserID,
+		&reversal.ReceiverUserID, &reversal.SenderWalletID, &reversal.ReceiverWalletID, &reversal.Amount, &reversal.ReasonCode, &reversal.Note,
+		&reversal.Status, &reversal.RequestedBy, &reversal.DecidedBy, &reversal.TxHash, &reversal.Error, &reversal.CreatedAt, &reversal.DecidedAt)
+	return reversal, err
+}
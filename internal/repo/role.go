@@ -0,0 +1,212 @@
+package repo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+	"github.com/lib/pq"
+)
+
+// Role is a set of permissions users can be assigned. Built-in roles cannot be changed.
+type Role struct {
+	RoleID      int
+	Name        string
+	Description string
+	BuiltIn     bool
+	Permissions []string
+	CreatedBy   string
+	CreatedAt   time.Time
+}
+
+// All Role Queries
+const (
+	roleColumns = `r.role_id, r.name, r.description, r.built_in,
+		ARRAY(SELECT permission FROM role_permissions p WHERE p.role_id = r.role_id ORDER BY permission),
+		COALESCE(r.created_by::TEXT, ''), r.created_at`
+	insertRoleQuery = `INSERT INTO role_definitions (name, description, created_by) VALUES ($1, $2, $3)
+		ON CONFLICT (name) DO NOTHING RETURNING role_id`
+	getRolesQuery              = `SELECT ` + roleColumns + ` FROM role_definitions r ORDER BY r.role_id`
+	getRoleQuery               = `SELECT ` + roleColumns + ` FROM role_definitions r WHERE r.role_id = $1`
+	deleteRolePermissionsQuery = `DELETE FROM role_permissions WHERE role_id = $1`
+	insertRolePermissionsQuery = `INSERT INTO role_permissions (role_id, permission) SELECT $1, UNNEST($2::TEXT[])`
+	deleteRoleAssignmentsQuery = `DELETE FROM user_roles_assignment WHERE role_id = $1`
+	deleteRoleQuery            = `DELETE FROM role_definitions WHERE role_id = $1 AND NOT built_in`
+	assignRoleQuery            = `INSERT INTO user_roles_assignment (user_id, role_id)
+		SELECT $1, $2 WHERE NOT EXISTS (SELECT 1 FROM user_roles_assignment WHERE user_id = $1 AND role_id = $2)`
+	unassignRoleQuery       = `DELETE FROM user_roles_assignment WHERE user_id = $1 AND role_id = $2`
+	getUserPermissionsQuery = `SELECT DISTINCT p.permission FROM user_roles_assignment a
+		JOIN role_permissions p ON p.role_id = a.role_id WHERE a.user_id = $1 ORDER BY p.permission`
+)
+
+type roleRepo struct {
+	DB *sql.DB
+}
+
+type RoleStorer interface {
+	CreateRole(role Role) (Role, error)
+	GetRoles() ([]Role, error)
+	GetRole(roleID int) (Role, error)
+	SetRolePermissions(roleID int, permissions []string) (Role, error)
+	DeleteRole(roleID int) (bool, error)
+	AssignRole(userID string, roleID int) error
+	UnassignRole(userID string, roleID int) (bool, error)
+	GetUserPermissions(userID string) ([]string, error)
+}
+
+// Constructor function
+func NewRoleRepo(db *sql.DB) RoleStorer {
+	return &roleRepo{DB: db}
+}
+
+// Creates a custom role with its permissions, a conflict error when the name is taken
+func (repoDep *roleRepo) CreateRole(role Role) (Role, error) {
+	tx, err := repoDep.DB.Begin()
+	if err != nil {
+		return role, fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var roleID int
+	err = tx.QueryRow(insertRoleQuery, role.Name, role.Description, role.CreatedBy).Scan(&roleID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return role, utils.Conflictf("role %s already exists", role.Name)
+	}
+	if err != nil {
+		log.Printf("Error creating role %s: %v", role.Name, err)
+		return role, fmt.Errorf("error creating role: %v", err)
+	}
+
+	if _, err := tx.Exec(insertRolePermissionsQuery, roleID, pq.Array(role.Permissions)); err != nil {
+		log.Printf("Error granting permissions to role %d: %v", roleID, err)
+		return role, fmt.Errorf("error granting permissions: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return role, fmt.Errorf("error committing role: %v", err)
+	}
+	return repoDep.GetRole(roleID)
+}
+
+// Returns every role, built-in ones first
+func (repoDep *roleRepo) GetRoles() ([]Role, error) {
+	rows, err := repoDep.DB.Query(getRolesQuery)
+	if err != nil {
+		log.Printf("Error executing query: %v", err)
+		return nil, fmt.Errorf("error fetching roles: %v", err)
+	}
+	defer rows.Close()
+
+	roles := []Role{}
+	for rows.Next() {
+		role, err := scanRole(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error reading roles: %v", err)
+		}
+		roles = append(roles, role)
+	}
+	return roles, rows.Err()
+}
+
+// Returns a role with its permissions
+func (repoDep *roleRepo) GetRole(roleID int) (Role, error) {
+	role, err := scanRole(repoDep.DB.QueryRow(getRoleQuery, roleID))
+	if err != nil {
+		return role, utils.FromDBError("role", err)
+	}
+	return role, nil
+}
+
+// Replaces the permissions of a role
+func (repoDep *roleRepo) SetRolePermissions(roleID int, permissions []string) (Role, error) {
+	tx, err := repoDep.DB.Begin()
+	if err != nil {
+		return Role{}, fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(deleteRolePermissionsQuery, roleID); err != nil {
+		log.Printf("Error clearing permissions of role %d: %v", roleID, err)
+		return Role{}, fmt.Errorf("error updating permissions: %v", err)
+	}
+	if _, err := tx.Exec(insertRolePermissionsQuery, roleID, pq.Array(permissions)); err != nil {
+		log.Printf("Error granting permissions to role %d: %v", roleID, err)
+		return Role{}, fmt.Errorf("error updating permissions: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return Role{}, fmt.Errorf("error committing permissions: %v", err)
+	}
+	return repoDep.GetRole(roleID)
+}
+
+// Deletes a custom role and takes it away from its users, false when there was no such role
+func (repoDep *roleRepo) DeleteRole(roleID int) (bool, error) {
+	tx, err := repoDep.DB.Begin()
+	if err != nil {
+		return false, fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(deleteRoleQuery, roleID)
+	if err != nil {
+		log.Printf("Error deleting role %d: %v", roleID, err)
+		return false, fmt.Errorf("error deleting role: %v", err)
+	}
+	if deleted, _ := result.RowsAffected(); deleted == 0 {
+		return false, nil
+	}
+	if _, err := tx.Exec(deleteRoleAssignmentsQuery, roleID); err != nil {
+		log.Printf("Error removing assignments of role %d: %v", roleID, err)
+		return false, fmt.Errorf("error deleting role: %v", err)
+	}
+	return true, tx.Commit()
+}
+
+// Gives a role to a user, assigning a role the user holds already changes nothing
+func (repoDep *roleRepo) AssignRole(userID string, roleID int) error {
+	if _, err := repoDep.DB.Exec(assignRoleQuery, userID, roleID); err != nil {
+		log.Printf("Error assigning role %d to %s: %v", roleID, userID, err)
+		return fmt.Errorf("error assigning role: %v", err)
+	}
+	return nil
+}
+
+// Takes a role away from a user, false when the user did not hold it
+func (repoDep *roleRepo) UnassignRole(userID string, roleID int) (bool, error) {
+	result, err := repoDep.DB.Exec(unassignRoleQuery, userID, roleID)
+	if err != nil {
+		log.Printf("Error unassigning role %d from %s: %v", roleID, userID, err)
+		return false, fmt.Errorf("error unassigning role: %v", err)
+	}
+	removed, err := result.RowsAffected()
+	return removed > 0, err
+}
+
+// Returns the permissions granted by all roles of a user
+func (repoDep *roleRepo) GetUserPermissions(userID string) ([]string, error) {
+	rows, err := repoDep.DB.Query(getUserPermissionsQuery, userID)
+	if err != nil {
+		log.Printf("Error executing query: %v", err)
+		return nil, fmt.Errorf("error fetching permissions: %v", err)
+	}
+	defer rows.Close()
+
+	permissions := []string{}
+	for rows.Next() {
+		var permission string
+		if err := rows.Scan(&permission); err != nil {
+			return nil, fmt.Errorf("error reading permissions: %v", err)
+		}
+		permissions = append(permissions, permission)
+	}
+	return permissions, rows.Err()
+}
+
+// Scans one row of the roleColumns
+func scanRole(row interface{ Scan(dest ...any) error }) (Role, error) {
+	var role Role
+	err := row.Scan(&role.RoleID, &role.Name, &role.Description, &role.BuiltIn, pq.Array(&role.Permissions), &role.CreatedBy, &role.CreatedAt)
+	return role, err
+}
@@ -0,0 +1,41 @@
+package repo
+
+import (
+	"database/sql"
+	"sync/atomic"
+)
+
+// DBRouter routes queries between the primary database and optional read replicas.
+// Writes always go to the primary, heavy reads may be served by a replica.
+type DBRouter struct {
+	primary  *sql.DB
+	replicas []*sql.DB
+	next     atomic.Uint32
+}
+
+// Constructor function
+func NewDBRouter(primary *sql.DB, replicas ...*sql.DB) *DBRouter {
+	return &DBRouter{primary: primary, replicas: replicas}
+}
+
+// Writer returns the primary connection
+func (router *DBRouter) Writer() *sql.DB {
+	return router.primary
+}
+
+// Reader returns a replica in round-robin order, or the primary when no replica is configured
+func (router *DBRouter) Reader() *sql.DB {
+	if len(router.replicas) == 0 {
+		return router.primary
+	}
+	n := router.next.Add(1)
+	return router.replicas[int(n-1)%len(router.replicas)]
+}
+
+// Close closes the primary and every replica connection
+func (router *DBRouter) Close() {
+	for _, replica := range router.replicas {
+		CloseDB(replica)
+	}
+	CloseDB(router.primary)
+}
@@ -0,0 +1,67 @@
+package repo
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+// RuntimeSetting Regular struct
+type RuntimeSetting struct {
+	Key       string
+	Value     string
+	UpdatedBy sql.NullString
+	UpdatedAt time.Time
+}
+
+// All Runtime Setting Queries
+const (
+	getRuntimeSettingsQuery   = `SELECT setting_key, setting_value, updated_by, updated_at FROM runtime_settings`
+	upsertRuntimeSettingQuery = `INSERT INTO runtime_settings (setting_key, setting_value, updated_by, updated_at) VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (setting_key) DO UPDATE SET setting_value = EXCLUDED.setting_value, updated_by = EXCLUDED.updated_by, updated_at = EXCLUDED.updated_at`
+)
+
+type settingsRepo struct {
+	DB *sql.DB
+}
+
+type SettingsStorer interface {
+	GetRuntimeSettings() ([]RuntimeSetting, error)
+	UpsertRuntimeSetting(key, value, updatedBy string) error
+}
+
+// Constructor function
+func NewSettingsRepo(db *sql.DB) SettingsStorer {
+	return &settingsRepo{DB: db}
+}
+
+// Returns every stored runtime setting override
+func (repoDep *settingsRepo) GetRuntimeSettings() ([]RuntimeSetting, error) {
+	rows, err := repoDep.DB.Query(getRuntimeSettingsQuery)
+	if err != nil {
+		log.Printf("Error executing query: %v", err)
+		return nil, fmt.Errorf("error fetching runtime settings: %v", err)
+	}
+	defer rows.Close()
+
+	var settings []RuntimeSetting
+	for rows.Next() {
+		var setting RuntimeSetting
+		if err := rows.Scan(&setting.Key, &setting.Value, &setting.UpdatedBy, &setting.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("error reading runtime settings: %v", err)
+		}
+		settings = append(settings, setting)
+	}
+	return settings, rows.Err()
+}
+
+// Stores a runtime setting override, replacing the previous value
+func (repoDep *settingsRepo) UpsertRuntimeSetting(key, value, updatedBy string) error {
+	_, err := repoDep.DB.Exec(upsertRuntimeSettingQuery, key, value, updatedBy)
+	if err != nil {
+		log.Printf("Error updating runtime setting %s: %v", key, err)
+		return fmt.Errorf("error updating runtime setting: %v", err)
+	}
+	return nil
+}
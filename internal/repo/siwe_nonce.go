@@ -0,0 +1,58 @@
+package repo
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+// All SIWE Nonce Queries
+const (
+	insertSIWENonceQuery = `INSERT INTO siwe_nonces (nonce, expires_at) VALUES ($1, $2)`
+	// Claiming is a single update so two logins racing with the same message cannot both succeed
+	useSIWENonceQuery = `UPDATE siwe_nonces SET used_at = NOW()
+		WHERE nonce = $1 AND used_at IS NULL AND expires_at > NOW()`
+	deleteExpiredSIWENoncesQuery = `DELETE FROM siwe_nonces WHERE expires_at < NOW()`
+)
+
+type siweNonceRepo struct {
+	DB *sql.DB
+}
+
+type SIWENonceStorer interface {
+	CreateSIWENonce(nonce string, expiresAt time.Time) error
+	UseSIWENonce(nonce string) (bool, error)
+}
+
+// Constructor function
+func NewSIWENonceRepo(db *sql.DB) SIWENonceStorer {
+	return &siweNonceRepo{DB: db}
+}
+
+// Stores a newly issued nonce and drops the expired ones, which can never sign in anymore
+func (repoDep *siweNonceRepo) CreateSIWENonce(nonce string, expiresAt time.Time) error {
+	if _, err := repoDep.DB.Exec(deleteExpiredSIWENoncesQuery); err != nil {
+		log.Printf("Error deleting expired SIWE nonces: %v", err)
+	}
+
+	if _, err := repoDep.DB.Exec(insertSIWENonceQuery, nonce, expiresAt); err != nil {
+		log.Printf("Error inserting SIWE nonce: %v", err)
+		return fmt.Errorf("error issuing nonce: %v", err)
+	}
+	return nil
+}
+
+// Marks the nonce used, false when it is unknown, expired or was already used
+func (repoDep *siweNonceRepo) UseSIWENonce(nonce string) (bool, error) {
+	result, err := repoDep.DB.Exec(useSIWENonceQuery, nonce)
+	if err != nil {
+		log.Printf("Error claiming SIWE nonce: %v", err)
+		return false, fmt.Errorf("error claiming nonce: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error checking affected rows: %v", err)
+	}
+	return rowsAffected > 0, nil
+}
@@ -0,0 +1,180 @@
+package repo
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+// SweepRule moves the balance above Threshold wei to DestinationAddress, sweeps smaller than
+// MinSweepAmount are skipped so gas is not spent on dust
+type SweepRule struct {
+	UserID             string
+	Threshold          string
+	MinSweepAmount     string
+	DestinationAddress string
+	Enabled            bool
+	LastRunAt          *time.Time
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+}
+
+// Sweep is one execution of a sweep rule, Status is sent with TxHash or failed with Error
+type Sweep struct {
+	SweepID            string
+	UserID             string
+	WalletID           string
+	DestinationAddress string
+	Balance            string
+	Threshold          string
+	Amount             string
+	Status             string
+	TxHash             string
+	Error              string
+	CreatedAt          time.Time
+}
+
+// All Sweep Queries
+const (
+	sweepRuleColumns     = `user_id, threshold::TEXT, min_sweep_amount::TEXT, destination_address, enabled, last_run_at, created_at, updated_at`
+	getSweepRuleQuery    = `SELECT ` + sweepRuleColumns + ` FROM sweep_rules WHERE user_id = $1`
+	upsertSweepRuleQuery = `INSERT INTO sweep_rules (user_id, threshold, min_sweep_amount, destination_address, enabled)
+		VALUES ($1, $2::NUMERIC, $3::NUMERIC, $4, $5)
+		ON CONFLICT (user_id) DO UPDATE SET threshold = EXCLUDED.threshold, min_sweep_amount = EXCLUDED.min_sweep_amount,
+			destination_address = EXCLUDED.destination_address, enabled = EXCLUDED.enabled, updated_at = NOW()
+		RETURNING ` + sweepRuleColumns
+	deleteSweepRuleQuery = `DELETE FROM sweep_rules WHERE user_id = $1`
+	// Enabled rules of open accounts that did not run within the interval, claimed in the same
+	// statement so concurrent schedulers do not sweep a wallet twice
+	claimDueSweepRulesQuery = `UPDATE sweep_rules SET last_run_at = NOW()
+		WHERE user_id IN (SELECT r.user_id FROM sweep_rules r INNER JOIN users u ON r.user_id = u.user_id
+			WHERE r.enabled AND u.account_status = 'active' AND (r.last_run_at IS NULL OR r.last_run_at <= NOW() - $1 * INTERVAL '1 second')
+			ORDER BY r.last_run_at NULLS FIRST LIMIT $2 FOR UPDATE OF r SKIP LOCKED)
+		RETURNING ` + sweepRuleColumns
+
+	sweepColumns     = `sweep_id, user_id, wallet_id, destination_address, balance::TEXT, threshold::TEXT, amount::TEXT, status, COALESCE(tx_hash, ''), error, created_at`
+	insertSweepQuery = `INSERT INTO sweeps (user_id, wallet_id, destination_address, balance, threshold, amount, status, tx_hash, error)
+		VALUES ($1, $2, $3, $4::NUMERIC, $5::NUMERIC, $6::NUMERIC, $7, NULLIF($8, ''), $9)
+		RETURNING ` + sweepColumns
+	getSweepsQuery = `SELECT ` + sweepColumns + ` FROM sweeps WHERE user_id = $1 ORDER BY created_at DESC LIMIT $2`
+)
+
+type sweepRepo struct {
+	DB *sql.DB
+}
+
+type SweepStorer interface {
+	GetSweepRule(userID string) (SweepRule, error)
+	UpsertSweepRule(rule SweepRule) (SweepRule, error)
+	DeleteSweepRule(userID string) error
+	ClaimDueSweepRules(interval time.Duration, limit int) ([]SweepRule, error)
+	RecordSweep(sweep Sweep) (Sweep, error)
+	GetSweeps(userID string, limit int) ([]Sweep, error)
+}
+
+// Constructor function
+func NewSweepRepo(db *sql.DB) SweepStorer {
+	return &sweepRepo{DB: db}
+}
+
+// Returns the sweep rule of a user
+func (repoDep *sweepRepo) GetSweepRule(userID string) (SweepRule, error) {
+	rule, err := scanSweepRule(repoDep.DB.QueryRow(getSweepRuleQuery, userID))
+	if err != nil {
+		return rule, utils.FromDBError("sweep rule", err)
+	}
+	return rule, nil
+}
+
+// Sets the sweep rule of a user, replacing the previous one
+func (repoDep *sweepRepo) UpsertSweepRule(rule SweepRule) (SweepRule, error) {
+	saved, err := scanSweepRule(repoDep.DB.QueryRow(upsertSweepRuleQuery, rule.UserID, rule.Threshold, rule.MinSweepAmount, rule.DestinationAddress, rule.Enabled))
+	if err != nil {
+		log.Printf("Error saving sweep rule of %s: %v", rule.UserID, err)
+		return rule, fmt.Errorf("error saving sweep rule: %v", err)
+	}
+	return saved, nil
+}
+
+// Removes the sweep rule of a user, their sweep history is kept
+func (repoDep *sweepRepo) DeleteSweepRule(userID string) error {
+	result, err := repoDep.DB.Exec(deleteSweepRuleQuery, userID)
+	if err != nil {
+		log.Printf("Error deleting sweep rule of %s: %v", userID, err)
+		return fmt.Errorf("error deleting sweep rule: %v", err)
+	}
+	if rowsAffected, err := result.RowsAffected(); err == nil && rowsAffected == 0 {
+		return utils.NotFound("sweep rule not found", nil)
+	}
+	return nil
+}
+
+// Claims up to limit enabled rules that are due to run
+func (repoDep *sweepRepo) ClaimDueSweepRules(interval time.Duration, limit int) ([]SweepRule, error) {
+	rows, err := repoDep.DB.Query(claimDueSweepRulesQuery, int(interval.Seconds()), limit)
+	if err != nil {
+		log.Printf("Error claiming sweep rules: %v", err)
+		return nil, fmt.Errorf("error claiming sweep rules: %v", err)
+	}
+	defer rows.Close()
+
+	rules := []SweepRule{}
+	for rows.Next() {
+		rule, err := scanSweepRule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error reading sweep rules: %v", err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// Records a sweep the scheduler sent or failed to send
+func (repoDep *sweepRepo) RecordSweep(sweep Sweep) (Sweep, error) {
+	recorded, err := scanSweep(repoDep.DB.QueryRow(insertSweepQuery, sweep.UserID, sweep.WalletID, sweep.DestinationAddress, sweep.Balance,
+		sweep.Threshold, sweep.Amount, sweep.Status, sweep.TxHash, sweep.Error))
+	if err != nil {
+		log.Printf("Error recording sweep of %s: %v", sweep.UserID, err)
+		return sweep, fmt.Errorf("error recording sweep: %v", err)
+	}
+	return recorded, nil
+}
+
+// Returns the latest sweeps of a user, newest first
+func (repoDep *sweepRepo) GetSweeps(userID string, limit int) ([]Sweep, error) {
+	rows, err := repoDep.DB.Query(getSweepsQuery, userID, limit)
+	if err != nil {
+		log.Printf("Error executing query: %v", err)
+		return nil, fmt.Errorf("error fetching sweeps: %v", err)
+	}
+	defer rows.Close()
+
+	sweeps := []Sweep{}
+	for rows.Next() {
+		sweep, err := scanSweep(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error reading sweeps: %v", err)
+		}
+		sweeps = append(sweeps, sweep)
+	}
+	return sweeps, rows.Err()
+}
+
+// Scans one row of the sweepRuleColumns
+func scanSweepRule(row interface{ Scan(dest ...any) error }) (SweepRule, error) {
+	var rule SweepRule
+	err := row.Scan(&rule.UserID, &rule.Threshold, &rule.MinSweepAmount, &rule.DestinationAddress, &rule.Enabled, &rule.LastRunAt,
+		&rule.CreatedAt, &rule.UpdatedAt)
+	return rule, err
+}
+
+// Scans one row of the sweepColumns
+func scanSweep(row interface{ Scan(dest ...any) error }) (Sweep, error) {
+	var sweep Sweep
+	err := row.Scan(&sweep.SweepID, &sweep.UserID, &sweep.WalletID, &sweep.DestinationAddress, &sweep.Balance, &sweep.Threshold,
+		&sweep.Amount, &sweep.Status, &sweep.TxHash, &sweep.Error, &sweep.CreatedAt)
+	return sweep, err
+}
@@ -0,0 +1,547 @@
+package repo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/domain"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+	"github.com/lib/pq"
+)
+
+// Transaction Regular struct
+type Transaction struct {
+	TransactionID    string                   `json:"transaction_id"`
+	TxHash           string                   `json:"tx_hash"`
+	SenderUserID     string                   `json:"sender_user_id"`
+	ReceiverUserID   string                   `json:"receiver_user_id"`
+	SenderWalletID   string                   `json:"sender_wallet_id"`
+	ReceiverWalletID string                   `json:"receiver_wallet_id"`
+	Amount           string                   `json:"amount"`
+	Status           domain.TransactionStatus `json:"status"`
+	CreatedAt        time.Time                `json:"created_at"`
+
+	// Block the transfer was mined in and how deep it must be buried before it is confirmed.
+	// Confirmations is worked out from the latest block when the transaction is served.
+	BlockNumber           *int64  `json:"block_number,omitempty"`
+	RequiredConfirmations int     `json:"required_confirmations"`
+	Confirmations         *uint64 `json:"confirmations,omitempty"`
+
+	// Broadcast details, only written on insert so a stuck transaction can be replaced
+	Nonce    *int64 `json:"-"`
+	GasPrice string `json:"-"`
+}
+
+// PendingTransfer is a broadcast transaction along with the history of its replacements
+type PendingTransfer struct {
+	Transaction
+	PreviousTxHashes []string
+	CancelRequested  bool
+	BroadcastAt      time.Time
+}
+
+// SettledTransfer is a settled transfer with the block it was mined in
+type SettledTransfer struct {
+	TransactionID string
+	TxHash        string
+	Status        domain.TransactionStatus
+	BlockNumber   uint64
+	BlockHash     string
+}
+
+// MonthlySummary aggregates a user's transfers in one calendar month of their time zone, amounts in wei
+type MonthlySummary struct {
+	Month         time.Time
+	OutgoingTotal *big.Int
+	OutgoingCount int
+	IncomingTotal *big.Int
+	IncomingCount int
+	FeesPaid      *big.Int
+}
+
+// PlatformStats aggregates transfers of every user since a point in time, amounts in wei
+type PlatformStats struct {
+	TransferCount int64
+	Volume        *big.Int
+	ActiveSenders int64
+}
+
+// PendingTotals sums a wallet's transfers that were broadcast but not yet mined, so they are missing
+// from its on-chain balance. Outgoing includes the gas reserved for each transfer, amounts in wei.
+type PendingTotals struct {
+	OutgoingTotal *big.Int
+	OutgoingCount int
+	IncomingTotal *big.Int
+	IncomingCount int
+}
+
+// HistoryState tells whether the transaction history of a user changed without loading it.
+// LastModified is nil while the user has no transactions.
+type HistoryState struct {
+	LastModified *time.Time
+	// Mined transfers waiting for confirmations
+	Confirming int
+}
+
+// TransactionCursor points at the last row of a page in (created_at, transaction_id) order
+type TransactionCursor struct {
+	CreatedAt     time.Time
+	TransactionID string
+}
+
+// TransactionChange is a transaction along with when it last changed
+type TransactionChange struct {
+	Transaction
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ChangeCursor points at the last change a client has seen in (changed at, ID) order
+type ChangeCursor struct {
+	ChangedAt time.Time
+	ID        string
+}
+
+// All Transaction Queries
+const (
+	insertTransactionQuery  = `INSERT INTO transactions (tx_hash, sender_user_id, receiver_user_id, sender_wallet_id, receiver_wallet_id, amount, status, nonce, gas_price, required_confirmations) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, '')::NUMERIC, $10) RETURNING transaction_id, created_at`
+	selectTransactionsQuery = `SELECT transaction_id, tx_hash, sender_user_id, receiver_user_id, sender_wallet_id, receiver_wallet_id, amount, status, created_at, block_number, required_confirmations FROM transactions`
+	transactionsOrder       = `created_at DESC, transaction_id DESC`
+	countPendingQuery       = `SELECT COUNT(*) FROM transactions WHERE (sender_user_id = $1 OR receiver_user_id = $1) AND status = 'pending'`
+	sumSentSinceQuery       = `SELECT COALESCE(SUM(amount), 0)::TEXT FROM transactions WHERE sender_user_id = $1 AND created_at >= $2 AND status NOT IN ('failed', 'cancelled')`
+	selectPendingQuery      = `SELECT transaction_id, tx_hash, sender_user_id, receiver_user_id, sender_wallet_id, receiver_wallet_id, amount, status, created_at,
+		block_number, required_confirmations, nonce, COALESCE(gas_price::TEXT, ''), previous_tx_hashes, cancel_requested, broadcast_at FROM transactions`
+	// Fees are gas price times the gas limit of a plain transfer, failed and cancelled transfers are left out
+	monthlySummaryQuery = `SELECT date_trunc('month', created_at AT TIME ZONE $4) AS month,
+		COALESCE(SUM(amount) FILTER (WHERE sender_user_id = $1), 0)::TEXT, COUNT(*) FILTER (WHERE sender_user_id = $1),
+		COALESCE(SUM(amount) FILTER (WHERE receiver_user_id = $1), 0)::TEXT, COUNT(*) FILTER (WHERE receiver_user_id = $1),
+		COALESCE(SUM(gas_price * $3) FILTER (WHERE sender_user_id = $1), 0)::TEXT
+		FROM transactions WHERE (sender_user_id = $1 OR receiver_user_id = $1) AND created_at >= $2 AND status NOT IN ('failed', 'cancelled')
+		GROUP BY month ORDER BY month`
+	pendingTotalsQuery = `SELECT COALESCE(SUM(amount + COALESCE(gas_price, 0) * $2) FILTER (WHERE LOWER(sender_wallet_id) = $1), 0)::TEXT,
+		COUNT(*) FILTER (WHERE LOWER(sender_wallet_id) = $1),
+		COALESCE(SUM(amount) FILTER (WHERE LOWER(receiver_wallet_id) = $1), 0)::TEXT, COUNT(*) FILTER (WHERE LOWER(receiver_wallet_id) = $1)
+		FROM transactions WHERE status = 'pending' AND block_number IS NULL AND (LOWER(sender_wallet_id) = $1 OR LOWER(receiver_wallet_id) = $1)`
+	platformStatsQuery = `SELECT COUNT(*), COALESCE(SUM(amount), 0)::TEXT, COUNT(DISTINCT sender_user_id)
+		FROM transactions WHERE created_at >= $1 AND status NOT IN ('failed', 'cancelled')`
+	settleTransactionQuery = `UPDATE transactions SET status = $2, block_number = $3, block_hash = $4, updated_at = NOW() WHERE transaction_id = $1 AND status = 'pending'`
+	recordInclusionQuery   = `UPDATE transactions SET block_number = $2, block_hash = $3, updated_at = NOW() WHERE transaction_id = $1 AND status = 'pending'`
+	getSettledSinceQuery   = `SELECT transaction_id, tx_hash, status, block_number, block_hash FROM transactions
+		WHERE block_number >= $1 AND block_hash IS NOT NULL AND status <> 'pending' ORDER BY block_number LIMIT $2`
+	// Only reverts while the transfer is still recorded in the orphaned block
+	revertSettlementQuery = `UPDATE transactions SET status = 'pending', block_number = NULL, block_hash = NULL, reorg_count = reorg_count + 1,
+		updated_at = NOW() WHERE transaction_id = $1 AND block_hash = $2 AND status <> 'pending'`
+	replaceBroadcastQuery = `UPDATE transactions SET previous_tx_hashes = array_append(previous_tx_hashes, tx_hash), tx_hash = $3, gas_price = $4::NUMERIC,
+		cancel_requested = cancel_requested OR $5, broadcast_at = NOW(), updated_at = NOW() WHERE transaction_id = $1 AND tx_hash = $2 AND status = 'pending'`
+	// Mined transfers still short of their confirmations show a count that grows with every block
+	historyStateQuery = `SELECT MAX(updated_at), COUNT(*) FILTER (WHERE status = 'pending' AND block_number IS NOT NULL)
+		FROM transactions WHERE sender_user_id = $1 OR receiver_user_id = $1`
+	// Live and archived transactions for admin exports, archived rows carry no block details
+	exportTransactionsQuery = `SELECT transaction_id, tx_hash, sender_user_id, receiver_user_id, sender_wallet_id, receiver_wallet_id, amount, status, created_at,
+		block_number, required_confirmations FROM (
+			SELECT transaction_id, tx_hash, sender_user_id, receiver_user_id, sender_wallet_id, receiver_wallet_id, amount, status, created_at,
+				block_number, required_confirmations FROM transactions
+			UNION ALL
+			SELECT transaction_id, tx_hash, sender_user_id, receiver_user_id, sender_wallet_id, receiver_wallet_id, amount, status, created_at,
+				NULL::BIGINT, 0 FROM transactions_archive
+		) AS all_transactions`
+
+	selectTransactionChangesQuery = `SELECT transaction_id, tx_hash, sender_user_id, receiver_user_id, sender_wallet_id, receiver_wallet_id, amount, status, created_at,
+		block_number, required_confirmations, updated_at FROM transactions`
+	// A change is only listed once it is a few seconds old. updated_at is the start of the writing
+	// transaction, so a change committed late could otherwise land behind a cursor already handed out.
+	settledChangeCondition = `updated_at < NOW() - INTERVAL '5 seconds'`
+)
+
+type transactionRepo struct {
+	DB *DBRouter
+}
+
+type TransactionStorer interface {
+	CreateTransaction(txn Transaction, events ...OutboxEvent) (Transaction, error)
+	GetTransactions(userID string, limit, offset int) ([]Transaction, error)
+	GetTransactionsAfter(userID string, limit int, cursor *TransactionCursor) ([]Transaction, error)
+	GetHistoryState(userID string) (HistoryState, error)
+	GetTransactionChanges(userID string, cursor *ChangeCursor, limit int) ([]TransactionChange, error)
+	GetWalletActivity(walletIDs []string, limit int) ([]Transaction, error)
+	ExportTransactions(from, to *time.Time, cursor *TransactionCursor, limit int) ([]Transaction, error)
+	SumSentSince(userID string, since time.Time) (*big.Int, error)
+	CountPending(userID string) (int, error)
+	GetPendingTotals(walletID string, gasLimit uint64) (PendingTotals, error)
+	GetMonthlySummaries(userID string, since time.Time, gasLimit uint64, timeZone string) ([]MonthlySummary, error)
+	GetPlatformStats(since time.Time) (PlatformStats, error)
+	GetPendingBroadcastBefore(cutoff time.Time, limit int) ([]PendingTransfer, error)
+	GetPendingTransfer(transactionID string) (PendingTransfer, error)
+	RecordInclusion(transactionID string, blockNumber uint64, blockHash string) error
+	SettleTransaction(transactionID string, status domain.TransactionStatus, blockNumber uint64, blockHash string) error
+	GetSettledSince(blockNumber uint64, limit int) ([]SettledTransfer, error)
+	RevertSettlement(transactionID, blockHash string) (bool, error)
+	ReplaceBroadcast(transactionID, oldTxHash, newTxHash, gasPrice string, cancel bool) error
+}
+
+// Constructor function
+func NewTransactionRepo(db *DBRouter) TransactionStorer {
+	return &transactionRepo{DB: db}
+}
+
+// Records a broadcast transaction together with the events announcing it and returns it with the
+// generated ID and timestamp
+func (repoDep *transactionRepo) CreateTransaction(txn Transaction, events ...OutboxEvent) (Transaction, error) {
+	tx, err := repoDep.DB.Writer().Begin()
+	if err != nil {
+		return txn, fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(insertTransactionQuery, txn.TxHash, txn.SenderUserID, txn.ReceiverUserID, txn.SenderWalletID, txn.ReceiverWalletID, txn.Amount, txn.Status, txn.Nonce, txn.GasPrice, txn.RequiredConfirmations).Scan(&txn.TransactionID, &txn.CreatedAt)
+	if err != nil {
+		log.Printf("Error inserting transaction into database: %v", err)
+		return txn, fmt.Errorf("error recording transaction: %v", err)
+	}
+	if err := insertOutboxEvents(tx, events); err != nil {
+		return txn, err
+	}
+	return txn, tx.Commit()
+}
+
+// Returns a page of the user's transactions using LIMIT/OFFSET, newest first
+func (repoDep *transactionRepo) GetTransactions(userID string, limit, offset int) ([]Transaction, error) {
+	query, args := newSelectQuery(selectTransactionsQuery).
+		where("sender_user_id = ? OR receiver_user_id = ?", userID, userID).
+		order(transactionsOrder).
+		page(limit, offset).
+		build()
+
+	return repoDep.queryTransactions(query, args)
+}
+
+// Returns a page of the user's transactions strictly older than the cursor, newest first.
+// A nil cursor returns the first page.
+func (repoDep *transactionRepo) GetTransactionsAfter(userID string, limit int, cursor *TransactionCursor) ([]Transaction, error) {
+	q := newSelectQuery(selectTransactionsQuery).
+		where("sender_user_id = ? OR receiver_user_id = ?", userID, userID)
+	if cursor != nil {
+		q.where("(created_at, transaction_id) < (?, ?)", cursor.CreatedAt, cursor.TransactionID)
+	}
+	query, args := q.order(transactionsOrder).page(limit, 0).build()
+
+	return repoDep.queryTransactions(query, args)
+}
+
+// Returns when the user's transactions last changed, read from the replica the listing is served from
+func (repoDep *transactionRepo) GetHistoryState(userID string) (HistoryState, error) {
+	var state HistoryState
+	if err := repoDep.DB.Reader().QueryRow(historyStateQuery, userID).Scan(&state.LastModified, &state.Confirming); err != nil {
+		log.Printf("Error executing query: %v", err)
+		return state, fmt.Errorf("error fetching transaction history state: %v", err)
+	}
+	return state, nil
+}
+
+// Returns the user's transactions that changed after the cursor, oldest change first. A nil cursor
+// starts from the first transaction.
+func (repoDep *transactionRepo) GetTransactionChanges(userID string, cursor *ChangeCursor, limit int) ([]TransactionChange, error) {
+	q := newSelectQuery(selectTransactionChangesQuery).
+		where("sender_user_id = ? OR receiver_user_id = ?", userID, userID).
+		where(settledChangeCondition)
+	if cursor != nil {
+		q.where("(updated_at, transaction_id) > (?, ?)", cursor.ChangedAt, cursor.ID)
+	}
+	query, args := q.order("updated_at, transaction_id").page(limit, 0).build()
+
+	rows, err := repoDep.DB.Reader().Query(query, args...)
+	if err != nil {
+		log.Printf("Error executing query: %v", err)
+		return nil, fmt.Errorf("error fetching transaction changes: %v", err)
+	}
+	defer rows.Close()
+
+	changes := []TransactionChange{}
+	for rows.Next() {
+		var change TransactionChange
+		txn := &change.Transaction
+		if err := rows.Scan(&txn.TransactionID, &txn.TxHash, &txn.SenderUserID, &txn.ReceiverUserID, &txn.SenderWalletID, &txn.ReceiverWalletID, &txn.Amount, &txn.Status, &txn.CreatedAt, &txn.BlockNumber, &txn.RequiredConfirmations, &change.UpdatedAt); err != nil {
+			log.Printf("Error scanning transaction change row: %v", err)
+			return nil, fmt.Errorf("error reading transaction changes: %v", err)
+		}
+		changes = append(changes, change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading transaction changes: %v", err)
+	}
+	return changes, nil
+}
+
+// Returns the latest transactions sending from or to any of the wallets, newest first. Wallet IDs
+// are compared case-insensitively since external addresses may be stored in any casing.
+func (repoDep *transactionRepo) GetWalletActivity(walletIDs []string, limit int) ([]Transaction, error) {
+	lowered := make([]string, len(walletIDs))
+	for i, walletID := range walletIDs {
+		lowered[i] = strings.ToLower(walletID)
+	}
+
+	query, args := newSelectQuery(selectTransactionsQuery).
+		where("LOWER(sender_wallet_id) = ANY(?) OR LOWER(receiver_wallet_id) = ANY(?)", pq.Array(lowered), pq.Array(lowered)).
+		order(transactionsOrder).
+		page(limit, 0).
+		build()
+
+	return repoDep.queryTransactions(query, args)
+}
+
+// Returns a page of live and archived transactions created from from up to, not including, to and
+// strictly newer than the cursor, oldest first. Nil bounds and a nil cursor are left out.
+func (repoDep *transactionRepo) ExportTransactions(from, to *time.Time, cursor *TransactionCursor, limit int) ([]Transaction, error) {
+	q := newSelectQuery(exportTransactionsQuery)
+	if from != nil {
+		q.where("created_at >= ?", *from)
+	}
+	if to != nil {
+		q.where("created_at < ?", *to)
+	}
+	if cursor != nil {
+		q.where("(created_at, transaction_id) > (?, ?)", cursor.CreatedAt, cursor.TransactionID)
+	}
+	query, args := q.order("created_at, transaction_id").page(limit, 0).build()
+
+	return repoDep.queryTransactions(query, args)
+}
+
+// Runs a built transaction listing query on a read replica and scans the result
+func (repoDep *transactionRepo) queryTransactions(query string, args []interface{}) ([]Transaction, error) {
+	rows, err := repoDep.DB.Reader().Query(query, args...)
+	if err != nil {
+		log.Printf("Error executing query: %v", err)
+		return nil, fmt.Errorf("error fetching transactions: %v", err)
+	}
+	return scanTransactions(rows)
+}
+
+// Returns the total amount in wei the user has sent since the given time
+func (repoDep *transactionRepo) SumSentSince(userID string, since time.Time) (*big.Int, error) {
+	var total string
+	err := repoDep.DB.Writer().QueryRow(sumSentSinceQuery, userID, since).Scan(&total)
+	if err != nil {
+		log.Printf("Error executing query: %v", err)
+		return nil, fmt.Errorf("error summing sent transactions: %v", err)
+	}
+
+	sum, ok := new(big.Int).SetString(total, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid transaction sum %q", total)
+	}
+	return sum, nil
+}
+
+// Returns how many of the user's transactions, sent or received, are still pending
+func (repoDep *transactionRepo) CountPending(userID string) (int, error) {
+	var count int
+	err := repoDep.DB.Writer().QueryRow(countPendingQuery, userID).Scan(&count)
+	if err != nil {
+		log.Printf("Error executing query: %v", err)
+		return 0, fmt.Errorf("error counting pending transactions: %v", err)
+	}
+	return count, nil
+}
+
+// Returns the wallet's unmined outgoing transfers with their gas and its unmined incoming transfers
+func (repoDep *transactionRepo) GetPendingTotals(walletID string, gasLimit uint64) (PendingTotals, error) {
+	var totals PendingTotals
+	var outgoing, incoming string
+	err := repoDep.DB.Writer().QueryRow(pendingTotalsQuery, strings.ToLower(walletID), gasLimit).
+		Scan(&outgoing, &totals.OutgoingCount, &incoming, &totals.IncomingCount)
+	if err != nil {
+		log.Printf("Error executing query: %v", err)
+		return totals, fmt.Errorf("error summing pending transactions: %v", err)
+	}
+	totals.OutgoingTotal, _ = new(big.Int).SetString(outgoing, 10)
+	totals.IncomingTotal, _ = new(big.Int).SetString(incoming, 10)
+	return totals, nil
+}
+
+// Returns the user's sent and received totals per month of timeZone from since onwards, months without
+// transfers are left out
+func (repoDep *transactionRepo) GetMonthlySummaries(userID string, since time.Time, gasLimit uint64, timeZone string) ([]MonthlySummary, error) {
+	rows, err := repoDep.DB.Reader().Query(monthlySummaryQuery, userID, since, gasLimit, timeZone)
+	if err != nil {
+		log.Printf("Error executing query: %v", err)
+		return nil, fmt.Errorf("error summarizing transactions: %v", err)
+	}
+	defer rows.Close()
+
+	var summaries []MonthlySummary
+	for rows.Next() {
+		var summary MonthlySummary
+		var outgoing, incoming, fees string
+		if err := rows.Scan(&summary.Month, &outgoing, &summary.OutgoingCount, &incoming, &summary.IncomingCount, &fees); err != nil {
+			return nil, fmt.Errorf("error reading transaction summaries: %v", err)
+		}
+		summary.OutgoingTotal, _ = new(big.Int).SetString(outgoing, 10)
+		summary.IncomingTotal, _ = new(big.Int).SetString(incoming, 10)
+		summary.FeesPaid, _ = new(big.Int).SetString(fees, 10)
+		summaries = append(summaries, summary)
+	}
+	return summaries, rows.Err()
+}
+
+// Returns the transfer totals of all users since the given time, read from a replica
+func (repoDep *transactionRepo) GetPlatformStats(since time.Time) (PlatformStats, error) {
+	var stats PlatformStats
+	var volume string
+	if err := repoDep.DB.Reader().QueryRow(platformStatsQuery, since).Scan(&stats.TransferCount, &volume, &stats.ActiveSenders); err != nil {
+		log.Printf("Error executing query: %v", err)
+		return stats, fmt.Errorf("error computing platform statistics: %v", err)
+	}
+	stats.Volume, _ = new(big.Int).SetString(volume, 10)
+	return stats, nil
+}
+
+// Returns pending transactions last broadcast before cutoff, oldest first
+func (repoDep *transactionRepo) GetPendingBroadcastBefore(cutoff time.Time, limit int) ([]PendingTransfer, error) {
+	query, args := newSelectQuery(selectPendingQuery).
+		where("status = 'pending'").
+		where("broadcast_at < ?", cutoff).
+		order("broadcast_at").
+		page(limit, 0).
+		build()
+
+	rows, err := repoDep.DB.Writer().Query(query, args...)
+	if err != nil {
+		log.Printf("Error executing query: %v", err)
+		return nil, fmt.Errorf("error fetching pending transactions: %v", err)
+	}
+	defer rows.Close()
+
+	transfers := []PendingTransfer{}
+	for rows.Next() {
+		transfer, err := scanPendingTransfer(rows)
+		if err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, transfer)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading pending transactions: %v", err)
+	}
+	return transfers, nil
+}
+
+// Returns a single transaction with its broadcast details, whatever its status
+func (repoDep *transactionRepo) GetPendingTransfer(transactionID string) (PendingTransfer, error) {
+	query, args := newSelectQuery(selectPendingQuery).where("transaction_id = ?", transactionID).build()
+
+	transfer, err := scanPendingTransfer(repoDep.DB.Writer().QueryRow(query, args...))
+	if err != nil {
+		return transfer, utils.FromDBError("transaction", err)
+	}
+	return transfer, nil
+}
+
+// Records the block a pending transaction was mined in while it waits for enough confirmations
+func (repoDep *transactionRepo) RecordInclusion(transactionID string, blockNumber uint64, blockHash string) error {
+	_, err := repoDep.DB.Writer().Exec(recordInclusionQuery, transactionID, int64(blockNumber), blockHash)
+	if err != nil {
+		log.Printf("Error recording block of transaction: %v", err)
+		return fmt.Errorf("error recording transaction block: %v", err)
+	}
+	return nil
+}
+
+// Moves a pending transaction to its final status
+func (repoDep *transactionRepo) SettleTransaction(transactionID string, status domain.TransactionStatus, blockNumber uint64, blockHash string) error {
+	_, err := repoDep.DB.Writer().Exec(settleTransactionQuery, transactionID, status, int64(blockNumber), blockHash)
+	if err != nil {
+		log.Printf("Error settling transaction: %v", err)
+		return fmt.Errorf("error settling transaction: %v", err)
+	}
+	return nil
+}
+
+// Returns the settled transfers mined in blockNumber or later, oldest block first
+func (repoDep *transactionRepo) GetSettledSince(blockNumber uint64, limit int) ([]SettledTransfer, error) {
+	rows, err := repoDep.DB.Writer().Query(getSettledSinceQuery, int64(blockNumber), limit)
+	if err != nil {
+		log.Printf("Error executing query: %v", err)
+		return nil, fmt.Errorf("error fetching settled transactions: %v", err)
+	}
+	defer rows.Close()
+
+	transfers := []SettledTransfer{}
+	for rows.Next() {
+		var transfer SettledTransfer
+		var number int64
+		if err := rows.Scan(&transfer.TransactionID, &transfer.TxHash, &transfer.Status, &number, &transfer.BlockHash); err != nil {
+			return nil, fmt.Errorf("error scanning settled transaction: %v", err)
+		}
+		transfer.BlockNumber = uint64(number)
+		transfers = append(transfers, transfer)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading settled transactions: %v", err)
+	}
+	return transfers, nil
+}
+
+// Puts a transfer whose block was orphaned back to pending so it is settled again, false if it
+// was already reverted or settled in another block
+func (repoDep *transactionRepo) RevertSettlement(transactionID, blockHash string) (bool, error) {
+	result, err := repoDep.DB.Writer().Exec(revertSettlementQuery, transactionID, blockHash)
+	if err != nil {
+		log.Printf("Error reverting settlement of transaction %s: %v", transactionID, err)
+		return false, fmt.Errorf("error reverting transaction settlement: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	return rowsAffected > 0, err
+}
+
+// Points a pending transaction at the replacement broadcast, keeping the hashes it replaced.
+// Fails with a conflict when the transaction changed since oldTxHash was read.
+func (repoDep *transactionRepo) ReplaceBroadcast(transactionID, oldTxHash, newTxHash, gasPrice string, cancel bool) error {
+	result, err := repoDep.DB.Writer().Exec(replaceBroadcastQuery, transactionID, oldTxHash, newTxHash, gasPrice, cancel)
+	if err != nil {
+		log.Printf("Error replacing transaction broadcast: %v", err)
+		return fmt.Errorf("error replacing transaction broadcast: %v", err)
+	}
+	if rowsAffected, err := result.RowsAffected(); err == nil && rowsAffected == 0 {
+		return utils.Conflict("transaction was settled or replaced concurrently")
+	}
+	return nil
+}
+
+// Scans one row of selectPendingQuery
+func scanPendingTransfer(row interface{ Scan(dest ...any) error }) (PendingTransfer, error) {
+	var transfer PendingTransfer
+	err := row.Scan(&transfer.TransactionID, &transfer.TxHash, &transfer.SenderUserID, &transfer.ReceiverUserID, &transfer.SenderWalletID, &transfer.ReceiverWalletID, &transfer.Amount, &transfer.Status, &transfer.CreatedAt,
+		&transfer.BlockNumber, &transfer.RequiredConfirmations, &transfer.Nonce, &transfer.GasPrice, pq.Array(&transfer.PreviousTxHashes), &transfer.CancelRequested, &transfer.BroadcastAt)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("Error scanning pending transaction: %v", err)
+		}
+		return transfer, err
+	}
+	return transfer, nil
+}
+
+// Reads all transaction rows and closes the result set
+func scanTransactions(rows *sql.Rows) ([]Transaction, error) {
+	defer rows.Close()
+
+	transactions := []Transaction{}
+	for rows.Next() {
+		var txn Transaction
+		if err := rows.Scan(&txn.TransactionID, &txn.TxHash, &txn.SenderUserID, &txn.ReceiverUserID, &txn.SenderWalletID, &txn.ReceiverWalletID, &txn.Amount, &txn.Status, &txn.CreatedAt, &txn.BlockNumber, &txn.RequiredConfirmations); err != nil {
+			log.Printf("Error scanning transaction row: %v", err)
+			return nil, fmt.Errorf("error reading transactions: %v", err)
+		}
+		transactions = append(transactions, txn)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading transactions: %v", err)
+	}
+	return transactions, nil
+}
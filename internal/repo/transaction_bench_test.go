@@ -0,0 +1,84 @@
+package repo
+
+import "testing"
+
+// Page size of the transaction history endpoint
+const benchmarkPageSize = 20
+
+// benchmarkHistoryUser returns the user with the most transactions, the worst case of the history
+func benchmarkHistoryUser(b *testing.B, router *DBRouter) string {
+	b.Helper()
+	var userID string
+	err := router.Reader().QueryRow(`SELECT user_id FROM (SELECT sender_user_id AS user_id FROM transactions
+		UNION ALL SELECT receiver_user_id FROM transactions) t GROUP BY user_id ORDER BY COUNT(*) DESC LIMIT 1`).Scan(&userID)
+	if err != nil {
+		b.Skipf("no transactions to benchmark, seed the database first: %v", err)
+	}
+	return userID
+}
+
+func BenchmarkGetTransactionsFirstPage(b *testing.B) {
+	router := NewDBRouter(testDB(b))
+	transactionRepo := NewTransactionRepo(router)
+	userID := benchmarkHistoryUser(b, router)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := transactionRepo.GetTransactionsAfter(userID, benchmarkPageSize, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// Walks the whole history page by page with the keyset cursor, the cost of a page should not grow
+// with its depth
+func BenchmarkGetTransactionsCursorWalk(b *testing.B) {
+	router := NewDBRouter(testDB(b))
+	transactionRepo := NewTransactionRepo(router)
+	userID := benchmarkHistoryUser(b, router)
+
+	b.ResetTimer()
+	pages := 0
+	for i := 0; i < b.N; i++ {
+		var cursor *TransactionCursor
+		for {
+			transactions, err := transactionRepo.GetTransactionsAfter(userID, benchmarkPageSize, cursor)
+			if err != nil {
+				b.Fatal(err)
+			}
+			pages++
+			if len(transactions) < benchmarkPageSize {
+				break
+			}
+			last := transactions[len(transactions)-1]
+			cursor = &TransactionCursor{CreatedAt: last.CreatedAt, TransactionID: last.TransactionID}
+		}
+	}
+	b.ReportMetric(float64(pages)/float64(b.N), "pages/op")
+}
+
+// Deep offset pages for comparison with the cursor walk
+func BenchmarkGetTransactionsOffsetPage(b *testing.B) {
+	router := NewDBRouter(testDB(b))
+	transactionRepo := NewTransactionRepo(router)
+	userID := benchmarkHistoryUser(b, router)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := transactionRepo.GetTransactions(userID, benchmarkPageSize, 10*benchmarkPageSize); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBuildTransactionsQuery(b *testing.B) {
+	cursor := TransactionCursor{TransactionID: "7f1c1f1e-8f55-4c55-9b8e-6a4f2c0d9a11"}
+	for i := 0; i < b.N; i++ {
+		newSelectQuery(selectTransactionsQuery).
+			where("sender_user_id = ? OR receiver_user_id = ?", "user", "user").
+			where("(created_at, transaction_id) < (?, ?)", cursor.CreatedAt, cursor.TransactionID).
+			order(transactionsOrder).
+			page(benchmarkPageSize, 0).
+			build()
+	}
+}
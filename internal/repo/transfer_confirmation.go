@@ -0,0 +1,295 @@
+package repo
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+// TOTPSecret is the authenticator secret of a user, usable for transfers once confirmed
+type TOTPSecret struct {
+	Secret       string
+	Confirmed    bool
+	LastUsedStep int64
+}
+
+// TransferDevice is a device allowed to confirm transfers, only the hash of its token is stored
+type TransferDevice struct {
+	DeviceID   string
+	UserID     string
+	Name       string
+	TokenHash  string
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+}
+
+// TransactionPIN is the hashed PIN of a user with its failed attempts, LockedUntil is set once
+// too many attempts failed
+type TransactionPIN struct {
+	PINHash        string
+	FailedAttempts int
+	LockedUntil    *time.Time
+}
+
+// All Transfer Confirmation Queries
+const (
+	getConfirmationModeQuery = `SELECT mode FROM transfer_confirmation_settings WHERE user_id = $1`
+	setConfirmationModeQuery = `INSERT INTO transfer_confirmation_settings (user_id, mode) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET mode = EXCLUDED.mode, updated_at = NOW()`
+	// Enrolling again replaces the secret, which then has to be confirmed again
+	saveTOTPSecretQuery = `INSERT INTO transfer_totp_secrets (user_id, secret, key_version, cipher) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, key_version = EXCLUDED.key_version, cipher = EXCLUDED.cipher,
+		confirmed_at = NULL, last_used_step = 0, created_at = NOW()`
+	getTOTPSecretQuery     = `SELECT secret, key_version, cipher, confirmed_at IS NOT NULL, last_used_step FROM transfer_totp_secrets WHERE user_id = $1`
+	confirmTOTPSecretQuery = `UPDATE transfer_totp_secrets SET confirmed_at = NOW(), last_used_step = $2
+		WHERE user_id = $1 AND confirmed_at IS NULL AND last_used_step < $2`
+	// Only succeeds for a step later than the last one used, so a code cannot be replayed
+	useTOTPStepQuery = `UPDATE transfer_totp_secrets SET last_used_step = $2
+		WHERE user_id = $1 AND confirmed_at IS NOT NULL AND last_used_step < $2`
+	insertTransferDeviceQuery = `INSERT INTO transfer_devices (user_id, name, token_hash) VALUES ($1, $2, $3) RETURNING device_id, created_at`
+	getTransferDevicesQuery   = `SELECT device_id, user_id, name, token_hash, created_at, last_used_at FROM transfer_devices
+		WHERE user_id = $1 AND revoked_at IS NULL ORDER BY created_at DESC`
+	useTransferDeviceQuery = `UPDATE transfer_devices SET last_used_at = NOW()
+		WHERE user_id = $1 AND token_hash = $2 AND revoked_at IS NULL`
+	revokeTransferDeviceQuery = `UPDATE transfer_devices SET revoked_at = NOW() WHERE device_id = $1 AND user_id = $2 AND revoked_at IS NULL`
+	// Setting a PIN also lifts any lockout, it is how a forgotten or locked PIN is reset
+	setTransactionPINQuery = `INSERT INTO transaction_pins (user_id, pin_hash) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET pin_hash = EXCLUDED.pin_hash, failed_attempts = 0, locked_until = NULL, updated_at = NOW()`
+	getTransactionPINQuery = `SELECT pin_hash, failed_attempts, locked_until FROM transaction_pins WHERE user_id = $1`
+	// Counts an attempt before it is checked, so concurrent guesses cannot exceed the limit. The
+	// attempt reaching the limit locks the PIN, and every later failure locks it again.
+	claimPINAttemptQuery = `UPDATE transaction_pins SET failed_attempts = failed_attempts + 1,
+		locked_until = CASE WHEN failed_attempts + 1 >= $2 THEN NOW() + make_interval(secs => $3) END
+		WHERE user_id = $1 AND (locked_until IS NULL OR locked_until <= NOW())
+		RETURNING pin_hash, failed_attempts, locked_until`
+	resetPINAttemptsQuery     = `UPDATE transaction_pins SET failed_attempts = 0, locked_until = NULL WHERE user_id = $1`
+	deleteTransactionPINQuery = `DELETE FROM transaction_pins WHERE user_id = $1`
+)
+
+type transferConfirmationRepo struct {
+	DB      *sql.DB
+	keyRing PrivateKeyRing
+}
+
+type TransferConfirmationStorer interface {
+	GetConfirmationMode(userID string) (string, error)
+	SetConfirmationMode(userID, mode string) error
+	SaveTOTPSecret(userID, secret string) error
+	GetTOTPSecret(userID string) (TOTPSecret, error)
+	ConfirmTOTPSecret(userID string, step int64) (bool, error)
+	UseTOTPStep(userID string, step int64) (bool, error)
+	CreateTransferDevice(device TransferDevice) (TransferDevice, error)
+	GetTransferDevices(userID string) ([]TransferDevice, error)
+	UseTransferDevice(userID, tokenHash string) (bool, error)
+	RevokeTransferDevice(userID, deviceID string) error
+	SetTransactionPIN(userID, pinHash string) error
+	GetTransactionPIN(userID string) (TransactionPIN, error)
+	ClaimPINAttempt(userID string, maxAttempts int, lockout time.Duration) (TransactionPIN, bool, error)
+	ResetPINAttempts(userID string) error
+	DeleteTransactionPIN(userID string) error
+}
+
+// Constructor function, TOTP secrets are encrypted with the same key ring as wallet private keys
+func NewTransferConfirmationRepo(db *sql.DB, keyRing PrivateKeyRing) TransferConfirmationStorer {
+	return &transferConfirmationRepo{DB: db, keyRing: keyRing}
+}
+
+// Returns the confirmation mode chosen by the user, empty if they never chose one
+func (repoDep *transferConfirmationRepo) GetConfirmationMode(userID string) (string, error) {
+	var mode string
+	err := repoDep.DB.QueryRow(getConfirmationModeQuery, userID).Scan(&mode)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		log.Printf("Error fetching confirmation mode of user %s: %v", userID, err)
+		return "", fmt.Errorf("error fetching confirmation mode: %v", err)
+	}
+	return mode, nil
+}
+
+// Stores the confirmation mode of the user
+func (repoDep *transferConfirmationRepo) SetConfirmationMode(userID, mode string) error {
+	if _, err := repoDep.DB.Exec(setConfirmationModeQuery, userID, mode); err != nil {
+		log.Printf("Error setting confirmation mode of user %s: %v", userID, err)
+		return fmt.Errorf("error setting confirmation mode: %v", err)
+	}
+	return nil
+}
+
+// Encrypts and stores a new unconfirmed TOTP secret, replacing any previous one
+func (repoDep *transferConfirmationRepo) SaveTOTPSecret(userID, secret string) error {
+	key, err := repoDep.keyRing.sealingKey()
+	if err != nil {
+		return fmt.Errorf("failed to encrypt TOTP secret: %v", err)
+	}
+	encryptedSecret, err := sealPrivateKey(secret, key, userID, repoDep.keyRing.Current)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt TOTP secret: %v", err)
+	}
+
+	if _, err := repoDep.DB.Exec(saveTOTPSecretQuery, userID, encryptedSecret, repoDep.keyRing.Current, cipherGCM); err != nil {
+		log.Printf("Error storing TOTP secret of user %s: %v", userID, err)
+		return fmt.Errorf("error storing TOTP secret: %v", err)
+	}
+	return nil
+}
+
+// Retrieves and decrypts the TOTP secret of the user
+func (repoDep *transferConfirmationRepo) GetTOTPSecret(userID string) (TOTPSecret, error) {
+	var totp TOTPSecret
+	var encryptedSecret, cipherName string
+	var keyVersion int
+	err := repoDep.DB.QueryRow(getTOTPSecretQuery, userID).Scan(&encryptedSecret, &keyVersion, &cipherName, &totp.Confirmed, &totp.LastUsedStep)
+	if err != nil {
+		return totp, utils.FromDBError("authenticator", err)
+	}
+	if cipherName != cipherGCM {
+		return totp, fmt.Errorf("unknown cipher %q", cipherName)
+	}
+
+	key, err := repoDep.keyRing.key(keyVersion)
+	if err != nil {
+		return totp, err
+	}
+	totp.Secret, err = openPrivateKey(encryptedSecret, key, userID, keyVersion)
+	if err != nil {
+		return totp, fmt.Errorf("failed to decrypt TOTP secret: %v", err)
+	}
+	return totp, nil
+}
+
+// Confirms a pending TOTP secret with the step of the code that proved it, false if there was
+// nothing to confirm
+func (repoDep *transferConfirmationRepo) ConfirmTOTPSecret(userID string, step int64) (bool, error) {
+	result, err := repoDep.DB.Exec(confirmTOTPSecretQuery, userID, step)
+	if err != nil {
+		log.Printf("Error confirming TOTP secret of user %s: %v", userID, err)
+		return false, fmt.Errorf("error confirming TOTP secret: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	return rowsAffected > 0, err
+}
+
+// Records a TOTP step as used, false if it or a later step was already used
+func (repoDep *transferConfirmationRepo) UseTOTPStep(userID string, step int64) (bool, error) {
+	result, err := repoDep.DB.Exec(useTOTPStepQuery, userID, step)
+	if err != nil {
+		log.Printf("Error recording TOTP step of user %s: %v", userID, err)
+		return false, fmt.Errorf("error recording TOTP code: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	return rowsAffected > 0, err
+}
+
+// Stores a device and returns it with the generated ID and timestamp
+func (repoDep *transferConfirmationRepo) CreateTransferDevice(device TransferDevice) (TransferDevice, error) {
+	err := repoDep.DB.QueryRow(insertTransferDeviceQuery, device.UserID, device.Name, device.TokenHash).Scan(&device.DeviceID, &device.CreatedAt)
+	if err != nil {
+		log.Printf("Error inserting transfer device: %v", err)
+		return device, fmt.Errorf("error creating transfer device: %v", err)
+	}
+	return device, nil
+}
+
+// Returns the devices of the user that have not been revoked, newest first
+func (repoDep *transferConfirmationRepo) GetTransferDevices(userID string) ([]TransferDevice, error) {
+	rows, err := repoDep.DB.Query(getTransferDevicesQuery, userID)
+	if err != nil {
+		log.Printf("Error executing query: %v", err)
+		return nil, fmt.Errorf("error fetching transfer devices: %v", err)
+	}
+	defer rows.Close()
+
+	devices := []TransferDevice{}
+	for rows.Next() {
+		var device TransferDevice
+		if err := rows.Scan(&device.DeviceID, &device.UserID, &device.Name, &device.TokenHash, &device.CreatedAt, &device.LastUsedAt); err != nil {
+			return nil, fmt.Errorf("error reading transfer devices: %v", err)
+		}
+		devices = append(devices, device)
+	}
+	return devices, rows.Err()
+}
+
+// Records the use of a device token, false if the user has no active device with that token
+func (repoDep *transferConfirmationRepo) UseTransferDevice(userID, tokenHash string) (bool, error) {
+	result, err := repoDep.DB.Exec(useTransferDeviceQuery, userID, tokenHash)
+	if err != nil {
+		log.Printf("Error recording transfer device use: %v", err)
+		return false, fmt.Errorf("error checking device token: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	return rowsAffected > 0, err
+}
+
+// Revokes a device of the user
+func (repoDep *transferConfirmationRepo) RevokeTransferDevice(userID, deviceID string) error {
+	result, err := repoDep.DB.Exec(revokeTransferDeviceQuery, deviceID, userID)
+	if err != nil {
+		log.Printf("Error revoking transfer device: %v", err)
+		return fmt.Errorf("error revoking transfer device: %v", err)
+	}
+	if rowsAffected, err := result.RowsAffected(); err == nil && rowsAffected == 0 {
+		return utils.NotFound("device not found", nil)
+	}
+	return nil
+}
+
+// Stores the hashed PIN of the user, replacing any previous one and clearing its failed attempts
+func (repoDep *transferConfirmationRepo) SetTransactionPIN(userID, pinHash string) error {
+	if _, err := repoDep.DB.Exec(setTransactionPINQuery, userID, pinHash); err != nil {
+		log.Printf("Error setting transaction PIN of user %s: %v", userID, err)
+		return fmt.Errorf("error setting transaction PIN: %v", err)
+	}
+	return nil
+}
+
+// Returns the PIN of the user
+func (repoDep *transferConfirmationRepo) GetTransactionPIN(userID string) (TransactionPIN, error) {
+	var pin TransactionPIN
+	err := repoDep.DB.QueryRow(getTransactionPINQuery, userID).Scan(&pin.PINHash, &pin.FailedAttempts, &pin.LockedUntil)
+	if err != nil {
+		return pin, utils.FromDBError("transaction PIN", err)
+	}
+	return pin, nil
+}
+
+// Counts an attempt against the PIN and returns it to be checked, false if it is locked
+func (repoDep *transferConfirmationRepo) ClaimPINAttempt(userID string, maxAttempts int, lockout time.Duration) (TransactionPIN, bool, error) {
+	var pin TransactionPIN
+	err := repoDep.DB.QueryRow(claimPINAttemptQuery, userID, maxAttempts, lockout.Seconds()).Scan(&pin.PINHash, &pin.FailedAttempts, &pin.LockedUntil)
+	if err == sql.ErrNoRows {
+		return pin, false, nil
+	}
+	if err != nil {
+		log.Printf("Error counting PIN attempt of user %s: %v", userID, err)
+		return pin, false, fmt.Errorf("error checking transaction PIN: %v", err)
+	}
+	return pin, true, nil
+}
+
+// Clears the failed attempts of the PIN after a correct one
+func (repoDep *transferConfirmationRepo) ResetPINAttempts(userID string) error {
+	if _, err := repoDep.DB.Exec(resetPINAttemptsQuery, userID); err != nil {
+		log.Printf("Error resetting PIN attempts of user %s: %v", userID, err)
+		return fmt.Errorf("error resetting PIN attempts: %v", err)
+	}
+	return nil
+}
+
+// Removes the PIN of the user
+func (repoDep *transferConfirmationRepo) DeleteTransactionPIN(userID string) error {
+	result, err := repoDep.DB.Exec(deleteTransactionPINQuery, userID)
+	if err != nil {
+		log.Printf("Error deleting transaction PIN of user %s: %v", userID, err)
+		return fmt.Errorf("error deleting transaction PIN: %v", err)
+	}
+	if rowsAffected, err := result.RowsAffected(); err == nil && rowsAffected == 0 {
+		return utils.NotFound("transaction PIN not found", nil)
+	}
+	return nil
+}
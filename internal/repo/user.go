@@ -3,74 +3,143 @@ package repo
 import (
 	"database/sql"
 	_ "database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/domain"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
 )
 
-// User Regular struct
+// User Regular struct, responses are built from it by the user serializer and the password hash is
+// kept out of any JSON encoding of it
 type User struct {
-	ID        string
-	Username  string
-	Email     string
-	Password  string
-	CreatedAt time.Time
+	ID            string
+	Username      string
+	Email         string
+	Password      string `json:"-"`
+	CreatedAt     time.Time
+	AccountStatus domain.AccountStatus
+	// Login tokens issued before this time are revoked, nil while none were
+	TokensValidAfter      *time.Time
+	PasswordResetRequired bool
+}
+
+// UserWithRole is a user along with the highest built-in role assigned to it, 0 when it has none
+type UserWithRole struct {
+	User
+	Role int
+}
+
+// AccountClosure records who closed an account and where its funds were swept
+type AccountClosure struct {
+	UserID       string
+	SweepAddress string
+	SweepTxHash  string
+	ClosedBy     string
+	Reason       string
 }
 
 // All User Queries
 const (
-	roleAssignmentQuery             = `INSERT INTO user_roles_assignment(user_id, role_id) VALUES ($1, $2)`
-	userRegisterQuery               = `INSERT INTO users (username, email, password_hash, full_name, date_of_birth) VALUES ($1, $2, $3, $4, $5)`
-	getUserByEmailQuery             = `SELECT user_id, username, email, password_hash, created_at FROM users WHERE email=$1`
-	updateLastLoginQuery            = `UPDATE users SET last_login = $1 WHERE user_id = $2`
-	usernameAlreadyInExistanceQuery = `SELECT CASE WHEN username = $1 THEN TRUE ELSE FALSE END FROM users`
-	emailAlreadyInExistanceQuery    = `SELECT CASE WHEN email = $1 THEN TRUE ELSE FALSE END FROM users`
-	getUserRolesQuery               = `SELECT MAX(role_id) FROM user_roles_assignment WHERE user_id = $1`
-	updateWalletIDQuery             = `INSERT INTO wallets (wallet_id,user_id) VALUES ($1,$2)`
+	roleAssignmentQuery       = `INSERT INTO user_roles_assignment(user_id, role_id) VALUES ($1, $2)`
+	userRegisterQuery         = `INSERT INTO users (username, email, password_hash) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING RETURNING user_id`
+	getUserByEmailQuery       = `SELECT user_id, username, email, password_hash, created_at, account_status, tokens_valid_after, password_reset_required FROM users WHERE email=$1`
+	getUserByIDQuery          = `SELECT user_id, username, email, password_hash, created_at, account_status, tokens_valid_after, password_reset_required FROM users WHERE user_id=$1`
+	closeAccountQuery         = `UPDATE users SET account_status = 'closed', closed_at = NOW() WHERE user_id = $1 AND account_status <> 'closed'`
+	insertAccountClosureQuery = `INSERT INTO account_closures (user_id, sweep_address, sweep_tx_hash, closed_by, reason) VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), $4, $5)`
+	updateLastLoginQuery      = `UPDATE users SET last_login = $1 WHERE user_id = $2`
+	getTakenFieldsQuery       = `SELECT EXISTS (SELECT 1 FROM users WHERE username = $1), EXISTS (SELECT 1 FROM users WHERE email = $2)`
+	getUserRolesQuery         = `SELECT MAX(role_id) FROM user_roles_assignment WHERE user_id = $1 AND role_id IN (SELECT role_id FROM role_definitions WHERE built_in)`
+	updateWalletIDQuery       = `INSERT INTO wallets (wallet_id,user_id) VALUES ($1,$2)`
+	getIdentityUserIDQuery    = `SELECT user_id FROM user_identities WHERE issuer = $1 AND subject = $2`
+	linkIdentityQuery         = `INSERT INTO user_identities (issuer, subject, user_id, email) VALUES ($1, $2, $3, $4)`
+	getUserTimeZoneQuery      = `SELECT time_zone FROM users WHERE user_id = $1`
+	setUserTimeZoneQuery      = `UPDATE users SET time_zone = $2 WHERE user_id = $1`
+	revokeSessionsQuery       = `UPDATE users SET tokens_valid_after = NOW(), password_reset_required = TRUE WHERE user_id = $1`
+	resetPasswordQuery        = `UPDATE users SET password_hash = $2, password_reset_required = FALSE, tokens_valid_after = NOW() WHERE user_id = $1`
+
+	// The user and its highest built-in role in one round trip, users.user_id being the key makes every
+	// column of users available next to the aggregate
+	selectUserWithRoleQuery = `SELECT u.user_id, u.username, u.email, u.password_hash, u.created_at, u.account_status, u.tokens_valid_after,
+			u.password_reset_required, COALESCE(MAX(ura.role_id), 0)
+		FROM users u
+		LEFT JOIN user_roles_assignment ura ON ura.user_id = u.user_id AND ura.role_id IN (SELECT role_id FROM role_definitions WHERE built_in)`
+	getUserWithRoleByEmailQuery = selectUserWithRoleQuery + ` WHERE u.email = $1 GROUP BY u.user_id`
+	getUserWithRoleByIDQuery    = selectUserWithRoleQuery + ` WHERE u.user_id = $1 GROUP BY u.user_id`
 )
 
 type userRepo struct {
-	DB *sql.DB
+	DB      *sql.DB
+	keyRing PrivateKeyRing
 }
 
 type UserStorer interface {
-	CreateUser(username, email, passwordHash, fullName, dob, walletAddress string, role int) error
+	CreateUser(username, email, passwordHash, fullName string, dob *time.Time, walletAddress string, role int) error
 	GetUserByEmail(email string) (User, error)
 	UpdateLastLogin(userID string) error
-	UserExists(userName, email string) (usernameAlreadyInExistance, emailAlreadyInExistance bool, err error)
+	CheckAvailable(username, email string) error
 	GetUserHighestRole(userID string) (int, error)
+	GetUserByID(userID string) (User, error)
+	GetUserWithRoleByEmail(email string) (UserWithRole, error)
+	GetUserWithRoleByID(userID string) (UserWithRole, error)
+	CloseAccount(closure AccountClosure) error
+	GetIdentityUserID(issuer, subject string) (string, error)
+	LinkIdentity(issuer, subject, userID, email string) error
+	GetUserLocation(userID string) (*time.Location, error)
+	SetUserTimeZone(userID, timeZone string) error
+	RevokeSessions(userID string) error
+	ResetPassword(userID, passwordHash string) error
+	GetUserPII(userID string) (UserPII, error)
+	CountUserPIIToEncrypt() (int64, error)
+	EncryptUserPII(batchSize int) (int64, error)
 }
 
 // Constructor function
-func NewUserRepo(db *sql.DB) UserStorer {
-	return &userRepo{DB: db}
+func NewUserRepo(db *sql.DB, keyRing PrivateKeyRing) UserStorer {
+	return &userRepo{DB: db, keyRing: keyRing}
 }
 
-// Creates a new user in DB
-func (repoDep *userRepo) CreateUser(username, email, passwordHash, fullName, dob, walletAddress string, role int) error {
-	_, err := repoDep.DB.Exec(userRegisterQuery, username, email, passwordHash, fullName, dob)
+// Creates a new user in DB with its personal data encrypted. The insert is skipped when the unique
+// indexes find the username or email taken, also by a concurrent signup, and a conflict error names
+// the fields taken.
+func (repoDep *userRepo) CreateUser(username, email, passwordHash, fullName string, dob *time.Time, walletAddress string, role int) error {
+	tx, err := repoDep.DB.Begin()
 	if err != nil {
-		log.Printf("Error inserting user into database: %v", err.Error())
-		return err
+		return fmt.Errorf("error starting transaction: %v", err)
 	}
+	defer tx.Rollback()
 
-	//Retrieveing the user object from email
-	user, err := repoDep.GetUserByEmail(email)
-
+	var userID string
+	err = tx.QueryRow(userRegisterQuery, username, email, passwordHash).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		if err := repoDep.CheckAvailable(username, email); err != nil {
+			return err
+		}
+		// The conflicting user was removed again in the meantime
+		return utils.Conflict("Username or email already taken")
+	}
 	if err != nil {
-		log.Printf("Error Finding the User related to email ID %v", &email)
+		log.Printf("Error inserting user into database: %v", err.Error())
+		return err
+	}
+	if err := repoDep.storeUserPII(tx, userID, UserPII{FullName: fullName, DateOfBirth: dob}); err != nil {
 		return err
 	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing user: %v", err)
+	}
 
 	// Assigning Role to user
-	_, err = repoDep.DB.Exec(roleAssignmentQuery, user.ID, role)
+	_, err = repoDep.DB.Exec(roleAssignmentQuery, userID, role)
 
 	if err != nil {
 		log.Println("Error Writing the role information realted to user in user_roles_assignment table")
 	}
 
 	//Update wallet_id In wallets table
-	_, err = repoDep.DB.Exec(updateWalletIDQuery, walletAddress, user.ID)
+	_, err = repoDep.DB.Exec(updateWalletIDQuery, walletAddress, userID)
 	if err != nil {
 		log.Println("Error Occured While Inserting data into wallet Table")
 	}
@@ -81,10 +150,73 @@ func (repoDep *userRepo) CreateUser(username, email, passwordHash, fullName, dob
 // Returnes a user object by passing email
 func (repoDep *userRepo) GetUserByEmail(email string) (User, error) {
 	var user User
-	err := repoDep.DB.QueryRow(getUserByEmailQuery, email).Scan(&user.ID, &user.Username, &user.Email, &user.Password, &user.CreatedAt)
+	err := repoDep.DB.QueryRow(getUserByEmailQuery, email).Scan(&user.ID, &user.Username, &user.Email, &user.Password, &user.CreatedAt, &user.AccountStatus,
+		&user.TokensValidAfter, &user.PasswordResetRequired)
+	return user, err
+}
+
+// Returnes a user object by passing user_id
+func (repoDep *userRepo) GetUserByID(userID string) (User, error) {
+	var user User
+	err := repoDep.DB.QueryRow(getUserByIDQuery, userID).Scan(&user.ID, &user.Username, &user.Email, &user.Password, &user.CreatedAt, &user.AccountStatus,
+		&user.TokensValidAfter, &user.PasswordResetRequired)
+	if err != nil {
+		return user, utils.FromDBError("user", err)
+	}
+	return user, nil
+}
+
+// Returns a user by email along with its highest built-in role
+func (repoDep *userRepo) GetUserWithRoleByEmail(email string) (UserWithRole, error) {
+	user, err := scanUserWithRole(repoDep.DB.QueryRow(getUserWithRoleByEmailQuery, email))
+	if err != nil {
+		return user, utils.FromDBError("user", err)
+	}
+	return user, nil
+}
+
+// Returns a user by user_id along with its highest built-in role
+func (repoDep *userRepo) GetUserWithRoleByID(userID string) (UserWithRole, error) {
+	user, err := scanUserWithRole(repoDep.DB.QueryRow(getUserWithRoleByIDQuery, userID))
+	if err != nil {
+		return user, utils.FromDBError("user", err)
+	}
+	return user, nil
+}
+
+// Scans one row of the user columns followed by the role
+func scanUserWithRole(row interface{ Scan(dest ...any) error }) (UserWithRole, error) {
+	var user UserWithRole
+	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.Password, &user.CreatedAt, &user.AccountStatus, &user.TokensValidAfter,
+		&user.PasswordResetRequired, &user.Role)
 	return user, err
 }
 
+// Marks the account closed and stores the closure record in one transaction
+func (repoDep *userRepo) CloseAccount(closure AccountClosure) error {
+	tx, err := repoDep.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(closeAccountQuery, closure.UserID)
+	if err != nil {
+		log.Printf("Error closing account %s: %v", closure.UserID, err)
+		return fmt.Errorf("error closing account: %v", err)
+	}
+	if rowsAffected, err := result.RowsAffected(); err != nil || rowsAffected == 0 {
+		return utils.Conflict("account is already closed")
+	}
+
+	if _, err := tx.Exec(insertAccountClosureQuery, closure.UserID, closure.SweepAddress, closure.SweepTxHash, closure.ClosedBy, closure.Reason); err != nil {
+		log.Printf("Error recording account closure %s: %v", closure.UserID, err)
+		return fmt.Errorf("error recording account closure: %v", err)
+	}
+
+	return tx.Commit()
+}
+
 // Updates the last login field in users table to current time
 func (repoDep *userRepo) UpdateLastLogin(userID string) error {
 	log.Print("Received the Request to update login time")
@@ -111,28 +243,29 @@ func (repoDep *userRepo) UpdateLastLogin(userID string) error {
 	return nil
 }
 
-// Returnes if User Already exists on the basis of email & username
-func (repoDep *userRepo) UserExists(userName, email string) (usernameAlreadyInExistance, emailAlreadyInExistance bool, err error) {
-
-	//Check if username already Exists
-	err = repoDep.DB.QueryRow(usernameAlreadyInExistanceQuery, userName).Scan(&usernameAlreadyInExistance)
-
-	if err != nil {
+// Returns a conflict error naming the fields another user already holds, nil when both are free
+func (repoDep *userRepo) CheckAvailable(username, email string) error {
+	var usernameTaken, emailTaken bool
+	if err := repoDep.DB.QueryRow(getTakenFieldsQuery, username, email).Scan(&usernameTaken, &emailTaken); err != nil {
 		log.Printf("Error Checking the user Existance status: %v", err)
-		return usernameAlreadyInExistance, emailAlreadyInExistance, err
+		return fmt.Errorf("error checking username and email: %v", err)
 	}
 
-	//Checking if Email Already Exists
-	err = repoDep.DB.QueryRow(emailAlreadyInExistanceQuery, email).Scan(&emailAlreadyInExistance)
-
-	if err != nil {
-		log.Printf("Error Checking the user Existance status: %v", err)
-		return usernameAlreadyInExistance, emailAlreadyInExistance, err
+	var taken []utils.FieldError
+	if usernameTaken {
+		taken = append(taken, utils.FieldError{Field: "username", Message: "is already taken"})
+	}
+	if emailTaken {
+		taken = append(taken, utils.FieldError{Field: "email", Message: "is already taken"})
 	}
-	return usernameAlreadyInExistance, emailAlreadyInExistance, err
+	if len(taken) > 0 {
+		return utils.ConflictFields("Username or email already taken", taken)
+	}
+	return nil
 }
 
-// GetHighestRole fetches the highest role assigned to a user based on user_id.
+// GetHighestRole fetches the highest built-in role assigned to a user based on user_id. Custom roles
+// only grant permissions.
 func (repoDep *userRepo) GetUserHighestRole(userID string) (int, error) {
 
 	var highestRoleLevel int
@@ -152,3 +285,69 @@ func (repoDep *userRepo) GetUserHighestRole(userID string) (int, error) {
 	// Return the highest role ID.
 	return highestRoleLevel, nil
 }
+
+// Returns the user linked to an external identity
+func (repoDep *userRepo) GetIdentityUserID(issuer, subject string) (string, error) {
+	var userID string
+	err := repoDep.DB.QueryRow(getIdentityUserIDQuery, issuer, subject).Scan(&userID)
+	if err != nil {
+		return "", utils.FromDBError("identity", err)
+	}
+	return userID, nil
+}
+
+// Links an external identity to a user
+func (repoDep *userRepo) LinkIdentity(issuer, subject, userID, email string) error {
+	_, err := repoDep.DB.Exec(linkIdentityQuery, issuer, subject, userID, email)
+	if err != nil {
+		log.Printf("Error linking identity for user %s: %v", userID, err)
+		return fmt.Errorf("error linking identity: %v", err)
+	}
+	return nil
+}
+
+// Returns the time zone the user's days and months are counted in, UTC if the stored zone is unknown
+func (repoDep *userRepo) GetUserLocation(userID string) (*time.Location, error) {
+	var timeZone string
+	if err := repoDep.DB.QueryRow(getUserTimeZoneQuery, userID).Scan(&timeZone); err != nil {
+		return time.UTC, utils.FromDBError("user", err)
+	}
+
+	location, err := time.LoadLocation(timeZone)
+	if err != nil {
+		log.Printf("Unknown time zone %q of user %s, using UTC: %v", timeZone, userID, err)
+		return time.UTC, nil
+	}
+	return location, nil
+}
+
+// Sets the IANA time zone of the user
+func (repoDep *userRepo) SetUserTimeZone(userID, timeZone string) error {
+	result, err := repoDep.DB.Exec(setUserTimeZoneQuery, userID, timeZone)
+	if err != nil {
+		log.Printf("Error setting time zone of %s: %v", userID, err)
+		return fmt.Errorf("error setting time zone: %v", err)
+	}
+	if rowsAffected, err := result.RowsAffected(); err == nil && rowsAffected == 0 {
+		return utils.NotFound("user not found", nil)
+	}
+	return nil
+}
+
+// Revokes every login token issued so far and blocks password sign-in until the password is reset
+func (repoDep *userRepo) RevokeSessions(userID string) error {
+	if _, err := repoDep.DB.Exec(revokeSessionsQuery, userID); err != nil {
+		log.Printf("Error revoking sessions of %s: %v", userID, err)
+		return fmt.Errorf("error revoking sessions: %v", err)
+	}
+	return nil
+}
+
+// Replaces the password and revokes the login tokens issued with the old one
+func (repoDep *userRepo) ResetPassword(userID, passwordHash string) error {
+	if _, err := repoDep.DB.Exec(resetPasswordQuery, userID, passwordHash); err != nil {
+		log.Printf("Error resetting password of %s: %v", userID, err)
+		return fmt.Errorf("error resetting password: %v", err)
+	}
+	return nil
+}
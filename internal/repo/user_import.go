@@ -0,0 +1,167 @@
+package repo
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+// UserImport is a bulk import of users uploaded by an admin
+type UserImport struct {
+	ImportID    string
+	CreatedBy   string
+	Status      string
+	CreatedAt   time.Time
+	CompletedAt *time.Time
+}
+
+// UserImportRow is one row of an import and its outcome, InitialFunding is empty when the row
+// asked for no preload
+type UserImportRow struct {
+	RowNumber      int
+	Email          string
+	FullName       string
+	Role           int
+	InitialFunding string
+	Status         string
+	UserID         string
+	WalletID       string
+	Error          string
+}
+
+// All User Import Queries
+const (
+	insertUserImportQuery    = `INSERT INTO user_imports (created_by) VALUES ($1) RETURNING import_id, status, created_at`
+	insertUserImportRowQuery = `INSERT INTO user_import_rows (import_id, row_number, email, full_name, role, initial_funding, status, error)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, '')::NUMERIC, $7, NULLIF($8, ''))`
+	getUserImportQuery       = `SELECT import_id, created_by, status, created_at, completed_at FROM user_imports WHERE import_id = $1`
+	getRunningImportIDsQuery = `SELECT import_id FROM user_imports WHERE status = 'running'`
+	getUserImportRowsQuery   = `SELECT row_number, email, full_name, role, COALESCE(initial_funding::TEXT, ''), status,
+		COALESCE(user_id::TEXT, ''), COALESCE(wallet_id, ''), COALESCE(error, '') FROM user_import_rows WHERE import_id = $1`
+	updateUserImportRowQuery = `UPDATE user_import_rows SET status = $3, user_id = NULLIF($4, '')::UUID, wallet_id = NULLIF($5, ''), error = NULLIF($6, '')
+		WHERE import_id = $1 AND row_number = $2`
+	completeUserImportQuery = `UPDATE user_imports SET status = 'completed', completed_at = NOW() WHERE import_id = $1`
+)
+
+type userImportRepo struct {
+	DB *sql.DB
+}
+
+type UserImportStorer interface {
+	CreateImport(createdBy string, rows []UserImportRow) (UserImport, error)
+	GetImport(importID string) (UserImport, error)
+	GetRunningImportIDs() ([]string, error)
+	GetImportRows(importID string, pendingOnly bool) ([]UserImportRow, error)
+	UpdateImportRow(importID string, row UserImportRow) error
+	CompleteImport(importID string) error
+}
+
+// Constructor function
+func NewUserImportRepo(db *sql.DB) UserImportStorer {
+	return &userImportRepo{DB: db}
+}
+
+// Stores an import with all of its rows in one transaction, rows that failed validation are stored failed
+func (repoDep *userImportRepo) CreateImport(createdBy string, rows []UserImportRow) (UserImport, error) {
+	userImport := UserImport{CreatedBy: createdBy}
+
+	tx, err := repoDep.DB.Begin()
+	if err != nil {
+		return userImport, fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := tx.QueryRow(insertUserImportQuery, createdBy).Scan(&userImport.ImportID, &userImport.Status, &userImport.CreatedAt); err != nil {
+		log.Printf("Error creating user import: %v", err)
+		return userImport, fmt.Errorf("error creating user import: %v", err)
+	}
+
+	stmt, err := tx.Prepare(insertUserImportRowQuery)
+	if err != nil {
+		return userImport, fmt.Errorf("error preparing user import rows: %v", err)
+	}
+	defer stmt.Close()
+	for _, row := range rows {
+		if _, err := stmt.Exec(userImport.ImportID, row.RowNumber, row.Email, row.FullName, row.Role, row.InitialFunding, row.Status, row.Error); err != nil {
+			log.Printf("Error storing row %d of user import %s: %v", row.RowNumber, userImport.ImportID, err)
+			return userImport, fmt.Errorf("error storing user import rows: %v", err)
+		}
+	}
+
+	return userImport, tx.Commit()
+}
+
+// Returns an import
+func (repoDep *userImportRepo) GetImport(importID string) (UserImport, error) {
+	var userImport UserImport
+	err := repoDep.DB.QueryRow(getUserImportQuery, importID).Scan(&userImport.ImportID, &userImport.CreatedBy, &userImport.Status, &userImport.CreatedAt, &userImport.CompletedAt)
+	if err != nil {
+		return userImport, utils.FromDBError("user import", err)
+	}
+	return userImport, nil
+}
+
+// Returns the imports not finished yet, for instance because the server restarted
+func (repoDep *userImportRepo) GetRunningImportIDs() ([]string, error) {
+	rows, err := repoDep.DB.Query(getRunningImportIDsQuery)
+	if err != nil {
+		log.Printf("Error executing query: %v", err)
+		return nil, fmt.Errorf("error fetching running user imports: %v", err)
+	}
+	defer rows.Close()
+
+	var importIDs []string
+	for rows.Next() {
+		var importID string
+		if err := rows.Scan(&importID); err != nil {
+			return nil, fmt.Errorf("error reading user imports: %v", err)
+		}
+		importIDs = append(importIDs, importID)
+	}
+	return importIDs, rows.Err()
+}
+
+// Returns the rows of an import in file order, only those not processed yet with pendingOnly
+func (repoDep *userImportRepo) GetImportRows(importID string, pendingOnly bool) ([]UserImportRow, error) {
+	query := getUserImportRowsQuery
+	if pendingOnly {
+		query += ` AND status = 'pending'`
+	}
+	rows, err := repoDep.DB.Query(query+` ORDER BY row_number`, importID)
+	if err != nil {
+		log.Printf("Error executing query: %v", err)
+		return nil, fmt.Errorf("error fetching user import rows: %v", err)
+	}
+	defer rows.Close()
+
+	importRows := []UserImportRow{}
+	for rows.Next() {
+		var row UserImportRow
+		if err := rows.Scan(&row.RowNumber, &row.Email, &row.FullName, &row.Role, &row.InitialFunding, &row.Status, &row.UserID, &row.WalletID, &row.Error); err != nil {
+			return nil, fmt.Errorf("error reading user import rows: %v", err)
+		}
+		importRows = append(importRows, row)
+	}
+	return importRows, rows.Err()
+}
+
+// Records the outcome of a row
+func (repoDep *userImportRepo) UpdateImportRow(importID string, row UserImportRow) error {
+	if _, err := repoDep.DB.Exec(updateUserImportRowQuery, importID, row.RowNumber, row.Status, row.UserID, row.WalletID, row.Error); err != nil {
+		log.Printf("Error updating row %d of user import %s: %v", row.RowNumber, importID, err)
+		return fmt.Errorf("error updating user import row: %v", err)
+	}
+	return nil
+}
+
+// Marks an import completed once every row has been processed
+func (repoDep *userImportRepo) CompleteImport(importID string) error {
+	if _, err := repoDep.DB.Exec(completeUserImportQuery, importID); err != nil {
+		log.Printf("Error completing user import %s: %v", importID, err)
+		return fmt.Errorf("error completing user import: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,261 @@
+package repo
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+// UserPII is the personal data a user signed up with, DateOfBirth is nil when none was given
+type UserPII struct {
+	FullName    string
+	DateOfBirth *time.Time
+}
+
+// Personal data is encrypted with a data key of its own per user, the data keys are encrypted with the
+// key ring like wallet private keys. Rotating the key ring re-encrypts the data keys only.
+const (
+	userDataKeySize     = 32
+	piiFieldFullName    = "full_name"
+	piiFieldDateOfBirth = "date_of_birth"
+	piiDateLayout       = "2006-01-02"
+)
+
+// All User PII Queries
+const (
+	insertUserDataKeyQuery = `INSERT INTO user_data_keys (user_id, data_key, key_version, cipher) VALUES ($1, $2, $3, $4) ON CONFLICT (user_id) DO NOTHING`
+	getUserDataKeyQuery    = `SELECT data_key, key_version, cipher FROM user_data_keys WHERE user_id = $1`
+	// Clears the plaintext columns rows from before encryption still hold
+	setUserPIIQuery = `UPDATE users SET full_name_encrypted = $2, date_of_birth_encrypted = $3, full_name = NULL, date_of_birth = NULL
+		WHERE user_id = $1`
+	getUserPIIQuery            = `SELECT COALESCE(full_name, ''), date_of_birth, full_name_encrypted, date_of_birth_encrypted FROM users WHERE user_id = $1`
+	countUserPIIToEncryptQuery = `SELECT COUNT(*) FROM users WHERE full_name IS NOT NULL OR date_of_birth IS NOT NULL`
+	// Locks one batch of users whose personal data is still in plaintext, concurrent backfills skip each other's rows
+	selectUserPIIToEncryptQuery = `SELECT user_id, COALESCE(full_name, ''), date_of_birth FROM users
+		WHERE full_name IS NOT NULL OR date_of_birth IS NOT NULL ORDER BY user_id LIMIT $1 FOR UPDATE SKIP LOCKED`
+)
+
+// Returns the personal data of a user. Users not reached by the backfill yet are read from the
+// plaintext columns.
+func (repoDep *userRepo) GetUserPII(userID string) (UserPII, error) {
+	var pii UserPII
+	var fullNameEncrypted, dateOfBirthEncrypted sql.NullString
+	err := repoDep.DB.QueryRow(getUserPIIQuery, userID).Scan(&pii.FullName, &pii.DateOfBirth, &fullNameEncrypted, &dateOfBirthEncrypted)
+	if err != nil {
+		return pii, utils.FromDBError("user", err)
+	}
+	if !fullNameEncrypted.Valid && !dateOfBirthEncrypted.Valid {
+		return pii, nil
+	}
+
+	var encryptedKey, cipherName string
+	var keyVersion int
+	if err := repoDep.DB.QueryRow(getUserDataKeyQuery, userID).Scan(&encryptedKey, &keyVersion, &cipherName); err != nil {
+		log.Printf("Error fetching data key of %s: %v", userID, err)
+		return pii, fmt.Errorf("error fetching data key: %v", err)
+	}
+	dataKey, err := repoDep.openDataKey(userID, encryptedKey, keyVersion, cipherName)
+	if err != nil {
+		return pii, err
+	}
+
+	if fullNameEncrypted.Valid {
+		if pii.FullName, err = openPII(fullNameEncrypted.String, dataKey, userID, piiFieldFullName); err != nil {
+			return pii, fmt.Errorf("failed to decrypt full name: %v", err)
+		}
+	}
+	if dateOfBirthEncrypted.Valid {
+		dob, err := openPII(dateOfBirthEncrypted.String, dataKey, userID, piiFieldDateOfBirth)
+		if err != nil {
+			return pii, fmt.Errorf("failed to decrypt date of birth: %v", err)
+		}
+		parsed, err := time.Parse(piiDateLayout, dob)
+		if err != nil {
+			return pii, fmt.Errorf("invalid date of birth: %v", err)
+		}
+		pii.DateOfBirth = &parsed
+	}
+	return pii, nil
+}
+
+// Returns the number of users whose personal data is still stored in plaintext
+func (repoDep *userRepo) CountUserPIIToEncrypt() (int64, error) {
+	var count int64
+	if err := repoDep.DB.QueryRow(countUserPIIToEncryptQuery).Scan(&count); err != nil {
+		log.Printf("Error counting personal data to encrypt: %v", err)
+		return 0, fmt.Errorf("error counting personal data to encrypt: %v", err)
+	}
+	return count, nil
+}
+
+// Encrypts the plaintext personal data of up to batchSize users in one transaction and returns the
+// number of users encrypted. Users left in plaintext are picked up again by the next call, so an
+// interrupted backfill can simply be resumed.
+func (repoDep *userRepo) EncryptUserPII(batchSize int) (int64, error) {
+	tx, err := repoDep.DB.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(selectUserPIIToEncryptQuery, batchSize)
+	if err != nil {
+		log.Printf("Error selecting personal data to encrypt: %v", err)
+		return 0, fmt.Errorf("error selecting personal data to encrypt: %v", err)
+	}
+
+	type plainUser struct {
+		userID string
+		pii    UserPII
+	}
+	var batch []plainUser
+	for rows.Next() {
+		var user plainUser
+		if err := rows.Scan(&user.userID, &user.pii.FullName, &user.pii.DateOfBirth); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("error scanning personal data: %v", err)
+		}
+		batch = append(batch, user)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error reading personal data: %v", err)
+	}
+
+	for _, user := range batch {
+		if err := repoDep.storeUserPII(tx, user.userID, user.pii); err != nil {
+			return 0, fmt.Errorf("user %s: %v", user.userID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error committing encrypted personal data: %v", err)
+	}
+	return int64(len(batch)), nil
+}
+
+// storeUserPII encrypts the personal data of a user with its data key, creating the data key on
+// first use, and clears the plaintext columns
+func (repoDep *userRepo) storeUserPII(tx *sql.Tx, userID string, pii UserPII) error {
+	dataKey, err := repoDep.userDataKey(tx, userID)
+	if err != nil {
+		return err
+	}
+
+	var fullName, dateOfBirth sql.NullString
+	if pii.FullName != "" {
+		if fullName.String, err = sealPII(pii.FullName, dataKey, userID, piiFieldFullName); err != nil {
+			return fmt.Errorf("failed to encrypt full name: %v", err)
+		}
+		fullName.Valid = true
+	}
+	if pii.DateOfBirth != nil {
+		if dateOfBirth.String, err = sealPII(pii.DateOfBirth.Format(piiDateLayout), dataKey, userID, piiFieldDateOfBirth); err != nil {
+			return fmt.Errorf("failed to encrypt date of birth: %v", err)
+		}
+		dateOfBirth.Valid = true
+	}
+
+	if _, err := tx.Exec(setUserPIIQuery, userID, fullName, dateOfBirth); err != nil {
+		log.Printf("Error storing personal data of %s: %v", userID, err)
+		return fmt.Errorf("error storing personal data: %v", err)
+	}
+	return nil
+}
+
+// userDataKey returns the data key of a user, a new random key is stored when the user has none yet
+func (repoDep *userRepo) userDataKey(tx *sql.Tx, userID string) ([]byte, error) {
+	var encryptedKey, cipherName string
+	var keyVersion int
+	err := tx.QueryRow(getUserDataKeyQuery, userID).Scan(&encryptedKey, &keyVersion, &cipherName)
+	if err == nil {
+		return repoDep.openDataKey(userID, encryptedKey, keyVersion, cipherName)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("Error fetching data key of %s: %v", userID, err)
+		return nil, fmt.Errorf("error fetching data key: %v", err)
+	}
+
+	dataKey := make([]byte, userDataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %v", err)
+	}
+	key, err := repoDep.keyRing.sealingKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt data key: %v", err)
+	}
+	encryptedKey, err = sealPrivateKey(hex.EncodeToString(dataKey), key, userID, repoDep.keyRing.Current)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt data key: %v", err)
+	}
+	if _, err := tx.Exec(insertUserDataKeyQuery, userID, encryptedKey, repoDep.keyRing.Current, cipherGCM); err != nil {
+		log.Printf("Error storing data key of %s: %v", userID, err)
+		return nil, fmt.Errorf("error storing data key: %v", err)
+	}
+	return dataKey, nil
+}
+
+// openDataKey decrypts a user data key stored encrypted with the key ring
+func (repoDep *userRepo) openDataKey(userID, encryptedKey string, keyVersion int, cipherName string) ([]byte, error) {
+	if cipherName != cipherGCM {
+		return nil, fmt.Errorf("unknown cipher %q", cipherName)
+	}
+	key, err := repoDep.keyRing.key(keyVersion)
+	if err != nil {
+		return nil, err
+	}
+	hexKey, err := openPrivateKey(encryptedKey, key, userID, keyVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt data key: %v", err)
+	}
+	dataKey, err := hex.DecodeString(hexKey)
+	if err != nil || len(dataKey) != userDataKeySize {
+		return nil, fmt.Errorf("invalid data key of user %s", userID)
+	}
+	return dataKey, nil
+}
+
+// sealPII encrypts one field of a user's personal data with AES-GCM. The user ID and field name are
+// authenticated with it, so a value copied to another user or column does not decrypt.
+func sealPII(value string, dataKey []byte, userID, field string) (string, error) {
+	gcm, err := newPrivateKeyGCM(dataKey)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %v", err)
+	}
+
+	sealed := gcm.Seal(nonce, nonce, []byte(value), []byte(userID+":"+field))
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// openPII reverses sealPII and fails on a wrong key or tampered ciphertext
+func openPII(encrypted string, dataKey []byte, userID, field string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode base64 string: %v", err)
+	}
+
+	gcm, err := newPrivateKeyGCM(dataKey)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("encrypted data is too short")
+	}
+
+	value, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], []byte(userID+":"+field))
+	if err != nil {
+		return "", fmt.Errorf("failed to authenticate encrypted data: %v", err)
+	}
+	return string(value), nil
+}
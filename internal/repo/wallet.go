@@ -1,38 +1,136 @@
 package repo
 
 import (
+	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
 	"database/sql"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"log"
 	"math/big"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
 )
 
 const (
 	getWalletIDFromUserIDQuery          = `SELECT wallet_id FROM wallets WHERE user_id = $1`
 	getWalletIDFromEmailQuery           = `SELECT w.wallet_id FROM wallets w INNER JOIN users u on w.user_id = u.user_id WHERE u.email = $1`
 	updateWalletBalanceQuery            = `UPDATE wallets SET balance =$1 WHERE user_id= $2`
-	retrievePrivateKeyFromUserIDQuery   = `SELECT private_key FROM wallet_private_keys WHERE user_id = $1`
-	retrievePrivateKeyFromWalletIDQuery = `SELECT private_key FROM wallet_private_keys WHERE wallet_id = $1`
+	retrievePrivateKeyFromUserIDQuery   = `SELECT wallet_id, private_key, key_version, cipher FROM wallet_private_keys WHERE user_id = $1`
+	retrievePrivateKeyFromWalletIDQuery = `SELECT wallet_id, private_key, key_version, cipher FROM wallet_private_keys WHERE wallet_id = $1`
+	insertOrganizationKeyQuery          = `INSERT INTO organization_wallet_keys (wallet_id, private_key, key_version, cipher) VALUES ($1, $2, $3, $4)`
+	retrieveOrganizationKeyQuery        = `SELECT wallet_id, private_key, key_version, cipher FROM organization_wallet_keys WHERE wallet_id = $1`
+	countPrivateKeysByVersionQuery      = `SELECT key_version, COUNT(*) FROM (SELECT key_version FROM wallet_private_keys
+		UNION ALL SELECT key_version FROM organization_wallet_keys UNION ALL SELECT key_version FROM transfer_totp_secrets
+		UNION ALL SELECT key_version FROM merchant_webhook_secrets UNION ALL SELECT key_version FROM bank_link_tokens
+		UNION ALL SELECT key_version FROM user_data_keys) k GROUP BY key_version`
+	countPrivateKeysToRotateQuery = `SELECT (SELECT COUNT(*) FROM wallet_private_keys WHERE key_version <> $1 OR cipher <> 'aes-gcm')
+		+ (SELECT COUNT(*) FROM organization_wallet_keys WHERE key_version <> $1 OR cipher <> 'aes-gcm')
+		+ (SELECT COUNT(*) FROM transfer_totp_secrets WHERE key_version <> $1 OR cipher <> 'aes-gcm')
+		+ (SELECT COUNT(*) FROM merchant_webhook_secrets WHERE key_version <> $1 OR cipher <> 'aes-gcm')
+		+ (SELECT COUNT(*) FROM bank_link_tokens WHERE key_version <> $1 OR cipher <> 'aes-gcm')
+		+ (SELECT COUNT(*) FROM user_data_keys WHERE key_version <> $1 OR cipher <> 'aes-gcm')`
+	// Locks one batch of keys still on another version or the legacy cipher, concurrent rotations skip each other's rows
+	selectPrivateKeysToRotateQuery = `SELECT %[2]s, %[3]s, key_version, cipher FROM %[1]s
+		WHERE key_version <> $1 OR cipher <> 'aes-gcm' ORDER BY %[2]s LIMIT $2 FOR UPDATE SKIP LOCKED`
+	updateRotatedPrivateKeyQuery = `UPDATE %[1]s SET %[3]s = $1, key_version = $2, cipher = 'aes-gcm'
+		WHERE %[2]s = $3 AND key_version = $4 AND cipher = $5`
+	insertPrivateKeyQuery         = `INSERT INTO wallet_private_keys (user_id, wallet_id, private_key, key_version, cipher, derivation_index) VALUES ($1, $2, $3, $4, $5, $6)`
+	nextDerivationIndexQuery      = `SELECT nextval('hd_wallet_index_seq')`
+	getDerivedWalletsQuery        = `SELECT wallet_id, derivation_index FROM wallet_private_keys WHERE derivation_index > $1 ORDER BY derivation_index LIMIT $2`
+	countImportedPrivateKeysQuery = `SELECT COUNT(*) FROM wallet_private_keys WHERE derivation_index IS NULL`
+)
+
+// encryptedKeyTable is a table of secrets sealed with the key ring, the ID column is authenticated
+// with each secret
+type encryptedKeyTable struct {
+	name         string
+	idColumn     string
+	secretColumn string
+}
+
+// Tables holding encrypted private keys and secrets, key rotation covers all of them
+var privateKeyTables = []encryptedKeyTable{
+	{name: "wallet_private_keys", idColumn: "wallet_id", secretColumn: "private_key"},
+	{name: "organization_wallet_keys", idColumn: "wallet_id", secretColumn: "private_key"},
+	{name: "transfer_totp_secrets", idColumn: "user_id", secretColumn: "secret"},
+	{name: "merchant_webhook_secrets", idColumn: "merchant_id", secretColumn: "secret"},
+	{name: "bank_link_tokens", idColumn: "link_id", secretColumn: "access_token"},
+	{name: "user_data_keys", idColumn: "user_id", secretColumn: "data_key"},
+}
+
+// Ciphers of stored private keys, every new key uses AES-GCM
+const (
+	cipherLegacyCFB = "aes-cfb"
+	cipherGCM       = "aes-gcm"
 )
 
+// legacyKeyVersion is the version of keys encrypted before key rotation existed
+const legacyKeyVersion = 1
+
+// PrivateKeyRing holds every key able to decrypt wallet private keys by version, new keys are
+// encrypted with the Current version
+type PrivateKeyRing struct {
+	Current int
+	Keys    map[int][]byte
+}
+
+// key returns the key of a version for decryption. Version 1 falls back to the original built-in
+// key, rows encrypted with it are read only to be re-sealed by key rotation.
+func (ring PrivateKeyRing) key(version int) ([]byte, error) {
+	if key, ok := ring.Keys[version]; ok {
+		return key, nil
+	}
+	if version == legacyKeyVersion {
+		return ensureValidKey(encryptionKey)
+	}
+	return nil, fmt.Errorf("no key configured for version %d", version)
+}
+
+// sealingKey returns the key of the Current version new secrets are sealed with, which has to be
+// configured, the built-in key never seals
+func (ring PrivateKeyRing) sealingKey() ([]byte, error) {
+	key, ok := ring.Keys[ring.Current]
+	if !ok {
+		return nil, fmt.Errorf("no key configured for version %d", ring.Current)
+	}
+	return key, nil
+}
+
+// DerivedWallet is a user wallet derived from the platform HD seed
+type DerivedWallet struct {
+	WalletID        string
+	DerivationIndex uint32
+}
+
 type WalletRepo struct {
-	DB *sql.DB
+	DB      *sql.DB
+	keyRing PrivateKeyRing
 }
 
 type WalletStorer interface {
 	GetWalletID(email, userID string) (string, error)
 	UpdateWalletBalance(userID string, balance *big.Float) error
 	InsertPrivateKey(userID, walletID, privateKey string) error
+	InsertDerivedPrivateKey(userID, walletID, privateKey string, derivationIndex uint32) error
+	NextDerivationIndex() (uint32, error)
+	GetDerivedWallets(afterIndex int64, limit int) ([]DerivedWallet, error)
+	CountImportedPrivateKeys() (int64, error)
 	RetrievePrivateKey(userID, walletID string) (string, error)
+	InsertOrganizationKey(walletID, privateKey string) error
+	RetrieveOrganizationKey(walletID string) (string, error)
+	CountPrivateKeysByVersion() (map[int]int64, error)
+	CountPrivateKeysToRotate() (int64, error)
+	RotatePrivateKeys(batchSize int) (int64, error)
+	CurrentKeyVersion() int
 }
 
 // Constructor function
-func NewWalletRepo(db *sql.DB) WalletStorer {
-	return &WalletRepo{DB: db}
+func NewWalletRepo(db *sql.DB, keyRing PrivateKeyRing) WalletStorer {
+	return &WalletRepo{DB: db, keyRing: keyRing}
 }
 
 // Returnes walletID from email or userID Precedance given to user_id if both parameters are passed
@@ -41,7 +139,7 @@ func (repoDep *WalletRepo) GetWalletID(email, userID string) (string, error) {
 
 	// Check if both parameters are empty
 	if email == "" && userID == "" {
-		return "", fmt.Errorf("both email and userID cannot be empty")
+		return "", utils.Validation("both email and userID cannot be empty")
 	}
 
 	// If userID is provided (non-empty), prioritize that
@@ -50,7 +148,7 @@ func (repoDep *WalletRepo) GetWalletID(email, userID string) (string, error) {
 		err := repoDep.DB.QueryRow(getWalletIDFromUserIDQuery, userID).Scan(&walletID)
 		if err != nil {
 			log.Println("Error Retrieving wallet_id from user_id", err.Error())
-			return "", fmt.Errorf("Error Retrieving wallet_id from user_id : %v", err.Error())
+			return "", utils.FromDBError("wallet", err)
 		}
 	} else if email != "" {
 		// If userID is not provided, fall back to email
@@ -58,7 +156,7 @@ func (repoDep *WalletRepo) GetWalletID(email, userID string) (string, error) {
 		err := repoDep.DB.QueryRow(getWalletIDFromEmailQuery, email).Scan(&walletID)
 		if err != nil {
 			log.Println("Error Retrieving wallet_id from email", err.Error())
-			return "", fmt.Errorf("Error Retrieving wallet_id from email : %v", err.Error())
+			return "", utils.FromDBError("wallet", err)
 		}
 	}
 
@@ -110,64 +208,69 @@ func ensureValidKey(key string) ([]byte, error) {
 	return []byte(key), nil
 }
 
-// Function to encrypt the private key
-func encryptPrivateKey(privateKey string) (string, error) {
-	log.Println("Encrypting private key...")
+// sealPrivateKey encrypts a private key with AES-GCM under a random nonce. The wallet ID and key
+// version are authenticated with it, so a ciphertext copied to another row does not decrypt.
+func sealPrivateKey(privateKey string, validKey []byte, walletID string, keyVersion int) (string, error) {
+	if privateKey == "" {
+		return "", fmt.Errorf("private key is empty")
+	}
+	// The built-in key is in the source, anything sealed with it is as good as plaintext
+	if builtInKey, _ := ensureValidKey(encryptionKey); bytes.Equal(validKey, builtInKey) {
+		return "", fmt.Errorf("refusing to seal with the built-in key, configure WALLET_KEYS")
+	}
 
-	// Ensure the encryption key is valid
-	validKey, err := ensureValidKey(encryptionKey)
+	gcm, err := newPrivateKeyGCM(validKey)
 	if err != nil {
-		log.Printf("Error: Invalid encryption key: %v\n", err)
 		return "", err
 	}
-
-	// Check if the private key is empty
-	if privateKey == "" {
-		log.Println("Error: Provided private key is empty.")
-		return "", fmt.Errorf("private key is empty")
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %v", err)
 	}
 
-	block, err := aes.NewCipher(validKey)
+	// The nonce comes first for later decryption
+	sealed := gcm.Seal(nonce, nonce, []byte(privateKey), privateKeyAdditionalData(walletID, keyVersion))
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// openPrivateKey reverses sealPrivateKey and fails on a wrong key or tampered ciphertext
+func openPrivateKey(encryptedKey string, validKey []byte, walletID string, keyVersion int) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(encryptedKey)
 	if err != nil {
-		log.Printf("Error: Failed to create cipher: %v\n", err)
-		return "", fmt.Errorf("failed to create cipher: %v", err)
+		return "", fmt.Errorf("failed to decode base64 string: %v", err)
 	}
 
-	// Generate random IV (Initialization Vector)
-	iv := make([]byte, aes.BlockSize)
-	if _, err := rand.Read(iv); err != nil {
-		log.Printf("Error: Failed to generate IV: %v\n", err)
-		return "", fmt.Errorf("failed to generate IV: %v", err)
+	gcm, err := newPrivateKeyGCM(validKey)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("encrypted data is too short")
 	}
 
-	// Pad the private key to a multiple of AES block size
-	paddedPrivateKey := pad([]byte(privateKey))
-
-	// Encrypt the private key
-	cipherText := make([]byte, len(paddedPrivateKey))
-	stream := cipher.NewCFBEncrypter(block, iv)
-	stream.XORKeyStream(cipherText, paddedPrivateKey)
-
-	// Combine the IV and cipherText (IV comes first for later decryption)
-	result := append(iv, cipherText...)
+	privateKey, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], privateKeyAdditionalData(walletID, keyVersion))
+	if err != nil {
+		return "", fmt.Errorf("failed to authenticate encrypted key: %v", err)
+	}
+	return string(privateKey), nil
+}
 
-	// Return the result as a base64 encoded string
-	encodedResult := base64.StdEncoding.EncodeToString(result)
+func newPrivateKeyGCM(validKey []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(validKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %v", err)
+	}
+	return cipher.NewGCM(block)
+}
 
-	return encodedResult, nil
+func privateKeyAdditionalData(walletID string, keyVersion int) []byte {
+	return []byte(fmt.Sprintf("%s:%d", walletID, keyVersion))
 }
 
-// Function to decrypt the private key
-func decryptPrivateKey(encryptedKey string) (string, error) {
+// Function to decrypt a private key stored with the legacy AES-CFB scheme
+func decryptLegacyPrivateKey(encryptedKey string, validKey []byte) (string, error) {
 	log.Println("Decrypting private key...")
 
-	// Ensure the encryption key is valid
-	validKey, err := ensureValidKey(encryptionKey)
-	if err != nil {
-		log.Printf("Error: Invalid encryption key: %v\n", err)
-		return "", err
-	}
-
 	// Check if the encrypted key is empty
 	if encryptedKey == "" {
 		log.Println("Error: Provided encrypted key is empty.")
@@ -182,7 +285,7 @@ func decryptPrivateKey(encryptedKey string) (string, error) {
 	}
 
 	// Ensure the encrypted data has the proper length (at least BlockSize + 1 byte for cipherText)
-	if len(encryptedData) < aes.BlockSize {
+	if len(encryptedData) <= aes.BlockSize {
 		log.Println("Error: Encrypted data is too short.")
 		return "", fmt.Errorf("encrypted data is too short")
 	}
@@ -191,9 +294,6 @@ func decryptPrivateKey(encryptedKey string) (string, error) {
 	iv := encryptedData[:aes.BlockSize]
 	cipherText := encryptedData[aes.BlockSize:]
 
-	log.Printf("IV: %x\n", iv)
-	log.Printf("CipherText: %x\n", cipherText)
-
 	block, err := aes.NewCipher(validKey)
 	if err != nil {
 		log.Printf("Error: Failed to create cipher: %v\n", err)
@@ -207,21 +307,10 @@ func decryptPrivateKey(encryptedKey string) (string, error) {
 
 	// Remove padding from the decrypted data
 	decrypted = unpad(decrypted)
-	log.Printf("Decrypted private key (after unpadding): %s\n", decrypted)
 
 	return string(decrypted), nil
 }
 
-// Padding function to pad the private key to AES block size
-func pad(data []byte) []byte {
-	padding := aes.BlockSize - len(data)%aes.BlockSize
-	padText := make([]byte, padding)
-	for i := 0; i < padding; i++ {
-		padText[i] = byte(padding)
-	}
-	return append(data, padText...)
-}
-
 // Unpadding function to remove padding from the decrypted private key
 func unpad(data []byte) []byte {
 	padding := int(data[len(data)-1])
@@ -237,20 +326,30 @@ func unpad(data []byte) []byte {
 
 // Function to insert the user_id, wallet_id, and encrypted private key into the database
 func (repoDep *WalletRepo) InsertPrivateKey(userID, walletID, privateKey string) error {
+	return repoDep.insertPrivateKey(userID, walletID, privateKey, nil)
+}
 
-	log.Println("Started Private key insertion")
-	encryptedKey, err := encryptPrivateKey(privateKey)
+// Stores the private key of a wallet derived from the HD seed along with its account index
+func (repoDep *WalletRepo) InsertDerivedPrivateKey(userID, walletID, privateKey string, derivationIndex uint32) error {
+	index := int64(derivationIndex)
+	return repoDep.insertPrivateKey(userID, walletID, privateKey, &index)
+}
 
+// insertPrivateKey encrypts and stores a user's private key, derivationIndex is nil for imported keys
+func (repoDep *WalletRepo) insertPrivateKey(userID, walletID, privateKey string, derivationIndex *int64) error {
+	log.Println("Started Private key insertion")
+	key, err := repoDep.keyRing.sealingKey()
 	if err != nil {
 		return fmt.Errorf("failed to encrypt private key: %v", err)
 	}
+	encryptedKey, err := sealPrivateKey(privateKey, key, walletID, repoDep.keyRing.Current)
 
-	// Prepare the SQL query to insert the data
-	query := `INSERT INTO wallet_private_keys (user_id, wallet_id, private_key)
-              VALUES ($1, $2, $3)`
+	if err != nil {
+		return fmt.Errorf("failed to encrypt private key: %v", err)
+	}
 
 	// Execute the insert query
-	_, err = repoDep.DB.Exec(query, userID, walletID, encryptedKey)
+	_, err = repoDep.DB.Exec(insertPrivateKeyQuery, userID, walletID, encryptedKey, repoDep.keyRing.Current, cipherGCM, derivationIndex)
 	if err != nil {
 		return fmt.Errorf("failed to execute insert query: %v", err)
 	}
@@ -260,7 +359,8 @@ func (repoDep *WalletRepo) InsertPrivateKey(userID, walletID, privateKey string)
 
 // Function to retrieve the encrypted private key from the database using either userID or walletID
 func (repoDep *WalletRepo) RetrievePrivateKey(userID, walletID string) (string, error) {
-	var encryptedKey string
+	var storedWalletID, encryptedKey, cipherName string
+	var keyVersion int
 
 	// Prepare the SQL query based on the available parameter (userID or walletID)
 	var query string
@@ -277,16 +377,222 @@ func (repoDep *WalletRepo) RetrievePrivateKey(userID, walletID string) (string,
 	}
 
 	// Execute the query
-	err := repoDep.DB.QueryRow(query, args...).Scan(&encryptedKey)
+	err := repoDep.DB.QueryRow(query, args...).Scan(&storedWalletID, &encryptedKey, &keyVersion, &cipherName)
 	if err != nil {
 		return "", fmt.Errorf("failed to retrieve private key: %v", err)
 	}
 
-	// Decrypt the private key
-	privateKey, err := decryptPrivateKey(encryptedKey)
+	// Decrypt the private key with the key and cipher it was stored under
+	privateKey, err := repoDep.decryptStoredKey(storedWalletID, encryptedKey, keyVersion, cipherName)
 	if err != nil {
 		return "", fmt.Errorf("failed to decrypt private key: %v", err)
 	}
 
 	return privateKey, nil
 }
+
+// Encrypts and stores the private key of an organization wallet
+func (repoDep *WalletRepo) InsertOrganizationKey(walletID, privateKey string) error {
+	key, err := repoDep.keyRing.sealingKey()
+	if err != nil {
+		return fmt.Errorf("failed to encrypt private key: %v", err)
+	}
+	encryptedKey, err := sealPrivateKey(privateKey, key, walletID, repoDep.keyRing.Current)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt private key: %v", err)
+	}
+
+	if _, err := repoDep.DB.Exec(insertOrganizationKeyQuery, walletID, encryptedKey, repoDep.keyRing.Current, cipherGCM); err != nil {
+		log.Printf("Error storing the key of organization wallet %s: %v", walletID, err)
+		return fmt.Errorf("failed to execute insert query: %v", err)
+	}
+	return nil
+}
+
+// Retrieves and decrypts the private key of an organization wallet
+func (repoDep *WalletRepo) RetrieveOrganizationKey(walletID string) (string, error) {
+	var storedWalletID, encryptedKey, cipherName string
+	var keyVersion int
+	if err := repoDep.DB.QueryRow(retrieveOrganizationKeyQuery, walletID).Scan(&storedWalletID, &encryptedKey, &keyVersion, &cipherName); err != nil {
+		return "", fmt.Errorf("failed to retrieve private key: %v", err)
+	}
+
+	privateKey, err := repoDep.decryptStoredKey(storedWalletID, encryptedKey, keyVersion, cipherName)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt private key: %v", err)
+	}
+	return privateKey, nil
+}
+
+// decryptStoredKey opens a stored private key, falling back to AES-CFB for rows not yet upgraded
+func (repoDep *WalletRepo) decryptStoredKey(walletID, encryptedKey string, keyVersion int, cipherName string) (string, error) {
+	key, err := repoDep.keyRing.key(keyVersion)
+	if err != nil {
+		return "", err
+	}
+
+	switch cipherName {
+	case cipherGCM:
+		return openPrivateKey(encryptedKey, key, walletID, keyVersion)
+	case cipherLegacyCFB:
+		privateKey, err := decryptLegacyPrivateKey(encryptedKey, key)
+		if err != nil {
+			return "", err
+		}
+		// CFB has no integrity check, a wrong key only shows up as a malformed private key
+		if _, err := hex.DecodeString(privateKey); err != nil || len(privateKey) != 64 {
+			return "", fmt.Errorf("private key does not decrypt with key version %d", keyVersion)
+		}
+		return privateKey, nil
+	}
+	return "", fmt.Errorf("unknown cipher %q", cipherName)
+}
+
+// Reserves the next unused account index of the HD seed
+func (repoDep *WalletRepo) NextDerivationIndex() (uint32, error) {
+	var index int64
+	if err := repoDep.DB.QueryRow(nextDerivationIndexQuery).Scan(&index); err != nil {
+		log.Printf("Error reserving HD wallet index: %v", err)
+		return 0, fmt.Errorf("error reserving HD wallet index: %v", err)
+	}
+	return uint32(index), nil
+}
+
+// Returns up to limit wallets derived from the HD seed with an index above afterIndex, lowest first
+func (repoDep *WalletRepo) GetDerivedWallets(afterIndex int64, limit int) ([]DerivedWallet, error) {
+	rows, err := repoDep.DB.Query(getDerivedWalletsQuery, afterIndex, limit)
+	if err != nil {
+		log.Printf("Error fetching derived wallets: %v", err)
+		return nil, fmt.Errorf("error fetching derived wallets: %v", err)
+	}
+	defer rows.Close()
+
+	wallets := []DerivedWallet{}
+	for rows.Next() {
+		var wallet DerivedWallet
+		if err := rows.Scan(&wallet.WalletID, &wallet.DerivationIndex); err != nil {
+			return nil, fmt.Errorf("error scanning derived wallet: %v", err)
+		}
+		wallets = append(wallets, wallet)
+	}
+	return wallets, rows.Err()
+}
+
+// Returns the number of user private keys that were not derived from the HD seed
+func (repoDep *WalletRepo) CountImportedPrivateKeys() (int64, error) {
+	var count int64
+	if err := repoDep.DB.QueryRow(countImportedPrivateKeysQuery).Scan(&count); err != nil {
+		log.Printf("Error counting imported private keys: %v", err)
+		return 0, fmt.Errorf("error counting imported private keys: %v", err)
+	}
+	return count, nil
+}
+
+// Returns the version new private keys are encrypted with
+func (repoDep *WalletRepo) CurrentKeyVersion() int {
+	return repoDep.keyRing.Current
+}
+
+// Returns the number of stored private keys per key version
+func (repoDep *WalletRepo) CountPrivateKeysByVersion() (map[int]int64, error) {
+	rows, err := repoDep.DB.Query(countPrivateKeysByVersionQuery)
+	if err != nil {
+		log.Printf("Error counting private keys: %v", err)
+		return nil, fmt.Errorf("error counting private keys: %v", err)
+	}
+	defer rows.Close()
+
+	counts := map[int]int64{}
+	for rows.Next() {
+		var version int
+		var count int64
+		if err := rows.Scan(&version, &count); err != nil {
+			return nil, fmt.Errorf("error scanning private key count: %v", err)
+		}
+		counts[version] = count
+	}
+	return counts, rows.Err()
+}
+
+// Returns the number of private keys on an old key version or the legacy cipher
+func (repoDep *WalletRepo) CountPrivateKeysToRotate() (int64, error) {
+	var count int64
+	if err := repoDep.DB.QueryRow(countPrivateKeysToRotateQuery, repoDep.keyRing.Current).Scan(&count); err != nil {
+		log.Printf("Error counting private keys to rotate: %v", err)
+		return 0, fmt.Errorf("error counting private keys to rotate: %v", err)
+	}
+	return count, nil
+}
+
+// Re-encrypts up to batchSize private keys stored under an older key version or the legacy
+// AES-CFB cipher with the current key and AES-GCM in one transaction, and returns the number of rows rotated. Rows left on an old version
+// are picked up again by the next call, so an interrupted rotation can simply be resumed.
+func (repoDep *WalletRepo) RotatePrivateKeys(batchSize int) (int64, error) {
+	for _, table := range privateKeyTables {
+		rotated, err := repoDep.rotatePrivateKeyBatch(table, batchSize)
+		if err != nil || rotated > 0 {
+			return rotated, err
+		}
+	}
+	return 0, nil
+}
+
+// rotatePrivateKeyBatch rotates one batch of the keys stored in table
+func (repoDep *WalletRepo) rotatePrivateKeyBatch(table encryptedKeyTable, batchSize int) (int64, error) {
+	currentKey, err := repoDep.keyRing.sealingKey()
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := repoDep.DB.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(fmt.Sprintf(selectPrivateKeysToRotateQuery, table.name, table.idColumn, table.secretColumn), repoDep.keyRing.Current, batchSize)
+	if err != nil {
+		log.Printf("Error selecting private keys to rotate: %v", err)
+		return 0, fmt.Errorf("error selecting private keys to rotate: %v", err)
+	}
+
+	type storedKey struct {
+		id           string
+		encryptedKey string
+		keyVersion   int
+		cipherName   string
+	}
+	var batch []storedKey
+	for rows.Next() {
+		var stored storedKey
+		if err := rows.Scan(&stored.id, &stored.encryptedKey, &stored.keyVersion, &stored.cipherName); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("error scanning private key: %v", err)
+		}
+		batch = append(batch, stored)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error reading private keys: %v", err)
+	}
+
+	for _, stored := range batch {
+		privateKey, err := repoDep.decryptStoredKey(stored.id, stored.encryptedKey, stored.keyVersion, stored.cipherName)
+		if err != nil {
+			return 0, fmt.Errorf("%s %s: failed to decrypt key: %v", table.name, stored.id, err)
+		}
+		reencrypted, err := sealPrivateKey(privateKey, currentKey, stored.id, repoDep.keyRing.Current)
+		if err != nil {
+			return 0, fmt.Errorf("%s %s: failed to encrypt key: %v", table.name, stored.id, err)
+		}
+		if _, err := tx.Exec(fmt.Sprintf(updateRotatedPrivateKeyQuery, table.name, table.idColumn, table.secretColumn), reencrypted, repoDep.keyRing.Current, stored.id, stored.keyVersion, stored.cipherName); err != nil {
+			log.Printf("Error rotating key %s of %s: %v", stored.id, table.name, err)
+			return 0, fmt.Errorf("error rotating private key: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error committing rotated keys: %v", err)
+	}
+	return int64(len(batch)), nil
+}
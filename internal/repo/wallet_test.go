@@ -0,0 +1,232 @@
+package repo
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"testing"
+)
+
+const (
+	testWalletID   = "0x71C7656EC7ab88b098defB751B7401B5f6d8976F"
+	testPrivateKey = "4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318"
+)
+
+func TestSealOpenPrivateKey(t *testing.T) {
+	key := testKey(t)
+	sealed, err := sealPrivateKey(testPrivateKey, key, testWalletID, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opened, err := openPrivateKey(sealed, key, testWalletID, 2)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if opened != testPrivateKey {
+		t.Errorf("opened %q, want %q", opened, testPrivateKey)
+	}
+
+	// A fresh nonce per seal keeps equal keys from producing equal ciphertexts
+	if again, _ := sealPrivateKey(testPrivateKey, key, testWalletID, 2); again == sealed {
+		t.Error("sealing twice gave the same ciphertext")
+	}
+}
+
+// The built-in key is in the source, nothing new may be sealed with it
+func TestSealPrivateKeyRefusesBuiltInKey(t *testing.T) {
+	builtInKey, _ := ensureValidKey(encryptionKey)
+	if sealed, err := sealPrivateKey(testPrivateKey, builtInKey, testWalletID, legacyKeyVersion); err == nil {
+		t.Errorf("sealed %q with the built-in key, want an error", sealed)
+	}
+
+	// A ring without a key for its current version does not fall back to it either
+	ring := PrivateKeyRing{Current: legacyKeyVersion, Keys: map[int][]byte{}}
+	if key, err := ring.sealingKey(); err == nil {
+		t.Errorf("sealing key %x of a ring without keys, want an error", key)
+	}
+	// It still decrypts the rows from before key rotation
+	if key, err := ring.key(legacyKeyVersion); err != nil || !bytes.Equal(key, builtInKey) {
+		t.Errorf("key of version 1 = %x, %v, want the built-in key", key, err)
+	}
+}
+
+func TestOpenPrivateKeyRejectsWrongContext(t *testing.T) {
+	key := testKey(t)
+	sealed, err := sealPrivateKey(testPrivateKey, key, testWalletID, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := base64.StdEncoding.DecodeString(sealed)
+	raw[len(raw)-1] ^= 1
+	tampered := base64.StdEncoding.EncodeToString(raw)
+
+	tests := []struct {
+		name       string
+		encrypted  string
+		key        []byte
+		walletID   string
+		keyVersion int
+	}{
+		// The wallet ID and key version are the additional data, a row copied elsewhere does not open
+		{"other wallet", sealed, key, "0x0000000000000000000000000000000000000001", 2},
+		{"other key version", sealed, key, testWalletID, 3},
+		{"other key", sealed, testKey(t), testWalletID, 2},
+		{"tampered ciphertext", tampered, key, testWalletID, 2},
+		{"too short", base64.StdEncoding.EncodeToString([]byte("short")), key, testWalletID, 2},
+		{"not base64", "not base64!", key, testWalletID, 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if opened, err := openPrivateKey(test.encrypted, test.key, test.walletID, test.keyVersion); err == nil {
+				t.Errorf("opened %q, want an error", opened)
+			}
+		})
+	}
+}
+
+func TestDecryptStoredKey(t *testing.T) {
+	legacyKey, _ := ensureValidKey(encryptionKey)
+	currentKey := testKey(t)
+	walletRepo := &WalletRepo{keyRing: PrivateKeyRing{Current: 2, Keys: map[int][]byte{2: currentKey}}}
+
+	sealed, err := sealPrivateKey(testPrivateKey, currentKey, testWalletID, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey := testKey(t)
+
+	tests := []struct {
+		name       string
+		encrypted  string
+		keyVersion int
+		cipherName string
+		wantErr    bool
+	}{
+		{"gcm", sealed, 2, cipherGCM, false},
+		// Version 1 falls back to the built-in key keys were encrypted with before rotation
+		{"legacy cfb", encryptLegacyCFB(t, testPrivateKey, legacyKey), legacyKeyVersion, cipherLegacyCFB, false},
+		{"legacy cfb with the wrong key", encryptLegacyCFB(t, testPrivateKey, otherKey), legacyKeyVersion, cipherLegacyCFB, true},
+		{"unknown key version", sealed, 5, cipherGCM, true},
+		{"unknown cipher", sealed, 2, "aes-ctr", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			privateKey, err := walletRepo.decryptStoredKey(testWalletID, test.encrypted, test.keyVersion, test.cipherName)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("decrypted %q, want an error", privateKey)
+				}
+				return
+			}
+			if err != nil || privateKey != testPrivateKey {
+				t.Errorf("decrypted %q, %v, want %q", privateKey, err, testPrivateKey)
+			}
+		})
+	}
+}
+
+// TestRotatePrivateKeyBatch rotates a temporary table shaped like the key tables, so keys of the
+// test database stay as they are
+func TestRotatePrivateKeyBatch(t *testing.T) {
+	db := testDB(t)
+	// One connection, the temporary table only exists on the connection creating it
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(`CREATE TEMPORARY TABLE rotation_test_keys (wallet_id VARCHAR(42) PRIMARY KEY,
+		private_key TEXT NOT NULL, key_version INT NOT NULL, cipher VARCHAR(16) NOT NULL)`); err != nil {
+		t.Fatal(err)
+	}
+	table := encryptedKeyTable{name: "rotation_test_keys", idColumn: "wallet_id", secretColumn: "private_key"}
+
+	legacyKey, _ := ensureValidKey(encryptionKey)
+	gcmWalletID := "0x0000000000000000000000000000000000000002"
+	sealed := sealWithBuiltInKey(t, testPrivateKey, gcmWalletID)
+	insert := `INSERT INTO rotation_test_keys (wallet_id, private_key, key_version, cipher) VALUES ($1, $2, $3, $4)`
+	if _, err := db.Exec(insert, testWalletID, encryptLegacyCFB(t, testPrivateKey, legacyKey), legacyKeyVersion, cipherLegacyCFB); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(insert, gcmWalletID, sealed, legacyKeyVersion, cipherGCM); err != nil {
+		t.Fatal(err)
+	}
+
+	walletRepo := &WalletRepo{DB: db, keyRing: PrivateKeyRing{Current: 2, Keys: map[int][]byte{2: testKey(t)}}}
+	rotated, err := walletRepo.rotatePrivateKeyBatch(table, 10)
+	if err != nil || rotated != 2 {
+		t.Fatalf("rotated %d, %v, want 2", rotated, err)
+	}
+	if rotated, err := walletRepo.rotatePrivateKeyBatch(table, 10); err != nil || rotated != 0 {
+		t.Errorf("second rotation rotated %d, %v, want 0", rotated, err)
+	}
+
+	rows, err := db.Query(`SELECT wallet_id, private_key, key_version, cipher FROM rotation_test_keys`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var walletID, encrypted, cipherName string
+		var keyVersion int
+		if err := rows.Scan(&walletID, &encrypted, &keyVersion, &cipherName); err != nil {
+			t.Fatal(err)
+		}
+		if keyVersion != 2 || cipherName != cipherGCM {
+			t.Errorf("%s is on version %d with %s, want version 2 with %s", walletID, keyVersion, cipherName, cipherGCM)
+		}
+		if privateKey, err := walletRepo.decryptStoredKey(walletID, encrypted, keyVersion, cipherName); err != nil || privateKey != testPrivateKey {
+			t.Errorf("%s decrypts to %q, %v, want %q", walletID, privateKey, err, testPrivateKey)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testKey returns a random AES-256 key
+func testKey(t *testing.T) []byte {
+	t.Helper()
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// sealWithBuiltInKey seals like sealPrivateKey did with the built-in key before it was refused, as
+// rows of deployments without WALLET_KEYS still are
+func sealWithBuiltInKey(t *testing.T, privateKey, walletID string) string {
+	t.Helper()
+	builtInKey, _ := ensureValidKey(encryptionKey)
+	gcm, err := newPrivateKeyGCM(builtInKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		t.Fatal(err)
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(privateKey), privateKeyAdditionalData(walletID, legacyKeyVersion))
+	return base64.StdEncoding.EncodeToString(sealed)
+}
+
+// encryptLegacyCFB encrypts the way private keys were stored before AES-GCM: a random IV followed by
+// the PKCS#7 padded key in AES-CFB
+func encryptLegacyCFB(t *testing.T, privateKey string, key []byte) string {
+	t.Helper()
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	padding := aes.BlockSize - len(privateKey)%aes.BlockSize
+	plaintext := append([]byte(privateKey), bytes.Repeat([]byte{byte(padding)}, padding)...)
+
+	encrypted := make([]byte, aes.BlockSize+len(plaintext))
+	if _, err := rand.Read(encrypted[:aes.BlockSize]); err != nil {
+		t.Fatal(err)
+	}
+	cipher.NewCFBEncrypter(block, encrypted[:aes.BlockSize]).XORKeyStream(encrypted[aes.BlockSize:], plaintext)
+	return base64.StdEncoding.EncodeToString(encrypted)
+}
@@ -0,0 +1,140 @@
+// Package respond writes API responses in a consistent envelope: {data, request_id} for single
+// resources, {data, pagination, request_id} for lists and {data: null, error, request_id} for errors.
+package respond
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Envelope wraps every response body, Error is only set on failed requests
+type Envelope struct {
+	Data       any         `json:"data"`
+	Pagination *Pagination `json:"pagination,omitempty"`
+	Error      *ErrorBody  `json:"error,omitempty"`
+	RequestID  string      `json:"request_id"`
+}
+
+// ErrorBody describes why a request failed, Fields maps invalid request fields to their problem
+type ErrorBody struct {
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+// Pagination describes the page of a list response. Offset is set for offset pagination and
+// NextCursor for keyset pagination when more results are available.
+type Pagination struct {
+	Count      int    `json:"count"`
+	Limit      int    `json:"limit,omitempty"`
+	Offset     *int   `json:"offset,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// JSON writes a single resource
+func JSON(w http.ResponseWriter, r *http.Request, data any) {
+	write(w, Envelope{Data: data, RequestID: RequestID(r)})
+}
+
+// List writes a list of resources with its pagination details
+func List(w http.ResponseWriter, r *http.Request, data any, pagination Pagination) {
+	write(w, Envelope{Data: data, Pagination: &pagination, RequestID: RequestID(r)})
+}
+
+// CachedJSON writes a single resource like JSON with an ETag of its data. A client already holding
+// that data gets 304 Not Modified without a body.
+func CachedJSON(w http.ResponseWriter, r *http.Request, data any) {
+	writeConditional(w, r, Envelope{Data: data, RequestID: RequestID(r)})
+}
+
+// CachedList writes a list like List with an ETag of its data and pagination. A client already
+// holding them gets 304 Not Modified without a body.
+func CachedList(w http.ResponseWriter, r *http.Request, data any, pagination Pagination) {
+	writeConditional(w, r, Envelope{Data: data, Pagination: &pagination, RequestID: RequestID(r)})
+}
+
+// Error writes a failed request with its status
+func Error(w http.ResponseWriter, r *http.Request, status int, body ErrorBody) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	encode(w, Envelope{Error: &body, RequestID: RequestID(r)})
+}
+
+// NotModifiedSince sets Last-Modified and answers If-Modified-Since. It writes 304 Not Modified and
+// returns true when the client's copy is still current, leaving nothing else for the caller to write.
+func NotModifiedSince(w http.ResponseWriter, r *http.Request, lastModified time.Time) bool {
+	lastModified = lastModified.UTC().Truncate(time.Second)
+	w.Header().Set("Last-Modified", lastModified.Format(http.TimeFormat))
+
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil || lastModified.After(since) {
+		return false
+	}
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// requestIDContextKey keys the correlation ID in a request context, unexported so that only
+// WithRequestID can set it
+type requestIDContextKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the correlation ID of the request
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey{}, requestID)
+}
+
+// RequestID returns the correlation ID stored by the request ID middleware
+func RequestID(r *http.Request) string {
+	requestID, _ := r.Context().Value(requestIDContextKey{}).(string)
+	return requestID
+}
+
+func write(w http.ResponseWriter, envelope Envelope) {
+	w.Header().Set("Content-Type", "application/json")
+	encode(w, envelope)
+}
+
+func encode(w http.ResponseWriter, envelope Envelope) {
+	if err := json.NewEncoder(w).Encode(envelope); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
+
+// writeConditional answers If-None-Match before writing the envelope. The ETag leaves out the request
+// ID, which differs on every response, and is weak since the body is not byte for byte the same.
+func writeConditional(w http.ResponseWriter, r *http.Request, envelope Envelope) {
+	encoded, err := json.Marshal(Envelope{Data: envelope.Data, Pagination: envelope.Pagination})
+	if err != nil {
+		write(w, envelope)
+		return
+	}
+	sum := sha256.Sum256(encoded)
+	etag := `W/"` + hex.EncodeToString(sum[:16]) + `"`
+
+	w.Header().Set("ETag", etag)
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	write(w, envelope)
+}
+
+// etagMatches compares an If-None-Match header with an ETag the weak way, ignoring W/ prefixes
+func etagMatches(ifNoneMatch, etag string) bool {
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+	opaque := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == opaque {
+			return true
+		}
+	}
+	return false
+}
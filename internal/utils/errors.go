@@ -0,0 +1,203 @@
+package utils
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/i18n"
+	"github.com/CodeWithKrushnal/ChainBank/internal/respond"
+)
+
+// Error categories, match them with errors.Is
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrValidation   = errors.New("validation failed")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrConflict     = errors.New("conflict")
+	ErrUpstream     = errors.New("upstream failure")
+
+	errInternal = errors.New("internal error")
+)
+
+// Error carries a client-facing message, its category and the underlying cause. When Args is
+// set Message is a format string, kept apart from its arguments so that it can be translated.
+type Error struct {
+	Kind    error
+	Message string
+	Args    []any
+	Err     error
+	// Problems of individual request fields, sent to the client one by one
+	Fields []FieldError
+}
+
+// FieldError is the problem of one request field, Message may be a format string filled from Args
+type FieldError struct {
+	Field   string
+	Message string
+	Args    []any
+}
+
+func (e *Error) Error() string {
+	message := e.text()
+	for i, field := range e.Fields {
+		separator := "; "
+		if i == 0 {
+			separator = ": "
+		}
+		message += separator + field.Field + " " + fmt.Sprintf(field.Message, field.Args...)
+	}
+	if e.Err != nil {
+		return message + ": " + e.Err.Error()
+	}
+	return message
+}
+
+// text returns the message with its arguments filled in
+func (e *Error) text() string {
+	if len(e.Args) == 0 {
+		return e.Message
+	}
+	return fmt.Sprintf(e.Message, e.Args...)
+}
+
+// Unwrap exposes both the category and the cause to errors.Is and errors.As
+func (e *Error) Unwrap() []error {
+	if e.Err != nil {
+		return []error{e.Kind, e.Err}
+	}
+	return []error{e.Kind}
+}
+
+// NewError builds an error of the given category
+func NewError(kind error, message string, err error) error {
+	return &Error{Kind: kind, Message: message, Err: err}
+}
+
+func NotFound(message string, err error) error {
+	return NewError(ErrNotFound, message, err)
+}
+
+func Validation(message string) error {
+	return NewError(ErrValidation, message, nil)
+}
+
+// Validationf builds a validation error whose message is a format string
+func Validationf(format string, args ...any) error {
+	return &Error{Kind: ErrValidation, Message: format, Args: args}
+}
+
+// ValidationFields builds a validation error listing the problems of individual fields
+func ValidationFields(fields []FieldError) error {
+	return &Error{Kind: ErrValidation, Message: "validation failed", Fields: fields}
+}
+
+func Unauthorized(message string) error {
+	return NewError(ErrUnauthorized, message, nil)
+}
+
+func Forbidden(message string) error {
+	return NewError(ErrForbidden, message, nil)
+}
+
+// Forbiddenf builds a forbidden error whose message is a format string
+func Forbiddenf(format string, args ...any) error {
+	return &Error{Kind: ErrForbidden, Message: format, Args: args}
+}
+
+func Conflict(message string) error {
+	return NewError(ErrConflict, message, nil)
+}
+
+// ConflictFields builds a conflict error naming the fields whose values are taken
+func ConflictFields(message string, fields []FieldError) error {
+	return &Error{Kind: ErrConflict, Message: message, Fields: fields}
+}
+
+// Conflictf builds a conflict error whose message is a format string
+func Conflictf(format string, args ...any) error {
+	return &Error{Kind: ErrConflict, Message: format, Args: args}
+}
+
+func Upstream(message string, err error) error {
+	return NewError(ErrUpstream, message, err)
+}
+
+// FromDBError turns sql.ErrNoRows into a NotFound error for the entity and wraps everything else
+func FromDBError(entity string, err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return NotFound(entity+" not found", err)
+	}
+	return NewError(errInternal, "error retrieving "+entity, err)
+}
+
+// HTTPStatus maps an error category to its HTTP status code
+func HTTPStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrValidation):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, ErrUpstream):
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// WriteError responds with the status derived from the error in the envelope of package respond,
+// translated into the request's locale. When the error wraps a cause only its message is sent to the client and the cause is logged.
+// Errors without a client-facing message are logged and answered with a generic internal error.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	status := HTTPStatus(err)
+	message := err.Error()
+	locale := i18n.FromContext(r.Context())
+
+	var appErr *Error
+	if errors.As(err, &appErr) && len(appErr.Fields) > 0 {
+		writeFieldErrors(w, r, locale, status, appErr)
+		return
+	}
+	if errors.As(err, &appErr) {
+		translated := i18n.T(locale, appErr.Message, appErr.Args...)
+		if appErr.Err != nil {
+			message = translated
+			log.Printf("Request failed (%d): %v", status, err)
+		} else {
+			// Keep any detail added by callers wrapping the error with fmt.Errorf
+			message = strings.Replace(message, appErr.text(), translated, 1)
+		}
+	} else if status == http.StatusInternalServerError {
+		// Raw database, RPC and driver errors stay in the server log
+		log.Printf("Request failed (%d): %v", status, err)
+		message = i18n.T(locale, errInternal.Error())
+	}
+
+	respond.Error(w, r, status, respond.ErrorBody{Message: message})
+}
+
+// writeFieldErrors responds with the error envelope mapping each field to its translated problem,
+// the first problem of a field wins
+func writeFieldErrors(w http.ResponseWriter, r *http.Request, locale string, status int, appErr *Error) {
+	fields := map[string]string{}
+	for _, field := range appErr.Fields {
+		if _, ok := fields[field.Field]; !ok {
+			fields[field.Field] = i18n.T(locale, field.Message, field.Args...)
+		}
+	}
+
+	respond.Error(w, r, status, respond.ErrorBody{
+		Message: i18n.T(locale, appErr.Message, appErr.Args...),
+		Fields:  fields,
+	})
+}
@@ -0,0 +1,117 @@
+package utils
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/respond"
+)
+
+func TestHTTPStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"not found", NotFound("wallet not found", nil), http.StatusNotFound},
+		{"validation", Validation("amount is required"), http.StatusBadRequest},
+		{"validation with fields", ValidationFields([]FieldError{{Field: "email", Message: "is required"}}), http.StatusBadRequest},
+		{"unauthorized", Unauthorized("invalid password"), http.StatusUnauthorized},
+		{"forbidden", Forbidden("admin access required"), http.StatusForbidden},
+		{"conflict", Conflict("username already taken"), http.StatusConflict},
+		{"upstream", Upstream("ethereum node unavailable", errors.New("dial tcp: refused")), http.StatusBadGateway},
+		{"wrapped app error", fmt.Errorf("loading wallet: %w", NotFound("wallet not found", nil)), http.StatusNotFound},
+		{"db no rows", FromDBError("user", sql.ErrNoRows), http.StatusNotFound},
+		{"db failure", FromDBError("user", errors.New("connection reset")), http.StatusInternalServerError},
+		{"plain error", errors.New("pq: relation does not exist"), http.StatusInternalServerError},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := HTTPStatus(test.err); got != test.want {
+				t.Errorf("HTTPStatus(%v) = %d, want %d", test.err, got, test.want)
+			}
+		})
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantBody   string
+		wantFields map[string]string
+		hiddenText string
+	}{
+		{
+			name:       "app error",
+			err:        Conflict("username already taken"),
+			wantStatus: http.StatusConflict,
+			wantBody:   "username already taken",
+		},
+		{
+			name:       "app error with cause",
+			err:        Upstream("ethereum node unavailable", errors.New("dial tcp 10.0.0.5:8545: refused")),
+			wantStatus: http.StatusBadGateway,
+			wantBody:   "ethereum node unavailable",
+			hiddenText: "10.0.0.5",
+		},
+		{
+			name:       "field errors",
+			err:        ValidationFields([]FieldError{{Field: "email", Message: "is required"}, {Field: "email", Message: "is invalid"}}),
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "validation failed",
+			wantFields: map[string]string{"email": "is required"},
+		},
+		{
+			name:       "plain error",
+			err:        errors.New(`pq: duplicate key value violates unique constraint "users_pkey"`),
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   "internal error",
+			hiddenText: "users_pkey",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			WriteError(recorder, req.WithContext(respond.WithRequestID(req.Context(), "req-1")), test.err)
+
+			body := recorder.Body.String()
+			if recorder.Code != test.wantStatus {
+				t.Errorf("status = %d, want %d", recorder.Code, test.wantStatus)
+			}
+
+			// Errors come in the envelope of successful responses
+			var envelope respond.Envelope
+			if err := json.Unmarshal(recorder.Body.Bytes(), &envelope); err != nil {
+				t.Fatalf("body %q is not an envelope: %v", body, err)
+			}
+			if envelope.RequestID != "req-1" || envelope.Data != nil || envelope.Error == nil {
+				t.Fatalf("envelope = %+v, want an error with request ID req-1", envelope)
+			}
+			if envelope.Error.Message != test.wantBody {
+				t.Errorf("message = %q, want %q", envelope.Error.Message, test.wantBody)
+			}
+			if len(envelope.Error.Fields) != len(test.wantFields) {
+				t.Errorf("fields = %v, want %v", envelope.Error.Fields, test.wantFields)
+			}
+			for field, message := range test.wantFields {
+				if envelope.Error.Fields[field] != message {
+					t.Errorf("field %s = %q, want %q", field, envelope.Error.Fields[field], message)
+				}
+			}
+			if test.hiddenText != "" && strings.Contains(body, test.hiddenText) {
+				t.Errorf("body %q leaks %q", body, test.hiddenText)
+			}
+		})
+	}
+}
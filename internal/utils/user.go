@@ -0,0 +1,39 @@
+package utils
+
+import "context"
+
+// User is the authenticated caller of a request. The auth middleware loads it once and handlers read
+// it from the request context instead of looking the user up again.
+type User struct {
+	UserID    string
+	UserEmail string
+	UserRole  int
+}
+
+// userContextKey keys the User in a request context, unexported so that no other package can set it
+type userContextKey struct{}
+
+// WithUser returns a copy of ctx carrying the authenticated user
+func WithUser(ctx context.Context, user User) context.Context {
+	return context.WithValue(ctx, userContextKey{}, user)
+}
+
+// UserFromContext returns the authenticated user of a request, false on routes without authentication
+func UserFromContext(ctx context.Context) (User, bool) {
+	user, ok := ctx.Value(userContextKey{}).(User)
+	return user, ok
+}
+
+// permissionsContextKey keys the permissions of the authenticated user, unexported like userContextKey
+type permissionsContextKey struct{}
+
+// WithPermissions returns a copy of ctx carrying the permissions the authenticated user's roles grant
+func WithPermissions(ctx context.Context, permissions []string) context.Context {
+	return context.WithValue(ctx, permissionsContextKey{}, permissions)
+}
+
+// PermissionsFrom returns the permissions of the authenticated user, nil on routes without authentication
+func PermissionsFrom(ctx context.Context) []string {
+	permissions, _ := ctx.Value(permissionsContextKey{}).([]string)
+	return permissions
+}
@@ -0,0 +1,121 @@
+// k6 load profile for the hot API endpoints.
+//
+// Run against a server started with SIMULATED_CHAIN=true so results measure the API and
+// database rather than an Ethereum node:
+//
+//   k6 run loadtest/hot_endpoints.js                      # full profile
+//   CI=true k6 run loadtest/hot_endpoints.js              # short run for CI, fails on budget breach
+//   BASE_URL=http://staging:8080 k6 run loadtest/hot_endpoints.js
+//
+// The transfer scenario needs RECIPIENT_USER_ID, the user_id of any existing account.
+//
+// Latency budgets (p95): balance 200ms, transaction listing 250ms, transfer 500ms.
+// k6 exits non-zero when a threshold is crossed, which fails the CI job.
+//
+// Go benchmarks cover the same paths below the HTTP layer. The repository benchmarks run against a
+// migrated database, e.g. one filled by cmd/seed, and are skipped without TEST_DATABASE_URL:
+//
+//   TEST_DATABASE_URL=postgres://... go test ./internal/repo -run '^$' -bench Transactions
+//   go test ./internal/app/ethereum ./internal/app/wallet -run '^$' -bench .
+
+import http from 'k6/http';
+import { check, fail } from 'k6';
+
+const BASE_URL = __ENV.BASE_URL || 'http://localhost:8080';
+const CI = __ENV.CI === 'true';
+
+export const options = {
+  scenarios: {
+    read_heavy: {
+      executor: 'ramping-vus',
+      exec: 'readPath',
+      startVUs: 1,
+      stages: CI
+        ? [{ duration: '10s', target: 5 }, { duration: '20s', target: 5 }]
+        : [{ duration: '1m', target: 50 }, { duration: '3m', target: 50 }, { duration: '1m', target: 0 }],
+    },
+    transfers: {
+      executor: 'constant-arrival-rate',
+      exec: 'transferPath',
+      rate: CI ? 2 : 20,
+      timeUnit: '1s',
+      duration: CI ? '30s' : '5m',
+      preAllocatedVUs: CI ? 5 : 40,
+    },
+  },
+  thresholds: {
+    'http_req_failed': ['rate<0.01'],
+    'http_req_duration{endpoint:balance}': ['p(95)<200'],
+    'http_req_duration{endpoint:transactions}': ['p(95)<250'],
+    'http_req_duration{endpoint:transfer}': ['p(95)<500'],
+  },
+};
+
+function signup(suffix, role) {
+  const user = {
+    username: `load_${suffix}`,
+    email: `load_${suffix}@example.com`,
+    password: 'Load-Test-Password-1',
+    full_name: `Load ${suffix}`,
+    dob: '1990-01-01',
+    role: role,
+  };
+  const res = http.post(`${BASE_URL}/signup`, JSON.stringify(user), {
+    headers: { 'Content-Type': 'application/json' },
+  });
+  if (res.status !== 200 && res.status !== 409) {
+    fail(`signup failed for ${user.email}: ${res.status} ${res.body}`);
+  }
+  return user;
+}
+
+function signin(user) {
+  const res = http.post(`${BASE_URL}/signin`, JSON.stringify({ email: user.email, password: user.password }), {
+    headers: { 'Content-Type': 'application/json' },
+  });
+  if (res.status !== 200) {
+    fail(`signin failed for ${user.email}: ${res.status} ${res.body}`);
+  }
+  return res.json('data.access_token');
+}
+
+// setup creates the sending account once per run
+export function setup() {
+  if (!__ENV.RECIPIENT_USER_ID) {
+    fail('RECIPIENT_USER_ID is required');
+  }
+  const sender = signup(`sender_${Date.now()}`, '1');
+
+  return {
+    senderToken: signin(sender),
+    senderPassword: sender.password,
+    recipientUserID: __ENV.RECIPIENT_USER_ID,
+  };
+}
+
+export function readPath(data) {
+  const headers = { Authorization: `Bearer ${data.senderToken}` };
+
+  const balance = http.get(`${BASE_URL}/api/balance`, { headers, tags: { endpoint: 'balance' } });
+  check(balance, { 'balance 200': (r) => r.status === 200 });
+
+  let url = `${BASE_URL}/api/transactions?limit=20`;
+  for (let page = 0; page < 3; page++) {
+    const res = http.get(url, { headers, tags: { endpoint: 'transactions' } });
+    check(res, { 'transactions 200': (r) => r.status === 200 });
+    const next = res.status === 200 ? res.json('pagination.next_cursor') : null;
+    if (!next) {
+      break;
+    }
+    url = `${BASE_URL}/api/transactions?limit=20&cursor=${next}`;
+  }
+}
+
+export function transferPath(data) {
+  const res = http.post(
+    `${BASE_URL}/api/transfer`,
+    JSON.stringify({ recipient_user_id: data.recipientUserID, amount: '1000', password: data.senderPassword }),
+    { headers: { Authorization: `Bearer ${data.senderToken}`, 'Content-Type': 'application/json' }, tags: { endpoint: 'transfer' } },
+  );
+  check(res, { 'transfer 200': (r) => r.status === 200 });
+}
@@ -1,16 +1,60 @@
 package middleware
 
 import (
-	"context"
 	"errors"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/apikeys"
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/delegations"
 	"github.com/CodeWithKrushnal/ChainBank/internal/config"
+	"github.com/CodeWithKrushnal/ChainBank/internal/domain"
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
 	"github.com/golang-jwt/jwt/v5"
 	"log"
 	"net/http"
+	"slices"
 	"strings"
+	"time"
 )
 
-func ValidateJWT(tokenString string) (string, error) {
+// Audience of service tokens, they are issued by the calling service for ChainBank
+const serviceTokenAudience = "chainbank"
+
+// ValidateServiceJWT checks a token signed with SERVICE_JWT_SECRET and returns the calling
+// service (iss) and the user it acts for (sub)
+func ValidateServiceJWT(tokenString string) (string, string, error) {
+	secret := []byte(config.ConfigDetails.ServiceJWTSecret)
+	if len(secret) == 0 {
+		return "", "", errors.New("service authentication is disabled")
+	}
+
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		return secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithAudience(serviceTokenAudience), jwt.WithExpirationRequired())
+	if err != nil {
+		return "", "", err
+	}
+
+	serviceName, err := token.Claims.GetIssuer()
+	if err != nil || serviceName == "" {
+		return "", "", errors.New("service token has no issuer")
+	}
+	userID, err := token.Claims.GetSubject()
+	if err != nil || userID == "" {
+		return "", "", errors.New("service token has no subject")
+	}
+	return serviceName, userID, nil
+}
+
+// clientCertificateName returns the common name of a verified client certificate
+func clientCertificateName(r *http.Request) (string, bool) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return "", false
+	}
+	return r.TLS.VerifiedChains[0][0].Subject.CommonName, true
+}
+
+// ValidateJWT checks an access token and returns its email and issue time, refresh tokens are refused
+func ValidateJWT(tokenString string) (string, time.Time, error) {
 
 	JWT_SECRET := []byte(config.ConfigDetails.JWTSecretKey)
 
@@ -23,19 +67,26 @@ func ValidateJWT(tokenString string) (string, error) {
 	})
 
 	if err != nil {
-		return "", err
+		return "", time.Time{}, err
 	}
 
 	// Extract claims
 	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+		if refresh, _ := claims["refresh"].(bool); refresh {
+			return "", time.Time{}, errors.New("refresh tokens cannot authenticate requests")
+		}
 		userEmail, ok := claims["email"].(string)
 		if !ok {
-			return "", errors.New("invalid token claims")
+			return "", time.Time{}, errors.New("invalid token claims")
+		}
+		issuedAt, err := claims.GetIssuedAt()
+		if err != nil || issuedAt == nil {
+			return "", time.Time{}, errors.New("invalid token claims")
 		}
-		return userEmail, nil
+		return userEmail, issuedAt.Time, nil
 	}
 
-	return "", errors.New("invalid token")
+	return "", time.Time{}, errors.New("invalid token")
 }
 
 type Handler struct {
@@ -63,47 +114,144 @@ func AuthMiddleware(authDep Handler) func(http.Handler) http.Handler {
 				return
 			}
 
-			// Check if it follows "Bearer <token>" format
+			// Check if it follows "Bearer <token>", "ApiKey <key>" or "Service <token>" format
 			tokenParts := strings.Split(authHeader, " ")
-			if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
+			if len(tokenParts) != 2 || (tokenParts[0] != "Bearer" && tokenParts[0] != "ApiKey" && tokenParts[0] != "Service") {
 				http.Error(w, "Invalid Authorization Header Format", http.StatusUnauthorized)
 				return
 			}
 
-			// Validate token
-			userEmail, err := ValidateJWT(tokenParts[1])
-			if err != nil {
-				http.Error(w, "Unauthorized: Invalid Token", http.StatusUnauthorized)
-				return
+			var user repo.UserWithRole
+			var apiKey repo.APIKey
+			var serviceName string
+			if tokenParts[0] == "Service" {
+				// Service tokens are only honoured over mutual TLS, from the service named in the client certificate
+				var userID string
+				var err error
+				serviceName, userID, err = ValidateServiceJWT(tokenParts[1])
+				if err != nil {
+					http.Error(w, "Unauthorized: Invalid Service Token", http.StatusUnauthorized)
+					return
+				}
+				if certName, ok := clientCertificateName(r); !ok || certName != serviceName {
+					http.Error(w, "Unauthorized: service token does not match the client certificate", http.StatusUnauthorized)
+					return
+				}
+
+				user, err = authDep.service.getUserByID(userID)
+				if err != nil {
+					log.Println("Error Retrieving the user of a service token in authmiddleware")
+					http.Error(w, "User not found", http.StatusUnauthorized)
+					return
+				}
+			} else if tokenParts[0] == "ApiKey" {
+				// API keys only reach the routes their scopes allow
+				var err error
+				apiKey, err = authDep.service.getAPIKey(tokenParts[1])
+				if err != nil {
+					http.Error(w, "Unauthorized: Invalid API Key", http.StatusUnauthorized)
+					return
+				}
+				scope, allowed := apiKeyScope(r)
+				if !allowed || !slices.Contains(apiKey.Scopes, scope) {
+					http.Error(w, "Forbidden: API key does not allow this request", http.StatusForbidden)
+					return
+				}
+
+				user, err = authDep.service.getUserByID(apiKey.UserID)
+				if err != nil {
+					log.Println("Error Retrieving the user of an API key in authmiddleware")
+					http.Error(w, "User not found", http.StatusUnauthorized)
+					return
+				}
+			} else {
+				// Validate token
+				userEmail, issuedAt, err := ValidateJWT(tokenParts[1])
+				if err != nil {
+					http.Error(w, "Unauthorized: Invalid Token", http.StatusUnauthorized)
+					return
+				}
+
+				// Getting User Details from userRepo
+				user, err = authDep.service.getUserByEmail(userEmail)
+				if err != nil {
+					log.Println("Error Retrieving the UserID From email in authmiddleware")
+					http.Error(w, "User not found", http.StatusUnauthorized)
+					return
+				}
+
+				// Tokens issued before the user reported a sign-in or reset the password are revoked.
+				// Token times have second precision, so the cutoff is rounded down to match.
+				if user.TokensValidAfter != nil && issuedAt.Before(user.TokensValidAfter.Truncate(time.Second)) {
+					http.Error(w, "Unauthorized: token has been revoked", http.StatusUnauthorized)
+					return
+				}
 			}
 
-			// Getting User Details from userRepo
-			user, err := authDep.service.getUserByEmail(userEmail)
-			if err != nil {
-				log.Println("Error Retrieving the UserID From email in authmiddleware")
-				http.Error(w, "User not found", http.StatusUnauthorized)
+			// Closed accounts lose access immediately, whatever tokens they still hold
+			if user.AccountStatus == domain.AccountClosed {
+				http.Error(w, "Unauthorized: account is closed", http.StatusUnauthorized)
 				return
 			}
 
-			// Getting User Role from userRepo
-			userRole, err := authDep.service.getUserHighestRole(user.ID)
-			if err != nil {
+			// A delegate acts as the user who granted them access, within the delegation's scopes.
+			// The delegation is looked up on every request so that revoking it takes effect at once.
+			actingUser := user
+			if grantorID := r.Header.Get(delegations.OnBehalfOfHeader); grantorID != "" {
+				if apiKey.KeyID != "" || serviceName != "" {
+					http.Error(w, "Forbidden: delegated access requires a login token", http.StatusForbidden)
+					return
+				}
+				delegation, err := authDep.service.getActiveDelegation(grantorID, user.ID)
+				if err != nil {
+					http.Error(w, "Forbidden: no active delegation from this user", http.StatusForbidden)
+					return
+				}
+				scope, allowed := delegationScope(r)
+				if !allowed || !slices.Contains(delegation.Scopes, scope) {
+					http.Error(w, "Forbidden: delegation does not allow this request", http.StatusForbidden)
+					return
+				}
+				actingUser, err = authDep.service.getUserByID(grantorID)
+				if err != nil || actingUser.AccountStatus == domain.AccountClosed {
+					http.Error(w, "Forbidden: no active delegation from this user", http.StatusForbidden)
+					return
+				}
+				// Delegated requests are only served once they are in the audit log
+				if err := authDep.service.recordDelegationUse(delegation, r.Method, r.URL.Path); err != nil {
+					http.Error(w, "Error recording delegated access", http.StatusInternalServerError)
+					return
+				}
+				log.Printf("User %s acting for user %s under delegation %s", user.ID, grantorID, delegation.DelegationID)
+			}
+
+			// The role came with the user, permissions are loaded once per request and shared through the context
+			userRole := actingUser.Role
+			if userRole == 0 {
 				log.Println("Error Retrieving the role for user")
 			}
+			permissions, err := authDep.service.getUserPermissions(actingUser.ID, userRole)
+			if err != nil {
+				log.Println("Error Retrieving the permissions for user")
+			}
 
 			// Add user info to request context
-			ctx := context.WithValue(r.Context(), "userInfo", struct {
-				UserID    string
-				UserEmail string
-				UserRole  int
-			}{
-				UserID:    user.ID,
-				UserEmail: userEmail,
+			ctx := utils.WithUser(r.Context(), utils.User{
+				UserID:    actingUser.ID,
+				UserEmail: actingUser.Email,
 				UserRole:  userRole,
 			})
+			ctx = utils.WithPermissions(ctx, permissions)
 
-			// Update last login
-			err = authDep.service.updateLastLogin(user.ID)
+			// Update last login, or the last use of the API key. Service calls are not logins.
+			switch {
+			case serviceName != "":
+				log.Printf("Service %s acting for user %s", serviceName, user.ID)
+			case apiKey.KeyID != "":
+				err = authDep.service.touchAPIKey(apiKey.KeyID)
+			default:
+				err = authDep.service.updateLastLogin(user.ID)
+			}
 			if err != nil {
 				log.Println("Error Updating the Login Info")
 				return
@@ -114,3 +262,37 @@ func AuthMiddleware(authDep Handler) func(http.Handler) http.Handler {
 		})
 	}
 }
+
+// delegationScope returns the scope a delegate needs for the request. Admin routes and the
+// management of credentials and delegations stay with the account holder.
+func delegationScope(r *http.Request) (string, bool) {
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/api/admin/"),
+		strings.HasPrefix(r.URL.Path, "/api/me/api-keys"),
+		strings.HasPrefix(r.URL.Path, "/api/me/delegations"),
+		strings.HasPrefix(r.URL.Path, "/api/me/transfer-confirmation"):
+		return "", false
+	case r.Method == http.MethodGet:
+		return delegations.ScopeView, true
+	}
+	return "", false
+}
+
+// apiKeyScope returns the scope an API key needs for the request. Reads need the read scope and
+// transfers the transfer scope, admin and account management routes are never open to API keys.
+// Merchant servers manage their payment intents with the merchant scope.
+func apiKeyScope(r *http.Request) (string, bool) {
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/api/admin/"):
+		return "", false
+	case r.Method == http.MethodGet:
+		return apikeys.ScopeRead, true
+	case r.Method == http.MethodPost && r.URL.Path == "/api/transfer",
+		r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/api/payment-requests/") && strings.HasSuffix(r.URL.Path, "/pay"),
+		r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/api/payment-intents/") && strings.HasSuffix(r.URL.Path, "/pay"):
+		return apikeys.ScopeTransfer, true
+	case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/api/merchants/me/payment-intents"):
+		return apikeys.ScopeMerchant, true
+	}
+	return "", false
+}
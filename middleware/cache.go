@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"sync"
+	"time"
+)
+
+// Expired entries are only swept once the cache holds this many users
+const permissionCacheSweepSize = 10000
+
+// permissionCache keeps the permissions of recently authenticated users for a short while, so that
+// their next requests skip that lookup. Permission changes apply once the entry expires.
+type permissionCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cachedPermissions
+}
+
+type cachedPermissions struct {
+	permissions []string
+	expiresAt   time.Time
+}
+
+func newPermissionCache(ttl time.Duration) *permissionCache {
+	return &permissionCache{ttl: ttl, entries: map[string]cachedPermissions{}}
+}
+
+// get returns the cached permissions of a user, false when they are missing or expired
+func (cache *permissionCache) get(userID string) ([]string, bool) {
+	if cache.ttl <= 0 {
+		return nil, false
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cached, ok := cache.entries[userID]
+	if !ok || time.Now().After(cached.expiresAt) {
+		return nil, false
+	}
+	return cached.permissions, true
+}
+
+// put caches the permissions of a user for the cache's TTL, nothing is kept while the TTL is 0
+func (cache *permissionCache) put(userID string, permissions []string) {
+	if cache.ttl <= 0 {
+		return
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	now := time.Now()
+	if len(cache.entries) >= permissionCacheSweepSize {
+		for cachedUserID, cached := range cache.entries {
+			if now.After(cached.expiresAt) {
+				delete(cache.entries, cachedUserID)
+			}
+		}
+	}
+	cache.entries[userID] = cachedPermissions{permissions: permissions, expiresAt: now.Add(cache.ttl)}
+}
@@ -0,0 +1,168 @@
+package middleware
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Responses smaller than this are sent as they are, compressing them saves less than it costs
+const minCompressSize = 1024
+
+// Content types worth compressing, files such as exports are compressed already
+var compressibleTypes = []string{"application/json", "text/"}
+
+// CompressionMiddleware compresses JSON and text responses with gzip or deflate, whichever the client
+// accepts, gzip first
+func CompressionMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		encoding := acceptedEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding}
+		next.ServeHTTP(cw, r)
+		if err := cw.close(); err != nil {
+			log.Printf("Error compressing response: %v", err)
+		}
+	})
+}
+
+// acceptedEncoding picks gzip or deflate from an Accept-Encoding header, empty when neither is accepted
+func acceptedEncoding(header string) string {
+	accepted := map[string]bool{}
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if weight, err := strconv.ParseFloat(q, 64); err != nil || weight <= 0 {
+				continue
+			}
+		}
+		accepted[strings.ToLower(strings.TrimSpace(name))] = true
+	}
+
+	switch {
+	case accepted["gzip"]:
+		return "gzip"
+	case accepted["deflate"]:
+		return "deflate"
+	}
+	return ""
+}
+
+// compressWriter holds back the start of a response until it knows whether the response is worth
+// compressing: a compressible type and at least minCompressSize bytes
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	status   int
+	buffer   []byte
+	// Set once the response is being compressed
+	encoder io.WriteCloser
+	// Set once the response is sent as it is
+	passthrough bool
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.status == 0 {
+		cw.status = status
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+
+	switch {
+	case cw.passthrough:
+		return cw.ResponseWriter.Write(p)
+	case cw.encoder != nil:
+		return cw.encoder.Write(p)
+	case !cw.compressible():
+		if err := cw.sendUncompressed(); err != nil {
+			return 0, err
+		}
+		return cw.ResponseWriter.Write(p)
+	}
+
+	cw.buffer = append(cw.buffer, p...)
+	if len(cw.buffer) >= minCompressSize {
+		if err := cw.startCompression(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// compressible reports whether the handler produced a response that should be compressed
+func (cw *compressWriter) compressible() bool {
+	if cw.status < http.StatusOK || cw.status == http.StatusNoContent || cw.status == http.StatusNotModified {
+		return false
+	}
+	if cw.Header().Get("Content-Encoding") != "" {
+		return false
+	}
+	contentType := cw.Header().Get("Content-Type")
+	for _, prefix := range compressibleTypes {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// startCompression sends the headers of a compressed response and compresses what was held back
+func (cw *compressWriter) startCompression() error {
+	header := cw.Header()
+	header.Set("Content-Encoding", cw.encoding)
+	header.Del("Content-Length")
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	if cw.encoding == "gzip" {
+		cw.encoder = gzip.NewWriter(cw.ResponseWriter)
+	} else {
+		encoder, err := flate.NewWriter(cw.ResponseWriter, flate.DefaultCompression)
+		if err != nil {
+			return err
+		}
+		cw.encoder = encoder
+	}
+
+	_, err := cw.encoder.Write(cw.buffer)
+	cw.buffer = nil
+	return err
+}
+
+// sendUncompressed sends the headers and what was held back as they are
+func (cw *compressWriter) sendUncompressed() error {
+	cw.passthrough = true
+	cw.ResponseWriter.WriteHeader(cw.status)
+	if len(cw.buffer) == 0 {
+		return nil
+	}
+	_, err := cw.ResponseWriter.Write(cw.buffer)
+	cw.buffer = nil
+	return err
+}
+
+// close finishes the response once the handler returned
+func (cw *compressWriter) close() error {
+	switch {
+	case cw.encoder != nil:
+		return cw.encoder.Close()
+	case cw.passthrough:
+		return nil
+	case cw.status == 0:
+		// Nothing was written, the server sends its default response
+		return nil
+	}
+	return cw.sendUncompressed()
+}
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/i18n"
+)
+
+// LocaleMiddleware negotiates the response language from Accept-Language and stores it in the
+// request context under "locale"
+func LocaleMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		locale := i18n.Negotiate(r.Header.Get("Accept-Language"))
+
+		w.Header().Set("Content-Language", locale)
+		w.Header().Add("Vary", "Accept-Language")
+		ctx := context.WithValue(r.Context(), "locale", locale)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
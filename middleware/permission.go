@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+// HasPermission reports whether the authenticated user's roles grant the permission
+func HasPermission(r *http.Request, permission string) bool {
+	return slices.Contains(utils.PermissionsFrom(r.Context()), permission)
+}
+
+// RequirePermission only lets requests through whose user holds the permission. It runs after
+// AuthMiddleware.
+func RequirePermission(permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !HasPermission(r, permission) {
+				http.Error(w, "Forbidden: "+permission+" permission required", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
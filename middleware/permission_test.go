@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/domain"
+	"github.com/CodeWithKrushnal/ChainBank/internal/utils"
+)
+
+func TestRequirePermission(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want int
+	}{
+		{"permission granted", utils.WithPermissions(context.Background(), []string{domain.PermissionRoleManage}), http.StatusOK},
+		{"other permissions", utils.WithPermissions(context.Background(), []string{domain.PermissionUserManage}), http.StatusForbidden},
+		{"no permissions", context.Background(), http.StatusForbidden},
+		// Only the auth middleware can grant permissions, a plain string key is not honoured
+		{"string key", context.WithValue(context.Background(), "permissions", []string{domain.PermissionRoleManage}), http.StatusForbidden},
+	}
+
+	handler := RequirePermission(domain.PermissionRoleManage)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil).WithContext(test.ctx))
+			if recorder.Code != test.want {
+				t.Errorf("status = %d, want %d", recorder.Code, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
+)
+
+// RateLimiter counts requests per client in fixed windows aligned to the clock. With a store the
+// counts are shared by every instance using it, without one, or while the store fails, each instance
+// counts on its own. The limit can be changed at runtime, a limit of 0 lets every request through.
+type RateLimiter struct {
+	// Keeps the counters of the limiters sharing a store apart
+	name  string
+	store repo.RateLimitStorer
+
+	mu          sync.Mutex
+	limit       int
+	window      time.Duration
+	windowStart time.Time
+	counts      map[string]int
+}
+
+// Constructor function, a nil store keeps the counts in memory
+func NewRateLimiter(name string, window time.Duration, store repo.RateLimitStorer) *RateLimiter {
+	return &RateLimiter{
+		name:        name,
+		store:       store,
+		window:      window,
+		windowStart: time.Now().Truncate(window),
+		counts:      map[string]int{},
+	}
+}
+
+// SetLimit changes the number of requests allowed per client and window
+func (rl *RateLimiter) SetLimit(limit int) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.limit = limit
+}
+
+// Allow records a request for the client and reports whether it is within the limit
+func (rl *RateLimiter) Allow(clientKey string) bool {
+	rl.mu.Lock()
+	limit := rl.limit
+	if limit <= 0 {
+		rl.mu.Unlock()
+		return true
+	}
+
+	// Start a fresh window, dropping counters of clients seen in the previous one
+	windowStart := time.Now().Truncate(rl.window)
+	if !windowStart.Equal(rl.windowStart) {
+		rl.windowStart = windowStart
+		rl.counts = map[string]int{}
+		if rl.store != nil {
+			go rl.store.DeleteRateLimitCountersBefore(rl.name, windowStart)
+		}
+	}
+
+	// The local count is the fallback should the store fail
+	rl.counts[clientKey]++
+	count := rl.counts[clientKey]
+	rl.mu.Unlock()
+
+	if rl.store != nil {
+		if shared, err := rl.store.IncrementRateLimitCounter(rl.name, clientKey, windowStart); err == nil {
+			count = shared
+		}
+	}
+	return count <= limit
+}
+
+// retryAfter returns the seconds left in the current window
+func (rl *RateLimiter) retryAfter() int {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	return int(time.Until(rl.windowStart.Add(rl.window)).Seconds()) + 1
+}
+
+// ClientIP returns the address the request came from, without the port
+func ClientIP(r *http.Request) string {
+	clientIP, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return clientIP
+}
+
+func RateLimitMiddleware(limiter *RateLimiter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !limiter.Allow(ClientIP(r)) {
+				w.Header().Set("Retry-After", strconv.Itoa(limiter.retryAfter()))
+				http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
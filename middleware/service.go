@@ -1,35 +1,89 @@
 package middleware
 
 import (
+	"time"
+
+	"github.com/CodeWithKrushnal/ChainBank/internal/app/apikeys"
+	"github.com/CodeWithKrushnal/ChainBank/internal/config"
+	"github.com/CodeWithKrushnal/ChainBank/internal/domain"
 	"github.com/CodeWithKrushnal/ChainBank/internal/repo"
 )
 
 type service struct {
-	userRepo   repo.UserStorer
-	walletRepo repo.WalletStorer
+	userRepo       repo.UserStorer
+	walletRepo     repo.WalletStorer
+	apiKeyRepo     repo.APIKeyStorer
+	delegationRepo repo.DelegationStorer
+	roleRepo       repo.RoleStorer
+	permissions    *permissionCache
 }
 
 type Service interface {
-	getUserByEmail(email string) (repo.User, error)
-	getUserHighestRole(userID string) (int, error)
+	getUserByEmail(email string) (repo.UserWithRole, error)
+	getUserByID(userID string) (repo.UserWithRole, error)
+	getUserPermissions(userID string, role int) ([]string, error)
 	updateLastLogin(userID string) error
+	getAPIKey(plainKey string) (repo.APIKey, error)
+	touchAPIKey(keyID string) error
+	getActiveDelegation(grantorUserID, delegateUserID string) (repo.Delegation, error)
+	recordDelegationUse(delegation repo.Delegation, method, path string) error
 }
 
-func NewService(userRepo repo.UserStorer, walletRepo repo.WalletStorer) Service {
+func NewService(userRepo repo.UserStorer, walletRepo repo.WalletStorer, apiKeyRepo repo.APIKeyStorer, delegationRepo repo.DelegationStorer, roleRepo repo.RoleStorer) Service {
 	return service{
-		userRepo:   userRepo,
-		walletRepo: walletRepo,
+		userRepo:       userRepo,
+		walletRepo:     walletRepo,
+		apiKeyRepo:     apiKeyRepo,
+		delegationRepo: delegationRepo,
+		roleRepo:       roleRepo,
+		permissions:    newPermissionCache(time.Duration(config.ConfigDetails.PermissionCacheTTLSeconds) * time.Second),
 	}
 }
 
-func (authServiceDep service) getUserByEmail(email string) (repo.User, error) {
-	return authServiceDep.userRepo.GetUserByEmail(email)
+// getUserByEmail returns a user with its role, loaded in a single query
+func (authServiceDep service) getUserByEmail(email string) (repo.UserWithRole, error) {
+	return authServiceDep.userRepo.GetUserWithRoleByEmail(email)
 }
 
-func (authServiceDep service) getUserHighestRole(userID string) (int, error) {
-	return authServiceDep.userRepo.GetUserHighestRole(userID)
+// getUserPermissions returns the permissions of a user with the given role, from the cache while they
+// are fresh. Admins hold every permission, everyone else those granted by their roles.
+func (authServiceDep service) getUserPermissions(userID string, role int) ([]string, error) {
+	if role == domain.RoleAdmin {
+		return domain.Permissions, nil
+	}
+	if permissions, ok := authServiceDep.permissions.get(userID); ok {
+		return permissions, nil
+	}
+
+	permissions, err := authServiceDep.roleRepo.GetUserPermissions(userID)
+	if err != nil {
+		return nil, err
+	}
+	authServiceDep.permissions.put(userID, permissions)
+	return permissions, nil
 }
 
 func (authServiceDep service) updateLastLogin(userID string) error {
 	return authServiceDep.userRepo.UpdateLastLogin(userID)
 }
+
+// getUserByID returns a user with its role, loaded in a single query
+func (authServiceDep service) getUserByID(userID string) (repo.UserWithRole, error) {
+	return authServiceDep.userRepo.GetUserWithRoleByID(userID)
+}
+
+func (authServiceDep service) getAPIKey(plainKey string) (repo.APIKey, error) {
+	return authServiceDep.apiKeyRepo.GetAPIKeyByHash(apikeys.HashKey(plainKey))
+}
+
+func (authServiceDep service) touchAPIKey(keyID string) error {
+	return authServiceDep.apiKeyRepo.TouchAPIKey(keyID)
+}
+
+func (authServiceDep service) getActiveDelegation(grantorUserID, delegateUserID string) (repo.Delegation, error) {
+	return authServiceDep.delegationRepo.GetActiveDelegation(grantorUserID, delegateUserID)
+}
+
+func (authServiceDep service) recordDelegationUse(delegation repo.Delegation, method, path string) error {
+	return authServiceDep.delegationRepo.RecordDelegationUse(delegation, method, path)
+}
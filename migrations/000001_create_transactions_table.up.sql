@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS transactions (
+    transaction_id     UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tx_hash            VARCHAR(66) NOT NULL UNIQUE,
+    sender_user_id     UUID NOT NULL REFERENCES users(user_id),
+    receiver_user_id   UUID NOT NULL REFERENCES users(user_id),
+    sender_wallet_id   VARCHAR(42) NOT NULL,
+    receiver_wallet_id VARCHAR(42) NOT NULL,
+    amount             NUMERIC(78, 0) NOT NULL,
+    status             VARCHAR(20) NOT NULL DEFAULT 'pending',
+    created_at         TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_wallets_user_id;
+DROP INDEX IF EXISTS idx_user_roles_assignment_user_id;
+DROP INDEX IF EXISTS idx_transactions_receiver_created_at;
+DROP INDEX IF EXISTS idx_transactions_sender_created_at;
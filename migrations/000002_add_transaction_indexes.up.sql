@@ -0,0 +1,13 @@
+-- Transaction history is always read per user, newest first, for either side of the transfer
+CREATE INDEX IF NOT EXISTS idx_transactions_sender_created_at
+    ON transactions (sender_user_id, created_at DESC, transaction_id DESC);
+
+CREATE INDEX IF NOT EXISTS idx_transactions_receiver_created_at
+    ON transactions (receiver_user_id, created_at DESC, transaction_id DESC);
+
+-- Role lookups on every authenticated request and wallet lookups on every transfer
+CREATE INDEX IF NOT EXISTS idx_user_roles_assignment_user_id
+    ON user_roles_assignment (user_id, role_id);
+
+CREATE INDEX IF NOT EXISTS idx_wallets_user_id
+    ON wallets (user_id);
@@ -0,0 +1,12 @@
+-- Cold storage for transactions older than the configured retention period
+CREATE TABLE IF NOT EXISTS transactions_archive (
+    LIKE transactions INCLUDING DEFAULTS,
+    archived_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (transaction_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_transactions_archive_created_at
+    ON transactions_archive (created_at);
+
+CREATE INDEX IF NOT EXISTS idx_transactions_created_at
+    ON transactions (created_at);
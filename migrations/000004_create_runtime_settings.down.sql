@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_transactions_sender_status_created_at;
+DROP TABLE IF EXISTS runtime_settings;
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS runtime_settings (
+    setting_key   VARCHAR(100) PRIMARY KEY,
+    setting_value TEXT NOT NULL,
+    updated_by    UUID REFERENCES users(user_id),
+    updated_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_transactions_sender_status_created_at
+    ON transactions (sender_user_id, status, created_at);
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS feature_flags (
+    flag_key           VARCHAR(100) PRIMARY KEY,
+    enabled            BOOLEAN NOT NULL DEFAULT FALSE,
+    rollout_percentage SMALLINT NOT NULL DEFAULT 0 CHECK (rollout_percentage BETWEEN 0 AND 100),
+    allowed_user_ids   TEXT[] NOT NULL DEFAULT '{}',
+    updated_by         UUID REFERENCES users(user_id),
+    updated_at         TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
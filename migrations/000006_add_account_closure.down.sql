@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS account_closures;
+ALTER TABLE users DROP COLUMN IF EXISTS closed_at;
+ALTER TABLE users DROP COLUMN IF EXISTS account_status;
@@ -0,0 +1,12 @@
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS account_status VARCHAR(20) NOT NULL DEFAULT 'active',
+    ADD COLUMN IF NOT EXISTS closed_at TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS account_closures (
+    user_id       UUID PRIMARY KEY REFERENCES users(user_id),
+    sweep_address VARCHAR(42),
+    sweep_tx_hash VARCHAR(66),
+    closed_by     UUID NOT NULL REFERENCES users(user_id),
+    reason        TEXT NOT NULL DEFAULT '',
+    closed_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
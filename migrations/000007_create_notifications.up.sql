@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS device_tokens (
+    device_token VARCHAR(512) PRIMARY KEY,
+    user_id      UUID NOT NULL REFERENCES users(user_id),
+    platform     VARCHAR(10) NOT NULL CHECK (platform IN ('android', 'ios')),
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_device_tokens_user_id ON device_tokens (user_id);
+
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id       UUID PRIMARY KEY REFERENCES users(user_id),
+    email_enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    push_enabled  BOOLEAN NOT NULL DEFAULT TRUE,
+    sms_enabled   BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS notification_digest_queue;
+ALTER TABLE notification_preferences DROP COLUMN IF EXISTS delivery_mode;
+ALTER TABLE notification_preferences DROP COLUMN IF EXISTS muted_categories;
@@ -0,0 +1,16 @@
+ALTER TABLE notification_preferences
+    ADD COLUMN IF NOT EXISTS muted_categories TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN IF NOT EXISTS delivery_mode VARCHAR(20) NOT NULL DEFAULT 'immediate'
+        CHECK (delivery_mode IN ('immediate', 'daily_digest'));
+
+CREATE TABLE IF NOT EXISTS notification_digest_queue (
+    digest_item_id BIGSERIAL PRIMARY KEY,
+    user_id        UUID NOT NULL REFERENCES users(user_id),
+    category       VARCHAR(50) NOT NULL,
+    title          TEXT NOT NULL,
+    body           TEXT NOT NULL,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_digest_queue_user_id
+    ON notification_digest_queue (user_id, created_at);
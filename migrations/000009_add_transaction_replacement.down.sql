@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_transactions_pending_broadcast;
+ALTER TABLE transactions
+    DROP COLUMN IF EXISTS broadcast_at,
+    DROP COLUMN IF EXISTS cancel_requested,
+    DROP COLUMN IF EXISTS previous_tx_hashes,
+    DROP COLUMN IF EXISTS gas_price,
+    DROP COLUMN IF EXISTS nonce;
@@ -0,0 +1,10 @@
+-- Broadcast details needed to replace or cancel a transfer that never got a receipt
+ALTER TABLE transactions
+    ADD COLUMN IF NOT EXISTS nonce              BIGINT,
+    ADD COLUMN IF NOT EXISTS gas_price          NUMERIC(78, 0),
+    ADD COLUMN IF NOT EXISTS previous_tx_hashes TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN IF NOT EXISTS cancel_requested   BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS broadcast_at       TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+CREATE INDEX IF NOT EXISTS idx_transactions_pending_broadcast
+    ON transactions (broadcast_at) WHERE status = 'pending';
@@ -0,0 +1,3 @@
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_account_status_check;
+ALTER TABLE transactions_archive DROP CONSTRAINT IF EXISTS transactions_archive_status_check;
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_status_check;
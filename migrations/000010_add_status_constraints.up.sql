@@ -0,0 +1,16 @@
+-- Statuses are compared case-sensitively in code, normalise any legacy rows before constraining them
+UPDATE transactions SET status = LOWER(status) WHERE status <> LOWER(status);
+UPDATE transactions_archive SET status = LOWER(status) WHERE status <> LOWER(status);
+UPDATE users SET account_status = LOWER(account_status) WHERE account_status <> LOWER(account_status);
+
+ALTER TABLE transactions
+    ADD CONSTRAINT transactions_status_check
+    CHECK (status IN ('pending', 'confirmed', 'failed', 'cancelled'));
+
+ALTER TABLE transactions_archive
+    ADD CONSTRAINT transactions_archive_status_check
+    CHECK (status IN ('pending', 'confirmed', 'failed', 'cancelled'));
+
+ALTER TABLE users
+    ADD CONSTRAINT users_account_status_check
+    CHECK (account_status IN ('active', 'closed'));
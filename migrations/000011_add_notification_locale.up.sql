@@ -0,0 +1,2 @@
+ALTER TABLE notification_preferences
+    ADD COLUMN IF NOT EXISTS locale VARCHAR(10) NOT NULL DEFAULT 'en';
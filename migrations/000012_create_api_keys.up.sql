@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+    key_id       UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id      UUID NOT NULL REFERENCES users(user_id),
+    name         VARCHAR(100) NOT NULL,
+    key_prefix   VARCHAR(16) NOT NULL,
+    key_hash     CHAR(64) NOT NULL UNIQUE,
+    scopes       TEXT[] NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMPTZ,
+    revoked_at   TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys (user_id);
@@ -0,0 +1,11 @@
+-- External identities (OIDC issuer and subject) linked to a local account
+CREATE TABLE IF NOT EXISTS user_identities (
+    issuer     VARCHAR(255) NOT NULL,
+    subject    VARCHAR(255) NOT NULL,
+    user_id    UUID NOT NULL REFERENCES users(user_id),
+    email      VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (issuer, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities (user_id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_wallet_private_keys_key_version;
+
+ALTER TABLE wallet_private_keys
+    DROP COLUMN IF EXISTS key_version;
@@ -0,0 +1,5 @@
+-- Version of the key each wallet private key is encrypted with, rows written before key rotation use version 1
+ALTER TABLE wallet_private_keys
+    ADD COLUMN IF NOT EXISTS key_version INT NOT NULL DEFAULT 1;
+
+CREATE INDEX IF NOT EXISTS idx_wallet_private_keys_key_version ON wallet_private_keys (key_version);
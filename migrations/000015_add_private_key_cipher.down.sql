@@ -0,0 +1,15 @@
+-- Without the column every key is read as AES-CFB, keys re-sealed with AES-GCM would decrypt to
+-- garbage and their funds be lost. The rollback refuses to run while any key is on AES-GCM.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM wallet_private_keys WHERE cipher <> 'aes-cfb') THEN
+        RAISE EXCEPTION 'wallet_private_keys holds keys sealed with AES-GCM, they cannot be read after rolling back 000015';
+    END IF;
+END
+$$;
+
+ALTER TABLE wallet_private_keys
+    DROP CONSTRAINT IF EXISTS wallet_private_keys_cipher_check;
+
+ALTER TABLE wallet_private_keys
+    DROP COLUMN IF EXISTS cipher;
@@ -0,0 +1,7 @@
+-- Cipher of each stored private key, rows written before AES-GCM stay on aes-cfb until the key
+-- rotation job (POST /api/admin/key-rotation) re-encrypts them
+ALTER TABLE wallet_private_keys
+    ADD COLUMN IF NOT EXISTS cipher VARCHAR(16) NOT NULL DEFAULT 'aes-cfb';
+
+ALTER TABLE wallet_private_keys
+    ADD CONSTRAINT wallet_private_keys_cipher_check CHECK (cipher IN ('aes-cfb', 'aes-gcm'));
@@ -0,0 +1,8 @@
+-- One on-chain balance per wallet and UTC day, recorded by the snapshot scheduler
+CREATE TABLE IF NOT EXISTS wallet_balance_snapshots (
+    wallet_id     VARCHAR(42) NOT NULL,
+    snapshot_date DATE NOT NULL,
+    balance       NUMERIC(78, 0) NOT NULL,
+    recorded_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (wallet_id, snapshot_date)
+);
@@ -0,0 +1,16 @@
+-- Monthly spending budget per user, checked against sent transfers by the budget scheduler
+CREATE TABLE IF NOT EXISTS budgets (
+    user_id       UUID PRIMARY KEY REFERENCES users(user_id),
+    monthly_limit NUMERIC(78, 0) NOT NULL CHECK (monthly_limit > 0),
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Thresholds already alerted on, so each one is only sent once per month
+CREATE TABLE IF NOT EXISTS budget_alerts (
+    user_id   UUID NOT NULL REFERENCES users(user_id),
+    month     DATE NOT NULL,
+    threshold INT NOT NULL,
+    sent_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, month, threshold)
+);
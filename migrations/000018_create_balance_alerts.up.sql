@@ -0,0 +1,9 @@
+-- Standing balance alerts per user, a NULL threshold leaves that alert off
+CREATE TABLE IF NOT EXISTS balance_alerts (
+    user_id             UUID PRIMARY KEY REFERENCES users(user_id),
+    low_balance_wei     NUMERIC(78, 0) CHECK (low_balance_wei > 0),
+    large_credit_wei    NUMERIC(78, 0) CHECK (large_credit_wei > 0),
+    -- Set once the low balance alert fired, cleared when the balance is seen above the threshold again
+    low_balance_alerted BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_at          TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS organization_payment_approvals;
+DROP TABLE IF EXISTS organization_payments;
+DROP TABLE IF EXISTS organization_members;
+DROP TABLE IF EXISTS organizations;
+DROP TABLE IF EXISTS organization_wallet_keys;
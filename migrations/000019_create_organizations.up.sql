@@ -0,0 +1,57 @@
+-- Private keys of organization wallets, encrypted like wallet_private_keys and rotated with them.
+-- A key is stored before its organization is created, so a failed creation never loses a key.
+CREATE TABLE IF NOT EXISTS organization_wallet_keys (
+    wallet_id   VARCHAR(42) PRIMARY KEY,
+    private_key TEXT NOT NULL,
+    key_version INT NOT NULL,
+    cipher      VARCHAR(16) NOT NULL DEFAULT 'aes-gcm' CHECK (cipher IN ('aes-cfb', 'aes-gcm')),
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Business accounts owning their own wallet, payments from it need approvals_required approvers
+CREATE TABLE IF NOT EXISTS organizations (
+    org_id             UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name               VARCHAR(255) NOT NULL,
+    owner_user_id      UUID NOT NULL REFERENCES users(user_id),
+    wallet_id          VARCHAR(42) NOT NULL UNIQUE REFERENCES organization_wallet_keys(wallet_id),
+    approvals_required INT NOT NULL DEFAULT 1 CHECK (approvals_required BETWEEN 1 AND 10),
+    created_at         TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Members and their permissions, invited members have no access until they accept
+CREATE TABLE IF NOT EXISTS organization_members (
+    org_id      UUID NOT NULL REFERENCES organizations(org_id),
+    user_id     UUID NOT NULL REFERENCES users(user_id),
+    permissions TEXT[] NOT NULL DEFAULT '{}',
+    status      VARCHAR(16) NOT NULL DEFAULT 'invited' CHECK (status IN ('invited', 'active')),
+    invited_by  UUID REFERENCES users(user_id),
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (org_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_organization_members_user_id ON organization_members (user_id);
+
+CREATE TABLE IF NOT EXISTS organization_payments (
+    payment_id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    org_id              UUID NOT NULL REFERENCES organizations(org_id),
+    initiated_by        UUID NOT NULL REFERENCES users(user_id),
+    recipient_user_id   UUID NOT NULL REFERENCES users(user_id),
+    recipient_wallet_id VARCHAR(42) NOT NULL,
+    amount              NUMERIC(78, 0) NOT NULL CHECK (amount > 0),
+    status              VARCHAR(20) NOT NULL DEFAULT 'pending_approval'
+        CHECK (status IN ('pending_approval', 'executing', 'executed', 'rejected', 'failed')),
+    tx_hash             VARCHAR(66),
+    rejected_by         UUID REFERENCES users(user_id),
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at        TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_organization_payments_org_id ON organization_payments (org_id, created_at DESC);
+
+-- One row per approver, the initiator of a payment cannot approve it
+CREATE TABLE IF NOT EXISTS organization_payment_approvals (
+    payment_id  UUID NOT NULL REFERENCES organization_payments(payment_id),
+    user_id     UUID NOT NULL REFERENCES users(user_id),
+    approved_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (payment_id, user_id)
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS delegation_audit_log;
+DROP TABLE IF EXISTS delegations;
@@ -0,0 +1,27 @@
+-- Access a user grants another user to their account until expires_at, revoking ends it at once
+CREATE TABLE IF NOT EXISTS delegations (
+    delegation_id    UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    grantor_user_id  UUID NOT NULL REFERENCES users(user_id),
+    delegate_user_id UUID NOT NULL REFERENCES users(user_id),
+    scopes           TEXT[] NOT NULL,
+    expires_at       TIMESTAMPTZ NOT NULL,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    revoked_at       TIMESTAMPTZ,
+    CHECK (grantor_user_id <> delegate_user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_delegations_grantor ON delegations (grantor_user_id);
+CREATE INDEX IF NOT EXISTS idx_delegations_delegate ON delegations (delegate_user_id);
+
+-- Every grant, revocation and request made under a delegation
+CREATE TABLE IF NOT EXISTS delegation_audit_log (
+    audit_id      BIGSERIAL PRIMARY KEY,
+    delegation_id UUID NOT NULL REFERENCES delegations(delegation_id),
+    actor_user_id UUID NOT NULL REFERENCES users(user_id),
+    action        VARCHAR(16) NOT NULL CHECK (action IN ('granted', 'revoked', 'used')),
+    method        VARCHAR(10),
+    path          TEXT,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_delegation_audit_log_delegation ON delegation_audit_log (delegation_id, created_at DESC);
@@ -0,0 +1,23 @@
+-- Bulk user imports uploaded by admins, rows are processed in the background
+CREATE TABLE IF NOT EXISTS user_imports (
+    import_id    UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_by   UUID NOT NULL REFERENCES users(user_id),
+    status       VARCHAR(16) NOT NULL DEFAULT 'running' CHECK (status IN ('running', 'completed')),
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMPTZ
+);
+
+-- Outcome of each CSV row, rows whose email already has an account are skipped so re-runs are safe
+CREATE TABLE IF NOT EXISTS user_import_rows (
+    import_id       UUID NOT NULL REFERENCES user_imports(import_id),
+    row_number      INT NOT NULL,
+    email           VARCHAR(255) NOT NULL,
+    full_name       VARCHAR(255) NOT NULL DEFAULT '',
+    role            INT NOT NULL,
+    initial_funding NUMERIC(78, 0),
+    status          VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'created', 'skipped', 'failed')),
+    user_id         UUID REFERENCES users(user_id),
+    wallet_id       VARCHAR(42),
+    error           TEXT,
+    PRIMARY KEY (import_id, row_number)
+);
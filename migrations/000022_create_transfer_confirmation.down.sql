@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS transfer_devices;
+DROP TABLE IF EXISTS transfer_totp_secrets;
+DROP TABLE IF EXISTS transfer_confirmation_settings;
@@ -0,0 +1,31 @@
+-- How each user confirms transfers, users without a row confirm with their password
+CREATE TABLE IF NOT EXISTS transfer_confirmation_settings (
+    user_id    UUID PRIMARY KEY REFERENCES users(user_id),
+    mode       VARCHAR(16) NOT NULL CHECK (mode IN ('password', 'totp', 'device')),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Authenticator app secrets, encrypted like wallet_private_keys and rotated with them. A code
+-- step is only accepted once, last_used_step is the latest one used.
+CREATE TABLE IF NOT EXISTS transfer_totp_secrets (
+    user_id        UUID PRIMARY KEY REFERENCES users(user_id),
+    secret         TEXT NOT NULL,
+    key_version    INT NOT NULL,
+    cipher         VARCHAR(16) NOT NULL DEFAULT 'aes-gcm' CHECK (cipher IN ('aes-gcm')),
+    confirmed_at   TIMESTAMPTZ,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Devices pre-authorized to confirm transfers, only the SHA-256 hash of their token is stored
+CREATE TABLE IF NOT EXISTS transfer_devices (
+    device_id    UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id      UUID NOT NULL REFERENCES users(user_id),
+    name         VARCHAR(100) NOT NULL,
+    token_hash   VARCHAR(64) NOT NULL UNIQUE,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMPTZ,
+    revoked_at   TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_transfer_devices_user ON transfer_devices (user_id) WHERE revoked_at IS NULL;
@@ -0,0 +1,9 @@
+-- 6-digit PINs confirming small transfers instead of the password, stored as bcrypt hashes.
+-- Each attempt is counted before it is checked, reaching the limit locks the PIN until locked_until.
+CREATE TABLE IF NOT EXISTS transaction_pins (
+    user_id         UUID PRIMARY KEY REFERENCES users(user_id),
+    pin_hash        TEXT NOT NULL,
+    failed_attempts INT NOT NULL DEFAULT 0,
+    locked_until    TIMESTAMPTZ,
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_transactions_block_number;
+ALTER TABLE transactions
+    DROP COLUMN IF EXISTS reorg_count,
+    DROP COLUMN IF EXISTS block_hash,
+    DROP COLUMN IF EXISTS block_number;
@@ -0,0 +1,9 @@
+-- Block a settled transfer was mined in, re-checked against the canonical chain to catch reorgs.
+-- reorg_count is how often the transfer went back to pending because its block was orphaned.
+ALTER TABLE transactions
+    ADD COLUMN IF NOT EXISTS block_number BIGINT,
+    ADD COLUMN IF NOT EXISTS block_hash   VARCHAR(66),
+    ADD COLUMN IF NOT EXISTS reorg_count  INT NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_transactions_block_number
+    ON transactions (block_number) WHERE block_hash IS NOT NULL;
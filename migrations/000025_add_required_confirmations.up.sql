@@ -0,0 +1,4 @@
+-- Blocks a transfer must be buried under before it is marked confirmed, fixed by the
+-- confirmations policy when the transfer is broadcast
+ALTER TABLE transactions
+    ADD COLUMN IF NOT EXISTS required_confirmations INT NOT NULL DEFAULT 1;
@@ -0,0 +1,2 @@
+ALTER TABLE wallet_private_keys DROP COLUMN IF EXISTS derivation_index;
+DROP SEQUENCE IF EXISTS hd_wallet_index_seq;
@@ -0,0 +1,6 @@
+-- BIP-44 account index of wallets derived from the platform HD seed. Keys generated before
+-- HD_WALLET_SEED was set keep a NULL index and are reported as imported.
+CREATE SEQUENCE IF NOT EXISTS hd_wallet_index_seq MINVALUE 0 START WITH 0;
+
+ALTER TABLE wallet_private_keys
+    ADD COLUMN IF NOT EXISTS derivation_index INT UNIQUE;
@@ -0,0 +1,12 @@
+-- External addresses linked in watch-only mode, the platform never holds their keys. A link stays
+-- unverified until the challenge is signed with the address, which proves ownership.
+CREATE TABLE IF NOT EXISTS external_wallets (
+    user_id              UUID NOT NULL REFERENCES users(user_id),
+    address              VARCHAR(42) NOT NULL,
+    label                VARCHAR(64) NOT NULL DEFAULT '',
+    challenge            TEXT NOT NULL,
+    challenge_expires_at TIMESTAMPTZ NOT NULL,
+    verified_at          TIMESTAMPTZ,
+    created_at           TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, address)
+);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_external_wallets_address;
+DROP TABLE IF EXISTS siwe_nonces;
@@ -0,0 +1,13 @@
+-- Nonces handed out for Sign-In with Ethereum (EIP-4361). Each nonce signs in once, used_at is set
+-- when a login claims it so a replayed message is rejected.
+CREATE TABLE IF NOT EXISTS siwe_nonces (
+    nonce      VARCHAR(64) PRIMARY KEY,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at    TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_siwe_nonces_expires_at ON siwe_nonces (expires_at);
+
+-- Login resolves the account through a verified link of the signing address
+CREATE INDEX IF NOT EXISTS idx_external_wallets_address ON external_wallets (address) WHERE verified_at IS NOT NULL;
@@ -0,0 +1,17 @@
+-- Requests for a payment to the requester, shared as a QR code. A request is paying while the
+-- payer's transfer is being confirmed and broadcast, so it cannot be paid twice.
+CREATE TABLE IF NOT EXISTS payment_requests (
+    request_id        UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    requester_user_id UUID NOT NULL REFERENCES users(user_id),
+    amount            NUMERIC(78, 0) NOT NULL CHECK (amount > 0),
+    memo              VARCHAR(140) NOT NULL DEFAULT '',
+    status            VARCHAR(16) NOT NULL DEFAULT 'open'
+        CHECK (status IN ('open', 'paying', 'paid', 'cancelled')),
+    expires_at        TIMESTAMPTZ NOT NULL,
+    paid_by           UUID REFERENCES users(user_id),
+    tx_hash           VARCHAR(66),
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    paid_at           TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_payment_requests_requester ON payment_requests (requester_user_id, created_at DESC);
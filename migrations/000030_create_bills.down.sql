@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_payment_requests_payer;
+DROP INDEX IF EXISTS idx_payment_requests_bill;
+ALTER TABLE payment_requests
+    DROP COLUMN IF EXISTS payer_user_id,
+    DROP COLUMN IF EXISTS bill_id;
+DROP TABLE IF EXISTS bills;
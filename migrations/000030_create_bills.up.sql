@@ -0,0 +1,19 @@
+-- A bill split among participants, each share is a payment request addressed to one participant.
+-- The status of a bill is derived from its requests.
+CREATE TABLE IF NOT EXISTS bills (
+    bill_id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organizer_user_id UUID NOT NULL REFERENCES users(user_id),
+    title             VARCHAR(100) NOT NULL,
+    total_amount      NUMERIC(78, 0) NOT NULL CHECK (total_amount > 0),
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_bills_organizer ON bills (organizer_user_id, created_at DESC);
+
+-- Requests of a bill can only be paid by their participant, other requests by anyone
+ALTER TABLE payment_requests
+    ADD COLUMN IF NOT EXISTS bill_id UUID REFERENCES bills(bill_id),
+    ADD COLUMN IF NOT EXISTS payer_user_id UUID REFERENCES users(user_id);
+
+CREATE INDEX IF NOT EXISTS idx_payment_requests_bill ON payment_requests (bill_id) WHERE bill_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_payment_requests_payer ON payment_requests (payer_user_id, created_at DESC) WHERE payer_user_id IS NOT NULL;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS merchant_settlements;
+DROP TABLE IF EXISTS merchant_webhook_events;
+DROP TABLE IF EXISTS payment_intents;
+DROP TABLE IF EXISTS merchant_webhook_secrets;
+DROP TABLE IF EXISTS merchants;
@@ -0,0 +1,66 @@
+-- Merchants accept payments from ChainBank customers into the wallet of their owner
+CREATE TABLE IF NOT EXISTS merchants (
+    merchant_id   UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    owner_user_id UUID NOT NULL UNIQUE REFERENCES users(user_id),
+    name          VARCHAR(100) NOT NULL,
+    webhook_url   TEXT NOT NULL DEFAULT '',
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Secrets signing the webhooks of a merchant, encrypted like wallet_private_keys and rotated with them
+CREATE TABLE IF NOT EXISTS merchant_webhook_secrets (
+    merchant_id UUID PRIMARY KEY REFERENCES merchants(merchant_id),
+    secret      TEXT NOT NULL,
+    key_version INT NOT NULL,
+    cipher      VARCHAR(16) NOT NULL DEFAULT 'aes-gcm' CHECK (cipher IN ('aes-gcm')),
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- An amount a merchant wants to collect. Reference is the merchant's own order ID and makes
+-- creating an intent idempotent.
+CREATE TABLE IF NOT EXISTS payment_intents (
+    intent_id        UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    merchant_id      UUID NOT NULL REFERENCES merchants(merchant_id),
+    amount           NUMERIC(78, 0) NOT NULL CHECK (amount > 0),
+    description      VARCHAR(255) NOT NULL DEFAULT '',
+    reference        VARCHAR(100) NOT NULL DEFAULT '',
+    status           VARCHAR(20) NOT NULL DEFAULT 'requires_payment'
+        CHECK (status IN ('requires_payment', 'processing', 'succeeded', 'cancelled')),
+    expires_at       TIMESTAMPTZ NOT NULL,
+    customer_user_id UUID REFERENCES users(user_id),
+    tx_hash          VARCHAR(66),
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at     TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_payment_intents_merchant ON payment_intents (merchant_id, created_at DESC);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payment_intents_reference ON payment_intents (merchant_id, reference) WHERE reference <> '';
+CREATE INDEX IF NOT EXISTS idx_payment_intents_completed ON payment_intents (completed_at) WHERE status = 'succeeded';
+
+-- Events waiting to be delivered to the merchant's webhook, retried with backoff until they
+-- are delivered or run out of attempts
+CREATE TABLE IF NOT EXISTS merchant_webhook_events (
+    event_id        UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    merchant_id     UUID NOT NULL REFERENCES merchants(merchant_id),
+    event_type      VARCHAR(50) NOT NULL,
+    payload         JSONB NOT NULL,
+    status          VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'failed')),
+    attempts        INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_error      TEXT NOT NULL DEFAULT '',
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    delivered_at    TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_merchant_webhook_events_due ON merchant_webhook_events (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_merchant_webhook_events_merchant ON merchant_webhook_events (merchant_id, created_at DESC);
+
+-- Daily totals of the payments a merchant received, by UTC day of completion
+CREATE TABLE IF NOT EXISTS merchant_settlements (
+    merchant_id     UUID NOT NULL REFERENCES merchants(merchant_id),
+    settlement_date DATE NOT NULL,
+    payment_count   INT NOT NULL,
+    gross_amount    NUMERIC(78, 0) NOT NULL,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (merchant_id, settlement_date)
+);
@@ -0,0 +1,36 @@
+-- An invoice from the issuer to another user or to an email address without an account yet.
+-- Overdue is derived from due_date, an incoming transfer of the total to the payee wallet marks it paid.
+CREATE TABLE IF NOT EXISTS invoices (
+    invoice_id        UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    issuer_user_id    UUID NOT NULL REFERENCES users(user_id),
+    recipient_user_id UUID REFERENCES users(user_id),
+    recipient_email   VARCHAR(255) NOT NULL DEFAULT '',
+    payee_wallet_id   VARCHAR(42) NOT NULL,
+    title             VARCHAR(100) NOT NULL,
+    notes             VARCHAR(500) NOT NULL DEFAULT '',
+    total_amount      NUMERIC(78, 0) NOT NULL CHECK (total_amount > 0),
+    due_date          DATE NOT NULL,
+    status            VARCHAR(16) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'paid', 'cancelled')),
+    tx_hash           VARCHAR(66),
+    paid_at           TIMESTAMPTZ,
+    reminder_count    INT NOT NULL DEFAULT 0,
+    last_reminder_at  TIMESTAMPTZ,
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (recipient_user_id IS NOT NULL OR recipient_email <> '')
+);
+
+CREATE INDEX IF NOT EXISTS idx_invoices_issuer ON invoices (issuer_user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_invoices_recipient ON invoices (recipient_user_id, created_at DESC) WHERE recipient_user_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_invoices_recipient_email ON invoices (LOWER(recipient_email)) WHERE recipient_user_id IS NULL;
+CREATE INDEX IF NOT EXISTS idx_invoices_open ON invoices (payee_wallet_id, due_date) WHERE status = 'open';
+-- A transfer settles one invoice at most
+CREATE UNIQUE INDEX IF NOT EXISTS idx_invoices_tx_hash ON invoices (tx_hash) WHERE tx_hash IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS invoice_line_items (
+    invoice_id  UUID NOT NULL REFERENCES invoices(invoice_id),
+    position    INT NOT NULL,
+    description VARCHAR(140) NOT NULL,
+    quantity    INT NOT NULL CHECK (quantity > 0),
+    unit_amount NUMERIC(78, 0) NOT NULL CHECK (unit_amount > 0),
+    PRIMARY KEY (invoice_id, position)
+);
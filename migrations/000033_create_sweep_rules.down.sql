@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS sweeps;
+DROP TABLE IF EXISTS sweep_rules;
@@ -0,0 +1,29 @@
+-- Standing instruction to move the balance above a threshold to one of the user's verified
+-- external wallets, the user's pot outside the platform
+CREATE TABLE IF NOT EXISTS sweep_rules (
+    user_id             UUID PRIMARY KEY REFERENCES users(user_id),
+    threshold           NUMERIC(78, 0) NOT NULL CHECK (threshold >= 0),
+    min_sweep_amount    NUMERIC(78, 0) NOT NULL DEFAULT 0 CHECK (min_sweep_amount >= 0),
+    destination_address VARCHAR(42) NOT NULL,
+    enabled             BOOLEAN NOT NULL DEFAULT TRUE,
+    last_run_at         TIMESTAMPTZ,
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at          TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Every sweep the scheduler attempted, failed attempts keep their error
+CREATE TABLE IF NOT EXISTS sweeps (
+    sweep_id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id             UUID NOT NULL REFERENCES users(user_id),
+    wallet_id           VARCHAR(42) NOT NULL,
+    destination_address VARCHAR(42) NOT NULL,
+    balance             NUMERIC(78, 0) NOT NULL,
+    threshold           NUMERIC(78, 0) NOT NULL,
+    amount              NUMERIC(78, 0) NOT NULL,
+    status              VARCHAR(16) NOT NULL CHECK (status IN ('sent', 'failed')),
+    tx_hash             VARCHAR(66),
+    error               TEXT NOT NULL DEFAULT '',
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_sweeps_user ON sweeps (user_id, created_at DESC);
@@ -0,0 +1,30 @@
+-- Admin reversals of erroneous transfers. The original receiver returns the amount to the original
+-- sender on-chain, the return is recorded in transactions as the compensating entry and linked here.
+-- transaction IDs carry no foreign key since settled transfers move to transactions_archive.
+CREATE TABLE IF NOT EXISTS transfer_reversals (
+    reversal_id             UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    original_transaction_id UUID NOT NULL,
+    reversal_transaction_id UUID,
+    sender_user_id          UUID NOT NULL REFERENCES users(user_id),
+    receiver_user_id        UUID NOT NULL REFERENCES users(user_id),
+    sender_wallet_id        VARCHAR(42) NOT NULL,
+    receiver_wallet_id      VARCHAR(42) NOT NULL,
+    amount                  NUMERIC(78, 0) NOT NULL CHECK (amount > 0),
+    reason_code             VARCHAR(32) NOT NULL,
+    note                    TEXT NOT NULL DEFAULT '',
+    status                  VARCHAR(20) NOT NULL DEFAULT 'pending_approval'
+        CHECK (status IN ('pending_approval', 'executing', 'executed', 'rejected', 'failed')),
+    requested_by            UUID NOT NULL REFERENCES users(user_id),
+    decided_by              UUID REFERENCES users(user_id),
+    tx_hash                 VARCHAR(66),
+    error                   TEXT NOT NULL DEFAULT '',
+    created_at              TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    decided_at              TIMESTAMPTZ,
+    CHECK (decided_by IS NULL OR decided_by <> requested_by)
+);
+
+-- A transfer has at most one reversal that is open or went through
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transfer_reversals_active ON transfer_reversals (original_transaction_id)
+    WHERE status IN ('pending_approval', 'executing', 'executed');
+
+CREATE INDEX IF NOT EXISTS idx_transfer_reversals_status ON transfer_reversals (status, created_at DESC);
@@ -0,0 +1,10 @@
+-- Non-business days besides weekends, per calendar region. Due dates falling on one of them or on a
+-- weekend roll forward to the next business day of the configured region.
+CREATE TABLE IF NOT EXISTS business_holidays (
+    region       VARCHAR(8) NOT NULL,
+    holiday_date DATE NOT NULL,
+    name         VARCHAR(100) NOT NULL,
+    created_by   UUID REFERENCES users(user_id),
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (region, holiday_date)
+);
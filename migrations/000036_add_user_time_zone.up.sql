@@ -0,0 +1,4 @@
+-- IANA time zone the user's days and months are counted in. Existing users get UTC, which is how
+-- every schedule was computed so far. Timestamps are TIMESTAMPTZ instants and need no conversion.
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS time_zone VARCHAR(64) NOT NULL DEFAULT 'UTC';
@@ -0,0 +1,19 @@
+-- Bulk exports requested by admins and written by a background worker. The gzipped file is kept in
+-- content, so any instance can serve the signed download link.
+CREATE TABLE IF NOT EXISTS export_jobs (
+    job_id       UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_by   UUID NOT NULL REFERENCES users(user_id),
+    dataset      VARCHAR(32) NOT NULL CHECK (dataset IN ('transactions')),
+    format       VARCHAR(8) NOT NULL CHECK (format IN ('csv', 'json')),
+    from_time    TIMESTAMPTZ,
+    to_time      TIMESTAMPTZ,
+    status       VARCHAR(16) NOT NULL DEFAULT 'queued' CHECK (status IN ('queued', 'running', 'completed', 'failed')),
+    row_count    INT NOT NULL DEFAULT 0,
+    content      BYTEA,
+    error        TEXT NOT NULL DEFAULT '',
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    started_at   TIMESTAMPTZ,
+    completed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_export_jobs_status ON export_jobs (status, created_at);
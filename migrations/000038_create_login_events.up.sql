@@ -0,0 +1,12 @@
+-- Successful sign-ins by password, SSO or Sign-In with Ethereum, shown in the user's activity feed.
+-- Authenticated API requests and API key use are not logins.
+CREATE TABLE IF NOT EXISTS login_events (
+    event_id   UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id    UUID NOT NULL REFERENCES users(user_id),
+    method     VARCHAR(16) NOT NULL CHECK (method IN ('password', 'oidc', 'siwe')),
+    ip_address VARCHAR(45) NOT NULL DEFAULT '',
+    user_agent VARCHAR(512) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_events_user ON login_events (user_id, created_at DESC);
@@ -0,0 +1,4 @@
+ALTER TABLE login_events DROP COLUMN IF EXISTS disowned_at;
+ALTER TABLE login_events DROP COLUMN IF EXISTS new_device;
+ALTER TABLE users DROP COLUMN IF EXISTS password_reset_required;
+ALTER TABLE users DROP COLUMN IF EXISTS tokens_valid_after;
@@ -0,0 +1,8 @@
+-- Login tokens issued before tokens_valid_after are rejected. It is set when the account holder
+-- reports a sign-in as not theirs, which also blocks password sign-in until the password is reset.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS tokens_valid_after TIMESTAMPTZ;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS password_reset_required BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Sign-ins from a network or user agent the user had not signed in from before are alerted on
+ALTER TABLE login_events ADD COLUMN IF NOT EXISTS new_device BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE login_events ADD COLUMN IF NOT EXISTS disowned_at TIMESTAMPTZ;
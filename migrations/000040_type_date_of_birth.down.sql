@@ -0,0 +1,2 @@
+-- Dates of birth stay typed, the column type before 000040 is not recorded and the cleared values
+-- cannot be restored
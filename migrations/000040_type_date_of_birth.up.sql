@@ -0,0 +1,4 @@
+-- Dates of birth are validated as YYYY-MM-DD on signup and stored as dates. Values that are not
+-- dates were never usable for the age check and are cleared.
+ALTER TABLE users ALTER COLUMN date_of_birth TYPE DATE
+    USING CASE WHEN date_of_birth::TEXT ~ '^\d{4}-\d{2}-\d{2}$' THEN date_of_birth::TEXT::DATE END;
@@ -0,0 +1,4 @@
+-- Usernames and emails identify a user, the indexes let concurrent signups for the same values
+-- conflict instead of both being inserted
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username_unique ON users (username);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_unique ON users (email);
@@ -0,0 +1,3 @@
+DELETE FROM user_roles_assignment WHERE role_id >= 100;
+DROP TABLE IF EXISTS role_permissions;
+DROP TABLE IF EXISTS role_definitions;
@@ -0,0 +1,24 @@
+-- Roles users can be assigned through user_roles_assignment. The built-in roles 1 to 3 keep their
+-- meaning, admins define further roles from 100 on and attach permissions to them.
+CREATE TABLE IF NOT EXISTS role_definitions (
+    role_id     SERIAL PRIMARY KEY,
+    name        VARCHAR(64) NOT NULL UNIQUE,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    built_in    BOOLEAN NOT NULL DEFAULT FALSE,
+    created_by  UUID REFERENCES users(user_id),
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO role_definitions (role_id, name, description, built_in) VALUES
+    (1, 'user', 'Self-service role chosen at signup', TRUE),
+    (2, 'member', 'Self-service role chosen at signup', TRUE),
+    (3, 'admin', 'Administrator, holds every permission', TRUE)
+ON CONFLICT (role_id) DO NOTHING;
+
+SELECT setval(pg_get_serial_sequence('role_definitions', 'role_id'), GREATEST(100, (SELECT MAX(role_id) + 1 FROM role_definitions)), FALSE);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role_id    INT NOT NULL REFERENCES role_definitions(role_id) ON DELETE CASCADE,
+    permission VARCHAR(64) NOT NULL,
+    PRIMARY KEY (role_id, permission)
+);
@@ -0,0 +1,23 @@
+-- Work done in the background: notification deliveries, merchant webhooks and scheduled runs. Failed
+-- attempts are retried with backoff, a job that runs out of attempts is dead until an admin requeues it.
+CREATE TABLE IF NOT EXISTS background_jobs (
+    job_id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    queue           VARCHAR(64) NOT NULL,
+    payload         JSONB NOT NULL,
+    status          VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'succeeded', 'dead')),
+    attempts        INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_error      TEXT NOT NULL DEFAULT '',
+    requeued_by     UUID REFERENCES users(user_id),
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at    TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_background_jobs_due ON background_jobs (queue, next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_background_jobs_status ON background_jobs (status, updated_at DESC);
+
+-- Webhook events still waiting for delivery are handed over to the job queue
+INSERT INTO background_jobs (queue, payload, attempts, next_attempt_at, last_error)
+SELECT 'merchant.webhook', jsonb_build_object('event_id', event_id), attempts, next_attempt_at, last_error
+FROM merchant_webhook_events WHERE status = 'pending';
@@ -0,0 +1,14 @@
+-- Events written in the same transaction as the change they announce. A relay publishes them to the
+-- subscribers in the background, so an event is not lost when the process stops right after a commit.
+CREATE TABLE IF NOT EXISTS outbox_events (
+    event_id        UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    topic           VARCHAR(64) NOT NULL,
+    payload         JSONB NOT NULL,
+    attempts        INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_error      TEXT NOT NULL DEFAULT '',
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    published_at    TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_events_unpublished ON outbox_events (next_attempt_at) WHERE published_at IS NULL;
@@ -0,0 +1,9 @@
+-- Request counts of the rate limiters per client and fixed window, shared by every instance. Losing them
+-- in a crash only resets the current windows, so the table skips the write-ahead log.
+CREATE UNLOGGED TABLE IF NOT EXISTS rate_limit_counters (
+    limiter      VARCHAR(32) NOT NULL,
+    client_key   TEXT NOT NULL,
+    window_start TIMESTAMPTZ NOT NULL,
+    hits         INT NOT NULL DEFAULT 1,
+    PRIMARY KEY (limiter, client_key, window_start)
+);
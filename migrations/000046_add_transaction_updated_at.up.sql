@@ -0,0 +1,4 @@
+-- Last change of a transaction, served as Last-Modified of the transaction history. Existing rows count
+-- as changed now so that no client keeps a copy from before.
+ALTER TABLE transactions
+    ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
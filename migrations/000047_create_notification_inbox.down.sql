@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_transactions_receiver_updated_at;
+DROP INDEX IF EXISTS idx_transactions_sender_updated_at;
+DROP TABLE IF EXISTS notification_inbox;
@@ -0,0 +1,19 @@
+-- Notifications delivered to a user, kept so that clients catching up through the sync endpoint see what
+-- they missed while offline. Entries are purged once they are older than the inbox retention.
+CREATE TABLE IF NOT EXISTS notification_inbox (
+    notification_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id         UUID NOT NULL REFERENCES users(user_id),
+    category        VARCHAR(50) NOT NULL,
+    title           TEXT NOT NULL,
+    body            TEXT NOT NULL,
+    data            JSONB NOT NULL DEFAULT '{}',
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_inbox_user_id
+    ON notification_inbox (user_id, created_at, notification_id);
+CREATE INDEX IF NOT EXISTS idx_notification_inbox_created_at ON notification_inbox (created_at);
+
+-- Changes of a user's transactions are read in (updated_at, transaction_id) order
+CREATE INDEX IF NOT EXISTS idx_transactions_sender_updated_at ON transactions (sender_user_id, updated_at, transaction_id);
+CREATE INDEX IF NOT EXISTS idx_transactions_receiver_updated_at ON transactions (receiver_user_id, updated_at, transaction_id);
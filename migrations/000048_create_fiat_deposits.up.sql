@@ -0,0 +1,21 @@
+-- Deposits the fiat on-ramp provider confirmed through its webhook, provider_deposit_id makes repeated
+-- deliveries of the same deposit a no-op. A deposit that bought ETH is credited by sending crypto_amount
+-- from the funding account to the user's wallet, a fiat only deposit is recorded and not credited.
+CREATE TABLE IF NOT EXISTS fiat_deposits (
+    deposit_id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    provider_deposit_id VARCHAR(128) NOT NULL UNIQUE,
+    user_id             UUID NOT NULL REFERENCES users(user_id),
+    wallet_id           VARCHAR(42) NOT NULL,
+    fiat_amount         NUMERIC(24, 6) NOT NULL CHECK (fiat_amount > 0),
+    fiat_currency       CHAR(3) NOT NULL,
+    crypto_amount       NUMERIC(78, 0) NOT NULL DEFAULT 0 CHECK (crypto_amount >= 0),
+    status              VARCHAR(16) NOT NULL
+        CHECK (status IN ('recorded', 'pending', 'crediting', 'credited', 'failed')),
+    tx_hash             VARCHAR(66),
+    error               TEXT NOT NULL DEFAULT '',
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at          TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_fiat_deposits_user ON fiat_deposits (user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_fiat_deposits_status ON fiat_deposits (status, created_at DESC);
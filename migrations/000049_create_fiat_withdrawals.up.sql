@@ -0,0 +1,28 @@
+-- Withdrawals to a bank account through the fiat off-ramp, paid out by ops by hand. The bank account
+-- is only known by the token the payout provider issued for it and its last four digits. The amount
+-- stays held in the wallet while the withdrawal is pending or being sent, approval sends it to the
+-- settlement address and ops complete the withdrawal once the bank payout went out.
+CREATE TABLE IF NOT EXISTS fiat_withdrawals (
+    withdrawal_id      UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id            UUID NOT NULL REFERENCES users(user_id),
+    wallet_id          VARCHAR(42) NOT NULL,
+    amount             NUMERIC(78, 0) NOT NULL CHECK (amount > 0),
+    fiat_currency      CHAR(3) NOT NULL,
+    bank_account_token VARCHAR(128) NOT NULL,
+    bank_account_last4 CHAR(4) NOT NULL,
+    status             VARCHAR(16) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'sending', 'processing', 'completed', 'rejected', 'failed')),
+    decided_by         UUID REFERENCES users(user_id),
+    tx_hash            VARCHAR(66),
+    payout_reference   VARCHAR(128),
+    reason             TEXT NOT NULL DEFAULT '',
+    created_at         TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at         TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Sums the holds on a wallet
+CREATE INDEX IF NOT EXISTS idx_fiat_withdrawals_held ON fiat_withdrawals (LOWER(wallet_id))
+    WHERE status IN ('pending', 'sending');
+
+CREATE INDEX IF NOT EXISTS idx_fiat_withdrawals_user ON fiat_withdrawals (user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_fiat_withdrawals_status ON fiat_withdrawals (status, created_at DESC);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS bank_link_tokens;
+DROP TABLE IF EXISTS bank_links;
@@ -0,0 +1,27 @@
+-- Bank accounts users linked through the bank data provider, their balances and income feed income
+-- verification. An unlinked account keeps its row for the record and loses its access token.
+CREATE TABLE IF NOT EXISTS bank_links (
+    link_id             UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id             UUID NOT NULL REFERENCES users(user_id),
+    provider            VARCHAR(32) NOT NULL,
+    provider_account_id VARCHAR(128) NOT NULL,
+    institution_name    VARCHAR(128) NOT NULL,
+    account_mask        VARCHAR(4) NOT NULL DEFAULT '',
+    account_type        VARCHAR(32) NOT NULL DEFAULT '',
+    currency            CHAR(3) NOT NULL,
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    unlinked_at         TIMESTAMPTZ
+);
+
+-- An account is linked to a user once at a time
+CREATE UNIQUE INDEX IF NOT EXISTS idx_bank_links_active ON bank_links (user_id, provider, provider_account_id)
+    WHERE unlinked_at IS NULL;
+
+-- Access tokens of the provider, encrypted like wallet_private_keys and rotated with them
+CREATE TABLE IF NOT EXISTS bank_link_tokens (
+    link_id      UUID PRIMARY KEY REFERENCES bank_links(link_id),
+    access_token TEXT NOT NULL,
+    key_version  INT NOT NULL,
+    cipher       VARCHAR(16) NOT NULL DEFAULT 'aes-gcm' CHECK (cipher IN ('aes-gcm')),
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,14 @@
+-- Encrypted names and dates of birth cannot be decrypted in SQL, dropping them would lose them for
+-- good. The rollback refuses to run while any user's personal data is encrypted.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM users WHERE full_name_encrypted IS NOT NULL OR date_of_birth_encrypted IS NOT NULL) THEN
+        RAISE EXCEPTION 'users hold encrypted personal data, decrypt it into full_name and date_of_birth before rolling back 000051';
+    END IF;
+END
+$$;
+
+DROP INDEX IF EXISTS idx_users_plaintext_pii;
+ALTER TABLE users DROP COLUMN IF EXISTS date_of_birth_encrypted;
+ALTER TABLE users DROP COLUMN IF EXISTS full_name_encrypted;
+DROP TABLE IF EXISTS user_data_keys;
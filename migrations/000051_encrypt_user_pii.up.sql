@@ -0,0 +1,20 @@
+-- Personal data of users is encrypted with a data key per user. The data keys are encrypted with the
+-- key ring like wallet_private_keys and rotated with them.
+CREATE TABLE IF NOT EXISTS user_data_keys (
+    user_id     UUID PRIMARY KEY REFERENCES users(user_id),
+    data_key    TEXT NOT NULL,
+    key_version INT NOT NULL,
+    cipher      VARCHAR(16) NOT NULL DEFAULT 'aes-gcm' CHECK (cipher IN ('aes-gcm')),
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- New users only fill the encrypted columns, the PII backfill moves existing users over and clears
+-- their plaintext columns
+ALTER TABLE users ADD COLUMN IF NOT EXISTS full_name_encrypted TEXT;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS date_of_birth_encrypted TEXT;
+ALTER TABLE users ALTER COLUMN full_name DROP NOT NULL;
+ALTER TABLE users ALTER COLUMN full_name DROP DEFAULT;
+
+-- Finds the users the backfill has left
+CREATE INDEX IF NOT EXISTS idx_users_plaintext_pii ON users (user_id)
+    WHERE full_name IS NOT NULL OR date_of_birth IS NOT NULL;
@@ -0,0 +1,11 @@
+-- Refresh tokens issued at sign-in, by the jti claim of the token. A token is used once: refreshing
+-- marks it used and issues a new one, a used token presented again revokes the user's open tokens.
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    token_id   UUID PRIMARY KEY,
+    user_id    UUID NOT NULL REFERENCES users(user_id),
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at    TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_open ON refresh_tokens (user_id) WHERE used_at IS NULL;
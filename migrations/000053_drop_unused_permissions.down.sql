@@ -0,0 +1 @@
+-- The removed permissions granted nothing, the grants deleted by 000053 are not restored
@@ -0,0 +1,3 @@
+-- kyc.review and loan.approve guarded nothing, there is no KYC review or loan in the service. Names
+-- and dates of birth are shown to holders of pii.read instead of kyc.review.
+DELETE FROM role_permissions WHERE permission IN ('kyc.review', 'loan.approve');